@@ -118,6 +118,21 @@ export function activate(context: vscode.ExtensionContext) {
   context.subscriptions.push(visualizeCommand);
   context.subscriptions.push(visualizeFolderCommand);
 
+  // Register jumps between a design and the code twf generate made from it.
+  // The server commands are registered by the language client, so these
+  // wrap them under their own names.
+  context.subscriptions.push(
+    vscode.commands.registerCommand("twf.goToGenerated", () =>
+      goToLinked("twf.openGenerated", "No generated code found for this definition")
+    ),
+    vscode.commands.registerCommand("twf.goToDesign", () =>
+      goToLinked("twf.openDesign", "No design definition found for this code")
+    ),
+    vscode.commands.registerCommand("twf.explain", explainKeyword),
+    vscode.commands.registerCommand("twf.showStatus", showStatus),
+    vscode.commands.registerCommand("twf.writeDebugBundle", writeDebugBundle)
+  );
+
   // Watch for document changes to update visualization
   context.subscriptions.push(
     vscode.workspace.onDidSaveTextDocument((doc) => {
@@ -225,17 +240,103 @@ async function copyDirRecursive(src: string, dest: string) {
   }
 }
 
+/**
+ * Build the language server flags for the ownership, review, unknown name,
+ * deprecated return, and await one order lint rules.
+ */
+function policyArgs(): string[] {
+  const config = vscode.workspace.getConfiguration("twf.lint");
+  const args: string[] = [];
+  if (config.get<boolean>("requireOwner", false)) {
+    args.push("--require-owner");
+  }
+  const criticalTag = config.get<string>("criticalTag", "");
+  if (criticalTag) {
+    args.push("--critical-tag", criticalTag);
+  }
+  const unknownNames = config.get<string>("unknownNames", "off");
+  if (unknownNames !== "off") {
+    args.push("--unknown-names", unknownNames);
+  }
+  const returnInWorkflow = config.get<string>("returnInWorkflow", "warning");
+  if (returnInWorkflow !== "warning") {
+    args.push("--return-in-workflow", returnInWorkflow);
+  }
+  const awaitOneOrder = config.get<string>("awaitOneOrder", "off");
+  if (awaitOneOrder !== "off") {
+    args.push("--await-one-order", awaitOneOrder);
+  }
+  return args;
+}
+
+/**
+ * Build the language server flags for its log level and format.
+ */
+function logArgs(): string[] {
+  const config = vscode.workspace.getConfiguration("twf.lsp");
+  return [
+    "--log-level",
+    config.get<string>("logLevel", "info"),
+    "--log-format",
+    config.get<string>("logFormat", "text"),
+  ];
+}
+
+/**
+ * Resolve the path in a twf.lsp setting, such as the keyword alias file,
+ * relative to the first workspace folder, or return undefined when none is
+ * configured.
+ */
+function settingPath(setting: string): string | undefined {
+  const file = vscode.workspace
+    .getConfiguration("twf.lsp")
+    .get<string>(setting, "");
+  if (!file) {
+    return undefined;
+  }
+  const folder = vscode.workspace.workspaceFolders?.[0];
+  return folder ? path.resolve(folder.uri.fsPath, file) : path.resolve(file);
+}
+
 function startLanguageClient(context: vscode.ExtensionContext) {
   const command = resolveTwfBinary(context);
 
+  const aliases = settingPath("aliases");
+  const args = ["lsp", ...policyArgs(), ...logArgs()];
+  if (aliases) {
+    args.push("--aliases", aliases);
+  }
+  const cacheDir = settingPath("cacheDir");
+  if (cacheDir) {
+    args.push("--cache-dir", cacheDir);
+  }
   const serverOptions: ServerOptions = {
-    run: { command, args: ["lsp"] } as Executable,
-    debug: { command, args: ["lsp"] } as Executable,
+    run: { command, args } as Executable,
+    debug: { command, args } as Executable,
   };
 
   const clientOptions: LanguageClientOptions = {
     documentSelector: [{ scheme: "file", language: "twf" }],
     outputChannelName: "TWF Language Server",
+    initializationOptions: {
+      crossRootResolution: vscode.workspace
+        .getConfiguration("twf.lsp")
+        .get<string>("crossRootResolution", "root"),
+      nexusEndpoints: vscode.workspace
+        .getConfiguration("twf.lsp")
+        .get<string[]>("nexusEndpoints", []),
+    },
+    // The server reloads the alias file when told it changed.
+    synchronize: aliases
+      ? {
+          fileEvents: vscode.workspace.createFileSystemWatcher(
+            new vscode.RelativePattern(
+              vscode.Uri.file(path.dirname(aliases)),
+              path.basename(aliases)
+            )
+          ),
+        }
+      : undefined,
   };
 
   client = new LanguageClient(
@@ -261,6 +362,130 @@ function startLanguageClient(context: vscode.ExtensionContext) {
   });
 }
 
+/**
+ * Ask the language server for the locations linked to the cursor through
+ * the twf generate source maps, and open the one chosen.
+ */
+async function goToLinked(command: string, notFound: string) {
+  const editor = vscode.window.activeTextEditor;
+  if (!editor || !client) {
+    return;
+  }
+  const result = await client.sendRequest<
+    { uri: string; range: { start: { line: number; character: number } } }[] | null
+  >("workspace/executeCommand", {
+    command,
+    arguments: [
+      {
+        textDocument: { uri: editor.document.uri.toString() },
+        position: editor.selection.active,
+      },
+    ],
+  });
+  if (!result || result.length === 0) {
+    vscode.window.showInformationMessage(notFound);
+    return;
+  }
+  let target = result[0];
+  if (result.length > 1) {
+    const picked = await vscode.window.showQuickPick(
+      result.map((loc) => ({
+        label: vscode.workspace.asRelativePath(vscode.Uri.parse(loc.uri)),
+        description: `line ${loc.range.start.line + 1}`,
+        loc,
+      }))
+    );
+    if (!picked) {
+      return;
+    }
+    target = picked.loc;
+  }
+  const start = new vscode.Position(target.range.start.line, target.range.start.character);
+  await vscode.window.showTextDocument(vscode.Uri.parse(target.uri), {
+    selection: new vscode.Range(start, start),
+  });
+}
+
+/**
+ * Explain the construct under the cursor with the server's twf/explain
+ * request, or one the user names when the cursor is not on a keyword.
+ */
+async function explainKeyword() {
+  if (!client) {
+    return;
+  }
+  type Entry = { keyword: string; summary: string; example: string; removed?: boolean };
+  const editor = vscode.window.activeTextEditor;
+  let entry: Entry | null = null;
+  if (editor && editor.document.languageId === "twf") {
+    entry = await client.sendRequest<Entry | null>("twf/explain", {
+      textDocument: { uri: editor.document.uri.toString() },
+      position: editor.selection.active,
+    });
+  }
+  if (!entry) {
+    const keyword = await vscode.window.showInputBox({
+      prompt: "Construct to explain",
+      placeHolder: "await one",
+    });
+    if (!keyword) {
+      return;
+    }
+    entry = await client.sendRequest<Entry | null>("twf/explain", { keyword });
+    if (!entry) {
+      vscode.window.showInformationMessage(`No explanation for ${keyword}`);
+      return;
+    }
+  }
+  const title = entry.removed ? `${entry.keyword} (removed)` : entry.keyword;
+  vscode.window.showInformationMessage(`${title}: ${entry.summary}`, {
+    modal: true,
+    detail: entry.example,
+  });
+}
+
+/**
+ * Show the server's estimated memory use from its twf/status request.
+ */
+async function showStatus() {
+  if (!client) {
+    return;
+  }
+  type Status = {
+    documents: number;
+    estimatedBytes: number;
+    index: { files: number; parsedFiles: number; maxParsedBytes: number; evictions: number };
+  };
+  const status = await client.sendRequest<Status>("twf/status");
+  const mib = (n: number) => `${(n / (1 << 20)).toFixed(1)} MiB`;
+  const limit = status.index.maxParsedBytes ? mib(status.index.maxParsedBytes) : "no limit";
+  vscode.window.showInformationMessage(`TWF server: about ${mib(status.estimatedBytes)}`, {
+    modal: true,
+    detail:
+      `${status.documents} open documents\n` +
+      `${status.index.parsedFiles} of ${status.index.files} workspace files parsed (${limit})\n` +
+      `${status.index.evictions} evictions`,
+  });
+}
+
+/**
+ * Ask the server for a debug bundle to attach to a bug report, and offer to
+ * reveal it.
+ */
+async function writeDebugBundle() {
+  if (!client) {
+    return;
+  }
+  const { path } = await client.sendRequest<{ path: string }>("twf/debugBundle");
+  const choice = await vscode.window.showInformationMessage(
+    `TWF debug bundle written to ${path}`,
+    "Reveal"
+  );
+  if (choice === "Reveal") {
+    vscode.commands.executeCommand("revealFileInOS", vscode.Uri.file(path));
+  }
+}
+
 export function deactivate(): Thenable<void> | undefined {
   if (client) {
     return client.stop();
@@ -0,0 +1,178 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/format"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/parser"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/resolver"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/validator"
+)
+
+// diagnostic is a parse, resolve, or validation error. File is set for parse
+// errors only; resolution runs over the merged definitions of all sources.
+type diagnostic struct {
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+type parseResult struct {
+	AST         *ast.File    `json:"ast"`
+	Diagnostics []diagnostic `json:"diagnostics"`
+}
+
+type checkResult struct {
+	OK          bool         `json:"ok"`
+	Workflows   int          `json:"workflows"`
+	Activities  int          `json:"activities"`
+	Diagnostics []diagnostic `json:"diagnostics"`
+}
+
+type symbol struct {
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	SourceFile string `json:"sourceFile,omitempty"`
+	Line       int    `json:"line"`
+}
+
+type symbolsResult struct {
+	Symbols     []symbol     `json:"symbols"`
+	Diagnostics []diagnostic `json:"diagnostics"`
+}
+
+type formatResult struct {
+	Formatted   string       `json:"formatted"`
+	Diagnostics []diagnostic `json:"diagnostics"`
+}
+
+// analyze parses each source independently, merges the definitions, and
+// resolves and validates them together, mirroring `twf parse`. Sources are
+// processed in name order so results are deterministic.
+func analyze(sources map[string]string) (*ast.File, []diagnostic) {
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	merged := &ast.File{}
+	diags := []diagnostic{}
+	for _, name := range names {
+		file, errs := parser.ParseFileAll(sources[name])
+		for _, e := range errs {
+			diags = append(diags, diagnostic{
+				File:     name,
+				Line:     e.Line,
+				Column:   e.Column,
+				Severity: "error",
+				Message:  e.Msg,
+			})
+		}
+		for _, def := range file.Definitions {
+			ast.SetSourceFile(def, name)
+			merged.Definitions = append(merged.Definitions, def)
+		}
+	}
+
+	resolved := resolver.ResolveFile(merged)
+	for _, e := range resolved.Errors {
+		diags = append(diags, diagnostic{
+			File:     e.File,
+			Line:     e.Line,
+			Column:   e.Column,
+			Severity: severity(e.Severity),
+			Message:  e.Msg,
+		})
+	}
+	for _, e := range validator.ValidateSymbols(resolved.Symbols) {
+		diags = append(diags, diagnostic{
+			Line:     e.Line,
+			Column:   e.Column,
+			Severity: severity(e.Severity),
+			Message:  e.Msg,
+		})
+	}
+	return merged, diags
+}
+
+func severity(s string) string {
+	if s == "" {
+		return "error"
+	}
+	return s
+}
+
+func parseSources(sources map[string]string) parseResult {
+	file, diags := analyze(sources)
+	return parseResult{AST: file, Diagnostics: diags}
+}
+
+func checkSources(sources map[string]string) checkResult {
+	file, diags := analyze(sources)
+	res := checkResult{OK: true, Diagnostics: diags}
+	for _, d := range diags {
+		if d.Severity == "error" {
+			res.OK = false
+		}
+	}
+	for _, def := range file.Definitions {
+		switch def.(type) {
+		case *ast.WorkflowDef:
+			res.Workflows++
+		case *ast.ActivityDef:
+			res.Activities++
+		}
+	}
+	return res
+}
+
+func symbolsOf(sources map[string]string) symbolsResult {
+	file, diags := analyze(sources)
+	res := symbolsResult{Symbols: []symbol{}, Diagnostics: diags}
+	for _, def := range file.Definitions {
+		sym := symbol{Line: def.NodeLine()}
+		switch d := def.(type) {
+		case *ast.WorkflowDef:
+			sym.Kind, sym.Name, sym.SourceFile = "workflow", d.Name, d.SourceFile
+		case *ast.ActivityDef:
+			sym.Kind, sym.Name, sym.SourceFile = "activity", d.Name, d.SourceFile
+		case *ast.WorkerDef:
+			sym.Kind, sym.Name, sym.SourceFile = "worker", d.Name, d.SourceFile
+		case *ast.NamespaceDef:
+			sym.Kind, sym.Name, sym.SourceFile = "namespace", d.Name, d.SourceFile
+		case *ast.NexusServiceDef:
+			sym.Kind, sym.Name, sym.SourceFile = "nexusService", d.Name, d.SourceFile
+		case *ast.ConstDef:
+			sym.Kind, sym.Name, sym.SourceFile = "const", d.Name, d.SourceFile
+		case *ast.EnumDef:
+			sym.Kind, sym.Name, sym.SourceFile = "enum", d.Name, d.SourceFile
+		default:
+			continue
+		}
+		res.Symbols = append(res.Symbols, sym)
+	}
+	return res
+}
+
+// formatSource lays out src as `twf fmt` does. A source that does not
+// parse, or whose layout would change its meaning, comes back unchanged
+// with the error as its one diagnostic.
+func formatSource(src string) formatResult {
+	out, err := format.Source(src)
+	if err == nil {
+		return formatResult{Formatted: out, Diagnostics: []diagnostic{}}
+	}
+	d := diagnostic{Line: 1, Column: 1, Severity: "error", Message: err.Error()}
+	var pe *parser.ParseError
+	if errors.As(err, &pe) {
+		d.Line, d.Column, d.Message = pe.Line, pe.Column, pe.Msg
+	}
+	return formatResult{Formatted: src, Diagnostics: []diagnostic{d}}
+}
@@ -0,0 +1,107 @@
+//go:build js && wasm
+
+// Command twf-wasm exposes the TWF parser to JavaScript as a WebAssembly
+// module, so browsers run the real parser instead of a re-implementation.
+//
+// Build it with:
+//
+//	GOOS=js GOARCH=wasm go build -o twf.wasm ./cmd/twf-wasm
+//
+// Once started it installs a global twf object whose functions take either a
+// single source string or an object mapping file names to sources, and
+// return plain objects:
+//
+//	twf.parse(sources)     -> {ast, diagnostics}
+//	twf.check(sources)     -> {ok, workflows, activities, diagnostics}
+//	twf.symbols(sources)   -> {symbols, diagnostics}
+//	twf.highlight(source)  -> HTML string
+//	twf.format(source)     -> {formatted, diagnostics}
+package main
+
+import (
+	"encoding/json"
+	"syscall/js"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/highlight"
+)
+
+const version = "0.1.0"
+
+// defaultName names a source passed as a bare string.
+const defaultName = "input.twf"
+
+func main() {
+	api := js.Global().Get("Object").New()
+	api.Set("version", version)
+	api.Set("parse", sourcesFunc(func(s map[string]string) any { return parseSources(s) }))
+	api.Set("check", sourcesFunc(func(s map[string]string) any { return checkSources(s) }))
+	api.Set("symbols", sourcesFunc(func(s map[string]string) any { return symbolsOf(s) }))
+	api.Set("highlight", js.FuncOf(func(_ js.Value, args []js.Value) any {
+		if len(args) == 0 || args[0].Type() != js.TypeString {
+			return jsError("highlight expects a source string")
+		}
+		return highlight.HTML(args[0].String())
+	}))
+	api.Set("format", js.FuncOf(func(_ js.Value, args []js.Value) any {
+		if len(args) == 0 || args[0].Type() != js.TypeString {
+			return jsError("format expects a source string")
+		}
+		return toJS(formatSource(args[0].String()))
+	}))
+	js.Global().Set("twf", api)
+
+	// Keep the Go runtime alive so the exported functions stay callable.
+	select {}
+}
+
+// sourcesFunc wraps fn as a JavaScript function taking sources and returning
+// fn's result converted to a plain object.
+func sourcesFunc(fn func(map[string]string) any) js.Func {
+	return js.FuncOf(func(_ js.Value, args []js.Value) any {
+		if len(args) == 0 {
+			return jsError("expected a source string or an object of sources")
+		}
+		sources, err := toSources(args[0])
+		if err != nil {
+			return err
+		}
+		return toJS(fn(sources))
+	})
+}
+
+// toSources accepts a source string or an object mapping file names to
+// source strings. On failure it returns a JavaScript Error value.
+func toSources(v js.Value) (map[string]string, any) {
+	switch v.Type() {
+	case js.TypeString:
+		return map[string]string{defaultName: v.String()}, nil
+	case js.TypeObject:
+		sources := make(map[string]string)
+		keys := js.Global().Get("Object").Call("keys", v)
+		for i := 0; i < keys.Length(); i++ {
+			name := keys.Index(i).String()
+			src := v.Get(name)
+			if src.Type() != js.TypeString {
+				return nil, jsError("source " + name + " is not a string")
+			}
+			sources[name] = src.String()
+		}
+		return sources, nil
+	default:
+		return nil, jsError("expected a source string or an object of sources")
+	}
+}
+
+// toJS converts v to a JavaScript object through its JSON encoding, which is
+// the same shape the CLI emits.
+func toJS(v any) any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return jsError(err.Error())
+	}
+	return js.Global().Get("JSON").Call("parse", string(data))
+}
+
+func jsError(msg string) js.Value {
+	return js.Global().Get("Error").New("twf: " + msg)
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+)
+
+// The filters below work on marshaled JSON rather than the AST so every node
+// type is handled uniformly, and on raw bytes rather than decoded maps so
+// object keys keep their schema order.
+
+// statementListKeys are the JSON keys that hold nested statement lists.
+// Each one entered adds a level of nesting for --depth.
+var statementListKeys = map[string]bool{"body": true, "elseBody": true, "default": true}
+
+// selector matches JSON objects whose key field equals value.
+type selector struct {
+	key   string
+	value string
+}
+
+func parseSelector(s string) (selector, error) {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok || key == "" {
+		return selector{}, fmt.Errorf("invalid --select %q: want key=value", s)
+	}
+	return selector{key: key, value: value}, nil
+}
+
+// field is one key/value pair of a JSON object, in source order.
+type field struct {
+	key   string
+	value json.RawMessage
+}
+
+// objectFields splits a JSON object into its fields. ok is false when raw is
+// not an object.
+func objectFields(raw json.RawMessage) (fields []field, ok bool, err error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || raw[0] != '{' {
+		return nil, false, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if _, err := dec.Token(); err != nil { // {
+		return nil, false, err
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, false, err
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, false, err
+		}
+		fields = append(fields, field{key: tok.(string), value: value})
+	}
+	return fields, true, nil
+}
+
+// arrayElems splits a JSON array into its elements. ok is false when raw is
+// not an array.
+func arrayElems(raw json.RawMessage) (elems []json.RawMessage, ok bool, err error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || raw[0] != '[' {
+		return nil, false, nil
+	}
+	if err := json.Unmarshal(raw, &elems); err != nil {
+		return nil, false, err
+	}
+	return elems, true, nil
+}
+
+// selectNodes returns every object in raw, at any depth and in document
+// order, that matches all selectors.
+func selectNodes(raw json.RawMessage, sels []selector) ([]json.RawMessage, error) {
+	var out []json.RawMessage
+	var visit func(json.RawMessage) error
+	visit = func(raw json.RawMessage) error {
+		if elems, ok, err := arrayElems(raw); ok || err != nil {
+			for _, e := range elems {
+				if err := visit(e); err != nil {
+					return err
+				}
+			}
+			return err
+		}
+		fields, ok, err := objectFields(raw)
+		if !ok || err != nil {
+			return err
+		}
+		if matchesAll(fields, sels) {
+			out = append(out, raw)
+		}
+		for _, f := range fields {
+			if err := visit(f.value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := visit(raw); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// matchesAll reports whether an object's fields satisfy every selector.
+// String values compare unquoted; other values compare by their JSON text.
+func matchesAll(fields []field, sels []selector) bool {
+	for _, sel := range sels {
+		matched := false
+		for _, f := range fields {
+			if f.key != sel.key {
+				continue
+			}
+			var s string
+			if json.Unmarshal(f.value, &s) == nil {
+				matched = s == sel.value
+			} else {
+				matched = string(f.value) == sel.value
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// truncateDepth drops statement lists nested more than maxDepth levels deep.
+// Definition bodies are level 1, so depth 0 keeps only definition headers.
+func truncateDepth(raw json.RawMessage, maxDepth int) (json.RawMessage, error) {
+	return truncate(raw, 0, maxDepth)
+}
+
+func truncate(raw json.RawMessage, level, maxDepth int) (json.RawMessage, error) {
+	if elems, ok, err := arrayElems(raw); ok || err != nil {
+		if err != nil {
+			return nil, err
+		}
+		var b bytes.Buffer
+		b.WriteByte('[')
+		for i, e := range elems {
+			t, err := truncate(e, level, maxDepth)
+			if err != nil {
+				return nil, err
+			}
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.Write(t)
+		}
+		b.WriteByte(']')
+		return b.Bytes(), nil
+	}
+
+	fields, ok, err := objectFields(raw)
+	if !ok || err != nil {
+		return raw, err
+	}
+	var b bytes.Buffer
+	b.WriteByte('{')
+	wrote := false
+	for _, f := range fields {
+		next := level
+		if statementListKeys[f.key] {
+			next++
+			if next > maxDepth {
+				continue
+			}
+		}
+		t, err := truncate(f.value, next, maxDepth)
+		if err != nil {
+			return nil, err
+		}
+		if wrote {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(f.key)
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(t)
+		wrote = true
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// definitionName returns the declared name of a definition.
+func definitionName(def ast.Definition) string {
+	switch d := def.(type) {
+	case *ast.WorkflowDef:
+		return d.Name
+	case *ast.ActivityDef:
+		return d.Name
+	case *ast.WorkerDef:
+		return d.Name
+	case *ast.NamespaceDef:
+		return d.Name
+	case *ast.NexusServiceDef:
+		return d.Name
+	case *ast.ConstDef:
+		return d.Name
+	case *ast.EnumDef:
+		return d.Name
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const filterDoc = `{"definitions":[
+	{"type":"workflowDef","name":"W","body":[
+		{"type":"if","line":2,"body":[
+			{"type":"activityCall","name":"A","line":3}
+		],"elseBody":[
+			{"type":"activityCall","name":"B","line":5}
+		]}
+	],"signals":[{"type":"signalDecl","name":"S","body":[{"type":"raw","line":9}]}]}
+]}`
+
+func TestTruncateDepth(t *testing.T) {
+	tests := []struct {
+		depth int
+		want  string
+	}{
+		{0, `{"definitions":[{"type":"workflowDef","name":"W","signals":[{"type":"signalDecl","name":"S"}]}]}`},
+		{1, `{"definitions":[{"type":"workflowDef","name":"W","body":[{"type":"if","line":2}],"signals":[{"type":"signalDecl","name":"S","body":[{"type":"raw","line":9}]}]}]}`},
+	}
+	for _, tt := range tests {
+		got, err := truncateDepth(json.RawMessage(filterDoc), tt.depth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("depth %d:\ngot  %s\nwant %s", tt.depth, got, tt.want)
+		}
+	}
+}
+
+func TestSelectNodes(t *testing.T) {
+	sel := func(args ...string) []string {
+		var sels []selector
+		for _, a := range args {
+			s, err := parseSelector(a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sels = append(sels, s)
+		}
+		nodes, err := selectNodes(json.RawMessage(filterDoc), sels)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []string
+		for _, n := range nodes {
+			var v struct{ Name string }
+			json.Unmarshal(n, &v)
+			out = append(out, v.Name)
+		}
+		return out
+	}
+
+	if got := sel("type=activityCall"); strings.Join(got, ",") != "A,B" {
+		t.Errorf("type=activityCall: got %v", got)
+	}
+	if got := sel("type=activityCall", "line=5"); strings.Join(got, ",") != "B" {
+		t.Errorf("type=activityCall line=5: got %v", got)
+	}
+	if got := sel("type=nexusCall"); len(got) != 0 {
+		t.Errorf("type=nexusCall: got %v", got)
+	}
+	if _, err := parseSelector("activityCall"); err == nil {
+		t.Error("expected error for selector without =")
+	}
+}
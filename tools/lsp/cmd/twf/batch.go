@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/internal/cache"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/lexer"
+)
+
+// batchInput is one line of `twf batch` input. When Content is nil the file
+// is read from Path.
+type batchInput struct {
+	Path    string  `json:"path"`
+	Content *string `json:"content"`
+}
+
+// batchResult is one line of `twf batch` output. Error is set, and the
+// analysis fields are empty, when the input line could not be processed.
+type batchResult struct {
+	Path        string       `json:"path"`
+	OK          bool         `json:"ok"`
+	Error       string       `json:"error,omitempty"`
+	Diagnostics []diagnostic `json:"diagnostics,omitempty"`
+	Symbols     []symbolJSON `json:"symbols,omitempty"`
+	AST         *ast.File    `json:"ast,omitempty"`
+}
+
+// batchCommand analyzes a JSON-lines stream of files, writing one result per
+// input line, so pipelines scan many files in a single process.
+func batchCommand(fs *flag.FlagSet) func() int {
+	includeAST := fs.Bool("ast", false, "Include the AST in each result")
+	cacheDir := fs.String("cache-dir", "", "Reuse the results of earlier runs for files with the same name and content, stored in `dir` (not with --ast)")
+	return func() int {
+		if fs.NArg() > 0 {
+			fmt.Fprintln(os.Stderr, "usage: twf batch [--ast] < input.jsonl")
+			return exitUsage
+		}
+		var c *cache.Cache
+		if *cacheDir != "" {
+			var err error
+			if c, err = cache.Open(*cacheDir); err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				return exitUsage
+			}
+		}
+		return runBatch(os.Stdin, os.Stdout, *includeAST, c)
+	}
+}
+
+// runBatch processes each line of r independently; files are not resolved
+// against each other. Results are flushed line by line so a consumer can
+// interleave writes and reads. It returns exitUsage if any line could not
+// be processed; files with diagnostics still count as processed. Results
+// without the AST are looked up in and added to c when it is not nil.
+func runBatch(r io.Reader, w io.Writer, includeAST bool, c *cache.Cache) int {
+	in := bufio.NewReader(r)
+	out := bufio.NewWriter(w)
+	enc := json.NewEncoder(out)
+
+	exitCode := 0
+	for {
+		line, readErr := in.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			res := batchFile(line, includeAST, c)
+			if res.Error != "" {
+				exitCode = exitUsage
+			}
+			if err := enc.Encode(res); err != nil {
+				fmt.Fprintf(os.Stderr, "json marshal error: %v\n", err)
+				return exitInternal
+			}
+			if err := out.Flush(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return exitInternal
+			}
+		}
+		if errors.Is(readErr, io.EOF) {
+			return exitCode
+		}
+		if readErr != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", readErr)
+			return exitInternal
+		}
+	}
+}
+
+// batchFile analyzes the file described by one input line.
+func batchFile(line []byte, includeAST bool, c *cache.Cache) batchResult {
+	var in batchInput
+	if err := json.Unmarshal(line, &in); err != nil {
+		return batchResult{Error: "invalid input: " + err.Error()}
+	}
+	if in.Path == "" {
+		return batchResult{Error: "invalid input: missing path"}
+	}
+
+	var text string
+	if in.Content != nil {
+		text = *in.Content
+	} else {
+		data, err := os.ReadFile(in.Path)
+		if err != nil {
+			return batchResult{Path: outputPath(in.Path), Error: err.Error()}
+		}
+		text = string(data)
+	}
+
+	src := source{Name: filepath.Base(in.Path), Text: text}
+	var res batchResult
+	run := func() {
+		file, diags := analyze([]source{src}, lexer.Options{})
+		res = batchResult{
+			OK:          true,
+			Diagnostics: diags,
+			Symbols:     extractSymbols(file),
+		}
+		for _, d := range diags {
+			if d.Severity == "error" {
+				res.OK = false
+			}
+		}
+		if includeAST {
+			res.AST = file
+		}
+	}
+	if c == nil || includeAST {
+		run()
+	} else if _, err := c.GetOrPut(cacheKey("batch", []source{src}, lexer.Options{}), &res, run); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: cache: %v\n", err)
+	}
+	res.Path = outputPath(in.Path)
+	return res
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunBatch(t *testing.T) {
+	dir := t.TempDir()
+	onDisk := filepath.Join(dir, "disk.twf")
+	if err := os.WriteFile(onDisk, []byte("activity Charge(id: string):\n    return\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	input := strings.Join([]string{
+		`{"path": "ok.twf", "content": "workflow Order():\n    timer(5m)\n"}`,
+		``,
+		`{"path": "bad.twf", "content": "workflow Order():\n    activity Missing()\n"}`,
+		`not json`,
+		`{"path": "` + onDisk + `"}`,
+	}, "\n")
+
+	var out bytes.Buffer
+	if code := runBatch(strings.NewReader(input), &out, true, nil); code != exitUsage {
+		t.Errorf("exit code %d, want %d for the malformed line", code, exitUsage)
+	}
+
+	// The AST does not unmarshal into ast.File, so keep it raw.
+	type result struct {
+		batchResult
+		AST json.RawMessage `json:"ast"`
+	}
+	var results []result
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var res result
+		if err := json.Unmarshal([]byte(line), &res); err != nil {
+			t.Fatalf("invalid output line %q: %v", line, err)
+		}
+		results = append(results, res)
+	}
+	if len(results) != 4 {
+		t.Fatalf("got %d results, want 4 (blank lines skipped)", len(results))
+	}
+
+	if r := results[0]; !r.OK || len(r.Symbols) != 1 || len(r.AST) == 0 {
+		t.Errorf("ok.twf: %+v", r)
+	}
+	if r := results[1]; r.OK || len(r.Diagnostics) != 1 || r.Diagnostics[0].Message != "undefined activity: Missing" {
+		t.Errorf("bad.twf: %+v", r)
+	}
+	if r := results[2]; r.Error == "" || r.OK {
+		t.Errorf("malformed line: %+v", r)
+	}
+	if r := results[3]; !r.OK || r.Path != onDisk || len(r.Symbols) != 1 || r.Symbols[0].Name != "Charge" {
+		t.Errorf("file read from path: %+v", r)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime/debug"
+	"sync"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/internal/cache"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/lexer"
+)
+
+// buildID identifies the running twf build, so a rebuilt binary does not
+// reuse the results of the one before it: the version, the VCS revision
+// when built from a checkout, and the executable's size and modification
+// time, which change on every rebuild.
+var buildID = sync.OnceValue(func() string {
+	id := version
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" || s.Key == "vcs.modified" {
+				id += " " + s.Value
+			}
+		}
+	}
+	if exe, err := os.Executable(); err == nil {
+		if info, err := os.Stat(exe); err == nil {
+			id += fmt.Sprintf(" %d %d", info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	return id
+})
+
+// cacheKey returns the cache key of analyzing sources, lexed with lex, for
+// kind, with the options that change the result. The lexer options are
+// part of it, since they change how the sources lex.
+func cacheKey(kind string, sources []source, lex lexer.Options, options ...string) string {
+	parts := []string{kind, buildID(), lex.Key()}
+	parts = append(parts, options...)
+	for _, src := range sources {
+		parts = append(parts, src.Name, src.Text)
+	}
+	return cache.Key(parts...)
+}
@@ -1,53 +1,268 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
 
+	"github.com/jmbarzee/temporal-skills/tools/lsp/internal/cache"
 	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/lexer"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/options"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/parser"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/resolver"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/validator"
 )
 
-// checkCommand validates TWF files and reports errors.
-func checkCommand(args []string) int {
-	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+// checkCommand validates TWF files and reports errors. With --cache-dir, a
+// run over the same files with the same options and build prints the
+// stored result instead of analyzing them again.
+func checkCommand(fs *flag.FlagSet) func() int {
 	lenient := fs.Bool("lenient", false, "Continue even with resolve errors")
-	if err := fs.Parse(args); err != nil {
-		return 1
-	}
+	policy := policyFlags(fs)
+	var lex lexer.Options
+	aliasesFlag(fs, &lex)
+	keywordCaseFlag(fs, &lex)
+	cacheDir := fs.String("cache-dir", "", "Reuse the results of earlier runs over the same files, stored in `dir`")
+	allowDuplicates := fs.Bool("allow-duplicates-across-files", false, "Let a definition in one file shadow a definition of the same name in a file given earlier, instead of reporting a duplicate")
+	var dialect string
+	fs.Func("dialect", "Report constructs outside the grammar `name`: legacy (workflows end with return), v2 (with close), or auto (per file, by its '# twf:dialect' comment or its contents); replaces --return-in-workflow", func(name string) error {
+		switch name {
+		case validator.DialectLegacy, validator.DialectV2, validator.DialectAuto:
+			dialect = name
+			return nil
+		}
+		return fmt.Errorf("must be %s, %s, or %s", validator.DialectLegacy, validator.DialectV2, validator.DialectAuto)
+	})
+	return func() int {
+		paths := fs.Args()
+		if len(paths) == 0 {
+			fmt.Fprintln(os.Stderr, "usage: twf check [--lenient] [--require-owner] [--critical-tag TAG] [--require-timeout] [--require-activity-timeout] [--option-defaults FILE] [--dialect legacy|v2|auto] [--allow-duplicates-across-files] [--aliases FILE] [--case-insensitive-keywords] [--cache-dir DIR] <file...>")
+			return exitUsage
+		}
+		if dialect != "" && flagGiven(fs, "return-in-workflow") {
+			fmt.Fprintln(os.Stderr, "error: --dialect replaces --return-in-workflow; give only one")
+			return exitUsage
+		}
+		sources, exitCode := readSources(paths)
+		if exitCode != 0 {
+			return exitCode
+		}
 
-	paths := fs.Args()
-	if len(paths) == 0 {
-		fmt.Fprintln(os.Stderr, "usage: twf check [--lenient] <file...>")
-		return 1
+		var c *cache.Cache
+		var key string
+		if *cacheDir != "" {
+			var err error
+			if c, err = cache.Open(*cacheDir); err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				return exitUsage
+			}
+			key = cacheKey("check", sources, lex, fmt.Sprint(*lenient), policyKey(*policy), dialect, fmt.Sprint(*allowDuplicates))
+		}
+		var res checkResult
+		run := func() { res = runCheck(sources, lex, *lenient, *allowDuplicates, *policy, dialect) }
+		if c == nil {
+			run()
+		} else if _, err := c.GetOrPut(key, &res, run); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: cache: %v\n", err)
+		}
+		return res.print()
 	}
+}
 
-	file, errs, exitCode := parseFiles(paths, *lenient)
+// checkResult is what twf check reports, as printed and as cached.
+type checkResult struct {
+	Errors     []string `json:"errors"`
+	Workflows  int      `json:"workflows"`
+	Activities int      `json:"activities"`
+	ExitCode   int      `json:"exitCode"`
+}
 
-	// Always report errors to stderr
-	printErrors(errs)
+func runCheck(sources []source, lex lexer.Options, lenient, allowDuplicates bool, policy validator.Policy, dialect string) checkResult {
+	file, errs, exitCode := parseSources(sources, lex, lenient, allowDuplicates)
+	if dialect != "" {
+		// The dialect decides whether returns in workflow bodies are allowed.
+		policy.ReturnInWorkflow = validator.ReturnInWorkflowOff
+		if file != nil {
+			dialectErrs := checkDialect(file, sources, dialect)
+			if len(dialectErrs) > 0 && !lenient {
+				exitCode = exitDiagnostics
+			}
+			errs = append(errs, dialectErrs...)
+		}
+	}
+	if file != nil && lex.CaseInsensitiveKeywords {
+		errs = append(errs, checkKeywordCase(sources)...)
+	}
+	if file != nil && policy.Enabled() {
+		policyErrs, failed := checkPolicy(file, policy)
+		if failed && !lenient {
+			exitCode = exitDiagnostics
+		}
+		errs = append(errs, policyErrs...)
+	}
 
 	// Count definitions from partial AST
-	var workflows, activities int
+	res := checkResult{Errors: errs, ExitCode: exitCode}
 	if file != nil {
 		for _, def := range file.Definitions {
 			switch def.(type) {
 			case *ast.WorkflowDef:
-				workflows++
+				res.Workflows++
 			case *ast.ActivityDef:
-				activities++
+				res.Activities++
 			}
 		}
 	}
+	return res
+}
 
-	if exitCode != 0 {
+// print reports r and returns the exit code.
+func (r checkResult) print() int {
+	// Always report errors to stderr
+	printErrors(r.Errors)
+
+	if r.ExitCode != 0 {
 		// Still show what we parsed
-		if workflows > 0 || activities > 0 {
-			fmt.Fprintf(os.Stderr, "Partial parse: %d workflow(s), %d activity(s)\n", workflows, activities)
+		if r.Workflows > 0 || r.Activities > 0 {
+			fmt.Fprintf(os.Stderr, "Partial parse: %d workflow(s), %d activity(s)\n", r.Workflows, r.Activities)
 		}
-		return exitCode
+		return r.ExitCode
 	}
 
-	fmt.Printf("✓ OK: %d workflow(s), %d activity(s)\n", workflows, activities)
+	fmt.Printf("✓ OK: %d workflow(s), %d activity(s)\n", r.Workflows, r.Activities)
 	return 0
 }
+
+// aliasesFlag registers --aliases, which loads a keyword alias table into
+// lex->Aliases.
+func aliasesFlag(fs *flag.FlagSet, lex *lexer.Options) {
+	fs.Func("aliases", "Lex the keyword aliases in the JSON `file` as the keywords they stand for", func(path string) (err error) {
+		lex.Aliases, err = parser.LoadAliases(path)
+		return err
+	})
+}
+
+// keywordCaseFlag registers --case-insensitive-keywords, which sets
+// lex.CaseInsensitiveKeywords.
+func keywordCaseFlag(fs *flag.FlagSet, lex *lexer.Options) {
+	fs.BoolVar(&lex.CaseInsensitiveKeywords, "case-insensitive-keywords", false, "Read keywords in any case, such as Workflow or IF, warning about each not in lowercase")
+}
+
+// optionDefaultsFlag registers --option-defaults, loading the defaults
+// file it names into *dst.
+func optionDefaultsFlag(fs *flag.FlagSet, dst **options.Config) {
+	fs.Func("option-defaults", "Merge the call option defaults in the JSON `file` below the options of definitions and calls", func(path string) (err error) {
+		*dst, err = options.LoadConfig(path)
+		return err
+	})
+}
+
+// policyFlags registers the flags that enable ownership and review rules.
+func policyFlags(fs *flag.FlagSet) *validator.Policy {
+	p := &validator.Policy{}
+	fs.BoolVar(&p.RequireOwner, "require-owner", false, "Require @owner on every workflow")
+	fs.StringVar(&p.CriticalTag, "critical-tag", "", "Require @sla and call timeouts on workflows tagged @tag(`TAG`)")
+	fs.BoolVar(&p.RequireTimeout, "require-timeout", false, "Warn about workflows with no timeout path")
+	fs.BoolVar(&p.RequireActivityTimeout, "require-activity-timeout", false, "Warn about activity calls whose effective options set no start_to_close_timeout or schedule_to_close_timeout")
+	fs.Func("unknown-names", "Warn about undeclared names in raw assignments and conditions; `mode` typos reports those near a declared name, all every one", func(mode string) error {
+		if mode != validator.UnknownNamesTypos && mode != validator.UnknownNamesAll {
+			return fmt.Errorf("must be %s or %s", validator.UnknownNamesTypos, validator.UnknownNamesAll)
+		}
+		p.UnknownNames = mode
+		return nil
+	})
+	p.ReturnInWorkflow = validator.ReturnInWorkflowWarning
+	fs.Func("return-in-workflow", "Report return statements in workflow bodies, deprecated for close, at severity `mode`: off, warning (the default), or error", func(mode string) error {
+		switch mode {
+		case validator.ReturnInWorkflowOff, validator.ReturnInWorkflowWarning, validator.ReturnInWorkflowError:
+			p.ReturnInWorkflow = mode
+			return nil
+		}
+		return fmt.Errorf("must be %s, %s, or %s", validator.ReturnInWorkflowOff, validator.ReturnInWorkflowWarning, validator.ReturnInWorkflowError)
+	})
+	fs.Func("await-one-order", "Warn about await one cases out of the order `mode` asks for, timer-last or watch-first, and about blocks with more than one timer case", func(mode string) error {
+		if mode != validator.AwaitOneOrderTimerLast && mode != validator.AwaitOneOrderWatchFirst {
+			return fmt.Errorf("must be %s or %s", validator.AwaitOneOrderTimerLast, validator.AwaitOneOrderWatchFirst)
+		}
+		p.AwaitOneOrder = mode
+		return nil
+	})
+	optionDefaultsFlag(fs, &p.OptionDefaults)
+	return p
+}
+
+// flagGiven reports whether the flag name was set, on the command line or
+// by a config file, rather than left at its default.
+func flagGiven(fs *flag.FlagSet, name string) bool {
+	given := false
+	fs.Visit(func(f *flag.Flag) { given = given || f.Name == name })
+	return given
+}
+
+// policyKey spells p for cache keys, with its option defaults by value
+// rather than by address.
+func policyKey(p validator.Policy) string {
+	data, _ := json.Marshal(p)
+	return string(data)
+}
+
+// checkPolicy formats the policy violations in file as validation errors,
+// reporting whether any is more than a warning.
+func checkPolicy(file *ast.File, p validator.Policy) (errs []string, failed bool) {
+	for _, e := range validator.CheckPolicy(resolver.CollectSymbols(file), p) {
+		failed = failed || e.Severity != "warning"
+		errs = append(errs, diagnostic{
+			Line:     e.Line,
+			Column:   e.Column,
+			Stage:    "validation",
+			Severity: severity(e.Severity),
+			Message:  e.Msg,
+		}.String())
+	}
+	return errs, failed
+}
+
+// checkKeywordCase formats the keywords of each source not in lowercase
+// as validation warnings, naming the file.
+func checkKeywordCase(sources []source) []string {
+	var errs []string
+	for _, src := range sources {
+		for _, e := range validator.CheckKeywordCase(src.Text) {
+			errs = append(errs, diagnostic{
+				File:     src.Name,
+				Line:     e.Line,
+				Column:   e.Column,
+				Stage:    "validation",
+				Severity: severity(e.Severity),
+				Message:  e.Msg,
+			}.String())
+		}
+	}
+	return errs
+}
+
+// checkDialect formats the constructs of each source outside dialect as
+// validation errors, naming the file since each is checked on its own.
+func checkDialect(file *ast.File, sources []source, dialect string) []string {
+	defs := make(map[string][]ast.Definition)
+	for _, def := range file.Definitions {
+		name := ast.SourceFile(def)
+		defs[name] = append(defs[name], def)
+	}
+	var errs []string
+	for _, src := range sources {
+		for _, e := range validator.CheckDialect(defs[src.Name], src.Text, dialect) {
+			errs = append(errs, diagnostic{
+				File:     src.Name,
+				Line:     e.Line,
+				Column:   e.Column,
+				Stage:    "validation",
+				Severity: severity(e.Severity),
+				Message:  e.Msg,
+			}.String())
+		}
+	}
+	return errs
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"runtime/debug"
+	"slices"
+	"strings"
+)
+
+// Exit codes. Every command keeps to them, so CI scripts can branch on the
+// kind of failure.
+const (
+	exitDiagnostics = 1 // the design has errors, or the check a command makes failed
+	exitUsage       = 2 // the command line, config, or an input is unusable
+	exitInternal    = 3 // twf failed, such as writing output, or panicked
+)
+
+// Environment variables standing in for global options.
+const (
+	envConfig  = "TWF_CONFIG"   // --config, when it is not given
+	envNoColor = "TWF_NO_COLOR" // --no-color, when set to anything but ""
+)
+
+// A command is a twf subcommand. Registering its flags is kept apart from
+// running it so that help and completion can list them without running
+// anything.
+type command struct {
+	name    string
+	summary string
+	args    string // the operands, as help shows them after the options
+	// setup registers the command's flags on fs and returns the function
+	// running the command once fs has parsed its arguments.
+	setup func(fs *flag.FlagSet) func() int
+	// subcommands, when set, take the place of setup: the first argument
+	// names the one to run.
+	subcommands []command
+	files       bool     // operands are .twf files or directories of them
+	words       []string // operands are these words
+}
+
+// globalOptions are the options every command takes, before or after its
+// name.
+type globalOptions struct {
+	json     bool
+	noColor  bool
+	config   string
+	hermetic bool
+}
+
+// global holds the global options of the running command.
+var global globalOptions
+
+// register registers the global options on fs. withJSON is false for
+// commands defining their own --json, which stands in for the global one.
+func (g *globalOptions) register(fs *flag.FlagSet, withJSON bool) {
+	if withJSON {
+		fs.BoolVar(&g.json, "json", false, "Output JSON, for commands that have a --json option")
+	}
+	fs.BoolVar(&g.noColor, "no-color", false, "Disable colored output, as setting "+envNoColor+" or NO_COLOR does")
+	fs.StringVar(&g.config, "config", "", "Read default command options from the JSON `file` (default: $"+envConfig+")")
+	fs.BoolVar(&g.hermetic, "hermetic", false, "Read only the inputs the command line names: not $"+envConfig+", caches, directories, nor files an earlier run wrote")
+}
+
+func newGlobalFlagSet(withJSON bool) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	new(globalOptions).register(fs, withJSON)
+	return fs
+}
+
+// colorEnabled reports whether output may be colored: not with --no-color,
+// nor when TWF_NO_COLOR or NO_COLOR (https://no-color.org) is set.
+func colorEnabled() bool {
+	return !global.noColor && os.Getenv(envNoColor) == "" && os.Getenv("NO_COLOR") == ""
+}
+
+// run runs the command args name, after the global options, with the
+// remaining arguments, and returns its exit code. A panic is reported as
+// an internal error rather than with Go's exit code 2, which would read as
+// a usage error.
+func run(args []string) (code int) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Fprintf(os.Stderr, "internal error: %v\n%s", r, debug.Stack())
+			code = exitInternal
+		}
+	}()
+
+	top := flag.NewFlagSet(name, flag.ContinueOnError)
+	global.register(top, true)
+	top.Usage = func() {}
+	if err := top.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fmt.Print(usage())
+			return 0
+		}
+		fmt.Fprint(os.Stderr, usage())
+		return exitUsage
+	}
+	if top.NArg() == 0 {
+		fmt.Fprint(os.Stderr, usage())
+		return exitUsage
+	}
+
+	cmd, path, args, ok := findCommand(top.Args())
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", top.Arg(0))
+		fmt.Fprint(os.Stderr, usage())
+		return exitUsage
+	}
+	if len(cmd.subcommands) > 0 {
+		if len(args) > 0 && (args[0] == "-h" || args[0] == "--help") {
+			writeHelp(os.Stderr, path, cmd)
+			return 0
+		}
+		if len(args) > 0 {
+			fmt.Fprintf(os.Stderr, "Unknown command: %s %s\n\n", path, args[0])
+		}
+		writeHelp(os.Stderr, path, cmd)
+		return exitUsage
+	}
+
+	fs := flag.NewFlagSet(path, flag.ContinueOnError)
+	runCommand := cmd.setup(fs)
+	ownJSON := fs.Lookup("json") != nil
+	var local globalOptions
+	local.register(fs, !ownJSON)
+	fs.Usage = func() { writeHelp(fs.Output(), path, cmd) }
+	if ownJSON && global.json {
+		fs.Set("json", "true")
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return exitUsage
+	}
+
+	global.hermetic = global.hermetic || local.hermetic
+	envConfigFile := os.Getenv(envConfig)
+	if global.hermetic {
+		envConfigFile = ""
+	}
+	if config := cmp.Or(local.config, global.config, envConfigFile); config != "" {
+		if err := applyConfig(fs, path, config); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return exitUsage
+		}
+	}
+	if f := fs.Lookup("cache-dir"); global.hermetic && f != nil && f.Value.String() != "" {
+		fmt.Fprintln(os.Stderr, "error: --cache-dir reuses the results of earlier runs, which --hermetic rules out")
+		return exitUsage
+	}
+	global.json = global.json || local.json
+	global.noColor = global.noColor || local.noColor
+	if global.json && !ownJSON {
+		fmt.Fprintf(os.Stderr, "error: twf %s has no JSON output\n", path)
+		return exitUsage
+	}
+	return runCommand()
+}
+
+// findCommand finds the command args start with, descending into the
+// subcommands of each command found, and returns it with its path, such as
+// "export history", and the arguments after it. A command with
+// subcommands is returned when the next argument names none of them.
+func findCommand(args []string) (cmd command, path string, rest []string, ok bool) {
+	cmds := commands
+	for len(args) > 0 && (!ok || len(cmd.subcommands) > 0) {
+		i := slices.IndexFunc(cmds, func(c command) bool { return c.name == args[0] })
+		if i < 0 {
+			break
+		}
+		cmd, ok = cmds[i], true
+		path = strings.TrimSpace(path + " " + cmd.name)
+		args = args[1:]
+		cmds = cmd.subcommands
+	}
+	return cmd, path, args, ok
+}
+
+// helpCommand prints the usage, or the help of the command its arguments
+// name.
+func helpCommand(fs *flag.FlagSet) func() int {
+	return func() int {
+		if fs.NArg() == 0 {
+			fmt.Print(usage())
+			return 0
+		}
+		cmd, path, _, ok := findCommand(fs.Args())
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Unknown command: %s\n", strings.Join(fs.Args(), " "))
+			return exitUsage
+		}
+		writeHelp(os.Stdout, path, cmd)
+		return 0
+	}
+}
+
+// writeHelp writes the help of the command at path, generated from its
+// table entry: how to call it, what it does, and its options, or its
+// subcommands.
+func writeHelp(w io.Writer, path string, cmd command) {
+	if len(cmd.subcommands) > 0 {
+		fmt.Fprintf(w, "usage: twf %s <command> [options] ...\n\n%s\n\nCommands:\n", path, cmd.summary)
+		for _, s := range cmd.subcommands {
+			fmt.Fprintf(w, "  %-10s %s\n", s.name, s.summary)
+		}
+		return
+	}
+	fs := flag.NewFlagSet(path, flag.ContinueOnError)
+	cmd.setup(fs)
+	synopsis := "twf " + path
+	hasFlags := false
+	fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+	if hasFlags {
+		synopsis += " [options]"
+	}
+	if cmd.args != "" {
+		synopsis += " " + cmd.args
+	}
+	fmt.Fprintf(w, "usage: %s\n\n%s\n", synopsis, cmd.summary)
+	if hasFlags {
+		fmt.Fprint(w, "\nOptions:\n")
+		printFlags(w, fs)
+	}
+	fmt.Fprint(w, "\nGlobal options:\n")
+	printFlags(w, newGlobalFlagSet(fs.Lookup("json") == nil))
+}
+
+// printFlags lists the flags of fs as --name VALUE with their usage and
+// any default other than the zero value.
+func printFlags(w io.Writer, fs *flag.FlagSet) {
+	fs.VisitAll(func(f *flag.Flag) {
+		valueName, usage := flag.UnquoteUsage(f)
+		flagName := "--" + f.Name
+		if valueName != "" {
+			flagName += " " + valueName
+		}
+		switch f.DefValue {
+		case "", "false", "0":
+		default:
+			usage += fmt.Sprintf(" (default %s)", f.DefValue)
+		}
+		if len(flagName) > 22 {
+			fmt.Fprintf(w, "  %s\n  %-22s %s\n", flagName, "", usage)
+			return
+		}
+		fmt.Fprintf(w, "  %-22s %s\n", flagName, usage)
+	})
+}
+
+// applyConfig sets the flags of fs the command line left unset from the
+// JSON config file, an object mapping command paths such as "check" or
+// "export history" to objects of flag values:
+//
+//	{"check": {"require-owner": true, "critical-tag": "critical"}, "graph": {"exclude": ["Notify*"]}}
+//
+// A list sets a repeatable flag once per element.
+func applyConfig(fs *flag.FlagSet, path, file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var config map[string]map[string]any
+	if err := dec.Decode(&config); err != nil {
+		return fmt.Errorf("%s: %v", file, err)
+	}
+	for _, key := range slices.Sorted(maps.Keys(config)) {
+		if cmd, p, rest, ok := findCommand(strings.Fields(key)); !ok || p != key || len(rest) > 0 || len(cmd.subcommands) > 0 {
+			return fmt.Errorf("%s: unknown command %q", file, key)
+		}
+	}
+
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	section := config[path]
+	for _, flagName := range slices.Sorted(maps.Keys(section)) {
+		if fs.Lookup(flagName) == nil || flagName == "config" {
+			return fmt.Errorf("%s: twf %s has no --%s option", file, path, flagName)
+		}
+		if set[flagName] {
+			continue
+		}
+		values, ok := section[flagName].([]any)
+		if !ok {
+			values = []any{section[flagName]}
+		}
+		for _, v := range values {
+			switch v.(type) {
+			case string, bool, json.Number:
+			default:
+				return fmt.Errorf("%s: %s.%s must be a string, number, boolean, or list of them", file, path, flagName)
+			}
+			if err := fs.Set(flagName, fmt.Sprint(v)); err != nil {
+				return fmt.Errorf("%s: %s.%s: %v", file, path, flagName, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindCommand(t *testing.T) {
+	tests := []struct {
+		args     []string
+		wantPath string
+		wantRest int
+		wantOK   bool
+	}{
+		{[]string{"check", "a.twf"}, "check", 1, true},
+		{[]string{"export", "history", "Order", "a.twf"}, "export history", 2, true},
+		{[]string{"export"}, "export", 0, true},
+		{[]string{"export", "bogus"}, "export", 1, true},
+		{[]string{"bogus"}, "", 1, false},
+	}
+	for _, tt := range tests {
+		_, path, rest, ok := findCommand(tt.args)
+		if path != tt.wantPath || len(rest) != tt.wantRest || ok != tt.wantOK {
+			t.Errorf("findCommand(%q) = %q, %q, %v; want %q, %d args, %v", tt.args, path, rest, ok, tt.wantPath, tt.wantRest, tt.wantOK)
+		}
+	}
+}
+
+func TestApplyConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig := func(content string) string {
+		path := filepath.Join(dir, "twf.json")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	checkFlags := func(args ...string) *flag.FlagSet {
+		fs := flag.NewFlagSet("check", flag.ContinueOnError)
+		checkCommand(fs)
+		if err := fs.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		return fs
+	}
+
+	config := writeConfig(`{
+		"check": {"critical-tag": "config", "require-owner": true},
+		"symbols": {"depth": 2},
+		"export history": {"task-queue": "orders"}
+	}`)
+	fs := checkFlags("--critical-tag", "flag")
+	if err := applyConfig(fs, "check", config); err != nil {
+		t.Fatal(err)
+	}
+	if got := fs.Lookup("critical-tag").Value.String(); got != "flag" {
+		t.Errorf("critical-tag = %q, want the command line's flag", got)
+	}
+	if got := fs.Lookup("require-owner").Value.String(); got != "true" {
+		t.Errorf("require-owner = %q, want true from the config", got)
+	}
+
+	for content, want := range map[string]string{
+		`{"chek": {}}`:                     `unknown command "chek"`,
+		`{"export": {}}`:                   `unknown command "export"`,
+		`{"check": {"json": true}}`:        "twf check has no --json option",
+		`{"check": {"lenient": {"a": 1}}}`: "check.lenient must be a string, number, boolean, or list of them",
+		`{"check": {"lenient": "maybe"}}`:  "check.lenient: parse error",
+		`[]`:                               "cannot unmarshal array",
+	} {
+		err := applyConfig(checkFlags(), "check", writeConfig(content))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("config %s: error %v, want it to mention %q", content, err, want)
+		}
+	}
+}
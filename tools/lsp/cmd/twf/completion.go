@@ -0,0 +1,278 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// shells are the shells twf completion writes scripts for.
+var shells = []string{"bash", "zsh", "fish"}
+
+// completionCommand prints a script completing twf's commands, their flags,
+// and .twf files in bash, zsh, or fish. The script is generated from the
+// command table, so it stays in step with the flags each command registers.
+func completionCommand(fs *flag.FlagSet) func() int {
+	return func() int {
+		if fs.NArg() != 1 || !slices.Contains(shells, fs.Arg(0)) {
+			fmt.Fprintln(os.Stderr, "usage: twf completion bash|zsh|fish")
+			return exitUsage
+		}
+		writeCompletion(os.Stdout, fs.Arg(0), commands)
+		return 0
+	}
+}
+
+// writeCompletion writes the completion script for shell to w.
+func writeCompletion(w io.Writer, shell string, cmds []command) {
+	switch shell {
+	case "bash":
+		writeBashCompletion(w, cmds)
+	case "zsh":
+		writeZshCompletion(w, cmds)
+	case "fish":
+		writeFishCompletion(w, cmds)
+	}
+}
+
+// completionFlag is a flag as completion offers it.
+type completionFlag struct {
+	name  string
+	usage string
+	value bool // takes a value, completed as a file name
+}
+
+// commandFlags returns the flags cmd registers and the global options,
+// sorted by name.
+func commandFlags(cmd command) []completionFlag {
+	if cmd.setup == nil {
+		return nil
+	}
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	cmd.setup(fs)
+	new(globalOptions).register(fs, fs.Lookup("json") == nil)
+	var flags []completionFlag
+	fs.VisitAll(func(f *flag.Flag) {
+		_, usage := flag.UnquoteUsage(f)
+		b, ok := f.Value.(interface{ IsBoolFlag() bool })
+		flags = append(flags, completionFlag{name: f.Name, usage: usage, value: !ok || !b.IsBoolFlag()})
+	})
+	return flags
+}
+
+func commandNames(cmds []command) string {
+	names := make([]string, len(cmds))
+	for i, c := range cmds {
+		names[i] = c.name
+	}
+	return strings.Join(names, " ")
+}
+
+func writeBashCompletion(w io.Writer, cmds []command) {
+	fmt.Fprintf(w, `# bash completion for twf. Load it with:
+#   source <(twf completion bash)
+
+_twf() {
+	local cur=${COMP_WORDS[COMP_CWORD]} prev=${COMP_WORDS[COMP_CWORD-1]}
+	local cmd=${COMP_WORDS[1]}
+	if [[ $COMP_CWORD -eq 1 ]]; then
+		COMPREPLY=($(compgen -W "%s" -- "$cur"))
+		return
+	fi
+	case $cmd in
+`, commandNames(cmds))
+	for _, c := range cmds {
+		if len(c.subcommands) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, `	%s)
+		if [[ $COMP_CWORD -eq 2 ]]; then
+			COMPREPLY=($(compgen -W "%s" -- "$cur"))
+			return
+		fi
+		cmd="$cmd ${COMP_WORDS[2]}"
+		;;
+`, c.name, commandNames(c.subcommands))
+	}
+	fmt.Fprint(w, `	esac
+	local flags= values= words= files=
+	case $cmd in
+`)
+	var leaf func(path string, c command)
+	leaf = func(path string, c command) {
+		if len(c.subcommands) > 0 {
+			for _, s := range c.subcommands {
+				leaf(path+" "+s.name, s)
+			}
+			return
+		}
+		var flags, values []string
+		for _, f := range commandFlags(c) {
+			flags = append(flags, "--"+f.name)
+			if f.value {
+				values = append(values, "--"+f.name)
+			}
+		}
+		fmt.Fprintf(w, "\t%q)\n", strings.TrimSpace(path))
+		if len(flags) > 0 {
+			fmt.Fprintf(w, "\t\tflags=%q\n", strings.Join(flags, " "))
+		}
+		if len(values) > 0 {
+			fmt.Fprintf(w, "\t\tvalues=%q\n", strings.Join(values, " "))
+		}
+		if len(c.words) > 0 {
+			fmt.Fprintf(w, "\t\twords=%q\n", strings.Join(c.words, " "))
+		}
+		if c.files {
+			fmt.Fprint(w, "\t\tfiles=1\n")
+		}
+		fmt.Fprint(w, "\t\t;;\n")
+	}
+	for _, c := range cmds {
+		leaf(c.name, c)
+	}
+	fmt.Fprint(w, `	esac
+	if [[ -n $prev && " $values " == *" $prev "* ]]; then
+		COMPREPLY=($(compgen -f -- "$cur"))
+	elif [[ $cur == -* ]]; then
+		COMPREPLY=($(compgen -W "$flags" -- "$cur"))
+	elif [[ -n $files ]]; then
+		COMPREPLY=($(compgen -d -- "$cur") $(compgen -f -X '!*.twf' -- "$cur"))
+	else
+		COMPREPLY=($(compgen -W "$words" -- "$cur"))
+	fi
+}
+
+complete -o filenames -F _twf twf
+`)
+}
+
+func writeZshCompletion(w io.Writer, cmds []command) {
+	fmt.Fprint(w, `#compdef twf
+# zsh completion for twf. Load it with:
+#   source <(twf completion zsh)
+# or install it as _twf in a directory on $fpath.
+
+_twf() {
+`)
+	writeZshCommands(w, cmds, "twf", 1)
+	fmt.Fprint(w, `}
+
+if [[ $funcstack[1] == _twf ]]; then
+	_twf "$@"
+else
+	compdef _twf twf
+fi
+`)
+}
+
+// writeZshCommands writes the completion of cmds, the commands of parent,
+// at the given indentation depth.
+func writeZshCommands(w io.Writer, cmds []command, parent string, depth int) {
+	in := strings.Repeat("\t", depth)
+	fmt.Fprintf(w, "%sif (( CURRENT == 2 )); then\n", in)
+	fmt.Fprintf(w, "%s\tlocal -a commands=(\n", in)
+	for _, c := range cmds {
+		fmt.Fprintf(w, "%s\t\t%s\n", in, zshQuote(c.name+":"+c.summary))
+	}
+	fmt.Fprintf(w, "%s\t)\n", in)
+	fmt.Fprintf(w, "%s\t_describe -t commands %s commands\n", in, zshQuote(parent+" command"))
+	fmt.Fprintf(w, "%s\treturn\n", in)
+	fmt.Fprintf(w, "%sfi\n", in)
+	fmt.Fprintf(w, "%sshift words\n", in)
+	fmt.Fprintf(w, "%s(( CURRENT-- ))\n", in)
+	fmt.Fprintf(w, "%scase $words[1] in\n", in)
+	for _, c := range cmds {
+		fmt.Fprintf(w, "%s%s)\n", in, c.name)
+		if len(c.subcommands) > 0 {
+			writeZshCommands(w, c.subcommands, parent+" "+c.name, depth+1)
+			fmt.Fprintf(w, "%s\t;;\n", in)
+			continue
+		}
+		var specs []string
+		for _, f := range commandFlags(c) {
+			usage := strings.NewReplacer(`[`, `\[`, `]`, `\]`, `:`, `\:`).Replace(f.usage)
+			if f.value {
+				specs = append(specs, zshQuote("--"+f.name+"=["+usage+"]:value:_files"))
+			} else {
+				specs = append(specs, zshQuote("--"+f.name+"["+usage+"]"))
+			}
+		}
+		switch {
+		case c.files:
+			specs = append(specs, zshQuote(`*:TWF file:_files -g "*.twf"`))
+		case len(c.words) > 0:
+			specs = append(specs, zshQuote("1:"+c.name+":("+strings.Join(c.words, " ")+")"))
+		}
+		if len(specs) == 0 {
+			fmt.Fprintf(w, "%s\t_message 'no arguments'\n", in)
+		} else {
+			fmt.Fprintf(w, "%s\t_arguments \\\n%s\t\t%s\n", in, in, strings.Join(specs, " \\\n"+in+"\t\t"))
+		}
+		fmt.Fprintf(w, "%s\t;;\n", in)
+	}
+	fmt.Fprintf(w, "%sesac\n", in)
+}
+
+// zshQuote quotes s in single quotes for zsh.
+func zshQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func writeFishCompletion(w io.Writer, cmds []command) {
+	fmt.Fprint(w, `# fish completion for twf. Load it with:
+#   twf completion fish | source
+
+complete -c twf -f
+`)
+	writeFishCommands(w, cmds, nil)
+}
+
+// writeFishCommands writes the completion of cmds, the subcommands of the
+// commands in parents, or the top-level commands when parents is empty.
+func writeFishCommands(w io.Writer, cmds []command, parents []string) {
+	seen := "__fish_use_subcommand"
+	if len(parents) > 0 {
+		var conds []string
+		for _, p := range parents {
+			conds = append(conds, "__fish_seen_subcommand_from "+p)
+		}
+		seen = strings.Join(conds, "; and ") + "; and not __fish_seen_subcommand_from " + commandNames(cmds)
+	}
+	for _, c := range cmds {
+		fmt.Fprintf(w, "complete -c twf -n %s -a %s -d %s\n", fishQuote(seen), c.name, fishQuote(c.summary))
+	}
+	for _, c := range cmds {
+		path := append(slices.Clip(parents), c.name)
+		if len(c.subcommands) > 0 {
+			writeFishCommands(w, c.subcommands, path)
+			continue
+		}
+		var conds []string
+		for _, p := range path {
+			conds = append(conds, "__fish_seen_subcommand_from "+p)
+		}
+		cond := fishQuote(strings.Join(conds, "; and "))
+		for _, f := range commandFlags(c) {
+			if f.value {
+				fmt.Fprintf(w, "complete -c twf -n %s -l %s -r -F -d %s\n", cond, f.name, fishQuote(f.usage))
+			} else {
+				fmt.Fprintf(w, "complete -c twf -n %s -l %s -d %s\n", cond, f.name, fishQuote(f.usage))
+			}
+		}
+		switch {
+		case c.files:
+			fmt.Fprintf(w, "complete -c twf -n %s -a '(__fish_complete_suffix .twf)'\n", cond)
+		case len(c.words) > 0:
+			fmt.Fprintf(w, "complete -c twf -n %s -a %s\n", cond, fishQuote(strings.Join(c.words, " ")))
+		}
+	}
+}
+
+// fishQuote quotes s in single quotes for fish.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompletionScripts(t *testing.T) {
+	for _, shell := range shells {
+		var out bytes.Buffer
+		writeCompletion(&out, shell, commands)
+		script := out.String()
+		for _, want := range []string{"check", "serve-api", "history", "critical-tag", "task-queue", ".twf"} {
+			if !strings.Contains(script, want) {
+				t.Errorf("%s script does not mention %q", shell, want)
+			}
+		}
+	}
+}
+
+func TestBashCompletion(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not installed")
+	}
+	dir := t.TempDir()
+	for _, name := range []string{"order.twf", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "designs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	var script bytes.Buffer
+	writeCompletion(&script, "bash", commands)
+	scriptPath := filepath.Join(t.TempDir(), "twf.bash")
+	if err := os.WriteFile(scriptPath, script.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		line string // words typed so far; the last is being completed
+		want string
+	}{
+		{"twf ch", "check"},
+		{"twf se", "serve-api"},
+		{"twf check --crit", "--critical-tag"},
+		{"twf check ", "designs order.twf"},
+		{"twf export ", "history"},
+		{"twf export history --task", "--task-queue"},
+		{"twf generate --out ", "designs notes.txt order.twf"},
+		{"twf completion ", "bash fish zsh"},
+		{"twf lsp ", ""},
+	}
+	for _, tt := range tests {
+		words := strings.Fields(tt.line)
+		if strings.HasSuffix(tt.line, " ") {
+			words = append(words, "")
+		}
+		quoted := make([]string, len(words))
+		for i, w := range words {
+			quoted[i] = "'" + w + "'"
+		}
+		cmd := exec.Command(bash, "--norc", "-c", `source "$1"; COMP_WORDS=(`+strings.Join(quoted, " ")+`); COMP_CWORD=$((${#COMP_WORDS[@]} - 1)); _twf; printf '%s\n' "${COMPREPLY[@]}" | sort`, "bash", scriptPath)
+		cmd.Dir = dir
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("%q: %v", tt.line, err)
+		}
+		if got := strings.Join(strings.Fields(string(out)), " "); got != tt.want {
+			t.Errorf("%q completes to %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
@@ -4,47 +4,50 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"maps"
 	"os"
+	"slices"
 
 	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/deps"
 )
 
 // depsCommand extracts and outputs the dependency graph.
-func depsCommand(args []string) int {
-	fs := flag.NewFlagSet("deps", flag.ContinueOnError)
+func depsCommand(fs *flag.FlagSet) func() int {
 	jsonOutput := fs.Bool("json", false, "Output in JSON format")
 	lenient := fs.Bool("lenient", false, "Continue even with resolve errors")
-	if err := fs.Parse(args); err != nil {
-		return 1
-	}
-
-	paths := fs.Args()
-	if len(paths) == 0 {
-		fmt.Fprintln(os.Stderr, "usage: twf deps [--json] [--lenient] <file...>")
-		return 1
-	}
+	return func() int {
+		paths := fs.Args()
+		if len(paths) == 0 {
+			fmt.Fprintln(os.Stderr, "usage: twf deps [--json] [--lenient] <file...>")
+			return exitUsage
+		}
 
-	file, errs, exitCode := parseFiles(paths, *lenient)
+		file, errs, exitCode := parseFiles(paths, *lenient)
 
-	printErrors(errs)
+		printErrors(errs)
 
-	if file == nil {
-		return exitCode
-	}
+		if file == nil {
+			return exitCode
+		}
 
-	graph := deps.Extract(file)
+		graph := deps.Extract(file)
 
-	if *jsonOutput {
-		return printDepsJSON(graph)
+		printGraph := printDepsText
+		if *jsonOutput {
+			printGraph = printDepsJSON
+		}
+		if code := printGraph(graph); code != 0 {
+			return code
+		}
+		return exitCode
 	}
-	return printDepsText(graph)
 }
 
 func printDepsJSON(graph *deps.Graph) int {
 	data, err := json.MarshalIndent(graph, "", "  ")
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "json marshal error: %v\n", err)
-		return 1
+		return exitInternal
 	}
 	fmt.Println(string(data))
 	return 0
@@ -59,9 +62,9 @@ func printDepsText(graph *deps.Graph) int {
 	// Containment.
 	if len(graph.Containment) > 0 {
 		fmt.Println("Containment:")
-		for parent, children := range graph.Containment {
+		for _, parent := range slices.Sorted(maps.Keys(graph.Containment)) {
 			fmt.Printf("  %s:\n", parent)
-			for _, child := range children {
+			for _, child := range graph.Containment[parent] {
 				fmt.Printf("    %s\n", child)
 			}
 		}
@@ -75,10 +78,23 @@ func printDepsText(graph *deps.Graph) int {
 		for _, e := range graph.Edges {
 			grouped[e.From] = append(grouped[e.From], e)
 		}
-		for from, edges := range grouped {
+		for _, from := range slices.Sorted(maps.Keys(grouped)) {
 			fmt.Printf("  %s:\n", from)
-			for _, e := range edges {
-				fmt.Printf("    -> %s (%s, line %d)\n", e.To, e.Kind, e.Line)
+			for _, e := range grouped[from] {
+				detail := fmt.Sprintf("%s, line %d", e.Kind, e.Line)
+				if e.WorkflowID != "" {
+					detail += fmt.Sprintf(", id %q", e.WorkflowID)
+				}
+				if e.Condition != "" {
+					detail += ", if " + e.Condition
+				}
+				if e.FanOut != nil {
+					detail += fmt.Sprintf(", for each %s in %s, max %s", e.FanOut.Variable, e.FanOut.Iterable, e.FanOut.Max)
+				}
+				if e.Join != nil {
+					detail += ", in " + e.Join.String()
+				}
+				fmt.Printf("    -> %s (%s)\n", e.To, detail)
 			}
 		}
 		fmt.Println()
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/examples"
+)
+
+// deployment is a design whose diagnostics come from passes over symbol
+// tables: an endpoint shared by two namespaces, definitions no worker
+// covers, workers of one queue disagreeing, and calls routed to several
+// queues.
+const deployment = `workflow Alpha(x: int) -> (int):
+    activity Step(x)
+    workflow Beta(x)
+    close complete(x)
+
+workflow Beta(x: int) -> (int):
+    activity Step(x)
+    close complete(x)
+
+workflow Gamma():
+    timer(5m)
+
+activity Step(x: int):
+    return
+
+activity Unused():
+    return
+
+worker w1:
+    workflow Alpha
+
+worker w2:
+    workflow Beta
+
+worker w3:
+    activity Step
+
+worker idle:
+    workflow Gamma
+
+namespace east:
+    worker w1
+        options:
+            task_queue: "q1"
+    worker w2
+        options:
+            task_queue: "q1"
+    nexus endpoint Shared
+        options:
+            task_queue: "q1"
+
+namespace west:
+    worker w3
+        options:
+            task_queue: "q2"
+    worker w2
+        options:
+            task_queue: "q3"
+    nexus endpoint Shared
+        options:
+            task_queue: "q2"
+`
+
+// captureRun runs twf with args and returns what it printed to stdout and
+// stderr, and its exit code.
+func captureRun(t *testing.T, args ...string) (string, int) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = w, w
+	done := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- data
+	}()
+	code := run(args)
+	os.Stdout, os.Stderr = stdout, stderr
+	w.Close()
+	out := <-done
+	r.Close()
+	return string(out), code
+}
+
+// TestDeterministicOutput runs each command several times over the same
+// files and requires the same bytes every time, since map iteration order
+// changes from run to run.
+func TestDeterministicOutput(t *testing.T) {
+	dir := t.TempDir()
+	var files []string
+	write := func(name, content string) {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, path)
+	}
+	for _, ex := range examples.All() {
+		write(ex.Name+".twf", ex.Source)
+	}
+	write("deployment.twf", deployment)
+
+	commands := [][]string{
+		{"check", "--lenient"},
+		{"symbols", "--json", "--lenient"},
+		{"deps", "--lenient"},
+		{"deps", "--json", "--lenient"},
+		{"graph", "--lenient"},
+		{"report", "topology", "--json", "--lenient"},
+		{"generate", "--lenient", "--workers", "--with-types", "--source-map"},
+		{"generate", "--lenient", "--lang", "python", "--workers", "--with-types"},
+	}
+	for _, cmd := range commands {
+		args := append(cmd[:len(cmd):len(cmd)], files...)
+		first, code := captureRun(t, args...)
+		if code != 0 {
+			t.Errorf("twf %v exited %d:\n%s", cmd, code, first)
+			continue
+		}
+		for range 5 {
+			if again, _ := captureRun(t, args...); again != first {
+				t.Errorf("twf %v printed different output on another run:\n%s\nthen\n%s", cmd, first, again)
+				break
+			}
+		}
+	}
+
+	// The same for files written to disk, with the maps of each run read
+	// back by the next.
+	out := filepath.Join(dir, "out")
+	args := append([]string{"generate", "--lenient", "--with-types", "--source-map", "--out", out}, files...)
+	if _, code := captureRun(t, args...); code != 0 {
+		t.Fatalf("generate --out exited %d", code)
+	}
+	snapshot := func() map[string][]byte {
+		got := make(map[string][]byte)
+		entries, err := os.ReadDir(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range entries {
+			data, err := os.ReadFile(filepath.Join(out, e.Name()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got[e.Name()] = data
+		}
+		return got
+	}
+	first := snapshot()
+	for range 3 {
+		if _, code := captureRun(t, args...); code != 0 {
+			t.Fatalf("generate --out exited %d", code)
+		}
+		for name, data := range snapshot() {
+			if !bytes.Equal(data, first[name]) {
+				t.Errorf("regenerating changed %s:\n%s\nthen\n%s", name, first[name], data)
+			}
+		}
+	}
+}
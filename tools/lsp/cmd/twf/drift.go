@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/drift"
+)
+
+// driftCommand compares designs with the Go code implementing them and
+// exits 1 when they differ, so CI can gate on it.
+func driftCommand(flags *flag.FlagSet) func() int {
+	design := flags.String("design", "", "TWF file or directory of .twf files")
+	codeDir := flags.String("code", "", "Directory of Go worker code")
+	jsonOut := flags.Bool("json", false, "Output findings as JSON")
+	lenient := flags.Bool("lenient", false, "Continue even with resolve errors")
+	return func() int {
+		paths := flags.Args()
+		if *design != "" {
+			found, err := twfFiles(*design)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				return exitUsage
+			}
+			paths = append(found, paths...)
+		}
+		if len(paths) == 0 || *codeDir == "" {
+			fmt.Fprintln(os.Stderr, "usage: twf drift --design PATH --code DIR [--json] [--lenient] [file...]")
+			return exitUsage
+		}
+
+		file, errs, exitCode := parseFiles(paths, *lenient)
+		printErrors(errs)
+		if file == nil || exitCode != 0 {
+			return exitCode
+		}
+
+		code, err := drift.ScanGo(*codeDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return exitUsage
+		}
+		findings := drift.Compare(file, code)
+		for i := range findings {
+			findings[i].File = outputPath(findings[i].File)
+		}
+
+		if *jsonOut {
+			if findings == nil {
+				findings = []drift.Finding{}
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(findings); err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				return exitInternal
+			}
+		} else {
+			for _, f := range findings {
+				fmt.Println(f)
+			}
+		}
+
+		if len(findings) > 0 {
+			fmt.Fprintf(os.Stderr, "%d drift finding(s)\n", len(findings))
+			return exitDiagnostics
+		}
+		if !*jsonOut {
+			fmt.Printf("✓ No drift: %d workflow(s), %d activity(s) implemented\n", len(code.Workflows), len(code.Activities))
+		}
+		return 0
+	}
+}
+
+// twfFiles returns path if it is a file, or the .twf files under it, sorted.
+// With --hermetic a directory is an error, since what it holds is not
+// listed on the command line.
+func twfFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	if global.hermetic {
+		return nil, fmt.Errorf("%s is a directory; with --hermetic, list its .twf files", path)
+	}
+	var files []string
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && strings.HasSuffix(p, ".twf") {
+			files = append(files, p)
+		}
+		return err
+	})
+	if err == nil && len(files) == 0 {
+		err = fmt.Errorf("no .twf files in %s", path)
+	}
+	return files, err
+}
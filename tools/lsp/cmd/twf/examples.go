@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/examples"
+)
+
+// examplesListCommand lists the embedded example designs.
+func examplesListCommand(fs *flag.FlagSet) func() int {
+	jsonOutput := fs.Bool("json", false, "Output the names and summaries as JSON")
+	return func() int {
+		if fs.NArg() != 0 {
+			fmt.Fprintln(os.Stderr, "usage: twf examples list [--json]")
+			return exitUsage
+		}
+		all := examples.All()
+		if *jsonOutput {
+			type entry struct {
+				Name    string `json:"name"`
+				Summary string `json:"summary"`
+			}
+			entries := make([]entry, len(all))
+			for i, ex := range all {
+				entries[i] = entry{ex.Name, ex.Summary}
+			}
+			data, err := json.MarshalIndent(entries, "", "  ")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "json marshal error: %v\n", err)
+				return exitInternal
+			}
+			fmt.Println(string(data))
+			return 0
+		}
+		for _, ex := range all {
+			fmt.Printf("%-20s %s\n", ex.Name, ex.Summary)
+		}
+		return 0
+	}
+}
+
+// examplesShowCommand prints the source of an example design.
+func examplesShowCommand(fs *flag.FlagSet) func() int {
+	return func() int {
+		if fs.NArg() != 1 {
+			fmt.Fprintln(os.Stderr, "usage: twf examples show <name>")
+			return exitUsage
+		}
+		ex, ok := lookupExample(fs.Arg(0))
+		if !ok {
+			return exitUsage
+		}
+		fmt.Print(ex.Source)
+		return 0
+	}
+}
+
+// examplesInitCommand writes an example design to a file, to start a
+// design from.
+func examplesInitCommand(fs *flag.FlagSet) func() int {
+	dir := fs.String("dir", ".", "Write the example into `dir`, creating it if needed")
+	force := fs.Bool("force", false, "Overwrite the file if it exists")
+	return func() int {
+		if fs.NArg() != 1 {
+			fmt.Fprintln(os.Stderr, "usage: twf examples init [--dir DIR] [--force] <name>")
+			return exitUsage
+		}
+		ex, ok := lookupExample(fs.Arg(0))
+		if !ok {
+			return exitUsage
+		}
+		if err := os.MkdirAll(*dir, 0o755); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return exitUsage
+		}
+		path := filepath.Join(*dir, ex.Name+".twf")
+		flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+		if *force {
+			flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		}
+		f, err := os.OpenFile(path, flags, 0o644)
+		if errors.Is(err, os.ErrExist) {
+			fmt.Fprintf(os.Stderr, "error: %s exists; pass --force to overwrite it\n", path)
+			return exitUsage
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return exitUsage
+		}
+		if _, err := f.WriteString(ex.Source); err != nil {
+			f.Close()
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return exitInternal
+		}
+		if err := f.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return exitInternal
+		}
+		fmt.Println(path)
+		return 0
+	}
+}
+
+// lookupExample returns the example named name, reporting the names there
+// are when there is none.
+func lookupExample(name string) (examples.Example, bool) {
+	ex, ok := examples.Lookup(name)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "error: no example named %q; want one of %s\n", name, strings.Join(examples.Names(), ", "))
+	}
+	return ex, ok
+}
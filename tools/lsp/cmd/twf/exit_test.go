@@ -0,0 +1,416 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/internal/cache"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/internal/server"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/codegen"
+)
+
+// silence discards what commands print for the rest of the test.
+func silence(t *testing.T) {
+	t.Helper()
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = devNull, devNull
+	t.Cleanup(func() {
+		os.Stdout, os.Stderr = stdout, stderr
+		devNull.Close()
+	})
+}
+
+func TestExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	ok := write("ok.twf", "workflow Order(id: string):\n    activity Charge(id)\n\nactivity Charge(id: string):\n    return\n")
+	bad := write("bad.twf", "workflow Order():\n    activity Missing()\n")
+	shadow := write("shadow.twf", "activity Charge(id: string):\n    return\n")
+	legacy := write("legacy.twf", "workflow Refund():\n    return\n")
+	mixed := write("mixed.twf", "workflow Cancel():\n    if (late):\n        close fail\n    return\n")
+	missing := filepath.Join(dir, "missing.twf")
+	badConfig := write("bad.json", `{"check": {"no-such-flag": true}}`)
+	ownerConfig := write("owner.json", `{"check": {"require-owner": true}}`)
+	out := filepath.Join(dir, "out")
+	gallery := filepath.Join(dir, "examples")
+	silence(t)
+
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{nil, exitUsage},
+		{[]string{"bogus"}, exitUsage},
+		{[]string{"--bogus", "check", ok}, exitUsage},
+		{[]string{"help"}, 0},
+		{[]string{"help", "bogus"}, exitUsage},
+		{[]string{"--help"}, 0},
+		{[]string{"check", "--help"}, 0},
+
+		{[]string{"check"}, exitUsage},
+		{[]string{"check", "--bogus", ok}, exitUsage},
+		{[]string{"check", ok}, 0},
+		{[]string{"check", bad}, exitDiagnostics},
+		{[]string{"check", "--lenient", bad}, 0},
+		{[]string{"check", missing}, exitUsage},
+		{[]string{"check", "--config", badConfig, ok}, exitUsage},
+		{[]string{"--config", ownerConfig, "check", ok}, exitDiagnostics},
+		{[]string{"--hermetic", "--config", ownerConfig, "check", ok}, exitDiagnostics},
+		{[]string{"check", "--hermetic", "--cache-dir", filepath.Join(dir, "cache"), ok}, exitUsage},
+		{[]string{"--json", "check", ok}, exitUsage},
+		{[]string{"check", ok, shadow}, exitDiagnostics},
+		{[]string{"check", "--allow-duplicates-across-files", ok, shadow}, 0},
+		{[]string{"check", "--allow-duplicates-across-files", ok, ok}, exitDiagnostics},
+		{[]string{"check", "--dialect", "v3", ok}, exitUsage},
+		{[]string{"check", "--dialect", "v2", "--return-in-workflow", "error", ok}, exitUsage},
+		{[]string{"check", "--dialect", "v2", legacy}, exitDiagnostics},
+		{[]string{"check", "--dialect", "legacy", legacy}, 0},
+		{[]string{"check", "--dialect", "auto", ok, legacy}, 0},
+		{[]string{"check", "--dialect", "auto", mixed}, exitDiagnostics},
+		{[]string{"check", "--dialect", "auto", "--lenient", mixed}, 0},
+
+		{[]string{"parse"}, exitUsage},
+		{[]string{"parse", bad}, 0},
+		{[]string{"parse", "--only", "Nope", ok}, exitUsage},
+		{[]string{"parse", missing}, exitUsage},
+
+		{[]string{"symbols"}, exitUsage},
+		{[]string{"symbols", ok}, 0},
+		{[]string{"--json", "symbols", ok}, 0},
+		{[]string{"symbols", bad}, exitDiagnostics},
+		{[]string{"symbols", "--tree", bad}, exitDiagnostics},
+
+		{[]string{"deps"}, exitUsage},
+		{[]string{"deps", ok}, 0},
+		{[]string{"deps", bad}, exitDiagnostics},
+
+		{[]string{"graph"}, exitUsage},
+		{[]string{"graph", ok}, 0},
+		{[]string{"graph", bad}, exitDiagnostics},
+		{[]string{"graph", "--root", "Nope", ok}, exitUsage},
+		{[]string{"graph", "--root", "Order", "--callers", ok}, 0},
+		{[]string{"explain"}, exitUsage},
+		{[]string{"explain", "await", "one"}, 0},
+		{[]string{"explain", "--list"}, 0},
+		{[]string{"explain", "goto"}, exitUsage},
+		{[]string{"examples"}, exitUsage},
+		{[]string{"examples", "list"}, 0},
+		{[]string{"examples", "show", "saga"}, 0},
+		{[]string{"examples", "show", "nope"}, exitUsage},
+		{[]string{"examples", "init", "--dir", gallery, "saga"}, 0},
+		{[]string{"examples", "init", "--dir", gallery, "saga"}, exitUsage},
+		{[]string{"examples", "init", "--dir", gallery, "--force", "saga"}, 0},
+		{[]string{"check", filepath.Join(gallery, "saga.twf")}, 0},
+		{[]string{"profile"}, exitUsage},
+		{[]string{"profile", "--iterations", "0", ok}, exitUsage},
+		{[]string{"profile", "--iterations", "2", bad}, 0},
+		{[]string{"profile", "--iterations", "1", "--cpuprofile", filepath.Join(dir, "cpu.out"), "--memprofile", filepath.Join(dir, "mem.out"), ok}, 0},
+		{[]string{"profile", missing}, exitUsage},
+		{[]string{"graph", "--annotate", missing, ok}, exitUsage},
+		{[]string{"graph", "--annotate", badConfig, ok}, exitUsage},
+
+		{[]string{"export"}, exitUsage},
+		{[]string{"export", "bogus"}, exitUsage},
+		{[]string{"export", "history", "Order"}, exitUsage},
+		{[]string{"export", "history", "Order", ok}, 0},
+		{[]string{"export", "history", "Order", bad}, exitDiagnostics},
+		{[]string{"export", "history", "Nope", ok}, exitUsage},
+
+		{[]string{"report"}, exitUsage},
+		{[]string{"report", "topology"}, exitUsage},
+		{[]string{"report", "topology", ok}, 0},
+		{[]string{"--json", "report", "topology", ok}, 0},
+		{[]string{"report", "topology", "--json", "--markdown", ok}, exitUsage},
+		{[]string{"report", "topology", bad}, exitDiagnostics},
+		{[]string{"report", "topology", "--lenient", bad}, 0},
+		{[]string{"report", "topology", missing}, exitUsage},
+		{[]string{"--hermetic", "report", "topology", dir}, exitUsage},
+
+		{[]string{"generate"}, exitUsage},
+		{[]string{"generate", "--check", ok}, exitUsage},
+		{[]string{"generate", "--lang", "cobol", ok}, exitUsage},
+		{[]string{"generate", bad}, exitDiagnostics},
+		{[]string{"generate", "--out", out, "--check", ok}, exitDiagnostics},
+		{[]string{"generate", "--out", out, ok}, 0},
+		{[]string{"generate", "--out", out, "--check", ok}, 0},
+		{[]string{"--hermetic", "generate", "--out", out, "--check", ok}, exitUsage},
+
+		{[]string{"drift"}, exitUsage},
+		{[]string{"drift", "--design", missing, "--code", dir}, exitUsage},
+
+		{[]string{"fix", ok}, exitUsage},
+		{[]string{"fix", "--apply", "bogus", ok}, exitUsage},
+		{[]string{"fix", "--apply", "return-to-close", missing}, exitUsage},
+		{[]string{"fix", "--apply", "return-to-close", "--check", ok}, 0},
+		{[]string{"fix", "--apply", "return-to-close", "--check", bad}, 0},
+		{[]string{"fix", "--apply", "keyword-case", "--check", ok}, exitUsage},
+		{[]string{"fix", "--apply", "keyword-case", "--case-insensitive-keywords", "--check", ok}, 0},
+
+		{[]string{"fmt"}, exitUsage},
+		{[]string{"fmt", ok}, 0},
+		{[]string{"fmt", "--check", ok}, 0},
+		{[]string{"fmt", "--check", bad}, 0},
+		{[]string{"fmt", missing}, exitUsage},
+		{[]string{"fmt", "--diff", "--check", ok}, exitUsage},
+		{[]string{"--hermetic", "fmt", dir}, exitUsage},
+
+		{[]string{"batch", ok}, exitUsage},
+
+		{[]string{"highlight"}, exitUsage},
+		{[]string{"highlight", ok}, 0},
+		{[]string{"highlight", missing}, exitUsage},
+
+		{[]string{"grammar"}, exitUsage},
+		{[]string{"grammar", "--textmate"}, 0},
+		{[]string{"--json", "grammar", "--textmate"}, exitUsage},
+
+		{[]string{"serve-api", "extra"}, exitUsage},
+		{[]string{"lsp", "--log-level", "loud"}, exitUsage},
+
+		{[]string{"completion"}, exitUsage},
+		{[]string{"completion", "bash"}, 0},
+	}
+	for _, tt := range tests {
+		if got := run(tt.args); got != tt.want {
+			t.Errorf("twf %q exited %d, want %d", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestExitCodeEnvironment(t *testing.T) {
+	dir := t.TempDir()
+	ok := filepath.Join(dir, "ok.twf")
+	config := filepath.Join(dir, "twf.json")
+	if err := os.WriteFile(ok, []byte("workflow Order():\n    timer(5m)\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(config, []byte(`{"check": {"require-owner": true}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	silence(t)
+
+	t.Setenv(envConfig, config)
+	if got := run([]string{"check", ok}); got != exitDiagnostics {
+		t.Errorf("check with %s exited %d, want %d for the missing @owner", envConfig, got, exitDiagnostics)
+	}
+	if got := run([]string{"--hermetic", "check", ok}); got != 0 {
+		t.Errorf("check --hermetic with %s exited %d, want 0 ignoring it", envConfig, got)
+	}
+	if got := run([]string{"check", "--config", filepath.Join(dir, "missing.json"), ok}); got != exitUsage {
+		t.Errorf("check with a missing --config exited %d, want %d", got, exitUsage)
+	}
+
+	t.Setenv(envNoColor, "1")
+	if colorEnabled() {
+		t.Errorf("colorEnabled() with %s set = true", envNoColor)
+	}
+}
+
+func TestGenerateHermetic(t *testing.T) {
+	dir := t.TempDir()
+	ok := filepath.Join(dir, "ok.twf")
+	out := filepath.Join(dir, "out")
+	idents := filepath.Join(out, codegen.IdentifierMapFile)
+	if err := os.WriteFile(ok, []byte("workflow Order():\n    timer(5m)\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(out, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(idents, []byte(`{"workflow:Order": "not-an-identifier"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	silence(t)
+
+	if got := run([]string{"generate", "--out", out, ok}); got != exitUsage {
+		t.Errorf("generate reading the invalid map in --out exited %d, want %d", got, exitUsage)
+	}
+	if got := run([]string{"--hermetic", "generate", "--identifier-map", idents, "--out", out, ok}); got != exitUsage {
+		t.Errorf("generate --identifier-map with the invalid map exited %d, want %d", got, exitUsage)
+	}
+	if got := run([]string{"--hermetic", "generate", "--out", out, ok}); got != exitUsage {
+		t.Errorf("hermetic generate over the map in --out exited %d, want %d", got, exitUsage)
+	}
+	if data, _ := os.ReadFile(idents); string(data) != `{"workflow:Order": "not-an-identifier"}` {
+		t.Errorf("hermetic generate overwrote %s:\n%s", codegen.IdentifierMapFile, data)
+	}
+
+	var runs [2][]byte
+	for i := range runs {
+		fresh := filepath.Join(dir, fmt.Sprintf("fresh%d", i))
+		if got := run([]string{"--hermetic", "generate", "--out", fresh, ok}); got != 0 {
+			t.Fatalf("hermetic generate into an empty --out exited %d, want 0", got)
+		}
+		data, err := os.ReadFile(filepath.Join(fresh, codegen.IdentifierMapFile))
+		if err != nil {
+			t.Fatal(err)
+		}
+		runs[i] = data
+	}
+	if string(runs[0]) != string(runs[1]) {
+		t.Errorf("hermetic runs wrote different %s:\n%s\nthen\n%s", codegen.IdentifierMapFile, runs[0], runs[1])
+	}
+}
+
+// TestGenerateHermeticKeepsCustomRegions checks that --hermetic, which
+// does not merge, refuses to write over generated files holding code
+// written by hand.
+func TestGenerateHermeticKeepsCustomRegions(t *testing.T) {
+	dir := t.TempDir()
+	design := filepath.Join(dir, "order.twf")
+	out := filepath.Join(dir, "out")
+	if err := os.WriteFile(design, []byte("workflow Order():\n    activity Charge()\n\nactivity Charge():\n    return\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	silence(t)
+
+	if got := run([]string{"generate", "--out", out, design}); got != 0 {
+		t.Fatalf("generate exited %d, want 0", got)
+	}
+	activities := filepath.Join(out, "activities.go")
+	data, err := os.ReadFile(activities)
+	if err != nil {
+		t.Fatal(err)
+	}
+	custom := strings.Replace(string(data), "// TODO: implement", "// charged by hand", 1)
+	if custom == string(data) {
+		t.Fatalf("no TODO in the custom region of activities.go:\n%s", data)
+	}
+	if err := os.WriteFile(activities, []byte(custom), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := run([]string{"--hermetic", "generate", "--out", out, design}); got != exitUsage {
+		t.Errorf("hermetic generate over existing files exited %d, want %d", got, exitUsage)
+	}
+	if got, _ := os.ReadFile(activities); string(got) != custom {
+		t.Errorf("hermetic generate overwrote the custom region of activities.go:\n%s", got)
+	}
+}
+
+func TestExitCodePanic(t *testing.T) {
+	saved := commands
+	defer func() { commands = saved }()
+	commands = append(commands[:len(commands):len(commands)], command{
+		name: "panic",
+		setup: func(*flag.FlagSet) func() int {
+			return func() int { panic("boom") }
+		},
+	})
+	silence(t)
+
+	if got := run([]string{"panic"}); got != exitInternal {
+		t.Errorf("a panicking command exited %d, want %d", got, exitInternal)
+	}
+}
+
+func TestCheckCache(t *testing.T) {
+	dir := t.TempDir()
+	cacheDir := filepath.Join(dir, "cache")
+	file := filepath.Join(dir, "order.twf")
+	if err := os.WriteFile(file, []byte("workflow Order():\n    timer(5m)\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	silence(t)
+
+	if got := run([]string{"check", "--cache-dir", cacheDir, file}); got != 0 {
+		t.Fatalf("first run exited %d, want 0", got)
+	}
+	entries, _ := filepath.Glob(filepath.Join(cacheDir, "*", "*.json"))
+	if len(entries) != 1 {
+		t.Fatalf("cache holds %d entries after one run, want 1", len(entries))
+	}
+
+	// Tamper with the entry to tell a cached result from a fresh one.
+	if err := os.WriteFile(entries[0], []byte(`{"errors": ["cached"], "exitCode": 1}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := run([]string{"check", "--cache-dir", cacheDir, file}); got != exitDiagnostics {
+		t.Errorf("unchanged run exited %d, want the cached %d", got, exitDiagnostics)
+	}
+	if got := run([]string{"check", "--cache-dir", cacheDir, "--require-owner", file}); got != exitDiagnostics {
+		t.Errorf("run with --require-owner exited %d, want %d for the missing @owner", got, exitDiagnostics)
+	}
+
+	if err := os.WriteFile(file, []byte("@owner(\"payments\")\nworkflow Order():\n    timer(5m)\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := run([]string{"check", "--cache-dir", cacheDir, file}); got != 0 {
+		t.Errorf("run after an edit exited %d, want 0 from a fresh analysis", got)
+	}
+}
+
+// TestCacheSharedWithLSP runs twf check over and over while language
+// servers, as in editors open on the same workspace, analyze documents
+// with the same cache directory.
+func TestCacheSharedWithLSP(t *testing.T) {
+	dir := t.TempDir()
+	cacheDir := filepath.Join(dir, "cache")
+	file := filepath.Join(dir, "order.twf")
+	content := func(i int) string {
+		return fmt.Sprintf("# revision %d\nworkflow Order():\n    close complete\n    timer(5m)\n", i%4)
+	}
+	silence(t)
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c, err := cache.Open(cacheDir)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			store := server.NewDocumentStore()
+			store.Cache, store.CacheBuild = c, buildID()
+			for i := range 20 {
+				doc, ok := store.Update("file://"+filepath.ToSlash(file), int32(i+1), content(i)).Wait()
+				if !ok || len(doc.ValidateErrs) != 1 || !strings.HasPrefix(doc.ValidateErrs[0].Msg, "unreachable") {
+					t.Errorf("server analysis %d: expected the unreachable timer, got %v", i, doc)
+				}
+			}
+		}()
+	}
+	for i := range 20 {
+		if err := os.WriteFile(file, []byte(content(i)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if got := run([]string{"check", "--cache-dir", cacheDir, file}); got != exitDiagnostics {
+			t.Errorf("check run %d exited %d, want %d for the unreachable timer", i, got, exitDiagnostics)
+		}
+	}
+	wg.Wait()
+
+	// Four contents each for twf check and the servers.
+	entries, _ := filepath.Glob(filepath.Join(cacheDir, "*", "*.json"))
+	if len(entries) != 8 {
+		t.Errorf("cache holds %d entries, want 8", len(entries))
+	}
+	for _, entry := range entries {
+		if data, err := os.ReadFile(entry); err != nil || !json.Valid(data) {
+			t.Errorf("entry %s is not valid JSON: %v", entry, err)
+		}
+	}
+	for _, pattern := range []string{"*.lock", "*.tmp"} {
+		if left, _ := filepath.Glob(filepath.Join(cacheDir, "*", pattern)); len(left) > 0 {
+			t.Errorf("left behind %v", left)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/explain"
+)
+
+// explainCommand prints the built-in explanation and example of a
+// construct, or with --list the constructs it explains.
+func explainCommand(fs *flag.FlagSet) func() int {
+	jsonOutput := fs.Bool("json", false, "Output the explanation as JSON")
+	list := fs.Bool("list", false, "List the constructs twf explain knows")
+	return func() int {
+		if *list {
+			for _, kw := range explain.Keywords() {
+				fmt.Println(kw)
+			}
+			return 0
+		}
+		if fs.NArg() == 0 {
+			fmt.Fprintln(os.Stderr, "usage: twf explain [--json] <keyword...> | --list")
+			return exitUsage
+		}
+		query := strings.Join(fs.Args(), " ")
+		e, ok := explain.Lookup(query)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "error: nothing explains %q; twf explain --list shows what does\n", query)
+			return exitUsage
+		}
+		if *jsonOutput {
+			data, err := json.MarshalIndent(e, "", "  ")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "json marshal error: %v\n", err)
+				return exitInternal
+			}
+			fmt.Println(string(data))
+			return 0
+		}
+		title := e.Keyword
+		if e.Removed {
+			title += " (removed)"
+		}
+		fmt.Printf("%s\n\n%s\n\nExample:\n\n    %s\n", title, e.Summary, strings.ReplaceAll(e.Example, "\n", "\n    "))
+		return 0
+	}
+}
+
+// explainWords are the operands shell completion offers twf explain: the
+// constructs named by one word.
+func explainWords() []string {
+	var words []string
+	for _, kw := range explain.Keywords() {
+		if !strings.Contains(kw, " ") {
+			words = append(words, kw)
+		}
+	}
+	return words
+}
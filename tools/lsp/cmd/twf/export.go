@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/history"
+)
+
+// exportHistoryCommand prints a synthetic event history for one workflow,
+// following its design in order, for seeding SDK replay tests.
+func exportHistoryCommand(fs *flag.FlagSet) func() int {
+	taskQueue := fs.String("task-queue", "", "Task queue of the workflow (default: from its namespace deployment)")
+	lenient := fs.Bool("lenient", false, "Continue even with resolve errors")
+	return func() int {
+		if fs.NArg() < 2 {
+			fmt.Fprintln(os.Stderr, "usage: twf export history [--task-queue NAME] [--lenient] <workflow> <file...>")
+			return exitUsage
+		}
+		workflow, paths := fs.Arg(0), fs.Args()[1:]
+
+		file, errs, exitCode := parseFiles(paths, *lenient)
+
+		printErrors(errs)
+
+		if file == nil || exitCode != 0 {
+			return exitCode
+		}
+
+		h, err := history.Skeleton(file, workflow, *taskQueue)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return exitUsage
+		}
+		data, err := json.MarshalIndent(h, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "json marshal error: %v\n", err)
+			return exitInternal
+		}
+		fmt.Println(string(data))
+		return 0
+	}
+}
@@ -1,85 +1,206 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"time"
 
 	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/lexer"
 	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/parser"
 	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/resolver"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/telemetry"
 	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/validator"
 )
 
+// source is a named TWF input.
+type source struct {
+	Name string
+	Text string
+}
+
+// diagnostic is an error or warning from parsing, resolving, or validating.
+type diagnostic struct {
+	File     string `json:"file,omitempty"` // set for parse, duplicate definition, and dialect errors only
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Stage    string `json:"stage"` // "parse", "resolve", or "validation"
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+
+	crossFile bool // a duplicate definition in another file; see ResolveError.CrossFile
+}
+
+// String formats d the way the CLI prints it.
+func (d diagnostic) String() string {
+	msg := fmt.Sprintf("%s error at %d:%d: %s", d.Stage, d.Line, d.Column, d.Message)
+	if d.File != "" {
+		msg = d.File + ": " + msg
+	}
+	return msg
+}
+
 // parseFiles reads and parses the given files, returning the AST and any errors.
 // Each file is parsed independently with per-file line numbers. Definitions are
 // stamped with their source file and merged into a single AST for resolution.
+// The exit code is exitUsage when a file cannot be read and exitDiagnostics
+// when there are errors, unless lenient.
 func parseFiles(paths []string, lenient bool) (*ast.File, []string, int) {
-	if len(paths) == 0 {
-		return nil, nil, 1
+	sources, exitCode := readSources(paths)
+	if exitCode != 0 {
+		return nil, nil, exitCode
 	}
+	return parseSources(sources, lexer.Options{}, lenient, false)
+}
 
-	merged := &ast.File{}
-	var allErrs []string
-
-	// Parse each file independently
+// readSources reads the given files as sources named by their base names.
+// The exit code is exitUsage when there are none or one cannot be read.
+func readSources(paths []string) ([]source, int) {
+	if len(paths) == 0 {
+		return nil, exitUsage
+	}
+	sources := make([]source, 0, len(paths))
 	for _, path := range paths {
 		data, err := os.ReadFile(path)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "error reading %s: %v\n", path, err)
-			return nil, nil, 1
+			return nil, exitUsage
 		}
+		sources = append(sources, source{Name: filepath.Base(path), Text: string(data)})
+	}
+	return sources, 0
+}
 
-		file, parseErrs := parser.ParseFileAll(string(data))
+// outputPath returns path as output names it. With --hermetic an absolute
+// path is made relative to the working directory, or reduced to its base
+// name outside it, so output does not embed where a build sandbox lives.
+func outputPath(path string) string {
+	if !global.hermetic || !filepath.IsAbs(path) {
+		return path
+	}
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, path); err == nil && filepath.IsLocal(rel) {
+			return rel
+		}
+	}
+	return filepath.Base(path)
+}
 
-		// Collect parse errors with filename prefix
+// parseSources is parseFiles for sources already read, lexed with lex. With
+// allowCrossFileDuplicates, a definition repeating one from another source
+// is not reported: it shadows the earlier one.
+func parseSources(sources []source, lex lexer.Options, lenient, allowCrossFileDuplicates bool) (*ast.File, []string, int) {
+	merged, diags := analyze(sources, lex)
+	if allowCrossFileDuplicates {
+		diags = slices.DeleteFunc(diags, func(d diagnostic) bool { return d.crossFile })
+	}
+	allErrs := make([]string, len(diags))
+	for i, d := range diags {
+		allErrs[i] = d.String()
+	}
+
+	// Determine exit code
+	exitCode := 0
+	if len(allErrs) > 0 && !lenient {
+		exitCode = exitDiagnostics
+	}
+
+	return merged, allErrs, exitCode
+}
+
+// analyze parses each source independently with lex, stamps and merges the
+// definitions, then resolves and validates across all of them.
+func analyze(sources []source, lex lexer.Options) (*ast.File, []diagnostic) {
+	file, diags, _ := analyzeContext(context.Background(), sources, lex, telemetry.Nop{})
+	return file, diags
+}
+
+// analyzeContext is analyze with cancellation between resolving definitions,
+// reporting the duration of each stage and the diagnostics to hooks. It
+// returns ctx.Err() once ctx is done.
+func analyzeContext(ctx context.Context, sources []source, lex lexer.Options, hooks telemetry.Hooks) (*ast.File, []diagnostic, error) {
+	merged := &ast.File{}
+	diags := []diagnostic{}
+	names := make([]string, len(sources))
+
+	for i, src := range sources {
+		names[i] = src.Name
+		start := time.Now()
+		file, parseErrs := parser.WithLexer(lex).ParseFileAll(src.Text)
+		hooks.Parsed(src.Name, time.Since(start))
 		for _, e := range parseErrs {
-			allErrs = append(allErrs, fmt.Sprintf("%s: %s", filepath.Base(path), e.Error()))
+			diags = append(diags, diagnostic{
+				File:     src.Name,
+				Line:     e.Line,
+				Column:   e.Column,
+				Stage:    "parse",
+				Severity: "error",
+				Message:  e.Msg,
+			})
 		}
 
 		// Stamp source file and merge definitions
-		base := filepath.Base(path)
 		for _, def := range file.Definitions {
-			setSourceFile(def, base)
+			ast.SetSourceFile(def, src.Name)
 			merged.Definitions = append(merged.Definitions, def)
 		}
 	}
 
 	// Resolve across all files
-	resolveErrs := resolver.Resolve(merged)
-	for _, e := range resolveErrs {
-		allErrs = append(allErrs, e.Error())
+	start := time.Now()
+	resolved, err := resolver.ResolveContext(ctx, merged)
+	if err != nil {
+		return nil, nil, err
+	}
+	hooks.Resolved(names, time.Since(start))
+	for _, e := range resolved.Errors {
+		diags = append(diags, diagnostic{
+			File:      e.File,
+			Line:      e.Line,
+			Column:    e.Column,
+			Stage:     "resolve",
+			Severity:  severity(e.Severity),
+			Message:   e.Msg,
+			crossFile: e.CrossFile(),
+		})
 	}
 
 	// Validate deployment/routing
-	validateErrs := validator.Validate(merged)
+	start = time.Now()
+	validateErrs := validator.ValidateSymbols(resolved.Symbols)
+	hooks.Validated(names, time.Since(start))
 	for _, e := range validateErrs {
-		allErrs = append(allErrs, e.Error())
+		diags = append(diags, diagnostic{
+			Line:     e.Line,
+			Column:   e.Column,
+			Stage:    "validation",
+			Severity: severity(e.Severity),
+			Message:  e.Msg,
+		})
 	}
 
-	// Determine exit code
-	exitCode := 0
-	if len(allErrs) > 0 && !lenient {
-		exitCode = 1
+	errs, warnings := 0, 0
+	for _, d := range diags {
+		if d.Severity == "error" {
+			errs++
+		} else {
+			warnings++
+		}
 	}
+	hooks.Diagnosed(names, errs, warnings)
 
-	return merged, allErrs, exitCode
+	return merged, diags, nil
 }
 
-// setSourceFile stamps a definition with its source file name.
-func setSourceFile(def ast.Definition, sourceFile string) {
-	switch d := def.(type) {
-	case *ast.WorkflowDef:
-		d.SourceFile = sourceFile
-	case *ast.ActivityDef:
-		d.SourceFile = sourceFile
-	case *ast.WorkerDef:
-		d.SourceFile = sourceFile
-	case *ast.NamespaceDef:
-		d.SourceFile = sourceFile
-	case *ast.NexusServiceDef:
-		d.SourceFile = sourceFile
+// severity defaults an empty severity to "error".
+func severity(s string) string {
+	if s == "" {
+		return "error"
 	}
+	return s
 }
 
 // printErrors writes error messages to stderr.
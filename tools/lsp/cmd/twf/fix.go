@@ -0,0 +1,165 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/fix"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/lexer"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/parser"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/resolver"
+)
+
+// fixCommand rewrites TWF files with the fixes named by --apply, in order.
+// Directories are searched for .twf files, so a whole tree can be fixed at
+// once, and references resolve across all the files given. Files that do
+// not parse are reported and left alone. With --check nothing is written:
+// the files a rewrite would change are listed. With --dry-run the changes
+// are printed as unified diffs instead. Either way a summary of the edits
+// each fix made goes to stderr.
+func fixCommand(fs *flag.FlagSet) func() int {
+	var apply []string
+	fs.Func("apply", "Apply the fix `name`, one of "+strings.Join(fix.Names(), ", ")+"; may be repeated", func(name string) error {
+		if fix.Fixes[name] == nil {
+			return fmt.Errorf("unknown fix; want one of %s", strings.Join(fix.Names(), ", "))
+		}
+		if !slices.Contains(apply, name) {
+			apply = append(apply, name)
+		}
+		return nil
+	})
+	var lex lexer.Options
+	keywordCaseFlag(fs, &lex)
+	check := fs.Bool("check", false, "Report the files the fixes would change instead of rewriting them")
+	dryRun := fs.Bool("dry-run", false, "Print the changes as unified diffs instead of rewriting the files")
+	return func() int {
+		paths := fs.Args()
+		if len(apply) == 0 || len(paths) == 0 {
+			fmt.Fprintln(os.Stderr, "usage: twf fix --apply NAME [--check] [--dry-run] [--case-insensitive-keywords] <file|dir...>")
+			return exitUsage
+		}
+		if slices.Contains(apply, "keyword-case") && !lex.CaseInsensitiveKeywords {
+			fmt.Fprintln(os.Stderr, "error: --apply keyword-case needs --case-insensitive-keywords, without which the words it would lowercase are names")
+			return exitUsage
+		}
+		var files []*fixFile
+		for _, path := range paths {
+			found, err := twfFiles(path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				return exitUsage
+			}
+			for _, path := range found {
+				data, err := os.ReadFile(path)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "error: %v\n", err)
+					return exitUsage
+				}
+				files = append(files, &fixFile{path: path, orig: string(data), src: string(data)})
+			}
+		}
+
+		exitCode := 0
+		summary := applyFixes(files, apply, lex)
+		changed := 0
+		for _, f := range files {
+			if f.err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v; not fixed\n", f.path, f.err)
+				exitCode = exitDiagnostics
+				continue
+			}
+			if f.edits == 0 {
+				continue
+			}
+			changed++
+			switch {
+			case *dryRun:
+				fmt.Print(unifiedDiff(f.path, f.orig, f.src))
+				continue
+			case *check:
+				fmt.Printf("%s: %d change(s)\n", f.path, f.edits)
+				continue
+			}
+			info, err := os.Stat(f.path)
+			if err == nil {
+				err = os.WriteFile(f.path, []byte(f.src), info.Mode().Perm())
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				return exitInternal
+			}
+			fmt.Printf("fixed %s: %d change(s)\n", f.path, f.edits)
+		}
+		for _, s := range summary {
+			fmt.Fprintf(os.Stderr, "%s: %d edit(s) in %d file(s)\n", s.name, s.edits, s.files)
+		}
+		if *check && changed > 0 {
+			fmt.Fprintf(os.Stderr, "%d file(s) need fixes; rerun twf fix without --check\n", changed)
+			return exitDiagnostics
+		}
+		return exitCode
+	}
+}
+
+// fixFile is a file being fixed: its source as read and as fixed so far,
+// the edits made to it, and the parse error that stopped its fixing.
+type fixFile struct {
+	path      string
+	orig, src string
+	edits     int
+	err       error
+}
+
+// fixSummary counts the edits one fix made and the files it changed.
+type fixSummary struct {
+	name         string
+	edits, files int
+}
+
+// applyFixes applies the named fixes to files in order. Before each fix
+// the files are parsed afresh with lex, so every fix sees the edits of those before
+// it, and resolved together, so calls find definitions in other files. A
+// file that stops parsing keeps its error and is not fixed further.
+func applyFixes(files []*fixFile, names []string, lex lexer.Options) []fixSummary {
+	summary := make([]fixSummary, len(names))
+	for i, name := range names {
+		summary[i].name = name
+		merged := &ast.File{}
+		parsed := make([]*ast.File, len(files))
+		for j, f := range files {
+			if f.err != nil {
+				continue
+			}
+			file, err := parser.WithLexer(lex).ParseFile(f.src)
+			if err != nil {
+				f.err = err
+				continue
+			}
+			for _, def := range file.Definitions {
+				ast.SetSourceFile(def, f.path)
+			}
+			merged.Definitions = append(merged.Definitions, file.Definitions...)
+			parsed[j] = file
+		}
+		resolver.Resolve(merged)
+
+		for j, f := range files {
+			if parsed[j] == nil {
+				continue
+			}
+			edits := fix.Fixes[name](parsed[j], f.src)
+			if len(edits) == 0 {
+				continue
+			}
+			f.src = fix.Apply(f.src, edits)
+			f.edits += len(edits)
+			summary[i].edits += len(edits)
+			summary[i].files++
+		}
+	}
+	return summary
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFixReturnToClose(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	order := write("order.twf", "workflow Order() -> (Result):\n    return result\n")
+	refund := write("nested/refund.twf", "workflow Refund():\n    return\n")
+	current := "workflow Ship():\n    close complete\n"
+	ship := write("nested/ship.twf", current)
+	broken := write("broken/broken.twf", "workflow Broken(:\n    return\n")
+	silence(t)
+
+	args := func(extra ...string) []string {
+		return append([]string{"fix", "--apply", "return-to-close"}, extra...)
+	}
+	if got := run(args("--check", order, filepath.Join(dir, "nested"))); got != exitDiagnostics {
+		t.Errorf("--check exited %d, want %d", got, exitDiagnostics)
+	}
+	if data, _ := os.ReadFile(order); string(data) != "workflow Order() -> (Result):\n    return result\n" {
+		t.Errorf("--check rewrote %s:\n%s", order, data)
+	}
+
+	if got := run(args(dir)); got != exitDiagnostics {
+		t.Errorf("fixing a tree with an unparsable file exited %d, want %d", got, exitDiagnostics)
+	}
+	for path, want := range map[string]string{
+		order:  "workflow Order() -> (Result):\n    close complete(result)\n",
+		refund: "workflow Refund():\n    close complete\n",
+		ship:   current,
+		broken: "workflow Broken(:\n    return\n",
+	} {
+		if data, _ := os.ReadFile(path); string(data) != want {
+			t.Errorf("%s:\n%s\nwant:\n%s", path, data, want)
+		}
+	}
+
+	if got := run(args("--check", filepath.Join(dir, "nested"))); got != 0 {
+		t.Errorf("--check after fixing exited %d, want 0", got)
+	}
+}
+
+func TestFixDryRun(t *testing.T) {
+	dir := t.TempDir()
+	fulfill := filepath.Join(dir, "fulfill.twf")
+	order := filepath.Join(dir, "order.twf")
+	orderSrc := "workflow Order(order: Order) -> (Result):\n    workflow Fulfill(order)\n    return result\n"
+	for path, content := range map[string]string{
+		fulfill: "@sla(24h)\nworkflow Fulfill(order: Order):\n    close complete\n",
+		order:   orderSrc,
+	} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	silence(t)
+	out, err := os.Create(filepath.Join(t.TempDir(), "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = out
+
+	if got := run([]string{"fix", "--apply", "missing-timeouts", "--apply", "return-to-close", "--dry-run", dir}); got != 0 {
+		t.Errorf("--dry-run exited %d, want 0", got)
+	}
+	want := "--- " + order + "\n+++ " + order + "\n" + `@@ -1,3 +1,5 @@
+ workflow Order(order: Order) -> (Result):
+     workflow Fulfill(order)
+-    return result
++        options:
++            workflow_execution_timeout: 24h
++    close complete(result)
+`
+	if data, _ := os.ReadFile(out.Name()); string(data) != want {
+		t.Errorf("diff:\n%s\nwant:\n%s", data, want)
+	}
+	if data, _ := os.ReadFile(order); string(data) != orderSrc {
+		t.Errorf("--dry-run rewrote %s:\n%s", order, data)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	var old, new []string
+	for i := 1; i <= 12; i++ {
+		old = append(old, fmt.Sprintf("line %d", i))
+	}
+	new = append(new, old...)
+	new[0] = "first"
+	new = slices.Insert(new, 11, "inserted")
+	got := unifiedDiff("f.twf", strings.Join(old, "\n")+"\n", strings.Join(new, "\n")+"\n")
+	want := `--- f.twf
++++ f.twf
+@@ -1,4 +1,4 @@
+-line 1
++first
+ line 2
+ line 3
+ line 4
+@@ -9,4 +9,5 @@
+ line 9
+ line 10
+ line 11
++inserted
+ line 12
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if got := unifiedDiff("f.twf", "same\n", "same\n"); got != "" {
+		t.Errorf("diff of equal sources = %q, want none", got)
+	}
+}
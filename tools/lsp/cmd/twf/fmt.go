@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/format"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/lexer"
+)
+
+// fmtCommand prints TWF files in the canonical layout. Directories are
+// searched for .twf files, and "-" reads stdin, so an editor can pipe a
+// buffer through it on save. Files that do not parse, or whose layout
+// would change their meaning, are reported and left alone. With --write
+// the files that change are rewritten in place; with --diff the changes
+// are printed as unified diffs with a/ and b/ prefixes, for patch -p1; with --check the files that would change
+// are listed and the exit code is 1.
+func fmtCommand(fs *flag.FlagSet) func() int {
+	var lex lexer.Options
+	aliasesFlag(fs, &lex)
+	keywordCaseFlag(fs, &lex)
+	write := fs.Bool("write", false, "Rewrite the files in place instead of printing them")
+	diff := fs.Bool("diff", false, "Print the changes as unified diffs instead of the formatted files")
+	check := fs.Bool("check", false, "List the files that are not formatted instead of printing them")
+	return func() int {
+		paths := fs.Args()
+		modes := 0
+		for _, on := range []bool{*write, *diff, *check} {
+			if on {
+				modes++
+			}
+		}
+		if len(paths) == 0 || modes > 1 {
+			fmt.Fprintln(os.Stderr, "usage: twf fmt [--write|--diff|--check] [--aliases FILE] [--case-insensitive-keywords] <file|dir...|->")
+			return exitUsage
+		}
+
+		var files []string
+		for _, path := range paths {
+			if path == "-" {
+				if *write {
+					fmt.Fprintln(os.Stderr, "error: --write needs files, not stdin")
+					return exitUsage
+				}
+				files = append(files, path)
+				continue
+			}
+			found, err := twfFiles(path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				return exitUsage
+			}
+			files = append(files, found...)
+		}
+
+		exitCode, changed := 0, 0
+		for _, path := range files {
+			src, err := readSource(path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				return exitUsage
+			}
+			out, err := format.SourceWith(src, lex)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v; not formatted\n", outputPath(path), err)
+				exitCode = exitDiagnostics
+				continue
+			}
+			if !*write && !*diff && !*check {
+				fmt.Print(out)
+				continue
+			}
+			if out == src {
+				continue
+			}
+			changed++
+			switch {
+			case *diff:
+				fmt.Print(unifiedDiffNamed("a/"+outputPath(path), "b/"+outputPath(path), src, out))
+				continue
+			case *check:
+				fmt.Println(outputPath(path))
+				continue
+			}
+			info, err := os.Stat(path)
+			if err == nil {
+				err = os.WriteFile(path, []byte(out), info.Mode().Perm())
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				return exitInternal
+			}
+		}
+		if *check && changed > 0 {
+			fmt.Fprintf(os.Stderr, "%d file(s) not formatted; run twf fmt --write\n", changed)
+			return exitDiagnostics
+		}
+		return exitCode
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFmt(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	messy := "workflow Order( id:string ):\n  activity Charge( id )\nactivity Charge(id: string):\n  return\n"
+	formatted := "workflow Order(id: string):\n    activity Charge(id)\n\nactivity Charge(id: string):\n    return\n"
+	order := write("order.twf", messy)
+	ship := write("nested/ship.twf", "workflow Ship():\n    close complete\n")
+	broken := write("broken/broken.twf", "workflow Broken(:\n    return\n")
+
+	out, code := captureRun(t, "fmt", order)
+	if code != 0 || out != formatted {
+		t.Errorf("fmt exited %d and printed:\n%s\nwant:\n%s", code, out, formatted)
+	}
+
+	out, code = captureRun(t, "fmt", "--check", order, filepath.Join(dir, "nested"))
+	if code != exitDiagnostics || out != order+"\n1 file(s) not formatted; run twf fmt --write\n" {
+		t.Errorf("--check exited %d and printed:\n%s", code, out)
+	}
+
+	out, code = captureRun(t, "fmt", "--diff", order)
+	want := "--- a/" + order + "\n+++ b/" + order + "\n" + `@@ -1,4 +1,5 @@
+-workflow Order( id:string ):
+-  activity Charge( id )
++workflow Order(id: string):
++    activity Charge(id)
++
+ activity Charge(id: string):
+-  return
++    return
+`
+	if code != 0 || out != want {
+		t.Errorf("--diff exited %d and printed:\n%s\nwant:\n%s", code, out, want)
+	}
+
+	if _, code := captureRun(t, "fmt", "--write", dir); code != exitDiagnostics {
+		t.Errorf("--write over a tree with an unparsable file exited %d, want %d", code, exitDiagnostics)
+	}
+	for path, want := range map[string]string{
+		order:  formatted,
+		ship:   "workflow Ship():\n    close complete\n",
+		broken: "workflow Broken(:\n    return\n",
+	} {
+		if data, _ := os.ReadFile(path); string(data) != want {
+			t.Errorf("%s:\n%s\nwant:\n%s", path, data, want)
+		}
+	}
+	if out, code := captureRun(t, "fmt", "--check", order, ship); code != 0 || out != "" {
+		t.Errorf("--check after --write exited %d and printed:\n%s", code, out)
+	}
+
+	for _, args := range [][]string{
+		{"fmt"},
+		{"fmt", "--write", "--check", order},
+		{"fmt", "--write", "-"},
+	} {
+		if _, code := captureRun(t, args...); code != exitUsage {
+			t.Errorf("twf %v exited %d, want %d", args, code, exitUsage)
+		}
+	}
+}
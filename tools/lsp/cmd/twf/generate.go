@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/codegen"
+)
+
+// generateCommand renders SDK stubs for the workflows and activities of a
+// design. Without --out each file is printed after a "==> path <==" line;
+// with --out the files are written under the directory. --workers adds a
+// worker bootstrap file for each task queue the design's namespaces deploy.
+// --with-types adds type stubs; the type map already in the --out directory
+// says which types are defined by hand and must not be stubbed. The
+// identifier map there keeps generated identifiers when new names collide
+// with them. Files that already exist keep their protected regions; --check
+// only reports the files a regeneration would change. --source-map adds a
+// source map linking the generated files, as written, to the design.
+// --type-map and --identifier-map name the maps to read in place of those
+// in --out; with --hermetic they are the only maps read, and nothing in
+// --out is merged, so a file already there is an error rather than
+// overwritten.
+func generateCommand(fs *flag.FlagSet) func() int {
+	var opts codegen.Options
+	fs.StringVar(&opts.Lang, "lang", "go", "Target language: "+strings.Join(codegen.Languages, ", "))
+	fs.StringVar(&opts.Package, "package", codegen.DefaultPackage, "Go package name")
+	fs.StringVar(&opts.Templates, "templates", "", "Directory of *.tmpl files replacing or adding to the built-in templates")
+	fs.BoolVar(&opts.Workers, "workers", false, "Also generate a worker bootstrap file per deployed task queue")
+	fs.BoolVar(&opts.Types, "with-types", false, "Also generate stubs for the types signatures use, and "+codegen.TypeMapFile)
+	optionDefaultsFlag(fs, &opts.OptionDefaults)
+	outDir := fs.String("out", "", "Write generated files into this directory")
+	check := fs.Bool("check", false, "Report files under --out that differ from a regeneration instead of writing them")
+	sourceMap := fs.Bool("source-map", false, "Also write "+codegen.SourceMapFile+", linking generated code to the design")
+	typeMapFile := fs.String("type-map", "", "Read the type map from `file` rather than from --out (with --with-types)")
+	identifierMapFile := fs.String("identifier-map", "", "Read the identifier map from `file` rather than from --out")
+	lenient := fs.Bool("lenient", false, "Continue even with resolve errors")
+	return func() int {
+		paths := fs.Args()
+		if *check && *outDir == "" {
+			fmt.Fprintln(os.Stderr, "error: --check requires --out")
+			return exitUsage
+		}
+		if *check && global.hermetic {
+			fmt.Fprintln(os.Stderr, "error: --check compares with the files in --out, which --hermetic does not read")
+			return exitUsage
+		}
+		if len(paths) == 0 {
+			fmt.Fprintln(os.Stderr, "usage: twf generate [--lang go|typescript|python] [--package NAME] [--templates DIR] [--workers] [--with-types] [--type-map FILE] [--identifier-map FILE] [--option-defaults FILE] [--source-map] [--out DIR [--check]] [--lenient] <file...>")
+			return exitUsage
+		}
+
+		file, errs, exitCode := parseFiles(paths, *lenient)
+
+		printErrors(errs)
+
+		if file == nil || exitCode != 0 {
+			return exitCode
+		}
+
+		// Without --hermetic, the maps a previous run left in --out stand
+		// in for those not given.
+		readOut := *outDir != "" && !global.hermetic
+		if opts.Types && (*typeMapFile != "" || readOut) {
+			var modules map[string]string
+			var err error
+			if *typeMapFile != "" {
+				modules, err = codegen.ReadTypeMapFile(*typeMapFile)
+			} else {
+				modules, err = codegen.ReadTypeMap(*outDir)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				return exitUsage
+			}
+			opts.TypeModules = modules
+		}
+		if *identifierMapFile != "" || readOut {
+			var idents map[string]string
+			var err error
+			if *identifierMapFile != "" {
+				idents, err = codegen.ReadIdentifierMapFile(*identifierMapFile)
+			} else {
+				idents, err = codegen.ReadIdentifierMap(*outDir)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				return exitUsage
+			}
+			opts.Identifiers = idents
+		}
+
+		outputs, err := codegen.Generate(file, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return exitUsage
+		}
+
+		if *outDir == "" {
+			if *sourceMap {
+				sm, err := codegen.BuildSourceMap(file, outputs)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "error: %v\n", err)
+					return exitInternal
+				}
+				outputs = append(outputs, sm)
+			}
+			for i, out := range outputs {
+				if i > 0 {
+					fmt.Println()
+				}
+				fmt.Printf("==> %s <==\n", out.Path)
+				os.Stdout.Write(out.Content)
+			}
+			return 0
+		}
+
+		// Merge every file first, so the source map matches what is written.
+		existing := make([][]byte, len(outputs))
+		if !global.hermetic {
+			for i, out := range outputs {
+				path := filepath.Join(*outDir, out.Path)
+				old, err := os.ReadFile(path)
+				if errors.Is(err, os.ErrNotExist) {
+					continue
+				}
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "error: %v\n", err)
+					return exitInternal
+				}
+				existing[i] = old
+				merged, dropped, err := codegen.Merge(out.Content, old)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "error: %s: %v\n", path, err)
+					return exitUsage
+				}
+				outputs[i].Content = merged
+				if !*check {
+					for _, name := range dropped {
+						fmt.Fprintf(os.Stderr, "warning: %s: dropping custom region %q, which the design no longer generates\n", path, name)
+					}
+				}
+			}
+		}
+		if *sourceMap {
+			sm, err := codegen.BuildSourceMap(file, outputs)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				return exitInternal
+			}
+			var old []byte
+			if !global.hermetic {
+				old, err = os.ReadFile(filepath.Join(*outDir, sm.Path))
+			}
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				return exitInternal
+			}
+			outputs = append(outputs, sm)
+			existing = append(existing, old)
+		}
+
+		// Without merging, writing over a file in --out would lose its
+		// custom regions.
+		if global.hermetic {
+			for _, out := range outputs {
+				path := filepath.Join(*outDir, out.Path)
+				if _, err := os.Lstat(path); err == nil {
+					fmt.Fprintf(os.Stderr, "error: %s exists, and --hermetic does not merge with files in --out; generate into an empty directory\n", path)
+					return exitUsage
+				}
+			}
+		}
+
+		stale := 0
+		for i, out := range outputs {
+			path := filepath.Join(*outDir, out.Path)
+			content := out.Content
+			missing := existing[i] == nil
+
+			if *check {
+				switch {
+				case missing:
+					fmt.Printf("%s: missing\n", path)
+					stale++
+				case !bytes.Equal(content, existing[i]):
+					fmt.Printf("%s: out of date\n", path)
+					stale++
+				}
+				continue
+			}
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				return exitInternal
+			}
+			if err := os.WriteFile(path, content, 0o644); err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				return exitInternal
+			}
+		}
+		if stale > 0 {
+			fmt.Fprintf(os.Stderr, "%d generated file(s) differ from the design; rerun twf generate without --check\n", stale)
+			return exitDiagnostics
+		}
+		return 0
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/grammar"
+)
+
+// grammarCommand generates editor grammars from the lexer's token table.
+// Without --out the primary grammar file is printed; with --out the full set
+// of files is written under the directory.
+func grammarCommand(fs *flag.FlagSet) func() int {
+	textMate := fs.Bool("textmate", false, "Generate a TextMate grammar (twf.tmLanguage.json)")
+	treeSitter := fs.Bool("tree-sitter", false, "Generate a tree-sitter grammar (grammar.js, queries/highlights.scm)")
+	outDir := fs.String("out", "", "Write grammar files into this directory")
+	return func() int {
+		if *textMate == *treeSitter || fs.NArg() > 0 {
+			fmt.Fprintln(os.Stderr, "usage: twf grammar --textmate|--tree-sitter [--out DIR]")
+			return exitUsage
+		}
+
+		files := make(map[string][]byte)
+		var primary string
+		if *textMate {
+			data, err := grammar.TextMate()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return exitInternal
+			}
+			primary = "twf.tmLanguage.json"
+			files[primary] = data
+		} else {
+			primary = "grammar.js"
+			files[primary] = []byte(grammar.TreeSitter())
+			files[filepath.Join("queries", "highlights.scm")] = []byte(grammar.TreeSitterHighlights())
+		}
+
+		if *outDir == "" {
+			os.Stdout.Write(files[primary])
+			return 0
+		}
+
+		for name, data := range files {
+			path := filepath.Join(*outDir, name)
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return exitInternal
+			}
+			if err := os.WriteFile(path, data, 0o644); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return exitInternal
+			}
+		}
+		return 0
+	}
+}
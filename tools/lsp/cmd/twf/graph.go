@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/deps"
+)
+
+// graphCommand renders the call graph as Mermaid or DOT, optionally cut down
+// to the subgraph reachable from one workflow, or reaching it, and overlaid
+// with metrics measured elsewhere.
+func graphCommand(fs *flag.FlagSet) func() int {
+	dotOutput := fs.Bool("dot", false, "Output Graphviz DOT")
+	jsonOutput := fs.Bool("json", false, "Output the filtered graph as JSON, like twf deps --json")
+	lenient := fs.Bool("lenient", false, "Continue even with resolve errors")
+	var opts deps.FilterOptions
+	fs.StringVar(&opts.Root, "root", "", "Keep only what this workflow reaches")
+	fs.IntVar(&opts.Depth, "depth", -1, "With --root, keep definitions at most N calls away (-1 for unlimited)")
+	fs.BoolVar(&opts.Callers, "callers", false, "With --root, keep what calls the workflow instead of what it calls")
+	fs.Func("exclude", "Drop definitions whose name matches this glob (repeatable)", func(s string) error {
+		opts.Exclude = append(opts.Exclude, s)
+		return nil
+	})
+	fs.Func("filter", "Keep workflows and activities with annotation `name=value` or `name` (repeatable; all must match)", func(s string) error {
+		opts.Annotations = append(opts.Annotations, s)
+		return nil
+	})
+	fs.BoolVar(&opts.CollapseActivities, "collapse-activities", false, "Draw one node per caller for the activities it calls")
+	annotate := fs.String("annotate", "", "Overlay the latency and failure rate in the JSON `file`, keyed by node name or ID, on the nodes")
+	return func() int {
+		paths := fs.Args()
+		if len(paths) == 0 || (*dotOutput && *jsonOutput) {
+			fmt.Fprintln(os.Stderr, "usage: twf graph [--dot|--json] [--root WORKFLOW [--callers]] [--depth N] [--exclude GLOB] [--filter NAME=VALUE] [--collapse-activities] [--annotate FILE] [--lenient] <file...>")
+			return exitUsage
+		}
+		var metrics map[string]deps.Metrics
+		if *annotate != "" {
+			data, err := os.ReadFile(*annotate)
+			if err == nil {
+				metrics, err = deps.ParseMetrics(data)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: %s: %v\n", *annotate, err)
+				return exitUsage
+			}
+		}
+
+		file, errs, exitCode := parseFiles(paths, *lenient)
+
+		printErrors(errs)
+
+		if file == nil {
+			return exitCode
+		}
+
+		full := deps.Extract(file)
+		// Annotated before filtering, so only keys naming no definition at
+		// all are reported.
+		for _, key := range full.Annotate(metrics) {
+			fmt.Fprintf(os.Stderr, "warning: %s: no node named %s\n", *annotate, key)
+		}
+		graph, err := full.Filter(opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return exitUsage
+		}
+
+		switch {
+		case *jsonOutput:
+			if printDepsJSON(graph) != 0 {
+				return exitInternal
+			}
+		case *dotOutput:
+			fmt.Print(graph.DOT())
+		default:
+			fmt.Print(graph.Mermaid())
+		}
+		return exitCode
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/highlight"
+)
+
+// highlightCommand prints TWF source with syntax coloring. Highlighting is
+// lexical, so files that do not parse are still colored. Unless --ansi asks
+// for colors, --no-color and NO_COLOR print the source as is.
+func highlightCommand(fs *flag.FlagSet) func() int {
+	htmlOutput := fs.Bool("html", false, "Output an HTML <pre> block")
+	ansiOutput := fs.Bool("ansi", false, "Output ANSI terminal colors (default)")
+	return func() int {
+		paths := fs.Args()
+		if len(paths) == 0 || (*htmlOutput && *ansiOutput) {
+			fmt.Fprintln(os.Stderr, "usage: twf highlight [--html|--ansi] <file...|->")
+			return exitUsage
+		}
+
+		render := highlight.ANSI
+		switch {
+		case *htmlOutput:
+			render = highlight.HTML
+		case !*ansiOutput && !colorEnabled():
+			render = func(src string) string { return src }
+		}
+
+		exitCode := 0
+		for _, path := range paths {
+			src, err := readSource(path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+				exitCode = exitUsage
+				continue
+			}
+			fmt.Print(render(src))
+		}
+		return exitCode
+	}
+}
+
+// readSource reads a file, or stdin when path is "-" so the command can
+// serve as a filter for fenced code blocks in documentation pipelines.
+func readSource(path string) (string, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	return string(data), err
+}
@@ -1,19 +1,129 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/internal/cache"
 	"github.com/jmbarzee/temporal-skills/tools/lsp/internal/server"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/lexer"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/parser"
 	"github.com/tliron/commonlog"
-	_ "github.com/tliron/commonlog/simple"
+	commonslog "github.com/tliron/commonlog/slog"
 	glspServer "github.com/tliron/glsp/server"
 )
 
 // lspCommand starts the LSP server over stdio.
-func lspCommand() {
-	commonlog.Configure(1, nil)
+func lspCommand(fs *flag.FlagSet) func() int {
+	policy := policyFlags(fs)
+	scope := fs.String("cross-root-resolution", server.ScopeRoot, "Resolve references across the files of the same workspace folder (root) or of every folder (workspace)")
+	logLevel := fs.String("log-level", "info", "Log messages at `level` and above: debug, info, warn, or error")
+	logFormat := fs.String("log-format", "text", "Log `format`: text or json")
+	aliases := fs.String("aliases", "", "Lex the keyword aliases in the JSON `file` as the keywords they stand for, reloading it when the client reports a change")
+	var lex lexer.Options
+	keywordCaseFlag(fs, &lex)
+	maxIndexMemory := fs.Int("max-index-memory", 256, "Bound the estimated memory of parsed workspace files to `MiB`, dropping the least recently used and parsing them again when needed; 0 for no limit")
+	debugBundle := fs.String("debug-bundle", "", "Write a debug bundle to attach to a bug report to `dir` whenever the server recovers from a panic")
+	bundleDocuments := fs.Bool("debug-bundle-documents", false, "Include the content of open documents in debug bundles")
+	cacheDir := fs.String("cache-dir", "", "Reuse the validation results of earlier analyses, by this server or other twf processes, stored in `dir`")
+	return func() int {
+		if err := configureLogging(os.Stderr, *logLevel, *logFormat); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
+		}
+		recorder := server.NewLogRecorder(slog.Default().Handler(), maxBundleRecords)
+		slog.SetDefault(slog.New(recorder))
+
+		handler, store := server.NewHandler(name, version)
+		store.Bundle.Dir = *debugBundle
+		store.Bundle.Documents = *bundleDocuments
+		store.Bundle.Version = version
+		store.Bundle.Log = recorder
+		store.Bundle.Flags = map[string]string{}
+		fs.Visit(func(f *flag.Flag) { store.Bundle.Flags[f.Name] = f.Value.String() })
+		if *debugBundle != "" {
+			if err := os.MkdirAll(*debugBundle, 0o755); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return exitUsage
+			}
+		}
+		store.Policy = *policy
+		if *cacheDir != "" {
+			c, err := cache.Open(*cacheDir)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return exitUsage
+			}
+			store.Cache, store.CacheBuild = c, buildID()
+		}
+		store.Workspace.MaxParsedBytes = *maxIndexMemory << 20
+		if *aliases != "" {
+			var err error
+			if lex.Aliases, err = parser.LoadAliases(*aliases); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return exitUsage
+			}
+			store.AliasesPath, _ = filepath.Abs(*aliases)
+		}
+		store.SetLexer(lex)
+		if err := store.Workspace.SetScope(*scope); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
+		}
 
-	handler, _ := server.NewHandler(name, version)
+		s := glspServer.NewServer(handler, name, false)
+
+		s.RunStdio()
+		// A client gone without shutdown leaves analyses running.
+		store.Shutdown()
+		return handler.ExitCode()
+	}
+}
 
-	s := glspServer.NewServer(handler, name, false)
+// maxBundleRecords is the number of recent log records debug bundles hold.
+const maxBundleRecords = 1000
+
+// configureLogging sends the server's logs, and those of the glsp library
+// through commonlog, to w as slog records at level and above, in format.
+func configureLogging(w io.Writer, level, format string) error {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("unknown log level %q (want debug, info, warn, or error)", level)
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+	var h slog.Handler
+	switch format {
+	case "text":
+		h = slog.NewTextHandler(w, opts)
+	case "json":
+		h = slog.NewJSONHandler(w, opts)
+	default:
+		return fmt.Errorf("unknown log format %q (want text or json)", format)
+	}
+	logger := slog.New(h)
+	slog.SetDefault(logger)
+
+	backend := commonslog.NewBackend()
+	backend.Logger = logger
+	backend.SetMaxLevel(commonlogLevel(lvl))
+	commonlog.SetBackend(backend)
+	return nil
+}
 
-	s.RunStdio()
+// commonlogLevel returns the commonlog level logging what lvl does.
+func commonlogLevel(lvl slog.Level) commonlog.Level {
+	switch {
+	case lvl <= slog.LevelDebug:
+		return commonlog.Debug
+	case lvl <= slog.LevelInfo:
+		return commonlog.Info
+	case lvl <= slog.LevelWarn:
+		return commonlog.Warning
+	default:
+		return commonlog.Error
+	}
 }
@@ -3,6 +3,9 @@ package main
 import (
 	"fmt"
 	"os"
+	"strings"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/examples"
 )
 
 const (
@@ -10,54 +13,101 @@ const (
 	version = "0.1.0"
 )
 
-const usage = `twf - Temporal Workflow Format CLI
+const usageHeader = `twf - Temporal Workflow Format CLI
 
 Usage:
-  twf <command> [options] <file...>
+  twf [global options] <command> [options] <args>
 
 Commands:
-  check     Parse and validate TWF files
-  parse     Output AST as JSON
-  symbols   List workflows and activities
-  deps      Show dependency graph
-  lsp       Start the language server (stdio)
-  help      Show this help
+`
+
+const usageFooter = `
+Run 'twf help <command>' for the options of a command.
 
-Options:
-  --lenient  Continue even with resolve errors
+Environment:
+  TWF_CONFIG    Config file when --config is not given
+  TWF_NO_COLOR  Disable colored output when set (as does NO_COLOR)
+
+Exit status:
+  0 success, 1 diagnostics found, 2 usage error, 3 internal error
 
 Examples:
   twf check workflow.twf
+  twf --config twf.json check workflow.twf
+  twf --json symbols workflow.twf
+  twf check --require-owner --critical-tag critical workflow.twf
   twf parse workflow.twf
   twf symbols workflow.twf
+  twf graph --root Order --depth 2 --exclude 'Notify*' workflow.twf
+  twf export history Order workflow.twf
+  twf generate --lang typescript --out src workflow.twf
+  twf generate --workers --out workers workflow.twf
+  twf generate --source-map --out src workflow.twf
+  twf drift --design designs/ --code ./internal/workflows
+  twf fix --apply return-to-close designs/
+  twf fix --apply missing-timeouts --dry-run designs/
+  twf profile --cpuprofile cpu.out --memprofile mem.out workflow.twf
+  twf highlight --html workflow.twf
+  twf grammar --tree-sitter --out tree-sitter-twf
+  twf serve-api --addr localhost:8421
+  twf explain await one
+  twf examples init saga
   twf lsp
+  twf lsp --cross-root-resolution workspace
+  twf lsp --log-level debug --log-format json
+  source <(twf completion bash)
 `
 
-func main() {
-	if len(os.Args) < 2 {
-		fmt.Fprint(os.Stderr, usage)
-		os.Exit(1)
+// commands are the twf subcommands, in the order usage lists them. They
+// are set in init since help and completion list them.
+var commands []command
+
+func init() {
+	commands = []command{
+		{name: "check", summary: "Parse and validate TWF files", args: "<file...>", setup: checkCommand, files: true},
+		{name: "parse", summary: "Output AST as JSON", args: "<file...>", setup: parseCommand, files: true},
+		{name: "symbols", summary: "List workflows and activities", args: "<file...>", setup: symbolsCommand, files: true},
+		{name: "deps", summary: "Show dependency graph", args: "<file...>", setup: depsCommand, files: true},
+		{name: "graph", summary: "Render the call graph as Mermaid or DOT", args: "<file...>", setup: graphCommand, files: true},
+		{name: "export", summary: "Export a workflow's event history skeleton (export history)", subcommands: []command{
+			{name: "history", summary: "Print a synthetic event history for one workflow", args: "<workflow> <file...>", setup: exportHistoryCommand, files: true},
+		}},
+		{name: "generate", summary: "Generate Go, TypeScript, or Python SDK stubs", args: "<file...>", setup: generateCommand, files: true},
+		{name: "drift", summary: "Compare designs with the Go workers implementing them", args: "[file...]", setup: driftCommand, files: true},
+		{name: "fix", summary: "Apply safe rewrites across files (--apply return-to-close|missing-timeouts)", args: "<file|dir...>", setup: fixCommand, files: true},
+		{name: "fmt", summary: "Print TWF files in the canonical layout (--write, --diff, --check)", args: "<file|dir...|->", setup: fmtCommand, files: true},
+		{name: "profile", summary: "Time each analysis phase over repeated runs, writing pprof profiles", args: "<file...>", setup: profileCommand, files: true},
+		{name: "batch", summary: `Analyze JSON lines {"path", "content"} from stdin`, args: "< input.jsonl", setup: batchCommand},
+		{name: "highlight", summary: "Print source with syntax coloring (--html or --ansi)", args: "<file...|->", setup: highlightCommand, files: true},
+		{name: "grammar", summary: "Generate editor grammars (--textmate or --tree-sitter)", setup: grammarCommand},
+		{name: "serve-api", summary: "Serve parse/check/symbols/graph over HTTP+JSON", setup: serveAPICommand},
+		{name: "report", summary: "Report on the designs as a whole (report topology)", subcommands: []command{
+			{name: "topology", summary: "List the task queues, workers, and schedules each workflow and activity runs on", args: "<file|dir...>", setup: reportTopologyCommand, files: true},
+		}},
+		{name: "lsp", summary: "Start the language server (stdio)", setup: lspCommand},
+		{name: "examples", summary: "List, print, or copy the built-in example designs (examples list|show|init)", subcommands: []command{
+			{name: "list", summary: "List the example designs with a summary of each", setup: examplesListCommand},
+			{name: "show", summary: "Print the source of an example design", args: "<name>", setup: examplesShowCommand, words: examples.Names()},
+			{name: "init", summary: "Write an example design to NAME.twf to start from", args: "<name>", setup: examplesInitCommand, words: examples.Names()},
+		}},
+		{name: "explain", summary: "Explain a language construct with an example", args: "<keyword...>", setup: explainCommand, words: explainWords()},
+		{name: "completion", summary: "Print a shell completion script (bash, zsh, or fish)", args: "bash|zsh|fish", setup: completionCommand, words: shells},
+		{name: "help", summary: "Show this help, or a command's", args: "[command]", setup: helpCommand},
 	}
+}
 
-	command := os.Args[1]
-
-	switch command {
-	case "check":
-		os.Exit(checkCommand(os.Args[2:]))
-	case "parse":
-		os.Exit(parseCommand(os.Args[2:]))
-	case "symbols":
-		os.Exit(symbolsCommand(os.Args[2:]))
-	case "deps":
-		os.Exit(depsCommand(os.Args[2:]))
-	case "lsp":
-		lspCommand()
-	case "help", "--help", "-h":
-		fmt.Print(usage)
-		os.Exit(0)
-	default:
-		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", command)
-		fmt.Fprint(os.Stderr, usage)
-		os.Exit(1)
+func usage() string {
+	var b strings.Builder
+	b.WriteString(usageHeader)
+	for _, c := range commands {
+		fmt.Fprintf(&b, "  %-10s %s\n", c.name, c.summary)
 	}
+	b.WriteString("\nGlobal options:\n")
+	printFlags(&b, newGlobalFlagSet(true))
+	b.WriteString(usageFooter)
+	return b.String()
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
 }
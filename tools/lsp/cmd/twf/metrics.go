@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// metrics collects the telemetry of twf serve-api's analyses and serves it
+// in the Prometheus text format, for twf serve-api --metrics.
+type metrics struct {
+	mu       sync.Mutex
+	stages   [3]stageMetrics // parse, resolve, validate
+	analyses int
+	errors   int
+	warnings int
+}
+
+// stageMetrics sums the durations of one analysis stage.
+type stageMetrics struct {
+	count int
+	sum   time.Duration
+}
+
+var stageNames = [3]string{"parse", "resolve", "validate"}
+
+func (m *metrics) observe(stage int, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stages[stage].count++
+	m.stages[stage].sum += d
+}
+
+func (m *metrics) Parsed(_ string, d time.Duration)      { m.observe(0, d) }
+func (m *metrics) Resolved(_ []string, d time.Duration)  { m.observe(1, d) }
+func (m *metrics) Validated(_ []string, d time.Duration) { m.observe(2, d) }
+
+func (m *metrics) Diagnosed(_ []string, errors, warnings int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.analyses++
+	m.errors += errors
+	m.warnings += warnings
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (m *metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	fmt.Fprintln(w, "# HELP twf_stage_duration_seconds Time spent in each analysis stage.")
+	fmt.Fprintln(w, "# TYPE twf_stage_duration_seconds summary")
+	for i, s := range m.stages {
+		fmt.Fprintf(w, "twf_stage_duration_seconds_sum{stage=%q} %g\n", stageNames[i], s.sum.Seconds())
+		fmt.Fprintf(w, "twf_stage_duration_seconds_count{stage=%q} %d\n", stageNames[i], s.count)
+	}
+	fmt.Fprintln(w, "# HELP twf_analyses_total Analyses completed.")
+	fmt.Fprintln(w, "# TYPE twf_analyses_total counter")
+	fmt.Fprintf(w, "twf_analyses_total %d\n", m.analyses)
+	fmt.Fprintln(w, "# HELP twf_diagnostics_total Diagnostics reported by completed analyses.")
+	fmt.Fprintln(w, "# TYPE twf_diagnostics_total counter")
+	fmt.Fprintf(w, "twf_diagnostics_total{severity=\"error\"} %d\n", m.errors)
+	fmt.Fprintf(w, "twf_diagnostics_total{severity=\"warning\"} %d\n", m.warnings)
+}
@@ -1,46 +1,115 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/lexer"
 )
 
 // parseCommand outputs the AST as JSON.
 // Always outputs partial AST even with errors (lenient by default).
 // Errors go to stderr, AST goes to stdout.
-func parseCommand(args []string) int {
-	fs := flag.NewFlagSet("parse", flag.ContinueOnError)
-	if err := fs.Parse(args); err != nil {
-		return 1
-	}
+func parseCommand(fs *flag.FlagSet) func() int {
+	schema := fs.Bool("schema", false, "Print the JSON Schema for the AST output and exit")
+	compact := fs.Bool("compact", false, "Output JSON without indentation")
+	only := fs.String("only", "", "Output only the definition with this name")
+	depth := fs.Int("depth", -1, "Drop statement bodies nested deeper than N (0 keeps definition headers only)")
+	var lex lexer.Options
+	aliasesFlag(fs, &lex)
+	keywordCaseFlag(fs, &lex)
+	var sels []selector
+	fs.Func("select", "Output a JSON array of the nodes where `key=value` (repeatable; all must match)", func(s string) error {
+		sel, err := parseSelector(s)
+		sels = append(sels, sel)
+		return err
+	})
+	return func() int {
+		if *schema {
+			data, err := ast.JSONSchema()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return exitInternal
+			}
+			os.Stdout.Write(data)
+			return 0
+		}
 
-	paths := fs.Args()
-	if len(paths) == 0 {
-		fmt.Fprintln(os.Stderr, "usage: twf parse <file...>")
-		return 1
-	}
+		paths := fs.Args()
+		if len(paths) == 0 {
+			fmt.Fprintln(os.Stderr, "usage: twf parse [--compact] [--only NAME] [--depth N] [--select key=value] [--aliases FILE] [--case-insensitive-keywords] <file...>\n       twf parse --schema")
+			return exitUsage
+		}
 
-	// Force lenient mode - always emit partial AST
-	file, errs, _ := parseFiles(paths, true)
+		sources, exitCode := readSources(paths)
+		if exitCode != 0 {
+			fmt.Println("null")
+			return exitCode
+		}
+		// Force lenient mode - always emit partial AST
+		file, errs, _ := parseSources(sources, lex, true, false)
 
-	// Output errors to stderr (but don't fail - we still emit JSON)
-	printErrors(errs)
+		// Output errors to stderr (but don't fail - we still emit JSON)
+		printErrors(errs)
 
-	if file == nil {
-		fmt.Println("null")
-		return 1
-	}
+		if *only != "" {
+			var kept []ast.Definition
+			for _, def := range file.Definitions {
+				if definitionName(def) == *only {
+					kept = append(kept, def)
+				}
+			}
+			if len(kept) == 0 {
+				fmt.Fprintf(os.Stderr, "no definition named %s\n", *only)
+				return exitUsage
+			}
+			file = &ast.File{Definitions: kept}
+		}
 
-	// Output AST to stdout even if there were errors
-	data, err := json.MarshalIndent(file, "", "  ")
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "json marshal error: %v\n", err)
-		return 1
-	}
-	fmt.Println(string(data))
+		// Output AST to stdout even if there were errors
+		data, err := json.Marshal(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "json marshal error: %v\n", err)
+			return exitInternal
+		}
+		if *depth >= 0 {
+			if data, err = truncateDepth(data, *depth); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return exitInternal
+			}
+		}
+		if len(sels) > 0 {
+			nodes, err := selectNodes(data, sels)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return exitInternal
+			}
+			if nodes == nil {
+				nodes = []json.RawMessage{}
+			}
+			if data, err = json.Marshal(nodes); err != nil {
+				fmt.Fprintf(os.Stderr, "json marshal error: %v\n", err)
+				return exitInternal
+			}
+		}
+
+		var out bytes.Buffer
+		if *compact {
+			err = json.Compact(&out, data)
+		} else {
+			err = json.Indent(&out, data, "", "  ")
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitInternal
+		}
+		fmt.Println(out.String())
 
-	// Exit 0 even with parse/resolve errors - the visualizer needs the partial AST
-	return 0
+		// Exit 0 even with parse/resolve errors - the visualizer needs the partial AST
+		return 0
+	}
 }
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"time"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/lexer"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/parser"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/resolver"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/validator"
+)
+
+// profilePhases are the phases twf profile times, in pipeline order.
+// Parsing lexes again, so the parse phase includes the cost of lex.
+var profilePhases = [...]string{"lex", "parse", "resolve", "validate", "marshal"}
+
+// phaseProfile is the average cost of one phase over the runs.
+type phaseProfile struct {
+	Phase       string `json:"phase"`
+	NsPerOp     int64  `json:"nsPerOp"`
+	AllocsPerOp uint64 `json:"allocsPerOp"`
+	BytesPerOp  uint64 `json:"bytesPerOp"`
+}
+
+// profileReport is what twf profile prints.
+type profileReport struct {
+	Files       []string       `json:"files"`
+	SourceBytes int            `json:"sourceBytes"`
+	Iterations  int            `json:"iterations"`
+	Diagnostics int            `json:"diagnostics"`
+	Phases      []phaseProfile `json:"phases"`
+}
+
+// profileCommand runs the analysis pipeline over files repeatedly and
+// prints the time and allocations of each phase, optionally writing pprof
+// profiles of the runs.
+func profileCommand(fs *flag.FlagSet) func() int {
+	iterations := fs.Int("iterations", 50, "Run the pipeline `N` times")
+	cpuProfile := fs.String("cpuprofile", "", "Write a CPU profile of the runs to `FILE`")
+	memProfile := fs.String("memprofile", "", "Write an allocation profile of the runs to `FILE`")
+	jsonOutput := fs.Bool("json", false, "Output the phase breakdown as JSON")
+	var lex lexer.Options
+	aliasesFlag(fs, &lex)
+	keywordCaseFlag(fs, &lex)
+	return func() int {
+		if fs.NArg() == 0 || *iterations < 1 {
+			fmt.Fprintln(os.Stderr, "usage: twf profile [--iterations N] [--cpuprofile FILE] [--memprofile FILE] [--json] <file...>")
+			return exitUsage
+		}
+		sources, exitCode := readSources(fs.Args())
+		if exitCode != 0 {
+			return exitCode
+		}
+
+		if *cpuProfile != "" {
+			f, err := os.Create(*cpuProfile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return exitUsage
+			}
+			defer f.Close()
+			if err := pprof.StartCPUProfile(f); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return exitInternal
+			}
+		}
+		report := profileSources(sources, lex, *iterations)
+		if *cpuProfile != "" {
+			pprof.StopCPUProfile()
+		}
+		if *memProfile != "" {
+			if err := writeAllocProfile(*memProfile); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return exitUsage
+			}
+		}
+
+		if *jsonOutput {
+			data, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "json marshal error: %v\n", err)
+				return exitInternal
+			}
+			fmt.Println(string(data))
+			return 0
+		}
+		printProfile(report)
+		return 0
+	}
+}
+
+// profileSources runs each phase of the pipeline over sources, lexed with
+// lex, iterations times, measuring each on its own. Diagnostics are counted from the last
+// run, so a report shows when a file profiled fails to parse or resolve.
+func profileSources(sources []source, lex lexer.Options, iterations int) profileReport {
+	report := profileReport{Iterations: iterations}
+	for _, src := range sources {
+		report.Files = append(report.Files, src.Name)
+		report.SourceBytes += len(src.Text)
+	}
+
+	var totals [len(profilePhases)]struct {
+		d             time.Duration
+		allocs, bytes uint64
+	}
+	measure := func(phase int, run func()) {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		start := time.Now()
+		run()
+		totals[phase].d += time.Since(start)
+		runtime.ReadMemStats(&after)
+		totals[phase].allocs += after.Mallocs - before.Mallocs
+		totals[phase].bytes += after.TotalAlloc - before.TotalAlloc
+	}
+
+	for range iterations {
+		report.Diagnostics = 0
+		measure(0, func() {
+			for _, src := range sources {
+				lex.New(src.Text).AllTokens()
+			}
+		})
+		merged := &ast.File{}
+		measure(1, func() {
+			for _, src := range sources {
+				file, errs := parser.WithLexer(lex).ParseFileAll(src.Text)
+				report.Diagnostics += len(errs)
+				for _, def := range file.Definitions {
+					ast.SetSourceFile(def, src.Name)
+					merged.Definitions = append(merged.Definitions, def)
+				}
+			}
+		})
+		var resolved *resolver.ResolveResult
+		measure(2, func() { resolved = resolver.ResolveFile(merged) })
+		report.Diagnostics += len(resolved.Errors)
+		measure(3, func() { report.Diagnostics += len(validator.ValidateSymbols(resolved.Symbols)) })
+		measure(4, func() { json.Marshal(merged) })
+	}
+
+	n := uint64(iterations)
+	for i, name := range profilePhases {
+		report.Phases = append(report.Phases, phaseProfile{
+			Phase:       name,
+			NsPerOp:     int64(totals[i].d) / int64(iterations),
+			AllocsPerOp: totals[i].allocs / n,
+			BytesPerOp:  totals[i].bytes / n,
+		})
+	}
+	return report
+}
+
+// writeAllocProfile writes the allocations sampled since the program
+// started to path.
+func writeAllocProfile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := pprof.Lookup("allocs").WriteTo(f, 0); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// printProfile prints report as a table with a row for each phase.
+func printProfile(report profileReport) {
+	fmt.Printf("%s: %d bytes, %d iterations, %d diagnostics\n\n", strings.Join(report.Files, ", "), report.SourceBytes, report.Iterations, report.Diagnostics)
+	fmt.Printf("%-10s %12s %12s %12s\n", "phase", "time/op", "allocs/op", "bytes/op")
+	var total phaseProfile
+	for _, p := range report.Phases {
+		fmt.Printf("%-10s %12s %12d %12d\n", p.Phase, time.Duration(p.NsPerOp), p.AllocsPerOp, p.BytesPerOp)
+		if p.Phase != "lex" {
+			total.NsPerOp += p.NsPerOp
+			total.AllocsPerOp += p.AllocsPerOp
+			total.BytesPerOp += p.BytesPerOp
+		}
+	}
+	fmt.Printf("%-10s %12s %12d %12d\n", "total", time.Duration(total.NsPerOp), total.AllocsPerOp, total.BytesPerOp)
+	fmt.Println("\nparse includes lexing, so the total leaves out the lex row")
+}
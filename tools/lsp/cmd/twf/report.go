@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/topology"
+)
+
+// reportTopologyCommand prints where the workflows and activities of the
+// designs run: each namespace's workers and task queues, the placement of
+// each definition, and the cron schedules.
+func reportTopologyCommand(fs *flag.FlagSet) func() int {
+	jsonOutput := fs.Bool("json", false, "Output the report as JSON")
+	markdown := fs.Bool("markdown", false, "Output the report as Markdown tables")
+	lenient := fs.Bool("lenient", false, "Continue even with resolve errors")
+	return func() int {
+		if fs.NArg() == 0 || (*jsonOutput && *markdown) {
+			fmt.Fprintln(os.Stderr, "usage: twf report topology [--json|--markdown] [--lenient] <file|dir...>")
+			return exitUsage
+		}
+		var paths []string
+		for _, arg := range fs.Args() {
+			found, err := twfFiles(arg)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				return exitUsage
+			}
+			paths = append(paths, found...)
+		}
+
+		file, errs, exitCode := parseFiles(paths, *lenient)
+		printErrors(errs)
+		if file == nil {
+			return exitCode
+		}
+
+		report := topology.Build(file)
+		switch {
+		case *jsonOutput:
+			data, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "json marshal error: %v\n", err)
+				return exitInternal
+			}
+			fmt.Println(string(data))
+		case *markdown:
+			fmt.Print(report.Markdown())
+		default:
+			fmt.Println(report.Text())
+		}
+		return exitCode
+	}
+}
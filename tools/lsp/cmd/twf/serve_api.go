@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/deps"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/lexer"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/telemetry"
+)
+
+// apiRequest is the body of every analysis endpoint: file names mapped to
+// TWF source. Sources are parsed independently and resolved together, like
+// passing several files to the CLI.
+type apiRequest struct {
+	Sources map[string]string `json:"sources"`
+}
+
+type parseResponse struct {
+	AST         *ast.File    `json:"ast"`
+	Diagnostics []diagnostic `json:"diagnostics"`
+}
+
+type checkResponse struct {
+	OK          bool         `json:"ok"`
+	Workflows   int          `json:"workflows"`
+	Activities  int          `json:"activities"`
+	Diagnostics []diagnostic `json:"diagnostics"`
+}
+
+type symbolsResponse struct {
+	Symbols     []symbolJSON `json:"symbols"`
+	Diagnostics []diagnostic `json:"diagnostics"`
+}
+
+type graphResponse struct {
+	Graph       *deps.Graph  `json:"graph"`
+	Diagnostics []diagnostic `json:"diagnostics"`
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// serveAPICommand serves parse, check, symbols, and graph analysis over
+// HTTP+JSON for tooling that cannot shell out to the CLI.
+func serveAPICommand(fs *flag.FlagSet) func() int {
+	addr := fs.String("addr", "localhost:8421", "Address to listen on")
+	maxBytes := fs.Int64("max-bytes", 1<<20, "Maximum request body size in bytes")
+	maxConcurrent := fs.Int("max-concurrent", runtime.NumCPU(), "Maximum requests analyzed at once")
+	withMetrics := fs.Bool("metrics", false, "Serve analysis metrics at /metrics in the Prometheus text format")
+	var timeouts apiTimeouts
+	fs.DurationVar(&timeouts.readHeader, "read-header-timeout", 10*time.Second, "Close connections whose request headers take longer than `duration` to arrive; 0 for no limit")
+	fs.DurationVar(&timeouts.read, "read-timeout", 30*time.Second, "Close connections whose whole request takes longer than `duration` to arrive; 0 for no limit")
+	fs.DurationVar(&timeouts.write, "write-timeout", 2*time.Minute, "Close connections whose response, including the wait for an analysis slot, is not written within `duration` of the request being read; 0 for no limit")
+	return func() int {
+		if fs.NArg() > 0 || *maxBytes <= 0 || *maxConcurrent <= 0 || timeouts.readHeader < 0 || timeouts.read < 0 || timeouts.write < 0 {
+			fmt.Fprintln(os.Stderr, "usage: twf serve-api [--addr HOST:PORT] [--max-bytes N] [--max-concurrent N] [--metrics] [--read-header-timeout D] [--read-timeout D] [--write-timeout D]")
+			return exitUsage
+		}
+
+		var m *metrics
+		if *withMetrics {
+			m = &metrics{}
+		}
+		fmt.Fprintf(os.Stderr, "twf serve-api listening on %s\n", *addr)
+		if err := newAPIServer(*addr, newAPIHandler(*maxBytes, *maxConcurrent, m), timeouts).ListenAndServe(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return exitInternal
+		}
+		return 0
+	}
+}
+
+// apiTimeouts bound how long serve-api waits on a client, so clients that
+// stall sending a request or reading a response do not hold connections
+// open for good. A zero timeout is no limit.
+type apiTimeouts struct {
+	readHeader time.Duration // for the request headers
+	read       time.Duration // for the whole request, body included
+	write      time.Duration // from reading the request to writing the response
+}
+
+// newAPIServer returns the server serving h on addr within timeouts.
+func newAPIServer(addr string, h http.Handler, timeouts apiTimeouts) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           h,
+		ReadHeaderTimeout: timeouts.readHeader,
+		ReadTimeout:       timeouts.read,
+		WriteTimeout:      timeouts.write,
+	}
+}
+
+// newAPIHandler returns the analysis API. Request bodies over maxBytes are
+// rejected, and at most maxConcurrent requests are analyzed at once; the
+// rest wait until a slot frees or their client goes away. With m, the
+// analyses are measured and the measurements served at /metrics.
+func newAPIHandler(maxBytes int64, maxConcurrent int, m *metrics) http.Handler {
+	slots := make(chan struct{}, maxConcurrent)
+	var hooks telemetry.Hooks = telemetry.Nop{}
+	if m != nil {
+		hooks = m
+	}
+
+	endpoint := func(respond func(*ast.File, []diagnostic) any) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				w.Header().Set("Allow", http.MethodPost)
+				writeJSON(w, http.StatusMethodNotAllowed, apiError{"method not allowed; use POST"})
+				return
+			}
+
+			var req apiRequest
+			dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBytes))
+			if err := dec.Decode(&req); err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					writeJSON(w, http.StatusRequestEntityTooLarge, apiError{fmt.Sprintf("request body exceeds %d bytes", maxBytes)})
+					return
+				}
+				writeJSON(w, http.StatusBadRequest, apiError{"invalid request: " + err.Error()})
+				return
+			}
+			if len(req.Sources) == 0 {
+				writeJSON(w, http.StatusBadRequest, apiError{"invalid request: no sources"})
+				return
+			}
+
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			case <-r.Context().Done():
+				return
+			}
+
+			// A client that disconnects cancels its analysis.
+			file, diags, err := analyzeContext(r.Context(), sortedSources(req.Sources), lexer.Options{}, hooks)
+			if err != nil {
+				return
+			}
+			writeJSON(w, http.StatusOK, respond(file, diags))
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/parse", endpoint(func(file *ast.File, diags []diagnostic) any {
+		return parseResponse{AST: file, Diagnostics: diags}
+	}))
+	mux.HandleFunc("/v1/check", endpoint(func(file *ast.File, diags []diagnostic) any {
+		resp := checkResponse{OK: true, Diagnostics: diags}
+		for _, d := range diags {
+			if d.Severity == "error" {
+				resp.OK = false
+			}
+		}
+		for _, def := range file.Definitions {
+			switch def.(type) {
+			case *ast.WorkflowDef:
+				resp.Workflows++
+			case *ast.ActivityDef:
+				resp.Activities++
+			}
+		}
+		return resp
+	}))
+	mux.HandleFunc("/v1/symbols", endpoint(func(file *ast.File, diags []diagnostic) any {
+		symbols := extractSymbols(file)
+		if symbols == nil {
+			symbols = []symbolJSON{}
+		}
+		return symbolsResponse{Symbols: symbols, Diagnostics: diags}
+	}))
+	mux.HandleFunc("/v1/graph", endpoint(func(file *ast.File, diags []diagnostic) any {
+		return graphResponse{Graph: deps.Extract(file), Diagnostics: diags}
+	}))
+	if m != nil {
+		mux.Handle("/metrics", m)
+	}
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok", "version": version})
+	})
+	return mux
+}
+
+// sortedSources orders request sources by name so responses are
+// deterministic.
+func sortedSources(m map[string]string) []source {
+	sources := make([]source, 0, len(m))
+	for name, text := range m {
+		sources = append(sources, source{Name: name, Text: text})
+	}
+	sort.Slice(sources, func(i, j int) bool { return sources[i].Name < sources[j].Name })
+	return sources
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func postJSON(t *testing.T, h http.Handler, path, body string) (*httptest.ResponseRecorder, map[string]any) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+	var out map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+		t.Fatalf("%s: invalid JSON response %q: %v", path, rec.Body.String(), err)
+	}
+	return rec, out
+}
+
+func TestServeAPIEndpoints(t *testing.T) {
+	h := newAPIHandler(1<<20, 2, nil)
+	body := `{"sources": {
+		"order.twf": "workflow Order(id: string):\n    activity Charge(id)\n",
+		"charge.twf": "activity Charge(id: string):\n    return\n"
+	}}`
+
+	rec, check := postJSON(t, h, "/v1/check", body)
+	if rec.Code != http.StatusOK || check["ok"] != true || check["workflows"] != 1.0 || check["activities"] != 1.0 {
+		t.Errorf("check: %d %v", rec.Code, check)
+	}
+
+	_, parsed := postJSON(t, h, "/v1/parse", body)
+	if _, ok := parsed["ast"].(map[string]any)["definitions"]; !ok {
+		t.Errorf("parse: missing ast definitions: %v", parsed)
+	}
+
+	_, symbols := postJSON(t, h, "/v1/symbols", body)
+	if syms := symbols["symbols"].([]any); len(syms) != 2 {
+		t.Errorf("symbols: got %v", syms)
+	}
+
+	_, graph := postJSON(t, h, "/v1/graph", body)
+	if edges := graph["graph"].(map[string]any)["edges"].([]any); len(edges) != 1 {
+		t.Errorf("graph: got edges %v", edges)
+	}
+}
+
+func TestServeAPIDiagnostics(t *testing.T) {
+	h := newAPIHandler(1<<20, 1, nil)
+	_, check := postJSON(t, h, "/v1/check", `{"sources": {"a.twf": "workflow A():\n    activity Missing()\n"}}`)
+	diags := check["diagnostics"].([]any)
+	if check["ok"] != false || len(diags) != 1 {
+		t.Fatalf("got %v", check)
+	}
+	d := diags[0].(map[string]any)
+	if d["stage"] != "resolve" || d["line"] != 2.0 || d["message"] != "undefined activity: Missing" {
+		t.Errorf("got diagnostic %v", d)
+	}
+}
+
+func TestServeAPIRejects(t *testing.T) {
+	h := newAPIHandler(64, 1, nil)
+	tests := []struct {
+		name string
+		body string
+		code int
+	}{
+		{"malformed", `{`, http.StatusBadRequest},
+		{"no sources", `{"sources": {}}`, http.StatusBadRequest},
+		{"too large", `{"sources": {"a.twf": "` + strings.Repeat("x", 100) + `"}}`, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		rec, out := postJSON(t, h, "/v1/check", tt.body)
+		if rec.Code != tt.code || out["error"] == nil {
+			t.Errorf("%s: got %d %v, want %d with error", tt.name, rec.Code, out, tt.code)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/check", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET: got %d", rec.Code)
+	}
+}
+
+func TestServeAPIMetrics(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newAPIHandler(1<<20, 1, nil).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected no metrics without --metrics, got %d", rec.Code)
+	}
+
+	h := newAPIHandler(1<<20, 1, &metrics{})
+	postJSON(t, h, "/v1/check", `{"sources": {"a.twf": "workflow A():\n    activity Missing()\n", "b.twf": "activity B():\n    return\n"}}`)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, want := range []string{
+		`twf_stage_duration_seconds_count{stage="parse"} 2`,
+		`twf_stage_duration_seconds_count{stage="resolve"} 1`,
+		`twf_stage_duration_seconds_count{stage="validate"} 1`,
+		"twf_analyses_total 1",
+		`twf_diagnostics_total{severity="error"} 1`,
+		`twf_diagnostics_total{severity="warning"} 0`,
+	} {
+		if !strings.Contains(rec.Body.String(), want+"\n") {
+			t.Errorf("expected %q in the metrics:\n%s", want, rec.Body.String())
+		}
+	}
+}
+
+// TestServeAPITimeouts checks that clients stalling mid-request are cut
+// off rather than holding their connections open.
+func TestServeAPITimeouts(t *testing.T) {
+	srv := httptest.NewUnstartedServer(nil)
+	srv.Config = newAPIServer("", newAPIHandler(1<<20, 1, nil), apiTimeouts{
+		readHeader: 100 * time.Millisecond,
+		read:       200 * time.Millisecond,
+		write:      time.Second,
+	})
+	srv.Start()
+	defer srv.Close()
+
+	stalls := map[string]string{
+		"in the headers": "POST /v1/check HTTP/1.1\r\nHost: twf\r\n",
+		"in the body":    "POST /v1/check HTTP/1.1\r\nHost: twf\r\nContent-Length: 100\r\n\r\n{\"sources\": ",
+	}
+	for name, partial := range stalls {
+		conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := conn.Write([]byte(partial)); err != nil {
+			t.Fatal(err)
+		}
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		_, err = io.ReadAll(conn)
+		conn.Close()
+		if ne, ok := err.(net.Error); ok && ne.Timeout() {
+			t.Errorf("a client stalling %s was not cut off", name)
+		}
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
 )
@@ -15,20 +17,37 @@ type subSymbol struct {
 	ReturnType string `json:"returnType,omitempty"`
 }
 
+// annotationJSON is an annotation with its argument unquoted.
+type annotationJSON struct {
+	Name  string `json:"name"`
+	Value string `json:"value,omitempty"`
+}
+
 type symbolJSON struct {
-	Kind       string      `json:"kind"`
-	Name       string      `json:"name"`
-	Params     string      `json:"params,omitempty"`
-	ReturnType string      `json:"returnType,omitempty"`
-	Signals    []subSymbol `json:"signals,omitempty"`
-	Queries    []subSymbol `json:"queries,omitempty"`
-	Updates    []subSymbol `json:"updates,omitempty"`
-	Workflows  []subSymbol `json:"workflows,omitempty"`
-	Activities []subSymbol `json:"activities,omitempty"`
-	Services   []subSymbol `json:"services,omitempty"`
-	Workers    []subSymbol `json:"workers,omitempty"`
-	Endpoints  []subSymbol `json:"endpoints,omitempty"`
-	Operations []subSymbol `json:"operations,omitempty"`
+	Kind        string           `json:"kind"`
+	Name        string           `json:"name"`
+	Params      string           `json:"params,omitempty"`
+	Value       string           `json:"value,omitempty"`
+	ReturnType  string           `json:"returnType,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Annotations []annotationJSON `json:"annotations,omitempty"`
+	Signals     []subSymbol      `json:"signals,omitempty"`
+	Queries     []subSymbol      `json:"queries,omitempty"`
+	Updates     []subSymbol      `json:"updates,omitempty"`
+	Workflows   []subSymbol      `json:"workflows,omitempty"`
+	Activities  []subSymbol      `json:"activities,omitempty"`
+	Services    []subSymbol      `json:"services,omitempty"`
+	Workers     []subSymbol      `json:"workers,omitempty"`
+	Endpoints   []subSymbol      `json:"endpoints,omitempty"`
+	Operations  []subSymbol      `json:"operations,omitempty"`
+}
+
+func annotationsJSON(anns []*ast.Annotation) []annotationJSON {
+	var out []annotationJSON
+	for _, a := range anns {
+		out = append(out, annotationJSON{Name: a.Name, Value: a.Value()})
+	}
+	return out
 }
 
 // extractSymbols collects workflow and activity definitions into a uniform slice.
@@ -39,10 +58,12 @@ func extractSymbols(file *ast.File) []symbolJSON {
 		switch d := def.(type) {
 		case *ast.WorkflowDef:
 			sym := symbolJSON{
-				Kind:       "workflow",
-				Name:       d.Name,
-				Params:     d.Params,
-				ReturnType: d.ReturnType,
+				Kind:        "workflow",
+				Name:        d.Name,
+				Params:      d.Params,
+				ReturnType:  d.ReturnType,
+				Description: d.Description,
+				Annotations: annotationsJSON(d.Annotations),
 			}
 			for _, s := range d.Signals {
 				sym.Signals = append(sym.Signals, subSymbol{
@@ -67,10 +88,11 @@ func extractSymbols(file *ast.File) []symbolJSON {
 			symbols = append(symbols, sym)
 		case *ast.ActivityDef:
 			symbols = append(symbols, symbolJSON{
-				Kind:       "activity",
-				Name:       d.Name,
-				Params:     d.Params,
-				ReturnType: d.ReturnType,
+				Kind:        "activity",
+				Name:        d.Name,
+				Params:      d.Params,
+				ReturnType:  d.ReturnType,
+				Annotations: annotationsJSON(d.Annotations),
 			})
 		case *ast.WorkerDef:
 			sym := symbolJSON{
@@ -115,6 +137,26 @@ func extractSymbols(file *ast.File) []symbolJSON {
 				})
 			}
 			symbols = append(symbols, sym)
+		case *ast.ConstDef:
+			value := d.Value
+			if d.ValueType == "string" {
+				value = strconv.Quote(value)
+			}
+			symbols = append(symbols, symbolJSON{
+				Kind:  "const",
+				Name:  d.Name,
+				Value: value,
+			})
+		case *ast.EnumDef:
+			values := make([]string, len(d.Values))
+			for i, v := range d.Values {
+				values[i] = v.Name
+			}
+			symbols = append(symbols, symbolJSON{
+				Kind:  "enum",
+				Name:  d.Name,
+				Value: strings.Join(values, ", "),
+			})
 		}
 	}
 
@@ -123,38 +165,55 @@ func extractSymbols(file *ast.File) []symbolJSON {
 
 // symbolsCommand lists all workflows and activities.
 // Works with partial AST - lists what was successfully parsed.
-func symbolsCommand(args []string) int {
-	fs := flag.NewFlagSet("symbols", flag.ContinueOnError)
+func symbolsCommand(fs *flag.FlagSet) func() int {
 	jsonOutput := fs.Bool("json", false, "Output in JSON format")
 	lenient := fs.Bool("lenient", false, "Continue even with resolve errors")
-	if err := fs.Parse(args); err != nil {
-		return 1
-	}
-
-	paths := fs.Args()
-	if len(paths) == 0 {
-		fmt.Fprintln(os.Stderr, "usage: twf symbols [--json] [--lenient] <file...>")
-		return 1
-	}
+	tree := fs.Bool("tree", false, "List each workflow with the activities and child workflows it calls")
+	depth := fs.Int("depth", -1, "With --tree, show at most N levels of calls below each workflow")
+	return func() int {
+		paths := fs.Args()
+		if len(paths) == 0 {
+			fmt.Fprintln(os.Stderr, "usage: twf symbols [--json] [--lenient] [--tree [--depth N]] <file...>")
+			return exitUsage
+		}
 
-	file, errs, exitCode := parseFiles(paths, *lenient)
+		file, errs, exitCode := parseFiles(paths, *lenient)
 
-	// Report errors to stderr but continue to show symbols
-	printErrors(errs)
+		// Report errors to stderr but continue to show symbols
+		printErrors(errs)
 
-	// Show symbols from partial AST
-	if file != nil {
-		if *jsonOutput {
-			return printSymbolsJSON(file)
+		// Show symbols from partial AST
+		if file == nil {
+			return exitCode
 		}
-		return printSymbolsText(file)
+		code := 0
+		switch {
+		case *tree && *jsonOutput:
+			code = printCallTreeJSON(buildCallTree(file, *depth))
+		case *tree:
+			code = printCallTreeText(buildCallTree(file, *depth))
+		case *jsonOutput:
+			code = printSymbolsJSON(file)
+		default:
+			code = printSymbolsText(file)
+		}
+		if code != 0 {
+			return code
+		}
+		return exitCode
 	}
-
-	return exitCode
 }
 
 func printSymbolsText(file *ast.File) int {
 	for _, sym := range extractSymbols(file) {
+		if sym.Kind == "const" {
+			fmt.Printf("const %s = %s\n", sym.Name, sym.Value)
+			continue
+		}
+		if sym.Kind == "enum" {
+			fmt.Printf("enum %s: %s\n", sym.Name, sym.Value)
+			continue
+		}
 		fmt.Printf("%s %s(%s)", sym.Kind, sym.Name, sym.Params)
 		if sym.ReturnType != "" {
 			fmt.Printf(" -> (%s)", sym.ReturnType)
@@ -204,7 +263,7 @@ func printSymbolsJSON(file *ast.File) int {
 	data, err := json.MarshalIndent(extractSymbols(file), "", "  ")
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "json marshal error: %v\n", err)
-		return 1
+		return exitInternal
 	}
 	fmt.Println(string(data))
 	return 0
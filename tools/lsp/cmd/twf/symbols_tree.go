@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+)
+
+// callNode is one entry of the symbols --tree output: a workflow or activity
+// and, for workflows, what it calls.
+type callNode struct {
+	Kind      string     `json:"kind"`
+	Name      string     `json:"name"`
+	Undefined bool       `json:"undefined,omitempty"` // the call names no definition
+	Recursive bool       `json:"recursive,omitempty"` // already expanded higher in this branch
+	Truncated bool       `json:"truncated,omitempty"` // calls omitted by --depth
+	Calls     []callNode `json:"calls,omitempty"`
+}
+
+// buildCallTree returns a tree for every workflow listing the activities and
+// child workflows it calls, expanding child workflows up to maxDepth levels
+// below the root (unlimited when negative).
+func buildCallTree(file *ast.File, maxDepth int) []callNode {
+	var roots []callNode
+	for _, def := range file.Definitions {
+		if wf, ok := def.(*ast.WorkflowDef); ok {
+			roots = append(roots, expandWorkflow(wf, 0, maxDepth, map[*ast.WorkflowDef]bool{}))
+		}
+	}
+	return roots
+}
+
+func expandWorkflow(wf *ast.WorkflowDef, depth, maxDepth int, onPath map[*ast.WorkflowDef]bool) callNode {
+	node := callNode{Kind: "workflow", Name: wf.Name}
+	callees := workflowCallees(wf)
+	if len(callees) == 0 {
+		return node
+	}
+	if maxDepth >= 0 && depth >= maxDepth {
+		node.Truncated = true
+		return node
+	}
+
+	onPath[wf] = true
+	defer delete(onPath, wf)
+	for _, c := range callees {
+		switch {
+		case c.workflow != nil && onPath[c.workflow]:
+			node.Calls = append(node.Calls, callNode{Kind: "workflow", Name: c.name, Recursive: true})
+		case c.workflow != nil:
+			node.Calls = append(node.Calls, expandWorkflow(c.workflow, depth+1, maxDepth, onPath))
+		default:
+			node.Calls = append(node.Calls, callNode{Kind: c.kind, Name: c.name, Undefined: !c.resolved})
+		}
+	}
+	return node
+}
+
+// callee is a distinct activity or workflow called from a workflow.
+type callee struct {
+	kind     string
+	name     string
+	resolved bool
+	workflow *ast.WorkflowDef // resolved child workflow
+}
+
+// workflowCallees returns the activities and workflows wf calls from its
+// body and handlers, each once, in order of first call.
+func workflowCallees(wf *ast.WorkflowDef) []callee {
+	var out []callee
+	seen := make(map[string]bool)
+	add := func(kind, name string, resolved bool, child *ast.WorkflowDef) {
+		if seen[kind+" "+name] {
+			return
+		}
+		seen[kind+" "+name] = true
+		out = append(out, callee{kind: kind, name: name, resolved: resolved, workflow: child})
+	}
+
+	walk := func(stmts []ast.Statement) {
+		ast.WalkStatements(stmts, func(s ast.Statement) bool {
+			switch s := s.(type) {
+			case *ast.ActivityCall:
+				add("activity", s.Activity.Name, s.Activity.Resolved != nil, nil)
+			case *ast.WorkflowCall:
+				add("workflow", s.Workflow.Name, s.Workflow.Resolved != nil, s.Workflow.Resolved)
+			}
+			return true
+		}, ast.WithAsyncTargets(func(target ast.AsyncTarget, _ ast.Statement) bool {
+			switch t := target.(type) {
+			case *ast.ActivityTarget:
+				add("activity", t.Activity.Name, t.Activity.Resolved != nil, nil)
+			case *ast.WorkflowTarget:
+				add("workflow", t.Workflow.Name, t.Workflow.Resolved != nil, t.Workflow.Resolved)
+			}
+			return true
+		}))
+	}
+	for _, s := range wf.Signals {
+		walk(s.Body)
+	}
+	for _, u := range wf.Updates {
+		walk(u.Body)
+	}
+	walk(wf.Body)
+	return out
+}
+
+func printCallTreeText(roots []callNode) int {
+	var print func(n callNode, indent int)
+	print = func(n callNode, indent int) {
+		fmt.Printf("%s%s %s", strings.Repeat("  ", indent), n.Kind, n.Name)
+		switch {
+		case n.Undefined:
+			fmt.Print(" (undefined)")
+		case n.Recursive:
+			fmt.Print(" (recursive)")
+		case n.Truncated:
+			fmt.Print(" ...")
+		}
+		fmt.Println()
+		for _, c := range n.Calls {
+			print(c, indent+1)
+		}
+	}
+	for _, r := range roots {
+		print(r, 0)
+	}
+	return 0
+}
+
+func printCallTreeJSON(roots []callNode) int {
+	if roots == nil {
+		roots = []callNode{}
+	}
+	data, err := json.MarshalIndent(roots, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "json marshal error: %v\n", err)
+		return exitInternal
+	}
+	fmt.Println(string(data))
+	return 0
+}
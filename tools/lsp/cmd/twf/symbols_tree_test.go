@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/parser"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/resolver"
+)
+
+const treeSource = `workflow Order(id: string):
+    signal Retry():
+        activity Charge(id)
+    activity Charge(id)
+    workflow Ship(id)
+    await all:
+        activity Missing(id)
+
+workflow Ship(id: string):
+    activity Pack(id)
+    workflow Order(id)
+
+activity Charge(id: string):
+    return id
+
+activity Pack(id: string):
+    return id
+`
+
+func TestBuildCallTree(t *testing.T) {
+	file, _ := parser.ParseFileAll(treeSource)
+	resolver.Resolve(file)
+
+	roots := buildCallTree(file, -1)
+	if len(roots) != 2 {
+		t.Fatalf("expected a tree per workflow, got %d", len(roots))
+	}
+	order := roots[0]
+	// Charge is called from the handler and the body but listed once.
+	if got := names(order.Calls); got != "Charge,Ship,Missing" {
+		t.Fatalf("Order calls: %s", got)
+	}
+	if !order.Calls[2].Undefined {
+		t.Error("Missing should be marked undefined")
+	}
+	ship := order.Calls[1]
+	if got := names(ship.Calls); got != "Pack,Order" || !ship.Calls[1].Recursive {
+		t.Errorf("Ship under Order: %+v", ship.Calls)
+	}
+
+	shallow := buildCallTree(file, 1)
+	if s := shallow[0].Calls[1]; !s.Truncated || len(s.Calls) != 0 {
+		t.Errorf("depth 1 should stop below Ship, got %+v", s)
+	}
+}
+
+func names(nodes []callNode) string {
+	var s string
+	for i, n := range nodes {
+		if i > 0 {
+			s += ","
+		}
+		s += n.Name
+	}
+	return s
+}
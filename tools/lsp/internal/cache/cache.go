@@ -0,0 +1,286 @@
+// Package cache stores analysis results on disk, keyed by a hash of
+// everything they depend on, so tools can skip analyzing inputs they have
+// seen before.
+//
+// Entries are never invalidated explicitly: a change to an input, an
+// option, or the tool yields a different key, and entries no run has used
+// for MaxAge are removed when a cache is opened.
+package cache
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Format is mixed into every key. It changes when the layout of entries or
+// the meaning of cached values does, orphaning the entries of older builds.
+const Format = "twf-cache-1"
+
+// MaxAge is how long an entry is kept after it was last written or read.
+const MaxAge = 30 * 24 * time.Hour
+
+// pruneMarker is touched each time the directory is pruned, so it is
+// walked at most once per pruneInterval.
+const (
+	pruneMarker   = ".pruned"
+	pruneInterval = 24 * time.Hour
+)
+
+// LockWait is how long GetOrPut waits for another process filling the
+// same entry before filling it too. A lock older than LockWait was left by
+// a process that died holding it and is broken.
+const LockWait = time.Minute
+
+// lockPoll is how often a waiting GetOrPut looks for the entry.
+const lockPoll = 50 * time.Millisecond
+
+// lockRefresh is how often a process holding a lock touches it, so a fill
+// taking longer than LockWait is not mistaken for an abandoned one. It is
+// a variable for tests.
+var lockRefresh = LockWait / 4
+
+// Cache is a directory of entries, each a JSON file named by its key.
+// Several processes may share one, such as an editor's language server
+// and a terminal running twf check: entries are written atomically, one
+// that cannot be read is treated as missing, and lock files keep two
+// processes from filling the same entry or pruning at once.
+type Cache struct {
+	dir string
+}
+
+// Open opens the cache in dir, creating the directory if needed, and
+// prunes stale entries if no process has done so recently.
+func Open(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	c := &Cache{dir: dir}
+	marker := filepath.Join(dir, pruneMarker)
+	if info, err := os.Stat(marker); err != nil || time.Since(info.ModTime()) > pruneInterval {
+		if unlock, ok := tryLock(marker + ".lock"); ok {
+			c.Prune(time.Now().Add(-MaxAge))
+			os.WriteFile(marker, nil, 0o644)
+			unlock()
+		}
+	}
+	return c, nil
+}
+
+// Dir returns the directory of c.
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// Key returns the key of a result depending on parts, in order. Each part
+// is length-prefixed, so ("ab", "c") and ("a", "bc") differ.
+func Key(parts ...string) string {
+	h := sha256.New()
+	h.Write([]byte(Format))
+	var n [8]byte
+	for _, p := range parts {
+		binary.BigEndian.PutUint64(n[:], uint64(len(p)))
+		h.Write(n[:])
+		h.Write([]byte(p))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key+".json")
+}
+
+// Get decodes the entry for key into v and reports whether there was one.
+// A hit marks the entry as used, so it outlives MaxAge while in use.
+func (c *Cache) Get(key string, v any) bool {
+	path := c.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil || json.Unmarshal(data, v) != nil {
+		return false
+	}
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return true
+}
+
+// Put stores v as the entry for key, replacing any entry there.
+func (c *Cache) Put(key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// GetOrPut decodes the entry for key into v and reports a hit, or else
+// calls fill to set v and stores it. While one process fills an entry,
+// others asking for the same key wait for its result rather than
+// computing it again, up to LockWait. An error storing the entry is
+// returned with v set.
+func (c *Cache) GetOrPut(key string, v any, fill func()) (bool, error) {
+	lock := strings.TrimSuffix(c.path(key), ".json") + ".lock"
+	deadline := time.Now().Add(LockWait)
+	for {
+		if c.Get(key, v) {
+			return true, nil
+		}
+		if err := os.MkdirAll(filepath.Dir(lock), 0o755); err != nil {
+			return false, err
+		}
+		if unlock, ok := tryLock(lock); ok {
+			defer unlock()
+			// The holder before us may have stored the entry between our
+			// Get and taking the lock.
+			if c.Get(key, v) {
+				return true, nil
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(lockPoll)
+	}
+	fill()
+	return false, c.Put(key, v)
+}
+
+// tryLock creates the lock file path, breaking one older than LockWait,
+// and returns the function releasing it. The file holds a token naming
+// this holder, and is touched every lockRefresh until it is released. It
+// reports false when another process holds the lock or the file cannot be
+// created.
+func tryLock(path string) (unlock func(), ok bool) {
+	var b [16]byte
+	rand.Read(b[:])
+	token := hex.EncodeToString(b[:])
+	for range 2 {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			_, err = f.WriteString(token)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(path)
+				return nil, false
+			}
+			return holdLock(path, token), true
+		}
+		if !errors.Is(err, fs.ErrExist) || !breakLock(path, token) {
+			return nil, false
+		}
+	}
+	return nil, false
+}
+
+// holdLock keeps the lock at path, created holding token, fresh until the
+// returned function is called. That function stops refreshing the lock and
+// removes it, unless it was broken and another process holds it now.
+func holdLock(path, token string) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(lockRefresh)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if ownsLock(path, token) {
+					now := time.Now()
+					os.Chtimes(path, now, now)
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+		if ownsLock(path, token) {
+			os.Remove(path)
+		}
+	}
+}
+
+// ownsLock reports whether the lock at path holds token.
+func ownsLock(path, token string) bool {
+	data, err := os.ReadFile(path)
+	return err == nil && string(data) == token
+}
+
+// breakLock removes the lock at path if it is older than LockWait and
+// reports whether it did. The lock is first renamed to a name only this
+// process uses, so of several processes breaking it only one succeeds,
+// and it is checked again there: a lock another process took between the
+// Stat and the Rename is fresh, and is put back. The renamed file ends in
+// .tmp, so Prune removes it if this process dies first.
+func breakLock(path, token string) bool {
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) <= LockWait {
+		return false
+	}
+	aside := path + "." + token + ".tmp"
+	if os.Rename(path, aside) != nil {
+		return false
+	}
+	defer os.Remove(aside)
+	if info, err := os.Stat(aside); err == nil && time.Since(info.ModTime()) > LockWait {
+		return true
+	}
+	os.Link(aside, path)
+	return false
+}
+
+// Prune removes the entries last used before cutoff, and temporary and
+// lock files left behind by processes that died writing them.
+func (c *Cache) Prune(cutoff time.Time) error {
+	var errs []error
+	filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || d.Name() == pruneMarker {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		stale := strings.HasSuffix(d.Name(), ".json") && info.ModTime().Before(cutoff)
+		abandoned := strings.HasSuffix(d.Name(), ".tmp") && time.Since(info.ModTime()) > time.Hour ||
+			strings.HasSuffix(d.Name(), ".lock") && time.Since(info.ModTime()) > LockWait
+		if stale || abandoned {
+			if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				errs = append(errs, err)
+			}
+		}
+		return nil
+	})
+	return errors.Join(errs...)
+}
@@ -0,0 +1,229 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestKey(t *testing.T) {
+	if Key("ab", "c") == Key("a", "bc") {
+		t.Error("keys of differently split parts are equal")
+	}
+	if Key("a", "b") != Key("a", "b") {
+		t.Error("keys of equal parts differ")
+	}
+	if Key() == Key("") {
+		t.Error("key of no parts equals key of one empty part")
+	}
+}
+
+func TestGetPut(t *testing.T) {
+	c, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	type entry struct{ Errors []string }
+
+	key := Key("check", "workflow Order():")
+	var got entry
+	if c.Get(key, &got) {
+		t.Fatal("hit in an empty cache")
+	}
+	want := entry{Errors: []string{"resolve error at 2:5: undefined activity: Missing"}}
+	if err := c.Put(key, want); err != nil {
+		t.Fatal(err)
+	}
+	if !c.Get(key, &got) || len(got.Errors) != 1 || got.Errors[0] != want.Errors[0] {
+		t.Fatalf("Get = %+v, want %+v", got, want)
+	}
+
+	// An entry that cannot be decoded is a miss, not an error.
+	if err := os.WriteFile(c.path(key), []byte("{truncated"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if c.Get(key, &got) {
+		t.Error("hit on a corrupt entry")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	c, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, fresh := Key("old"), Key("fresh")
+	for _, key := range []string{old, fresh} {
+		if err := c.Put(key, key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	longAgo := time.Now().Add(-2 * MaxAge)
+	if err := os.Chtimes(c.path(old), longAgo, longAgo); err != nil {
+		t.Fatal(err)
+	}
+	abandoned := filepath.Join(c.Dir(), "ab", "abandoned.123.tmp")
+	if err := os.MkdirAll(filepath.Dir(abandoned), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(abandoned, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(abandoned, longAgo, longAgo); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Prune(time.Now().Add(-MaxAge)); err != nil {
+		t.Fatal(err)
+	}
+	var v string
+	if c.Get(old, &v) {
+		t.Error("stale entry survived pruning")
+	}
+	if !c.Get(fresh, &v) || v != fresh {
+		t.Error("fresh entry was pruned")
+	}
+	if _, err := os.Stat(abandoned); !os.IsNotExist(err) {
+		t.Error("abandoned temporary file survived pruning")
+	}
+}
+
+func TestGetOrPutConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	key := Key("check", "workflow Order():")
+	var fills atomic.Int32
+	var wg sync.WaitGroup
+	results := make([]string, 8)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Each consumer opens the directory itself, as separate
+			// processes would.
+			c, err := Open(dir)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if _, err := c.GetOrPut(key, &results[i], func() {
+				fills.Add(1)
+				time.Sleep(100 * time.Millisecond)
+				results[i] = "analyzed"
+			}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := fills.Load(); n != 1 {
+		t.Errorf("entry filled %d times, want once", n)
+	}
+	for i, r := range results {
+		if r != "analyzed" {
+			t.Errorf("consumer %d got %q", i, r)
+		}
+	}
+	if locks, _ := filepath.Glob(filepath.Join(dir, "*", "*.lock")); len(locks) != 0 {
+		t.Errorf("locks left behind: %v", locks)
+	}
+}
+
+func TestGetOrPutBreaksAbandonedLock(t *testing.T) {
+	c, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := Key("abandoned")
+	lock := filepath.Join(c.Dir(), key[:2], key+".lock")
+	if err := os.MkdirAll(filepath.Dir(lock), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(lock, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	longAgo := time.Now().Add(-2 * LockWait)
+	if err := os.Chtimes(lock, longAgo, longAgo); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	var v string
+	hit, err := c.GetOrPut(key, &v, func() { v = "filled" })
+	if err != nil || hit || v != "filled" {
+		t.Fatalf("GetOrPut = %v, %v with v %q", hit, err, v)
+	}
+	if elapsed := time.Since(start); elapsed > LockWait/2 {
+		t.Errorf("waited %v on an abandoned lock", elapsed)
+	}
+	if hit, _ := c.GetOrPut(key, &v, func() { t.Error("filled a stored entry") }); !hit {
+		t.Error("miss after the entry was stored")
+	}
+}
+
+func TestLockRefreshedWhileHeld(t *testing.T) {
+	defer func(d time.Duration) { lockRefresh = d }(lockRefresh)
+	lockRefresh = 10 * time.Millisecond
+
+	lock := filepath.Join(t.TempDir(), "entry.lock")
+	unlock, ok := tryLock(lock)
+	if !ok {
+		t.Fatal("tryLock failed on a free lock")
+	}
+	// A fill running past LockWait: without refreshing, the lock would
+	// look abandoned and be broken.
+	longAgo := time.Now().Add(-2 * LockWait)
+	if err := os.Chtimes(lock, longAgo, longAgo); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, ok := tryLock(lock); ok {
+		t.Fatal("broke a lock whose holder is still filling")
+	}
+	unlock()
+	if _, err := os.Stat(lock); !os.IsNotExist(err) {
+		t.Errorf("lock left behind after unlock: %v", err)
+	}
+	if leftovers, _ := filepath.Glob(lock + ".*"); len(leftovers) != 0 {
+		t.Errorf("files left behind: %v", leftovers)
+	}
+}
+
+func TestUnlockLeavesAnotherHoldersLock(t *testing.T) {
+	lock := filepath.Join(t.TempDir(), "entry.lock")
+	unlock, ok := tryLock(lock)
+	if !ok {
+		t.Fatal("tryLock failed on a free lock")
+	}
+	// Another process broke the lock, thinking it abandoned, and took it.
+	if err := os.WriteFile(lock, []byte("other"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+	if data, err := os.ReadFile(lock); err != nil || string(data) != "other" {
+		t.Errorf("unlock removed the other holder's lock: %q, %v", data, err)
+	}
+}
+
+func TestBreakLock(t *testing.T) {
+	dir := t.TempDir()
+	lock := filepath.Join(dir, "entry.lock")
+	if err := os.WriteFile(lock, []byte("gone"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if breakLock(lock, "mine") {
+		t.Fatal("broke a fresh lock")
+	}
+	longAgo := time.Now().Add(-2 * LockWait)
+	if err := os.Chtimes(lock, longAgo, longAgo); err != nil {
+		t.Fatal(err)
+	}
+	if !breakLock(lock, "mine") {
+		t.Fatal("kept an abandoned lock")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("files left behind: %v", entries)
+	}
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"log/slog"
+	"path/filepath"
+	"strings"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/parser"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/token"
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// didChangeWatchedFilesHandler loads the keyword alias file again when the
+// client reports that it changed, then parses the workspace and the open
+// documents again. A file that no longer parses leaves the previous aliases
+// active; a deleted one removes them all. Clients report only the files they
+// watch, so the editor must watch the alias file.
+func didChangeWatchedFilesHandler(store *DocumentStore) protocol.WorkspaceDidChangeWatchedFilesFunc {
+	return func(context *glsp.Context, params *protocol.DidChangeWatchedFilesParams) error {
+		if store.AliasesPath == "" {
+			return nil
+		}
+		for _, change := range params.Changes {
+			if uriPath(change.URI) != filepath.Clean(store.AliasesPath) {
+				continue
+			}
+			lex := store.Lexer()
+			if change.Type == protocol.FileChangeTypeDeleted {
+				lex.Aliases = nil
+			} else if aliases, err := parser.LoadAliases(store.AliasesPath); err != nil {
+				slog.Warn("aliases", "outcome", "kept previous", "error", err.Error())
+				return nil
+			} else {
+				lex.Aliases = aliases
+			}
+			slog.Info("aliases", "outcome", "reloaded", "path", store.AliasesPath)
+			refreshInBackground(context, store, store.SetLexer(lex))
+			return nil
+		}
+		return nil
+	}
+}
+
+// aliasItems returns a completion item for each of aliases whose expansion
+// starts with the keyword of one of items, offered where that keyword is.
+func aliasItems(aliases token.Aliases, items []protocol.CompletionItem) []protocol.CompletionItem {
+	if len(aliases) == 0 {
+		return nil
+	}
+	offered := make(map[string]bool, len(items))
+	for _, item := range items {
+		offered[item.Label] = true
+	}
+	var out []protocol.CompletionItem
+	for _, name := range aliases.Names() {
+		expansion := aliases.Expansion(name)
+		if !offered[strings.Fields(expansion)[0]] {
+			continue
+		}
+		out = append(out, keywordItem(name, "Alias for "+expansion+" (experimental)"))
+	}
+	return out
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/lexer"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/token"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestAliasReparse(t *testing.T) {
+	const uri = "file:///sleep.twf"
+	content := "workflow Order():\n    sleep(5m)\n    \n"
+	store := NewDocumentStore()
+	first := func(doc *Document) ast.Statement {
+		return doc.File.Definitions[0].(*ast.WorkflowDef).Body[0]
+	}
+	if _, ok := first(store.Open(uri, 1, content)).(*ast.RawStmt); !ok {
+		t.Fatal("expected sleep to be a raw statement without aliases")
+	}
+
+	aliases, err := token.ParseAliases([]byte(`{"sleep": "await timer"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	analyses := store.SetLexer(lexer.Options{Aliases: aliases})
+	if len(analyses) != 1 {
+		t.Fatalf("expected the open document to be analyzed again, got %d analyses", len(analyses))
+	}
+	if doc, ok := analyses[0].Wait(); !ok || len(doc.ParseErrs) != 0 {
+		t.Fatalf("expected sleep to parse, got %v", doc.ParseErrs)
+	} else if _, ok := first(doc).(*ast.AwaitStmt); !ok {
+		t.Fatalf("expected sleep to lex as await timer, got %T", first(doc))
+	}
+
+	result, err := completionHandler(store)(nil, &protocol.CompletionParams{TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+		Position:     protocol.Position{Line: 2, Character: 4},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sleep *protocol.CompletionItem
+	for _, item := range result.(*protocol.CompletionList).Items {
+		if item.Label == "sleep" {
+			sleep = &item
+		}
+	}
+	if sleep == nil || sleep.Detail == nil || *sleep.Detail != "Alias for await timer (experimental)" {
+		t.Errorf("expected a completion for the sleep alias, got %+v", sleep)
+	}
+}
@@ -0,0 +1,269 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"runtime"
+	"runtime/pprof"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tliron/glsp"
+)
+
+// methodDebugBundle is the custom request writing a debug bundle, a zip to
+// attach to a bug report. It takes no params and answers a bundleResult.
+const methodDebugBundle = "twf/debugBundle"
+
+type bundleResult struct {
+	Path string `json:"path"`
+}
+
+// maxPanicBundles bounds the bundles written on panic in one session, so a
+// panic repeating on every keystroke does not fill the disk.
+const maxPanicBundles = 5
+
+// DebugBundle configures the debug bundles the server writes. A bundle
+// holds the server's version and flags, its status, the recent log, the
+// stacks of every goroutine, and a list of the open documents. Paths under
+// the user's home directory are written from ~.
+type DebugBundle struct {
+	// Dir is where a bundle is written each time the server recovers from
+	// a panic; empty for none. Requested bundles go to the temporary
+	// directory when it is empty.
+	Dir string
+	// Documents includes the content of the open documents, which the user
+	// opts in to since designs may be confidential.
+	Documents bool
+	// Version and Flags are the server's version and the command-line
+	// flags it was started with.
+	Version string
+	Flags   map[string]string
+	// Log holds the recent log records; nil for none.
+	Log *LogRecorder
+
+	panics atomic.Int32 // bundles written on panic so far
+}
+
+// handleDebugBundle answers twf/debugBundle, reporting whether method is it.
+func handleDebugBundle(store *DocumentStore, context *glsp.Context) (r any, ok bool, err error) {
+	if context.Method != methodDebugBundle {
+		return nil, false, nil
+	}
+	dir := store.Bundle.Dir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	path, err := writeBundle(store, dir, "requested", "")
+	if err != nil {
+		return nil, true, err
+	}
+	return bundleResult{Path: path}, true, nil
+}
+
+// panicBundle writes a bundle for a recovered panic, with the stack of the
+// goroutine that panicked, when bundles on panic are on.
+func panicBundle(store *DocumentStore, reason, stack string) {
+	if store == nil || store.Bundle.Dir == "" || store.Bundle.panics.Add(1) > maxPanicBundles {
+		return
+	}
+	if path, err := writeBundle(store, store.Bundle.Dir, reason, stack); err != nil {
+		slog.Warn("debug bundle", "error", err.Error())
+	} else {
+		slog.Error("debug bundle", "path", path)
+	}
+}
+
+// bundleInfo is info.json in a bundle.
+type bundleInfo struct {
+	Version   string            `json:"version"`
+	GoVersion string            `json:"goVersion"`
+	OS        string            `json:"os"`
+	Arch      string            `json:"arch"`
+	Time      time.Time         `json:"time"`
+	Reason    string            `json:"reason"`
+	Flags     map[string]string `json:"flags,omitempty"`
+	Status    statusResult      `json:"status"`
+}
+
+// bundleDocument is an entry of documents.json in a bundle.
+type bundleDocument struct {
+	URI         string `json:"uri"`
+	Version     int32  `json:"version"`
+	Bytes       int    `json:"bytes"`
+	Lines       int    `json:"lines"`
+	ParseErrors int    `json:"parseErrors"`
+	// File is the document's entry under documents/ when contents are
+	// included.
+	File string `json:"file,omitempty"`
+}
+
+// writeBundle writes a bundle to a new file in dir and returns its path.
+// reason says why it was written, and stack is the stack of a panic.
+func writeBundle(store *DocumentStore, dir, reason, stack string) (string, error) {
+	f, err := os.CreateTemp(dir, "twf-debug-"+time.Now().Format("20060102-150405")+"-*.zip")
+	if err != nil {
+		return "", err
+	}
+	home, _ := os.UserHomeDir()
+	sanitize := func(s string) string {
+		if home == "" || home == "/" {
+			return s
+		}
+		return strings.ReplaceAll(s, home, "~")
+	}
+
+	zw := zip.NewWriter(f)
+	add := func(name string, data []byte) error {
+		w, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write([]byte(sanitize(string(data))))
+		return err
+	}
+	addJSON := func(name string, v any) error {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		return add(name, data)
+	}
+
+	info := bundleInfo{
+		Version:   store.Bundle.Version,
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		Time:      time.Now(),
+		Reason:    reason,
+		Flags:     store.Bundle.Flags,
+		Status:    serverStatus(store),
+	}
+	var goroutines bytes.Buffer
+	pprof.Lookup("goroutine").WriteTo(&goroutines, 2)
+
+	errs := []error{
+		addJSON("info.json", info),
+		addJSON("documents.json", bundleDocuments(store, add)),
+		add("goroutines.txt", goroutines.Bytes()),
+	}
+	if stack != "" {
+		errs = append(errs, add("panic.txt", []byte(reason+"\n\n"+stack)))
+	}
+	if store.Bundle.Log != nil {
+		errs = append(errs, add("log.jsonl", store.Bundle.Log.Bytes()))
+	}
+	errs = append(errs, zw.Close(), f.Close())
+	for _, err := range errs {
+		if err != nil {
+			os.Remove(f.Name())
+			return "", err
+		}
+	}
+	return f.Name(), nil
+}
+
+// bundleDocuments lists the open documents, adding their content under
+// documents/ with add when the bundle includes it.
+func bundleDocuments(store *DocumentStore, add func(name string, data []byte) error) []bundleDocument {
+	store.mu.Lock()
+	docs := make([]*Document, 0, len(store.docs))
+	for _, doc := range store.docs {
+		docs = append(docs, doc)
+	}
+	store.mu.Unlock()
+	slices.SortFunc(docs, func(a, b *Document) int { return strings.Compare(a.URI, b.URI) })
+
+	out := make([]bundleDocument, 0, len(docs))
+	for i, doc := range docs {
+		bd := bundleDocument{
+			URI:         doc.URI,
+			Version:     doc.Version,
+			Bytes:       len(doc.Content),
+			Lines:       strings.Count(doc.Content, "\n") + 1,
+			ParseErrors: len(doc.ParseErrs),
+		}
+		if store.Bundle.Documents {
+			bd.File = fmt.Sprintf("documents/%d-%s", i+1, path.Base(doc.URI))
+			if add(bd.File, []byte(doc.Content)) != nil {
+				bd.File = ""
+			}
+		}
+		out = append(out, bd)
+	}
+	return out
+}
+
+// LogRecorder is a slog.Handler keeping the most recent records as JSON
+// lines, at every level, for debug bundles. It passes each record on to
+// the handler it wraps when that handler is enabled for its level.
+type LogRecorder struct {
+	next slog.Handler
+	enc  slog.Handler // writes to ring.buf
+	ring *logRing
+}
+
+type logRing struct {
+	mu    sync.Mutex
+	buf   bytes.Buffer
+	lines [][]byte // oldest first
+	max   int
+}
+
+// NewLogRecorder returns a LogRecorder keeping up to max records and
+// passing them on to next.
+func NewLogRecorder(next slog.Handler, max int) *LogRecorder {
+	ring := &logRing{max: max}
+	return &LogRecorder{
+		next: next,
+		enc:  slog.NewJSONHandler(&ring.buf, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		ring: ring,
+	}
+}
+
+func (r *LogRecorder) Enabled(context.Context, slog.Level) bool { return true }
+
+func (r *LogRecorder) Handle(ctx context.Context, rec slog.Record) error {
+	r.ring.mu.Lock()
+	r.ring.buf.Reset()
+	if r.enc.Handle(ctx, rec) == nil {
+		r.ring.lines = append(r.ring.lines, bytes.Clone(r.ring.buf.Bytes()))
+		if len(r.ring.lines) > r.ring.max {
+			r.ring.lines = r.ring.lines[len(r.ring.lines)-r.ring.max:]
+		}
+	}
+	r.ring.mu.Unlock()
+	if r.next.Enabled(ctx, rec.Level) {
+		return r.next.Handle(ctx, rec)
+	}
+	return nil
+}
+
+func (r *LogRecorder) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &LogRecorder{next: r.next.WithAttrs(attrs), enc: r.enc.WithAttrs(attrs), ring: r.ring}
+}
+
+func (r *LogRecorder) WithGroup(name string) slog.Handler {
+	return &LogRecorder{next: r.next.WithGroup(name), enc: r.enc.WithGroup(name), ring: r.ring}
+}
+
+// Bytes returns the records kept, oldest first, one JSON object a line.
+func (r *LogRecorder) Bytes() []byte {
+	r.ring.mu.Lock()
+	defer r.ring.mu.Unlock()
+	var b bytes.Buffer
+	for _, line := range r.ring.lines {
+		b.Write(line)
+	}
+	return b.Bytes()
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"archive/zip"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/tliron/glsp"
+	protocol317 "github.com/tliron/glsp/protocol_3_17"
+)
+
+func TestDebugBundle(t *testing.T) {
+	store := NewDocumentStore()
+	store.Open("file:///work/order.twf", 1, "workflow Order():\n    close complete\n")
+	store.Bundle.Version = "test"
+	store.Bundle.Flags = map[string]string{"log-level": "warn"}
+	store.Bundle.Log = NewLogRecorder(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelWarn}), 2)
+	logger := slog.New(store.Bundle.Log)
+	for _, method := range []string{"initialize", "textDocument/didOpen", "textDocument/hover"} {
+		logger.Debug("request", "method", method)
+	}
+	h := &Handler{Handler: &protocol317.Handler{}, store: store}
+
+	read := func(path string) map[string]string {
+		t.Helper()
+		zr, err := zip.OpenReader(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer zr.Close()
+		files := map[string]string{}
+		for _, f := range zr.File {
+			rc, _ := f.Open()
+			data, _ := io.ReadAll(rc)
+			rc.Close()
+			files[f.Name] = string(data)
+		}
+		return files
+	}
+
+	store.Bundle.Dir = t.TempDir()
+	r, _, _, err := h.Handle(&glsp.Context{Method: methodDebugBundle})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := read(r.(bundleResult).Path)
+	if !strings.Contains(files["info.json"], `"reason": "requested"`) || !strings.Contains(files["info.json"], `"log-level": "warn"`) {
+		t.Errorf("unexpected info.json: %s", files["info.json"])
+	}
+	if !strings.Contains(files["documents.json"], `"uri": "file:///work/order.twf"`) || strings.Contains(files["documents.json"], `"file"`) {
+		t.Errorf("expected the document listed without its content, got %s", files["documents.json"])
+	}
+	if log := files["log.jsonl"]; strings.Count(log, "\n") != 2 || strings.Contains(log, "initialize") || !strings.Contains(log, "textDocument/hover") {
+		t.Errorf("expected the last 2 debug records, got %q", log)
+	}
+	if !strings.Contains(files["goroutines.txt"], "goroutine") || files["panic.txt"] != "" {
+		t.Errorf("expected goroutine stacks and no panic, got %v", slices.Sorted(maps.Keys(files)))
+	}
+
+	store.Bundle.Documents = true
+	for range maxPanicBundles + 2 {
+		panicBundle(store, "panic in textDocument/hover: boom", "goroutine 1 [running]:")
+	}
+	entries, _ := os.ReadDir(store.Bundle.Dir)
+	if len(entries) != 1+maxPanicBundles {
+		t.Fatalf("expected %d bundles, got %d", 1+maxPanicBundles, len(entries))
+	}
+	panics := 0
+	for _, e := range entries {
+		files := read(filepath.Join(store.Bundle.Dir, e.Name()))
+		if files["panic.txt"] == "" {
+			continue
+		}
+		panics++
+		if files["documents/1-order.twf"] != "workflow Order():\n    close complete\n" || !strings.HasPrefix(files["panic.txt"], "panic in textDocument/hover: boom") {
+			t.Errorf("expected the document and the panic, got %v", slices.Sorted(maps.Keys(files)))
+		}
+	}
+	if panics != maxPanicBundles {
+		t.Errorf("expected %d bundles for panics, got %d", maxPanicBundles, panics)
+	}
+}
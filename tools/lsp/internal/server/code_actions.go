@@ -2,10 +2,13 @@ package server
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/fix"
 	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/resolver"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/validator"
 	"github.com/tliron/glsp"
 	protocol "github.com/tliron/glsp/protocol_3_16"
 )
@@ -22,6 +25,14 @@ func codeActionHandler(store *DocumentStore) protocol.TextDocumentCodeActionFunc
 		// Collect all available code actions based on diagnostics and context
 		actions = append(actions, addMissingDefinitionActions(doc, params)...)
 		actions = append(actions, convertReturnToCloseActions(doc, params)...)
+		actions = append(actions, convertHintActions(doc, params)...)
+		actions = append(actions, addAnnotationActions(doc, params)...)
+		actions = append(actions, addEnumCaseActions(doc, params)...)
+		actions = append(actions, applySuggestionActions(doc, params)...)
+		actions = append(actions, wrapCloseValueActions(doc, params)...)
+		actions = append(actions, addTimeoutActions(doc, params)...)
+		actions = append(actions, addActivityTimeoutActions(store, doc, params)...)
+		actions = append(actions, reorderAwaitOneActions(store, doc, params)...)
 
 		// Return as interface slice for JSON encoding
 		result := make([]any, len(actions))
@@ -67,20 +78,7 @@ func addMissingDefinitionActions(doc *Document, params *protocol.CodeActionParam
 			}
 
 			// Generate the definition
-			var def string
-			if kind == "activity" {
-				if returnType != "" {
-					def = fmt.Sprintf("\nactivity %s(%s) -> (%s):\n    # TODO: implement\n    return result\n", name, params, returnType)
-				} else {
-					def = fmt.Sprintf("\nactivity %s(%s):\n    # TODO: implement\n", name, params)
-				}
-			} else {
-				if returnType != "" {
-					def = fmt.Sprintf("\nworkflow %s(%s) -> (%s):\n    # TODO: implement\n    close result\n", name, params, returnType)
-				} else {
-					def = fmt.Sprintf("\nworkflow %s(%s):\n    # TODO: implement\n    close\n", name, params)
-				}
-			}
+			def := "\n" + definitionStub(kind, name, params, returnType)
 
 			// Insert at end of file
 			lines := strings.Split(doc.Content, "\n")
@@ -110,58 +108,380 @@ func addMissingDefinitionActions(doc *Document, params *protocol.CodeActionParam
 	return actions
 }
 
-// convertReturnToCloseActions suggests converting old return statements to close
+// definitionStub returns the source of an empty activity or workflow
+// definition, returning or closing with a placeholder result when returnType
+// is set.
+func definitionStub(kind, name, params, returnType string) string {
+	if kind == "activity" {
+		if returnType != "" {
+			return fmt.Sprintf("activity %s(%s) -> (%s):\n    # TODO: implement\n    return result\n", name, params, returnType)
+		}
+		return fmt.Sprintf("activity %s(%s):\n    # TODO: implement\n", name, params)
+	}
+	if returnType != "" {
+		return fmt.Sprintf("workflow %s(%s) -> (%s):\n    # TODO: implement\n    close complete(result)\n", name, params, returnType)
+	}
+	return fmt.Sprintf("workflow %s(%s):\n    # TODO: implement\n    close complete\n", name, params)
+}
+
+// convertReturnToCloseActions converts the return statements in workflow
+// bodies in range to close complete. Where the deprecated return diagnostic
+// reports one, the action is its quick fix.
 func convertReturnToCloseActions(doc *Document, params *protocol.CodeActionParams) []protocol.CodeAction {
 	var actions []protocol.CodeAction
 
-	// Look for return statements in workflow bodies (not in signal/query/update handlers)
-	for _, def := range doc.File.Definitions {
-		wf, ok := def.(*ast.WorkflowDef)
-		if !ok {
-			continue
+	deprecated := make(map[int]bool)
+	for _, err := range doc.ValidateErrs {
+		if err.Kind == validator.ErrDeprecatedReturn {
+			deprecated[err.Line] = true
 		}
+	}
 
-		// Check workflow body for return statements
-		returns := findReturnStatements(wf.Body)
-		for _, ret := range returns {
-			retRange := lineRange(ret.Line, ret.Line)
-			if !rangesOverlap(params.Range, retRange) {
-				continue
+	for _, e := range fix.ReturnToClose(doc.File, doc.Content) {
+		rng := editRange(e)
+		if !rangesOverlap(params.Range, rng) {
+			continue
+		}
+		action := protocol.CodeAction{
+			Title: "Convert 'return' to 'close'",
+			Kind:  ptrTo(protocol.CodeActionKindRefactor),
+			Edit: &protocol.WorkspaceEdit{
+				Changes: map[string][]protocol.TextEdit{
+					doc.URI: {{Range: rng, NewText: e.NewText}},
+				},
+			},
+		}
+		if deprecated[e.Line] {
+			action.Kind = ptrTo(protocol.CodeActionKindQuickFix)
+			action.IsPreferred = ptrTo(true)
+			for _, d := range params.Context.Diagnostics {
+				if d.Range.Start.Line == rng.Start.Line && strings.Contains(d.Message, "is deprecated; use close") {
+					action.Diagnostics = append(action.Diagnostics, d)
+				}
 			}
+		}
+		actions = append(actions, action)
+	}
 
-			// Suggest converting to close
-			var newText string
-			if ret.Value != "" {
-				// return Foo{} -> close Foo{}
-				newText = fmt.Sprintf("    close %s", ret.Value)
-			} else {
-				newText = "    close"
+	return actions
+}
+
+// convertHintActions replaces the hint statements in range, which the
+// language no longer has, with the await one blocks they stood for, as
+// the quick fix of their diagnostics.
+func convertHintActions(doc *Document, params *protocol.CodeActionParams) []protocol.CodeAction {
+	var actions []protocol.CodeAction
+	for _, e := range fix.HintToAwait(doc.File, doc.Content) {
+		rng := editRange(e)
+		if !rangesOverlap(params.Range, rng) {
+			continue
+		}
+		action := protocol.CodeAction{
+			Title:       "Convert 'hint' to 'await one'",
+			Kind:        ptrTo(protocol.CodeActionKindQuickFix),
+			IsPreferred: ptrTo(true),
+			Edit: &protocol.WorkspaceEdit{
+				Changes: map[string][]protocol.TextEdit{
+					doc.URI: {{Range: rng, NewText: e.NewText}},
+				},
+			},
+		}
+		for _, d := range params.Context.Diagnostics {
+			if d.Range.Start.Line == rng.Start.Line && strings.HasPrefix(d.Message, "hint was removed") {
+				action.Diagnostics = append(action.Diagnostics, d)
 			}
+		}
+		actions = append(actions, action)
+	}
+	return actions
+}
 
-			action := protocol.CodeAction{
-				Title: "Convert 'return' to 'close'",
-				Kind:  ptrTo(protocol.CodeActionKindRefactor),
-				Edit: &protocol.WorkspaceEdit{
-					Changes: map[string][]protocol.TextEdit{
-						doc.URI: {
-							{
-								Range: protocol.Range{
-									Start: protocol.Position{Line: uint32(ret.Line - 1), Character: 0},
-									End:   protocol.Position{Line: uint32(ret.Line - 1), Character: 1000},
-								},
-								NewText: newText,
-							},
+// addAnnotationActions creates code actions that insert the annotations a
+// workflow is missing under the store's policy, above its header.
+func addAnnotationActions(doc *Document, params *protocol.CodeActionParams) []protocol.CodeAction {
+	var actions []protocol.CodeAction
+
+	// A workflow missing both @owner and @sla gets one action inserting both.
+	missing := make(map[string][]string)
+	var order []*validator.Error
+	for _, err := range doc.ValidateErrs {
+		var template string
+		switch err.Kind {
+		case validator.ErrMissingOwner:
+			template = "@owner(\"TODO\")"
+		case validator.ErrMissingSLA:
+			template = "@sla(TODO)"
+		default:
+			continue
+		}
+		if !rangesOverlap(params.Range, posToRange(err.Line, err.Column)) {
+			continue
+		}
+		if missing[err.Name] == nil {
+			order = append(order, err)
+		}
+		missing[err.Name] = append(missing[err.Name], template)
+	}
+
+	for _, err := range order {
+		header := protocol.Position{Line: uint32(err.Line - 1), Character: 0}
+		actions = append(actions, protocol.CodeAction{
+			Title: fmt.Sprintf("Add %s to workflow '%s'", strings.Join(missing[err.Name], " "), err.Name),
+			Kind:  ptrTo(protocol.CodeActionKindQuickFix),
+			Edit: &protocol.WorkspaceEdit{
+				Changes: map[string][]protocol.TextEdit{
+					doc.URI: {
+						{
+							Range:   protocol.Range{Start: header, End: header},
+							NewText: strings.Join(missing[err.Name], "\n") + "\n",
 						},
 					},
 				},
+			},
+		})
+	}
+
+	return actions
+}
+
+// addEnumCaseActions creates code actions that add a stub case for each
+// value a switch on an enum is missing, after its last case.
+func addEnumCaseActions(doc *Document, params *protocol.CodeActionParams) []protocol.CodeAction {
+	var actions []protocol.CodeAction
+
+	for _, err := range doc.ValidateErrs {
+		if err.Kind != validator.ErrNonExhaustiveSwitch || doc.Symbols == nil {
+			continue
+		}
+		if !rangesOverlap(params.Range, posToRange(err.Line, err.Column)) {
+			continue
+		}
+		enum := doc.Symbols.Enums[err.Name]
+		sw := findSwitchAtLine(doc.File, err.Line)
+		if enum == nil || sw == nil {
+			continue
+		}
+		missing := validator.MissingEnumCases(sw, enum)
+		if len(missing) == 0 {
+			continue
+		}
+
+		caseIndent := strings.Repeat(" ", sw.Cases[0].Column-1)
+		bodyIndent := caseIndent + strings.Repeat(" ", sw.Cases[0].Column-sw.Column)
+		var stubs strings.Builder
+		for _, name := range missing {
+			fmt.Fprintf(&stubs, "%scase %s:\n%s# TODO: handle %s\n", caseIndent, name, bodyIndent, name)
+		}
+
+		end := protocol.Position{Line: uint32(blockEnd(doc.Content, sw.Line, sw.Column-1)), Character: 0}
+		actions = append(actions, protocol.CodeAction{
+			Title: fmt.Sprintf("Add missing cases for %s", enum.Name),
+			Kind:  ptrTo(protocol.CodeActionKindQuickFix),
+			Edit: &protocol.WorkspaceEdit{
+				Changes: map[string][]protocol.TextEdit{
+					doc.URI: {
+						{
+							Range:   protocol.Range{Start: end, End: end},
+							NewText: stubs.String(),
+						},
+					},
+				},
+			},
+		})
+	}
+
+	return actions
+}
+
+// applySuggestionActions creates code actions that replace the name a
+// diagnostic is about with its suggestion: an unknown name with the
+// declared name it is likely a misspelling of, or a keyword such as IF
+// with its lowercase spelling.
+func applySuggestionActions(doc *Document, params *protocol.CodeActionParams) []protocol.CodeAction {
+	var actions []protocol.CodeAction
+
+	for _, err := range doc.ValidateErrs {
+		if err.Kind != validator.ErrUnknownName && err.Kind != validator.ErrKeywordCase || err.Suggestion == "" {
+			continue
+		}
+		rng := posToRange(err.Line, err.Column)
+		if !rangesOverlap(params.Range, rng) {
+			continue
+		}
+		rng.End.Character = rng.Start.Character + uint32(len(err.Name))
+		actions = append(actions, protocol.CodeAction{
+			Title: fmt.Sprintf("Change %s to %s", err.Name, err.Suggestion),
+			Kind:  ptrTo(protocol.CodeActionKindQuickFix),
+			Edit: &protocol.WorkspaceEdit{
+				Changes: map[string][]protocol.TextEdit{
+					doc.URI: {{Range: rng, NewText: err.Suggestion}},
+				},
+			},
+		})
+	}
+
+	return actions
+}
+
+// wrapCloseValueActions creates code actions that wrap a close complete
+// value not of the workflow's return type in a literal of that type.
+func wrapCloseValueActions(doc *Document, params *protocol.CodeActionParams) []protocol.CodeAction {
+	var actions []protocol.CodeAction
+
+	for _, err := range doc.ValidateErrs {
+		if err.Kind != validator.ErrCloseValueMismatch || err.Suggestion == "" {
+			continue
+		}
+		rng := posToRange(err.Line, err.Column)
+		if !rangesOverlap(params.Range, rng) {
+			continue
+		}
+		// The fix replaces the close's single value, wherever in the
+		// parentheses it starts and however many lines it spans.
+		c := findCloseAtLine(doc.File, err.Line)
+		if c == nil || len(c.ArgExprs) != 1 {
+			continue
+		}
+		value := strings.TrimSpace(c.Args)
+		i := strings.Index(c.Args, value)
+		start := ast.TextPos(c.Args, c.ArgsPos, i)
+		end := ast.TextPos(c.Args, c.ArgsPos, i+len(value))
+		rng = protocol.Range{
+			Start: posToRange(start.Line, start.Column).Start,
+			End:   posToRange(end.Line, end.Column).Start,
+		}
+		actions = append(actions, protocol.CodeAction{
+			Title: fmt.Sprintf("Wrap value in %s", err.Suggestion[:strings.IndexByte(err.Suggestion, '{')]),
+			Kind:  ptrTo(protocol.CodeActionKindQuickFix),
+			Edit: &protocol.WorkspaceEdit{
+				Changes: map[string][]protocol.TextEdit{
+					doc.URI: {{Range: rng, NewText: err.Suggestion}},
+				},
+			},
+		})
+	}
+
+	return actions
+}
+
+// addTimeoutActions creates code actions that bound a call starting a
+// critical workflow by the workflow's @sla, as twf fix does.
+func addTimeoutActions(doc *Document, params *protocol.CodeActionParams) []protocol.CodeAction {
+	var actions []protocol.CodeAction
+
+	for _, err := range doc.ValidateErrs {
+		if err.Kind != validator.ErrMissingTimeout || !rangesOverlap(params.Range, posToRange(err.Line, err.Column)) {
+			continue
+		}
+		call := findCallAtLine[*ast.WorkflowCall](doc.File, err.Line)
+		if call == nil {
+			continue
+		}
+		e, ok := fix.MissingTimeout(doc.Content, call)
+		if !ok {
+			continue
+		}
+		actions = append(actions, fixAction(fmt.Sprintf("Set workflow_execution_timeout to the @sla of '%s'", err.Name), doc.URI, e))
+	}
+
+	return actions
+}
+
+// addActivityTimeoutActions creates code actions that set the
+// start_to_close_timeout an activity runs without: at the call, when it is
+// one that takes options, or in the activity's definition for every call,
+// wherever in the workspace it is defined.
+func addActivityTimeoutActions(store *DocumentStore, doc *Document, params *protocol.CodeActionParams) []protocol.CodeAction {
+	var actions []protocol.CodeAction
+
+	for _, err := range doc.ValidateErrs {
+		if err.Kind != validator.ErrMissingActivityTimeout || !rangesOverlap(params.Range, posToRange(err.Line, err.Column)) {
+			continue
+		}
+		if call := findCallAtLine[*ast.ActivityCall](doc.File, err.Line); call != nil {
+			if e, ok := fix.ActivityTimeoutAtCall(doc.Content, call); ok {
+				actions = append(actions, fixAction(fmt.Sprintf("Set start_to_close_timeout: %s at this call", fix.ActivityTimeout), doc.URI, e))
 			}
-			actions = append(actions, action)
+		}
+		if doc.Symbols == nil {
+			continue
+		}
+		def := doc.Symbols.Activities[err.Name]
+		if def == nil {
+			continue
+		}
+		uri, content, ok := definitionSource(store, doc, def)
+		if !ok {
+			continue
+		}
+		if e, ok := fix.ActivityTimeoutAtDefinition(content, def); ok {
+			actions = append(actions, fixAction(fmt.Sprintf("Set start_to_close_timeout: %s for every call of '%s'", fix.ActivityTimeout, def.Name), uri, e))
 		}
 	}
 
 	return actions
 }
 
+// reorderAwaitOneActions moves the cases of the await one blocks reported
+// out of order in range into the order the store's policy asks for.
+func reorderAwaitOneActions(store *DocumentStore, doc *Document, params *protocol.CodeActionParams) []protocol.CodeAction {
+	var actions []protocol.CodeAction
+	mode := store.Policy.AwaitOneOrder
+	for _, err := range doc.ValidateErrs {
+		if err.Kind != validator.ErrAwaitOneOrder || len(err.Related) == 0 || !rangesOverlap(params.Range, posToRange(err.Line, err.Column)) {
+			continue
+		}
+		block := findAwaitOneAtLine(doc.File, err.Related[0].Line)
+		if block == nil {
+			continue
+		}
+		if e, ok := fix.AwaitOneOrder(doc.Content, block, mode); ok {
+			action := fixAction(fmt.Sprintf("Reorder await one cases (%s)", mode), doc.URI, e)
+			action.IsPreferred = ptrTo(true)
+			for _, d := range params.Context.Diagnostics {
+				if d.Range.Start.Line == uint32(err.Line-1) && strings.Contains(d.Message, "case of await one comes after") {
+					action.Diagnostics = append(action.Diagnostics, d)
+				}
+			}
+			actions = append(actions, action)
+		}
+	}
+	return actions
+}
+
+// fixAction is the quick fix titled title applying e to the file at uri.
+func fixAction(title, uri string, e fix.Edit) protocol.CodeAction {
+	rng := editRange(e)
+	return protocol.CodeAction{
+		Title: title,
+		Kind:  ptrTo(protocol.CodeActionKindQuickFix),
+		Edit: &protocol.WorkspaceEdit{
+			Changes: map[string][]protocol.TextEdit{
+				uri: {{Range: rng, NewText: e.NewText}},
+			},
+		},
+	}
+}
+
+// editRange returns the range e replaces.
+func editRange(e fix.Edit) protocol.Range {
+	return protocol.Range{
+		Start: protocol.Position{Line: uint32(e.Line - 1), Character: uint32(e.Column - 1)},
+		End:   protocol.Position{Line: uint32(e.End() - 1), Character: uint32(e.EndColumn - 1)},
+	}
+}
+
+// definitionSource returns the URI and content of the file def was parsed
+// from: doc, or a workspace file doc resolves against.
+func definitionSource(store *DocumentStore, doc *Document, def ast.Definition) (uri, content string, ok bool) {
+	if slices.Contains(doc.File.Definitions, def) {
+		return doc.URI, doc.Content, true
+	}
+	uri = ast.SourceFile(def)
+	content, ok = store.Workspace.content(uri)
+	return uri, content, ok
+}
+
 // Helper functions
 
 func rangesOverlap(a, b protocol.Range) bool {
@@ -192,13 +512,106 @@ func findCallInStatements(stmts []ast.Statement, name string) *ast.ActivityCall
 	return found
 }
 
-func findReturnStatements(stmts []ast.Statement) []*ast.ReturnStmt {
-	var returns []*ast.ReturnStmt
-	ast.WalkStatements(stmts, func(s ast.Statement) bool {
-		if ret, ok := s.(*ast.ReturnStmt); ok {
-			returns = append(returns, ret)
+func findSwitchAtLine(file *ast.File, line int) *ast.SwitchBlock {
+	var found *ast.SwitchBlock
+	for _, def := range file.Definitions {
+		wf, ok := def.(*ast.WorkflowDef)
+		if !ok || found != nil {
+			continue
 		}
-		return true
-	})
-	return returns
+		for _, body := range ast.WorkflowBodies(wf) {
+			ast.WalkStatements(body, func(s ast.Statement) bool {
+				if sw, ok := s.(*ast.SwitchBlock); ok && sw.Line == line {
+					found = sw
+				}
+				return found == nil
+			})
+		}
+	}
+	return found
+}
+
+func findAwaitOneAtLine(file *ast.File, line int) *ast.AwaitOneBlock {
+	var found *ast.AwaitOneBlock
+	for _, def := range file.Definitions {
+		wf, ok := def.(*ast.WorkflowDef)
+		if !ok || found != nil {
+			continue
+		}
+		for _, body := range ast.WorkflowBodies(wf) {
+			ast.WalkStatements(body, func(s ast.Statement) bool {
+				if b, ok := s.(*ast.AwaitOneBlock); ok && b.Line == line {
+					found = b
+				}
+				return found == nil
+			})
+		}
+	}
+	return found
+}
+
+func findCloseAtLine(file *ast.File, line int) *ast.CloseStmt {
+	var found *ast.CloseStmt
+	for _, def := range file.Definitions {
+		wf, ok := def.(*ast.WorkflowDef)
+		if !ok || found != nil {
+			continue
+		}
+		for _, body := range ast.WorkflowBodies(wf) {
+			ast.WalkStatements(body, func(s ast.Statement) bool {
+				if c, ok := s.(*ast.CloseStmt); ok && c.Line <= line && line <= max(c.Line, ast.TextPos(c.Args, c.ArgsPos, len(c.Args)).Line) {
+					found = c
+				}
+				return found == nil
+			})
+		}
+	}
+	return found
+}
+
+// findCallAtLine returns the call statement of type C on line, in the
+// workflow and nexus operation bodies of file, or nil.
+func findCallAtLine[C interface {
+	*ast.WorkflowCall | *ast.ActivityCall
+	ast.Statement
+}](file *ast.File, line int) C {
+	var found C
+	visit := func(s ast.Statement) bool {
+		if c, ok := s.(C); ok && s.NodeLine() == line {
+			found = c
+		}
+		return found == nil
+	}
+	for _, def := range file.Definitions {
+		switch def := def.(type) {
+		case *ast.WorkflowDef:
+			for _, body := range ast.WorkflowBodies(def) {
+				ast.WalkStatements(body, visit)
+			}
+		case *ast.NexusServiceDef:
+			for _, op := range def.Operations {
+				ast.WalkStatements(op.Body, visit)
+			}
+		}
+	}
+	return found
+}
+
+// blockEnd returns the 0-based line just after the last non-blank line of
+// the block whose header is on the 1-based line header, indented by indent
+// columns: the block ends at the next line indented no deeper.
+func blockEnd(content string, header, indent int) int {
+	lines := strings.Split(content, "\n")
+	last := header
+	for i := header; i < len(lines); i++ {
+		text := strings.TrimRight(lines[i], " \t\r")
+		if text == "" {
+			continue
+		}
+		if len(text)-len(strings.TrimLeft(text, " \t")) <= indent {
+			break
+		}
+		last = i + 1
+	}
+	return last
 }
@@ -0,0 +1,350 @@
+package server
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/lexer"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/parser"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/validator"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestAnnotationQuickFix(t *testing.T) {
+	store := NewDocumentStore()
+	store.Policy = validator.Policy{RequireOwner: true, CriticalTag: "critical"}
+	doc := store.Open("file:///a.twf", 1, "@tag(critical)\nworkflow A():\n    activity X()\n\nactivity X():\n    return\n")
+	if len(doc.ValidateErrs) != 2 {
+		t.Fatalf("expected missing @owner and @sla errors, got %v", doc.ValidateErrs)
+	}
+
+	actions := addAnnotationActions(doc, &protocol.CodeActionParams{Range: lineRange(2, 2)})
+	if len(actions) != 1 {
+		t.Fatalf("expected one action, got %d", len(actions))
+	}
+	edit := actions[0].Edit.Changes["file:///a.twf"][0]
+	if edit.Range.Start.Line != 1 || edit.NewText != "@owner(\"TODO\")\n@sla(TODO)\n" {
+		t.Errorf("unexpected edit: %+v", edit)
+	}
+	if actions := addAnnotationActions(doc, &protocol.CodeActionParams{Range: lineRange(5, 5)}); len(actions) != 0 {
+		t.Errorf("expected no actions away from the header, got %d", len(actions))
+	}
+}
+
+func TestTimeoutQuickFix(t *testing.T) {
+	content := `@tag(critical)
+@sla(24h)
+workflow Fulfill():
+    close complete
+
+workflow Order():
+    workflow Fulfill()
+    close complete
+`
+	store := NewDocumentStore()
+	store.Policy = validator.Policy{CriticalTag: "critical"}
+	doc := store.Open("file:///a.twf", 1, content)
+	actions := addTimeoutActions(doc, &protocol.CodeActionParams{Range: lineRange(7, 7)})
+	if len(actions) != 1 {
+		t.Fatalf("expected one action, got %v (%v)", actions, doc.ValidateErrs)
+	}
+	edit := actions[0].Edit.Changes["file:///a.twf"][0]
+	want := "\n        options:\n            workflow_execution_timeout: 24h"
+	if edit.NewText != want || edit.Range.Start != (protocol.Position{Line: 6, Character: 22}) || edit.Range.End != edit.Range.Start {
+		t.Errorf("unexpected edit: %+v", edit)
+	}
+	if actions := addTimeoutActions(doc, &protocol.CodeActionParams{Range: lineRange(3, 3)}); len(actions) != 0 {
+		t.Errorf("expected no actions away from the call, got %d", len(actions))
+	}
+}
+
+func TestActivityTimeoutQuickFix(t *testing.T) {
+	dir := writeWorkspace(t, map[string]string{
+		"charge.twf": "activity Charge():\n    return\n",
+	})
+	store := NewDocumentStore()
+	store.Policy = validator.Policy{RequireActivityTimeout: true}
+	store.Workspace.AddFolder(pathURI(dir))
+	order, charge := pathURI(filepath.Join(dir, "order.twf")), pathURI(filepath.Join(dir, "charge.twf"))
+	doc := store.Open(order, 1, "workflow Order():\n    activity Charge()\n    close complete\n")
+	actions := addActivityTimeoutActions(store, doc, &protocol.CodeActionParams{Range: lineRange(1, 1)})
+	if len(actions) != 2 {
+		t.Fatalf("expected two actions, got %v (%v)", actions, doc.ValidateErrs)
+	}
+	call := actions[0].Edit.Changes[order][0]
+	if call.NewText != "\n        options:\n            start_to_close_timeout: 1m" || call.Range.Start != (protocol.Position{Line: 1, Character: 21}) {
+		t.Errorf("unexpected call edit: %+v", call)
+	}
+	def := actions[1].Edit.Changes[charge]
+	if len(def) != 1 || def[0].NewText != "    options:\n        start_to_close_timeout: 1m\n" || def[0].Range.Start != (protocol.Position{Line: 1}) {
+		t.Errorf("unexpected definition edit: %+v (%v)", def, actions[1].Edit.Changes)
+	}
+	if actions := addActivityTimeoutActions(store, doc, &protocol.CodeActionParams{Range: lineRange(0, 0)}); len(actions) != 0 {
+		t.Errorf("expected no actions away from the call, got %d", len(actions))
+	}
+}
+
+func TestAwaitOneOrderQuickFix(t *testing.T) {
+	const uri = "file:///order.twf"
+	content := `workflow Order():
+    signal Cancel():
+        cancelled = true
+    await one:
+        timer(1h):
+            close fail("late")
+        signal Cancel:
+            close complete
+`
+	store := NewDocumentStore()
+	store.Policy = validator.Policy{AwaitOneOrder: validator.AwaitOneOrderTimerLast}
+	doc := store.Open(uri, 1, content)
+	actions := reorderAwaitOneActions(store, doc, &protocol.CodeActionParams{Range: lineRange(6, 6)})
+	if len(actions) != 1 {
+		t.Fatalf("expected one action, got %v (%v)", actions, doc.ValidateErrs)
+	}
+	edits := actions[0].Edit.Changes[uri]
+	want := "        signal Cancel:\n            close complete\n        timer(1h):\n            close fail(\"late\")"
+	if len(edits) != 1 || edits[0].NewText != want || edits[0].Range.Start != (protocol.Position{Line: 4}) || edits[0].Range.End != (protocol.Position{Line: 7, Character: 26}) {
+		t.Errorf("unexpected edit: %+v", edits)
+	}
+	if actions := reorderAwaitOneActions(store, doc, &protocol.CodeActionParams{Range: lineRange(4, 4)}); len(actions) != 0 {
+		t.Errorf("expected no actions away from the case, got %d", len(actions))
+	}
+}
+
+func TestUnknownNameQuickFix(t *testing.T) {
+	content := `workflow A():
+    state:
+        status: string = "new"
+
+    signal Pay():
+        statu = "paid"
+
+    close complete
+`
+	store := NewDocumentStore()
+	store.Policy = validator.Policy{UnknownNames: validator.UnknownNamesTypos}
+	doc := store.Open("file:///a.twf", 1, content)
+	actions := applySuggestionActions(doc, &protocol.CodeActionParams{Range: lineRange(6, 6)})
+	if len(actions) != 1 || actions[0].Title != "Change statu to status" {
+		t.Fatalf("expected one action, got %v (%v)", actions, doc.ValidateErrs)
+	}
+	edit := actions[0].Edit.Changes["file:///a.twf"][0]
+	if edit.NewText != "status" || edit.Range.Start != (protocol.Position{Line: 5, Character: 8}) || edit.Range.End.Character != 13 {
+		t.Errorf("unexpected edit: %+v", edit)
+	}
+}
+
+func TestKeywordCaseQuickFix(t *testing.T) {
+	store := NewDocumentStore()
+	store.SetLexer(lexer.Options{CaseInsensitiveKeywords: true})
+	doc := store.Open("file:///a.twf", 1, "Workflow A():\n    close complete\n")
+	actions := applySuggestionActions(doc, &protocol.CodeActionParams{Range: lineRange(0, 0)})
+	if len(actions) != 1 || actions[0].Title != "Change Workflow to workflow" {
+		t.Fatalf("expected one action, got %v (%v, %v)", actions, doc.ParseErrs, doc.ValidateErrs)
+	}
+	edit := actions[0].Edit.Changes["file:///a.twf"][0]
+	if edit.NewText != "workflow" || edit.Range.Start != (protocol.Position{}) || edit.Range.End.Character != 8 {
+		t.Errorf("unexpected edit: %+v", edit)
+	}
+}
+
+func TestEnumCaseQuickFix(t *testing.T) {
+	content := `enum OrderType: invoice, refund, subscription
+
+workflow A(kind: OrderType):
+    switch (kind):
+        case refund:
+            activity X()
+                options:
+                    start_to_close_timeout: 1m
+
+    close complete
+
+activity X():
+    return
+`
+	store := NewDocumentStore()
+	doc := store.Open("file:///a.twf", 1, content)
+	actions := addEnumCaseActions(doc, &protocol.CodeActionParams{Range: lineRange(4, 4)})
+	if len(actions) != 1 {
+		t.Fatalf("expected one action, got %d (%v)", len(actions), doc.ValidateErrs)
+	}
+	edit := actions[0].Edit.Changes["file:///a.twf"][0]
+	want := "        case invoice:\n            # TODO: handle invoice\n        case subscription:\n            # TODO: handle subscription\n"
+	if edit.Range.Start.Line != 8 || edit.NewText != want {
+		t.Fatalf("unexpected edit at line %d: %q", edit.Range.Start.Line, edit.NewText)
+	}
+
+	lines := strings.SplitAfter(content, "\n")
+	fixed := strings.Join(lines[:8], "") + edit.NewText + strings.Join(lines[8:], "")
+	doc, ok := store.Update("file:///a.twf", 2, fixed).Wait()
+	if !ok {
+		t.Fatal("expected the fixed document to be analyzed")
+	}
+	for _, e := range doc.ValidateErrs {
+		if e.Kind == validator.ErrNonExhaustiveSwitch {
+			t.Errorf("expected the fix to cover every value, got %s", e.Msg)
+		}
+	}
+	if len(doc.ParseErrs) != 0 {
+		t.Errorf("expected the fixed switch to parse, got %v", doc.ParseErrs)
+	}
+}
+
+func TestCloseValueQuickFix(t *testing.T) {
+	content := `workflow A() -> (OrderResult):
+    close complete({status: "done"})
+`
+	store := NewDocumentStore()
+	doc := store.Open("file:///a.twf", 1, content)
+	actions := wrapCloseValueActions(doc, &protocol.CodeActionParams{Range: lineRange(2, 2)})
+	if len(actions) != 1 || actions[0].Title != "Wrap value in OrderResult" {
+		t.Fatalf("expected one action, got %v (%v)", actions, doc.ValidateErrs)
+	}
+	edit := actions[0].Edit.Changes["file:///a.twf"][0]
+	if edit.NewText != `OrderResult{status: "done"}` || edit.Range.Start != (protocol.Position{Line: 1, Character: 19}) || edit.Range.End.Character != 35 {
+		t.Fatalf("unexpected edit: %+v", edit)
+	}
+
+	line := strings.Split(content, "\n")[1]
+	fixed := strings.Replace(content, line, line[:edit.Range.Start.Character]+edit.NewText+line[edit.Range.End.Character:], 1)
+	doc, ok := store.Update("file:///a.twf", 2, fixed).Wait()
+	if !ok {
+		t.Fatal("expected the fixed document to be analyzed")
+	}
+	for _, e := range doc.ValidateErrs {
+		if e.Kind == validator.ErrCloseValueMismatch {
+			t.Errorf("expected the fix to match the return type, got %s", e.Msg)
+		}
+	}
+}
+
+// The quick fix finds the value from the position of the close's
+// arguments, so tabs, extra spaces, and line breaks inside the
+// parentheses do not shift its range.
+func TestCloseValueQuickFixSpacing(t *testing.T) {
+	tests := []struct {
+		name       string
+		close      string
+		line       int
+		start, end protocol.Position
+	}{
+		{"tab", "close complete(\t{status: \"done\"})", 2, protocol.Position{Line: 1, Character: 20}, protocol.Position{Line: 1, Character: 36}},
+		{"spaces", "close complete(   {status: \"done\"}  )", 2, protocol.Position{Line: 1, Character: 22}, protocol.Position{Line: 1, Character: 38}},
+		{"multi-line", "close complete(\n        {status: \"done\"}\n    )", 3, protocol.Position{Line: 2, Character: 8}, protocol.Position{Line: 2, Character: 24}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := "workflow A() -> (OrderResult):\n    " + tt.close + "\n"
+			doc := NewDocumentStore().Open("file:///a.twf", 1, content)
+			actions := wrapCloseValueActions(doc, &protocol.CodeActionParams{Range: lineRange(tt.line, tt.line)})
+			if len(actions) != 1 {
+				t.Fatalf("expected one action, got %v (%v)", actions, doc.ValidateErrs)
+			}
+			edit := actions[0].Edit.Changes["file:///a.twf"][0]
+			if edit.Range.Start != tt.start || edit.Range.End != tt.end {
+				t.Errorf("edit covers %v-%v, want %v-%v", edit.Range.Start, edit.Range.End, tt.start, tt.end)
+			}
+		})
+	}
+}
+
+func TestHintQuickFix(t *testing.T) {
+	content := `workflow A():
+    signal Approved():
+        approved = true
+    hints signal Approved, Override
+    close complete
+`
+	store := NewDocumentStore()
+	doc := store.Open("file:///a.twf", 1, content)
+	var hint []protocol.Diagnostic
+	var names []protocol.Range
+	for _, d := range diagnostics(doc) {
+		switch {
+		case strings.HasPrefix(d.Message, "hint was removed"):
+			hint = append(hint, d)
+		case d.Message == "workflow A declares no signal Override":
+			names = append(names, d.Range)
+		}
+	}
+	if len(hint) != 1 || len(hint[0].Tags) != 1 || hint[0].Tags[0] != protocol.DiagnosticTagDeprecated {
+		t.Fatalf("expected one deprecated hint diagnostic, got %v", hint)
+	}
+	want := protocol.Range{Start: protocol.Position{Line: 3, Character: 27}, End: protocol.Position{Line: 3, Character: 35}}
+	if len(names) != 1 || names[0] != want {
+		t.Errorf("expected the undeclared name at %v, got %v", want, names)
+	}
+
+	params := &protocol.CodeActionParams{Range: lineRange(4, 4)}
+	params.Context.Diagnostics = hint
+	actions := convertHintActions(doc, params)
+	if len(actions) != 1 || len(actions[0].Diagnostics) != 1 {
+		t.Fatalf("expected one quick fix for the diagnostic, got %v", actions)
+	}
+	edit := actions[0].Edit.Changes["file:///a.twf"][0]
+	if edit.NewText != "await one:\n        signal Approved:\n        signal Override:" || edit.Range.Start.Character != 4 || edit.Range.End.Character != 35 {
+		t.Errorf("unexpected edit: %+v", edit)
+	}
+}
+
+func TestReturnToCloseQuickFix(t *testing.T) {
+	content := `workflow A() -> (Result):
+    update Rename(name: string) -> (string):
+        return name
+    return result
+`
+	store := NewDocumentStore()
+	store.Policy = validator.Policy{ReturnInWorkflow: validator.ReturnInWorkflowWarning}
+	doc := store.Open("file:///a.twf", 1, content)
+	diags := diagnostics(doc)
+	var deprecated []protocol.Diagnostic
+	for _, d := range diags {
+		if len(d.Tags) == 1 && d.Tags[0] == protocol.DiagnosticTagDeprecated {
+			deprecated = append(deprecated, d)
+		}
+	}
+	if len(deprecated) != 1 || deprecated[0].Range.Start.Line != 3 {
+		t.Fatalf("expected one deprecated diagnostic on line 4, got %v", diags)
+	}
+
+	params := &protocol.CodeActionParams{Range: lineRange(4, 4)}
+	params.Context.Diagnostics = deprecated
+	actions := convertReturnToCloseActions(doc, params)
+	if len(actions) != 1 {
+		t.Fatalf("expected one action, got %v", actions)
+	}
+	a := actions[0]
+	if *a.Kind != protocol.CodeActionKindQuickFix || len(a.Diagnostics) != 1 {
+		t.Errorf("expected a quick fix for the diagnostic, got %+v", a)
+	}
+	edit := a.Edit.Changes["file:///a.twf"][0]
+	if edit.NewText != "close complete(result)" || edit.Range.Start != (protocol.Position{Line: 3, Character: 4}) || edit.Range.End.Character != 17 {
+		t.Errorf("unexpected edit: %+v", edit)
+	}
+
+	// The update handler's return is its result, not deprecated.
+	handler := protocol.Range{Start: protocol.Position{Line: 2, Character: 8}, End: protocol.Position{Line: 2, Character: 19}}
+	if actions := convertReturnToCloseActions(doc, &protocol.CodeActionParams{Range: handler}); len(actions) != 0 {
+		t.Errorf("expected no action for the handler's return, got %v", actions)
+	}
+}
+
+func TestDefinitionStubsParse(t *testing.T) {
+	for _, kind := range []string{"activity", "workflow"} {
+		for _, returnType := range []string{"", "Result"} {
+			stub := definitionStub(kind, "Charge", "id", returnType)
+			def, err := parser.ParseDefinition(stub)
+			if err != nil {
+				t.Errorf("%s stub does not parse: %v\n%s", kind, err, stub)
+				continue
+			}
+			if _, isWorkflow := def.(*ast.WorkflowDef); isWorkflow != (kind == "workflow") {
+				t.Errorf("expected a %s, got %T", kind, def)
+			}
+		}
+	}
+}
@@ -1,7 +1,16 @@
 package server
 
 import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
 	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/resolver"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/token"
 	"github.com/tliron/glsp"
 	protocol "github.com/tliron/glsp/protocol_3_16"
 )
@@ -14,26 +23,150 @@ func completionHandler(store *DocumentStore) protocol.TextDocumentCompletionFunc
 		}
 
 		line := int(params.Position.Line) + 1 // LSP 0-based → parser 1-based
+		prefix := wordBefore(doc.Content, params.Position)
+
+		if atNexusEndpoint(doc.Content, params.Position) {
+			return &protocol.CompletionList{
+				IsIncomplete: false,
+				Items:        rankCompletions(nexusEndpointCompletions(doc.Symbols, store.NexusEndpoints), prefix),
+			}, nil
+		}
 
 		ctx := findCompletionContext(doc.File, line)
 
 		var items []protocol.CompletionItem
 		switch ctx.kind {
 		case contextTopLevel:
-			items = topLevelCompletions()
+			items = topLevelCompletions(doc.lexer.Aliases)
 		case contextWorkflow:
-			items = workflowCompletions(doc.File, ctx.workflow)
+			items = workflowCompletions(doc.URI, doc.File, ctx.workflow, doc.lexer.Aliases)
 		case contextActivity:
-			items = activityCompletions()
+			items = activityCompletions(doc.lexer.Aliases)
 		}
 
 		return &protocol.CompletionList{
 			IsIncomplete: false,
-			Items:        items,
+			Items:        rankCompletions(items, prefix),
 		}, nil
 	}
 }
 
+// wordBefore returns the identifier characters before pos on its line: the
+// word being completed.
+func wordBefore(content string, pos protocol.Position) string {
+	lines := strings.Split(content, "\n")
+	if int(pos.Line) >= len(lines) {
+		return ""
+	}
+	text := lines[pos.Line]
+	text = text[:min(int(pos.Character), len(text))]
+	start := len(text)
+	for start > 0 && isIdentByte(text[start-1]) {
+		start--
+	}
+	return text[start:]
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// Completion ranks, lowest first. The signals and updates of the enclosing
+// workflow come before other names. Keywords come first while nothing is
+// typed, where they are what a new statement starts with, and last once a
+// prefix is typed, which more likely begins a name.
+const (
+	rankLeadingKeyword = iota
+	rankDeclared
+	rankName
+	rankTrailingKeyword
+)
+
+// rankCompletions drops the items that prefix does not match and sets the
+// sortText and filterText of the rest: by rank, then prefix matches before
+// camel-hump matches, then in the order given.
+func rankCompletions(items []protocol.CompletionItem, prefix string) []protocol.CompletionItem {
+	ranked := []protocol.CompletionItem{}
+	for i, item := range items {
+		quality, ok := fuzzyMatch(prefix, item.Label)
+		if !ok {
+			continue
+		}
+		rank := rankName
+		switch {
+		case item.Kind != nil && *item.Kind == protocol.CompletionItemKindKeyword && prefix == "":
+			rank = rankLeadingKeyword
+		case item.Kind != nil && *item.Kind == protocol.CompletionItemKindKeyword:
+			rank = rankTrailingKeyword
+		case isDeclaredItem(item):
+			rank = rankDeclared
+		}
+		item.SortText = ptrTo(fmt.Sprintf("%d%d%05d", rank, quality, i))
+		item.FilterText = ptrTo(item.Label)
+		ranked = append(ranked, item)
+	}
+	return ranked
+}
+
+// isDeclaredItem reports whether item is a signal or update of the
+// enclosing workflow.
+func isDeclaredItem(item protocol.CompletionItem) bool {
+	data, ok := item.Data.(completionData)
+	return ok && (data.Kind == "signal" || data.Kind == "update")
+}
+
+// fuzzyMatch reports whether query matches label, case-insensitively, as a
+// prefix (quality 0) or by camel humps (quality 1): each part of the query
+// begins a hump of the label, in order, so "PR" and "payRec" both match
+// "PaymentReceived". An empty query matches everything.
+func fuzzyMatch(query, label string) (quality int, ok bool) {
+	if strings.HasPrefix(strings.ToLower(label), strings.ToLower(query)) {
+		return 0, true
+	}
+	if matchHumps(strings.ToLower(query), humps(label)) {
+		return 1, true
+	}
+	return 0, false
+}
+
+// humps splits an identifier into lowercased words at upper-case letters,
+// digits following letters, and underscores.
+func humps(label string) []string {
+	var words []string
+	start := 0
+	for i := 1; i <= len(label); i++ {
+		if i < len(label) {
+			c, prev := label[i], label[i-1]
+			upper := 'A' <= c && c <= 'Z' && !('A' <= prev && prev <= 'Z')
+			digit := '0' <= c && c <= '9' && !('0' <= prev && prev <= '9')
+			if !upper && !digit && c != '_' && prev != '_' {
+				continue
+			}
+		}
+		if word := strings.Trim(label[start:i], "_"); word != "" {
+			words = append(words, strings.ToLower(word))
+		}
+		start = i
+	}
+	return words
+}
+
+// matchHumps reports whether query splits into prefixes of words, in order,
+// skipping any words.
+func matchHumps(query string, words []string) bool {
+	if query == "" {
+		return true
+	}
+	for i, word := range words {
+		for k := min(len(query), len(word)); k > 0; k-- {
+			if query[:k] == word[:k] && matchHumps(query[k:], words[i+1:]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 type completionContextKind int
 
 const (
@@ -78,14 +211,22 @@ func findCompletionContext(file *ast.File, line int) completionContext {
 	return completionContext{kind: contextTopLevel}
 }
 
-func topLevelCompletions() []protocol.CompletionItem {
-	return []protocol.CompletionItem{
+func topLevelCompletions(aliases token.Aliases) []protocol.CompletionItem {
+	items := []protocol.CompletionItem{
 		keywordItem("workflow", "Define a new workflow"),
 		keywordItem("activity", "Define a new activity"),
+		keywordItem("const", "Define a named constant"),
+		keywordItem("enum", "Define an enumeration of named values"),
 	}
+	return append(items, aliasItems(aliases, items)...)
 }
 
-func workflowCompletions(file *ast.File, enclosing *ast.WorkflowDef) []protocol.CompletionItem {
+// workflowCompletions offers the keywords of a workflow body, the
+// definitions of file, and the signals and updates of the enclosing
+// workflow, with the keyword aliases the file was lexed with. The
+// definition items are labels only; completionItem/resolve documents the
+// one the client highlights.
+func workflowCompletions(uri string, file *ast.File, enclosing *ast.WorkflowDef, aliases token.Aliases) []protocol.CompletionItem {
 	items := []protocol.CompletionItem{
 		keywordItem("activity", "Call an activity"),
 		keywordItem("workflow", "Call a child workflow"),
@@ -100,6 +241,7 @@ func workflowCompletions(file *ast.File, enclosing *ast.WorkflowDef) []protocol.
 		keywordItem("select", "Race between branches"),
 		keywordItem("switch", "Switch on an expression"),
 		keywordItem("if", "Conditional statement"),
+		keywordItem("elif", "Else-if clause of a conditional"),
 		keywordItem("for", "Loop statement"),
 		keywordItem("close", "Terminate workflow (complete, fail, or continue_as_new)"),
 		keywordItem("return", "Return a value"),
@@ -110,17 +252,22 @@ func workflowCompletions(file *ast.File, enclosing *ast.WorkflowDef) []protocol.
 		keywordItem("update", "Declare an update handler"),
 		keywordItem("hint", "Hint that a signal or update may arrive here"),
 	}
+	items = append(items, aliasItems(aliases, items)...)
 
 	// Add defined activity/workflow names as completion targets.
 	if file != nil {
 		for _, def := range file.Definitions {
 			switch d := def.(type) {
 			case *ast.ActivityDef:
-				items = append(items, nameItem(d.Name, "Activity definition"))
+				items = append(items, definitionItem(completionData{URI: uri, Kind: "activity", Name: d.Name}))
 			case *ast.WorkflowDef:
 				if enclosing == nil || d.Name != enclosing.Name {
-					items = append(items, nameItem(d.Name, "Workflow definition"))
+					items = append(items, definitionItem(completionData{URI: uri, Kind: "workflow", Name: d.Name}))
 				}
+			case *ast.ConstDef:
+				items = append(items, definitionItem(completionData{URI: uri, Kind: "const", Name: d.Name}))
+			case *ast.EnumDef:
+				items = append(items, definitionItem(completionData{URI: uri, Kind: "enum", Name: d.Name}))
 			}
 		}
 	}
@@ -128,25 +275,81 @@ func workflowCompletions(file *ast.File, enclosing *ast.WorkflowDef) []protocol.
 	// Add signal/update names from the enclosing workflow.
 	if enclosing != nil {
 		for _, s := range enclosing.Signals {
-			items = append(items, nameItem(s.Name, "Signal"))
+			items = append(items, definitionItem(completionData{URI: uri, Kind: "signal", Name: s.Name, Workflow: enclosing.Name}))
 		}
 		for _, u := range enclosing.Updates {
-			items = append(items, nameItem(u.Name, "Update"))
+			items = append(items, definitionItem(completionData{URI: uri, Kind: "update", Name: u.Name, Workflow: enclosing.Name}))
 		}
 	}
 
 	return items
 }
 
-func activityCompletions() []protocol.CompletionItem {
-	return []protocol.CompletionItem{
+// nexusEndpointPrefix matches a workflow line typed up to the endpoint of a
+// nexus call: nexus, detach nexus, await nexus, or promise p <- nexus.
+var nexusEndpointPrefix = regexp.MustCompile(`^\s+(?:detach\s+|await\s+|promise\s+\w+\s*<-\s*)?nexus\s+\w*$`)
+
+// atNexusEndpoint reports whether pos is at the endpoint name of a nexus
+// call. The text is matched rather than the AST, since the call does not
+// parse until it is complete, and the enclosing definition is found by its
+// header line for the same reason: nexus service and nexus endpoint lines in
+// workers and namespaces have the same shape.
+func atNexusEndpoint(content string, pos protocol.Position) bool {
+	lines := strings.Split(content, "\n")
+	if int(pos.Line) >= len(lines) {
+		return false
+	}
+	text := lines[pos.Line]
+	text = text[:min(int(pos.Character), len(text))]
+	if !nexusEndpointPrefix.MatchString(text) {
+		return false
+	}
+	for i := int(pos.Line) - 1; i >= 0; i-- {
+		header := lines[i]
+		if header == "" || header[0] == ' ' || header[0] == '\t' || header[0] == '#' || header[0] == '@' {
+			continue
+		}
+		return strings.HasPrefix(header, "workflow ")
+	}
+	return false
+}
+
+// nexusEndpointCompletions offers the nexus endpoints declared by the
+// namespaces in scope, followed by the configured endpoints not declared.
+func nexusEndpointCompletions(symbols *resolver.SymbolTable, configured []string) []protocol.CompletionItem {
+	items := []protocol.CompletionItem{}
+	declared := make(map[string]bool)
+	if symbols != nil {
+		for _, name := range slices.Sorted(maps.Keys(symbols.Endpoints)) {
+			declared[name] = true
+			ep := symbols.Endpoints[name]
+			detail := "Nexus endpoint (namespace " + ep.Namespace + ")"
+			if tq := extractEndpointTaskQueue(ep); tq != "" {
+				detail += ", task_queue " + tq
+			}
+			items = append(items, nameItem(name, detail))
+		}
+	}
+	for _, name := range configured {
+		if !declared[name] {
+			declared[name] = true
+			items = append(items, nameItem(name, "Nexus endpoint (configured)"))
+		}
+	}
+	return items
+}
+
+func activityCompletions(aliases token.Aliases) []protocol.CompletionItem {
+	items := []protocol.CompletionItem{
 		keywordItem("switch", "Switch on an expression"),
 		keywordItem("if", "Conditional statement"),
+		keywordItem("elif", "Else-if clause of a conditional"),
 		keywordItem("for", "Loop statement"),
 		keywordItem("return", "Return a value"),
 		keywordItem("break", "Break out of a loop"),
 		keywordItem("continue", "Continue to next iteration"),
 	}
+	return append(items, aliasItems(aliases, items)...)
 }
 
 func keywordItem(kw, detail string) protocol.CompletionItem {
@@ -166,3 +369,113 @@ func nameItem(name, detail string) protocol.CompletionItem {
 		Detail: &detail,
 	}
 }
+
+// completionData is the data of a definition completion item, from which
+// completionItem/resolve finds the definition again.
+type completionData struct {
+	URI      string `json:"uri"`
+	Kind     string `json:"kind"` // activity, workflow, const, enum, signal, or update
+	Name     string `json:"name"`
+	Workflow string `json:"workflow,omitempty"` // the workflow declaring a signal or update
+}
+
+func definitionItem(data completionData) protocol.CompletionItem {
+	kind := protocol.CompletionItemKindReference
+	return protocol.CompletionItem{
+		Label: data.Name,
+		Kind:  &kind,
+		Data:  data,
+	}
+}
+
+// completionResolveHandler fills in the detail and documentation of a
+// definition item: its kind, its full signature, and the comment lines
+// directly above it. Other items are returned unchanged.
+func completionResolveHandler(store *DocumentStore) protocol.CompletionItemResolveFunc {
+	return func(context *glsp.Context, item *protocol.CompletionItem) (*protocol.CompletionItem, error) {
+		var data completionData
+		raw, err := json.Marshal(item.Data)
+		if err != nil || json.Unmarshal(raw, &data) != nil || data.URI == "" {
+			return item, nil
+		}
+		doc, ok := store.Get(data.URI)
+		if !ok || doc.File == nil {
+			return item, nil
+		}
+		node, detail := completionTarget(doc.File, data)
+		if node == nil {
+			return item, nil
+		}
+
+		value := fmt.Sprintf("```twf\n%s\n```", signatureFor(node))
+		if comment := docComment(doc.Content, node); comment != "" {
+			value += "\n\n" + comment
+		}
+		item.Detail = &detail
+		item.Documentation = protocol.MarkupContent{Kind: protocol.MarkupKindMarkdown, Value: value}
+		return item, nil
+	}
+}
+
+// completionTarget returns the node a definition item names, with the
+// item's detail, or nil when it no longer exists.
+func completionTarget(file *ast.File, data completionData) (ast.Node, string) {
+	for _, def := range file.Definitions {
+		switch d := def.(type) {
+		case *ast.ActivityDef:
+			if data.Kind == "activity" && d.Name == data.Name {
+				return d, "Activity definition"
+			}
+		case *ast.ConstDef:
+			if data.Kind == "const" && d.Name == data.Name {
+				return d, "Constant (" + d.ValueType + ")"
+			}
+		case *ast.EnumDef:
+			if data.Kind == "enum" && d.Name == data.Name {
+				return d, "Enum definition"
+			}
+		case *ast.WorkflowDef:
+			if data.Kind == "workflow" && d.Name == data.Name {
+				return d, "Workflow definition"
+			}
+			if d.Name != data.Workflow {
+				continue
+			}
+			for _, s := range d.Signals {
+				if data.Kind == "signal" && s.Name == data.Name {
+					return s, "Signal of " + d.Name
+				}
+			}
+			for _, u := range d.Updates {
+				if data.Kind == "update" && u.Name == data.Name {
+					return u, "Update of " + d.Name
+				}
+			}
+		}
+	}
+	return nil, ""
+}
+
+// docComment returns the text of the comment lines directly above node, and
+// above its annotations, at the same indentation, without their markers.
+func docComment(content string, node ast.Node) string {
+	line := node.NodeLine()
+	if def, ok := node.(ast.Definition); ok {
+		line = definitionStart(def)
+	}
+	lines := strings.Split(content, "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	indent := lines[line-1][:len(lines[line-1])-len(strings.TrimLeft(lines[line-1], " \t"))]
+	start := line - 1
+	for start > 0 && strings.HasPrefix(lines[start-1], indent+"#") {
+		start--
+	}
+	var text []string
+	for _, l := range lines[start : line-1] {
+		l = strings.TrimPrefix(l, indent+"#")
+		text = append(text, strings.TrimPrefix(l, " "))
+	}
+	return strings.Join(text, "\n")
+}
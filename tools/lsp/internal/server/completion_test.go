@@ -0,0 +1,196 @@
+package server
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestNexusEndpointCompletionAndHover(t *testing.T) {
+	const uri = "file:///nexus.twf"
+	content := "nexus service Payments:\n" +
+		"    async Charge workflow ChargeWorkflow\n" +
+		"\n" +
+		"workflow ChargeWorkflow():\n" +
+		"    # charge\n" +
+		"\n" +
+		"workflow Order():\n" +
+		"    nexus PaymentsEndpoint Payments.Charge() -> result\n" +
+		"    detach nexus PaymentsEndpoint Payments.Charge()\n" +
+		"\n" +
+		"worker paymentWorker:\n" +
+		"    workflow ChargeWorkflow\n" +
+		"    nexus service Payments\n" +
+		"\n" +
+		"namespace payments:\n" +
+		"    worker paymentWorker\n" +
+		"        options:\n" +
+		"            task_queue: \"payments\"\n" +
+		"    nexus endpoint PaymentsEndpoint\n" +
+		"        options:\n" +
+		"            task_queue: \"payments\"\n"
+	store := NewDocumentStore()
+	store.NexusEndpoints = []string{"BillingEndpoint", "PaymentsEndpoint"}
+	store.Open(uri, 1, content)
+
+	complete := func(line, char uint32) []string {
+		t.Helper()
+		result, err := completionHandler(store)(nil, &protocol.CompletionParams{TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Position:     protocol.Position{Line: line, Character: char},
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var labels []string
+		for _, item := range result.(*protocol.CompletionList).Items {
+			labels = append(labels, item.Label)
+		}
+		return labels
+	}
+	if got := complete(8, 17); !slices.Equal(got, []string{"PaymentsEndpoint", "BillingEndpoint"}) {
+		t.Errorf("expected the declared then configured endpoints, got %v", got)
+	}
+	if got := complete(8, 20); !slices.Equal(got, []string{"PaymentsEndpoint"}) {
+		t.Errorf("expected the endpoints matching Pay, got %v", got)
+	}
+	if got := complete(12, 12); slices.Contains(got, "PaymentsEndpoint") {
+		t.Errorf("expected no endpoints in a worker, got %v", got)
+	}
+
+	for _, line := range []uint32{7, 18} {
+		hover, err := hoverHandler(store)(nil, &protocol.HoverParams{TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Position:     protocol.Position{Line: line},
+		}})
+		if err != nil || hover == nil {
+			t.Fatalf("line %d: no hover (%v)", line, err)
+		}
+		if value := hover.Contents.(protocol.MarkupContent).Value; !strings.Contains(value, "\n  operation Payments.Charge\n") {
+			t.Errorf("line %d: expected the endpoint's operations, got %q", line, value)
+		}
+	}
+}
+
+func TestCompletionResolve(t *testing.T) {
+	const uri = "file:///resolve.twf"
+	content := "# Charges the card.\n" +
+		"# Retries on decline.\n" +
+		"@owner(\"payments\")\n" +
+		"activity Charge(card: Card) -> (Receipt):\n" +
+		"    return receipt\n" +
+		"\n" +
+		"workflow Order():\n" +
+		"    # Cancels the order.\n" +
+		"    signal Cancel(reason: string):\n" +
+		"        close fail\n" +
+		"\n" +
+		"    activity Charge(card)\n"
+	store := NewDocumentStore()
+	store.Open(uri, 1, content)
+
+	result, err := completionHandler(store)(nil, &protocol.CompletionParams{TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+		Position:     protocol.Position{Line: 11, Character: 4},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := make(map[string]protocol.CompletionItem)
+	for _, item := range result.(*protocol.CompletionList).Items {
+		items[item.Label] = item
+	}
+	charge, cancel := items["Charge"], items["Cancel"]
+	if charge.Data == nil || charge.Detail != nil || charge.Documentation != nil {
+		t.Fatalf("expected a label-only item with data, got %+v", charge)
+	}
+
+	// The client sends the item back as JSON.
+	var sent protocol.CompletionItem
+	raw, _ := json.Marshal(charge)
+	if err := json.Unmarshal(raw, &sent); err != nil {
+		t.Fatal(err)
+	}
+	resolved, err := completionResolveHandler(store)(nil, &sent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "```twf\n@owner(\"payments\")\nactivity Charge(card: Card) -> (Receipt)\n```\n\nCharges the card.\nRetries on decline."
+	if resolved.Detail == nil || *resolved.Detail != "Activity definition" || resolved.Documentation.(protocol.MarkupContent).Value != want {
+		t.Errorf("unexpected resolved activity: %v %+v", resolved.Detail, resolved.Documentation)
+	}
+
+	resolved, _ = completionResolveHandler(store)(nil, &cancel)
+	if resolved.Detail == nil || *resolved.Detail != "Signal of Order" || !strings.HasSuffix(resolved.Documentation.(protocol.MarkupContent).Value, "```\n\nCancels the order.") {
+		t.Errorf("unexpected resolved signal: %v %+v", resolved.Detail, resolved.Documentation)
+	}
+
+	keyword := items["activity"]
+	if resolved, _ := completionResolveHandler(store)(nil, &keyword); resolved.Documentation != nil {
+		t.Errorf("expected keywords to resolve unchanged, got %+v", resolved)
+	}
+}
+
+func TestCompletionRanking(t *testing.T) {
+	const uri = "file:///rank.twf"
+	content := "activity ProcessRefund():\n" +
+		"    return\n" +
+		"\n" +
+		"activity Ship():\n" +
+		"    return\n" +
+		"\n" +
+		"workflow Order():\n" +
+		"    signal PaymentReceived():\n" +
+		"        close complete\n" +
+		"\n" +
+		"    activity Ship()\n" +
+		"    pr\n"
+	store := NewDocumentStore()
+	store.Open(uri, 1, content)
+
+	complete := func(char uint32) []string {
+		t.Helper()
+		result, err := completionHandler(store)(nil, &protocol.CompletionParams{TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Position:     protocol.Position{Line: 11, Character: char},
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		items := result.(*protocol.CompletionList).Items
+		slices.SortFunc(items, func(a, b protocol.CompletionItem) int { return strings.Compare(*a.SortText, *b.SortText) })
+		var labels []string
+		for _, item := range items {
+			labels = append(labels, item.Label)
+		}
+		return labels
+	}
+
+	if got := complete(6); !slices.Equal(got, []string{"PaymentReceived", "ProcessRefund", "promise"}) {
+		t.Errorf("expected the signal, then the activity, then the keyword, got %v", got)
+	}
+	if got := complete(4); got[0] != "activity" || !slices.Equal(got[len(got)-3:], []string{"PaymentReceived", "ProcessRefund", "Ship"}) {
+		t.Errorf("expected keywords, then the signal, then definitions with no prefix, got %v", got)
+	}
+
+	for _, tc := range []struct {
+		query, label string
+		quality      int
+		ok           bool
+	}{
+		{"pay", "PaymentReceived", 0, true},
+		{"PR", "PaymentReceived", 1, true},
+		{"payRec", "PaymentReceived", 1, true},
+		{"pRx", "PaymentReceived", 0, false},
+		{"cw", "charge_workflow", 1, true},
+		{"s3", "UploadS3", 1, true},
+		{"", "Ship", 0, true},
+	} {
+		if quality, ok := fuzzyMatch(tc.query, tc.label); quality != tc.quality || ok != tc.ok {
+			t.Errorf("fuzzyMatch(%q, %q) = %d, %t; want %d, %t", tc.query, tc.label, quality, ok, tc.quality, tc.ok)
+		}
+	}
+}
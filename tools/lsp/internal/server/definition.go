@@ -2,6 +2,7 @@ package server
 
 import (
 	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/resolver"
 	"github.com/tliron/glsp"
 	protocol "github.com/tliron/glsp/protocol_3_16"
 )
@@ -26,12 +27,31 @@ func definitionHandler(store *DocumentStore) protocol.TextDocumentDefinitionFunc
 		}
 
 		return protocol.Location{
-			URI:   params.TextDocument.URI,
+			URI:   nodeURI(target, doc.Symbols, params.TextDocument.URI),
 			Range: posToRange(target.NodeLine(), target.NodeColumn()),
 		}, nil
 	}
 }
 
+// nodeURI returns the URI of the file defining node: the source file of a
+// definition resolved from another workspace file, or uri when node is in
+// the document itself.
+func nodeURI(node ast.Node, symbols *resolver.SymbolTable, uri string) string {
+	var file string
+	switch n := node.(type) {
+	case ast.Definition:
+		file = ast.SourceFile(n)
+	case *ast.NamespaceEndpoint:
+		if symbols != nil && symbols.Namespaces[n.Namespace] != nil {
+			file = symbols.Namespaces[n.Namespace].SourceFile
+		}
+	}
+	if file == "" {
+		return uri
+	}
+	return file
+}
+
 // resolvedTarget returns the definition node that a call/reference resolves to.
 func resolvedTarget(node ast.Node) ast.Node {
 	switch n := node.(type) {
@@ -75,6 +95,18 @@ func resolvedTarget(node ast.Node) ast.Node {
 		if n.Target != nil {
 			return resolvedTargetFromAsync(n.Target)
 		}
+	case *ast.Ref[*ast.ConstDef]:
+		if n.Resolved != nil {
+			return n.Resolved
+		}
+	case *ast.BreakStmt:
+		if n.Label.Resolved != nil {
+			return n.Label.Resolved.Label
+		}
+	case *ast.ContinueStmt:
+		if n.Label.Resolved != nil {
+			return n.Label.Resolved.Label
+		}
 	}
 	return nil
 }
@@ -82,6 +114,10 @@ func resolvedTarget(node ast.Node) ast.Node {
 // resolvedTargetFromAsync returns the resolved definition from an async target.
 func resolvedTargetFromAsync(target ast.AsyncTarget) ast.Node {
 	switch t := target.(type) {
+	case *ast.TimerTarget:
+		if t.Const.Resolved != nil {
+			return t.Const.Resolved
+		}
 	case *ast.SignalTarget:
 		if t.Signal.Resolved != nil {
 			return t.Signal.Resolved
@@ -1,14 +1,23 @@
 package server
 
 import (
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/validator"
 	"github.com/tliron/glsp"
 	protocol "github.com/tliron/glsp/protocol_3_16"
 )
 
 func didOpenHandler(store *DocumentStore) protocol.TextDocumentDidOpenFunc {
 	return func(context *glsp.Context, params *protocol.DidOpenTextDocumentParams) error {
-		doc := store.Open(params.TextDocument.URI, params.TextDocument.Text)
-		return publishDiagnostics(context, doc)
+		changed := store.Workspace.Set(params.TextDocument.URI, params.TextDocument.Text)
+		doc := store.Open(params.TextDocument.URI, params.TextDocument.Version, params.TextDocument.Text)
+		if changed {
+			refreshInBackground(context, store, store.Refresh(params.TextDocument.URI))
+		}
+		if store.pullDiagnostics {
+			return nil
+		}
+		publishDiagnostics(context, store, doc)
+		return nil
 	}
 }
 
@@ -16,14 +25,30 @@ func didChangeHandler(store *DocumentStore) protocol.TextDocumentDidChangeFunc {
 	return func(context *glsp.Context, params *protocol.DidChangeTextDocumentParams) error {
 		// Full sync: last content change has the full text.
 		text := params.ContentChanges[len(params.ContentChanges)-1].(protocol.TextDocumentContentChangeEventWhole).Text
-		doc := store.Update(params.TextDocument.URI, text)
-		return publishDiagnostics(context, doc)
+		// Analyze in the background so a newer edit can cancel this one;
+		// superseded versions publish nothing.
+		analysis := store.Update(params.TextDocument.URI, params.TextDocument.Version, text)
+		if !store.pullDiagnostics {
+			store.background(func() { publishAnalyses(context, store, []*Analysis{analysis}) })
+		}
+		// Open documents resolving against this one see the edit too.
+		if store.Workspace.Set(params.TextDocument.URI, text) {
+			refreshInBackground(context, store, store.Refresh(params.TextDocument.URI))
+		}
+		return nil
 	}
 }
 
 func didCloseHandler(store *DocumentStore) protocol.TextDocumentDidCloseFunc {
 	return func(context *glsp.Context, params *protocol.DidCloseTextDocumentParams) error {
 		store.Close(params.TextDocument.URI)
+		// Other documents go back to the file as saved.
+		if store.Workspace.Reload(params.TextDocument.URI) {
+			refreshInBackground(context, store, store.Refresh(params.TextDocument.URI))
+		}
+		if store.pullDiagnostics {
+			return nil
+		}
 		// Clear diagnostics for the closed document.
 		context.Notify(protocol.ServerTextDocumentPublishDiagnostics, protocol.PublishDiagnosticsParams{
 			URI:         params.TextDocument.URI,
@@ -33,7 +58,55 @@ func didCloseHandler(store *DocumentStore) protocol.TextDocumentDidCloseFunc {
 	}
 }
 
-func publishDiagnostics(context *glsp.Context, doc *Document) error {
+// publishAnalyses publishes the diagnostics of each analysis as it
+// completes. Superseded analyses publish nothing.
+func publishAnalyses(context *glsp.Context, store *DocumentStore, analyses []*Analysis) {
+	for _, a := range analyses {
+		if doc, ok := a.Wait(); ok {
+			publishDiagnostics(context, store, doc)
+		}
+	}
+}
+
+// refreshDiagnostics publishes the diagnostics of analyses started because
+// another file changed. A client pulling diagnostics is instead asked to
+// pull again once they complete.
+func refreshDiagnostics(context *glsp.Context, store *DocumentStore, analyses []*Analysis) {
+	if !store.pullDiagnostics {
+		publishAnalyses(context, store, analyses)
+		return
+	}
+	if len(analyses) == 0 || !store.pullRefresh {
+		return
+	}
+	for _, a := range analyses {
+		a.Wait()
+	}
+	context.Call(methodWorkspaceDiagnosticRefresh, nil, nil)
+}
+
+// refreshInBackground runs refreshDiagnostics in the background of store.
+func refreshInBackground(context *glsp.Context, store *DocumentStore, analyses []*Analysis) {
+	store.background(func() { refreshDiagnostics(context, store, analyses) })
+}
+
+// publishDiagnostics publishes the diagnostics of doc with its version, so
+// the client can tell which edit they belong to. Diagnostics of a version
+// that is no longer the latest are dropped, since sending them would briefly
+// replace those of the newer version.
+func publishDiagnostics(context *glsp.Context, store *DocumentStore, doc *Document) {
+	store.Publish(doc, func() {
+		context.Notify(protocol.ServerTextDocumentPublishDiagnostics, protocol.PublishDiagnosticsParams{
+			URI:         doc.URI,
+			Version:     ptrTo(protocol.UInteger(doc.Version)),
+			Diagnostics: diagnostics(doc),
+		})
+	})
+}
+
+// diagnostics converts the errors of an analyzed document to LSP
+// diagnostics, for publishing or a pull request.
+func diagnostics(doc *Document) []protocol.Diagnostic {
 	var diags []protocol.Diagnostic
 
 	for _, pe := range doc.ParseErrs {
@@ -41,20 +114,33 @@ func publishDiagnostics(context *glsp.Context, doc *Document) error {
 	}
 	for _, re := range doc.ResolveErrs {
 		diags = appendDiag(diags, re.Line, re.Column, re.Severity, re.Msg)
+		related := make([]validator.Related, len(re.Related))
+		for i, r := range re.Related {
+			related[i] = validator.Related(r)
+		}
+		diags[len(diags)-1].RelatedInformation = relatedInfo(doc.URI, related)
 	}
 	for _, ve := range doc.ValidateErrs {
 		diags = appendDiag(diags, ve.Line, ve.Column, ve.Severity, ve.Msg)
+		diags[len(diags)-1].RelatedInformation = relatedInfo(doc.URI, ve.Related)
+		if ve.Kind == validator.ErrUnreachable || ve.Kind == validator.ErrDeadHandler {
+			// Clients fade unnecessary code.
+			diags[len(diags)-1].Tags = []protocol.DiagnosticTag{protocol.DiagnosticTagUnnecessary}
+		}
+		if ve.Kind == validator.ErrDeprecatedReturn || ve.Kind == validator.ErrRemovedHint && ve.Name == "" {
+			// Clients strike deprecated code through.
+			diags[len(diags)-1].Tags = []protocol.DiagnosticTag{protocol.DiagnosticTagDeprecated}
+		}
+		if ve.Kind == validator.ErrRemovedHint && ve.Name != "" {
+			// Several names share the line, so each covers only itself.
+			diags[len(diags)-1].Range.End.Character = diags[len(diags)-1].Range.Start.Character + uint32(len(ve.Name))
+		}
 	}
 
 	if diags == nil {
 		diags = []protocol.Diagnostic{}
 	}
-
-	context.Notify(protocol.ServerTextDocumentPublishDiagnostics, protocol.PublishDiagnosticsParams{
-		URI:         doc.URI,
-		Diagnostics: diags,
-	})
-	return nil
+	return diags
 }
 
 func appendDiag(diags []protocol.Diagnostic, line, column int, severity, msg string) []protocol.Diagnostic {
@@ -70,6 +156,26 @@ func appendDiag(diags []protocol.Diagnostic, line, column int, severity, msg str
 	})
 }
 
+// relatedInfo converts validator related locations to LSP related
+// information. Locations without a file are in the document at uri.
+func relatedInfo(uri protocol.DocumentUri, related []validator.Related) []protocol.DiagnosticRelatedInformation {
+	if len(related) == 0 {
+		return nil
+	}
+	infos := make([]protocol.DiagnosticRelatedInformation, 0, len(related))
+	for _, r := range related {
+		loc := protocol.Location{URI: uri, Range: posToRange(r.Line, r.Column)}
+		if r.File != "" {
+			loc.URI = r.File
+		}
+		infos = append(infos, protocol.DiagnosticRelatedInformation{
+			Location: loc,
+			Message:  r.Msg,
+		})
+	}
+	return infos
+}
+
 // lineRange converts 1-based start/end lines to an LSP 0-based range spanning those lines.
 func lineRange(startLine, endLine int) protocol.Range {
 	s := uint32(0)
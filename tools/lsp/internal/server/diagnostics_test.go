@@ -0,0 +1,24 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/validator"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestUnreachableDiagnosticTag(t *testing.T) {
+	doc := &Document{URI: "file:///a.twf", Content: "workflow Order():\n    close complete\n    close fail\n"}
+	doc.analyze(context.Background(), nil, validator.Policy{}, nil, nil, "")
+	for _, d := range diagnostics(doc) {
+		if strings.HasPrefix(d.Message, "unreachable:") {
+			if len(d.Tags) != 1 || d.Tags[0] != protocol.DiagnosticTagUnnecessary {
+				t.Errorf("expected the unreachable statement to be tagged unnecessary, got %v", d.Tags)
+			}
+			return
+		}
+	}
+	t.Error("expected an unreachable diagnostic")
+}
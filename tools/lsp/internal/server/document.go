@@ -1,89 +1,515 @@
 package server
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/jmbarzee/temporal-skills/tools/lsp/internal/cache"
 	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/lexer"
 	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/parser"
 	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/resolver"
 	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/validator"
 )
 
-// Document holds the content and analysis results for a single open file.
+// Document holds the content and analysis results for one version of an
+// open file. A Document is a snapshot: once analyzed it is never modified,
+// and an edit produces a new Document, so a handler that gets a document
+// computes its whole response against one version, which it can report as
+// Version.
 type Document struct {
 	URI          string
+	Version      int32 // the client's version of the content
 	Content      string
+	Hash         string // content address: the SHA-256 of Content, in hex
 	File         *ast.File
 	ParseErrs    []*parser.ParseError
 	ResolveErrs  []*resolver.ResolveError
 	ValidateErrs []*validator.Error
-}
+	Symbols      *resolver.SymbolTable // nil when the document has no definitions
 
-// analyze parses, resolves, and validates the document content.
-func (d *Document) analyze() {
-	d.File = nil
-	d.ParseErrs = nil
-	d.ResolveErrs = nil
-	d.ValidateErrs = nil
+	lexer    lexer.Options           // how Content was lexed
+	resolved *resolver.ResolveResult // reused by the analysis of the next version
+	external bool                    // resolved against other workspace files
+	resultID string                  // identifies the diagnostics for pull requests
+}
 
-	f, errs := parser.ParseFileAll(d.Content)
+// analyze parses, resolves, and validates the document content. Definitions
+// whose text and starting line are unchanged from prev keep prev's nodes when
+// the edit does not affect them, so resolution only revisits edited
+// definitions and those that depend on them. prev may be nil. The rules
+// policy enables are reported with the validation errors.
+//
+// References may also resolve to the external definitions of other
+// workspace files, which are checked against but not reported on. A
+// document with external definitions is resolved in full each time, since
+// the other files may have changed since prev.
+//
+// With a cache, the validation errors are stored under key, and taken from
+// there when another analysis, by this process or another sharing the
+// cache, stored them first.
+func (d *Document) analyze(ctx context.Context, prev *Document, policy validator.Policy, external []ast.Definition, c *cache.Cache, key string) error {
+	f, errs := parser.WithLexer(d.lexer).ParseFileAll(d.Content)
 	d.File = f
 	d.ParseErrs = errs
+	if len(f.Definitions) == 0 {
+		return ctx.Err()
+	}
+
+	var resolved *resolver.ResolveResult
+	var err error
+	if len(external) > 0 {
+		d.external = true
+		resolved, err = resolver.ResolveWith(ctx, f, external)
+	} else {
+		var reuse map[ast.Definition]ast.Definition
+		var prevResolved *resolver.ResolveResult
+		if prev != nil && prev.resolved != nil && !prev.external {
+			reuse = unchangedDefinitions(f, d.Content, prev.File, prev.Content)
+			prevResolved = prev.resolved
+		}
+		resolved, err = resolver.ResolveIncremental(ctx, prevResolved, f, reuse)
+	}
+	if err != nil {
+		return err
+	}
+	d.resolved = resolved
+	d.ResolveErrs = resolved.Errors
+	d.Symbols = resolved.Symbols
+	validate := func() {
+		d.ValidateErrs = validator.ValidateDefinitions(resolved.Symbols, f.Definitions)
+		if d.lexer.CaseInsensitiveKeywords {
+			d.ValidateErrs = append(d.ValidateErrs, validator.CheckKeywordCase(d.Content)...)
+		}
+		if policy.Enabled() {
+			d.ValidateErrs = append(d.ValidateErrs, validator.CheckPolicyDefinitions(resolved.Symbols, policy, f.Definitions)...)
+		}
+	}
+	if c == nil {
+		validate()
+	} else if _, err := c.GetOrPut(key, &d.ValidateErrs, validate); err != nil {
+		slog.Warn("cache", "uri", d.URI, "error", err.Error())
+	}
+	return ctx.Err()
+}
+
+// unchangedDefinitions maps each definition in file to the definition of prev
+// that starts on the same line and spans the same text. A definition spans
+// from its first line to the line before the next definition.
+func unchangedDefinitions(file *ast.File, content string, prev *ast.File, prevContent string) map[ast.Definition]ast.Definition {
+	old := make(map[int]ast.Definition, len(prev.Definitions))
+	oldText := make(map[int]string, len(prev.Definitions))
+	prevLines := strings.Split(prevContent, "\n")
+	for i, def := range prev.Definitions {
+		old[def.NodeLine()] = def
+		oldText[def.NodeLine()] = definitionText(prevLines, prev.Definitions, i)
+	}
+
+	reuse := make(map[ast.Definition]ast.Definition)
+	lines := strings.Split(content, "\n")
+	for i, def := range file.Definitions {
+		line := def.NodeLine()
+		text := definitionText(lines, file.Definitions, i)
+		if o, ok := old[line]; ok && text != "" && text == oldText[line] {
+			reuse[def] = o
+		}
+	}
+	return reuse
+}
+
+// definitionText returns the source lines spanned by defs[i], starting at
+// its first annotation.
+func definitionText(lines []string, defs []ast.Definition, i int) string {
+	start := definitionStart(defs[i]) - 1
+	end := len(lines)
+	if i+1 < len(defs) {
+		end = definitionStart(defs[i+1]) - 1
+	}
+	if start < 0 || start > end || end > len(lines) {
+		return ""
+	}
+	return strings.Join(lines[start:end], "\n")
+}
 
-	if len(f.Definitions) > 0 {
-		d.ResolveErrs = resolver.Resolve(f)
-		d.ValidateErrs = validator.Validate(f)
+// definitionStart returns the first line of def, including annotations.
+func definitionStart(def ast.Definition) int {
+	if anns := ast.Annotations(def); len(anns) > 0 {
+		return anns[0].Line
 	}
+	return def.NodeLine()
 }
 
-// DocumentStore is a thread-safe store of open documents.
+// DocumentStore is a thread-safe store of open documents. Edits are
+// analyzed in the background; a newer edit cancels the analysis of an older
+// one, and reads wait for the latest analysis to finish.
 type DocumentStore struct {
-	mu   sync.RWMutex
-	docs map[string]*Document
+	// Policy selects the ownership and review rules checked in every
+	// document. Set it before opening documents.
+	Policy validator.Policy
+	// Workspace indexes the files of the client's workspace folders, whose
+	// definitions a document's references may resolve to.
+	Workspace *Workspace
+	// NexusEndpoints are endpoint names offered when completing a nexus
+	// call besides those declared in scope, for endpoints declared outside
+	// the workspace.
+	NexusEndpoints []string
+	// Bundle configures the debug bundles written for bug reports.
+	Bundle DebugBundle
+	// AliasesPath is the absolute path of the keyword alias file loaded at
+	// startup, loaded again when the client reports that it changed. Empty
+	// when there is none.
+	AliasesPath string
+	// Cache, when set, stores the validation errors of each analysis, so
+	// content analyzed before against the same files, by this server or by
+	// another process sharing the directory, such as twf check or another
+	// editor's server, is not validated again. CacheBuild identifies the
+	// running build in its keys, so a new build does not reuse them.
+	Cache      *cache.Cache
+	CacheBuild string
+
+	// pullDiagnostics is set when the client pulls diagnostics, so they are
+	// not also published; pullRefresh when it can be asked to pull again.
+	pullDiagnostics bool
+	pullRefresh     bool
+	// annotateEdits is set when the client takes workspace edits as
+	// versioned document changes with change annotations.
+	annotateEdits bool
+
+	publishMu sync.Mutex // serializes Publish
+	mu        sync.Mutex
+	docs      map[string]*Document
+	pending   map[string]*Analysis
+	stored    int // documents stored so far, for result IDs
+	// running counts the analyses and background publishes in flight,
+	// which Shutdown waits for; once shutdown is set, no more start.
+	running  sync.WaitGroup
+	lexer    lexer.Options // of the analyses started from now on
+	shutdown bool
+}
+
+// Analysis is a background analysis of one version of a document.
+type Analysis struct {
+	version int32
+	content string
+	cancel  context.CancelFunc
+	done    chan struct{}
+	doc     *Document // set when the analysis completes and is stored
+}
+
+// Wait blocks until the analysis ends and returns its document. ok is false
+// when a newer edit or a close superseded it.
+func (a *Analysis) Wait() (doc *Document, ok bool) {
+	<-a.done
+	return a.doc, a.doc != nil
 }
 
 // NewDocumentStore creates an empty document store.
 func NewDocumentStore() *DocumentStore {
 	return &DocumentStore{
-		docs: make(map[string]*Document),
+		Workspace: NewWorkspace(),
+		docs:      make(map[string]*Document),
+		pending:   make(map[string]*Analysis),
 	}
 }
 
-// Open adds or replaces a document in the store and analyzes it.
-func (s *DocumentStore) Open(uri, content string) *Document {
+// Open adds or replaces a document in the store and analyzes content as its
+// version.
+func (s *DocumentStore) Open(uri string, version int32, content string) *Document {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-	doc := &Document{URI: uri, Content: content}
-	doc.analyze()
-	s.docs[uri] = doc
+	a := s.startLocked(uri, version, content, nil)
+	s.mu.Unlock()
+	doc, _ := a.Wait()
 	return doc
 }
 
-// Update updates the content of an existing document and re-analyzes it.
-func (s *DocumentStore) Update(uri, content string) *Document {
+// Update starts analyzing new content for an open document, cancelling any
+// analysis of older content still running. The analysis reuses what it can
+// from the last analyzed version. Content with the same hash as the last
+// analyzed version, with no analysis running, is not analyzed again: the
+// result is that version's snapshot under the new version number.
+func (s *DocumentStore) Update(uri string, version int32, content string) *Analysis {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	doc, ok := s.docs[uri]
-	if !ok {
-		doc = &Document{URI: uri}
+	prev := s.docs[uri]
+	if prev != nil && s.pending[uri] == nil && prev.Hash == contentHash(content) {
+		doc := *prev
+		doc.Version = version
+		s.docs[uri] = &doc
+		a := &Analysis{version: version, content: content, cancel: func() {}, done: make(chan struct{}), doc: &doc}
+		close(a.done)
+		return a
+	}
+	return s.startLocked(uri, version, content, prev)
+}
+
+// startLocked registers and runs the analysis of content as the latest
+// version of uri. s.mu must be held.
+func (s *DocumentStore) startLocked(uri string, version int32, content string, prev *Document) *Analysis {
+	if s.shutdown {
+		a := &Analysis{version: version, content: content, cancel: func() {}, done: make(chan struct{})}
+		close(a.done)
+		return a
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	a := &Analysis{version: version, content: content, cancel: cancel, done: make(chan struct{})}
+	if p := s.pending[uri]; p != nil {
+		p.cancel()
+	}
+	s.pending[uri] = a
+	lex := s.lexer
+
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		defer close(a.done)
+		defer cancel()
+		start := time.Now()
+		defer func() {
+			p := recover()
+			logAnalysis(uri, start, a.doc != nil, p)
+			if p == nil {
+				return
+			}
+			panicBundle(s, fmt.Sprintf("panic analyzing %s: %v", uri, p), panicStack())
+			// Get waits while an analysis is pending, so one that panicked
+			// must not stay pending; the last stored version stands.
+			s.mu.Lock()
+			if s.pending[uri] == a {
+				delete(s.pending, uri)
+			}
+			s.mu.Unlock()
+		}()
+		doc := &Document{URI: uri, Version: version, Content: content, Hash: contentHash(content), lexer: lex}
+		if err := s.analyze(ctx, doc, prev); err != nil {
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.pending[uri] != a {
+			return
+		}
+		delete(s.pending, uri)
+		s.stored++
+		doc.resultID = strconv.Itoa(s.stored)
 		s.docs[uri] = doc
+		a.doc = doc
+	}()
+	return a
+}
+
+// analyze analyzes doc against the definitions of the files in its scope,
+// under s's policy and through s's cache.
+func (s *DocumentStore) analyze(ctx context.Context, doc, prev *Document) error {
+	var external []ast.Definition
+	var scope []string
+	for _, f := range s.Workspace.externalFiles(doc.URI) {
+		external = append(external, f.defs...)
+		scope = append(scope, f.uri, f.content)
 	}
-	doc.Content = content
-	doc.analyze()
-	return doc
+	var key string
+	if s.Cache != nil {
+		key = s.validationKey(doc, scope)
+	}
+	return doc.analyze(ctx, prev, s.Policy, external, s.Cache, key)
+}
+
+// validationKey returns the cache key of the validation errors of doc,
+// resolved against the files whose URIs and contents alternate in scope.
+// The policy and the lexer options are part of it, since they change the
+// result.
+func (s *DocumentStore) validationKey(doc *Document, scope []string) string {
+	policy, _ := json.Marshal(s.Policy)
+	parts := []string{"lsp-validate", s.CacheBuild, doc.lexer.Key(), string(policy), doc.URI, doc.Content}
+	return cache.Key(append(parts, scope...)...)
+}
+
+// Publish calls publish to send the results of doc, unless doc is no longer
+// the latest version of its document: a newer version is stored or being
+// analyzed, or the document was closed. It reports whether it published.
+// Publications are serialized, so results that were the latest when checked
+// cannot be sent after those of a newer version.
+func (s *DocumentStore) Publish(doc *Document, publish func()) bool {
+	s.publishMu.Lock()
+	defer s.publishMu.Unlock()
+	s.mu.Lock()
+	latest := doc != nil && s.docs[doc.URI] == doc && s.pending[doc.URI] == nil
+	s.mu.Unlock()
+	if latest {
+		publish()
+	}
+	return latest
+}
+
+// Refresh starts analyzing again the open documents that see any of the
+// files at uris in the workspace, after their indexed content changed, and
+// returns their analyses. A uri may also name a folder. The documents at or
+// under uris are not analyzed again.
+func (s *DocumentStore) Refresh(uris ...string) []*Analysis {
+	return s.refresh(func(doc string) bool {
+		sees := false
+		for _, uri := range uris {
+			if path := uriPath(uri); doc == uri || path != "" && within(uriPath(doc), path) {
+				return false
+			}
+			sees = sees || s.Workspace.Sees(doc, uri)
+		}
+		return sees
+	}, false, true)
+}
+
+// RefreshAll starts analyzing every open document again, after the
+// workspace folders or scope changed, and returns their analyses. Each
+// document's latest content is indexed again first, since a folder change
+// can bring it into the workspace.
+func (s *DocumentStore) RefreshAll() []*Analysis {
+	return s.refresh(func(string) bool { return true }, true, true)
+}
+
+// Lexer returns the lexer options documents are analyzed with.
+func (s *DocumentStore) Lexer() lexer.Options {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lexer
+}
+
+// SetLexer sets the lexer options of the workspace index and of every
+// analysis, as when the keyword aliases change how the same text lexes. It
+// starts analyzing every open document again without reusing anything from
+// its previous analysis, and returns their analyses.
+func (s *DocumentStore) SetLexer(lex lexer.Options) []*Analysis {
+	s.Workspace.SetLexer(lex)
+	s.mu.Lock()
+	s.lexer = lex
+	s.mu.Unlock()
+	return s.refresh(func(string) bool { return true }, false, false)
+}
+
+// refresh restarts the analysis of the latest content of each open document
+// match selects, in URI order, first indexing the content when reindex is
+// set. The analyses reuse the unchanged definitions of the previous ones
+// when reuse is set.
+func (s *DocumentStore) refresh(match func(uri string) bool, reindex, reuse bool) []*Analysis {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	uris := s.openURIs()
+	type version struct {
+		number  int32
+		content string
+	}
+	latest := make(map[string]version, len(uris))
+	for _, uri := range uris {
+		if a := s.pending[uri]; a != nil {
+			latest[uri] = version{a.version, a.content}
+		} else {
+			latest[uri] = version{s.docs[uri].Version, s.docs[uri].Content}
+		}
+		if reindex {
+			s.Workspace.Set(uri, latest[uri].content)
+		}
+	}
+
+	var analyses []*Analysis
+	for _, uri := range uris {
+		if !match(uri) {
+			continue
+		}
+		prev := s.docs[uri]
+		if !reuse {
+			prev = nil
+		}
+		analyses = append(analyses, s.startLocked(uri, latest[uri].number, latest[uri].content, prev))
+	}
+	return analyses
 }
 
-// Get returns a document by URI.
+// Get returns the snapshot of the latest version of a document by URI,
+// waiting for any analysis in progress.
 func (s *DocumentStore) Get(uri string) (*Document, bool) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	doc, ok := s.docs[uri]
-	return doc, ok
+	for {
+		s.mu.Lock()
+		a := s.pending[uri]
+		if a == nil {
+			doc, ok := s.docs[uri]
+			s.mu.Unlock()
+			return doc, ok
+		}
+		s.mu.Unlock()
+		<-a.done
+	}
+}
+
+// URIs returns the URIs of the open documents, sorted.
+func (s *DocumentStore) URIs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.openURIs()
+}
+
+// openURIs returns the URIs of the open documents, analyzed or not, sorted.
+// s.mu must be held.
+func (s *DocumentStore) openURIs() []string {
+	uris := slices.Collect(maps.Keys(s.docs))
+	for uri := range s.pending {
+		if _, ok := s.docs[uri]; !ok {
+			uris = append(uris, uri)
+		}
+	}
+	slices.Sort(uris)
+	return uris
+}
+
+// background runs f in its own goroutine, which Shutdown waits for. After
+// Shutdown it does nothing.
+func (s *DocumentStore) background(f func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.shutdown {
+		return
+	}
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		f()
+	}()
+}
+
+// Shutdown cancels the analyses running and waits for them, and for the
+// diagnostics being published in the background, to finish, so none is
+// cut off when the connection closes. Edits after it are not analyzed.
+func (s *DocumentStore) Shutdown() {
+	s.mu.Lock()
+	s.shutdown = true
+	for uri, a := range s.pending {
+		a.cancel()
+		delete(s.pending, uri)
+	}
+	s.mu.Unlock()
+	s.running.Wait()
 }
 
-// Close removes a document from the store.
+// Close removes a document from the store, cancelling its analysis.
 func (s *DocumentStore) Close(uri string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if a := s.pending[uri]; a != nil {
+		a.cancel()
+		delete(s.pending, uri)
+	}
 	delete(s.docs, uri)
 }
+
+// contentHash returns the content address of a document's content.
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,204 @@
+package server
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/internal/cache"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+)
+
+func TestDocumentUpdateReusesUnchangedDefinitions(t *testing.T) {
+	store := NewDocumentStore()
+	const before = "workflow A():\n" +
+		"    activity X()\n" +
+		"\n" +
+		"activity X():\n" +
+		"    return\n"
+	doc := store.Open("file:///a.twf", 1, before)
+	wf, act := doc.File.Definitions[0], doc.File.Definitions[1]
+
+	// Edit only the workflow body; the activity keeps its line and text.
+	doc, ok := store.Update("file:///a.twf", 2, strings.Replace(before, "activity X()\n\n", "activity Y()\n\n", 1)).Wait()
+	if !ok {
+		t.Fatal("analysis was superseded")
+	}
+	if doc.File.Definitions[1] != act {
+		t.Error("expected the unchanged activity node to be reused")
+	}
+	if doc.File.Definitions[0] == wf {
+		t.Error("expected the edited workflow to be reparsed")
+	}
+	if len(doc.ResolveErrs) != 1 || doc.ResolveErrs[0].Name != "Y" {
+		t.Errorf("expected one undefined activity error for Y, got %v", doc.ResolveErrs)
+	}
+	if refs := doc.Symbols.References(act); len(refs) != 0 {
+		t.Errorf("expected no references to X after the edit, got %d", len(refs))
+	}
+}
+
+func TestDocumentUpdateAnnotationEdit(t *testing.T) {
+	store := NewDocumentStore()
+	const before = "workflow A():\n" +
+		"    activity X()\n" +
+		"\n" +
+		"@owner(\"payments\")\n" +
+		"activity X():\n" +
+		"    return\n"
+	store.Open("file:///a.twf", 1, before)
+
+	// The activity keeps its line and body, but its annotation changed.
+	doc, ok := store.Update("file:///a.twf", 2, strings.Replace(before, "payments", "billing", 1)).Wait()
+	if !ok {
+		t.Fatal("analysis was superseded")
+	}
+	act := doc.File.Definitions[1].(*ast.ActivityDef)
+	if got := act.Annotations[0].Value(); got != "billing" {
+		t.Errorf("expected the edited annotation, got %q", got)
+	}
+	if sig := signatureFor(act); sig != "@owner(\"billing\")\nactivity X()" {
+		t.Errorf("unexpected hover: %q", sig)
+	}
+}
+
+func TestDocumentUpdateSupersedesOlderAnalysis(t *testing.T) {
+	store := NewDocumentStore()
+	store.Open("file:///a.twf", 1, "workflow A():\n    activity X()\n")
+	first := store.Update("file:///a.twf", 2, "workflow A():\n    activity Y()\n")
+	second := store.Update("file:///a.twf", 3, "workflow A():\n    activity Z()\n")
+
+	if doc, ok := second.Wait(); !ok || doc.ResolveErrs[0].Name != "Z" {
+		t.Fatalf("expected the latest version to be analyzed, got %v", doc)
+	}
+	// The first analysis either lost the race or was cancelled; in neither
+	// case may it replace the newer version.
+	first.Wait()
+	doc, _ := store.Get("file:///a.twf")
+	if doc.ResolveErrs[0].Name != "Z" {
+		t.Errorf("expected the stored document to be the latest version, got errors %v", doc.ResolveErrs)
+	}
+}
+
+func TestGetAfterPanickedAnalysis(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	store := NewDocumentStore()
+	const uri = "file:///a.twf"
+	store.Open(uri, 1, "workflow A():\n    close complete\n")
+	// Without a workspace the analysis panics looking up the files in
+	// the document's scope.
+	workspace := store.Workspace
+	store.Workspace = nil
+	store.Update(uri, 2, "workflow B():\n    close complete\n").Wait()
+	store.Workspace = workspace
+
+	got := make(chan *Document)
+	go func() {
+		doc, _ := store.Get(uri)
+		got <- doc
+	}()
+	select {
+	case doc := <-got:
+		if doc == nil || doc.Version != 1 {
+			t.Errorf("expected the last analyzed version, got %v", doc)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Get did not return after an analysis panicked")
+	}
+
+	if doc, ok := store.Update(uri, 3, "workflow C():\n    close complete\n").Wait(); !ok || doc.Version != 3 {
+		t.Errorf("expected the next version to be analyzed, got %v", doc)
+	}
+}
+
+func TestDocumentCache(t *testing.T) {
+	dir := t.TempDir()
+	open := func(content string) *Document {
+		c, err := cache.Open(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		store := NewDocumentStore()
+		store.Cache, store.CacheBuild = c, "test"
+		return store.Open("file:///a.twf", 1, content)
+	}
+	const content = "workflow A():\n    close complete\n    timer(5m)\n"
+	if doc := open(content); len(doc.ValidateErrs) != 1 {
+		t.Fatalf("expected the unreachable timer to be reported, got %v", doc.ValidateErrs)
+	}
+	entries, _ := filepath.Glob(filepath.Join(dir, "*", "*.json"))
+	if len(entries) != 1 {
+		t.Fatalf("cache holds %d entries after one analysis, want 1", len(entries))
+	}
+
+	// Tamper with the entry to tell a cached result from a fresh one. A
+	// second server sharing the directory takes it instead of validating.
+	if err := os.WriteFile(entries[0], []byte(`[{"Msg": "cached", "Line": 1, "Column": 1}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if doc := open(content); len(doc.ValidateErrs) != 1 || doc.ValidateErrs[0].Msg != "cached" {
+		t.Errorf("expected the cached errors, got %v", doc.ValidateErrs)
+	}
+	if doc := open(content + "    timer(1m)\n"); len(doc.ValidateErrs) != 1 || doc.ValidateErrs[0].Msg == "cached" {
+		t.Errorf("expected an edit to be validated afresh, got %v", doc.ValidateErrs)
+	}
+}
+
+func TestPublishDropsStaleVersions(t *testing.T) {
+	store := NewDocumentStore()
+	v1 := store.Open("file:///a.twf", 1, "workflow A():\n    activity X()\n")
+	v2, _ := store.Update("file:///a.twf", 2, "workflow A():\n    activity Y()\n").Wait()
+
+	var published []int32
+	publish := func(doc *Document) bool {
+		return store.Publish(doc, func() { published = append(published, doc.Version) })
+	}
+	if publish(v1) {
+		t.Error("expected version 1 not to be published after version 2")
+	}
+	if !publish(v2) {
+		t.Error("expected the latest version to be published")
+	}
+	store.Close("file:///a.twf")
+	if publish(v2) {
+		t.Error("expected nothing to be published after close")
+	}
+	if !slices.Equal(published, []int32{2}) {
+		t.Errorf("expected only version 2 to be published, got %v", published)
+	}
+}
+
+func TestDocumentSnapshots(t *testing.T) {
+	store := NewDocumentStore()
+	const content = "workflow A():\n    activity X()\n"
+	first := store.Open("file:///a.twf", 1, content)
+
+	// Unchanged content is the same snapshot under the new version.
+	same, ok := store.Update("file:///a.twf", 2, content).Wait()
+	if !ok || same.Version != 2 || same.File != first.File || same.resultID != first.resultID {
+		t.Fatalf("expected version 2 to reuse the analysis of version 1, got %+v", same)
+	}
+	if first.Version != 1 {
+		t.Errorf("expected the version 1 snapshot to be unchanged, got version %d", first.Version)
+	}
+
+	edited, ok := store.Update("file:///a.twf", 3, "workflow A():\n    activity Y()\n").Wait()
+	if !ok || edited.Version != 3 || edited.Hash == first.Hash || edited.ResolveErrs[0].Name != "Y" {
+		t.Fatalf("expected version 3 to be analyzed, got %+v", edited)
+	}
+	if first.ResolveErrs[0].Name != "X" {
+		t.Errorf("expected the version 1 snapshot to keep its diagnostics, got %v", first.ResolveErrs)
+	}
+
+	report := workspaceDiagnostic(store, &workspaceDiagnosticParams{})
+	if len(report.Items) != 1 || report.Items[0].Version == nil || *report.Items[0].Version != 3 {
+		t.Errorf("expected the report to carry version 3, got %+v", report.Items)
+	}
+}
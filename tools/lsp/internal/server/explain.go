@@ -0,0 +1,56 @@
+package server
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/explain"
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// methodExplain is the custom request for the built-in explanation of a
+// construct, as twf explain prints it: by keyword, or for the keyword at a
+// position in a document. It answers an explain.Entry, or null.
+const methodExplain = "twf/explain"
+
+type explainParams struct {
+	Keyword      string                           `json:"keyword,omitempty"`
+	TextDocument *protocol.TextDocumentIdentifier `json:"textDocument,omitempty"`
+	Position     *protocol.Position               `json:"position,omitempty"`
+}
+
+// handleExplain answers twf/explain, reporting whether method is it.
+func handleExplain(store *DocumentStore, context *glsp.Context) (r any, ok bool, err error) {
+	if context.Method != methodExplain {
+		return nil, false, nil
+	}
+	var params explainParams
+	if err := json.Unmarshal(context.Params, &params); err != nil {
+		return nil, true, err
+	}
+	if e, found := explainAt(store, &params); found {
+		return e, true, nil
+	}
+	return nil, true, nil
+}
+
+// explainAt returns the entry the params ask for: the keyword's, or that of
+// the keyword at the position.
+func explainAt(store *DocumentStore, params *explainParams) (explain.Entry, bool) {
+	if params.Keyword != "" {
+		return explain.Lookup(params.Keyword)
+	}
+	if params.TextDocument == nil || params.Position == nil {
+		return explain.Entry{}, false
+	}
+	doc, ok := store.Get(params.TextDocument.URI)
+	if !ok {
+		return explain.Entry{}, false
+	}
+	lines := strings.Split(doc.Content, "\n")
+	if int(params.Position.Line) >= len(lines) {
+		return explain.Entry{}, false
+	}
+	return explain.At(lines[params.Position.Line], int(params.Position.Character))
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/explain"
+	"github.com/tliron/glsp"
+	protocol317 "github.com/tliron/glsp/protocol_3_17"
+)
+
+func TestExplainRequest(t *testing.T) {
+	store := NewDocumentStore()
+	store.Open("file:///a.twf", 1, "workflow W():\n    await one:\n        timer(1h):\n    close complete\n")
+	h := &Handler{Handler: &protocol317.Handler{}, store: store}
+	for _, tc := range []struct{ params, want string }{
+		{`{"keyword": "continue_as_new"}`, "continue_as_new"},
+		{`{"textDocument": {"uri": "file:///a.twf"}, "position": {"line": 1, "character": 11}}`, "await one"},
+		{`{"textDocument": {"uri": "file:///a.twf"}, "position": {"line": 0, "character": 10}}`, ""},
+		{`{"keyword": "goto"}`, ""},
+	} {
+		r, validMethod, validParams, err := h.Handle(&glsp.Context{Method: methodExplain, Params: json.RawMessage(tc.params)})
+		if !validMethod || !validParams || err != nil {
+			t.Fatalf("%s: %t, %t, %v", tc.params, validMethod, validParams, err)
+		}
+		got := ""
+		if r != nil {
+			got = r.(explain.Entry).Keyword
+		}
+		if got != tc.want {
+			t.Errorf("%s: explained %q, want %q", tc.params, got, tc.want)
+		}
+	}
+}
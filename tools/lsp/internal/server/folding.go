@@ -75,18 +75,14 @@ func foldStmts(stmts []ast.Statement, ranges *[]protocol.FoldingRange) {
 		case *ast.AwaitOneBlock:
 			endLine := n.Line
 			for _, c := range n.Cases {
-				if c.AwaitAll != nil {
-					aaEnd := lastLineInStmts(c.AwaitAll.Body, c.Line)
-					if aaEnd > endLine {
-						endLine = aaEnd
-					}
-					addFold(ranges, c.Line, aaEnd)
-				}
-				cEnd := lastLineInStmts(c.Body, c.Line)
+				cEnd := lastLineInStmts(ast.Children(c), c.Line)
 				if cEnd > endLine {
 					endLine = cEnd
 				}
-				addFold(ranges, c.Line, lastLineInStmts(c.Body, c.Line))
+				// An await all case folds as the block the walk visits next.
+				if c.AwaitAll == nil {
+					addFold(ranges, c.Line, cEnd)
+				}
 			}
 			addFold(ranges, n.Line, endLine)
 		case *ast.SwitchBlock:
@@ -105,7 +101,11 @@ func foldStmts(stmts []ast.Statement, ranges *[]protocol.FoldingRange) {
 			addFold(ranges, n.Line, endLine)
 		case *ast.IfStmt:
 			endLine := lastLineInStmts(n.Body, n.Line)
-			endLine = lastLineInStmts(n.ElseBody, endLine)
+			if !n.ElseIf {
+				endLine = lastLineInStmts(n.ElseBody, endLine)
+			}
+			// In an elif chain each clause folds on its own; the chained
+			// IfStmt in ElseBody is visited by the walk.
 			addFold(ranges, n.Line, endLine)
 		case *ast.ForStmt:
 			endLine := lastLineInStmts(n.Body, n.Line)
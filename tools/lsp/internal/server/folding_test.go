@@ -0,0 +1,49 @@
+package server
+
+import (
+	"testing"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestElifFolding(t *testing.T) {
+	const uri = "file:///elif.twf"
+	store := NewDocumentStore()
+	store.Open(uri, 1, `workflow Route(tier: string):
+    if (tier == "gold"):
+        activity Expedite()
+    elif (tier == "silver"):
+        activity Prioritize()
+    else if (tier == "bronze"):
+        activity Queue()
+        activity Notify()
+    else:
+        activity Drop()
+
+activity Expedite()
+activity Prioritize()
+activity Queue()
+activity Notify()
+activity Drop()
+`)
+	ranges, err := foldingRangeHandler(store)(nil, &protocol.FoldingRangeParams{TextDocument: protocol.TextDocumentIdentifier{URI: uri}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[[2]uint32]bool)
+	for _, r := range ranges {
+		got[[2]uint32{r.StartLine, r.EndLine}] = true
+	}
+	// 0-based: the if and elif clauses end at their bodies, and the last
+	// clause folds through the else that follows it.
+	for _, want := range [][2]uint32{{1, 2}, {3, 4}, {5, 9}} {
+		if !got[want] {
+			t.Errorf("no fold %v in %v", want, ranges)
+		}
+	}
+	for _, r := range ranges {
+		if r.StartLine == 1 && r.EndLine != 2 {
+			t.Errorf("if clause folds the whole chain: %v", r)
+		}
+	}
+}
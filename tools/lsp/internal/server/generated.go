@@ -0,0 +1,192 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/codegen"
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// Commands the server executes through workspace/executeCommand. Each takes
+// one TextDocumentPositionParams argument and returns the matching
+// locations in the other artifact, found through the twf generate source
+// maps under the workspace folders; the client decides how to show them.
+const (
+	// CommandOpenGenerated maps a position in a .twf file to the generated
+	// code implementing the workflow, handler, or activity there.
+	CommandOpenGenerated = "twf.openGenerated"
+	// CommandOpenDesign maps a position in a generated file to the design
+	// definition it implements.
+	CommandOpenDesign = "twf.openDesign"
+)
+
+// Commands are the commands the server advertises.
+var Commands = []string{CommandOpenGenerated, CommandOpenDesign}
+
+func executeCommandHandler(store *DocumentStore) protocol.WorkspaceExecuteCommandFunc {
+	return func(context *glsp.Context, params *protocol.ExecuteCommandParams) (any, error) {
+		if !slices.Contains(Commands, params.Command) {
+			return nil, fmt.Errorf("unknown command %q", params.Command)
+		}
+		if len(params.Arguments) != 1 {
+			return nil, fmt.Errorf("%s takes one text document position, got %d arguments", params.Command, len(params.Arguments))
+		}
+		var pos protocol.TextDocumentPositionParams
+		raw, _ := json.Marshal(params.Arguments[0])
+		if err := json.Unmarshal(raw, &pos); err != nil || pos.TextDocument.URI == "" {
+			return nil, fmt.Errorf("%s takes one text document position", params.Command)
+		}
+		found := sourceMaps(store.Workspace.Folders())
+		if params.Command == CommandOpenGenerated {
+			return generatedLocations(store, found, pos), nil
+		}
+		return designLocations(store, found, pos), nil
+	}
+}
+
+// sourceMap is a source map read from dir, the directory its paths are
+// relative to.
+type sourceMap struct {
+	dir string
+	*codegen.SourceMap
+}
+
+// sourceMaps reads the source maps under folders, skipping the directories
+// indexing skips. Unreadable maps are logged and left out.
+func sourceMaps(folders []string) []sourceMap {
+	var out []sourceMap
+	for _, root := range folders {
+		filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				if path != root && (strings.HasPrefix(d.Name(), ".") || d.Name() == "node_modules") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.Name() != codegen.SourceMapFile {
+				return nil
+			}
+			sm, err := codegen.ReadSourceMap(filepath.Dir(path))
+			if err != nil {
+				slog.Warn("source map", "path", path, "error", err.Error())
+				return nil
+			}
+			out = append(out, sourceMap{dir: filepath.Dir(path), SourceMap: sm})
+			return nil
+		})
+	}
+	return out
+}
+
+// generatedLocations returns the regions implementing the node at pos in
+// an open .twf document, in every source map generated from its file.
+func generatedLocations(store *DocumentStore, maps []sourceMap, pos protocol.TextDocumentPositionParams) []protocol.Location {
+	doc, ok := store.Get(pos.TextDocument.URI)
+	if !ok || doc.File == nil {
+		return nil
+	}
+	id := nodeIDAtLine(doc.File, int(pos.Position.Line)+1)
+	if id == "" {
+		return nil
+	}
+	base := filepath.Base(uriPath(pos.TextDocument.URI))
+	locs := []protocol.Location{}
+	for _, sm := range maps {
+		for _, m := range sm.Mappings {
+			if m.Node == id && (m.SourceFile == "" || filepath.Base(m.SourceFile) == base) {
+				locs = append(locs, protocol.Location{
+					URI:   pathURI(filepath.Join(sm.dir, filepath.FromSlash(m.Path))),
+					Range: lineRange(m.StartLine, m.EndLine),
+				})
+			}
+		}
+	}
+	return locs
+}
+
+// designLocations returns the definitions in the indexed .twf files that
+// the region at pos in a generated file implements.
+func designLocations(store *DocumentStore, maps []sourceMap, pos protocol.TextDocumentPositionParams) []protocol.Location {
+	path := uriPath(pos.TextDocument.URI)
+	line := int(pos.Position.Line) + 1
+	files, _ := store.Workspace.snapshot()
+	locs := []protocol.Location{}
+	for _, sm := range maps {
+		rel, err := filepath.Rel(sm.dir, path)
+		if err != nil {
+			continue
+		}
+		for _, m := range sm.Mappings {
+			if filepath.FromSlash(m.Path) != rel || line < m.StartLine || line > m.EndLine {
+				continue
+			}
+			for _, f := range files {
+				if m.SourceFile != "" && filepath.Base(uriPath(f.uri)) != filepath.Base(m.SourceFile) {
+					continue
+				}
+				for _, n := range codegen.SourceNodes(f.defs) {
+					if n.ID == m.Node {
+						locs = append(locs, protocol.Location{URI: f.uri, Range: posToRange(n.Line, n.Column)})
+					}
+				}
+			}
+		}
+	}
+	return locs
+}
+
+// nodeIDAtLine returns the source map node ID of the workflow, handler, or
+// activity whose text holds line, or "". A definition runs until the next
+// one starts, and a handler until the next handler or the workflow's first
+// statement, since handlers are declared before it.
+func nodeIDAtLine(file *ast.File, line int) string {
+	var def ast.Definition
+	for _, d := range file.Definitions {
+		if d.NodeLine() <= line && (def == nil || d.NodeLine() > def.NodeLine()) {
+			def = d
+		}
+	}
+	switch d := def.(type) {
+	case *ast.ActivityDef:
+		return "activity:" + d.Name
+	case *ast.WorkflowDef:
+		bodyStart := 0
+		if len(d.Body) > 0 {
+			bodyStart = d.Body[0].NodeLine()
+		}
+		if bodyStart != 0 && line >= bodyStart {
+			return "workflow:" + d.Name
+		}
+		kind, name, start := "", "", 0
+		handler := func(k, n string, l int) {
+			if l <= line && l > start {
+				kind, name, start = k, n, l
+			}
+		}
+		for _, s := range d.Signals {
+			handler("signal", s.Name, s.Line)
+		}
+		for _, q := range d.Queries {
+			handler("query", q.Name, q.Line)
+		}
+		for _, u := range d.Updates {
+			handler("update", u.Name, u.Line)
+		}
+		if kind == "" {
+			return "workflow:" + d.Name
+		}
+		return kind + ":" + d.Name + "." + name
+	}
+	return ""
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/codegen"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/parser"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/resolver"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestGeneratedCodeCommands(t *testing.T) {
+	design := "workflow Order(id: string):\n" +
+		"    signal Cancel(reason: string):\n" +
+		"        close fail\n" +
+		"\n" +
+		"    activity Charge(id)\n" +
+		"\n" +
+		"activity Charge(id: string):\n" +
+		"    return\n"
+	file, errs := parser.ParseFileAll(design)
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	for _, def := range file.Definitions {
+		ast.SetSourceFile(def, "order.twf")
+	}
+	resolver.Resolve(file)
+	outputs, err := codegen.Generate(file, codegen.Options{Lang: "python"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm, err := codegen.BuildSourceMap(file, outputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{"order.twf": design}
+	for _, out := range append(outputs, sm) {
+		files["gen/"+out.Path] = string(out.Content)
+	}
+	dir := writeWorkspace(t, files)
+	store := NewDocumentStore()
+	store.Workspace.AddFolder(pathURI(dir))
+	uri := pathURI(filepath.Join(dir, "order.twf"))
+	store.Open(uri, 1, design)
+
+	run := func(command, uri string, line uint32) []protocol.Location {
+		t.Helper()
+		result, err := executeCommandHandler(store)(nil, &protocol.ExecuteCommandParams{
+			Command:   command,
+			Arguments: []any{map[string]any{"textDocument": map[string]any{"uri": uri}, "position": map[string]any{"line": line, "character": 0}}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result.([]protocol.Location)
+	}
+
+	// Each line of the design maps to the region of its definition.
+	workflows := pathURI(filepath.Join(dir, "gen", "workflows.py"))
+	generated := strings.Split(files["gen/workflows.py"], "\n")
+	for line, region := range map[uint32]string{0: "Order", 2: "Order.Cancel", 4: "Order"} {
+		locs := run(CommandOpenGenerated, uri, line)
+		if len(locs) != 1 || locs[0].URI != workflows {
+			t.Fatalf("line %d: unexpected locations %+v", line, locs)
+		}
+		if got := generated[locs[0].Range.Start.Line]; !strings.HasSuffix(got, "twf:begin custom "+region) {
+			t.Errorf("line %d: expected region %s, got %q", line, region, got)
+		}
+	}
+	if locs := run(CommandOpenGenerated, uri, 7); len(locs) != 1 || !strings.HasSuffix(locs[0].URI, "/gen/activities.py") {
+		t.Errorf("expected the activity stub, got %+v", locs)
+	}
+
+	// And back: a line inside a region maps to the definition it implements.
+	back := run(CommandOpenDesign, workflows, run(CommandOpenGenerated, uri, 2)[0].Range.Start.Line+1)
+	if len(back) != 1 || back[0].URI != uri || back[0].Range.Start.Line != 1 {
+		t.Errorf("expected signal Cancel at line 2, got %+v", back)
+	}
+	if locs := run(CommandOpenDesign, workflows, 0); len(locs) != 0 {
+		t.Errorf("expected the file header to map nowhere, got %+v", locs)
+	}
+
+	if _, err := executeCommandHandler(store)(nil, &protocol.ExecuteCommandParams{Command: "twf.unknown"}); err == nil {
+		t.Error("expected an error for an unknown command")
+	}
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"encoding/json"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/deps"
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// Graph requests, for editors drawing the call graph of the workflow under
+// the cursor beside the source. They take textDocument/position params and
+// answer a graphResult, or null when the cursor is not in or on a workflow.
+const (
+	// methodWorkflowGraph answers the workflows and activities the
+	// workflow reaches through calls.
+	methodWorkflowGraph = "twf/workflowGraph"
+	// methodCallers answers the workflows and operations that reach it.
+	methodCallers = "twf/callers"
+)
+
+// graphResult is a call graph rooted at Workflow, as twf graph --json
+// prints it, with where each of its nodes is defined.
+type graphResult struct {
+	Workflow  string         `json:"workflow"`
+	Graph     *deps.Graph    `json:"graph"`
+	Locations []nodeLocation `json:"locations"`
+}
+
+// nodeLocation is where the graph node of a kind and name is defined, so
+// clicking the node can open its source.
+type nodeLocation struct {
+	Name     string            `json:"name"`
+	Kind     string            `json:"kind"`
+	Location protocol.Location `json:"location"`
+}
+
+// handleGraphRequests answers the graph requests, reporting whether method
+// is one of them.
+func handleGraphRequests(store *DocumentStore, context *glsp.Context) (r any, ok bool, err error) {
+	var callers bool
+	switch context.Method {
+	case methodWorkflowGraph:
+	case methodCallers:
+		callers = true
+	default:
+		return nil, false, nil
+	}
+	var params protocol.TextDocumentPositionParams
+	if err := json.Unmarshal(context.Params, &params); err != nil {
+		return nil, true, err
+	}
+	result, err := workflowGraph(store, &params, callers)
+	if result == nil {
+		// A typed nil would encode as an empty object rather than null.
+		return nil, true, err
+	}
+	return result, true, err
+}
+
+// workflowGraph returns the call graph of the workflow at the params'
+// position, and of the workspace files the document resolves against: the
+// definitions it reaches, or with callers those reaching it.
+func workflowGraph(store *DocumentStore, params *protocol.TextDocumentPositionParams, callers bool) (*graphResult, error) {
+	doc, ok := store.Get(params.TextDocument.URI)
+	if !ok || doc.File == nil {
+		return nil, nil
+	}
+	wf := workflowAt(doc.File, int(params.Position.Line)+1)
+	if wf == nil {
+		return nil, nil
+	}
+
+	defs := store.Workspace.External(doc.URI)
+	defs = append(defs[:len(defs):len(defs)], doc.File.Definitions...)
+	full := deps.Extract(&ast.File{Definitions: defs})
+	full.Link()
+	graph, err := full.Filter(deps.FilterOptions{Root: wf.Name, Depth: -1, Callers: callers})
+	if err != nil {
+		return nil, err
+	}
+
+	locations := []nodeLocation{}
+	for _, n := range graph.Nodes {
+		uri := n.SourceFile
+		if uri == "" {
+			uri = doc.URI
+		}
+		line, col := uint32(max(n.Line-1, 0)), uint32(max(n.Column-1, 0))
+		locations = append(locations, nodeLocation{
+			Name: n.Name,
+			Kind: n.Kind,
+			Location: protocol.Location{
+				URI: uri,
+				Range: protocol.Range{
+					Start: protocol.Position{Line: line, Character: col},
+					End:   protocol.Position{Line: line, Character: col + uint32(len(n.Name))},
+				},
+			},
+		})
+	}
+	return &graphResult{Workflow: wf.Name, Graph: graph, Locations: locations}, nil
+}
+
+// workflowAt returns the workflow a reference on line names, as in a call
+// or a worker's workflow list, or else the workflow whose definition spans
+// line. It returns nil when there is neither.
+func workflowAt(file *ast.File, line int) *ast.WorkflowDef {
+	if node := findNodeAtLine(file, line); node != nil {
+		if wf, ok := resolvedTarget(node).(*ast.WorkflowDef); ok {
+			return wf
+		}
+	}
+	wf, _ := enclosingDefinition(file, line).(*ast.WorkflowDef)
+	return wf
+}
+
+// enclosingDefinition returns the definition of file spanning line, or nil
+// before the first.
+func enclosingDefinition(file *ast.File, line int) ast.Definition {
+	var enclosing ast.Definition
+	for _, def := range file.Definitions {
+		if definitionStart(def) > line {
+			break
+		}
+		enclosing = def
+	}
+	return enclosing
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+	protocol317 "github.com/tliron/glsp/protocol_3_17"
+)
+
+func TestGraphRequests(t *testing.T) {
+	dir := writeWorkspace(t, map[string]string{
+		"checkout.twf": "workflow Checkout():\n    workflow Order()\n",
+		"charge.twf":   "activity Charge():\n    return\n",
+	})
+	store := NewDocumentStore()
+	store.Workspace.AddFolder(pathURI(dir))
+	uri := pathURI(filepath.Join(dir, "order.twf"))
+	store.Open(uri, 1, "workflow Order():\n    activity Charge()\n    workflow Audit()\n\nworkflow Audit():\n    close complete\n")
+	h := &Handler{Handler: &protocol317.Handler{}, store: store}
+
+	request := func(method string, line int) graphResult {
+		t.Helper()
+		params, _ := json.Marshal(protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Position:     protocol.Position{Line: uint32(line)},
+		})
+		r, validMethod, validParams, err := h.Handle(&glsp.Context{Method: method, Params: params})
+		if !validMethod || !validParams || err != nil {
+			t.Fatalf("%s: %t, %t, %v", method, validMethod, validParams, err)
+		}
+		var result graphResult
+		if r != nil {
+			result = *r.(*graphResult)
+		}
+		return result
+	}
+	names := func(r graphResult) string {
+		var out []string
+		for _, l := range r.Locations {
+			out = append(out, l.Kind+" "+l.Name)
+		}
+		return strings.Join(out, ", ")
+	}
+
+	// Inside Order, the graph follows its calls into charge.twf.
+	r := request(methodWorkflowGraph, 1)
+	if r.Workflow != "Order" || names(r) != "activity Charge, workflow Order, workflow Audit" {
+		t.Fatalf("workflow graph: %s rooted at %q", names(r), r.Workflow)
+	}
+	if l := r.Locations[0].Location; l.URI != pathURI(filepath.Join(dir, "charge.twf")) || l.Range.Start.Line != 0 {
+		t.Errorf("Charge location: %+v", l)
+	}
+	if l := r.Locations[2].Location; l.URI != uri || l.Range.Start.Line != 4 {
+		t.Errorf("Audit location: %+v", l)
+	}
+
+	// On the call to Audit, the callers are those of Audit, across files.
+	if r := request(methodCallers, 2); r.Workflow != "Audit" || names(r) != "workflow Checkout, workflow Order, workflow Audit" {
+		t.Errorf("callers: %s rooted at %q", names(r), r.Workflow)
+	}
+
+	h.store.Open(uri, 2, "activity Charge2():\n    return\n")
+	if r, _, _, err := h.Handle(&glsp.Context{Method: methodCallers, Params: json.RawMessage(`{"textDocument": {"uri": "` + uri + `"}, "position": {"line": 0}}`)}); r != nil || err != nil {
+		t.Errorf("expected null outside a workflow, got %v, %v", r, err)
+	}
+}
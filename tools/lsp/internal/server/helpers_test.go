@@ -1,6 +1,8 @@
 package server
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
@@ -148,26 +150,6 @@ func TestFindCallInStatementsSwitch(t *testing.T) {
 	}
 }
 
-func TestFindReturnStatements(t *testing.T) {
-	body := mustParseWorkflowBody(t,
-		"    if (cond):\n"+
-			"        return x\n"+
-			"    return y\n")
-
-	returns := findReturnStatements(body)
-	if len(returns) != 2 {
-		t.Fatalf("expected 2 return statements, got %d", len(returns))
-	}
-}
-
-func TestFindReturnStatementsNone(t *testing.T) {
-	body := mustParseWorkflowBody(t, "    activity Foo()\n")
-	returns := findReturnStatements(body)
-	if len(returns) != 0 {
-		t.Errorf("expected 0 return statements, got %d", len(returns))
-	}
-}
-
 func TestCollectRefsInStmts(t *testing.T) {
 	body := mustParseWorkflowBody(t,
 		"    activity Foo()\n"+
@@ -209,3 +191,20 @@ func TestCollectRefsInStmtsWorkflow(t *testing.T) {
 		t.Fatalf("expected 0 refs for activity 'Child', got %d", len(refs))
 	}
 }
+
+// writeWorkspace writes files, keyed by slash-separated path, under a
+// temporary directory and returns its path.
+func writeWorkspace(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
@@ -2,9 +2,15 @@ package server
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/eval"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/options"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/resolver"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/validator"
 	"github.com/tliron/glsp"
 	protocol "github.com/tliron/glsp/protocol_3_16"
 )
@@ -27,11 +33,36 @@ func hoverHandler(store *DocumentStore) protocol.TextDocumentHoverFunc {
 		if sig == "" {
 			return nil, nil
 		}
+		if ep := hoverEndpoint(node); ep != nil {
+			for _, op := range endpointOperations(doc.Symbols, ep) {
+				sig += "\n  operation " + op
+			}
+		}
+		value := fmt.Sprintf("```twf\n%s\n```", sig)
+		if wf := hoverWorkflow(node); wf != nil && wf.Description != "" {
+			value = wf.Description + "\n\n" + value
+		}
+		if wf, ok := node.(*ast.WorkflowDef); ok && doc.Symbols != nil {
+			if summary := validator.TimeoutSummary(validator.TimeoutPaths(doc.Symbols, wf, store.Policy.OptionDefaults)); summary != "" {
+				value += "\n\n" + summary
+			}
+		}
+		if preview := valuePreview(doc.File, node); preview != "" {
+			value += "\n\n" + preview
+		}
+		if block := hoverAwaitAll(node); block != nil {
+			value += "\n\n" + awaitAllPolicy(block.Options)
+		}
+		if callOptionsUnparsed(node) {
+			value += "\n\nThe call's options block does not parse, so its effective options are unknown."
+		} else if eff := hoverOptions(node, store.Policy.OptionDefaults); len(eff) > 0 {
+			value += "\n\nEffective options:\n" + strings.TrimSuffix(effectiveOptions(eff, ""), "\n")
+		}
 
 		return &protocol.Hover{
 			Contents: protocol.MarkupContent{
 				Kind:  protocol.MarkupKindMarkdown,
-				Value: fmt.Sprintf("```twf\n%s\n```", sig),
+				Value: value,
 			},
 		}, nil
 	}
@@ -64,14 +95,20 @@ func signatureFor(node ast.Node) string {
 		}
 		return fmt.Sprintf("activity %s(%s)", n.Activity.Name, n.Args)
 	case *ast.WorkflowCall:
+		var sig string
 		if n.Workflow.Resolved != nil {
-			return workflowSig(n.Workflow.Resolved)
+			sig = workflowSig(n.Workflow.Resolved)
+		} else {
+			prefix := "workflow"
+			if n.Mode == ast.CallDetach {
+				prefix = "detach workflow"
+			}
+			sig = fmt.Sprintf("%s %s(%s)", prefix, n.Workflow.Name, n.Args)
 		}
-		prefix := "workflow"
-		if n.Mode == ast.CallDetach {
-			prefix = "detach workflow"
+		if n.ID != "" {
+			sig += fmt.Sprintf("\n  id %q", n.ID)
 		}
-		return fmt.Sprintf("%s %s(%s)", prefix, n.Workflow.Name, n.Args)
+		return sig
 	case *ast.WorkerDef:
 		sig := fmt.Sprintf("worker %s", n.Name)
 		if len(n.Workflows) > 0 || len(n.Activities) > 0 || len(n.Services) > 0 {
@@ -182,8 +219,27 @@ func signatureFor(node ast.Node) string {
 			sig += "\n" + strings.Join(ops, "\n")
 		}
 		return sig
+	case *ast.ConstDef:
+		return constSig(n)
+	case *ast.Ref[*ast.ConstDef]:
+		if n.Resolved != nil {
+			return constSig(n.Resolved)
+		}
+		return fmt.Sprintf("const %s (unresolved)", n.Name)
+	case *ast.EnumDef:
+		return enumSig(n)
 	case *ast.AwaitStmt:
 		return signatureForAwait(n)
+	case *ast.AwaitAllBlock:
+		return awaitAllSig(n)
+	case *ast.AwaitOneCase:
+		return signatureForAwaitOneCase(n)
+	case *ast.CloseStmt:
+		sig := "close " + closeReasonKeyword(n.Reason)
+		if n.Args != "" {
+			sig += "(" + n.Args + ")"
+		}
+		return sig
 	default:
 		return ""
 	}
@@ -195,7 +251,7 @@ func workflowSig(w *ast.WorkflowDef) string {
 	if w.ReturnType != "" {
 		parts = append(parts, "-> ("+w.ReturnType+")")
 	}
-	return strings.Join(parts, " ")
+	return annotationLines(w.Annotations) + strings.Join(parts, " ")
 }
 
 func activitySig(a *ast.ActivityDef) string {
@@ -204,7 +260,37 @@ func activitySig(a *ast.ActivityDef) string {
 	if a.ReturnType != "" {
 		parts = append(parts, "-> ("+a.ReturnType+")")
 	}
-	return strings.Join(parts, " ")
+	return annotationLines(a.Annotations) + strings.Join(parts, " ")
+}
+
+// annotationLines renders annotations one per line, as written above a
+// definition.
+func annotationLines(anns []*ast.Annotation) string {
+	var b strings.Builder
+	for _, a := range anns {
+		b.WriteString("@" + a.Name)
+		if a.Args != "" {
+			b.WriteString("(" + a.Args + ")")
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func constSig(c *ast.ConstDef) string {
+	value := c.Value
+	if c.ValueType == "string" {
+		value = strconv.Quote(value)
+	}
+	return fmt.Sprintf("const %s = %s", c.Name, value)
+}
+
+func enumSig(e *ast.EnumDef) string {
+	names := make([]string, len(e.Values))
+	for i, v := range e.Values {
+		names[i] = v.Name
+	}
+	return fmt.Sprintf("enum %s: %s", e.Name, strings.Join(names, ", "))
 }
 
 // signatureForAwait builds a human-readable signature for an await statement.
@@ -214,36 +300,33 @@ func signatureForAwait(n *ast.AwaitStmt) string {
 	}
 	switch t := n.Target.(type) {
 	case *ast.TimerTarget:
-		return fmt.Sprintf("await timer(%s)", t.Duration)
+		if t.Const.Resolved != nil {
+			// Show the constant as written alongside its inlined value.
+			return fmt.Sprintf("await timer(%s = %s)", t.Const.Name, t.Duration) + arrow(t.Result)
+		}
+		return fmt.Sprintf("await timer(%s)", t.Duration) + arrow(t.Result)
 	case *ast.SignalTarget:
-		if t.Params != "" {
-			return fmt.Sprintf("await signal %s -> %s", t.Signal.Name, t.Params)
+		var params string
+		if t.Signal.Resolved != nil {
+			params = t.Signal.Resolved.Params
 		}
-		return fmt.Sprintf("await signal %s", t.Signal.Name)
+		return fmt.Sprintf("await signal %s", t.Signal.Name) + payloadArrow(t.Params, params)
 	case *ast.UpdateTarget:
-		if t.Params != "" {
-			return fmt.Sprintf("await update %s -> %s", t.Update.Name, t.Params)
+		var params string
+		if t.Update.Resolved != nil {
+			params = t.Update.Resolved.Params
 		}
-		return fmt.Sprintf("await update %s", t.Update.Name)
+		return fmt.Sprintf("await update %s", t.Update.Name) + payloadArrow(t.Params, params)
 	case *ast.ActivityTarget:
-		if t.Result != "" {
-			return fmt.Sprintf("await activity %s(%s) -> %s", t.Activity.Name, t.Args, t.Result)
-		}
-		return fmt.Sprintf("await activity %s(%s)", t.Activity.Name, t.Args)
+		return fmt.Sprintf("await activity %s(%s)", t.Activity.Name, t.Args) + arrow(t.Result)
 	case *ast.WorkflowTarget:
 		prefix := "await workflow"
 		if t.Mode == ast.CallDetach {
 			prefix = "await detach workflow"
 		}
-		if t.Result != "" {
-			return fmt.Sprintf("%s %s(%s) -> %s", prefix, t.Workflow.Name, t.Args, t.Result)
-		}
-		return fmt.Sprintf("%s %s(%s)", prefix, t.Workflow.Name, t.Args)
+		return fmt.Sprintf("%s %s(%s)", prefix, t.Workflow.Name, t.Args) + arrow(t.Result)
 	case *ast.NexusTarget:
-		sig := fmt.Sprintf("await nexus %s %s.%s(%s)", t.Endpoint.Name, t.Service.Name, t.Operation.Name, t.Args)
-		if t.Result != "" {
-			sig += " -> " + t.Result
-		}
+		sig := fmt.Sprintf("await nexus %s %s.%s(%s)", t.Endpoint.Name, t.Service.Name, t.Operation.Name, t.Args) + arrow(t.Result)
 		if t.Endpoint.Resolved != nil {
 			tq := extractEndpointTaskQueue(t.Endpoint.Resolved)
 			if tq != "" {
@@ -251,10 +334,401 @@ func signatureForAwait(n *ast.AwaitStmt) string {
 			}
 		}
 		return sig
+	case *ast.IdentTarget:
+		return "await " + t.Name + arrow(t.Result)
 	}
 	return "await"
 }
 
+// payloadArrow renders the names an await binds from a signal or update
+// payload, each typed by the handler parameter it binds when that has a
+// type.
+func payloadArrow(bound, params string) string {
+	if bound == "" {
+		return ""
+	}
+	names, ps := ast.SplitList(bound), ast.ParseParams(params)
+	for i, name := range names {
+		name = strings.TrimSpace(name)
+		if i < len(ps) && ps[i].Type != "" {
+			name += ": " + ps[i].Type
+		}
+		names[i] = name
+	}
+	return " -> (" + strings.Join(names, ", ") + ")"
+}
+
+// arrow renders an optional result binding, in parens when it binds the
+// values of a multi-value return.
+func arrow(result string) string {
+	switch names := ast.SplitList(result); len(names) {
+	case 0:
+		return ""
+	case 1:
+		return " -> " + names[0]
+	default:
+		return " -> (" + strings.Join(names, ", ") + ")"
+	}
+}
+
+// signatureForAwaitOneCase builds a signature for an await one case,
+// including its guard when present.
+func signatureForAwaitOneCase(n *ast.AwaitOneCase) string {
+	if n.AwaitAll != nil {
+		return awaitAllSig(n.AwaitAll)
+	}
+	sig := strings.TrimPrefix(signatureForAwait(&ast.AwaitStmt{Target: n.Target}), "await ")
+	if n.Guard != "" {
+		sig += fmt.Sprintf(" if (%s)", n.Guard)
+	}
+	return sig
+}
+
+func awaitAllSig(n *ast.AwaitAllBlock) string {
+	if n.Options == nil {
+		return "await all"
+	}
+	return "await all " + n.Options.String()
+}
+
+// closeReasonKeyword returns the keyword following close for r.
+func closeReasonKeyword(r ast.CloseReason) string {
+	switch r {
+	case ast.CloseFailWorkflow:
+		return "fail"
+	case ast.CloseContinueAsNew:
+		return "continue_as_new"
+	}
+	return "complete"
+}
+
+// valuePreview describes the value a hovered timer or close statement
+// takes: how long a timer waits, and what a close hands back to the
+// workflow's caller. It is empty for other nodes.
+func valuePreview(file *ast.File, node ast.Node) string {
+	switch n := node.(type) {
+	case *ast.AwaitStmt:
+		if t, ok := n.Target.(*ast.TimerTarget); ok {
+			return timerPreview(enclosingDefinition(file, n.Line), n, t)
+		}
+	case *ast.AwaitOneCase:
+		if t, ok := n.Target.(*ast.TimerTarget); ok {
+			return timerPreview(enclosingDefinition(file, n.Line), n, t)
+		}
+	case *ast.CloseStmt:
+		if wf, ok := enclosingDefinition(file, n.Line).(*ast.WorkflowDef); ok {
+			return closePreview(wf, n)
+		}
+	}
+	return ""
+}
+
+// timerPreview spells out how long timer t of stmt waits and, when the
+// await timer statements run before it on its path through def all have
+// literal durations, how long after the workflow or handler starts it
+// fires at the earliest. Other waits on the path are not counted.
+func timerPreview(def ast.Definition, stmt ast.Statement, t *ast.TimerTarget) string {
+	d, ok := eval.ParseDuration(t.Duration)
+	if !ok {
+		return ""
+	}
+	preview := "Fires " + humanDuration(d) + " after reaching this point"
+	before, scope := timersOnPath(def, stmt)
+	var total time.Duration
+	for _, lit := range before {
+		bd, ok := eval.ParseDuration(lit)
+		if !ok {
+			return preview + "."
+		}
+		total += bd
+	}
+	if len(before) == 0 {
+		return preview + "."
+	}
+	return fmt.Sprintf("%s, and at least %s into the %s with %s awaited before it on this path.",
+		preview, humanDuration(total+d), scope, pluralize(len(before), "timer"))
+}
+
+// timersOnPath returns the durations of the await timer statements run
+// before stmt on its path through def, and whether that path is the body
+// of the workflow or of one of its handlers.
+func timersOnPath(def ast.Definition, stmt ast.Statement) ([]string, string) {
+	wf, ok := def.(*ast.WorkflowDef)
+	if !ok {
+		return nil, ""
+	}
+	if before, found := timersBefore(wf.Body, stmt); found {
+		return before, "workflow"
+	}
+	var handlers [][]ast.Statement
+	for _, s := range wf.Signals {
+		handlers = append(handlers, s.Body)
+	}
+	for _, u := range wf.Updates {
+		handlers = append(handlers, u.Body)
+	}
+	for _, body := range handlers {
+		if before, found := timersBefore(body, stmt); found {
+			return before, "handler"
+		}
+	}
+	return nil, ""
+}
+
+// timersBefore returns the durations of the await timer statements run
+// one after another before target in stmts, reporting whether target is
+// in them. Only the branch holding target counts, and the statements of an
+// await all or the cases of an await one run side by side, so none of
+// them precede another.
+func timersBefore(stmts []ast.Statement, target ast.Statement) ([]string, bool) {
+	var timers []string
+	for _, s := range stmts {
+		if s == target {
+			return timers, true
+		}
+		for _, path := range sequentialPaths(s) {
+			if inner, found := timersBefore(path, target); found {
+				return append(timers, inner...), true
+			}
+		}
+		if a, ok := s.(*ast.AwaitStmt); ok {
+			if t, ok := a.Target.(*ast.TimerTarget); ok {
+				timers = append(timers, t.Duration)
+			}
+		}
+	}
+	return nil, false
+}
+
+// sequentialPaths returns the statement lists nested in s that each run
+// from their start. The body of an await one timer case runs after its
+// timer, which leads it as an await statement.
+func sequentialPaths(s ast.Statement) [][]ast.Statement {
+	switch s := s.(type) {
+	case *ast.IfStmt:
+		return [][]ast.Statement{s.Body, s.ElseBody}
+	case *ast.SwitchBlock:
+		paths := make([][]ast.Statement, 0, len(s.Cases)+1)
+		for _, c := range s.Cases {
+			paths = append(paths, []ast.Statement{c})
+		}
+		return append(paths, s.Default)
+	case *ast.AwaitAllBlock:
+		paths := make([][]ast.Statement, len(s.Body))
+		for i, child := range s.Body {
+			paths[i] = []ast.Statement{child}
+		}
+		return paths
+	case *ast.AwaitOneBlock:
+		paths := make([][]ast.Statement, len(s.Cases))
+		for i, c := range s.Cases {
+			paths[i] = []ast.Statement{c}
+		}
+		return paths
+	case *ast.AwaitOneCase:
+		paths := [][]ast.Statement{s.Body}
+		if t, ok := s.Target.(*ast.TimerTarget); ok {
+			paths[0] = append([]ast.Statement{&ast.AwaitStmt{Pos: s.Pos, Target: t}}, s.Body...)
+		}
+		if s.AwaitAll != nil {
+			paths = append(paths, []ast.Statement{s.AwaitAll})
+		}
+		return paths
+	case *ast.SwitchCase:
+		return [][]ast.Statement{s.Body}
+	case *ast.ForStmt:
+		return [][]ast.Statement{s.Body}
+	}
+	return nil
+}
+
+// closePreview names what close statement c of wf hands back: the
+// workflow's declared return type for close complete, and its parameters
+// for the next run of close continue_as_new.
+func closePreview(wf *ast.WorkflowDef, c *ast.CloseStmt) string {
+	switch c.Reason {
+	case ast.CloseComplete:
+		if wf.ReturnType == "" {
+			return fmt.Sprintf("Completes `%s`, which declares no return type.", wf.Name)
+		}
+		return fmt.Sprintf("Completes `%s`, which returns `(%s)`.", wf.Name, wf.ReturnType)
+	case ast.CloseContinueAsNew:
+		return fmt.Sprintf("Starts a new run of `%s(%s)`.", wf.Name, wf.Params)
+	}
+	return fmt.Sprintf("Fails `%s` with an error or a message string.", wf.Name)
+}
+
+// humanDuration spells d in days down to milliseconds, as in "1 day 12
+// hours".
+func humanDuration(d time.Duration) string {
+	units := []struct {
+		size time.Duration
+		name string
+	}{{24 * time.Hour, "day"}, {time.Hour, "hour"}, {time.Minute, "minute"}, {time.Second, "second"}, {time.Millisecond, "millisecond"}}
+	var parts []string
+	for _, u := range units {
+		if n := d / u.size; n > 0 {
+			parts = append(parts, pluralize(int(n), u.name))
+			d -= n * u.size
+		}
+	}
+	if len(parts) == 0 {
+		return "0 seconds"
+	}
+	return strings.Join(parts, " ")
+}
+
+// pluralize returns n and word, pluralized unless n is 1.
+func pluralize(n int, word string) string {
+	if n == 1 {
+		return "1 " + word
+	}
+	return fmt.Sprintf("%d %ss", n, word)
+}
+
+// hoverWorkflow returns the workflow a hovered node defines or calls, or nil.
+func hoverWorkflow(node ast.Node) *ast.WorkflowDef {
+	switch n := node.(type) {
+	case *ast.WorkflowDef:
+		return n
+	case *ast.WorkflowCall:
+		return n.Workflow.Resolved
+	case *ast.Ref[*ast.WorkflowDef]:
+		return n.Resolved
+	}
+	return nil
+}
+
+// hoverOptions returns the effective options of a hovered call, or nil.
+func hoverOptions(node ast.Node, defaults *options.Config) options.Effective {
+	switch n := node.(type) {
+	case *ast.ActivityCall:
+		return options.Resolve(n.Activity.Resolved, n, defaults)
+	case *ast.WorkflowCall:
+		return options.Resolve(n.Workflow.Resolved, n, defaults)
+	case *ast.NexusCall:
+		return options.Resolve(nil, n, defaults)
+	}
+	return nil
+}
+
+// callOptionsUnparsed reports whether node is a call whose options block
+// failed to parse.
+func callOptionsUnparsed(node ast.Node) bool {
+	switch n := node.(type) {
+	case *ast.ActivityCall:
+		return n.Options.Unparsed()
+	case *ast.WorkflowCall:
+		return n.Options.Unparsed()
+	case *ast.NexusCall:
+		return n.Options.Unparsed()
+	}
+	return false
+}
+
+// effectiveOptions lists opts as markdown, each with the layer setting it,
+// nested options indented below their block.
+func effectiveOptions(opts []options.Option, indent string) string {
+	var b strings.Builder
+	for _, o := range opts {
+		if o.Nested != nil {
+			fmt.Fprintf(&b, "%s- `%s`\n%s", indent, o.Key, effectiveOptions(o.Nested, indent+"  "))
+			continue
+		}
+		fmt.Fprintf(&b, "%s- `%s: %s` (%s)\n", indent, o.Key, o.Value, o.Layer)
+	}
+	return b.String()
+}
+
+// hoverAwaitAll returns the await all block a hovered node opens, or nil.
+func hoverAwaitAll(node ast.Node) *ast.AwaitAllBlock {
+	switch n := node.(type) {
+	case *ast.AwaitAllBlock:
+		return n
+	case *ast.AwaitOneCase:
+		return n.AwaitAll
+	}
+	return nil
+}
+
+// awaitAllPolicy describes what an await all block does when one of its
+// operations fails.
+func awaitAllPolicy(o *ast.AwaitAllOptions) string {
+	switch {
+	case o == nil || o.OnError == ast.AwaitAllFail:
+		return "Fails as soon as one operation fails."
+	case o.MinSuccess > 0:
+		return fmt.Sprintf("Waits for every operation, even after failures, and fails unless at least %d succeed.", o.MinSuccess)
+	default:
+		return "Waits for every operation, even after failures."
+	}
+}
+
+// hoverEndpoint returns the resolved nexus endpoint a hovered node declares
+// or calls, or nil.
+func hoverEndpoint(node ast.Node) *ast.NamespaceEndpoint {
+	var target ast.AsyncTarget
+	switch n := node.(type) {
+	case *ast.NamespaceEndpoint:
+		return n
+	case *ast.NexusCall:
+		return n.Endpoint.Resolved
+	case *ast.AwaitStmt:
+		target = n.Target
+	case *ast.AwaitOneCase:
+		target = n.Target
+	}
+	if t, ok := target.(*ast.NexusTarget); ok {
+		return t.Endpoint.Resolved
+	}
+	return nil
+}
+
+// endpointOperations lists the operations served behind a nexus endpoint,
+// as service.operation: those of the nexus services registered by the
+// workers of its namespace that poll its task queue. Workers and services
+// are looked up by name when their references were resolved elsewhere, as
+// for a namespace in another workspace file.
+func endpointOperations(symbols *resolver.SymbolTable, ep *ast.NamespaceEndpoint) []string {
+	if symbols == nil {
+		return nil
+	}
+	ns := symbols.Namespaces[ep.Namespace]
+	if ns == nil {
+		return nil
+	}
+	tq := extractEndpointTaskQueue(ep)
+	var ops []string
+	seen := make(map[*ast.NexusServiceDef]bool)
+	for i := range ns.Workers {
+		w := &ns.Workers[i]
+		if extractWorkerTaskQueue(w) != tq {
+			continue
+		}
+		worker := w.Worker.Resolved
+		if worker == nil {
+			worker = symbols.Workers[w.Worker.Name]
+		}
+		if worker == nil {
+			continue
+		}
+		for _, ref := range worker.Services {
+			svc := ref.Resolved
+			if svc == nil {
+				svc = symbols.NexusServices[ref.Name]
+			}
+			if svc == nil || seen[svc] {
+				continue
+			}
+			seen[svc] = true
+			for _, op := range svc.Operations {
+				ops = append(ops, svc.Name+"."+op.Name)
+			}
+		}
+	}
+	return ops
+}
+
 // extractEndpointTaskQueue returns the task_queue value from a namespace endpoint's options.
 func extractEndpointTaskQueue(ep *ast.NamespaceEndpoint) string {
 	if ep == nil || ep.Options == nil {
@@ -0,0 +1,437 @@
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/options"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/parser"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/resolver"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/validator"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestConstDefinitionAndHover(t *testing.T) {
+	input := "const approvalTimeout = 7d\n" +
+		"workflow Test():\n" +
+		"    await timer(approvalTimeout)\n" +
+		"    activity A()\n" +
+		"        options:\n" +
+		"            start_to_close_timeout: approvalTimeout\n" +
+		"activity A():\n" +
+		"    return\n"
+	file, err := parser.ParseFile(input)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	resolver.Resolve(file)
+
+	for _, line := range []int{3, 6} {
+		node := findNodeAtLine(file, line)
+		if node == nil {
+			t.Fatalf("line %d: no node found", line)
+		}
+		def := resolvedTarget(node)
+		if def == nil || def.NodeLine() != 1 {
+			t.Errorf("line %d: expected definition on line 1, got %v", line, def)
+		}
+	}
+	if sig := signatureFor(findNodeAtLine(file, 3)); sig != "await timer(approvalTimeout = 7d)" {
+		t.Errorf("unexpected timer hover: %q", sig)
+	}
+	if sig := signatureFor(findNodeAtLine(file, 6)); sig != "const approvalTimeout = 7d" {
+		t.Errorf("unexpected option hover: %q", sig)
+	}
+}
+
+func TestWorkflowCallIDHover(t *testing.T) {
+	input := "workflow Order(order: Order):\n" +
+		"    workflow Ship(order) id \"ship-{order.id}\" -> shipped\n" +
+		"workflow Ship(order: Order):\n" +
+		"    return\n"
+	file, err := parser.ParseFile(input)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	resolver.Resolve(file)
+
+	want := "workflow Ship(order: Order)\n  id \"ship-{order.id}\""
+	if sig := signatureFor(findNodeAtLine(file, 2)); sig != want {
+		t.Errorf("unexpected call hover: %q, want %q", sig, want)
+	}
+}
+
+func TestAwaitPayloadHover(t *testing.T) {
+	input := "workflow Order():\n" +
+		"    signal Approved(approver: string, at: time):\n" +
+		"        log(approver)\n" +
+		"    await signal Approved -> (who, when)\n" +
+		"    await one:\n" +
+		"        signal Approved -> who:\n" +
+		"            close complete\n" +
+		"        signal Missing -> (x):\n" +
+		"            close fail\n"
+	file, _ := parser.ParseFile(input)
+	resolver.Resolve(file)
+
+	for line, want := range map[int]string{
+		4: "await signal Approved -> (who: string, when: time)",
+		6: "signal Approved -> (who: string)",
+		8: "signal Missing -> (x)",
+	} {
+		if sig := signatureFor(findNodeAtLine(file, line)); sig != want {
+			t.Errorf("line %d: hover %q, want %q", line, sig, want)
+		}
+	}
+}
+
+func TestAwaitAllHover(t *testing.T) {
+	const uri = "file:///await_all.twf"
+	content := "workflow Order():\n" +
+		"    await all options(onError: continue, minSuccess: 2):\n" +
+		"        activity A()\n" +
+		"    await one:\n" +
+		"        await all:\n" +
+		"            activity A()\n"
+	store := NewDocumentStore()
+	store.Open(uri, 1, content)
+
+	for line, want := range map[uint32]string{
+		1: "```twf\nawait all options(onError: continue, minSuccess: 2)\n```\n\nWaits for every operation, even after failures, and fails unless at least 2 succeed.",
+		4: "```twf\nawait all\n```\n\nFails as soon as one operation fails.",
+	} {
+		h, err := hoverHandler(store)(nil, &protocol.HoverParams{TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Position:     protocol.Position{Line: line, Character: 10},
+		}})
+		if err != nil || h == nil {
+			t.Fatalf("line %d: no hover (%v)", line, err)
+		}
+		if got := h.Contents.(protocol.MarkupContent).Value; got != want {
+			t.Errorf("line %d: hover %q, want %q", line, got, want)
+		}
+	}
+}
+
+// nestedAwaitAll nests await all blocks in await one cases two deep, with
+// calls and a condition set only inside them.
+const nestedAwaitAll = `workflow Order():
+    state:
+        condition packed
+    await one:
+        await all:
+            activity Charge()
+            await one:
+                await all:
+                    workflow Ship()
+                    set packed
+                timer(1h):
+                    close fail("late")
+        timer(2h):
+            close fail("late")
+    await packed
+    close complete
+
+activity Charge():
+    return
+
+workflow Ship():
+    close complete
+`
+
+func TestNestedAwaitAllCoverage(t *testing.T) {
+	const uri = "file:///nested.twf"
+	store := NewDocumentStore()
+	doc := store.Open(uri, 1, nestedAwaitAll)
+	if len(doc.ParseErrs)+len(doc.ResolveErrs) > 0 {
+		t.Fatalf("unexpected errors: %v %v", doc.ParseErrs, doc.ResolveErrs)
+	}
+	pos := func(line int) protocol.TextDocumentPositionParams {
+		return protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Position:     protocol.Position{Line: uint32(line - 1), Character: 16},
+		}
+	}
+
+	tests := []struct {
+		name  string
+		check func(t *testing.T)
+	}{
+		{"hover on a call in a nested await all", func(t *testing.T) {
+			for line, want := range map[int]string{6: "activity Charge()", 9: "workflow Ship()", 8: "await all"} {
+				h, err := hoverHandler(store)(nil, &protocol.HoverParams{TextDocumentPositionParams: pos(line)})
+				if err != nil || h == nil {
+					t.Fatalf("line %d: no hover (%v)", line, err)
+				}
+				if got := h.Contents.(protocol.MarkupContent).Value; !strings.Contains(got, want) {
+					t.Errorf("line %d: hover %q, want it to show %q", line, got, want)
+				}
+			}
+		}},
+		{"references reach nested await all bodies", func(t *testing.T) {
+			for def, want := range map[int]int{18: 6, 21: 9} {
+				locs, err := referencesHandler(store)(nil, &protocol.ReferenceParams{TextDocumentPositionParams: pos(def)})
+				if err != nil || len(locs) != 1 || locs[0].Range.Start.Line != uint32(want-1) {
+					t.Errorf("references of line %d = %v (%v), want line %d", def, locs, err, want)
+				}
+			}
+		}},
+		{"folding covers each await all once", func(t *testing.T) {
+			ranges, err := foldingRangeHandler(store)(nil, &protocol.FoldingRangeParams{TextDocument: protocol.TextDocumentIdentifier{URI: uri}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			count := make(map[[2]uint32]int)
+			for _, r := range ranges {
+				count[[2]uint32{r.StartLine, r.EndLine}]++
+			}
+			// 0-based: the outer await all case, the inner await one and
+			// its await all case, and the outer await one.
+			for _, want := range [][2]uint32{{4, 11}, {6, 11}, {7, 9}, {3, 13}} {
+				if count[want] != 1 {
+					t.Errorf("fold %v appears %d times in %v", want, count[want], ranges)
+				}
+			}
+		}},
+		{"symbols span nested statements", func(t *testing.T) {
+			result, err := documentSymbolHandler(store)(nil, &protocol.DocumentSymbolParams{TextDocument: protocol.TextDocumentIdentifier{URI: uri}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			symbols := result.([]protocol.DocumentSymbol)
+			if len(symbols) != 3 || symbols[0].Name != "Order" || symbols[0].Range.End.Line != 16 {
+				t.Errorf("expected Order to span to line 16, got %+v", symbols)
+			}
+		}},
+		{"resolver resolves nested calls and conditions", func(t *testing.T) {
+			wf := doc.File.Definitions[0].(*ast.WorkflowDef)
+			var calls, sets int
+			ast.WalkStatements(wf.Body, func(s ast.Statement) bool {
+				switch n := s.(type) {
+				case *ast.ActivityCall:
+					if n.Activity.Resolved != nil {
+						calls++
+					}
+				case *ast.WorkflowCall:
+					if n.Workflow.Resolved != nil {
+						calls++
+					}
+				case *ast.SetStmt:
+					if n.Condition.Resolved != nil {
+						sets++
+					}
+				}
+				return true
+			})
+			if calls != 2 || sets != 1 {
+				t.Errorf("resolved %d calls and %d sets, want 2 and 1", calls, sets)
+			}
+		}},
+		{"validator sees sets in nested await all", func(t *testing.T) {
+			for _, e := range doc.ValidateErrs {
+				if e.Kind == validator.ErrUnsetCondition {
+					t.Errorf("unexpected %v", e)
+				}
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, tt.check)
+	}
+}
+
+func TestEffectiveOptionsHover(t *testing.T) {
+	const uri = "file:///options.twf"
+	content := "activity Charge():\n" +
+		"    options:\n" +
+		"        start_to_close_timeout: 30s\n" +
+		"        retry_policy:\n" +
+		"            maximum_attempts: 5\n" +
+		"    return\n" +
+		"\n" +
+		"workflow Order():\n" +
+		"    activity Charge()\n" +
+		"        options:\n" +
+		"            retry_policy:\n" +
+		"                maximum_attempts: 3\n" +
+		"    close complete\n"
+	store := NewDocumentStore()
+	defaults, err := options.ParseConfig([]byte(`{"activity": {"heartbeat_timeout": "10s"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Policy.OptionDefaults = defaults
+	store.Open(uri, 1, content)
+
+	h, err := hoverHandler(store)(nil, &protocol.HoverParams{TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+		Position:     protocol.Position{Line: 8, Character: 14},
+	}})
+	if err != nil || h == nil {
+		t.Fatalf("no hover (%v)", err)
+	}
+	want := "Effective options:\n" +
+		"- `heartbeat_timeout: 10s` (config)\n" +
+		"- `start_to_close_timeout: 30s` (definition)\n" +
+		"- `retry_policy`\n" +
+		"  - `maximum_attempts: 3` (call)"
+	if got := h.Contents.(protocol.MarkupContent).Value; !strings.HasSuffix(got, want) {
+		t.Errorf("hover %q, want it to end with %q", got, want)
+	}
+}
+
+func TestUnparsedOptionsHover(t *testing.T) {
+	const uri = "file:///unparsed.twf"
+	content := "workflow Order():\n" +
+		"    activity Charge()\n" +
+		"        options:\n" +
+		"            start_to_close_timeout = 30s\n" +
+		"    close complete\n" +
+		"\n" +
+		"activity Charge():\n" +
+		"    return\n"
+	store := NewDocumentStore()
+	store.Open(uri, 1, content)
+
+	h, err := hoverHandler(store)(nil, &protocol.HoverParams{TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+		Position:     protocol.Position{Line: 1, Character: 14},
+	}})
+	if err != nil || h == nil {
+		t.Fatalf("no hover (%v)", err)
+	}
+	got := h.Contents.(protocol.MarkupContent).Value
+	if !strings.HasSuffix(got, "The call's options block does not parse, so its effective options are unknown.") || strings.Contains(got, "Effective options") {
+		t.Errorf("hover %q, want the unknown options noted", got)
+	}
+}
+
+func TestValuePreviewHover(t *testing.T) {
+	const uri = "file:///preview.twf"
+	content := `workflow Remind(id: string) -> (Reminder):
+    signal Snooze():
+        await timer(5m)
+        close fail("snoozed")
+    await timer(36h)
+    if (urgent):
+        await timer(90m)
+    else:
+        await timer(2d)
+    await one:
+        timer(30m):
+            await timer(1s)
+        signal Snooze:
+            close continue_as_new(id)
+    await timer(later)
+    close complete(Reminder{})
+`
+	store := NewDocumentStore()
+	store.Open(uri, 1, content)
+
+	for _, tt := range []struct {
+		line int
+		want string
+	}{
+		{3, "Fires 5 minutes after reaching this point."},
+		{4, "Fails `Remind` with an error or a message string."},
+		{5, "Fires 1 day 12 hours after reaching this point."},
+		{7, "Fires 1 hour 30 minutes after reaching this point, and at least 1 day 13 hours 30 minutes into the workflow with 1 timer awaited before it on this path."},
+		{9, "at least 3 days 12 hours into the workflow"},
+		{11, "Fires 30 minutes after reaching this point, and at least 1 day 12 hours 30 minutes into the workflow"},
+		{12, "at least 1 day 12 hours 30 minutes 1 second into the workflow with 2 timers"},
+		{14, "Starts a new run of `Remind(id: string)`."},
+		{16, "Completes `Remind`, which returns `(Reminder)`."},
+	} {
+		h, err := hoverHandler(store)(nil, &protocol.HoverParams{TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Position:     protocol.Position{Line: uint32(tt.line - 1), Character: 10},
+		}})
+		if err != nil || h == nil {
+			t.Errorf("line %d: no hover (%v)", tt.line, err)
+			continue
+		}
+		if got := h.Contents.(protocol.MarkupContent).Value; !strings.Contains(got, tt.want) {
+			t.Errorf("line %d: hover %q, want it to contain %q", tt.line, got, tt.want)
+		}
+	}
+
+	// A timer whose duration is not a literal has no preview.
+	h, err := hoverHandler(store)(nil, &protocol.HoverParams{TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+		Position:     protocol.Position{Line: 14, Character: 10},
+	}})
+	if err != nil || h == nil {
+		t.Fatalf("no hover (%v)", err)
+	}
+	if got := h.Contents.(protocol.MarkupContent).Value; strings.Contains(got, "Fires") {
+		t.Errorf("hover %q previews a timer of unknown duration", got)
+	}
+}
+
+func TestWorkflowDescriptionHover(t *testing.T) {
+	const uri = "file:///description.twf"
+	content := "workflow Order():\n" +
+		"    description:\n" +
+		"        Ships one order.\n" +
+		"    workflow Ship()\n" +
+		"\n" +
+		"workflow Ship():\n" +
+		"    description:\n" +
+		"        Hands the order\n" +
+		"        to the carrier.\n" +
+		"    close complete\n"
+	store := NewDocumentStore()
+	store.Open(uri, 1, content)
+
+	for line, want := range map[uint32]string{
+		0: "Ships one order.\n\n```twf\nworkflow Order()\n```",
+		3: "Hands the order\nto the carrier.\n\n```twf\nworkflow Ship()\n```",
+	} {
+		h, err := hoverHandler(store)(nil, &protocol.HoverParams{TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Position:     protocol.Position{Line: line, Character: 10},
+		}})
+		if err != nil || h == nil {
+			t.Fatalf("line %d: no hover (%v)", line, err)
+		}
+		if got := h.Contents.(protocol.MarkupContent).Value; !strings.HasPrefix(got, want) {
+			t.Errorf("line %d: hover %q, want it to start with %q", line, got, want)
+		}
+	}
+}
+
+func TestWorkflowTimeoutHover(t *testing.T) {
+	const uri = "file:///timeouts.twf"
+	content := "workflow Approval():\n" +
+		"    await one:\n" +
+		"        signal Approve:\n" +
+		"            close complete\n" +
+		"        timer (24h):\n" +
+		"            close fail\n" +
+		"\n" +
+		"workflow Order():\n" +
+		"    workflow Approval()\n" +
+		"        options:\n" +
+		"            workflow_execution_timeout: 72h\n"
+	store := NewDocumentStore()
+	store.Open(uri, 1, content)
+
+	hover := func(line uint32) string {
+		t.Helper()
+		h, err := hoverHandler(store)(nil, &protocol.HoverParams{TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Position:     protocol.Position{Line: line},
+		}})
+		if err != nil || h == nil {
+			t.Fatalf("line %d: no hover (%v)", line, err)
+		}
+		return h.Contents.(protocol.MarkupContent).Value
+	}
+	if got := hover(0); !strings.HasSuffix(got, "```\n\ntimes out after 24h via await one at line 2; execution timeout 72h") {
+		t.Errorf("expected the timeout summary, got %q", got)
+	}
+	if got := hover(7); strings.Contains(got, "times out") || strings.Contains(got, "timeout") {
+		t.Errorf("expected no timeout summary for a workflow without timeout paths, got %q", got)
+	}
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/tliron/glsp"
+)
+
+// The server logs through slog.Default, one record per message handled and
+// per document analysis, so a session can be traced from its log alone:
+//
+//	level=DEBUG msg=request method=textDocument/hover uri=file:///a.twf duration=1.2ms outcome=ok
+//
+// Outcomes are ok, error, unsupported, and panic for requests, and ok,
+// cancelled, and panic for analyses. Errors are logged at warn and panics
+// at error, with a stack trace; everything else at debug.
+
+// maxStack is the number of bytes of a panic's stack trace logged.
+const maxStack = 4096
+
+// logRequest logs the handling of context's message. p is the value of a
+// recovered panic, if any.
+func logRequest(context *glsp.Context, start time.Time, validMethod bool, err error, p any) {
+	attrs := []any{"method", context.Method}
+	if uri := paramsURI(context.Params); uri != "" {
+		attrs = append(attrs, "uri", uri)
+	}
+	attrs = append(attrs, "duration", time.Since(start))
+	switch {
+	case p != nil:
+		slog.Error("request", append(attrs, "outcome", "panic", "panic", fmt.Sprint(p), "stack", panicStack())...)
+	case !validMethod:
+		slog.Debug("request", append(attrs, "outcome", "unsupported")...)
+	case err != nil:
+		slog.Warn("request", append(attrs, "outcome", "error", "error", err.Error())...)
+	default:
+		slog.Debug("request", append(attrs, "outcome", "ok")...)
+	}
+}
+
+// logAnalysis logs the end of the analysis of the document at uri. p is the
+// value of a recovered panic, if any.
+func logAnalysis(uri string, start time.Time, stored bool, p any) {
+	attrs := []any{"uri", uri, "duration", time.Since(start)}
+	switch {
+	case p != nil:
+		slog.Error("analysis", append(attrs, "outcome", "panic", "panic", fmt.Sprint(p), "stack", panicStack())...)
+	case stored:
+		slog.Debug("analysis", append(attrs, "outcome", "ok")...)
+	default:
+		slog.Debug("analysis", append(attrs, "outcome", "cancelled")...)
+	}
+}
+
+// panicStack returns the current goroutine's stack from the panic being
+// recovered, truncated to maxStack bytes, leaving out the frames recovering
+// and logging it.
+func panicStack() string {
+	stack := string(debug.Stack())
+	if header, rest, ok := strings.Cut(stack, "\n"); ok {
+		if i := strings.Index(rest, "\npanic("); i >= 0 {
+			stack = header + rest[i:]
+		}
+	}
+	if len(stack) > maxStack {
+		return stack[:maxStack] + "\n..."
+	}
+	return stack
+}
+
+// paramsURI returns the document URI of a message's params, or "" for
+// messages not about one document.
+func paramsURI(params json.RawMessage) string {
+	var p struct {
+		TextDocument struct {
+			URI string `json:"uri"`
+		} `json:"textDocument"`
+	}
+	if json.Unmarshal(params, &p) != nil {
+		return ""
+	}
+	return p.TextDocument.URI
+}
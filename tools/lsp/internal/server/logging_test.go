@@ -0,0 +1,48 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/tliron/glsp"
+	protocol317 "github.com/tliron/glsp/protocol_3_17"
+)
+
+func TestRequestLogging(t *testing.T) {
+	var buf strings.Builder
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	store := NewDocumentStore()
+	store.Open("file:///a.twf", 1, "activity A():\n    return\n")
+	h := &Handler{Handler: &protocol317.Handler{}, store: store}
+	params := json.RawMessage(`{"textDocument": {"uri": "file:///a.twf"}}`)
+	h.Handle(&glsp.Context{Method: methodTextDocumentDiagnostic, Params: params})
+	h.store = nil
+	h.Handle(&glsp.Context{Method: methodTextDocumentDiagnostic, Params: params})
+
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var r map[string]any
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("log line is not JSON: %s", line)
+		}
+		if r["msg"] == "request" {
+			records = append(records, r)
+		}
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 request records, got %s", buf.String())
+	}
+	for i, want := range []struct{ level, outcome string }{{"DEBUG", "ok"}, {"ERROR", "panic"}} {
+		r := records[i]
+		if r["level"] != want.level || r["outcome"] != want.outcome || r["method"] != methodTextDocumentDiagnostic || r["uri"] != "file:///a.twf" || r["duration"] == nil {
+			t.Errorf("record %d: expected a %s %s record, got %v", i, want.level, want.outcome, r)
+		}
+	}
+	if stack, _ := records[1]["stack"].(string); !strings.Contains(stack, "documentDiagnostic") {
+		t.Errorf("expected the panic's stack, got %q", stack)
+	}
+}
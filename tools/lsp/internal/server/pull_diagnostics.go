@@ -0,0 +1,156 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/tliron/glsp"
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+// LSP 3.17 pull diagnostics. The protocol package routes only 3.16 methods,
+// so Handler routes these itself and the types are declared here.
+const (
+	methodTextDocumentDiagnostic     = "textDocument/diagnostic"
+	methodWorkspaceDiagnostic        = "workspace/diagnostic"
+	methodWorkspaceDiagnosticRefresh = "workspace/diagnostic/refresh"
+)
+
+// diagnosticOptions is the diagnosticProvider server capability.
+type diagnosticOptions struct {
+	InterFileDependencies bool `json:"interFileDependencies"`
+	WorkspaceDiagnostics  bool `json:"workspaceDiagnostics"`
+}
+
+type documentDiagnosticParams struct {
+	TextDocument     protocol.TextDocumentIdentifier `json:"textDocument"`
+	PreviousResultID string                          `json:"previousResultId,omitempty"`
+}
+
+type workspaceDiagnosticParams struct {
+	PreviousResultIDs []previousResultID `json:"previousResultIds"`
+}
+
+type previousResultID struct {
+	URI   protocol.DocumentUri `json:"uri"`
+	Value string               `json:"value"`
+}
+
+// Report kinds.
+const (
+	reportFull      = "full"
+	reportUnchanged = "unchanged"
+)
+
+// documentReport is a full report, listing the diagnostics, or an
+// unchanged report, saying those of the previous result still stand.
+type documentReport struct {
+	Kind     string                 `json:"kind"`
+	ResultID string                 `json:"resultId,omitempty"`
+	Items    *[]protocol.Diagnostic `json:"items,omitempty"` // full reports only
+}
+
+type workspaceDocumentReport struct {
+	URI     protocol.DocumentUri `json:"uri"`
+	Version *protocol.Integer    `json:"version"` // null for files that are not open
+	documentReport
+}
+
+type workspaceReport struct {
+	Items []workspaceDocumentReport `json:"items"`
+}
+
+// report returns the report for diagnostics identified by resultID, given
+// the ID of the diagnostics the client already has.
+func report(resultID, previous string, diagnostics func() []protocol.Diagnostic) documentReport {
+	if resultID != "" && resultID == previous {
+		return documentReport{Kind: reportUnchanged, ResultID: resultID}
+	}
+	items := diagnostics()
+	return documentReport{Kind: reportFull, ResultID: resultID, Items: &items}
+}
+
+// documentDiagnostic answers textDocument/diagnostic from the latest
+// analysis of an open document. Other documents have no diagnostics.
+func documentDiagnostic(store *DocumentStore, params *documentDiagnosticParams) documentReport {
+	doc, ok := store.Get(params.TextDocument.URI)
+	if !ok {
+		return report("", "", func() []protocol.Diagnostic { return []protocol.Diagnostic{} })
+	}
+	return report(doc.resultID, params.PreviousResultID, func() []protocol.Diagnostic { return diagnostics(doc) })
+}
+
+// workspaceDiagnostic answers workspace/diagnostic with a report for each
+// open document and each indexed workspace file. Files that are not open
+// are analyzed on demand; their result IDs follow the index generation, so
+// they are analyzed again only after something in the workspace changed.
+func workspaceDiagnostic(store *DocumentStore, params *workspaceDiagnosticParams) workspaceReport {
+	previous := make(map[string]string, len(params.PreviousResultIDs))
+	for _, p := range params.PreviousResultIDs {
+		previous[p.URI] = p.Value
+	}
+
+	items := []workspaceDocumentReport{}
+	open := make(map[string]bool)
+	for _, uri := range store.URIs() {
+		open[uri] = true
+		if doc, ok := store.Get(uri); ok {
+			items = append(items, workspaceDocumentReport{URI: uri, Version: &doc.Version, documentReport: report(doc.resultID, previous[uri], func() []protocol.Diagnostic { return diagnostics(doc) })})
+		}
+	}
+	files, generation := store.Workspace.snapshot()
+	for _, f := range files {
+		if open[f.uri] {
+			continue
+		}
+		resultID := "w" + strconv.Itoa(generation)
+		items = append(items, workspaceDocumentReport{URI: f.uri, documentReport: report(resultID, previous[f.uri], func() []protocol.Diagnostic {
+			doc := &Document{URI: f.uri, Content: f.content}
+			store.analyze(context.Background(), doc, nil)
+			return diagnostics(doc)
+		})})
+	}
+	return workspaceReport{Items: items}
+}
+
+// clientPullsDiagnostics reports whether the initialize params declare
+// support for pulling diagnostics and for being asked to pull again.
+func clientPullsDiagnostics(params json.RawMessage) (pull, refresh bool) {
+	var init struct {
+		Capabilities struct {
+			TextDocument struct {
+				Diagnostic *struct{} `json:"diagnostic"`
+			} `json:"textDocument"`
+			Workspace struct {
+				Diagnostics struct {
+					RefreshSupport bool `json:"refreshSupport"`
+				} `json:"diagnostics"`
+			} `json:"workspace"`
+		} `json:"capabilities"`
+	}
+	if json.Unmarshal(params, &init) != nil {
+		return false, false
+	}
+	return init.Capabilities.TextDocument.Diagnostic != nil, init.Capabilities.Workspace.Diagnostics.RefreshSupport
+}
+
+// handlePullDiagnostics answers the pull diagnostic requests, reporting
+// whether method is one of them.
+func handlePullDiagnostics(store *DocumentStore, context *glsp.Context) (r any, ok bool, err error) {
+	switch context.Method {
+	case methodTextDocumentDiagnostic:
+		var params documentDiagnosticParams
+		if err := json.Unmarshal(context.Params, &params); err != nil {
+			return nil, true, err
+		}
+		return documentDiagnostic(store, &params), true, nil
+	case methodWorkspaceDiagnostic:
+		var params workspaceDiagnosticParams
+		if err := json.Unmarshal(context.Params, &params); err != nil {
+			return nil, true, err
+		}
+		return workspaceDiagnostic(store, &params), true, nil
+	}
+	return nil, false, nil
+}
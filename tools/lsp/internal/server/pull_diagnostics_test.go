@@ -0,0 +1,46 @@
+package server
+
+import (
+	"path/filepath"
+	"testing"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestPullDiagnostics(t *testing.T) {
+	dir := writeWorkspace(t, map[string]string{
+		"order.twf":  "workflow Order():\n    activity Charge()\n",
+		"broken.twf": "workflow Broken():\n    activity Missing()\n",
+	})
+	store := NewDocumentStore()
+	store.Workspace.AddFolder(pathURI(dir))
+	order := pathURI(filepath.Join(dir, "order.twf"))
+	store.Open(order, 1, "workflow Order():\n    activity Charge()\n")
+
+	rep := documentDiagnostic(store, &documentDiagnosticParams{TextDocument: protocol.TextDocumentIdentifier{URI: order}})
+	if rep.Kind != reportFull || rep.ResultID == "" || len(*rep.Items) != 1 {
+		t.Fatalf("expected a full report with the undefined Charge, got %+v", rep)
+	}
+	again := documentDiagnostic(store, &documentDiagnosticParams{TextDocument: protocol.TextDocumentIdentifier{URI: order}, PreviousResultID: rep.ResultID})
+	if again.Kind != reportUnchanged || again.ResultID != rep.ResultID || again.Items != nil {
+		t.Errorf("expected an unchanged report, got %+v", again)
+	}
+
+	ws := workspaceDiagnostic(store, &workspaceDiagnosticParams{})
+	if len(ws.Items) != 2 || ws.Items[0].URI != order {
+		t.Fatalf("expected the open document then broken.twf, got %+v", ws.Items)
+	}
+	broken := ws.Items[1]
+	if broken.Kind != reportFull || len(*broken.Items) != 1 || (*broken.Items)[0].Message != "undefined activity: Missing" {
+		t.Errorf("expected broken.twf to be analyzed on demand, got %+v", broken)
+	}
+	ws = workspaceDiagnostic(store, &workspaceDiagnosticParams{PreviousResultIDs: []previousResultID{
+		{URI: order, Value: rep.ResultID},
+		{URI: broken.URI, Value: broken.ResultID},
+	}})
+	for _, item := range ws.Items {
+		if item.Kind != reportUnchanged {
+			t.Errorf("expected %s to be unchanged, got %+v", item.URI, item)
+		}
+	}
+}
@@ -104,6 +104,89 @@ func findNodeAtLine(file *ast.File, line int) ast.Node {
 					return &d.Endpoints[i]
 				}
 			}
+
+		case *ast.ConstDef:
+			if d.Line == line {
+				return d
+			}
+
+		case *ast.EnumDef:
+			if d.Line == line {
+				return d
+			}
+		}
+	}
+	return findConstRefAtLine(file, line)
+}
+
+// findConstRefAtLine finds an option value naming a constant on the given
+// line. Option entries sit on their own lines below the statement or
+// namespace entry that owns them, so the searches above never reach them.
+func findConstRefAtLine(file *ast.File, line int) ast.Node {
+	var found ast.Node
+	inOptions := func(ob *ast.OptionsBlock) {
+		if ob == nil || found != nil {
+			return
+		}
+		var walk func([]*ast.OptionEntry)
+		walk = func(entries []*ast.OptionEntry) {
+			for _, e := range entries {
+				if found != nil {
+					return
+				}
+				if e.Const.Name != "" && e.Const.Line == line {
+					found = &e.Const
+					return
+				}
+				walk(e.Nested)
+			}
+		}
+		walk(ob.Entries)
+	}
+	inStmts := func(stmts []ast.Statement) {
+		ast.WalkStatements(stmts, func(s ast.Statement) bool {
+			switch n := s.(type) {
+			case *ast.ActivityCall:
+				inOptions(n.Options)
+			case *ast.WorkflowCall:
+				inOptions(n.Options)
+			case *ast.NexusCall:
+				inOptions(n.Options)
+			}
+			return found == nil
+		})
+	}
+	for _, def := range file.Definitions {
+		switch d := def.(type) {
+		case *ast.WorkflowDef:
+			inOptions(d.Options)
+			for _, s := range d.Signals {
+				inStmts(s.Body)
+			}
+			for _, q := range d.Queries {
+				inStmts(q.Body)
+			}
+			for _, u := range d.Updates {
+				inStmts(u.Body)
+			}
+			inStmts(d.Body)
+		case *ast.ActivityDef:
+			inOptions(d.Options)
+			inStmts(d.Body)
+		case *ast.NexusServiceDef:
+			for _, op := range d.Operations {
+				inStmts(op.Body)
+			}
+		case *ast.NamespaceDef:
+			for i := range d.Workers {
+				inOptions(d.Workers[i].Options)
+			}
+			for i := range d.Endpoints {
+				inOptions(d.Endpoints[i].Options)
+			}
+		}
+		if found != nil {
+			return found
 		}
 	}
 	return nil
@@ -25,7 +25,17 @@ func referencesHandler(store *DocumentStore) protocol.TextDocumentReferencesFunc
 			return nil, nil
 		}
 
-		refs := collectReferences(doc.File, name, kind, params.Context.IncludeDeclaration)
+		var refs []ast.Node
+		if kind == "label" {
+			refs = collectLabelReferences(node, params.Context.IncludeDeclaration)
+		} else if def := indexedSymbol(node); def != nil && doc.Symbols != nil {
+			if params.Context.IncludeDeclaration {
+				refs = append(refs, def)
+			}
+			refs = append(refs, doc.Symbols.References(def)...)
+		} else {
+			refs = collectReferences(doc.File, name, kind, params.Context.IncludeDeclaration)
+		}
 		if len(refs) == 0 {
 			return nil, nil
 		}
@@ -33,7 +43,7 @@ func referencesHandler(store *DocumentStore) protocol.TextDocumentReferencesFunc
 		var locs []protocol.Location
 		for _, ref := range refs {
 			locs = append(locs, protocol.Location{
-				URI:   params.TextDocument.URI,
+				URI:   nodeURI(ref, doc.Symbols, params.TextDocument.URI),
 				Range: nameRange(ref),
 			})
 		}
@@ -42,7 +52,7 @@ func referencesHandler(store *DocumentStore) protocol.TextDocumentReferencesFunc
 }
 
 // nameOfNode returns the name and kind ("workflow", "activity", "signal",
-// "query", "update", "nexus_service", "nexus_endpoint", "worker") for an AST node.
+// "query", "update", "nexus_service", "nexus_endpoint", "worker", "label") for an AST node.
 // For references it follows the Resolved pointer to normalize to the definition identity.
 func nameOfNode(node ast.Node) (name, kind string) {
 	switch n := node.(type) {
@@ -95,10 +105,36 @@ func nameOfNode(node ast.Node) (name, kind string) {
 		if n.Target != nil {
 			return nameOfAsyncTarget(n.Target)
 		}
+	case *ast.ForStmt, *ast.BreakStmt, *ast.ContinueStmt:
+		if l := labelNode(n); l != nil {
+			return nameOfNode(l)
+		}
+	case *ast.LoopLabel:
+		return n.Name, "label"
+	case *ast.Ref[*ast.ForStmt]:
+		return n.Name, "label"
 	}
 	return "", ""
 }
 
+// indexedSymbol returns the definition or declaration at node, or the one a
+// reference at node resolves to, when its references can be read from the
+// resolver's index. Unresolved names and kinds the index does not cover
+// return nil and fall back to collectReferences.
+func indexedSymbol(node ast.Node) ast.Node {
+	switch node.(type) {
+	case *ast.WorkflowDef, *ast.ActivityDef, *ast.WorkerDef, *ast.NexusServiceDef,
+		*ast.SignalDecl, *ast.QueryDecl, *ast.UpdateDecl:
+		return node
+	}
+	switch def := resolvedTarget(node).(type) {
+	case *ast.WorkflowDef, *ast.ActivityDef, *ast.WorkerDef, *ast.NexusServiceDef,
+		*ast.SignalDecl, *ast.UpdateDecl:
+		return def
+	}
+	return nil
+}
+
 // collectReferences walks the file and returns every node whose name and kind
 // match the target. When includeDecl is true the definition node is included.
 func collectReferences(file *ast.File, name, kind string, includeDecl bool) []ast.Node {
@@ -150,10 +186,13 @@ func collectReferences(file *ast.File, name, kind string, includeDecl bool) []as
 			if includeDecl && kind == "nexus_service" && d.Name == name {
 				refs = append(refs, d)
 			}
-			// Walk sync operation bodies for nested references.
+			// Walk sync operation bodies for nested references, and take the
+			// workflows backing async operations.
 			for _, op := range d.Operations {
 				if op.OpType == ast.NexusOpSync {
 					refs = collectRefsInStmts(op.Body, name, kind, refs)
+				} else if kind == "workflow" && op.Workflow.Name == name {
+					refs = append(refs, &op.Workflow)
 				}
 			}
 
@@ -235,6 +274,70 @@ func collectRefsInStmts(stmts []ast.Statement, name, kind string, refs []ast.Nod
 	return refs
 }
 
+// labelNode returns the node spanning the loop label on a for, break, or
+// continue statement, or nil when the statement is unlabeled.
+func labelNode(node ast.Node) ast.Node {
+	switch n := node.(type) {
+	case *ast.ForStmt:
+		if n.Label != nil {
+			return n.Label
+		}
+	case *ast.BreakStmt:
+		if n.Label.Name != "" {
+			return &n.Label
+		}
+	case *ast.ContinueStmt:
+		if n.Label.Name != "" {
+			return &n.Label
+		}
+	}
+	return nil
+}
+
+// labeledLoop returns the labeled loop a for, break, or continue statement
+// refers to. Labels are scoped to their loop, so references are collected
+// from the loop rather than by name across the file.
+func labeledLoop(node ast.Node) *ast.ForStmt {
+	switch n := node.(type) {
+	case *ast.ForStmt:
+		if n.Label != nil {
+			return n
+		}
+	case *ast.BreakStmt:
+		return n.Label.Resolved
+	case *ast.ContinueStmt:
+		return n.Label.Resolved
+	}
+	return nil
+}
+
+// collectLabelReferences returns the label nodes of every break and continue
+// targeting the loop labeled at node, plus the label itself when includeDecl is true.
+func collectLabelReferences(node ast.Node, includeDecl bool) []ast.Node {
+	loop := labeledLoop(node)
+	if loop == nil {
+		return nil
+	}
+	var refs []ast.Node
+	if includeDecl {
+		refs = append(refs, loop.Label)
+	}
+	ast.WalkStatements(loop.Body, func(s ast.Statement) bool {
+		switch n := s.(type) {
+		case *ast.BreakStmt:
+			if n.Label.Resolved == loop {
+				refs = append(refs, &n.Label)
+			}
+		case *ast.ContinueStmt:
+			if n.Label.Resolved == loop {
+				refs = append(refs, &n.Label)
+			}
+		}
+		return true
+	})
+	return refs
+}
+
 // nameOfAsyncTarget returns the name and kind for an async target node.
 func nameOfAsyncTarget(target ast.AsyncTarget) (name, kind string) {
 	switch t := target.(type) {
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/parser"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/resolver"
+)
+
+func TestCollectLabelReferences(t *testing.T) {
+	input := "workflow Test():\n" +
+		"    outer: for (a in as):\n" +
+		"        for (b in bs):\n" +
+		"            break outer\n" +
+		"        continue outer\n" +
+		"    other: for:\n" +
+		"        break other\n"
+	file, err := parser.ParseFile(input)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	resolver.Resolve(file)
+
+	brk := findNodeAtLine(file, 4)
+	if name, kind := nameOfNode(brk); name != "outer" || kind != "label" {
+		t.Fatalf("expected label outer, got %q %q", name, kind)
+	}
+	refs := collectLabelReferences(brk, true)
+	if len(refs) != 3 {
+		t.Fatalf("expected 3 references (label, break, continue), got %d", len(refs))
+	}
+	wantLines := []int{2, 4, 5}
+	for i, ref := range refs {
+		if ref.NodeLine() != wantLines[i] {
+			t.Errorf("ref %d: expected line %d, got %d", i, wantLines[i], ref.NodeLine())
+		}
+	}
+	if r := nameRange(refs[1]); r.Start.Character != 18 || r.End.Character != 23 {
+		t.Errorf("expected break label range 18-23, got %d-%d", r.Start.Character, r.End.Character)
+	}
+
+	def := resolvedTarget(brk)
+	if def == nil || def.NodeLine() != 2 || def.NodeColumn() != 5 {
+		t.Errorf("expected definition at 2:5, got %v", def)
+	}
+}
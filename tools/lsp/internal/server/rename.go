@@ -1,10 +1,41 @@
 package server
 
 import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
 	"github.com/tliron/glsp"
 	protocol "github.com/tliron/glsp/protocol_3_16"
 )
 
+// globalKinds are the kinds of definition named across the workspace:
+// renaming one renames its references in every file the document sees.
+// Signals, queries, and updates belong to their workflow, and labels to
+// their loop, so they are renamed in the document alone.
+var globalKinds = map[string]bool{
+	"workflow":       true,
+	"activity":       true,
+	"nexus_service":  true,
+	"nexus_endpoint": true,
+	"worker":         true,
+	"namespace":      true,
+}
+
+// renameFile is a file a rename edits: an open document, with its version,
+// or a workspace file as indexed from disk.
+type renameFile struct {
+	uri     string
+	version *protocol.Integer // nil for files not open
+	content string
+	file    *ast.File
+	edits   []protocol.TextEdit
+}
+
 func renameHandler(store *DocumentStore) protocol.TextDocumentRenameFunc {
 	return func(context *glsp.Context, params *protocol.RenameParams) (*protocol.WorkspaceEdit, error) {
 		doc, ok := store.Get(params.TextDocument.URI)
@@ -24,25 +55,196 @@ func renameHandler(store *DocumentStore) protocol.TextDocumentRenameFunc {
 			return nil, nil
 		}
 
-		refs := collectReferences(doc.File, name, kind, true)
-		if len(refs) == 0 {
+		if err := renameConflict(store, doc, line, name, kind, params.NewName); err != nil {
+			return nil, err
+		}
+
+		files := []*renameFile{{uri: doc.URI, version: &doc.Version, content: doc.Content, file: doc.File}}
+		if kind == "label" {
+			for _, ref := range collectLabelReferences(node, true) {
+				files[0].edits = append(files[0].edits, protocol.TextEdit{Range: nameRange(ref), NewText: params.NewName})
+			}
+		} else {
+			if globalKinds[kind] {
+				files = append(files, store.renameScope(doc.URI)...)
+			}
+			word := regexp.MustCompile(`\b` + regexp.QuoteMeta(name) + `\b`)
+			for _, f := range files {
+				lines := strings.Split(f.content, "\n")
+				for _, ref := range collectReferences(f.file, name, kind, true) {
+					f.edits = append(f.edits, protocol.TextEdit{Range: renameRange(lines, ref, word, len(name)), NewText: params.NewName})
+				}
+			}
+		}
+		files = slices.DeleteFunc(files, func(f *renameFile) bool { return len(f.edits) == 0 })
+		if len(files) == 0 {
 			return nil, nil
 		}
+		return renameEdit(files, fmt.Sprintf("Rename %s to %s", name, params.NewName), store.annotateEdits), nil
+	}
+}
+
+// renameConflict returns an error when renaming name, of kind, to newName
+// would leave two definitions of the kind with the same name in the
+// document's scope, or two handlers with the same name in the workflow
+// enclosing line. Applying the edit would only turn either into duplicate
+// diagnostics.
+func renameConflict(store *DocumentStore, doc *Document, line int, name, kind, newName string) error {
+	if newName == name {
+		return nil
+	}
+	conflict := func(other ast.Node, otherKind, where string) error {
+		return fmt.Errorf("cannot rename %s %s to %s: %s %s is already defined %s at line %d",
+			kindName(kind), name, newName, kindName(otherKind), newName, where, other.NodeLine())
+	}
+	switch kind {
+	case "signal", "query", "update":
+		wf, ok := enclosingDefinition(doc.File, line).(*ast.WorkflowDef)
+		if !ok {
+			return nil
+		}
+		for _, h := range workflowHandlers(wf) {
+			if n, k := nameOfNode(h); n == newName {
+				return conflict(h, k, "in workflow "+wf.Name)
+			}
+		}
+	default:
+		if !globalKinds[kind] {
+			return nil
+		}
+		defs := store.Workspace.External(doc.URI)
+		defs = append(defs[:len(defs):len(defs)], doc.File.Definitions...)
+		for _, def := range defs {
+			nodes := []ast.Node{def}
+			if ns, ok := def.(*ast.NamespaceDef); ok {
+				for i := range ns.Endpoints {
+					nodes = append(nodes, &ns.Endpoints[i])
+				}
+			}
+			for _, n := range nodes {
+				if otherName, otherKind := nameOfNode(n); otherName == newName && otherKind == kind {
+					return conflict(n, kind, "in "+path.Base(nodeURI(def, nil, doc.URI)))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// workflowHandlers returns the signal, query, and update declarations of wf.
+func workflowHandlers(wf *ast.WorkflowDef) []ast.Node {
+	var out []ast.Node
+	for _, s := range wf.Signals {
+		out = append(out, s)
+	}
+	for _, q := range wf.Queries {
+		out = append(out, q)
+	}
+	for _, u := range wf.Updates {
+		out = append(out, u)
+	}
+	return out
+}
 
-		var edits []protocol.TextEdit
-		for _, ref := range refs {
-			edits = append(edits, protocol.TextEdit{
-				Range:   nameRange(ref),
-				NewText: params.NewName,
-			})
+// kindName returns kind as written in messages, such as "nexus service".
+func kindName(kind string) string {
+	return strings.ReplaceAll(kind, "_", " ")
+}
+
+// renameScope returns the workspace files other than the document at uri
+// whose definitions it sees, read from the open document where there is
+// one.
+func (s *DocumentStore) renameScope(uri string) []*renameFile {
+	indexed, _ := s.Workspace.snapshot()
+	var files []*renameFile
+	for _, f := range indexed {
+		if !s.Workspace.Sees(uri, f.uri) {
+			continue
+		}
+		if doc, ok := s.Get(f.uri); ok && doc.File != nil {
+			files = append(files, &renameFile{uri: f.uri, version: &doc.Version, content: doc.Content, file: doc.File})
+			continue
 		}
+		files = append(files, &renameFile{uri: f.uri, content: f.content, file: &ast.File{Definitions: f.defs}})
+	}
+	return files
+}
 
-		return &protocol.WorkspaceEdit{
-			Changes: map[string][]protocol.TextEdit{
-				params.TextDocument.URI: edits,
+// renameRange returns the range of the name in the source of node: the
+// first match of word, the name as a whole word, on the node's line at or
+// after the node's column.
+// Definitions and calls start at their keyword, so nameRange alone would
+// cover the keyword instead.
+func renameRange(lines []string, node ast.Node, word *regexp.Regexp, n int) protocol.Range {
+	r := nameRange(node)
+	if int(r.Start.Line) >= len(lines) {
+		return r
+	}
+	text := lines[r.Start.Line]
+	from := min(int(r.Start.Character), len(text))
+	loc := word.FindStringIndex(text[from:])
+	if loc == nil {
+		return r
+	}
+	start := uint32(from + loc[0])
+	r.Start.Character, r.End.Character = start, start+uint32(n)
+	return r
+}
+
+// renameEdit returns the edit of files. A client supporting change
+// annotations gets one annotation per file counting its edits, which it
+// asks the user to confirm when the rename reaches beyond one file.
+func renameEdit(files []*renameFile, label string, annotate bool) *protocol.WorkspaceEdit {
+	if !annotate {
+		changes := make(map[string][]protocol.TextEdit, len(files))
+		for _, f := range files {
+			changes[f.uri] = f.edits
+		}
+		return &protocol.WorkspaceEdit{Changes: changes}
+	}
+	confirm := len(files) > 1
+	edit := &protocol.WorkspaceEdit{ChangeAnnotations: make(map[protocol.ChangeAnnotationIdentifier]protocol.ChangeAnnotation, len(files))}
+	for _, f := range files {
+		id := f.uri
+		description := fmt.Sprintf("%d edits in %s", len(f.edits), path.Base(f.uri))
+		if len(f.edits) == 1 {
+			description = "1 edit in " + path.Base(f.uri)
+		}
+		edit.ChangeAnnotations[id] = protocol.ChangeAnnotation{Label: label, NeedsConfirmation: &confirm, Description: &description}
+		edits := make([]any, len(f.edits))
+		for i, e := range f.edits {
+			edits[i] = protocol.AnnotatedTextEdit{TextEdit: e, AnnotationID: id}
+		}
+		edit.DocumentChanges = append(edit.DocumentChanges, protocol.TextDocumentEdit{
+			TextDocument: protocol.OptionalVersionedTextDocumentIdentifier{
+				TextDocumentIdentifier: protocol.TextDocumentIdentifier{URI: f.uri},
+				Version:                f.version,
 			},
-		}, nil
+			Edits: edits,
+		})
 	}
+	return edit
+}
+
+// clientAnnotatesEdits reports whether the initialize params declare
+// support for versioned document changes with change annotations in
+// workspace edits.
+func clientAnnotatesEdits(params json.RawMessage) bool {
+	var init struct {
+		Capabilities struct {
+			Workspace struct {
+				WorkspaceEdit struct {
+					DocumentChanges         bool      `json:"documentChanges"`
+					ChangeAnnotationSupport *struct{} `json:"changeAnnotationSupport"`
+				} `json:"workspaceEdit"`
+			} `json:"workspace"`
+		} `json:"capabilities"`
+	}
+	if json.Unmarshal(params, &init) != nil {
+		return false
+	}
+	e := init.Capabilities.Workspace.WorkspaceEdit
+	return e.DocumentChanges && e.ChangeAnnotationSupport != nil
 }
 
 func prepareRenameHandler(store *DocumentStore) protocol.TextDocumentPrepareRenameFunc {
@@ -63,6 +265,9 @@ func prepareRenameHandler(store *DocumentStore) protocol.TextDocumentPrepareRena
 		if name == "" || kind == "" {
 			return nil, nil
 		}
+		if l := labelNode(node); l != nil {
+			return nameRange(l), nil
+		}
 
 		return nameRange(node), nil
 	}
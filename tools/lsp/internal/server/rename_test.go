@@ -0,0 +1,133 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestRenameAcrossFiles(t *testing.T) {
+	dir := writeWorkspace(t, map[string]string{
+		"charge.twf": "activity Charge():\n    return\n",
+		"worker.twf": "worker orders:\n    workflow Order\n    activity Charge\n\nnexus service Orders:\n    async Place workflow Order\n",
+	})
+	store := NewDocumentStore()
+	store.Workspace.AddFolder(pathURI(dir))
+	order := pathURI(filepath.Join(dir, "order.twf"))
+	charge, worker := pathURI(filepath.Join(dir, "charge.twf")), pathURI(filepath.Join(dir, "worker.twf"))
+	store.Open(order, 1, "workflow Order():\n    activity Charge()\n    activity Charge()\n    close complete\n")
+	rename := func(line uint32, newName string) *protocol.WorkspaceEdit {
+		t.Helper()
+		edit, err := renameHandler(store)(nil, &protocol.RenameParams{
+			TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: order},
+				Position:     protocol.Position{Line: line},
+			},
+			NewName: newName,
+		})
+		if err != nil || edit == nil {
+			t.Fatalf("rename on line %d: %v, %v", line, edit, err)
+		}
+		return edit
+	}
+	at := func(edits []protocol.TextEdit) string {
+		var out []string
+		for _, e := range edits {
+			out = append(out, fmt.Sprintf("%d:%d-%d", e.Range.Start.Line, e.Range.Start.Character, e.Range.End.Character))
+		}
+		return strings.Join(out, " ")
+	}
+
+	// Renaming the activity from a call edits the calls, the definition in
+	// charge.twf, and the worker's registration, each at the name.
+	edit := rename(1, "Bill")
+	for uri, want := range map[string]string{order: "1:13-19 2:13-19", charge: "0:9-15", worker: "2:13-19"} {
+		if got := at(edit.Changes[uri]); got != want {
+			t.Errorf("%s: edits at %s, want %s", path.Base(uri), got, want)
+		}
+	}
+	if len(edit.Changes) != 3 || edit.DocumentChanges != nil {
+		t.Errorf("expected plain changes to three files, got %+v", edit)
+	}
+
+	// A client taking change annotations gets versioned document changes,
+	// with the edits of each file counted, reaching the workflow backing an
+	// async nexus operation.
+	store.annotateEdits = true
+	edit = rename(0, "PlaceOrder")
+	if len(edit.DocumentChanges) != 2 {
+		t.Fatalf("expected changes to two files, got %+v", edit.DocumentChanges)
+	}
+	for i, want := range []struct {
+		uri, at, description string
+		version              bool
+	}{
+		{order, "0:9-14", "1 edit in order.twf", true},
+		{worker, "1:13-18 5:25-30", "2 edits in worker.twf", false},
+	} {
+		change := edit.DocumentChanges[i].(protocol.TextDocumentEdit)
+		var edits []protocol.TextEdit
+		for _, e := range change.Edits {
+			a := e.(protocol.AnnotatedTextEdit)
+			if a.AnnotationID != want.uri {
+				t.Errorf("%s: annotated %q", path.Base(want.uri), a.AnnotationID)
+			}
+			edits = append(edits, a.TextEdit)
+		}
+		if change.TextDocument.URI != want.uri || at(edits) != want.at || (change.TextDocument.Version != nil) != want.version {
+			t.Errorf("change %d: %s at %s, version %v", i, change.TextDocument.URI, at(edits), change.TextDocument.Version)
+		}
+		a := edit.ChangeAnnotations[want.uri]
+		if a.Label != "Rename Order to PlaceOrder" || *a.Description != want.description || !*a.NeedsConfirmation {
+			t.Errorf("%s: annotation %q, %q", path.Base(want.uri), a.Label, *a.Description)
+		}
+	}
+
+	if !clientAnnotatesEdits(json.RawMessage(`{"capabilities": {"workspace": {"workspaceEdit": {"documentChanges": true, "changeAnnotationSupport": {}}}}}`)) {
+		t.Error("expected change annotation support to be read from the capabilities")
+	}
+	if clientAnnotatesEdits(json.RawMessage(`{"capabilities": {"workspace": {"workspaceEdit": {"changeAnnotationSupport": {}}}}}`)) {
+		t.Error("expected annotations to need document changes")
+	}
+}
+
+func TestRenameConflicts(t *testing.T) {
+	dir := writeWorkspace(t, map[string]string{
+		"bill.twf": "activity Bill():\n    return\n",
+	})
+	store := NewDocumentStore()
+	store.Workspace.AddFolder(pathURI(dir))
+	uri := pathURI(filepath.Join(dir, "order.twf"))
+	store.Open(uri, 1, "workflow Order():\n    signal Approve():\n        approved = true\n    update Cancel() -> (bool):\n        return true\n    activity Charge()\n    close complete\n\nactivity Charge():\n    return\n")
+	for _, tc := range []struct {
+		line    uint32
+		newName string
+		want    string
+	}{
+		{5, "Bill", "cannot rename activity Charge to Bill: activity Bill is already defined in bill.twf at line 1"},
+		{1, "Cancel", "cannot rename signal Approve to Cancel: update Cancel is already defined in workflow Order at line 4"},
+		{0, "Bill", ""},
+		{1, "Approve", ""},
+		{5, "Invoice", ""},
+	} {
+		_, err := renameHandler(store)(nil, &protocol.RenameParams{
+			TextDocumentPositionParams: protocol.TextDocumentPositionParams{
+				TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+				Position:     protocol.Position{Line: tc.line},
+			},
+			NewName: tc.newName,
+		})
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tc.want {
+			t.Errorf("rename on line %d to %s: error %q, want %q", tc.line, tc.newName, got, tc.want)
+		}
+	}
+}
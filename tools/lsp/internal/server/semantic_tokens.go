@@ -1,38 +1,17 @@
 package server
 
 import (
+	"strconv"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/highlight"
 	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/lexer"
-	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/token"
 	"github.com/tliron/glsp"
 	protocol "github.com/tliron/glsp/protocol_3_16"
 )
 
-// Semantic token type indices (must match legend order).
-type semanticTokenType = uint32
-
-const (
-	semKeyword   semanticTokenType = iota
-	semFunction
-	semMethod
-	semEvent
-	semString
-	semComment
-	semOperator
-	semParameter
-	semType
-	semVariable
-	semProperty
-	semNumber
-	semCount // sentinel for compile-time check
-)
-
-// Semantic token modifier bits.
-type semanticTokenModifier = uint32
-
-const (
-	modDeclaration semanticTokenModifier = 1 << iota
-)
-
+// semanticTokensHandler returns the tokens of the latest version of the
+// document, identified by its version number: tokens depend only on the
+// content and the lexer options it was analyzed with.
 func semanticTokensHandler(store *DocumentStore) protocol.TextDocumentSemanticTokensFullFunc {
 	return func(context *glsp.Context, params *protocol.SemanticTokensParams) (*protocol.SemanticTokens, error) {
 		doc, ok := store.Get(params.TextDocument.URI)
@@ -40,201 +19,39 @@ func semanticTokensHandler(store *DocumentStore) protocol.TextDocumentSemanticTo
 			return nil, nil
 		}
 
-		data := buildSemanticTokens(doc.Content)
+		data := buildSemanticTokens(doc.Content, doc.lexer)
 		return &protocol.SemanticTokens{
-			Data: data,
+			ResultID: ptrTo(strconv.Itoa(int(doc.Version))),
+			Data:     data,
 		}, nil
 	}
 }
 
-// buildSemanticTokens lexes the content and returns delta-encoded semantic token data.
-func buildSemanticTokens(content string) []uint32 {
-	tokens := lexer.New(content).AllTokens()
-
+// buildSemanticTokens classifies the content, lexed with lex, and returns
+// delta-encoded semantic token data. Token type indices are highlight.Kind values, which
+// follow highlight.Legend order.
+func buildSemanticTokens(content string, lex lexer.Options) []uint32 {
 	var data []uint32
 	var prevLine, prevCol uint32
-	var prevType token.TokenType
-	indentLevel := 0
-	inOptions := false
-	optionsBaseIndent := 0
 
-	for _, tok := range tokens {
-		switch tok.Type {
-		case token.INDENT:
-			indentLevel++
-			prevType = tok.Type
-			continue
-		case token.DEDENT:
-			indentLevel--
-			if indentLevel < 0 {
-				indentLevel = 0
-			}
-			if inOptions && indentLevel <= optionsBaseIndent {
-				inOptions = false
-			}
-			prevType = tok.Type
+	for _, s := range highlight.SpansWith(content, lex) {
+		// Control-flow keywords are left to the TextMate grammar, which
+		// the extension colors explicitly via tokenColorCustomizations.
+		if s.Kind == highlight.Control {
 			continue
 		}
 
-		if tok.Type == token.OPTIONS {
-			inOptions = true
-			optionsBaseIndent = indentLevel
-		}
-
-		tokenType, modifiers, shouldEmit := classifyToken(tok, prevType, indentLevel, inOptions)
-		if !shouldEmit {
-			if !isStructural(tok.Type) {
-				prevType = tok.Type
-			}
-			continue
-		}
-
-		length := tokenLength(tok)
-		line := uint32(tok.Line - 1)   // LSP 0-based
-		col := uint32(tok.Column - 1)  // LSP 0-based
-
+		line, col := uint32(s.Line), uint32(s.Column)
 		deltaLine := line - prevLine
-		var deltaCol uint32
+		deltaCol := col
 		if deltaLine == 0 {
 			deltaCol = col - prevCol
-		} else {
-			deltaCol = col
 		}
 
-		data = append(data, deltaLine, deltaCol, length, tokenType, modifiers)
+		data = append(data, deltaLine, deltaCol, uint32(s.Length), uint32(s.Kind), uint32(s.Modifiers))
 		prevLine = line
 		prevCol = col
-
-		if !isStructural(tok.Type) {
-			prevType = tok.Type
-		}
 	}
 
 	return data
 }
-
-// isStructural returns true for tokens that don't affect classification context.
-func isStructural(tt token.TokenType) bool {
-	switch tt {
-	case token.NEWLINE, token.EOF:
-		return true
-	default:
-		return false
-	}
-}
-
-// classifyToken determines the semantic token type and modifiers for a token.
-func classifyToken(tok token.Token, prevType token.TokenType, indentLevel int, inOptions bool) (tokenType uint32, modifiers uint32, shouldEmit bool) {
-	switch tok.Type {
-	// Category 1: Temporal primitive keywords → semType
-	case token.WORKFLOW, token.ACTIVITY, token.WORKER, token.NAMESPACE,
-		token.SIGNAL, token.QUERY, token.UPDATE,
-		token.TIMER,
-		token.PROMISE, token.STATE, token.CONDITION, token.SET, token.UNSET,
-		token.CLOSE, token.COMPLETE, token.FAIL, token.CONTINUE_AS_NEW,
-		token.SYNC, token.ASYNC:
-		return semType, 0, true
-
-	// OPTIONS / config keywords: muted (property).
-	case token.OPTIONS, token.TASK_QUEUE:
-		return semProperty, 0, true
-
-	// Category 3: Control flow keywords — no semantic token emitted.
-	// The TextMate grammar handles these with keyword.control.twf, and
-	// the extension provides an explicit color via tokenColorCustomizations.
-	// This avoids themes that collapse keyword and type semantic tokens
-	// into the same color.
-	case token.IF, token.ELSE, token.FOR, token.IN,
-		token.SWITCH, token.CASE,
-		token.AWAIT, token.ALL, token.ONE,
-		token.RETURN, token.BREAK, token.CONTINUE,
-		token.DETACH, token.NEXUS:
-		return 0, 0, false
-
-	case token.IDENT:
-		if inOptions {
-			// Inside options block: option keys and enum values.
-			return semProperty, 0, true
-		}
-		return classifyIdent(prevType, indentLevel)
-
-	case token.STRING:
-		return semString, 0, true
-
-	case token.COMMENT:
-		return semComment, 0, true
-
-	case token.COLON, token.ARROW, token.DOT:
-		return semOperator, 0, true
-
-	case token.ARGS:
-		return semParameter, 0, true
-
-	case token.DURATION, token.NUMBER:
-		return semNumber, 0, true
-
-	default:
-		return 0, 0, false
-	}
-}
-
-// classifyIdent determines the semantic type for an IDENT based on context.
-func classifyIdent(prevType token.TokenType, indentLevel int) (tokenType uint32, modifiers uint32, shouldEmit bool) {
-	switch prevType {
-	// Category 2: Temporal defined symbols — all use semFunction for consistent coloring.
-	case token.WORKFLOW, token.ACTIVITY:
-		if indentLevel == 0 {
-			return semFunction, modDeclaration, true
-		}
-		return semFunction, 0, true
-
-	case token.WORKER:
-		if indentLevel == 0 {
-			return semFunction, modDeclaration, true
-		}
-		// Worker reference inside namespace block
-		return semFunction, 0, true
-
-	case token.NAMESPACE:
-		if indentLevel == 0 {
-			return semFunction, modDeclaration, true
-		}
-		return semVariable, 0, true
-
-	case token.SIGNAL, token.QUERY, token.UPDATE:
-		if indentLevel == 1 {
-			// Handler declarations live at indent 1 inside a workflow.
-			return semFunction, modDeclaration, true
-		}
-		return semFunction, 0, true
-
-	case token.NEXUS:
-		// After NEXUS: endpoint name in call, or soft keyword ("service"/"endpoint")
-		return semFunction, 0, true
-
-	case token.SYNC, token.ASYNC:
-		// After SYNC/ASYNC: operation name declaration
-		return semFunction, modDeclaration, true
-
-	case token.TASK_QUEUE:
-		return semVariable, 0, true
-
-	default:
-		// Category 4: Bare ident in body — loose statement (params, assignments, expressions).
-		return semVariable, 0, true
-	}
-}
-
-// tokenLength returns the display length of a token.
-func tokenLength(tok token.Token) uint32 {
-	switch tok.Type {
-	case token.ARGS:
-		return uint32(len(tok.Literal)) + 2 // parens
-	case token.STRING:
-		return uint32(len(tok.Literal)) + 2 // quotes
-	case token.COMMENT:
-		return uint32(len(tok.Literal)) + 1 // #
-	default:
-		return uint32(len(tok.Literal))
-	}
-}
@@ -1,19 +1,100 @@
 package server
 
 import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/highlight"
 	"github.com/tliron/glsp"
 	protocol316 "github.com/tliron/glsp/protocol_3_16"
 	protocol "github.com/tliron/glsp/protocol_3_17"
 )
 
 // tokenTypeLegend defines the semantic token type names advertised to the client.
-// Order must match the semXxx iota constants in semantic_tokens.go.
-// The array size [semCount] is a compile-time assertion: if the number of
-// iota constants changes without updating this list, the build breaks.
-var tokenTypeLegend = [semCount]string{"keyword", "function", "method", "event", "string", "comment", "operator", "parameter", "type", "variable", "property", "number"}
+// Semantic token types are highlight.Kind values, so the legend is highlight's.
+var tokenTypeLegend = highlight.Legend
+
+// Handler is the server's glsp.Handler: a protocol.Handler with all LSP
+// methods registered, plus the LSP 3.17 pull diagnostic requests, which
+// protocol.Handler does not route, and the custom twf/ requests.
+type Handler struct {
+	*protocol.Handler
+	store *DocumentStore
+
+	shutdown atomic.Bool // set once shutdown is answered
+}
+
+// errShutdown answers the messages sent after shutdown other than exit.
+var errShutdown = errors.New("the server is shutting down")
 
-// NewHandler creates a protocol.Handler with all LSP methods registered.
-func NewHandler(name, version string) (*protocol.Handler, *DocumentStore) {
+// Handle answers the pull diagnostic requests and the custom twf/ requests
+// and passes every other message to the protocol handler, noting on
+// initialize whether the client pulls diagnostics and takes annotated
+// workspace edits. Each message is logged; a panic in its handler is
+// answered with an error rather than ending the server. After shutdown,
+// every message but exit is answered with an error.
+func (h *Handler) Handle(context *glsp.Context) (r any, validMethod bool, validParams bool, err error) {
+	start := time.Now()
+	defer func() {
+		p := recover()
+		if p != nil {
+			r, validMethod, validParams, err = nil, true, true, fmt.Errorf("internal error in %s: %v", context.Method, p)
+		}
+		logRequest(context, start, validMethod, err, p)
+		if p != nil {
+			panicBundle(h.store, fmt.Sprintf("panic in %s: %v", context.Method, p), panicStack())
+		}
+	}()
+	if context.Method == string(protocol316.MethodExit) {
+		// The connection closes once this returns. The protocol handler
+		// would refuse exit as uninitialized after shutdown.
+		return nil, true, true, nil
+	}
+	if h.shutdown.Load() {
+		return nil, true, true, errShutdown
+	}
+	if context.Method == string(protocol316.MethodShutdown) {
+		// Drain before answering, so the client's exit does not close the
+		// connection on diagnostics still being sent.
+		defer h.shutdown.Store(true)
+		h.store.Shutdown()
+	}
+	if context.Method == string(protocol316.MethodInitialize) {
+		h.store.pullDiagnostics, h.store.pullRefresh = clientPullsDiagnostics(context.Params)
+		h.store.annotateEdits = clientAnnotatesEdits(context.Params)
+	}
+	if r, ok, err := handlePullDiagnostics(h.store, context); ok {
+		return r, true, err == nil, err
+	}
+	if r, ok, err := handleGraphRequests(h.store, context); ok {
+		return r, true, err == nil, err
+	}
+	if r, ok, err := handleExplain(h.store, context); ok {
+		return r, true, err == nil, err
+	}
+	if r, ok, err := handleStatus(h.store, context); ok {
+		return r, true, err == nil, err
+	}
+	if r, ok, err := handleDebugBundle(h.store, context); ok {
+		return r, true, err == nil, err
+	}
+	return h.Handler.Handle(context)
+}
+
+// ExitCode returns the code the server exits with once the connection
+// closes: 0 after a shutdown request, and 1 when the client exited or went
+// away without one, as the LSP specification asks.
+func (h *Handler) ExitCode() int {
+	if h.shutdown.Load() {
+		return 0
+	}
+	return 1
+}
+
+// NewHandler creates a Handler with all LSP methods registered.
+func NewHandler(name, version string) (*Handler, *DocumentStore) {
 	store := NewDocumentStore()
 
 	handler := &protocol.Handler{
@@ -22,6 +103,11 @@ func NewHandler(name, version string) (*protocol.Handler, *DocumentStore) {
 			Shutdown:    shutdownHandler(),
 			SetTrace:    setTraceHandler(),
 
+			WorkspaceDidChangeWorkspaceFolders: didChangeWorkspaceFoldersHandler(store),
+			WorkspaceWillRenameFiles:           willRenameFilesHandler(store),
+			WorkspaceDidChangeWatchedFiles:     didChangeWatchedFilesHandler(store),
+			WorkspaceExecuteCommand:            executeCommandHandler(store),
+
 			TextDocumentDidOpen:  didOpenHandler(store),
 			TextDocumentDidChange: didChangeHandler(store),
 			TextDocumentDidClose:  didCloseHandler(store),
@@ -30,6 +116,7 @@ func NewHandler(name, version string) (*protocol.Handler, *DocumentStore) {
 			TextDocumentDefinition:         definitionHandler(store),
 			TextDocumentDocumentSymbol:     documentSymbolHandler(store),
 			TextDocumentCompletion:         completionHandler(store),
+			CompletionItemResolve:          completionResolveHandler(store),
 			TextDocumentReferences:         referencesHandler(store),
 			TextDocumentRename:             renameHandler(store),
 			TextDocumentPrepareRename:      prepareRenameHandler(store),
@@ -38,14 +125,56 @@ func NewHandler(name, version string) (*protocol.Handler, *DocumentStore) {
 			TextDocumentSignatureHelp:      signatureHelpHandler(store),
 			TextDocumentCodeAction:         codeActionHandler(store),
 		},
-		Initialize: initializeHandler(name, version),
+		Initialize: initializeHandler(name, version, store),
 	}
 
-	return handler, store
+	return &Handler{Handler: handler, store: store}, store
 }
 
-func initializeHandler(name, version string) protocol.InitializeFunc {
+// initializeResult is protocol.InitializeResult with the diagnosticProvider
+// capability, which protocol.ServerCapabilities does not declare. Its
+// Capabilities field hides the embedded result's in JSON.
+type initializeResult struct {
+	protocol.InitializeResult
+	Capabilities serverCapabilities `json:"capabilities"`
+}
+
+type serverCapabilities struct {
+	protocol.ServerCapabilities
+	DiagnosticProvider *diagnosticOptions `json:"diagnosticProvider,omitempty"`
+}
+
+// initializeHandler indexes the client's workspace folders, or its root
+// when it sends no folders, and applies the initialization options:
+//
+//	{"crossRootResolution": "root" | "workspace", "nexusEndpoints": [...]}
+//
+// crossRootResolution overrides the scope set before the server started;
+// nexusEndpoints adds endpoint names to offer in nexus call completion.
+func initializeHandler(name, version string, store *DocumentStore) protocol.InitializeFunc {
 	return func(context *glsp.Context, params *protocol.InitializeParams) (any, error) {
+		if opts, ok := params.InitializationOptions.(map[string]any); ok {
+			if scope, ok := opts["crossRootResolution"].(string); ok {
+				if err := store.Workspace.SetScope(scope); err != nil {
+					return nil, err
+				}
+			}
+			if names, ok := opts["nexusEndpoints"].([]any); ok {
+				for _, name := range names {
+					if name, ok := name.(string); ok && name != "" {
+						store.NexusEndpoints = append(store.NexusEndpoints, name)
+					}
+				}
+			}
+		}
+		if len(params.WorkspaceFolders) > 0 {
+			for _, f := range params.WorkspaceFolders {
+				store.Workspace.AddFolder(f.URI)
+			}
+		} else if params.RootURI != nil {
+			store.Workspace.AddFolder(*params.RootURI)
+		}
+
 		capabilities := protocol.InitializeResult{
 			Capabilities: protocol.ServerCapabilities{
 				ServerCapabilities: protocol316.ServerCapabilities{
@@ -56,7 +185,7 @@ func initializeHandler(name, version string) protocol.InitializeFunc {
 					HoverProvider:          &protocol316.HoverOptions{},
 					DefinitionProvider:     &protocol316.DefinitionOptions{},
 					DocumentSymbolProvider: &protocol316.DocumentSymbolOptions{},
-					CompletionProvider:     &protocol316.CompletionOptions{},
+					CompletionProvider:     &protocol316.CompletionOptions{ResolveProvider: boolPtr(true)},
 					ReferencesProvider:     &protocol316.ReferenceOptions{},
 					RenameProvider:         &protocol316.RenameOptions{PrepareProvider: boolPtr(true)},
 					FoldingRangeProvider:   &protocol316.FoldingRangeOptions{},
@@ -66,6 +195,9 @@ func initializeHandler(name, version string) protocol.InitializeFunc {
 							protocol316.CodeActionKindRefactor,
 						},
 					},
+					ExecuteCommandProvider: &protocol316.ExecuteCommandOptions{
+						Commands: Commands,
+					},
 					SignatureHelpProvider: &protocol316.SignatureHelpOptions{
 						TriggerCharacters: []string{"("},
 					},
@@ -76,6 +208,20 @@ func initializeHandler(name, version string) protocol.InitializeFunc {
 						},
 						Full: true,
 					},
+					Workspace: &protocol316.ServerCapabilitiesWorkspace{
+						WorkspaceFolders: &protocol316.WorkspaceFoldersServerCapabilities{
+							Supported:           boolPtr(true),
+							ChangeNotifications: &protocol316.BoolOrString{Value: true},
+						},
+						FileOperations: &protocol316.ServerCapabilitiesWorkspaceFileOperations{
+							WillRename: &protocol316.FileOperationRegistrationOptions{
+								Filters: []protocol316.FileOperationFilter{
+									{Scheme: ptrTo("file"), Pattern: protocol316.FileOperationPattern{Glob: "**/*.twf", Matches: ptrTo(protocol316.FileOperationPatternKindFile)}},
+									{Scheme: ptrTo("file"), Pattern: protocol316.FileOperationPattern{Glob: "**", Matches: ptrTo(protocol316.FileOperationPatternKindFolder)}},
+								},
+							},
+						},
+					},
 				},
 			},
 			ServerInfo: &protocol316.InitializeResultServerInfo{
@@ -83,7 +229,48 @@ func initializeHandler(name, version string) protocol.InitializeFunc {
 				Version: &version,
 			},
 		}
-		return capabilities, nil
+		return initializeResult{
+			InitializeResult: capabilities,
+			Capabilities: serverCapabilities{
+				ServerCapabilities: capabilities.Capabilities,
+				DiagnosticProvider: &diagnosticOptions{InterFileDependencies: true, WorkspaceDiagnostics: true},
+			},
+		}, nil
+	}
+}
+
+// didChangeWorkspaceFoldersHandler updates the workspace index and analyzes
+// the open documents again, since any of them may have gained or lost
+// definitions to resolve against.
+func didChangeWorkspaceFoldersHandler(store *DocumentStore) protocol316.WorkspaceDidChangeWorkspaceFoldersFunc {
+	return func(context *glsp.Context, params *protocol316.DidChangeWorkspaceFoldersParams) error {
+		for _, f := range params.Event.Removed {
+			store.Workspace.RemoveFolder(f.URI)
+		}
+		for _, f := range params.Event.Added {
+			store.Workspace.AddFolder(f.URI)
+		}
+		refreshInBackground(context, store, store.RefreshAll())
+		return nil
+	}
+}
+
+// willRenameFilesHandler moves renamed .twf files and folders in the
+// workspace index before the client renames them, and analyzes again the
+// open documents resolving against them. TWF files do not import each
+// other, so no references name a file and the returned edit is empty.
+func willRenameFilesHandler(store *DocumentStore) protocol316.WorkspaceWillRenameFilesFunc {
+	return func(context *glsp.Context, params *protocol316.RenameFilesParams) (*protocol316.WorkspaceEdit, error) {
+		var changed []string
+		for _, f := range params.Files {
+			if store.Workspace.Rename(f.OldURI, f.NewURI) {
+				changed = append(changed, f.OldURI, f.NewURI)
+			}
+		}
+		if len(changed) > 0 {
+			refreshInBackground(context, store, store.Refresh(changed...))
+		}
+		return nil, nil
 	}
 }
 
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/tliron/glsp"
+	protocol317 "github.com/tliron/glsp/protocol_3_17"
+)
+
+func TestInitializeResultJSON(t *testing.T) {
+	result, err := initializeHandler("twf", "test", NewDocumentStore())(&glsp.Context{}, &protocol317.InitializeParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Capabilities struct {
+			HoverProvider      any                `json:"hoverProvider"`
+			DiagnosticProvider *diagnosticOptions `json:"diagnosticProvider"`
+		} `json:"capabilities"`
+		ServerInfo struct{ Name string } `json:"serverInfo"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Capabilities.HoverProvider == nil || got.ServerInfo.Name != "twf" {
+		t.Errorf("expected the protocol capabilities and server info, got %s", data)
+	}
+	if p := got.Capabilities.DiagnosticProvider; p == nil || !p.InterFileDependencies || !p.WorkspaceDiagnostics {
+		t.Errorf("expected the diagnostic provider, got %s", data)
+	}
+
+	pull, refresh := clientPullsDiagnostics(json.RawMessage(`{"capabilities": {"textDocument": {"diagnostic": {}}, "workspace": {"diagnostics": {"refreshSupport": true}}}}`))
+	if !pull || !refresh {
+		t.Errorf("expected pull and refresh support, got %t, %t", pull, refresh)
+	}
+	if pull, _ := clientPullsDiagnostics(json.RawMessage(`{"capabilities": {}}`)); pull {
+		t.Error("expected no pull support without the diagnostic capability")
+	}
+}
+
+func TestHandlerRecoversPanics(t *testing.T) {
+	// Without a store, the pull diagnostic handler dereferences nil.
+	h := &Handler{Handler: &protocol317.Handler{}}
+	r, validMethod, validParams, err := h.Handle(&glsp.Context{
+		Method: methodTextDocumentDiagnostic,
+		Params: json.RawMessage(`{"textDocument": {"uri": "file:///a.twf"}}`),
+	})
+	if r != nil || !validMethod || !validParams || err == nil || !strings.Contains(err.Error(), "internal error in textDocument/diagnostic") {
+		t.Errorf("expected an error response, got %v, %t, %t, %v", r, validMethod, validParams, err)
+	}
+	if uri := paramsURI(json.RawMessage(`{"textDocument": {"uri": "file:///a.twf"}, "position": {}}`)); uri != "file:///a.twf" {
+		t.Errorf("expected the document URI, got %q", uri)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	h, store := NewHandler("twf", "test")
+	call := func(method, params string) error {
+		t.Helper()
+		_, _, _, err := h.Handle(&glsp.Context{Method: method, Params: json.RawMessage(params), Notify: func(string, any) {}})
+		return err
+	}
+	if err := call("initialize", `{"capabilities": {}}`); err != nil {
+		t.Fatal(err)
+	}
+	const uri = "file:///order.twf"
+	if err := call("textDocument/didOpen", `{"textDocument": {"uri": "`+uri+`", "version": 1, "text": "workflow Order():\n    close complete\n"}}`); err != nil {
+		t.Fatal(err)
+	}
+	if err := call("textDocument/didChange", `{"textDocument": {"uri": "`+uri+`", "version": 2}, "contentChanges": [{"text": "workflow Order():\n    close fail\n"}]}`); err != nil {
+		t.Fatal(err)
+	}
+	if h.ExitCode() != 1 {
+		t.Error("expected exit code 1 before shutdown")
+	}
+
+	if err := call("shutdown", ""); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if len(store.pending) != 0 {
+		t.Errorf("expected no analyses left running, got %v", store.pending)
+	}
+	if _, ok := store.Update(uri, 3, "workflow Order():\n    close continue_as_new\n").Wait(); ok {
+		t.Error("expected edits after shutdown not to be analyzed")
+	}
+	for _, method := range []string{"textDocument/hover", methodStatus, "shutdown"} {
+		if err := call(method, `{"textDocument": {"uri": "`+uri+`"}, "position": {}}`); err != errShutdown {
+			t.Errorf("%s after shutdown: expected errShutdown, got %v", method, err)
+		}
+	}
+	if err := call("exit", ""); err != nil {
+		t.Errorf("exit: %v", err)
+	}
+	if h.ExitCode() != 0 {
+		t.Error("expected exit code 0 after shutdown")
+	}
+}
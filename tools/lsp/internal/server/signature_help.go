@@ -47,10 +47,9 @@ func buildSignatureHelp(name, keyword, params, returnType string) *protocol.Sign
 	if params != "" {
 		// Offset within the label where params start: after "keyword name("
 		paramsOffset := len(keyword) + 1 + len(name) + 1 // "keyword name("
-		parts := strings.Split(params, ",")
+		decls := ast.ParseParams(params)
 		offset := paramsOffset
-		for _, p := range parts {
-			p = strings.TrimSpace(p)
+		for i, p := range ast.SplitList(params) {
 			// Find the actual position of this param in the label
 			start := strings.Index(label[offset:], p)
 			if start < 0 {
@@ -58,9 +57,13 @@ func buildSignatureHelp(name, keyword, params, returnType string) *protocol.Sign
 			}
 			start += offset
 			end := start + len(p)
-			parameters = append(parameters, protocol.ParameterInformation{
+			info := protocol.ParameterInformation{
 				Label: [2]protocol.UInteger{protocol.UInteger(start), protocol.UInteger(end)},
-			})
+			}
+			if d := decls[i].Default; d != "" {
+				info.Documentation = "optional, defaults to " + d
+			}
+			parameters = append(parameters, info)
 			offset = end
 		}
 	}
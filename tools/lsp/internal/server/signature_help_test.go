@@ -0,0 +1,26 @@
+package server
+
+import (
+	"testing"
+
+	protocol "github.com/tliron/glsp/protocol_3_16"
+)
+
+func TestSignatureHelpOptionalParams(t *testing.T) {
+	help := buildSignatureHelp("Notify", "activity", `order: Order, channel: string = "email, sms"`, "")
+	params := help.Signatures[0].Parameters
+	if len(params) != 2 {
+		t.Fatalf("expected 2 parameters, got %+v", params)
+	}
+	if params[0].Documentation != nil {
+		t.Errorf("expected no documentation for a required parameter, got %v", params[0].Documentation)
+	}
+	label := help.Signatures[0].Label
+	span := params[1].Label.([2]protocol.UInteger)
+	if got := label[span[0]:span[1]]; got != `channel: string = "email, sms"` {
+		t.Errorf("unexpected parameter span %q", got)
+	}
+	if params[1].Documentation != `optional, defaults to "email, sms"` {
+		t.Errorf("expected the default in the documentation, got %v", params[1].Documentation)
+	}
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"github.com/tliron/glsp"
+)
+
+// methodStatus is the custom request for the server's estimated memory
+// use, so users of large workspaces can see what holds it. It takes no
+// params and answers a statusResult.
+const methodStatus = "twf/status"
+
+type statusResult struct {
+	Documents int `json:"documents"`
+	// DocumentBytes estimates the memory of the open documents: their
+	// content and parsed definitions.
+	DocumentBytes int        `json:"documentBytes"`
+	Index         indexStats `json:"index"`
+	// EstimatedBytes totals the estimates of the documents and the index.
+	EstimatedBytes int `json:"estimatedBytes"`
+}
+
+// handleStatus answers twf/status, reporting whether method is it.
+func handleStatus(store *DocumentStore, context *glsp.Context) (r any, ok bool, err error) {
+	if context.Method != methodStatus {
+		return nil, false, nil
+	}
+	return serverStatus(store), true, nil
+}
+
+// serverStatus returns the size of the store and its workspace index.
+func serverStatus(store *DocumentStore) statusResult {
+	store.mu.Lock()
+	st := statusResult{Documents: len(store.docs)}
+	for _, doc := range store.docs {
+		st.DocumentBytes += len(doc.Content) + parsedSize(doc.Content)
+	}
+	store.mu.Unlock()
+	st.Index = store.Workspace.stats()
+	st.EstimatedBytes = st.DocumentBytes + st.Index.ContentBytes + st.Index.ParsedBytes
+	return st
+}
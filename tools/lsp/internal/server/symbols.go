@@ -26,6 +26,16 @@ func documentSymbolHandler(store *DocumentStore) protocol.TextDocumentDocumentSy
 				symbols = append(symbols, namespaceSymbol(d))
 			case *ast.NexusServiceDef:
 				symbols = append(symbols, nexusServiceSymbol(d))
+			case *ast.ConstDef:
+				symbols = append(symbols, protocol.DocumentSymbol{
+					Name:           d.Name,
+					Detail:         ptrTo(d.Value),
+					Kind:           protocol.SymbolKindConstant,
+					Range:          lineRange(d.Line, d.Line),
+					SelectionRange: posToRange(d.Line, d.Column),
+				})
+			case *ast.EnumDef:
+				symbols = append(symbols, enumSymbol(d))
 			}
 		}
 
@@ -33,6 +43,26 @@ func documentSymbolHandler(store *DocumentStore) protocol.TextDocumentDocumentSy
 	}
 }
 
+// enumSymbol returns an enum with its values as children. The whole
+// declaration sits on one line.
+func enumSymbol(e *ast.EnumDef) protocol.DocumentSymbol {
+	sym := protocol.DocumentSymbol{
+		Name:           e.Name,
+		Kind:           protocol.SymbolKindEnum,
+		Range:          lineRange(e.Line, e.Line),
+		SelectionRange: posToRange(e.Line, e.Column),
+	}
+	for _, v := range e.Values {
+		sym.Children = append(sym.Children, protocol.DocumentSymbol{
+			Name:           v.Name,
+			Kind:           protocol.SymbolKindEnumMember,
+			Range:          posToRange(v.Line, v.Column),
+			SelectionRange: posToRange(v.Line, v.Column),
+		})
+	}
+	return sym
+}
+
 func workflowSymbol(wf *ast.WorkflowDef) protocol.DocumentSymbol {
 	r := defRange(wf)
 	sym := protocol.DocumentSymbol{
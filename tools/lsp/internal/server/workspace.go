@@ -0,0 +1,474 @@
+package server
+
+import (
+	"cmp"
+	"fmt"
+	"io/fs"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/lexer"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/parser"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/resolver"
+)
+
+// Cross-root resolution scopes, selecting which workspace files a
+// document's references may resolve to.
+const (
+	// ScopeRoot resolves against the files of the innermost workspace
+	// folder containing the document, so separate projects opened together
+	// do not see each other's definitions.
+	ScopeRoot = "root"
+	// ScopeWorkspace resolves against the files of every workspace folder,
+	// for a design split across folders.
+	ScopeWorkspace = "workspace"
+)
+
+// Scopes are the valid Workspace scopes. The first is the default.
+var Scopes = []string{ScopeRoot, ScopeWorkspace}
+
+// Workspace indexes the .twf files under the client's workspace folders, so
+// a document's references resolve to definitions in the other files in its
+// scope. Each file belongs to the innermost folder containing it. Documents
+// outside every folder, and all documents when there are no folders, see
+// only their own definitions.
+//
+// Indexed definitions are parsed when first needed, resolved privately,
+// stamped with their file's URI as SourceFile, and never modified
+// afterwards, so concurrent analyses may share them. Under MaxParsedBytes
+// the definitions of the least recently used files are dropped and parsed
+// again on demand, so only the files' content stays in memory for good.
+type Workspace struct {
+	// MaxParsedBytes bounds the estimated memory of the parsed definitions
+	// the index keeps; 0 keeps them all.
+	MaxParsedBytes int
+
+	mu        sync.Mutex
+	scope     string
+	lexer     lexer.Options             // how indexed files are lexed
+	roots     map[string]bool           // folder paths
+	files     map[string]*workspaceFile // by path
+	gen       int                       // bumped by every change to what files see
+	tick      int                       // bumped by every use of a file's definitions
+	evictions int                       // parsed files dropped so far
+}
+
+// workspaceFile is the indexed content of one file, and its definitions
+// while they are parsed. Entries in the index are only changed under the
+// workspace's lock; the copies load returns are the caller's.
+type workspaceFile struct {
+	uri     string
+	content string
+	defs    []ast.Definition
+	parsed  bool // defs hold the parsed definitions
+	used    int  // tick of the last use of defs
+}
+
+// astBytesPerSourceByte estimates the memory of parsed definitions from
+// the size of their source: resolved ASTs of the parser's test data take
+// about four times their source.
+const astBytesPerSourceByte = 4
+
+// parsedSize estimates the memory of the parsed definitions of content.
+func parsedSize(content string) int {
+	return len(content) * astBytesPerSourceByte
+}
+
+// NewWorkspace creates a workspace with no folders and the default scope.
+func NewWorkspace() *Workspace {
+	return &Workspace{
+		scope: Scopes[0],
+		roots: make(map[string]bool),
+		files: make(map[string]*workspaceFile),
+	}
+}
+
+// SetScope sets the cross-root resolution scope, one of Scopes. Documents
+// already analyzed keep their results until they are analyzed again.
+func (w *Workspace) SetScope(scope string) error {
+	if !slices.Contains(Scopes, scope) {
+		return fmt.Errorf("unknown cross-root resolution scope %q (want %s)", scope, strings.Join(Scopes, " or "))
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.scope = scope
+	w.gen++
+	return nil
+}
+
+// AddFolder adds the workspace folder at uri and indexes the .twf files
+// under it, skipping hidden directories, node_modules, and files larger than
+// the parser accepts. Files already
+// indexed, such as open documents, keep their indexed content. Folders that
+// are not file URIs are ignored.
+func (w *Workspace) AddFolder(uri string) {
+	root := uriPath(uri)
+	if root == "" {
+		return
+	}
+	found := make(map[string]*workspaceFile)
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // skip what cannot be read
+		}
+		if d.IsDir() {
+			if path != root && (strings.HasPrefix(d.Name(), ".") || d.Name() == "node_modules") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".twf") {
+			return nil
+		}
+		if info, err := d.Info(); err != nil || tooLarge(info.Size()) {
+			return nil // opening it reports the limit
+		}
+		if content, err := os.ReadFile(path); err == nil {
+			found[path] = &workspaceFile{uri: pathURI(path), content: string(content)}
+		}
+		return nil
+	})
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.roots[root] = true
+	w.gen++
+	for path, f := range found {
+		if _, ok := w.files[path]; !ok {
+			w.files[path] = f
+		}
+	}
+}
+
+// Folders returns the paths of the workspace folders, sorted.
+func (w *Workspace) Folders() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return slices.Sorted(maps.Keys(w.roots))
+}
+
+// RemoveFolder removes the workspace folder at uri and drops the files no
+// remaining folder contains.
+func (w *Workspace) RemoveFolder(uri string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.roots, uriPath(uri))
+	w.gen++
+	for path := range w.files {
+		if w.rootOf(path) == "" {
+			delete(w.files, path)
+		}
+	}
+}
+
+// Set indexes content as the file at uri, as an open document's buffer
+// stands in for the file on disk, and reports whether the indexed content
+// changed. Files outside every folder are not indexed.
+func (w *Workspace) Set(uri, content string) bool {
+	path := uriPath(uri)
+	if path == "" {
+		return false
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if old, ok := w.files[path]; w.rootOf(path) == "" || (ok && old.content == content) {
+		return false
+	}
+	w.files[path] = &workspaceFile{uri: uri, content: content}
+	w.gen++
+	return true
+}
+
+// Reload indexes the file at uri from disk again, as when its document is
+// closed without saving, dropping it when it no longer exists. It reports
+// whether the indexed content changed.
+func (w *Workspace) Reload(uri string) bool {
+	path := uriPath(uri)
+	if path == "" {
+		return false
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		_, ok := w.files[path]
+		if ok {
+			delete(w.files, path)
+			w.gen++
+		}
+		return ok
+	}
+	return w.Set(uri, string(content))
+}
+
+// SetLexer sets how indexed files are lexed and drops the parsed
+// definitions of every one, so each is parsed again from its indexed
+// content, as when the keyword aliases changed how it lexes.
+func (w *Workspace) SetLexer(lex lexer.Options) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lexer = lex
+	for path, f := range w.files {
+		w.files[path] = &workspaceFile{uri: f.uri, content: f.content}
+	}
+	w.gen++
+}
+
+// Rename moves the indexed files at or under oldURI, a file or a folder, to
+// the matching paths under newURI, so their definitions carry the new URIs.
+// Files moved outside every folder are dropped; files moved into a folder
+// from outside are indexed when opened. It reports whether the index
+// changed.
+func (w *Workspace) Rename(oldURI, newURI string) bool {
+	from, to := uriPath(oldURI), uriPath(newURI)
+	if from == "" || to == "" || from == to {
+		return false
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	changed := false
+	moved := make(map[string]*workspaceFile)
+	for path, f := range w.files {
+		if !within(path, from) {
+			continue
+		}
+		changed = true
+		delete(w.files, path)
+		dest := to + strings.TrimPrefix(path, from)
+		if w.rootOf(dest) != "" {
+			moved[dest] = &workspaceFile{uri: pathURI(dest), content: f.content}
+		}
+	}
+	maps.Copy(w.files, moved)
+	if changed {
+		w.gen++
+	}
+	return changed
+}
+
+// snapshot returns the indexed files in path order, with their
+// definitions, and the generation of the index: a number that changes
+// whenever the files or what they see change.
+func (w *Workspace) snapshot() (files []*workspaceFile, generation int) {
+	w.mu.Lock()
+	for _, path := range slices.Sorted(maps.Keys(w.files)) {
+		files = append(files, w.files[path])
+	}
+	generation = w.gen
+	w.mu.Unlock()
+	return w.load(files), generation
+}
+
+// content returns the indexed content of the file at uri.
+func (w *Workspace) content(uri string) (string, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	f, ok := w.files[uriPath(uri)]
+	if !ok {
+		return "", false
+	}
+	return f.content, true
+}
+
+// External returns the definitions of the files in scope for the document
+// at uri, other than its own, in path order.
+func (w *Workspace) External(uri string) []ast.Definition {
+	var defs []ast.Definition
+	for _, f := range w.externalFiles(uri) {
+		defs = append(defs, f.defs...)
+	}
+	return defs
+}
+
+// externalFiles returns the files in scope for the document at uri, other
+// than its own, in path order, holding their definitions.
+func (w *Workspace) externalFiles(uri string) []*workspaceFile {
+	self := uriPath(uri)
+	if self == "" {
+		return nil
+	}
+	w.mu.Lock()
+	var files []*workspaceFile
+	for _, path := range slices.Sorted(maps.Keys(w.files)) {
+		if path != self && w.inScope(self, path) {
+			files = append(files, w.files[path])
+		}
+	}
+	w.mu.Unlock()
+	return w.load(files)
+}
+
+// load returns copies of files, entries of the index, holding their
+// definitions, parsing those not parsed yet or dropped. The entries are
+// marked used, and those still in the index keep what was parsed, within
+// MaxParsedBytes.
+func (w *Workspace) load(files []*workspaceFile) []*workspaceFile {
+	out := make([]*workspaceFile, len(files))
+	w.mu.Lock()
+	for i, f := range files {
+		w.tick++
+		f.used = w.tick
+		out[i] = &workspaceFile{uri: f.uri, content: f.content, defs: f.defs, parsed: f.parsed}
+	}
+	lex := w.lexer
+	w.mu.Unlock()
+
+	parsed := false
+	for _, f := range out {
+		if !f.parsed {
+			f.defs, f.parsed = parseIndexed(f.uri, f.content, lex), true
+			parsed = true
+		}
+	}
+	if !parsed {
+		return out
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for i, f := range files {
+		// An entry replaced meanwhile holds newer content; one parsed
+		// meanwhile keeps its definitions.
+		if w.files[uriPath(f.uri)] == f && !f.parsed {
+			f.defs, f.parsed = out[i].defs, true
+		}
+	}
+	w.evictLocked()
+	return out
+}
+
+// evictLocked drops the definitions of the least recently used files until
+// the estimated memory of those kept is within MaxParsedBytes. w.mu must
+// be held.
+func (w *Workspace) evictLocked() {
+	if w.MaxParsedBytes <= 0 {
+		return
+	}
+	var parsed []*workspaceFile
+	total := 0
+	for _, f := range w.files {
+		if f.parsed {
+			parsed = append(parsed, f)
+			total += parsedSize(f.content)
+		}
+	}
+	slices.SortFunc(parsed, func(a, b *workspaceFile) int { return cmp.Or(cmp.Compare(a.used, b.used), cmp.Compare(a.uri, b.uri)) })
+	for _, f := range parsed {
+		if total <= w.MaxParsedBytes {
+			break
+		}
+		f.defs, f.parsed = nil, false
+		total -= parsedSize(f.content)
+		w.evictions++
+	}
+}
+
+// indexStats is the size of the index, as reported by twf/status.
+type indexStats struct {
+	Files        int `json:"files"`
+	ParsedFiles  int `json:"parsedFiles"`
+	ContentBytes int `json:"contentBytes"`
+	// ParsedBytes estimates the memory of the parsed definitions kept.
+	ParsedBytes    int `json:"parsedBytes"`
+	MaxParsedBytes int `json:"maxParsedBytes"`
+	// Evictions counts the files whose definitions were dropped to stay
+	// within MaxParsedBytes.
+	Evictions int `json:"evictions"`
+}
+
+// stats returns the size of the index.
+func (w *Workspace) stats() indexStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	st := indexStats{Files: len(w.files), MaxParsedBytes: w.MaxParsedBytes, Evictions: w.evictions}
+	for _, f := range w.files {
+		st.ContentBytes += len(f.content)
+		if f.parsed {
+			st.ParsedFiles++
+			st.ParsedBytes += parsedSize(f.content)
+		}
+	}
+	return st
+}
+
+// Sees reports whether the analysis of the document at uri includes the
+// definitions of the file at other, so a change to other calls for
+// analyzing uri again.
+func (w *Workspace) Sees(uri, other string) bool {
+	self, path := uriPath(uri), uriPath(other)
+	if self == "" || path == "" || self == path {
+		return false
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.inScope(self, path)
+}
+
+// inScope reports whether the file at path is in scope for the document at
+// self. w.mu must be held.
+func (w *Workspace) inScope(self, path string) bool {
+	root := w.rootOf(self)
+	if root == "" {
+		return false
+	}
+	if w.scope == ScopeWorkspace {
+		return w.rootOf(path) != ""
+	}
+	return w.rootOf(path) == root
+}
+
+// rootOf returns the innermost folder containing path, or "". w.mu must be
+// held.
+func (w *Workspace) rootOf(path string) string {
+	var best string
+	for root := range w.roots {
+		if within(path, root) && len(root) > len(best) {
+			best = root
+		}
+	}
+	return best
+}
+
+// within reports whether path is dir or inside it.
+func within(path, dir string) bool {
+	return path == dir || strings.HasPrefix(path, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator))
+}
+
+// tooLarge reports whether a file of size bytes is beyond what the parser
+// accepts, so indexing skips it without reading it.
+func tooLarge(size int64) bool {
+	limit := parser.DefaultLimits.MaxBytes
+	return limit > 0 && size > int64(limit)
+}
+
+// parseIndexed parses and resolves content privately for the index. Parse
+// and resolve errors are left to the file's own analysis when it is opened.
+func parseIndexed(uri, content string, lex lexer.Options) []ast.Definition {
+	f, _ := parser.WithLexer(lex).ParseFileAll(content)
+	for _, def := range f.Definitions {
+		ast.SetSourceFile(def, uri)
+	}
+	resolver.ResolveFile(f)
+	return f.Definitions
+}
+
+// uriPath returns the cleaned file system path of a file URI, or "" for
+// other URIs.
+func uriPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" || u.Path == "" {
+		return ""
+	}
+	return filepath.Clean(filepath.FromSlash(u.Path))
+}
+
+// pathURI returns the file URI of a file system path.
+func pathURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
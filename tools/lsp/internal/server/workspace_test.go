@@ -0,0 +1,184 @@
+package server
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+)
+
+func TestWorkspaceResolution(t *testing.T) {
+	dir := writeWorkspace(t, map[string]string{
+		"orders/order.twf":           "workflow Order():\n    activity Charge()\n    activity Ship()\n",
+		"orders/charge.twf":          "activity Charge():\n    return\n",
+		"orders/.hidden/ignored.twf": "activity Ship():\n    return\n",
+		"shipping/ship.twf":          "activity Ship():\n    return\n",
+	})
+	store := NewDocumentStore()
+	store.Workspace.AddFolder(pathURI(filepath.Join(dir, "orders")))
+	store.Workspace.AddFolder(pathURI(filepath.Join(dir, "shipping")))
+
+	uri := pathURI(filepath.Join(dir, "orders", "order.twf"))
+	doc := store.Open(uri, 1, "workflow Order():\n    activity Charge()\n    activity Ship()\n")
+	if len(doc.ResolveErrs) != 1 || doc.ResolveErrs[0].Name != "Ship" {
+		t.Fatalf("expected only Ship to be undefined within the root, got %v", doc.ResolveErrs)
+	}
+	call := doc.File.Definitions[0].(*ast.WorkflowDef).Body[0].(*ast.ActivityCall)
+	want := pathURI(filepath.Join(dir, "orders", "charge.twf"))
+	if got := nodeURI(resolvedTarget(call), doc.Symbols, uri); got != want {
+		t.Errorf("Charge is defined in %s, want %s", got, want)
+	}
+	if len(doc.ValidateErrs) != 0 {
+		t.Errorf("expected no diagnostics from other files, got %v", doc.ValidateErrs)
+	}
+
+	if err := store.Workspace.SetScope(ScopeWorkspace); err != nil {
+		t.Fatal(err)
+	}
+	analyses := store.RefreshAll()
+	if len(analyses) != 1 {
+		t.Fatalf("expected the open document to be analyzed again, got %d analyses", len(analyses))
+	}
+	if doc, ok := analyses[0].Wait(); !ok || len(doc.ResolveErrs) != 0 {
+		t.Errorf("expected Ship to resolve across roots, got %v", doc.ResolveErrs)
+	}
+}
+
+func TestWorkspaceDuplicateDefinition(t *testing.T) {
+	dir := writeWorkspace(t, map[string]string{
+		"charge.twf": "activity Charge():\n    return\n",
+	})
+	store := NewDocumentStore()
+	store.Workspace.AddFolder(pathURI(dir))
+
+	uri := pathURI(filepath.Join(dir, "order.twf"))
+	doc := store.Open(uri, 1, "workflow Order():\n    activity Charge()\n\nactivity Charge():\n    return\n")
+	diags := diagnostics(doc)
+	if len(diags) != 1 {
+		t.Fatalf("expected one duplicate definition error, got %v", diags)
+	}
+	other := pathURI(filepath.Join(dir, "charge.twf"))
+	if want := "duplicate activity definition: Charge, also defined at " + other + ":1:1"; diags[0].Message != want {
+		t.Errorf("message = %q, want %q", diags[0].Message, want)
+	}
+	related := diags[0].RelatedInformation
+	if len(related) != 1 || related[0].Location.URI != other || related[0].Location.Range.Start.Line != 0 {
+		t.Errorf("expected the related location in %s, got %+v", other, related)
+	}
+}
+
+func TestWorkspaceEviction(t *testing.T) {
+	dir := writeWorkspace(t, map[string]string{
+		"a.twf": "activity A():\n    return\n",
+		"b.twf": "activity B():\n    return\n",
+		"c.twf": "activity C():\n    return\n",
+	})
+	store := NewDocumentStore()
+	store.Workspace.MaxParsedBytes = 2 * parsedSize("activity A():\n    return\n")
+	store.Workspace.AddFolder(pathURI(dir))
+	if st := serverStatus(store); st.Index.Files != 3 || st.Index.ParsedFiles != 0 {
+		t.Fatalf("expected three files indexed and none parsed, got %+v", st)
+	}
+
+	uri := pathURI(filepath.Join(dir, "order.twf"))
+	doc := store.Open(uri, 1, "workflow Order():\n    activity A()\n    activity B()\n    activity C()\n    close complete\n")
+	if len(doc.ResolveErrs) != 0 {
+		t.Fatalf("unexpected resolve errors: %v", doc.ResolveErrs)
+	}
+	st := serverStatus(store)
+	if st.Documents != 1 || st.Index.ParsedFiles != 2 || st.Index.Evictions != 1 || st.Index.ParsedBytes > st.Index.MaxParsedBytes {
+		t.Errorf("expected two of three files kept parsed, got %+v", st)
+	}
+	if st.EstimatedBytes != st.DocumentBytes+st.Index.ContentBytes+st.Index.ParsedBytes {
+		t.Errorf("estimate does not add up: %+v", st)
+	}
+
+	// The evicted file is parsed again when next needed.
+	doc = store.Open(uri, 2, doc.Content+"\n")
+	if len(doc.ResolveErrs) != 0 {
+		t.Errorf("unexpected resolve errors after eviction: %v", doc.ResolveErrs)
+	}
+	if st := serverStatus(store); st.Index.Evictions != 2 {
+		t.Errorf("expected a second eviction, got %+v", st)
+	}
+}
+
+func TestWorkspaceEditRefreshesDependents(t *testing.T) {
+	dir := writeWorkspace(t, map[string]string{
+		"order.twf":  "workflow Order():\n    activity Charge()\n",
+		"charge.twf": "activity Charge():\n    return\n",
+	})
+	store := NewDocumentStore()
+	store.Workspace.AddFolder(pathURI(dir))
+	order, charge := pathURI(filepath.Join(dir, "order.twf")), pathURI(filepath.Join(dir, "charge.twf"))
+	store.Open(order, 1, "workflow Order():\n    activity Charge()\n")
+
+	// Renaming the activity in the open buffer breaks the other document.
+	if !store.Workspace.Set(charge, "activity Bill():\n    return\n") {
+		t.Fatal("expected the edit to change the index")
+	}
+	store.Open(charge, 1, "activity Bill():\n    return\n")
+	analyses := store.Refresh(charge)
+	if len(analyses) != 1 {
+		t.Fatalf("expected order.twf to be analyzed again, got %d analyses", len(analyses))
+	}
+	if doc, ok := analyses[0].Wait(); !ok || doc.URI != order || len(doc.ResolveErrs) != 1 {
+		t.Errorf("expected Charge to be undefined in order.twf, got %v", doc.ResolveErrs)
+	}
+
+	// Closing the buffer unsaved goes back to the file on disk.
+	store.Close(charge)
+	if !store.Workspace.Reload(charge) {
+		t.Fatal("expected reloading to change the index")
+	}
+	if doc, ok := store.Refresh(charge)[0].Wait(); !ok || len(doc.ResolveErrs) != 0 {
+		t.Errorf("expected Charge to resolve again, got %v", doc.ResolveErrs)
+	}
+
+	store.Workspace.RemoveFolder(pathURI(dir))
+	if ext := store.Workspace.External(order); len(ext) != 0 {
+		t.Errorf("expected no external definitions without folders, got %d", len(ext))
+	}
+}
+
+func TestWorkspaceRename(t *testing.T) {
+	dir := writeWorkspace(t, map[string]string{
+		"order.twf":          "workflow Order():\n    activity Charge()\n    activity Ship()\n",
+		"billing/charge.twf": "activity Charge():\n    return\n",
+		"ship.twf":           "activity Ship():\n    return\n",
+	})
+	store := NewDocumentStore()
+	store.Workspace.AddFolder(pathURI(dir))
+	order := pathURI(filepath.Join(dir, "order.twf"))
+	store.Open(order, 1, "workflow Order():\n    activity Charge()\n    activity Ship()\n")
+
+	// Moving a folder keeps its definitions, under their new URIs.
+	oldDir, newDir := pathURI(filepath.Join(dir, "billing")), pathURI(filepath.Join(dir, "payments"))
+	if !store.Workspace.Rename(oldDir, newDir) {
+		t.Fatal("expected the folder rename to change the index")
+	}
+	analyses := store.Refresh(oldDir, newDir)
+	if len(analyses) != 1 {
+		t.Fatalf("expected order.twf to be analyzed again, got %d analyses", len(analyses))
+	}
+	doc, ok := analyses[0].Wait()
+	if !ok || len(doc.ResolveErrs) != 0 {
+		t.Fatalf("expected the moved definitions to resolve, got %v", doc.ResolveErrs)
+	}
+	call := doc.File.Definitions[0].(*ast.WorkflowDef).Body[0].(*ast.ActivityCall)
+	want := pathURI(filepath.Join(dir, "payments", "charge.twf"))
+	if got := nodeURI(resolvedTarget(call), doc.Symbols, order); got != want {
+		t.Errorf("Charge is defined in %s, want %s", got, want)
+	}
+
+	// Moving a file out of every folder drops it.
+	if !store.Workspace.Rename(pathURI(filepath.Join(dir, "ship.twf")), pathURI(filepath.Join(t.TempDir(), "ship.twf"))) {
+		t.Fatal("expected the file rename to change the index")
+	}
+	if ext := store.Workspace.External(order); len(ext) != 1 {
+		t.Errorf("expected only Charge to remain external, got %d definitions", len(ext))
+	}
+	if store.Workspace.Rename(pathURI(filepath.Join(dir, "missing.twf")), pathURI(filepath.Join(dir, "other.twf"))) {
+		t.Error("expected renaming an unindexed file to leave the index alone")
+	}
+}
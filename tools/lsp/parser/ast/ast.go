@@ -1,12 +1,18 @@
 package ast
 
+import (
+	"strconv"
+	"strings"
+)
+
 // Node is the base interface for all AST nodes.
 type Node interface {
 	NodeLine() int
 	NodeColumn() int
 }
 
-// Definition is a top-level definition (workflow, activity, worker, or namespace).
+// Definition is a top-level definition (workflow, activity, worker, namespace,
+// or constant).
 type Definition interface {
 	Node
 	defNode()
@@ -27,6 +33,19 @@ type Pos struct {
 func (p Pos) NodeLine() int   { return p.Line }
 func (p Pos) NodeColumn() int { return p.Column }
 
+// TextPos returns the position of byte i of text, a string from the source
+// that starts at start, such as a call's Args at its ArgsPos. Lines after
+// the first start at column 1, and like every column here a tab or any
+// other byte counts as one, so the result is exact whatever the spacing.
+func TextPos(text string, start Pos, i int) Pos {
+	i = max(0, min(i, len(text)))
+	nl := strings.LastIndexByte(text[:i], '\n')
+	if nl < 0 {
+		return Pos{Line: start.Line, Column: start.Column + i}
+	}
+	return Pos{Line: start.Line + strings.Count(text[:i], "\n"), Column: i - nl}
+}
+
 // Ref is a named reference to another AST node, resolved after parsing.
 type Ref[T any] struct {
 	Pos
@@ -45,9 +64,14 @@ type File struct {
 
 type WorkflowDef struct {
 	Pos
-	Name       string
-	Params     string // opaque content inside parens
-	ReturnType string // opaque, optional
+	Annotations []*Annotation // @name(args) lines above the definition
+	Name        string
+	Params      string // opaque content inside parens
+	ReturnType  string // opaque, optional
+	Description string // text of the description: block, dedented; empty when absent
+	// Options are the defaults for calls starting the workflow; options
+	// written at a call override them key by key.
+	Options    *OptionsBlock
 	State      *StateBlock
 	Signals    []*SignalDecl
 	Queries    []*QueryDecl
@@ -60,15 +84,91 @@ func (*WorkflowDef) defNode() {}
 
 type ActivityDef struct {
 	Pos
-	Name       string
-	Params     string
-	ReturnType string
+	Annotations []*Annotation // @name(args) lines above the definition
+	Name        string
+	Params      string
+	ReturnType  string
+	// Options are the defaults for calls of the activity; options written
+	// at a call override them key by key.
+	Options    *OptionsBlock
 	Body       []Statement
 	SourceFile string
 }
 
 func (*ActivityDef) defNode() {}
 
+// Annotation is metadata such as @owner("payments-team") or @sla(24h)
+// written above a workflow or activity definition. Names are free-form.
+type Annotation struct {
+	Pos
+	Name string
+	Args string // opaque content inside parens; empty when written without them
+}
+
+// Value returns the annotation's argument with the quotes of a single
+// string literal removed, so @tag(critical) and @tag("critical") both
+// have the value critical.
+func (a *Annotation) Value() string {
+	if v, err := strconv.Unquote(strings.TrimSpace(a.Args)); err == nil {
+		return v
+	}
+	return strings.TrimSpace(a.Args)
+}
+
+// Annotations returns the annotations of a workflow or activity definition,
+// or nil for other definitions.
+func Annotations(def Definition) []*Annotation {
+	switch d := def.(type) {
+	case *WorkflowDef:
+		return d.Annotations
+	case *ActivityDef:
+		return d.Annotations
+	}
+	return nil
+}
+
+// SourceFile returns the source file a definition is stamped with, or "".
+func SourceFile(def Definition) string {
+	switch d := def.(type) {
+	case *WorkflowDef:
+		return d.SourceFile
+	case *ActivityDef:
+		return d.SourceFile
+	case *WorkerDef:
+		return d.SourceFile
+	case *NamespaceDef:
+		return d.SourceFile
+	case *NexusServiceDef:
+		return d.SourceFile
+	case *ConstDef:
+		return d.SourceFile
+	case *EnumDef:
+		return d.SourceFile
+	}
+	return ""
+}
+
+// SetSourceFile stamps a definition with the source file it came from, for
+// definitions merged from several files.
+func SetSourceFile(def Definition, sourceFile string) {
+	switch d := def.(type) {
+	case *WorkflowDef:
+		d.SourceFile = sourceFile
+	case *ActivityDef:
+		d.SourceFile = sourceFile
+	case *WorkerDef:
+		d.SourceFile = sourceFile
+	case *NamespaceDef:
+		d.SourceFile = sourceFile
+	case *NexusServiceDef:
+		d.SourceFile = sourceFile
+	case *ConstDef:
+		d.SourceFile = sourceFile
+	case *EnumDef:
+		d.SourceFile = sourceFile
+	}
+}
+
 type WorkerDef struct {
 	Pos
 	Name       string
@@ -106,6 +206,38 @@ type NamespaceDef struct {
 
 func (*NamespaceDef) defNode() {}
 
+// ConstDef is a top-level named constant: const approvalTimeout = 7d.
+// Timer durations and scalar option values may name it instead of repeating
+// the literal; the resolver inlines the value at each use.
+type ConstDef struct {
+	Pos
+	Name       string
+	Value      string // literal text; strings are unquoted
+	ValueType  string // "string", "duration", "number", "bool"
+	ValueExpr  Expr   // the literal as an expression
+	SourceFile string
+}
+
+func (*ConstDef) defNode() {}
+
+// EnumDef is a top-level enumeration: enum OrderType: invoice, refund.
+// A parameter or state variable typed with its name holds one of its values,
+// and a switch on such a name must cover every value or have an else.
+type EnumDef struct {
+	Pos
+	Name       string
+	Values     []*EnumValue
+	SourceFile string
+}
+
+func (*EnumDef) defNode() {}
+
+// EnumValue is one value of an enum declaration.
+type EnumValue struct {
+	Pos
+	Name string
+}
+
 // ---------------------------------------------------------------------------
 // Workflow-level declarations (embedded in WorkflowDef)
 // ---------------------------------------------------------------------------
@@ -147,6 +279,8 @@ type ActivityCall struct {
 	Pos
 	Activity Ref[*ActivityDef]
 	Args     string
+	ArgsPos  Pos    // where Args starts, just inside the '('
+	ArgExprs []Expr // structured Args; nil when Args is not a plain expression list
 	Result   string // optional
 	Options  *OptionsBlock
 }
@@ -166,6 +300,9 @@ type WorkflowCall struct {
 	Mode     WorkflowCallMode
 	Workflow Ref[*WorkflowDef]
 	Args     string
+	ArgsPos  Pos    // where Args starts, just inside the '('
+	ArgExprs []Expr // structured Args; nil when Args is not a plain expression list
+	ID       string // workflow ID template after "id", unquoted, with {expr} placeholders; optional
 	Result   string // optional
 	Options  *OptionsBlock
 }
@@ -206,6 +343,8 @@ func AsyncTargetKind(t AsyncTarget) string {
 
 type TimerTarget struct {
 	Duration string
+	Const    Ref[*ConstDef] // set when Duration names a constant; the resolver inlines its value into Duration
+	Result   string         // name bound to the time the timer fired; await one cases only
 }
 
 func (*TimerTarget) asyncTarget() {}
@@ -227,6 +366,8 @@ func (*UpdateTarget) asyncTarget() {}
 type ActivityTarget struct {
 	Activity Ref[*ActivityDef]
 	Args     string
+	ArgsPos  Pos
+	ArgExprs []Expr
 	Result   string
 }
 
@@ -236,6 +377,8 @@ type WorkflowTarget struct {
 	Workflow Ref[*WorkflowDef]
 	Mode     WorkflowCallMode
 	Args     string
+	ArgsPos  Pos
+	ArgExprs []Expr
 	Result   string
 }
 
@@ -246,6 +389,8 @@ type NexusTarget struct {
 	Service   Ref[*NexusServiceDef]
 	Operation Ref[*NexusOperation]
 	Args      string
+	ArgsPos   Pos
+	ArgExprs  []Expr
 	Result    string
 	Detach    bool
 }
@@ -261,7 +406,7 @@ type IdentResolution struct {
 
 type IdentTarget struct {
 	Name     string
-	Result   string
+	Result   string // the promise's result, or what made the condition true in an await one case
 	Resolved IdentResolution
 }
 
@@ -278,18 +423,46 @@ func (*AwaitStmt) stmtNode() {}
 // AwaitAllBlock represents an "await all:" block that waits for all operations to complete.
 type AwaitAllBlock struct {
 	Pos
-	Body []Statement
+	Options *AwaitAllOptions // nil without an options clause
+	Body    []Statement
 }
 
 func (*AwaitAllBlock) stmtNode() {}
 
+// AwaitAllOptions is the partial-failure policy of an await all block, as in
+// "await all options(onError: continue, minSuccess: 2):".
+type AwaitAllOptions struct {
+	Pos
+	OnError    string // AwaitAllFail (the default) or AwaitAllContinue
+	MinSuccess int    // operations that must succeed despite failures; 0 when unset
+}
+
+// Values of AwaitAllOptions.OnError.
+const (
+	// AwaitAllFail fails the block as soon as one operation fails.
+	AwaitAllFail = "fail"
+	// AwaitAllContinue waits for every operation whatever their outcome.
+	AwaitAllContinue = "continue"
+)
+
+// String renders the options as written, keeping only the keys that were set.
+func (o *AwaitAllOptions) String() string {
+	parts := []string{"onError: " + o.OnError}
+	if o.MinSuccess > 0 {
+		parts = append(parts, "minSuccess: "+strconv.Itoa(o.MinSuccess))
+	}
+	return "options(" + strings.Join(parts, ", ") + ")"
+}
+
 // AwaitOneCase represents a single case in an "await one:" block.
 // Can be signal, update, timer, activity, workflow, nexus, ident, or nested await all.
 type AwaitOneCase struct {
 	Pos
-	Target   AsyncTarget    // nil when AwaitAll is set
-	AwaitAll *AwaitAllBlock // nil when Target is set
-	Body     []Statement
+	Target    AsyncTarget    // nil when AwaitAll is set
+	AwaitAll  *AwaitAllBlock // nil when Target is set
+	Guard     string         // opaque condition after "if"; empty when unguarded
+	GuardExpr Expr           // parsed Guard; nil when unguarded or free-form
+	Body      []Statement
 }
 
 func (*AwaitOneCase) stmtNode() {}
@@ -305,17 +478,19 @@ func (*AwaitOneBlock) stmtNode() {}
 // SwitchCase represents a single case in a switch block.
 type SwitchCase struct {
 	Pos
-	Value string // opaque expression after "case"
-	Body  []Statement
+	Value     string // opaque expression after "case"
+	ValueExpr Expr   // parsed Value; nil when free-form
+	Body      []Statement
 }
 
 func (*SwitchCase) stmtNode() {}
 
 type SwitchBlock struct {
 	Pos
-	Expr    string // opaque, paren-delimited
-	Cases   []*SwitchCase
-	Default []Statement // optional else block
+	Expr        string // opaque, paren-delimited
+	SubjectExpr Expr   // parsed Expr; nil when free-form
+	Cases       []*SwitchCase
+	Default     []Statement // optional else block
 }
 
 func (*SwitchBlock) stmtNode() {}
@@ -323,8 +498,10 @@ func (*SwitchBlock) stmtNode() {}
 type IfStmt struct {
 	Pos
 	Condition string // opaque, paren-delimited
+	CondExpr  Expr   // structured Condition; nil when not a plain expression
 	Body      []Statement
 	ElseBody  []Statement // optional
+	ElseIf    bool        // ElseBody is a single IfStmt written as elif / else if
 }
 
 func (*IfStmt) stmtNode() {}
@@ -336,19 +513,29 @@ const (
 	ForInfinite    ForVariant = iota // for:
 	ForConditional                   // for (condition):
 	ForIteration                     // for (var in collection):
+	ForParallel                      // for each (var in collection) parallel(max: N):
 )
 
 type ForStmt struct {
 	Pos
-	Variant   ForVariant
-	Condition string // for conditional loops
-	Variable  string // for iteration loops
-	Iterable  string // for iteration loops
-	Body      []Statement
+	Label       *LoopLabel // optional; nil when the loop is unlabeled
+	Variant     ForVariant
+	Condition   string // for conditional loops
+	CondExpr    Expr   // structured Condition; nil when not a plain expression
+	Variable    string // for iteration and parallel loops
+	Iterable    string // for iteration and parallel loops
+	Concurrency string // for parallel loops: the max: value as written
+	Body        []Statement
 }
 
 func (*ForStmt) stmtNode() {}
 
+// LoopLabel names a loop (outer: for ...) so break and continue can target it.
+type LoopLabel struct {
+	Pos
+	Name string
+}
+
 type ReturnStmt struct {
 	Pos
 	Value string // opaque, optional
@@ -367,20 +554,24 @@ const (
 
 type CloseStmt struct {
 	Pos
-	Reason CloseReason
-	Args   string // opaque, optional (parenthesized args)
+	Reason   CloseReason
+	Args     string // opaque, optional (parenthesized args)
+	ArgsPos  Pos    // where Args starts, just inside the '('; zero when there are none
+	ArgExprs []Expr // structured Args; nil when Args is not a plain expression list
 }
 
 func (*CloseStmt) stmtNode() {}
 
 type BreakStmt struct {
 	Pos
+	Label Ref[*ForStmt] // optional target loop; Name is empty when unlabeled
 }
 
 func (*BreakStmt) stmtNode() {}
 
 type ContinueStmt struct {
 	Pos
+	Label Ref[*ForStmt] // optional target loop; Name is empty when unlabeled
 }
 
 func (*ContinueStmt) stmtNode() {}
@@ -456,12 +647,12 @@ const (
 // NexusOperation is an operation inside a nexus service definition.
 type NexusOperation struct {
 	Pos
-	OpType       NexusOperationType
-	Name         string
-	Workflow      Ref[*WorkflowDef] // async only: backing workflow
-	Params       string      // sync only
-	ReturnType   string      // sync only
-	Body         []Statement // sync only
+	OpType     NexusOperationType
+	Name       string
+	Workflow   Ref[*WorkflowDef] // async only: backing workflow
+	Params     string            // sync only
+	ReturnType string            // sync only
+	Body       []Statement       // sync only
 }
 
 // NexusServiceDef is a top-level nexus service definition.
@@ -482,6 +673,8 @@ type NexusCall struct {
 	Service   Ref[*NexusServiceDef]
 	Operation Ref[*NexusOperation]
 	Args      string
+	ArgsPos   Pos    // where Args starts, just inside the '('
+	ArgExprs  []Expr // structured Args; nil when Args is not a plain expression list
 	Result    string // optional
 	Options   *OptionsBlock
 }
@@ -496,6 +689,16 @@ func (*NexusCall) stmtNode() {}
 type OptionsBlock struct {
 	Pos
 	Entries []*OptionEntry
+	// RawText is the source of a block whose entries failed to parse, with
+	// its indentation removed; Entries is then empty. Only ParseFileAll
+	// keeps such blocks.
+	RawText string
+}
+
+// Unparsed reports whether ob is a block whose entries failed to parse, so
+// what it sets is unknown.
+func (ob *OptionsBlock) Unparsed() bool {
+	return ob != nil && ob.RawText != ""
 }
 
 // OptionEntry represents a single key-value pair or nested block inside options.
@@ -503,6 +706,8 @@ type OptionEntry struct {
 	Pos
 	Key       string
 	Value     string         // literal for flat entries
-	ValueType string         // "string", "duration", "number", "bool", "enum"
+	ValueType string         // "string", "duration", "number", "bool", "enum", "list", "map"
+	Expr      Expr           // structured value for "list" and "map" entries
+	Const     Ref[*ConstDef] // set when a scalar value names a constant; the resolver inlines its value into Value
 	Nested    []*OptionEntry // non-nil for nested blocks (e.g. retry_policy)
 }
@@ -0,0 +1,196 @@
+package ast
+
+import "strings"
+
+// Expr is a structured expression parsed from call arguments or option values.
+// Expressions are best-effort: content that does not parse as an expression
+// stays available only as the opaque source string on the owning node.
+type Expr interface {
+	Node
+	exprNode()
+}
+
+// Ident is a bare identifier reference (e.g. order).
+type Ident struct {
+	Pos
+	Name string
+}
+
+func (*Ident) exprNode() {}
+
+// SelectorExpr is a field access (e.g. order.items).
+type SelectorExpr struct {
+	Pos
+	X   Expr
+	Sel string
+}
+
+func (*SelectorExpr) exprNode() {}
+
+// StringLit is a quoted string literal. Value excludes the quotes.
+type StringLit struct {
+	Pos
+	Value string
+}
+
+func (*StringLit) exprNode() {}
+
+// NumberLit is a numeric literal, kept in source form (e.g. 2.5).
+type NumberLit struct {
+	Pos
+	Value string
+}
+
+func (*NumberLit) exprNode() {}
+
+// DurationLit is a duration literal, kept in source form (e.g. 30s).
+type DurationLit struct {
+	Pos
+	Value string
+}
+
+func (*DurationLit) exprNode() {}
+
+// BoolLit is a true or false literal.
+type BoolLit struct {
+	Pos
+	Value bool
+}
+
+func (*BoolLit) exprNode() {}
+
+// ListLit is a bracketed list literal (e.g. [a, b, c]).
+type ListLit struct {
+	Pos
+	Elems []Expr
+}
+
+func (*ListLit) exprNode() {}
+
+// MapLit is a braced map literal (e.g. {key: value}). A typed literal names
+// the record type it constructs before the brace (e.g. OrderResult{id: id}).
+type MapLit struct {
+	Pos
+	Type    string // record type of a typed literal; empty for a plain map
+	Entries []*MapEntry
+}
+
+func (*MapLit) exprNode() {}
+
+// BinaryExpr is a binary operation (e.g. retries < 3). Op is kept as
+// written, so logical operators may be spelled "and" or "&&".
+type BinaryExpr struct {
+	Pos
+	Op string
+	X  Expr
+	Y  Expr
+}
+
+func (*BinaryExpr) exprNode() {}
+
+// UnaryExpr is a prefix operation (e.g. not approved, -1).
+type UnaryExpr struct {
+	Pos
+	Op string
+	X  Expr
+}
+
+func (*UnaryExpr) exprNode() {}
+
+// MapEntry is a single key: value pair in a MapLit. Keys are identifiers or
+// quoted strings; KeyPos locates the key for highlighting and diagnostics.
+type MapEntry struct {
+	KeyPos Pos
+	Key    string
+	Value  Expr
+}
+
+// ExprString renders an expression back to canonical TWF source.
+func ExprString(x Expr) string {
+	var b strings.Builder
+	writeExpr(&b, x)
+	return b.String()
+}
+
+func writeExpr(b *strings.Builder, x Expr) {
+	switch x := x.(type) {
+	case *Ident:
+		b.WriteString(x.Name)
+	case *SelectorExpr:
+		writeExpr(b, x.X)
+		b.WriteByte('.')
+		b.WriteString(x.Sel)
+	case *StringLit:
+		b.WriteString(quoteString(x.Value))
+	case *NumberLit:
+		b.WriteString(x.Value)
+	case *DurationLit:
+		b.WriteString(x.Value)
+	case *BoolLit:
+		if x.Value {
+			b.WriteString("true")
+		} else {
+			b.WriteString("false")
+		}
+	case *ListLit:
+		b.WriteByte('[')
+		for i, e := range x.Elems {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			writeExpr(b, e)
+		}
+		b.WriteByte(']')
+	case *MapLit:
+		b.WriteString(x.Type)
+		b.WriteByte('{')
+		for i, e := range x.Entries {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			if isIdentName(e.Key) {
+				b.WriteString(e.Key)
+			} else {
+				b.WriteString(quoteString(e.Key))
+			}
+			b.WriteString(": ")
+			writeExpr(b, e.Value)
+		}
+		b.WriteByte('}')
+	case *BinaryExpr:
+		writeExpr(b, x.X)
+		b.WriteByte(' ')
+		b.WriteString(x.Op)
+		b.WriteByte(' ')
+		writeExpr(b, x.Y)
+	case *UnaryExpr:
+		b.WriteString(x.Op)
+		if x.Op == "not" {
+			b.WriteByte(' ')
+		}
+		writeExpr(b, x.X)
+	}
+}
+
+// quoteString quotes s as a TWF string literal. Strings that cannot be
+// written with single quotes (embedded quotes or newlines) use triple quotes.
+func quoteString(s string) string {
+	if strings.ContainsAny(s, "\"\n") {
+		return `"""` + s + `"""`
+	}
+	return `"` + s + `"`
+}
+
+// isIdentName reports whether s is a valid TWF identifier.
+func isIdentName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		isLetter := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_'
+		if !isLetter && (i == 0 || c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,125 @@
+package ast
+
+// Expression JSON types. Each expression marshals as an object with a
+// "kind" discriminator: ident, selector, string, number, duration, bool,
+// list, map, binary, or unary.
+
+type identExprJSON struct {
+	Kind   string `json:"kind"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Name   string `json:"name"`
+}
+
+type selectorExprJSON struct {
+	Kind   string `json:"kind"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	X      any    `json:"x"`
+	Sel    string `json:"sel"`
+}
+
+// literalExprJSON covers string, number, and duration literals, whose values
+// are kept in source form.
+type literalExprJSON struct {
+	Kind   string `json:"kind"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Value  string `json:"value"`
+}
+
+type boolExprJSON struct {
+	Kind   string `json:"kind"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Value  bool   `json:"value"`
+}
+
+type listExprJSON struct {
+	Kind   string `json:"kind"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Elems  []any  `json:"elems"`
+}
+
+type mapExprJSON struct {
+	Kind    string         `json:"kind"`
+	Line    int            `json:"line"`
+	Column  int            `json:"column"`
+	Type    string         `json:"type,omitempty"` // record type of a typed literal
+	Entries []mapEntryJSON `json:"entries"`
+}
+
+type binaryExprJSON struct {
+	Kind   string `json:"kind"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Op     string `json:"op"`
+	X      any    `json:"x"`
+	Y      any    `json:"y"`
+}
+
+type unaryExprJSON struct {
+	Kind   string `json:"kind"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Op     string `json:"op"`
+	X      any    `json:"x"`
+}
+
+type mapEntryJSON struct {
+	Key    string `json:"key"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Value  any    `json:"value"`
+}
+
+// marshalExpr converts an expression to its JSON representation.
+func marshalExpr(x Expr) any {
+	switch x := x.(type) {
+	case *Ident:
+		return identExprJSON{Kind: "ident", Line: x.Line, Column: x.Column, Name: x.Name}
+	case *SelectorExpr:
+		return selectorExprJSON{Kind: "selector", Line: x.Line, Column: x.Column, X: marshalExpr(x.X), Sel: x.Sel}
+	case *StringLit:
+		return literalExprJSON{Kind: "string", Line: x.Line, Column: x.Column, Value: x.Value}
+	case *NumberLit:
+		return literalExprJSON{Kind: "number", Line: x.Line, Column: x.Column, Value: x.Value}
+	case *DurationLit:
+		return literalExprJSON{Kind: "duration", Line: x.Line, Column: x.Column, Value: x.Value}
+	case *BoolLit:
+		return boolExprJSON{Kind: "bool", Line: x.Line, Column: x.Column, Value: x.Value}
+	case *ListLit:
+		return listExprJSON{Kind: "list", Line: x.Line, Column: x.Column, Elems: marshalExprs(x.Elems)}
+	case *MapLit:
+		entries := make([]mapEntryJSON, 0, len(x.Entries))
+		for _, e := range x.Entries {
+			entries = append(entries, mapEntryJSON{Key: e.Key, Line: e.KeyPos.Line, Column: e.KeyPos.Column, Value: marshalExpr(e.Value)})
+		}
+		return mapExprJSON{Kind: "map", Line: x.Line, Column: x.Column, Type: x.Type, Entries: entries}
+	case *BinaryExpr:
+		return binaryExprJSON{Kind: "binary", Line: x.Line, Column: x.Column, Op: x.Op, X: marshalExpr(x.X), Y: marshalExpr(x.Y)}
+	case *UnaryExpr:
+		return unaryExprJSON{Kind: "unary", Line: x.Line, Column: x.Column, Op: x.Op, X: marshalExpr(x.X)}
+	default:
+		return nil
+	}
+}
+
+// marshalExprs converts an expression list, always returning a non-nil slice.
+func marshalExprs(xs []Expr) []any {
+	result := make([]any, 0, len(xs))
+	for _, x := range xs {
+		result = append(result, marshalExpr(x))
+	}
+	return result
+}
+
+// marshalArgExprs converts structured call arguments, returning nil (omitted)
+// when the arguments were not parsed into expressions.
+func marshalArgExprs(xs []Expr) []any {
+	if xs == nil {
+		return nil
+	}
+	return marshalExprs(xs)
+}
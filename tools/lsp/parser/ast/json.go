@@ -22,18 +22,22 @@ type FileSummary struct {
 	Workflows     int `json:"workflows"`
 	Activities    int `json:"activities"`
 	NexusServices int `json:"nexusServices"`
+	Constants     int `json:"constants,omitempty"`
+	Enums         int `json:"enums,omitempty"`
 }
 
 // FileJSON is the JSON-serializable representation of a File.
 type FileJSON struct {
-	Summary     FileSummary       `json:"summary"`
-	Definitions []json.RawMessage `json:"definitions"`
+	SchemaVersion int               `json:"schemaVersion"`
+	Summary       FileSummary       `json:"summary"`
+	Definitions   []json.RawMessage `json:"definitions"`
 }
 
 // MarshalJSON implements json.Marshaler for File.
 func (f *File) MarshalJSON() ([]byte, error) {
 	fj := FileJSON{
-		Definitions: make([]json.RawMessage, 0, len(f.Definitions)),
+		SchemaVersion: SchemaVersion,
+		Definitions:   make([]json.RawMessage, 0, len(f.Definitions)),
 	}
 	for _, def := range f.Definitions {
 		switch def.(type) {
@@ -47,6 +51,10 @@ func (f *File) MarshalJSON() ([]byte, error) {
 			fj.Summary.Namespaces++
 		case *NexusServiceDef:
 			fj.Summary.NexusServices++
+		case *ConstDef:
+			fj.Summary.Constants++
+		case *EnumDef:
+			fj.Summary.Enums++
 		}
 		data, err := marshalDefinition(def)
 		if err != nil {
@@ -59,9 +67,6 @@ func (f *File) MarshalJSON() ([]byte, error) {
 
 // marshalStatements marshals a slice of statements into JSON.
 func marshalStatements(stmts []Statement) ([]json.RawMessage, error) {
-	if len(stmts) == 0 {
-		return nil, nil
-	}
 	out := make([]json.RawMessage, 0, len(stmts))
 	for _, stmt := range stmts {
 		data, err := marshalStatement(stmt)
@@ -145,6 +150,10 @@ func marshalDefinition(def Definition) (json.RawMessage, error) {
 		return json.Marshal(d)
 	case *NexusServiceDef:
 		return json.Marshal(d)
+	case *ConstDef:
+		return json.Marshal(d)
+	case *EnumDef:
+		return json.Marshal(d)
 	default:
 		return nil, fmt.Errorf("marshalDefinition: unhandled definition type %T", def)
 	}
@@ -153,6 +162,7 @@ func marshalDefinition(def Definition) (json.RawMessage, error) {
 // OptionsBlockJSON is the JSON representation of an options block.
 type OptionsBlockJSON struct {
 	Entries []OptionEntryJSON `json:"entries"`
+	RawText string            `json:"rawText,omitempty"`
 }
 
 // OptionEntryJSON is the JSON representation of a single option entry.
@@ -160,6 +170,8 @@ type OptionEntryJSON struct {
 	Key       string            `json:"key"`
 	Value     string            `json:"value,omitempty"`
 	ValueType string            `json:"valueType,omitempty"`
+	Expr      any               `json:"expr,omitempty"`
+	Const     string            `json:"const,omitempty"`
 	Nested    []OptionEntryJSON `json:"nested,omitempty"`
 }
 
@@ -169,6 +181,7 @@ func marshalOptionsBlock(ob *OptionsBlock) *OptionsBlockJSON {
 	}
 	obj := &OptionsBlockJSON{
 		Entries: marshalOptionEntries(ob.Entries),
+		RawText: ob.RawText,
 	}
 	return obj
 }
@@ -181,6 +194,10 @@ func marshalOptionEntries(entries []*OptionEntry) []OptionEntryJSON {
 			Value:     e.Value,
 			ValueType: e.ValueType,
 		}
+		if e.Expr != nil {
+			ej.Expr = marshalExpr(e.Expr)
+		}
+		ej.Const = e.Const.Name
 		if len(e.Nested) > 0 {
 			ej.Nested = marshalOptionEntries(e.Nested)
 		}
@@ -191,24 +208,44 @@ func marshalOptionEntries(entries []*OptionEntry) []OptionEntryJSON {
 
 // WorkflowDefJSON is the JSON representation of WorkflowDef.
 type WorkflowDefJSON struct {
-	Type       string             `json:"type"`
-	Line       int                `json:"line"`
-	Column     int                `json:"column"`
-	SourceFile string             `json:"sourceFile,omitempty"`
-	Name       string             `json:"name"`
-	Params     string             `json:"params"`
-	ReturnType string             `json:"returnType,omitempty"`
-	State      *StateBlockJSON    `json:"state,omitempty"`
-	Signals    []*SignalDeclJSON  `json:"signals"`
-	Queries    []*QueryDeclJSON   `json:"queries"`
-	Updates    []*UpdateDeclJSON  `json:"updates"`
-	Body       []json.RawMessage  `json:"body"`
+	Type        string            `json:"type"`
+	Line        int               `json:"line"`
+	Column      int               `json:"column"`
+	SourceFile  string            `json:"sourceFile,omitempty"`
+	Annotations []AnnotationJSON  `json:"annotations,omitempty"`
+	Name        string            `json:"name"`
+	Params      string            `json:"params"`
+	ReturnType  string            `json:"returnType,omitempty"`
+	Returns     []string          `json:"returns,omitempty"` // ReturnType split into its types
+	Description string            `json:"description,omitempty"`
+	Options     *OptionsBlockJSON `json:"options,omitempty"`
+	State       *StateBlockJSON   `json:"state,omitempty"`
+	Signals     []*SignalDeclJSON `json:"signals"`
+	Queries     []*QueryDeclJSON  `json:"queries"`
+	Updates     []*UpdateDeclJSON `json:"updates"`
+	Body        []json.RawMessage `json:"body"`
+}
+
+// AnnotationJSON is the JSON representation of an annotation.
+type AnnotationJSON struct {
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Name   string `json:"name"`
+	Args   string `json:"args,omitempty"`
+}
+
+func marshalAnnotations(anns []*Annotation) []AnnotationJSON {
+	var out []AnnotationJSON
+	for _, a := range anns {
+		out = append(out, AnnotationJSON{Line: a.Line, Column: a.Column, Name: a.Name, Args: a.Args})
+	}
+	return out
 }
 
 // StateBlockJSON is the JSON representation of a state: block.
 type StateBlockJSON struct {
-	Conditions []*ConditionDeclJSON `json:"conditions,omitempty"`
-	RawStmts   []rawStmtJSON        `json:"rawStmts,omitempty"`
+	Conditions []*ConditionDeclJSON `json:"conditions"`
+	RawStmts   []rawStmtJSON        `json:"rawStmts"`
 }
 
 // ConditionDeclJSON is the JSON representation of a condition declaration.
@@ -220,16 +257,20 @@ type ConditionDeclJSON struct {
 
 func (w *WorkflowDef) MarshalJSON() ([]byte, error) {
 	wj := WorkflowDefJSON{
-		Type:       "workflowDef",
-		Line:       w.Line,
-		Column:     w.Column,
-		SourceFile: w.SourceFile,
-		Name:       w.Name,
-		Params:     w.Params,
-		ReturnType: w.ReturnType,
+		Type:        "workflowDef",
+		Line:        w.Line,
+		Column:      w.Column,
+		SourceFile:  w.SourceFile,
+		Annotations: marshalAnnotations(w.Annotations),
+		Name:        w.Name,
+		Params:      w.Params,
+		ReturnType:  w.ReturnType,
+		Returns:     SplitList(w.ReturnType),
+		Description: w.Description,
+		Options:     marshalOptionsBlock(w.Options),
 	}
 	if w.State != nil {
-		sj := &StateBlockJSON{}
+		sj := &StateBlockJSON{Conditions: []*ConditionDeclJSON{}, RawStmts: []rawStmtJSON{}}
 		for _, c := range w.State.Conditions {
 			sj.Conditions = append(sj.Conditions, &ConditionDeclJSON{Line: c.Line, Column: c.Column, Name: c.Name})
 		}
@@ -261,25 +302,31 @@ func (w *WorkflowDef) MarshalJSON() ([]byte, error) {
 
 // ActivityDefJSON is the JSON representation of ActivityDef.
 type ActivityDefJSON struct {
-	Type       string            `json:"type"`
-	Line       int               `json:"line"`
-	Column     int               `json:"column"`
-	SourceFile string            `json:"sourceFile,omitempty"`
-	Name       string            `json:"name"`
-	Params     string            `json:"params"`
-	ReturnType string            `json:"returnType,omitempty"`
-	Body       []json.RawMessage `json:"body"`
+	Type        string            `json:"type"`
+	Line        int               `json:"line"`
+	Column      int               `json:"column"`
+	SourceFile  string            `json:"sourceFile,omitempty"`
+	Annotations []AnnotationJSON  `json:"annotations,omitempty"`
+	Name        string            `json:"name"`
+	Params      string            `json:"params"`
+	ReturnType  string            `json:"returnType,omitempty"`
+	Returns     []string          `json:"returns,omitempty"` // ReturnType split into its types
+	Options     *OptionsBlockJSON `json:"options,omitempty"`
+	Body        []json.RawMessage `json:"body"`
 }
 
 func (a *ActivityDef) MarshalJSON() ([]byte, error) {
 	aj := ActivityDefJSON{
-		Type:       "activityDef",
-		Line:       a.Line,
-		Column:     a.Column,
-		SourceFile: a.SourceFile,
-		Name:       a.Name,
-		Params:     a.Params,
-		ReturnType: a.ReturnType,
+		Type:        "activityDef",
+		Line:        a.Line,
+		Column:      a.Column,
+		SourceFile:  a.SourceFile,
+		Annotations: marshalAnnotations(a.Annotations),
+		Name:        a.Name,
+		Params:      a.Params,
+		ReturnType:  a.ReturnType,
+		Returns:     SplitList(a.ReturnType),
+		Options:     marshalOptionsBlock(a.Options),
 	}
 	var err error
 	if aj.Body, err = marshalStatements(a.Body); err != nil {
@@ -298,9 +345,6 @@ type WorkerRefJSON struct {
 
 // marshalWorkerRefs converts a slice of Ref[T] to JSON form.
 func marshalWorkerRefs[T interface{ comparable; Node }](refs []Ref[T]) []WorkerRefJSON {
-	if len(refs) == 0 {
-		return nil
-	}
 	out := make([]WorkerRefJSON, 0, len(refs))
 	for _, ref := range refs {
 		rj := WorkerRefJSON{
@@ -328,9 +372,9 @@ type WorkerDefJSON struct {
 	Column     int             `json:"column"`
 	SourceFile string          `json:"sourceFile,omitempty"`
 	Name       string          `json:"name"`
-	Workflows  []WorkerRefJSON `json:"workflows,omitempty"`
-	Activities []WorkerRefJSON `json:"activities,omitempty"`
-	Services   []WorkerRefJSON `json:"services,omitempty"`
+	Workflows  []WorkerRefJSON `json:"workflows"`
+	Activities []WorkerRefJSON `json:"activities"`
+	Services   []WorkerRefJSON `json:"services"`
 }
 
 func (w *WorkerDef) MarshalJSON() ([]byte, error) {
@@ -347,6 +391,66 @@ func (w *WorkerDef) MarshalJSON() ([]byte, error) {
 	return json.Marshal(wj)
 }
 
+// ConstDefJSON is the JSON representation of ConstDef.
+type ConstDefJSON struct {
+	Type       string `json:"type"`
+	Line       int    `json:"line"`
+	Column     int    `json:"column"`
+	SourceFile string `json:"sourceFile,omitempty"`
+	Name       string `json:"name"`
+	Value      string `json:"value"`
+	ValueType  string `json:"valueType"`
+	ValueExpr  any    `json:"valueExpr,omitempty"`
+}
+
+func (c *ConstDef) MarshalJSON() ([]byte, error) {
+	cj := ConstDefJSON{
+		Type:       "constDef",
+		Line:       c.Line,
+		Column:     c.Column,
+		SourceFile: c.SourceFile,
+		Name:       c.Name,
+		Value:      c.Value,
+		ValueType:  c.ValueType,
+	}
+	if c.ValueExpr != nil {
+		cj.ValueExpr = marshalExpr(c.ValueExpr)
+	}
+	return json.Marshal(cj)
+}
+
+// EnumDefJSON is the JSON representation of EnumDef.
+type EnumDefJSON struct {
+	Type       string          `json:"type"`
+	Line       int             `json:"line"`
+	Column     int             `json:"column"`
+	SourceFile string          `json:"sourceFile,omitempty"`
+	Name       string          `json:"name"`
+	Values     []EnumValueJSON `json:"values"`
+}
+
+// EnumValueJSON is the JSON representation of one value of an enum.
+type EnumValueJSON struct {
+	Name   string `json:"name"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+func (e *EnumDef) MarshalJSON() ([]byte, error) {
+	ej := EnumDefJSON{
+		Type:       "enumDef",
+		Line:       e.Line,
+		Column:     e.Column,
+		SourceFile: e.SourceFile,
+		Name:       e.Name,
+		Values:     make([]EnumValueJSON, len(e.Values)),
+	}
+	for i, v := range e.Values {
+		ej.Values[i] = EnumValueJSON{Name: v.Name, Line: v.Line, Column: v.Column}
+	}
+	return json.Marshal(ej)
+}
+
 // NamespaceWorkerJSON is the JSON representation of a worker instantiation in a namespace.
 type NamespaceWorkerJSON struct {
 	WorkerName     string            `json:"workerName"`
@@ -371,8 +475,8 @@ type NamespaceDefJSON struct {
 	Column     int                     `json:"column"`
 	SourceFile string                  `json:"sourceFile,omitempty"`
 	Name       string                  `json:"name"`
-	Workers    []NamespaceWorkerJSON   `json:"workers,omitempty"`
-	Endpoints  []NamespaceEndpointJSON `json:"endpoints,omitempty"`
+	Workers    []NamespaceWorkerJSON   `json:"workers"`
+	Endpoints  []NamespaceEndpointJSON `json:"endpoints"`
 }
 
 func (n *NamespaceDef) MarshalJSON() ([]byte, error) {
@@ -382,6 +486,8 @@ func (n *NamespaceDef) MarshalJSON() ([]byte, error) {
 		Column:     n.Column,
 		SourceFile: n.SourceFile,
 		Name:       n.Name,
+		Workers:    make([]NamespaceWorkerJSON, 0, len(n.Workers)),
+		Endpoints:  make([]NamespaceEndpointJSON, 0, len(n.Endpoints)),
 	}
 	for _, w := range n.Workers {
 		wj := NamespaceWorkerJSON{
@@ -413,7 +519,7 @@ type SignalDeclJSON struct {
 	Column int               `json:"column"`
 	Name   string            `json:"name"`
 	Params string            `json:"params"`
-	Body   []json.RawMessage `json:"body,omitempty"`
+	Body   []json.RawMessage `json:"body"`
 }
 
 type QueryDeclJSON struct {
@@ -423,7 +529,7 @@ type QueryDeclJSON struct {
 	Name       string            `json:"name"`
 	Params     string            `json:"params"`
 	ReturnType string            `json:"returnType,omitempty"`
-	Body       []json.RawMessage `json:"body,omitempty"`
+	Body       []json.RawMessage `json:"body"`
 }
 
 type UpdateDeclJSON struct {
@@ -433,7 +539,7 @@ type UpdateDeclJSON struct {
 	Name       string            `json:"name"`
 	Params     string            `json:"params"`
 	ReturnType string            `json:"returnType,omitempty"`
-	Body       []json.RawMessage `json:"body,omitempty"`
+	Body       []json.RawMessage `json:"body"`
 }
 
 // marshalStatement marshals a Statement with type discrimination.
@@ -482,13 +588,15 @@ func marshalStatement(stmt Statement) (json.RawMessage, error) {
 
 func marshalActivityCall(s *ActivityCall) (json.RawMessage, error) {
 	aj := activityCallJSON{
-		Type:    "activityCall",
-		Line:    s.Line,
-		Column:  s.Column,
-		Name:    s.Activity.Name,
-		Args:    s.Args,
-		Result:  s.Result,
-		Options: marshalOptionsBlock(s.Options),
+		Type:     "activityCall",
+		Line:     s.Line,
+		Column:   s.Column,
+		Name:     s.Activity.Name,
+		Args:     s.Args,
+		ArgExprs: marshalArgExprs(s.ArgExprs),
+		Result:   s.Result,
+		Results:  SplitList(s.Result),
+		Options:  marshalOptionsBlock(s.Options),
 	}
 	if s.Activity.Resolved != nil {
 		aj.Resolved = &resolvedRefJSON{
@@ -502,14 +610,17 @@ func marshalActivityCall(s *ActivityCall) (json.RawMessage, error) {
 
 func marshalWorkflowCall(s *WorkflowCall) (json.RawMessage, error) {
 	wj := workflowCallJSON{
-		Type:    "workflowCall",
-		Line:    s.Line,
-		Column:  s.Column,
-		Mode:    workflowCallModeString(s.Mode),
-		Name:    s.Workflow.Name,
-		Args:    s.Args,
-		Result:  s.Result,
-		Options: marshalOptionsBlock(s.Options),
+		Type:     "workflowCall",
+		Line:     s.Line,
+		Column:   s.Column,
+		Mode:     workflowCallModeString(s.Mode),
+		Name:     s.Workflow.Name,
+		Args:     s.Args,
+		ArgExprs: marshalArgExprs(s.ArgExprs),
+		ID:       s.ID,
+		Result:   s.Result,
+		Results:  SplitList(s.Result),
+		Options:  marshalOptionsBlock(s.Options),
 	}
 	if s.Workflow.Resolved != nil {
 		wj.Resolved = &resolvedRefJSON{
@@ -531,7 +642,9 @@ func marshalNexusCall(s *NexusCall) (json.RawMessage, error) {
 		Service:   s.Service.Name,
 		Operation: s.Operation.Name,
 		Args:      s.Args,
+		ArgExprs:  marshalArgExprs(s.ArgExprs),
 		Result:    s.Result,
+		Results:   SplitList(s.Result),
 		Options:   marshalOptionsBlock(s.Options),
 	}
 	if s.Endpoint.Resolved != nil {
@@ -561,12 +674,16 @@ func marshalAwaitAllBlock(s *AwaitAllBlock) (json.RawMessage, error) {
 	if err != nil {
 		return nil, err
 	}
-	return json.Marshal(awaitAllBlockJSON{
+	aj := awaitAllBlockJSON{
 		Type:   "awaitAll",
 		Line:   s.Line,
 		Column: s.Column,
 		Body:   body,
-	})
+	}
+	if o := s.Options; o != nil {
+		aj.Options = &awaitAllOptionsJSON{Line: o.Line, Column: o.Column, OnError: o.OnError, MinSuccess: o.MinSuccess}
+	}
+	return json.Marshal(aj)
 }
 
 func marshalAwaitOneBlock(s *AwaitOneBlock) (json.RawMessage, error) {
@@ -577,9 +694,11 @@ func marshalAwaitOneBlock(s *AwaitOneBlock) (json.RawMessage, error) {
 			return nil, err
 		}
 		cj := awaitOneCaseJSON{
-			Line:   c.Line,
-			Column: c.Column,
-			Body:   caseBody,
+			Line:      c.Line,
+			Column:    c.Column,
+			Guard:     c.Guard,
+			GuardExpr: marshalExpr(c.GuardExpr),
+			Body:      caseBody,
 		}
 		if c.AwaitAll != nil {
 			data, err := marshalStatement(c.AwaitAll)
@@ -609,10 +728,11 @@ func marshalSwitchBlock(s *SwitchBlock) (json.RawMessage, error) {
 			return nil, err
 		}
 		cases = append(cases, switchCaseJSON{
-			Line:   c.Line,
-			Column: c.Column,
-			Value:  c.Value,
-			Body:   caseBody,
+			Line:      c.Line,
+			Column:    c.Column,
+			Value:     c.Value,
+			ValueExpr: marshalExpr(c.ValueExpr),
+			Body:      caseBody,
 		})
 	}
 	defaultBody, err := marshalStatements(s.Default)
@@ -620,12 +740,13 @@ func marshalSwitchBlock(s *SwitchBlock) (json.RawMessage, error) {
 		return nil, err
 	}
 	return json.Marshal(switchBlockJSON{
-		Type:    "switch",
-		Line:    s.Line,
-		Column:  s.Column,
-		Expr:    s.Expr,
-		Cases:   cases,
-		Default: defaultBody,
+		Type:        "switch",
+		Line:        s.Line,
+		Column:      s.Column,
+		Expr:        s.Expr,
+		SubjectExpr: marshalExpr(s.SubjectExpr),
+		Cases:       cases,
+		Default:     defaultBody,
 	})
 }
 
@@ -639,12 +760,14 @@ func marshalIfStmt(s *IfStmt) (json.RawMessage, error) {
 		return nil, err
 	}
 	return json.Marshal(ifStmtJSON{
-		Type:      "if",
-		Line:      s.Line,
-		Column:    s.Column,
-		Condition: s.Condition,
-		Body:      body,
-		ElseBody:  elseBody,
+		Type:          "if",
+		Line:          s.Line,
+		Column:        s.Column,
+		Condition:     s.Condition,
+		ConditionExpr: marshalExpr(s.CondExpr),
+		Body:          body,
+		ElseBody:      elseBody,
+		ElseIf:        s.ElseIf,
 	})
 }
 
@@ -653,15 +776,22 @@ func marshalForStmt(s *ForStmt) (json.RawMessage, error) {
 	if err != nil {
 		return nil, err
 	}
+	var label string
+	if s.Label != nil {
+		label = s.Label.Name
+	}
 	return json.Marshal(forStmtJSON{
-		Type:      "for",
-		Line:      s.Line,
-		Column:    s.Column,
-		Variant:   forVariantString(s.Variant),
-		Condition: s.Condition,
-		Variable:  s.Variable,
-		Iterable:  s.Iterable,
-		Body:      body,
+		Type:          "for",
+		Line:          s.Line,
+		Column:        s.Column,
+		Label:         label,
+		Variant:       forVariantString(s.Variant),
+		Condition:     s.Condition,
+		ConditionExpr: marshalExpr(s.CondExpr),
+		Variable:      s.Variable,
+		Iterable:      s.Iterable,
+		Concurrency:   s.Concurrency,
+		Body:          body,
 	})
 }
 
@@ -679,17 +809,18 @@ func marshalCloseStmt(s *CloseStmt) (json.RawMessage, error) {
 		Type:   "close",
 		Line:   s.Line,
 		Column: s.Column,
-		Reason: closeReasonString(s.Reason),
-		Args:   s.Args,
+		Reason:   closeReasonString(s.Reason),
+		Args:     s.Args,
+		ArgExprs: marshalArgExprs(s.ArgExprs),
 	})
 }
 
 func marshalBreakStmt(s *BreakStmt) (json.RawMessage, error) {
-	return json.Marshal(breakStmtJSON{Type: "break", Line: s.Line, Column: s.Column})
+	return json.Marshal(breakStmtJSON{Type: "break", Line: s.Line, Column: s.Column, Label: s.Label.Name})
 }
 
 func marshalContinueStmt(s *ContinueStmt) (json.RawMessage, error) {
-	return json.Marshal(continueStmtJSON{Type: "continue", Line: s.Line, Column: s.Column})
+	return json.Marshal(continueStmtJSON{Type: "continue", Line: s.Line, Column: s.Column, Label: s.Label.Name})
 }
 
 func marshalRawStmt(s *RawStmt) (json.RawMessage, error) {
@@ -737,6 +868,8 @@ func forVariantString(v ForVariant) string {
 		return "conditional"
 	case ForIteration:
 		return "iteration"
+	case ForParallel:
+		return "parallel"
 	default:
 		return "infinite"
 	}
@@ -749,7 +882,9 @@ type activityCallJSON struct {
 	Column   int               `json:"column"`
 	Name     string            `json:"name"`
 	Args     string            `json:"args"`
+	ArgExprs []any             `json:"argExprs,omitempty"`
 	Result   string            `json:"result,omitempty"`
+	Results  []string          `json:"results,omitempty"` // Result split into its names
 	Options  *OptionsBlockJSON `json:"options,omitempty"`
 	Resolved *resolvedRefJSON  `json:"resolved,omitempty"`
 }
@@ -761,7 +896,10 @@ type workflowCallJSON struct {
 	Mode     string            `json:"mode"`
 	Name     string            `json:"name"`
 	Args     string            `json:"args"`
+	ArgExprs []any             `json:"argExprs,omitempty"`
+	ID       string            `json:"id,omitempty"`
 	Result   string            `json:"result,omitempty"`
+	Results  []string          `json:"results,omitempty"` // Result split into its names
 	Options  *OptionsBlockJSON `json:"options,omitempty"`
 	Resolved *resolvedRefJSON  `json:"resolved,omitempty"`
 }
@@ -794,6 +932,8 @@ type asyncTargetJSON struct {
 
 type timerTargetJSON struct {
 	Duration string `json:"duration"`
+	Const    string `json:"const,omitempty"`
+	Result   string `json:"result,omitempty"`
 }
 
 type signalTargetJSON struct {
@@ -809,7 +949,9 @@ type updateTargetJSON struct {
 type activityTargetJSON struct {
 	Name     string           `json:"name"`
 	Args     string           `json:"args,omitempty"`
+	ArgExprs []any            `json:"argExprs,omitempty"`
 	Result   string           `json:"result,omitempty"`
+	Results  []string         `json:"results,omitempty"` // Result split into its names
 	Resolved *resolvedRefJSON `json:"resolved,omitempty"`
 }
 
@@ -817,7 +959,9 @@ type workflowTargetJSON struct {
 	Name     string           `json:"name"`
 	Mode     string           `json:"mode"`
 	Args     string           `json:"args,omitempty"`
+	ArgExprs []any            `json:"argExprs,omitempty"`
 	Result   string           `json:"result,omitempty"`
+	Results  []string         `json:"results,omitempty"` // Result split into its names
 	Resolved *resolvedRefJSON `json:"resolved,omitempty"`
 }
 
@@ -826,7 +970,9 @@ type nexusTargetJSON struct {
 	Service                       string           `json:"service"`
 	Operation                     string           `json:"operation"`
 	Args                          string           `json:"args,omitempty"`
+	ArgExprs                      []any            `json:"argExprs,omitempty"`
 	Result                        string           `json:"result,omitempty"`
+	Results                       []string         `json:"results,omitempty"` // Result split into its names
 	Detach                        bool             `json:"detach,omitempty"`
 	ResolvedEndpoint              *resolvedRefJSON `json:"resolvedEndpoint,omitempty"`
 	ResolvedEndpointNamespace     string           `json:"resolvedEndpointNamespace,omitempty"`
@@ -843,19 +989,19 @@ func marshalAsyncTarget(target AsyncTarget) asyncTargetJSON {
 	at := asyncTargetJSON{Kind: AsyncTargetKind(target)}
 	switch t := target.(type) {
 	case *TimerTarget:
-		at.Timer = &timerTargetJSON{Duration: t.Duration}
+		at.Timer = &timerTargetJSON{Duration: t.Duration, Const: t.Const.Name, Result: t.Result}
 	case *SignalTarget:
 		at.Signal = &signalTargetJSON{Name: t.Signal.Name, Params: t.Params}
 	case *UpdateTarget:
 		at.Update = &updateTargetJSON{Name: t.Update.Name, Params: t.Params}
 	case *ActivityTarget:
-		aj := &activityTargetJSON{Name: t.Activity.Name, Args: t.Args, Result: t.Result}
+		aj := &activityTargetJSON{Name: t.Activity.Name, Args: t.Args, ArgExprs: marshalArgExprs(t.ArgExprs), Result: t.Result, Results: SplitList(t.Result)}
 		if t.Activity.Resolved != nil {
 			aj.Resolved = &resolvedRefJSON{Name: t.Activity.Resolved.Name, Line: t.Activity.Resolved.Line, Column: t.Activity.Resolved.Column}
 		}
 		at.Activity = aj
 	case *WorkflowTarget:
-		wj := &workflowTargetJSON{Name: t.Workflow.Name, Mode: workflowCallModeString(t.Mode), Args: t.Args, Result: t.Result}
+		wj := &workflowTargetJSON{Name: t.Workflow.Name, Mode: workflowCallModeString(t.Mode), Args: t.Args, ArgExprs: marshalArgExprs(t.ArgExprs), Result: t.Result, Results: SplitList(t.Result)}
 		if t.Workflow.Resolved != nil {
 			wj.Resolved = &resolvedRefJSON{Name: t.Workflow.Resolved.Name, Line: t.Workflow.Resolved.Line, Column: t.Workflow.Resolved.Column}
 		}
@@ -866,7 +1012,9 @@ func marshalAsyncTarget(target AsyncTarget) asyncTargetJSON {
 			Service:   t.Service.Name,
 			Operation: t.Operation.Name,
 			Args:      t.Args,
+			ArgExprs:  marshalArgExprs(t.ArgExprs),
 			Result:    t.Result,
+			Results:   SplitList(t.Result),
 			Detach:    t.Detach,
 		}
 		if t.Endpoint.Resolved != nil {
@@ -894,18 +1042,28 @@ type awaitStmtJSON struct {
 }
 
 type awaitAllBlockJSON struct {
-	Type   string            `json:"type"`
-	Line   int               `json:"line"`
-	Column int               `json:"column"`
-	Body   []json.RawMessage `json:"body"`
+	Type    string               `json:"type"`
+	Line    int                  `json:"line"`
+	Column  int                  `json:"column"`
+	Options *awaitAllOptionsJSON `json:"options,omitempty"`
+	Body    []json.RawMessage    `json:"body"`
+}
+
+type awaitAllOptionsJSON struct {
+	Line       int    `json:"line"`
+	Column     int    `json:"column"`
+	OnError    string `json:"onError"`
+	MinSuccess int    `json:"minSuccess,omitempty"`
 }
 
 type awaitOneCaseJSON struct {
-	Line     int               `json:"line"`
-	Column   int               `json:"column"`
-	Target   *asyncTargetJSON  `json:"target,omitempty"`
-	AwaitAll json.RawMessage   `json:"awaitAll,omitempty"`
-	Body     []json.RawMessage `json:"body"`
+	Line      int               `json:"line"`
+	Column    int               `json:"column"`
+	Target    *asyncTargetJSON  `json:"target,omitempty"`
+	AwaitAll  json.RawMessage   `json:"awaitAll,omitempty"`
+	Guard     string            `json:"guard,omitempty"`
+	GuardExpr any               `json:"guardExpr,omitempty"`
+	Body      []json.RawMessage `json:"body"`
 }
 
 type awaitOneBlockJSON struct {
@@ -916,39 +1074,46 @@ type awaitOneBlockJSON struct {
 }
 
 type switchCaseJSON struct {
-	Line   int               `json:"line"`
-	Column int               `json:"column"`
-	Value  string            `json:"value"`
-	Body   []json.RawMessage `json:"body"`
+	Line      int               `json:"line"`
+	Column    int               `json:"column"`
+	Value     string            `json:"value"`
+	ValueExpr any               `json:"valueExpr,omitempty"`
+	Body      []json.RawMessage `json:"body"`
 }
 
 type switchBlockJSON struct {
-	Type    string            `json:"type"`
-	Line    int               `json:"line"`
-	Column  int               `json:"column"`
-	Expr    string            `json:"expr"`
-	Cases   []switchCaseJSON  `json:"cases"`
-	Default []json.RawMessage `json:"default,omitempty"`
+	Type        string            `json:"type"`
+	Line        int               `json:"line"`
+	Column      int               `json:"column"`
+	Expr        string            `json:"expr"`
+	SubjectExpr any               `json:"subjectExpr,omitempty"`
+	Cases       []switchCaseJSON  `json:"cases"`
+	Default     []json.RawMessage `json:"default,omitempty"`
 }
 
 type ifStmtJSON struct {
-	Type      string            `json:"type"`
-	Line      int               `json:"line"`
-	Column    int               `json:"column"`
-	Condition string            `json:"condition"`
-	Body      []json.RawMessage `json:"body"`
-	ElseBody  []json.RawMessage `json:"elseBody,omitempty"`
+	Type          string            `json:"type"`
+	Line          int               `json:"line"`
+	Column        int               `json:"column"`
+	Condition     string            `json:"condition"`
+	ConditionExpr any               `json:"conditionExpr,omitempty"`
+	Body          []json.RawMessage `json:"body"`
+	ElseBody      []json.RawMessage `json:"elseBody,omitempty"`
+	ElseIf        bool              `json:"elseIf,omitempty"`
 }
 
 type forStmtJSON struct {
-	Type      string            `json:"type"`
-	Line      int               `json:"line"`
-	Column    int               `json:"column"`
-	Variant   string            `json:"variant"`
-	Condition string            `json:"condition,omitempty"`
-	Variable  string            `json:"variable,omitempty"`
-	Iterable  string            `json:"iterable,omitempty"`
-	Body      []json.RawMessage `json:"body"`
+	Type          string            `json:"type"`
+	Line          int               `json:"line"`
+	Column        int               `json:"column"`
+	Label         string            `json:"label,omitempty"`
+	Variant       string            `json:"variant"`
+	Condition     string            `json:"condition,omitempty"`
+	ConditionExpr any               `json:"conditionExpr,omitempty"`
+	Variable      string            `json:"variable,omitempty"`
+	Iterable      string            `json:"iterable,omitempty"`
+	Concurrency   string            `json:"concurrency,omitempty"`
+	Body          []json.RawMessage `json:"body"`
 }
 
 type returnStmtJSON struct {
@@ -962,20 +1127,23 @@ type closeStmtJSON struct {
 	Type   string `json:"type"`
 	Line   int    `json:"line"`
 	Column int    `json:"column"`
-	Reason string `json:"reason"`
-	Args   string `json:"args,omitempty"`
+	Reason   string `json:"reason"`
+	Args     string `json:"args,omitempty"`
+	ArgExprs []any  `json:"argExprs,omitempty"`
 }
 
 type breakStmtJSON struct {
 	Type   string `json:"type"`
 	Line   int    `json:"line"`
 	Column int    `json:"column"`
+	Label  string `json:"label,omitempty"`
 }
 
 type continueStmtJSON struct {
 	Type   string `json:"type"`
 	Line   int    `json:"line"`
 	Column int    `json:"column"`
+	Label  string `json:"label,omitempty"`
 }
 
 type rawStmtJSON struct{
@@ -1023,7 +1191,9 @@ type nexusCallJSON struct {
 	Service   string            `json:"service"`
 	Operation string            `json:"operation"`
 	Args      string            `json:"args"`
+	ArgExprs  []any             `json:"argExprs,omitempty"`
 	Result    string            `json:"result,omitempty"`
+	Results   []string          `json:"results,omitempty"` // Result split into its names
 	Options   *OptionsBlockJSON `json:"options,omitempty"`
 	// Resolution links
 	ResolvedEndpoint          *resolvedRefJSON `json:"resolvedEndpoint,omitempty"`
@@ -1051,7 +1221,7 @@ type NexusServiceDefJSON struct {
 	Column     int                   `json:"column"`
 	SourceFile string                `json:"sourceFile,omitempty"`
 	Name       string                `json:"name"`
-	Operations []*NexusOperationJSON `json:"operations,omitempty"`
+	Operations []*NexusOperationJSON `json:"operations"`
 }
 
 func (n *NexusServiceDef) MarshalJSON() ([]byte, error) {
@@ -1061,6 +1231,7 @@ func (n *NexusServiceDef) MarshalJSON() ([]byte, error) {
 		Column:     n.Column,
 		SourceFile: n.SourceFile,
 		Name:       n.Name,
+		Operations: make([]*NexusOperationJSON, 0, len(n.Operations)),
 	}
 	for _, op := range n.Operations {
 		opj := &NexusOperationJSON{
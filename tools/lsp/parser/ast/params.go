@@ -0,0 +1,95 @@
+package ast
+
+import (
+	"slices"
+	"strings"
+)
+
+// Param is one parameter of an opaque parameter list. Type is empty when
+// the design omits it, and Default when the parameter is required.
+type Param struct {
+	Name        string
+	Type        string
+	Default     string   // value as written after "="
+	Annotations []string // names of the markers before it, as pii for @pii
+}
+
+// Annotated reports whether p is marked @name.
+func (p Param) Annotated(name string) bool {
+	return slices.Contains(p.Annotations, name)
+}
+
+// ParseParams splits an opaque parameter list such as
+// "@pii email: string, verbose: bool = false" into its parameters. State
+// entries, such as "@pii address: string", parse the same way.
+func ParseParams(params string) []Param {
+	var out []Param
+	for _, p := range SplitList(params) {
+		decl, def, _ := strings.Cut(p, "=")
+		var anns []string
+		decl = strings.TrimSpace(decl)
+		for strings.HasPrefix(decl, "@") {
+			marker, rest, _ := strings.Cut(decl[1:], " ")
+			anns = append(anns, marker)
+			decl = strings.TrimSpace(rest)
+		}
+		name, typ, _ := strings.Cut(decl, ":")
+		out = append(out, Param{Name: strings.TrimSpace(name), Type: strings.TrimSpace(typ), Default: strings.TrimSpace(def), Annotations: anns})
+	}
+	return out
+}
+
+// RequiredParams returns how many leading parameters a call must pass: all
+// of them up to the last one without a default.
+func RequiredParams(params []Param) int {
+	for i := len(params) - 1; i >= 0; i-- {
+		if params[i].Default == "" {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// SplitList splits s at commas outside brackets and string literals,
+// dropping empty parts, as for the return types "Order, error" or the
+// result bindings "order, err".
+func SplitList(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	quoted, escaped := false, false
+	for i, r := range s {
+		if quoted {
+			switch {
+			case escaped:
+				escaped = false
+			case r == '\\':
+				escaped = true
+			case r == '"':
+				quoted = false
+			}
+			continue
+		}
+		switch r {
+		case '"':
+			quoted = true
+		case '(', '[', '{', '<':
+			depth++
+		case ')', ']', '}', '>':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	parts = append(parts, s[start:])
+
+	var out []string
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
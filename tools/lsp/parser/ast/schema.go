@@ -0,0 +1,296 @@
+package ast
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// SchemaVersion is the version of the AST JSON format, emitted as the
+// top-level schemaVersion field. It is incremented whenever a field is
+// removed, renamed, or changes type; additive changes keep the version.
+const SchemaVersion = 1
+
+// SchemaID identifies the JSON Schema returned by JSONSchema.
+const SchemaID = "https://github.com/jmbarzee/temporal-skills/schemas/twf-ast.schema.json"
+
+// omissionPolicy states how the JSON output marks what a node lacks. It is
+// the schema's description, and the JSON struct tags follow it: fields
+// without omitempty are required, and required lists are never nil.
+const omissionPolicy = "Lists are always present, as [] when empty, and never null: bodies, cases, handlers, worker registrations, namespace workers and endpoints, service operations, and state entries. " +
+	"Parts standing for optional syntax are omitted when it is absent, never null: else branches (elseBody, default), options blocks, nested option entries, annotations, descriptions, state blocks, and resolved references, as are the lists split from an optional string (returns, results, argExprs). " +
+	"Strings and numbers the syntax requires are always present; optional ones are omitted when unset."
+
+// unionMember is one variant of a discriminated union: objects whose
+// discriminator field holds one of tags have the shape of typ.
+type unionMember struct {
+	tags []string
+	typ  reflect.Type
+}
+
+// union describes a polymorphic JSON value. The JSON structs hold these as
+// json.RawMessage or any, so their variants are listed here by hand.
+type union struct {
+	name          string
+	discriminator string
+	members       []unionMember
+}
+
+func member[T any](tags ...string) unionMember {
+	return unionMember{tags: tags, typ: reflect.TypeFor[T]()}
+}
+
+var definitionUnion = union{"definition", "type", []unionMember{
+	member[WorkflowDefJSON]("workflowDef"),
+	member[ActivityDefJSON]("activityDef"),
+	member[WorkerDefJSON]("workerDef"),
+	member[NamespaceDefJSON]("namespaceDef"),
+	member[NexusServiceDefJSON]("nexusServiceDef"),
+	member[ConstDefJSON]("constDef"),
+	member[EnumDefJSON]("enumDef"),
+}}
+
+var statementUnion = union{"statement", "type", []unionMember{
+	member[activityCallJSON]("activityCall"),
+	member[workflowCallJSON]("workflowCall"),
+	member[nexusCallJSON]("nexusCall"),
+	member[awaitStmtJSON]("await"),
+	member[awaitAllBlockJSON]("awaitAll"),
+	member[awaitOneBlockJSON]("awaitOne"),
+	member[switchBlockJSON]("switch"),
+	member[ifStmtJSON]("if"),
+	member[forStmtJSON]("for"),
+	member[returnStmtJSON]("return"),
+	member[closeStmtJSON]("close"),
+	member[breakStmtJSON]("break"),
+	member[continueStmtJSON]("continue"),
+	member[rawStmtJSON]("raw"),
+	member[commentJSON]("comment"),
+	member[promiseStmtJSON]("promise"),
+	member[setStmtJSON]("set"),
+	member[unsetStmtJSON]("unset"),
+}}
+
+var expressionUnion = union{"expression", "kind", []unionMember{
+	member[identExprJSON]("ident"),
+	member[selectorExprJSON]("selector"),
+	member[literalExprJSON]("string", "number", "duration"),
+	member[boolExprJSON]("bool"),
+	member[listExprJSON]("list"),
+	member[mapExprJSON]("map"),
+	member[binaryExprJSON]("binary"),
+	member[unaryExprJSON]("unary"),
+}}
+
+// Fields typed json.RawMessage hold statements and fields typed any hold
+// expressions, except for the overrides below.
+var (
+	rawMessageType = reflect.TypeFor[json.RawMessage]()
+	anyType        = reflect.TypeFor[any]()
+)
+
+// fieldOverrides maps "StructName.FieldName" to the $defs entry the field
+// refers to when the default for its type does not apply.
+var fieldOverrides = map[string]string{
+	"FileJSON.Definitions":      definitionUnion.name,
+	"awaitOneCaseJSON.AwaitAll": defName(reflect.TypeFor[awaitAllBlockJSON]()),
+}
+
+// JSONSchema returns a JSON Schema (draft 2020-12) for the output of
+// File.MarshalJSON. It is generated by reflecting over the JSON struct types,
+// so it changes whenever they do.
+func JSONSchema() ([]byte, error) {
+	g := &schemaGen{defs: make(map[string]any), tags: make(map[reflect.Type]union)}
+	for _, u := range []union{definitionUnion, statementUnion, expressionUnion} {
+		for _, m := range u.members {
+			g.tags[m.typ] = u
+		}
+	}
+	for _, u := range []union{definitionUnion, statementUnion, expressionUnion} {
+		if err := g.union(u); err != nil {
+			return nil, err
+		}
+	}
+
+	root, err := g.object(reflect.TypeFor[FileJSON]())
+	if err != nil {
+		return nil, err
+	}
+	root["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	root["$id"] = SchemaID
+	root["title"] = "TWF AST"
+	root["description"] = omissionPolicy
+	root["$defs"] = g.defs
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(root); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// schemaGen accumulates $defs while walking struct types.
+type schemaGen struct {
+	defs map[string]any
+	// tags maps union member types to their union, so their discriminator
+	// property is constrained to the member's tags.
+	tags map[reflect.Type]union
+}
+
+// union adds a oneOf over the union's members to $defs.
+func (g *schemaGen) union(u union) error {
+	var refs []any
+	for _, m := range u.members {
+		ref, err := g.ref(m.typ)
+		if err != nil {
+			return err
+		}
+		refs = append(refs, ref)
+	}
+	g.defs[u.name] = map[string]any{"oneOf": refs}
+	return nil
+}
+
+// ref returns a $ref to the $defs entry for struct type t, generating the
+// entry on first use.
+func (g *schemaGen) ref(t reflect.Type) (map[string]any, error) {
+	name := defName(t)
+	if _, ok := g.defs[name]; !ok {
+		g.defs[name] = nil // reserve against recursion
+		obj, err := g.object(t)
+		if err != nil {
+			return nil, err
+		}
+		g.defs[name] = obj
+	}
+	return map[string]any{"$ref": "#/$defs/" + name}, nil
+}
+
+// object returns the schema for struct type t. Fields without omitempty are
+// required, and only those that are not lists may be null.
+func (g *schemaGen) object(t reflect.Type) (map[string]any, error) {
+	if reflect.PointerTo(t).Implements(reflect.TypeFor[json.Marshaler]()) {
+		return nil, fmt.Errorf("JSONSchema: %s has a custom MarshalJSON", t)
+	}
+	props := make(map[string]any)
+	var required []string
+
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		omitEmpty := strings.Contains(opts, "omitempty")
+
+		var s map[string]any
+		var err error
+		if def, ok := fieldOverrides[t.Name()+"."+f.Name]; ok {
+			s = map[string]any{"$ref": "#/$defs/" + def}
+			if f.Type.Kind() == reflect.Slice && f.Type != rawMessageType {
+				s = map[string]any{"type": "array", "items": s}
+			}
+		} else if s, err = g.value(f.Type); err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", t.Name(), f.Name, err)
+		}
+
+		if u, ok := g.tags[t]; ok && name == u.discriminator {
+			s = discriminator(u, t)
+		}
+		if !omitEmpty {
+			required = append(required, name)
+			if nullable(f.Type) {
+				s = map[string]any{"anyOf": []any{s, map[string]any{"type": "null"}}}
+			}
+		}
+		props[name] = s
+	}
+
+	obj := map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		obj["required"] = required
+	}
+	return obj, nil
+}
+
+// value returns the schema for a field of type t.
+func (g *schemaGen) value(t reflect.Type) (map[string]any, error) {
+	switch {
+	case t == rawMessageType:
+		return map[string]any{"$ref": "#/$defs/" + statementUnion.name}, nil
+	case t == anyType:
+		return map[string]any{"$ref": "#/$defs/" + expressionUnion.name}, nil
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}, nil
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int64, reflect.Int32:
+		return map[string]any{"type": "integer"}, nil
+	case reflect.Pointer:
+		return g.value(t.Elem())
+	case reflect.Slice:
+		items, err := g.value(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "array", "items": items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported map key %s", t.Key())
+		}
+		values, err := g.value(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "object", "additionalProperties": values}, nil
+	case reflect.Struct:
+		return g.ref(t)
+	}
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
+
+// discriminator constrains a union member's discriminator field to its tags.
+func discriminator(u union, t reflect.Type) map[string]any {
+	for _, m := range u.members {
+		if m.typ != t {
+			continue
+		}
+		if len(m.tags) == 1 {
+			return map[string]any{"const": m.tags[0]}
+		}
+		return map[string]any{"enum": m.tags}
+	}
+	return map[string]any{"type": "string"}
+}
+
+// nullable reports whether a non-omitempty field of type t can marshal as
+// null: nil maps and pointers do. Required lists are made non-nil, by the
+// omission policy.
+func nullable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Map, reflect.Pointer, reflect.Interface:
+		return true
+	}
+	return false
+}
+
+// defName names the $defs entry for a struct type: its Go name without the
+// JSON suffix, in lower camel case.
+func defName(t reflect.Type) string {
+	name := strings.TrimSuffix(t.Name(), "JSON")
+	return strings.ToLower(name[:1]) + name[1:]
+}
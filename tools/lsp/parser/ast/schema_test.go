@@ -0,0 +1,237 @@
+package ast_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/parser"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/resolver"
+)
+
+// exprSample exercises expression and constant JSON, which the testdata
+// files barely use.
+const exprSample = `const Limit = 3
+const Wait = 5m
+
+workflow Sample(items: list) -> (Result):
+    state:
+        condition ready
+    signal Go():
+        set ready
+    for (item in items):
+        if (item.count > Limit && !item.skip):
+            break
+        elif (item.kind == "a"):
+            continue
+        else:
+            activity Process(item, ["x", 1], {key: true}) -> out
+                options:
+                    retry_policy:
+                        maximum_attempts: Limit
+    switch (status):
+        case "done":
+            return
+        else:
+            close fail
+    await one:
+        ready:
+            return
+        timer(Wait):
+            close complete(Result{ok: true})
+
+activity Process(item: Item, tags: list, meta: map) -> (Out):
+    return out
+`
+
+func TestJSONSchemaConformance(t *testing.T) {
+	data, err := ast.JSONSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("schema is not valid JSON: %v", err)
+	}
+	defs := schema["$defs"].(map[string]any)
+
+	sources := map[string]string{"exprSample": exprSample}
+	paths, _ := filepath.Glob("../testdata/*.twf")
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sources[filepath.Base(path)] = string(src)
+	}
+
+	for name, src := range sources {
+		file, _ := parser.ParseFileAll(src)
+		resolver.Resolve(file)
+		out, err := json.Marshal(file)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var doc any
+		if err := json.Unmarshal(out, &doc); err != nil {
+			t.Fatal(err)
+		}
+		if err := validate(schema, defs, doc, "$"); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+		if v := doc.(map[string]any)["schemaVersion"]; v != float64(ast.SchemaVersion) {
+			t.Errorf("%s: schemaVersion = %v", name, v)
+		}
+	}
+}
+
+func TestOmissionPolicy(t *testing.T) {
+	src := `workflow Empty():
+    state:
+        condition ready
+    signal Ping():
+        set ready
+    await ready
+    close complete
+
+worker idle:
+    workflow Empty
+
+namespace quiet:
+    worker idle
+`
+	file, errs := parser.ParseFileAll(src)
+	if len(errs) != 0 {
+		t.Fatal(errs)
+	}
+	file.Definitions = append(file.Definitions, &ast.ActivityDef{Pos: ast.Pos{Line: 14, Column: 1}, Name: "Bare"})
+	out, err := json.Marshal(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(out, []byte("null")) {
+		t.Errorf("expected no nulls, got %s", out)
+	}
+	for _, want := range []string{`"rawStmts":[]`, `"queries":[]`, `"activities":[],"services":[]`, `"endpoints":[]`, `"name":"Bare","params":"","body":[]`} {
+		if !bytes.Contains(out, []byte(want)) {
+			t.Errorf("expected %s in %s", want, out)
+		}
+	}
+}
+
+// TestJSONSchemaCheckedIn fails when the published schema differs from the
+// generated one.
+func TestJSONSchemaCheckedIn(t *testing.T) {
+	const path = "../../../../schemas/twf-ast.schema.json"
+	want, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		t.Skip("published schema not present")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ast.JSONSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s is stale; regenerate with: twf parse --schema > schemas/twf-ast.schema.json", path)
+	}
+}
+
+// validate checks doc against the subset of JSON Schema that JSONSchema
+// emits.
+func validate(s map[string]any, defs map[string]any, doc any, at string) error {
+	if ref, ok := s["$ref"].(string); ok {
+		return validate(defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]any), defs, doc, at)
+	}
+	if c, ok := s["const"]; ok && doc != c {
+		return fmt.Errorf("%s: got %v, want %v", at, doc, c)
+	}
+	if e, ok := s["enum"].([]any); ok && !slices.Contains(e, doc) {
+		return fmt.Errorf("%s: %v not in %v", at, doc, e)
+	}
+	for _, key := range []string{"oneOf", "anyOf"} {
+		alts, ok := s[key].([]any)
+		if !ok {
+			continue
+		}
+		matched := 0
+		var errs []string
+		for _, alt := range alts {
+			if err := validate(alt.(map[string]any), defs, doc, at); err != nil {
+				errs = append(errs, err.Error())
+			} else {
+				matched++
+			}
+		}
+		if matched == 0 || (key == "oneOf" && matched > 1) {
+			return fmt.Errorf("%s: %d of %s matched: %s", at, matched, key, strings.Join(errs, "; "))
+		}
+	}
+
+	switch s["type"] {
+	case "object":
+		obj, ok := doc.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: want object, got %T", at, doc)
+		}
+		for _, r := range asSlice(s["required"]) {
+			if _, ok := obj[r.(string)]; !ok {
+				return fmt.Errorf("%s: missing required %q", at, r)
+			}
+		}
+		props, _ := s["properties"].(map[string]any)
+		for k, v := range obj {
+			ps, ok := props[k].(map[string]any)
+			if !ok {
+				extra, ok := s["additionalProperties"].(map[string]any)
+				if !ok {
+					return fmt.Errorf("%s: unexpected property %q", at, k)
+				}
+				ps = extra
+			}
+			if err := validate(ps, defs, v, at+"."+k); err != nil {
+				return err
+			}
+		}
+	case "array":
+		arr, ok := doc.([]any)
+		if !ok {
+			return fmt.Errorf("%s: want array, got %T", at, doc)
+		}
+		for i, v := range arr {
+			if err := validate(s["items"].(map[string]any), defs, v, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		if _, ok := doc.(string); !ok {
+			return fmt.Errorf("%s: want string, got %T", at, doc)
+		}
+	case "integer":
+		if f, ok := doc.(float64); !ok || f != float64(int64(f)) {
+			return fmt.Errorf("%s: want integer, got %v", at, doc)
+		}
+	case "boolean":
+		if _, ok := doc.(bool); !ok {
+			return fmt.Errorf("%s: want boolean, got %T", at, doc)
+		}
+	case "null":
+		if doc != nil {
+			return fmt.Errorf("%s: want null, got %T", at, doc)
+		}
+	}
+	return nil
+}
+
+func asSlice(v any) []any {
+	s, _ := v.([]any)
+	return s
+}
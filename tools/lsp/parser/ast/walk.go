@@ -16,7 +16,9 @@ func WithAsyncTargets(fn func(target AsyncTarget, parent Statement) bool) WalkOp
 }
 
 // WalkStatements calls fn on each statement in stmts in pre-order.
-// For compound statements, children are visited after the parent.
+// For compound statements, the statements Children returns are visited
+// after the parent, so an await all block racing in an await one case is
+// visited itself, before its body.
 // If fn returns false, the walk stops immediately.
 // Returns false if the walk was stopped early.
 func WalkStatements(stmts []Statement, fn func(Statement) bool, opts ...WalkOption) bool {
@@ -46,81 +48,107 @@ func AsyncTargetOf(s Statement) AsyncTarget {
 	return nil
 }
 
-// walkStatement visits a single statement and recursively visits its children.
-func walkStatement(stmt Statement, fn func(Statement) bool, cfg *walkConfig) bool {
-	if !fn(stmt) {
-		return false
-	}
-
-	// Invoke async target callback if configured.
-	if cfg.asyncTargetFn != nil {
-		if target := AsyncTargetOf(stmt); target != nil {
-			if !cfg.asyncTargetFn(target, stmt) {
-				return false
-			}
-		}
-	}
-
+// Children returns the statements directly inside stmt, in source order,
+// or nil for a statement with no body. It is the traversal contract every
+// walk of the statement tree shares, WalkStatements included:
+//
+//   - an await all block's children are its body;
+//   - an await one block's children are its cases;
+//   - an await one case's children are its await all block, when it races
+//     one, and then its body;
+//   - a switch's children are its cases and then its else body;
+//   - an if's children are its body and then its else body, which holds
+//     the chained if of an elif;
+//   - a for loop's children are its body.
+//
+// Code recursing by hand should call Children rather than reading the
+// fields, so nested blocks, such as an await all inside an await one case,
+// are not missed.
+func Children(stmt Statement) []Statement {
 	switch s := stmt.(type) {
 	case *AwaitAllBlock:
-		for _, child := range s.Body {
-			if !walkStatement(child, fn, cfg) {
-				return false
-			}
-		}
+		return s.Body
 	case *AwaitOneBlock:
-		for _, c := range s.Cases {
-			if !walkStatement(c, fn, cfg) {
-				return false
-			}
+		out := make([]Statement, len(s.Cases))
+		for i, c := range s.Cases {
+			out[i] = c
 		}
+		return out
 	case *AwaitOneCase:
-		if s.AwaitAll != nil {
-			for _, child := range s.AwaitAll.Body {
-				if !walkStatement(child, fn, cfg) {
-					return false
-				}
-			}
-		}
-		for _, child := range s.Body {
-			if !walkStatement(child, fn, cfg) {
-				return false
-			}
+		if s.AwaitAll == nil {
+			return s.Body
 		}
+		return append([]Statement{s.AwaitAll}, s.Body...)
 	case *SwitchBlock:
+		out := make([]Statement, 0, len(s.Cases)+len(s.Default))
 		for _, c := range s.Cases {
-			if !walkStatement(c, fn, cfg) {
-				return false
-			}
-		}
-		for _, child := range s.Default {
-			if !walkStatement(child, fn, cfg) {
-				return false
-			}
+			out = append(out, c)
 		}
+		return append(out, s.Default...)
 	case *SwitchCase:
-		for _, child := range s.Body {
-			if !walkStatement(child, fn, cfg) {
-				return false
-			}
-		}
+		return s.Body
 	case *IfStmt:
-		for _, child := range s.Body {
-			if !walkStatement(child, fn, cfg) {
-				return false
-			}
-		}
-		for _, child := range s.ElseBody {
-			if !walkStatement(child, fn, cfg) {
-				return false
-			}
+		if len(s.ElseBody) == 0 {
+			return s.Body
 		}
+		return append(append(make([]Statement, 0, len(s.Body)+len(s.ElseBody)), s.Body...), s.ElseBody...)
 	case *ForStmt:
-		for _, child := range s.Body {
-			if !walkStatement(child, fn, cfg) {
+		return s.Body
+	}
+	return nil
+}
+
+// WorkflowBodies returns the statement lists of wf: its body and those of
+// its signal, query, and update handlers. Code looking for statements
+// anywhere in a workflow should walk each of them, not only wf.Body.
+func WorkflowBodies(wf *WorkflowDef) [][]Statement {
+	bodies := [][]Statement{wf.Body}
+	for _, s := range wf.Signals {
+		bodies = append(bodies, s.Body)
+	}
+	for _, q := range wf.Queries {
+		bodies = append(bodies, q.Body)
+	}
+	for _, u := range wf.Updates {
+		bodies = append(bodies, u.Body)
+	}
+	return bodies
+}
+
+// OptionValue returns the value of the top-level entry key in ob, or ""
+// when ob is nil or does not set it. Nested blocks such as retry_policy
+// have no value of their own.
+func OptionValue(ob *OptionsBlock, key string) string {
+	if ob == nil {
+		return ""
+	}
+	for _, e := range ob.Entries {
+		if e.Key == key {
+			return e.Value
+		}
+	}
+	return ""
+}
+
+// walkStatement visits a single statement and recursively visits its children.
+func walkStatement(stmt Statement, fn func(Statement) bool, cfg *walkConfig) bool {
+	if !fn(stmt) {
+		return false
+	}
+
+	// Invoke async target callback if configured.
+	if cfg.asyncTargetFn != nil {
+		if target := AsyncTargetOf(stmt); target != nil {
+			if !cfg.asyncTargetFn(target, stmt) {
 				return false
 			}
 		}
 	}
+
+	for _, child := range Children(stmt) {
+		if !walkStatement(child, fn, cfg) {
+			return false
+		}
+	}
 	return true
 }
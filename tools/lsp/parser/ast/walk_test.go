@@ -120,7 +120,7 @@ func TestWalkStatementsAwaitOneNestedAwaitAll(t *testing.T) {
 		return true
 	})
 
-	want := []int{1, 10, 12, 13}
+	want := []int{1, 10, 11, 12, 13}
 	if len(lines) != len(want) {
 		t.Fatalf("expected %d visits, got %d: %v", len(want), len(lines), lines)
 	}
@@ -347,3 +347,78 @@ func TestAsyncTargetKind(t *testing.T) {
 		}
 	}
 }
+
+func TestChildren(t *testing.T) {
+	raw := func(line int) Statement { return &RawStmt{Pos: Pos{Line: line}} }
+	nested := &AwaitAllBlock{Pos: Pos{Line: 2}, Body: []Statement{raw(3)}}
+	tests := []struct {
+		name string
+		stmt Statement
+		want []int
+	}{
+		{"await all", &AwaitAllBlock{Body: []Statement{raw(2), raw(3)}}, []int{2, 3}},
+		{"await one", &AwaitOneBlock{Cases: []*AwaitOneCase{{Pos: Pos{Line: 2}}, {Pos: Pos{Line: 4}}}}, []int{2, 4}},
+		{"await one case", &AwaitOneCase{Body: []Statement{raw(3)}}, []int{3}},
+		{"await all case", &AwaitOneCase{AwaitAll: nested, Body: []Statement{raw(4)}}, []int{2, 4}},
+		{"switch", &SwitchBlock{Cases: []*SwitchCase{{Pos: Pos{Line: 2}}}, Default: []Statement{raw(5)}}, []int{2, 5}},
+		{"switch case", &SwitchCase{Body: []Statement{raw(3)}}, []int{3}},
+		{"if", &IfStmt{Body: []Statement{raw(2)}, ElseBody: []Statement{raw(4)}}, []int{2, 4}},
+		{"for", &ForStmt{Body: []Statement{raw(2)}}, []int{2}},
+		{"leaf", raw(1), nil},
+	}
+	for _, tt := range tests {
+		var got []int
+		for _, c := range Children(tt.stmt) {
+			got = append(got, c.NodeLine())
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: children at lines %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: children at lines %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestWorkflowBodies(t *testing.T) {
+	wf := &WorkflowDef{
+		Body:    []Statement{&RawStmt{Pos: Pos{Line: 5}}},
+		Signals: []*SignalDecl{{Body: []Statement{&RawStmt{Pos: Pos{Line: 2}}}}},
+		Queries: []*QueryDecl{{Body: []Statement{&RawStmt{Pos: Pos{Line: 3}}}}},
+		Updates: []*UpdateDecl{{Body: []Statement{&RawStmt{Pos: Pos{Line: 4}}}}},
+	}
+	var got []int
+	for _, body := range WorkflowBodies(wf) {
+		for _, s := range body {
+			got = append(got, s.NodeLine())
+		}
+	}
+	want := []int{5, 2, 3, 4}
+	if len(got) != len(want) {
+		t.Fatalf("bodies at lines %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("bodies at lines %v, want %v", got, want)
+		}
+	}
+}
+
+func TestOptionValue(t *testing.T) {
+	ob := &OptionsBlock{Entries: []*OptionEntry{
+		{Key: "task_queue", Value: "orders"},
+		{Key: "retry_policy", Nested: []*OptionEntry{{Key: "maximum_attempts", Value: "3"}}},
+	}}
+	for key, want := range map[string]string{"task_queue": "orders", "retry_policy": "", "maximum_attempts": "", "cron_schedule": ""} {
+		if got := OptionValue(ob, key); got != want {
+			t.Errorf("OptionValue(%q) = %q, want %q", key, got, want)
+		}
+	}
+	if got := OptionValue(nil, "task_queue"); got != "" {
+		t.Errorf("OptionValue(nil) = %q, want \"\"", got)
+	}
+}
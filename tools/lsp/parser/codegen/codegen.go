@@ -0,0 +1,266 @@
+// Package codegen generates Temporal SDK stubs from TWF designs.
+//
+// Each target language has built-in text/template templates, one per output
+// file, named after the file with a .tmpl suffix (workflows.go.tmpl writes
+// workflows.go). Templates execute against a Design built from the AST and
+// may use the helpers in Funcs. A template directory can replace built-in
+// templates by name and add output files of its own, so teams can match
+// their SDK wrappers without forking the generator. Merge carries the
+// hand-written protected regions of a previous generation into a new one.
+package codegen
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/format"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/options"
+)
+
+//go:embed templates
+var builtin embed.FS
+
+// Languages are the built-in target languages.
+var Languages = []string{"go", "typescript", "python"}
+
+// DefaultPackage is the Go package name used when Options.Package is empty.
+const DefaultPackage = "workflows"
+
+// TypesModule is the module of the generated type stubs: types.go,
+// types.ts, or types.py.
+const TypesModule = "types"
+
+// TypeMapFile is the output mapping each type name to the module defining
+// it. Reading it back into Options.TypeModules keeps regeneration from
+// stubbing types that have moved to hand-written modules.
+const TypeMapFile = "twf-types.json"
+
+// Options configures Generate.
+type Options struct {
+	Lang    string // one of Languages
+	Package string // Go package name; DefaultPackage when empty
+	// Templates is a directory of *.tmpl files. Each replaces the built-in
+	// template of the same name or, if there is none, adds an output file.
+	// Files whose names start with "_" only define templates for the others
+	// to call. Empty uses the built-in templates alone.
+	Templates string
+	// Workers also renders the worker templates, named worker.*.tmpl, once
+	// per task queue. Their output is named after the queue: worker.go.tmpl
+	// writes worker_orders.go for queue "orders".
+	Workers bool
+	// Types also renders the types templates, named types.*.tmpl, with a
+	// stub for each type that signatures use and TypeModules does not place
+	// elsewhere, and writes TypeMapFile.
+	Types bool
+	// TypeModules maps type names to the modules defining them, as read
+	// from TypeMapFile. Types mapped to a module other than TypesModule are
+	// imported from it instead of stubbed.
+	TypeModules map[string]string
+	// Identifiers maps names to the identifiers a previous generation gave
+	// them, as read from IdentifierMapFile. Those still free are kept.
+	Identifiers map[string]string
+	// OptionDefaults are the workspace defaults for call options, merged
+	// below the options of activity definitions and calls.
+	OptionDefaults *options.Config
+}
+
+// Output is one generated file. Path is relative to the output directory.
+type Output struct {
+	Path    string
+	Content []byte
+}
+
+// Generate renders the stubs for file, which should be resolved. Go
+// outputs are gofmt'ed, so a template producing invalid Go is an error.
+// Other outputs end with a single newline and have runs of blank lines
+// collapsed to one, or to two in Python, where PEP 8 separates top-level
+// definitions with two.
+func Generate(file *ast.File, opts Options) ([]Output, error) {
+	if !slices.Contains(Languages, opts.Lang) {
+		return nil, fmt.Errorf("unknown language %q (want %s)", opts.Lang, strings.Join(Languages, ", "))
+	}
+	if opts.Package == "" {
+		opts.Package = DefaultPackage
+	}
+
+	tmpl, names, err := loadTemplates(opts.Lang, opts.Templates)
+	if err != nil {
+		return nil, err
+	}
+
+	design := NewDesign(file, opts)
+	var outputs []Output
+	paths := make(map[string]string) // output path to the queue producing it
+	for _, name := range names {
+		path := strings.TrimSuffix(name, ".tmpl")
+		if strings.HasPrefix(path, TypesModule+".") && !opts.Types {
+			continue
+		}
+		ext, isWorker := strings.CutPrefix(path, "worker.")
+		if !isWorker {
+			out, err := render(tmpl, name, path, design)
+			if err != nil {
+				return nil, err
+			}
+			outputs = append(outputs, out)
+			continue
+		}
+		if !opts.Workers {
+			continue
+		}
+		for _, q := range design.TaskQueues {
+			path := "worker_" + snake(q.Name) + "." + ext
+			if other, dup := paths[path]; dup {
+				return nil, fmt.Errorf("task queues %q and %q both generate %s", other, q.Name, path)
+			}
+			paths[path] = q.Name
+			out, err := render(tmpl, name, path, &QueueDesign{Design: design, Queue: q})
+			if err != nil {
+				return nil, err
+			}
+			outputs = append(outputs, out)
+		}
+	}
+	if opts.Types {
+		out, err := typeMap(design, opts.TypeModules)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, out)
+	}
+	out, err := identifierMap(design)
+	if err != nil {
+		return nil, err
+	}
+	return append(outputs, out), nil
+}
+
+// typeMap renders TypeMapFile: modules updated with the module of each
+// type design uses. Entries for types no longer used are kept, so a
+// type's hand-written module survives it briefly leaving the design.
+func typeMap(design *Design, modules map[string]string) (Output, error) {
+	merged := maps.Clone(modules)
+	if merged == nil {
+		merged = make(map[string]string)
+	}
+	for _, t := range design.Types {
+		merged[t.Name] = t.Module
+	}
+	data, err := json.MarshalIndent(merged, "", "  ")
+	if err != nil {
+		return Output{}, err
+	}
+	return Output{Path: TypeMapFile, Content: append(data, '\n')}, nil
+}
+
+// ReadTypeMap reads the TypeMapFile in dir for Options.TypeModules. A
+// missing file is an empty map.
+func ReadTypeMap(dir string) (map[string]string, error) {
+	modules, err := ReadTypeMapFile(filepath.Join(dir, TypeMapFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return modules, err
+}
+
+// ReadTypeMapFile is ReadTypeMap for a type map at path, which must exist.
+func ReadTypeMapFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var modules map[string]string
+	if err := json.Unmarshal(data, &modules); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return modules, nil
+}
+
+// render executes the named template into the output at path.
+func render(tmpl *template.Template, name, path string, data any) (Output, error) {
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+		return Output{}, err
+	}
+	content := buf.Bytes()
+	if strings.HasSuffix(path, ".go") {
+		formatted, err := format.Source(content)
+		if err != nil {
+			return Output{}, fmt.Errorf("%s: generated Go does not parse: %v", path, err)
+		}
+		content = formatted
+	} else {
+		content = tidy(content, strings.HasSuffix(path, ".py"))
+	}
+	return Output{Path: path, Content: content}, nil
+}
+
+// loadTemplates parses the built-in templates for lang, then those in dir,
+// so a file in dir replaces the built-in of the same name. It returns the
+// names of the templates that produce output files, sorted.
+func loadTemplates(lang, dir string) (*template.Template, []string, error) {
+	tmpl := template.New(lang).Funcs(Funcs)
+	var names []string
+	add := func(fsys fs.FS, pattern string) error {
+		paths, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return err
+		}
+		for _, path := range paths {
+			data, err := fs.ReadFile(fsys, path)
+			if err != nil {
+				return err
+			}
+			name := filepath.Base(path)
+			if _, err := tmpl.New(name).Parse(string(data)); err != nil {
+				return err
+			}
+			if !strings.HasPrefix(name, "_") && !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+		return nil
+	}
+
+	if err := add(builtin, "templates/"+lang+"/*.tmpl"); err != nil {
+		return nil, nil, err
+	}
+	if dir != "" {
+		if _, err := os.Stat(dir); err != nil {
+			return nil, nil, err
+		}
+		if err := add(os.DirFS(dir), "*.tmpl"); err != nil {
+			return nil, nil, err
+		}
+	}
+	slices.Sort(names)
+	return tmpl, names, nil
+}
+
+var (
+	blankLines       = regexp.MustCompile(`\n(?:[ \t]*\n){2,}`)
+	doubleBlankLines = regexp.MustCompile(`\n(?:[ \t]*\n){3,}`)
+)
+
+// tidy collapses runs of blank lines and trailing newlines, so templates
+// can space their blocks freely.
+func tidy(content []byte, python bool) []byte {
+	if python {
+		content = doubleBlankLines.ReplaceAll(content, []byte("\n\n\n"))
+	} else {
+		content = blankLines.ReplaceAll(content, []byte("\n\n"))
+	}
+	return append(bytes.TrimRight(content, "\n"), '\n')
+}
@@ -0,0 +1,667 @@
+package codegen
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/options"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/twftest"
+)
+
+const design = `@owner("payments")
+workflow OrderFulfillment(order: Order, items: map[string]Item) -> (OrderResult):
+    signal CancelOrder(reason: string):
+        activity Refund(order)
+    query GetStatus() -> (Status):
+        return status
+    activity ChargePayment(order) -> payment
+        options:
+            start_to_close_timeout: 30s
+            task_queue: "payments"
+            retry_policy:
+                maximum_attempts: 3
+    activity ChargePayment(order)
+        options:
+            start_to_close_timeout: 1m
+    workflow ShipOrder(order) id "ship-{order.id}"
+    for each (item in items) parallel(max: 4):
+        activity Refund(order)
+    close complete(OrderResult{})
+
+workflow ShipOrder(order: Order):
+    await activity Pack(order, 1500ms)
+
+activity ChargePayment(order: Order) -> (Payment):
+    return payment
+
+activity Refund(order: Order):
+    return
+
+activity Pack(order: Order, wait: duration = 30s, gift: bool = false) -> (Box, int):
+    return box, 1
+
+worker orderWorker:
+    workflow OrderFulfillment
+    workflow ShipOrder
+    activity ChargePayment
+    activity Refund
+
+worker packWorker:
+    activity Pack
+    activity Refund
+
+namespace prod:
+    worker orderWorker
+        options:
+            task_queue: "orders"
+    worker packWorker
+        options:
+            task_queue: "packing.v2"
+
+namespace staging:
+    worker orderWorker
+        options:
+            task_queue: "orders"
+    worker packWorker
+`
+
+func TestDesign(t *testing.T) {
+	d := NewDesign(twftest.AssertResolves(t, design), Options{Package: "orders"})
+
+	if len(d.Workflows) != 2 || len(d.Activities) != 3 {
+		t.Fatalf("expected 2 workflows and 3 activities, got %d and %d", len(d.Workflows), len(d.Activities))
+	}
+	wf := d.Workflows[0]
+	if len(wf.Params) != 2 || wf.Params[1] != (Param{Name: "items", Type: "map[string]Item"}) {
+		t.Errorf("unexpected params: %+v", wf.Params)
+	}
+	if strings.Join(wf.Activities, ",") != "Refund,ChargePayment" || strings.Join(wf.Children, ",") != "ShipOrder" {
+		t.Errorf("unexpected calls: %v %v", wf.Activities, wf.Children)
+	}
+	if len(wf.ChildIDs) != 1 || wf.ChildIDs[0] != (ChildID{Workflow: "ShipOrder", ID: "ship-{order.id}"}) {
+		t.Errorf("unexpected child IDs: %+v", wf.ChildIDs)
+	}
+	if len(wf.FanOuts) != 1 || wf.FanOuts[0] != (FanOut{Variable: "item", Iterable: "items", Max: "4"}) {
+		t.Errorf("unexpected fan-outs: %+v", wf.FanOuts)
+	}
+	if len(wf.Annotations) != 1 || wf.Annotations[0] != (Annotation{Name: "owner", Value: "payments"}) {
+		t.Errorf("unexpected annotations: %+v", wf.Annotations)
+	}
+	if len(wf.Queries) != 1 || wf.Queries[0].Results[0] != "Status" {
+		t.Errorf("unexpected queries: %+v", wf.Queries)
+	}
+	if got := d.Workflows[1].Activities; len(got) != 1 || got[0] != "Pack" {
+		t.Errorf("expected await targets to count as calls, got %v", got)
+	}
+
+	charge := d.Activities[0]
+	if len(charge.Options) != 3 || option("start_to_close_timeout", charge.Options) != "30s" {
+		t.Errorf("expected the options of the first call, got %+v", charge.Options)
+	}
+	if retry := charge.Options[2]; retry.Type != "nested" || retry.Nested[0].Key != "maximum_attempts" {
+		t.Errorf("unexpected nested option: %+v", retry)
+	}
+	if pack := d.Activities[2]; len(pack.Results) != 2 {
+		t.Errorf("expected two results, got %v", pack.Results)
+	}
+	if !d.ActivitiesUseDurations() || !d.WorkflowsUseDurations() {
+		t.Error("expected durations in both activity signatures and options")
+	}
+
+	if len(d.TaskQueues) != 2 {
+		t.Fatalf("expected 2 task queues, got %d", len(d.TaskQueues))
+	}
+	orders := d.TaskQueues[0]
+	if orders.Name != "orders" || strings.Join(orders.Namespaces, ",") != "prod,staging" || strings.Join(orders.Workers, ",") != "orderWorker" {
+		t.Errorf("unexpected queue: %+v", orders)
+	}
+	if len(orders.Workflows) != 2 || len(orders.Activities) != 2 || orders.Activities[1].Name != "Refund" {
+		t.Errorf("unexpected registrations: %+v %+v", orders.Workflows, orders.Activities)
+	}
+	if packing := d.TaskQueues[1]; packing.Name != "packing.v2" || len(packing.Workflows) != 0 || len(packing.Activities) != 2 {
+		t.Errorf("unexpected queue: %+v", packing)
+	}
+}
+
+func TestDesignOptions(t *testing.T) {
+	file := twftest.AssertResolves(t, `activity Charge():
+    options:
+        start_to_close_timeout: 30s
+    return
+
+activity Refund():
+    return
+
+workflow Order():
+    activity Refund()
+    activity Charge()
+        options:
+            start_to_close_timeout: 10s
+    close complete
+`)
+	defaults, err := options.ParseConfig([]byte(`{"activity": {"heartbeat_timeout": "5s"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := NewDesign(file, Options{OptionDefaults: defaults})
+	charge, refund := d.Activities[0], d.Activities[1]
+	if len(charge.Options) != 2 || option("heartbeat_timeout", charge.Options) != "5s" || option("start_to_close_timeout", charge.Options) != "10s" {
+		t.Errorf("expected the call's options over the definition's and the defaults, got %+v", charge.Options)
+	}
+	if len(refund.Options) != 1 || option("heartbeat_timeout", refund.Options) != "5s" {
+		t.Errorf("expected the defaults for an activity no call configures, got %+v", refund.Options)
+	}
+}
+
+func TestFuncs(t *testing.T) {
+	tests := []struct {
+		fn   func(string) string
+		in   string
+		want string
+	}{
+		{camel, "OrderID", "orderID"},
+		{camel, "start_to_close_timeout", "startToCloseTimeout"},
+		{pascal, "order_id", "OrderId"},
+		{pascal, "HTTPServer", "HTTPServer"},
+		{snake, "ChargePayment", "charge_payment"},
+		{snake, "HTTPServer2Go", "http_server2_go"},
+		{snake, "packing.v2", "packing_v2"},
+		{pascal, "orders-high", "OrdersHigh"},
+		{GoType, "[]map[string]duration", "[]map[string]time.Duration"},
+		{tsType, "map[string][]int", "Record<string, number[]>"},
+		{pyType, "[]string", "list[str]"},
+	}
+	for _, tt := range tests {
+		if got := tt.fn(tt.in); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	durations := map[string]string{"7d": "168 * time.Hour", "90s": "90 * time.Second", "1500ms": "1500 * time.Millisecond"}
+	for in, want := range durations {
+		if got, err := goDuration(in); err != nil || got != want {
+			t.Errorf("goDuration(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if ms, _ := durationMs("1m"); ms != 60000 {
+		t.Errorf("durationMs(1m) = %d", ms)
+	}
+	if s, _ := durationSeconds("1500ms"); s != "1.5" {
+		t.Errorf("durationSeconds(1500ms) = %s", s)
+	}
+	if _, err := goDuration("soon"); err == nil {
+		t.Error("expected an error for an invalid duration")
+	}
+	if got := goResults([]string{"Box", "int"}); got != "(result1 Box, result2 int, err error)" {
+		t.Errorf("unexpected Go results: %s", got)
+	}
+
+	ids := []struct {
+		fn   func(string) string
+		in   string
+		want string
+	}{
+		{goWorkflowID, "ship-{order.id}", `fmt.Sprintf("ship-%v", order.Id)`},
+		{goWorkflowID, "100%-{ n }", `fmt.Sprintf("100%%-%v", n)`},
+		{goWorkflowID, "nightly", `"nightly"`},
+		{tsWorkflowID, "ship-{order.orderID}", "`ship-${order.orderID}`"},
+		{tsWorkflowID, "`{items[0]}", "`\\`${items[0]}`"},
+		{pyWorkflowID, "ship-{Order.OrderID}", `f"ship-{order.order_id}"`},
+		{pyWorkflowID, "ship-{order", `f"ship-{{order"`},
+	}
+	for _, tt := range ids {
+		if got := tt.fn(tt.in); got != tt.want {
+			t.Errorf("%q: got %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateBuiltin(t *testing.T) {
+	file := twftest.AssertResolves(t, design)
+	want := map[string]map[string][]string{
+		"go": {
+			"activities.go": {"package orders", "// gift is optional in the design; callers omitting it pass false.\nfunc Pack(ctx context.Context, order Order, wait time.Duration, gift bool) (result1 Box, result2 int, err error) {"},
+			"workflows.go": {
+				"const OrderFulfillmentCancelOrderSignal = \"CancelOrder\"",
+				"func OrderFulfillment(ctx workflow.Context, order Order, items map[string]Item) (result OrderResult, err error) {",
+				"StartToCloseTimeout: 30 * time.Second,",
+				"// It starts ShipOrder with WorkflowID fmt.Sprintf(\"ship-%v\", order.Id).",
+				"// It fans out over items, at most 4 at a time: acquire workflow.NewSemaphore(ctx, 4) before starting each item.",
+			},
+			IdentifierMapFile: {`"activity:Pack/wait": "wait",`, `"workflow:OrderFulfillment": "OrderFulfillment",`},
+		},
+		"typescript": {
+			"activities.ts":   {"export async function pack(order: Order, wait: string = \"30s\", gift: boolean = false): Promise<[Box, number]> {"},
+			"workflows.ts":    {"startToCloseTimeout: 30000,", "wf.defineQuery<Status, []>('GetStatus');", " * It starts ShipOrder with workflowId `ship-${order.id}`."},
+			IdentifierMapFile: {`"activity:ChargePayment": "chargePayment",`},
+		},
+		"python": {
+			"activities.py":   {"async def charge_payment(order: Order) -> Payment:", "async def pack(order: Order, wait: timedelta = timedelta(seconds=30), gift: bool = False) -> tuple[Box, int]:"},
+			"workflows.py":    {"start_to_close_timeout=timedelta(seconds=30),", "    async def cancel_order(self, reason: str) -> None:", "    Starts ShipOrder with id=f\"ship-{order.id}\".", "with asyncio.Semaphore(4)."},
+			IdentifierMapFile: {`"signal:OrderFulfillment.CancelOrder": "cancel_order",`},
+		},
+	}
+	for lang, files := range want {
+		outputs, err := Generate(file, Options{Lang: lang, Package: "orders"})
+		if err != nil {
+			t.Fatalf("%s: %v", lang, err)
+		}
+		if len(outputs) != len(files) {
+			t.Errorf("%s: expected %d files, got %d", lang, len(files), len(outputs))
+		}
+		for _, out := range outputs {
+			content := string(out.Content)
+			for _, line := range files[out.Path] {
+				if !strings.Contains(content, line) {
+					t.Errorf("%s: expected %q in:\n%s", out.Path, line, content)
+				}
+			}
+			if strings.Contains(content, "\n\n\n\n") || strings.HasSuffix(content, "\n\n") {
+				t.Errorf("%s: untidy output:\n%s", out.Path, content)
+			}
+			if _, err := regions(strings.SplitAfter(content, "\n")); err != nil {
+				t.Errorf("%s: %v", out.Path, err)
+			}
+		}
+	}
+}
+
+func TestGenerateWorkers(t *testing.T) {
+	file := twftest.AssertResolves(t, design)
+	want := map[string]map[string][]string{
+		"go": {
+			"worker_orders.go": {
+				"const OrdersTaskQueue = \"orders\"",
+				"func NewOrdersWorker(c client.Client, options worker.Options) worker.Worker {",
+				"\tw.RegisterWorkflow(ShipOrder)\n\tw.RegisterActivity(ChargePayment)\n\tw.RegisterActivity(Refund)\n",
+			},
+			"worker_packing_v2.go": {"const PackingV2TaskQueue = \"packing.v2\"", "w.RegisterActivity(Pack)"},
+		},
+		"typescript": {
+			"worker_orders.ts":     {"taskQueue: ordersTaskQueue,", "workflowsPath: require.resolve('./workflows'),", "namespace = 'prod'"},
+			"worker_packing_v2.ts": {"export async function runPackingV2Worker(", "      pack: activities.pack,"},
+		},
+		"python": {
+			"worker_orders.py":     {"workflows=[workflows.OrderFulfillment, workflows.ShipOrder],"},
+			"worker_packing_v2.py": {"PACKING_V2_TASK_QUEUE = \"packing.v2\"", "activities=[activities.pack, activities.refund],"},
+		},
+	}
+	for lang, files := range want {
+		outputs, err := Generate(file, Options{Lang: lang, Workers: true})
+		if err != nil {
+			t.Fatalf("%s: %v", lang, err)
+		}
+		got := make(map[string]string)
+		for _, out := range outputs {
+			got[out.Path] = string(out.Content)
+		}
+		if len(got) != 5 {
+			t.Errorf("%s: expected stubs, two workers, and the identifier map, got %d files", lang, len(got))
+		}
+		for path, lines := range files {
+			for _, line := range lines {
+				if !strings.Contains(got[path], line) {
+					t.Errorf("%s: expected %q in:\n%s", path, line, got[path])
+				}
+			}
+		}
+		if lang == "typescript" && strings.Contains(got["worker_packing_v2.ts"], "workflowsPath:") {
+			t.Error("expected no workflow bundle for a queue without workflows")
+		}
+	}
+}
+
+func TestGenerateTypes(t *testing.T) {
+	file := twftest.AssertResolves(t, design)
+	modules := map[string]string{"Order": "models", "Legacy": "legacy"}
+	want := map[string]map[string][]string{
+		"go": {"types.go": {"type OrderResult struct {", "type Box struct {"}},
+		"typescript": {
+			"types.ts":      {"export interface Item {"},
+			"activities.ts": {"import type { Order } from './models';\nimport type { Box, Payment } from './types';\n"},
+			"workflows.ts":  {"import type { Item, OrderResult, Status } from './types';"},
+		},
+		"python": {
+			"types.py":     {"@dataclass\nclass Status:\n"},
+			"workflows.py": {"from .models import Order\nfrom .types import Item, OrderResult, Status\n"},
+		},
+	}
+	for lang, files := range want {
+		outputs, err := Generate(file, Options{Lang: lang, Types: true, TypeModules: modules})
+		if err != nil {
+			t.Fatalf("%s: %v", lang, err)
+		}
+		got := make(map[string]string)
+		for _, out := range outputs {
+			got[out.Path] = string(out.Content)
+		}
+		for path, lines := range files {
+			for _, line := range lines {
+				if !strings.Contains(got[path], line) {
+					t.Errorf("%s: expected %q in:\n%s", path, line, got[path])
+				}
+			}
+		}
+		for path, content := range got {
+			if strings.HasPrefix(path, "types.") && strings.Contains(content, " Order ") {
+				t.Errorf("%s: expected no stub for a type mapped to another module:\n%s", path, content)
+			}
+		}
+		wantMap := `{
+  "Box": "types",
+  "Item": "types",
+  "Legacy": "legacy",
+  "Order": "models",
+  "OrderResult": "types",
+  "Payment": "types",
+  "Status": "types"
+}
+`
+		if got[TypeMapFile] != wantMap {
+			t.Errorf("%s: unexpected type map:\n%s", lang, got[TypeMapFile])
+		}
+	}
+}
+
+func TestReadTypeMap(t *testing.T) {
+	dir := t.TempDir()
+	if modules, err := ReadTypeMap(dir); err != nil || modules != nil {
+		t.Errorf("expected no map for a missing file, got %v, %v", modules, err)
+	}
+	os.WriteFile(filepath.Join(dir, TypeMapFile), []byte(`{"Order": "models"}`), 0o644)
+	if modules, err := ReadTypeMap(dir); err != nil || modules["Order"] != "models" {
+		t.Errorf("unexpected map: %v, %v", modules, err)
+	}
+	os.WriteFile(filepath.Join(dir, TypeMapFile), []byte(`["Order"]`), 0o644)
+	if _, err := ReadTypeMap(dir); err == nil || !strings.Contains(err.Error(), TypeMapFile) {
+		t.Errorf("expected a decode error naming the file, got %v", err)
+	}
+}
+
+func TestGenerateTemplateDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"_helpers.tmpl":      `{{define "names"}}{{range .Workflows}}{{.Name}} {{end}}{{end}}`,
+		"workflows.go.tmpl":  "package {{.Package}}\n\n// Workflows: {{template \"names\" .}}\n",
+		"registry.json.tmpl": `{"activities": [{{range $i, $a := .Activities}}{{if $i}}, {{end}}"{{snake $a.Name}}"{{end}}]}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	outputs, err := Generate(twftest.AssertResolves(t, design), Options{Lang: "go", Templates: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for _, out := range outputs {
+		got[out.Path] = string(out.Content)
+	}
+	if len(got) != 4 || !strings.Contains(got["activities.go"], "func ChargePayment(") || got[IdentifierMapFile] == "" {
+		t.Errorf("expected the built-in activities.go and the identifier map alongside the custom files, got %v", got)
+	}
+	if got["workflows.go"] != "package workflows\n\n// Workflows: OrderFulfillment ShipOrder\n" {
+		t.Errorf("expected the replaced workflows.go, got %q", got["workflows.go"])
+	}
+	if got["registry.json"] != `{"activities": ["charge_payment", "refund", "pack"]}`+"\n" {
+		t.Errorf("unexpected added output: %q", got["registry.json"])
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	file := twftest.AssertResolves(t, design)
+	if _, err := Generate(file, Options{Lang: "rust"}); err == nil || !strings.Contains(err.Error(), `unknown language "rust"`) {
+		t.Errorf("expected an unknown language error, got %v", err)
+	}
+	if _, err := Generate(file, Options{Lang: "go", Templates: filepath.Join(t.TempDir(), "missing")}); err == nil {
+		t.Error("expected an error for a missing template directory")
+	}
+
+	clash := twftest.AssertResolves(t, `activity A():
+    return
+
+worker w:
+    activity A
+
+namespace prod:
+    worker w
+        options:
+            task_queue: "orders-v2"
+    worker w
+        options:
+            task_queue: "orders.v2"
+`)
+	if _, err := Generate(clash, Options{Lang: "go", Workers: true}); err == nil || !strings.Contains(err.Error(), `task queues "orders-v2" and "orders.v2" both generate worker_orders_v2.go`) {
+		t.Errorf("expected a worker file collision, got %v", err)
+	}
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "workflows.go.tmpl"), []byte("package {{.Package}}\nfunc {"), 0o644)
+	if _, err := Generate(file, Options{Lang: "go", Templates: dir}); err == nil || !strings.Contains(err.Error(), "workflows.go: generated Go does not parse") {
+		t.Errorf("expected a Go syntax error, got %v", err)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	existing := `package orders
+
+// twf:begin custom imports
+import "log"
+// twf:end custom imports
+
+func Charge() {
+	// twf:begin custom Charge
+	log.Println("charged")
+	// twf:end custom Charge
+}
+
+func Refund() {
+	// twf:begin custom Refund
+	log.Println("refunded")
+	// twf:end custom
+}
+
+# twf:begin custom
+unnamed
+# twf:end custom
+`
+	generated := `package orders
+
+// twf:begin custom imports
+// twf:end custom imports
+
+func Charge(amount int) {
+	// twf:begin custom Charge
+	// TODO: implement
+	// twf:end custom Charge
+}
+
+func Ship() {
+	// twf:begin custom Ship
+	// TODO: implement
+	// twf:end custom Ship
+}
+
+# twf:begin custom
+# twf:end custom
+`
+	want := `package orders
+
+// twf:begin custom imports
+import "log"
+// twf:end custom imports
+
+func Charge(amount int) {
+	// twf:begin custom Charge
+	log.Println("charged")
+	// twf:end custom Charge
+}
+
+func Ship() {
+	// twf:begin custom Ship
+	// TODO: implement
+	// twf:end custom Ship
+}
+
+# twf:begin custom
+unnamed
+# twf:end custom
+`
+	merged, dropped, err := Merge([]byte(generated), []byte(existing))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(merged) != want {
+		t.Errorf("unexpected merge:\n%s", merged)
+	}
+	if len(dropped) != 1 || dropped[0] != "Refund" {
+		t.Errorf("expected Refund to be dropped, got %v", dropped)
+	}
+
+	errors := map[string]string{
+		"// twf:begin custom A\n":                                        `line 1: region "A" is never ended`,
+		"// twf:end custom\n":                                            "line 1: twf:end custom without twf:begin custom",
+		"// twf:begin custom A\n// twf:end custom B\n":                   `line 2: twf:end custom B ends region "A"`,
+		"// twf:begin custom A\n// twf:begin custom B\n":                 `line 2: region "A" is not ended before the next begins`,
+		"# twf:begin custom A\n# twf:end custom\n# twf:begin custom A\n": `line 3: duplicate region "A"`,
+	}
+	for existing, want := range errors {
+		if _, _, err := Merge([]byte(generated), []byte(existing)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: expected %q, got %v", existing, want, err)
+		}
+	}
+}
+
+func TestBuildSourceMap(t *testing.T) {
+	file := twftest.AssertResolves(t, design)
+	outputs, err := Generate(file, Options{Lang: "python"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := BuildSourceMap(file, outputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Path != SourceMapFile {
+		t.Errorf("path = %q, want %q", out.Path, SourceMapFile)
+	}
+	var sm SourceMap
+	if err := json.Unmarshal(out.Content, &sm); err != nil {
+		t.Fatal(err)
+	}
+	byNode := make(map[string]Mapping)
+	for _, m := range sm.Mappings {
+		byNode[m.Node] = m
+	}
+	for _, node := range []string{"workflow:OrderFulfillment", "signal:OrderFulfillment.CancelOrder", "query:OrderFulfillment.GetStatus", "workflow:ShipOrder", "activity:ChargePayment", "activity:Refund"} {
+		if _, ok := byNode[node]; !ok {
+			t.Errorf("missing mapping for %s in %+v", node, sm.Mappings)
+		}
+	}
+	if len(sm.Mappings) != len(byNode) || sm.Version != SourceMapVersion {
+		t.Errorf("unexpected source map: %+v", sm)
+	}
+
+	// The lines are those of the region in the file, and the position that
+	// of the definition in the design.
+	m := byNode["signal:OrderFulfillment.CancelOrder"]
+	if m.Line != 3 || m.Column != 5 || m.Path != "workflows.py" {
+		t.Errorf("unexpected signal mapping: %+v", m)
+	}
+	for _, o := range outputs {
+		if o.Path != m.Path {
+			continue
+		}
+		lines := strings.Split(string(o.Content), "\n")
+		if !strings.Contains(lines[m.StartLine-1], "twf:begin custom OrderFulfillment.CancelOrder") || !strings.Contains(lines[m.EndLine-1], "twf:end custom") {
+			t.Errorf("lines %d-%d are not the region:\n%s", m.StartLine, m.EndLine, strings.Join(lines[m.StartLine-1:m.EndLine], "\n"))
+		}
+	}
+}
+
+const collidingDesign = `workflow Order(type: string, ctx: int) -> (Order):
+    signal run(x: int):
+        return
+    activity Delete(type)
+    close complete(Order{})
+
+activity Delete(class: string):
+    return
+`
+
+func TestIdentifiers(t *testing.T) {
+	file := twftest.AssertResolves(t, collidingDesign)
+	want := map[string]map[string]string{
+		"go": {
+			"workflow:Order":        "Order2",
+			"workflow:Order/type":   "type_",
+			"workflow:Order/ctx":    "ctx2",
+			"signal:Order.run":      "Run",
+			"activity:Delete":       "Delete",
+			"activity:Delete/class": "class",
+		},
+		"typescript": {
+			"workflow:Order":        "Order2",
+			"workflow:Order/type":   "type",
+			"activity:Delete":       "delete_",
+			"activity:Delete/class": "class_",
+		},
+		"python": {
+			"workflow:Order":        "Order_2",
+			"signal:Order.run":      "run_2",
+			"signal:Order.run/x":    "x",
+			"activity:Delete":       "delete",
+			"activity:Delete/class": "class_",
+		},
+	}
+	for lang, idents := range want {
+		d := NewDesign(file, Options{Lang: lang})
+		for key, id := range idents {
+			if d.Identifiers[key] != id {
+				t.Errorf("%s: %s = %q, want %q", lang, key, d.Identifiers[key], id)
+			}
+		}
+		if !d.Workflows[0].Renamed() {
+			t.Errorf("%s: expected workflow Order, colliding with type Order, to be renamed", lang)
+		}
+	}
+
+	outputs, err := Generate(file, Options{Lang: "go", Workers: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, out := range outputs {
+		if out.Path == "workflows.go" && !strings.Contains(string(out.Content), "func Order2(ctx workflow.Context, type_ string, ctx2 int) (result Order, err error) {") {
+			t.Errorf("expected mangled identifiers in:\n%s", out.Content)
+		}
+	}
+}
+
+func TestIdentifiersStable(t *testing.T) {
+	before := NewDesign(twftest.AssertResolves(t, "activity Delete():\n    return\n"), Options{Lang: "typescript"})
+	if got := before.Activities[0].Ident; got != "delete_" {
+		t.Fatalf("Delete = %q, want delete_", got)
+	}
+
+	// A new activity before Delete, with the same spelling, would take
+	// delete_ in source order; the previous map keeps it for Delete.
+	after := twftest.AssertResolves(t, "activity delete():\n    return\n\nactivity Delete():\n    return\n")
+	if d := NewDesign(after, Options{Lang: "typescript"}); d.Activities[0].Ident != "delete_" {
+		t.Errorf("without a map, expected source order to decide, got %q", d.Activities[0].Ident)
+	}
+	d := NewDesign(after, Options{Lang: "typescript", Identifiers: before.Identifiers})
+	if d.Activities[0].Ident != "delete_2" || d.Activities[1].Ident != "delete_" {
+		t.Errorf("expected Delete to keep delete_, got %q and %q", d.Activities[0].Ident, d.Activities[1].Ident)
+	}
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, IdentifierMapFile), []byte(`{"activity:Delete": "delete-it"}`), 0o644)
+	if _, err := ReadIdentifierMap(dir); err == nil || !strings.Contains(err.Error(), "not an identifier") {
+		t.Errorf("expected an error for an invalid identifier, got %v", err)
+	}
+}
@@ -0,0 +1,369 @@
+package codegen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/eval"
+)
+
+// Funcs are the helper functions available to templates, in addition to
+// the text/template builtins.
+var Funcs = template.FuncMap{
+	"camel":  camel,
+	"pascal": pascal,
+	"snake":  snake,
+	"upper":  strings.ToUpper,
+	"lower":  strings.ToLower,
+	"join":   func(sep string, elems []string) string { return strings.Join(elems, sep) },
+	"option": option,
+
+	"goType":    GoType,
+	"goResults": goResults,
+	"tsType":    tsType,
+	"tsResult":  tsResult,
+	"pyType":    pyType,
+	"pyResult":  pyResult,
+
+	"goDuration":      goDuration,
+	"durationMs":      durationMs,
+	"durationSeconds": durationSeconds,
+
+	"tsDefault": tsDefault,
+	"pyDefault": pyDefault,
+
+	"goWorkflowID": goWorkflowID,
+	"tsWorkflowID": tsWorkflowID,
+	"pyWorkflowID": pyWorkflowID,
+}
+
+// words splits an identifier into words at characters other than letters
+// and digits and at case changes, keeping acronyms together: "OrderID" is
+// Order, ID, and "orders.high-v2" is orders, high, v2.
+func words(s string) []string {
+	var out []string
+	runes := []rune(s)
+	start := -1
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if start >= 0 {
+				out = append(out, string(runes[start:i]))
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+			continue
+		}
+		prev := runes[i-1]
+		lowerNext := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+		if unicode.IsUpper(r) && (unicode.IsLower(prev) || unicode.IsDigit(prev) || unicode.IsUpper(prev) && lowerNext) {
+			out = append(out, string(runes[start:i]))
+			start = i
+		}
+	}
+	if start >= 0 {
+		out = append(out, string(runes[start:]))
+	}
+	return out
+}
+
+// pascal joins the words of s with each capitalized: order_id is OrderId.
+func pascal(s string) string {
+	var b strings.Builder
+	for _, w := range words(s) {
+		r := []rune(w)
+		b.WriteString(strings.ToUpper(string(r[0])) + string(r[1:]))
+	}
+	return b.String()
+}
+
+// camel is pascal with the first word lowercased: OrderID is orderID.
+func camel(s string) string {
+	ws := words(s)
+	if len(ws) == 0 {
+		return ""
+	}
+	return strings.ToLower(ws[0]) + pascal(strings.Join(ws[1:], "_"))
+}
+
+// snake joins the lowercased words of s with underscores: OrderID is
+// order_id.
+func snake(s string) string {
+	ws := words(s)
+	for i, w := range ws {
+		ws[i] = strings.ToLower(w)
+	}
+	return strings.Join(ws, "_")
+}
+
+// option returns the value of the option with key, or "".
+func option(key string, opts []Option) string {
+	for _, o := range opts {
+		if o.Key == key {
+			return o.Value
+		}
+	}
+	return ""
+}
+
+// mapType rewrites the list, map, and scalar types of a TWF type using the
+// given target spellings.
+func mapType(t string, scalars map[string]string, list func(elem string) string, dict func(key, value string) string) string {
+	t = strings.TrimSpace(t)
+	if elem, ok := strings.CutPrefix(t, "[]"); ok {
+		return list(mapType(elem, scalars, list, dict))
+	}
+	if rest, ok := strings.CutPrefix(t, "map["); ok {
+		if key, value, ok := strings.Cut(rest, "]"); ok {
+			return dict(mapType(key, scalars, list, dict), mapType(value, scalars, list, dict))
+		}
+	}
+	if s, ok := scalars[t]; ok {
+		return s
+	}
+	return t
+}
+
+var goScalars = map[string]string{"": "any", "float": "float64", "number": "float64", "duration": "time.Duration"}
+
+// GoType spells a TWF type in Go, as the generated Go stubs do.
+func GoType(t string) string {
+	return mapType(t, goScalars,
+		func(elem string) string { return "[]" + elem },
+		func(key, value string) string { return "map[" + key + "]" + value })
+}
+
+// goResults returns the result list of a Go function returning results and
+// an error, with named results so a stub body can be a bare return.
+func goResults(results []string) string {
+	var parts []string
+	for i, r := range results {
+		name := "result"
+		if len(results) > 1 {
+			name += strconv.Itoa(i + 1)
+		}
+		parts = append(parts, name+" "+GoType(r))
+	}
+	return "(" + strings.Join(append(parts, "err error"), ", ") + ")"
+}
+
+var tsScalars = map[string]string{"": "unknown", "int": "number", "float": "number", "bool": "boolean", "duration": "string", "any": "unknown"}
+
+// tsType spells a TWF type in TypeScript. Durations are strings such as
+// "30s", which the TypeScript SDK accepts.
+func tsType(t string) string {
+	return mapType(t, tsScalars,
+		func(elem string) string { return elem + "[]" },
+		func(key, value string) string { return "Record<" + key + ", " + value + ">" })
+}
+
+// tsResult returns the promised result of an async TypeScript function.
+func tsResult(results []string) string {
+	switch len(results) {
+	case 0:
+		return "Promise<void>"
+	case 1:
+		return "Promise<" + tsType(results[0]) + ">"
+	}
+	types := make([]string, len(results))
+	for i, r := range results {
+		types[i] = tsType(r)
+	}
+	return "Promise<[" + strings.Join(types, ", ") + "]>"
+}
+
+var pyScalars = map[string]string{"": "Any", "string": "str", "number": "float", "duration": "timedelta", "any": "Any"}
+
+// pyType spells a TWF type in Python.
+func pyType(t string) string {
+	return mapType(t, pyScalars,
+		func(elem string) string { return "list[" + elem + "]" },
+		func(key, value string) string { return "dict[" + key + ", " + value + "]" })
+}
+
+// pyResult returns the return annotation of a Python function.
+func pyResult(results []string) string {
+	switch len(results) {
+	case 0:
+		return "None"
+	case 1:
+		return pyType(results[0])
+	}
+	types := make([]string, len(results))
+	for i, r := range results {
+		types[i] = pyType(r)
+	}
+	return "tuple[" + strings.Join(types, ", ") + "]"
+}
+
+func parseDuration(lit string) (time.Duration, error) {
+	d, ok := eval.ParseDuration(lit)
+	if !ok {
+		return 0, fmt.Errorf("invalid duration %q", lit)
+	}
+	return d, nil
+}
+
+// goDuration spells a TWF duration as a Go expression in its largest exact
+// unit: 7d is 168 * time.Hour and 90s is 90 * time.Second.
+func goDuration(lit string) (string, error) {
+	d, err := parseDuration(lit)
+	if err != nil {
+		return "", err
+	}
+	units := []struct {
+		unit time.Duration
+		name string
+	}{
+		{time.Hour, "time.Hour"},
+		{time.Minute, "time.Minute"},
+		{time.Second, "time.Second"},
+		{time.Millisecond, "time.Millisecond"},
+	}
+	for _, u := range units {
+		if d%u.unit == 0 {
+			return fmt.Sprintf("%d * %s", d/u.unit, u.name), nil
+		}
+	}
+	return fmt.Sprintf("time.Duration(%d)", d), nil
+}
+
+// durationMs converts a TWF duration to whole milliseconds.
+func durationMs(lit string) (int64, error) {
+	d, err := parseDuration(lit)
+	return d.Milliseconds(), err
+}
+
+// durationSeconds converts a TWF duration to seconds, with a fraction only
+// when needed.
+func durationSeconds(lit string) (string, error) {
+	d, err := parseDuration(lit)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64), nil
+}
+
+// tsDefault spells the default of a parameter in TypeScript. Durations
+// are strings there, and other values are written the same way.
+func tsDefault(p Param) string {
+	if p.Type == "duration" {
+		return strconv.Quote(p.Default)
+	}
+	return p.Default
+}
+
+// pyDefault spells the default of a parameter in Python: true is True,
+// null is None, and a duration is a timedelta.
+func pyDefault(p Param) (string, error) {
+	switch p.Default {
+	case "true":
+		return "True", nil
+	case "false":
+		return "False", nil
+	case "null", "nil":
+		return "None", nil
+	}
+	if p.Type == "duration" {
+		secs, err := durationSeconds(p.Default)
+		if err != nil {
+			return "", err
+		}
+		return "timedelta(seconds=" + secs + ")", nil
+	}
+	return p.Default, nil
+}
+
+// idTemplate splits a workflow ID template into its literal text and the
+// expressions of its {expr} placeholders, with one more literal than
+// expressions. An unterminated placeholder is literal text.
+func idTemplate(id string) (lits, exprs []string) {
+	for {
+		open := strings.IndexByte(id, '{')
+		end := strings.IndexByte(id[max(open, 0):], '}')
+		if open < 0 || end < 0 {
+			return append(lits, id), exprs
+		}
+		lits = append(lits, id[:open])
+		exprs = append(exprs, strings.TrimSpace(id[open+1:open+end]))
+		id = id[open+end+1:]
+	}
+}
+
+// idExpr spells a placeholder such as order.id in the target language,
+// recasing the variable with root and the fields with field. Other
+// expressions are kept as written.
+func idExpr(expr string, root, field func(string) string) string {
+	parts := strings.Split(expr, ".")
+	for _, p := range parts {
+		if !isIdent(p) {
+			return expr
+		}
+	}
+	out := []string{root(parts[0])}
+	for _, p := range parts[1:] {
+		out = append(out, field(p))
+	}
+	return strings.Join(out, ".")
+}
+
+// goWorkflowID spells a workflow ID template as a Go expression:
+// "ship-{order.id}" is fmt.Sprintf("ship-%v", order.Id).
+func goWorkflowID(id string) string {
+	lits, exprs := idTemplate(id)
+	if len(exprs) == 0 {
+		return strconv.Quote(id)
+	}
+	var format strings.Builder
+	args := make([]string, len(exprs))
+	for i, expr := range exprs {
+		format.WriteString(strings.ReplaceAll(lits[i], "%", "%%") + "%v")
+		args[i] = idExpr(expr, camel, pascal)
+	}
+	format.WriteString(strings.ReplaceAll(lits[len(exprs)], "%", "%%"))
+	return fmt.Sprintf("fmt.Sprintf(%s, %s)", strconv.Quote(format.String()), strings.Join(args, ", "))
+}
+
+// tsWorkflowID spells a workflow ID template as a TypeScript template
+// literal: "ship-{order.id}" is `ship-${order.id}`.
+func tsWorkflowID(id string) string {
+	lits, exprs := idTemplate(id)
+	escape := strings.NewReplacer("\\", "\\\\", "`", "\\`", "${", "\\${").Replace
+	var b strings.Builder
+	b.WriteByte('`')
+	for i, expr := range exprs {
+		b.WriteString(escape(lits[i]) + "${" + idExpr(expr, camel, camel) + "}")
+	}
+	b.WriteString(escape(lits[len(exprs)]) + "`")
+	return b.String()
+}
+
+// pyWorkflowID spells a workflow ID template as a Python f-string:
+// "ship-{order.id}" is f"ship-{order.id}".
+func pyWorkflowID(id string) string {
+	lits, exprs := idTemplate(id)
+	escape := strings.NewReplacer("\\", "\\\\", `"`, `\"`, "{", "{{", "}", "}}").Replace
+	var b strings.Builder
+	b.WriteString(`f"`)
+	for i, expr := range exprs {
+		b.WriteString(escape(lits[i]) + "{" + idExpr(expr, snake, snake) + "}")
+	}
+	b.WriteString(escape(lits[len(exprs)]) + `"`)
+	return b.String()
+}
+
+// isIdent reports whether s is a plain identifier.
+func isIdent(s string) bool {
+	for i, r := range s {
+		if !(r == '_' || unicode.IsLetter(r) || i > 0 && unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,349 @@
+package codegen
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"unicode"
+)
+
+// IdentifierMapFile is the output mapping each name of the design to the
+// identifier generated for it. Reading it back into Options.Identifiers
+// keeps regeneration from renaming code when a new name collides with an
+// old one.
+const IdentifierMapFile = "twf-identifiers.json"
+
+// language holds the identifier rules of a target language.
+type language struct {
+	// reserved are the keywords, the predeclared names, and the names the
+	// built-in templates import, which no identifier may shadow. A
+	// reserved name gets an underscore appended.
+	reserved map[string]bool
+	// workflow, activity, handler, param, and queue spell a name in the
+	// case of its kind of identifier.
+	workflow, activity, handler, param, queue func(string) string
+	// paramsReserved returns the parameter names the templates take for
+	// themselves in a function with the given number of results, or in a
+	// method: ctx and the named results in Go, self in Python methods.
+	paramsReserved func(results int, method bool) []string
+	// sharedScope reports whether workflows and activities are declared in
+	// one scope, as in a Go package or the TypeScript workflows module,
+	// which imports the activities it proxies.
+	sharedScope bool
+	// methods reports whether handlers are methods of the workflow's class,
+	// beside run, rather than the suffixes of constants.
+	methods bool
+	// numberSep goes between an identifier and the number resolving a
+	// collision.
+	numberSep string
+}
+
+func keep(s string) string { return s }
+
+var languages = map[string]*language{
+	"go": {
+		reserved: reservedSet(
+			// keywords
+			"break", "case", "chan", "const", "continue", "default", "defer", "else", "fallthrough",
+			"for", "func", "go", "goto", "if", "import", "interface", "map", "package", "range",
+			"return", "select", "struct", "switch", "type", "var",
+			// predeclared
+			"any", "bool", "byte", "comparable", "complex64", "complex128", "error", "float32",
+			"float64", "int", "int8", "int16", "int32", "int64", "rune", "string", "uint", "uint8",
+			"uint16", "uint32", "uint64", "uintptr", "true", "false", "iota", "nil", "append", "cap",
+			"clear", "close", "complex", "copy", "delete", "imag", "len", "make", "max", "min", "new",
+			"panic", "print", "println", "real", "recover",
+			// imports
+			"activity", "client", "context", "time", "worker", "workflow"),
+		workflow:       keep,
+		activity:       keep,
+		handler:        pascal,
+		param:          camel,
+		queue:          pascal,
+		paramsReserved: goParamsReserved,
+		sharedScope:    true,
+	},
+	"typescript": {
+		reserved: reservedSet(
+			"arguments", "await", "break", "case", "catch", "class", "const", "continue", "debugger",
+			"default", "delete", "do", "else", "enum", "eval", "export", "extends", "false", "finally",
+			"for", "function", "if", "implements", "import", "in", "instanceof", "interface", "let",
+			"new", "null", "package", "private", "protected", "public", "return", "static", "super",
+			"switch", "this", "throw", "true", "try", "typeof", "undefined", "var", "void", "while",
+			"with", "yield",
+			// imports
+			"activities", "NativeConnection", "require", "wf", "Worker"),
+		workflow:    keep,
+		activity:    camel,
+		handler:     pascal,
+		param:       camel,
+		queue:       camel,
+		sharedScope: true,
+	},
+	"python": {
+		reserved: reservedSet(
+			"False", "None", "True", "and", "as", "assert", "async", "await", "break", "class",
+			"continue", "def", "del", "elif", "else", "except", "finally", "for", "from", "global",
+			"if", "import", "in", "is", "lambda", "nonlocal", "not", "or", "pass", "raise", "return",
+			"try", "while", "with", "yield",
+			// builtins the stubs annotate with
+			"bool", "dict", "float", "int", "list", "str", "tuple",
+			// imports
+			"activities", "activity", "Any", "Client", "dataclass", "timedelta", "Worker", "workflow",
+			"workflows"),
+		workflow:       keep,
+		activity:       snake,
+		handler:        snake,
+		param:          snake,
+		queue:          snake,
+		paramsReserved: pyParamsReserved,
+		methods:        true,
+		numberSep:      "_",
+	},
+}
+
+// goParamsReserved returns ctx and the named results of goResults.
+func goParamsReserved(results int, _ bool) []string {
+	names := []string{"ctx", "err", "result"}
+	for i := range results {
+		names = append(names, "result"+strconv.Itoa(i+1))
+	}
+	return names
+}
+
+// pyParamsReserved returns self for the methods of workflow classes.
+func pyParamsReserved(_ int, method bool) []string {
+	if method {
+		return []string{"self"}
+	}
+	return nil
+}
+
+func reservedSet(names ...string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, n := range names {
+		set[n] = true
+	}
+	return set
+}
+
+// identifier spells name with spell and makes the result an identifier
+// that is not reserved: "order-id" is order_id in Python, "1st" is _1st,
+// and "type" is type_.
+func (l *language) identifier(name string, spell func(string) string) string {
+	s := spell(name)
+	if s != "" && unicode.IsDigit([]rune(s)[0]) {
+		s = "_" + s
+	}
+	if !isIdent(s) {
+		s = pascal(s)
+		if s != "" && unicode.IsDigit([]rune(s)[0]) {
+			s = "_" + s
+		}
+	}
+	if s == "" || s == "_" {
+		s = "v"
+	}
+	if l.reserved[s] {
+		s += "_"
+	}
+	return s
+}
+
+// namer assigns identifiers to the names of a design, each in a scope
+// where no two identifiers may be equal. Identifiers recorded by a previous
+// generation are kept when they are still free, so adding a name renames
+// only the new identifier; the rest are numbered in request order, the
+// first keeping its spelling and the next getting 2, 3, and so on.
+type namer struct {
+	lang     *language
+	previous map[string]string
+	taken    map[string]map[string]bool // identifiers by scope
+	pending  []identRequest
+	// assigned maps each key to its identifier, for IdentifierMapFile.
+	assigned map[string]string
+}
+
+type identRequest struct {
+	scope, key, base string
+	dst              *string
+}
+
+// ident is the identifier of a name, and the spelling it would have if
+// nothing were reserved or colliding.
+type ident struct {
+	Ident   string
+	spelled string
+}
+
+// Renamed reports whether the identifier differs from its plain spelling,
+// as when it would collide with another or is a keyword.
+func (i ident) Renamed() bool {
+	return i.Ident != i.spelled
+}
+
+func newNamer(lang *language, previous map[string]string) *namer {
+	return &namer{lang: lang, previous: previous, taken: make(map[string]map[string]bool), assigned: make(map[string]string)}
+}
+
+// reserve takes idents in scope before any request is resolved.
+func (n *namer) reserve(scope string, idents ...string) {
+	if n.taken[scope] == nil {
+		n.taken[scope] = make(map[string]bool)
+	}
+	for _, id := range idents {
+		n.taken[scope][id] = true
+	}
+}
+
+// request asks for an identifier spelling name with spell in scope, to be
+// stored in dst by resolve. key names the request in IdentifierMapFile.
+func (n *namer) request(scope, key, name string, spell func(string) string, dst *ident) {
+	dst.spelled = spell(name)
+	n.pending = append(n.pending, identRequest{scope: scope, key: key, base: n.lang.identifier(name, spell), dst: &dst.Ident})
+}
+
+// resolve assigns the pending requests, first those whose previous
+// identifier is still free and then the rest in order.
+func (n *namer) resolve() {
+	var fresh []identRequest
+	for _, r := range n.pending {
+		n.reserve(r.scope)
+		if prev, ok := n.previous[r.key]; ok && isIdent(prev) && !n.lang.reserved[prev] && !n.taken[r.scope][prev] {
+			n.assign(r, prev)
+		} else {
+			fresh = append(fresh, r)
+		}
+	}
+	for _, r := range fresh {
+		id := r.base
+		sep := n.lang.numberSep
+		if last := id[len(id)-1]; last >= '0' && last <= '9' {
+			sep = "_"
+		}
+		for i := 2; n.taken[r.scope][id]; i++ {
+			id = r.base + sep + strconv.Itoa(i)
+		}
+		n.assign(r, id)
+	}
+	n.pending = nil
+}
+
+func (n *namer) assign(r identRequest, id string) {
+	n.taken[r.scope][id] = true
+	*r.dst = id
+	n.assigned[r.key] = id
+}
+
+// nameIdentifiers sets the identifiers of d's definitions, handlers,
+// parameters, and task queues in lang.
+func (d *Design) nameIdentifiers(lang *language, previous map[string]string) {
+	n := newNamer(lang, previous)
+	types := d.workflowTypeNames(d.activityTypeNames(nil))
+	workflowScope, activityScope := "workflows", "activities"
+	if lang.sharedScope {
+		activityScope = workflowScope
+	}
+	n.reserve(workflowScope, types...)
+	n.reserve(activityScope, types...)
+	for _, wf := range d.Workflows {
+		n.request(workflowScope, "workflow:"+wf.Name, wf.Name, lang.workflow, &wf.ident)
+	}
+	for _, act := range d.Activities {
+		n.request(activityScope, "activity:"+act.Name, act.Name, lang.activity, &act.ident)
+	}
+	for _, q := range d.TaskQueues {
+		n.request("queues", "queue:"+q.Name, q.Name, lang.queue, &q.ident)
+	}
+	n.resolve()
+
+	params := func(owner string, ps []Param, results []string, method bool) {
+		if lang.paramsReserved != nil {
+			n.reserve(owner, lang.paramsReserved(len(results), method)...)
+		}
+		for i := range ps {
+			n.request(owner, owner+"/"+ps[i].Name, ps[i].Name, lang.param, &ps[i].ident)
+		}
+	}
+	for _, wf := range d.Workflows {
+		owner := "workflow:" + wf.Name
+		params(owner, wf.Params, wf.Results, true)
+		for _, group := range []struct {
+			kind     string
+			handlers []*Handler
+		}{{"signal", wf.Signals}, {"query", wf.Queries}, {"update", wf.Updates}} {
+			scope := owner + "/" + group.kind
+			if lang.methods {
+				scope = owner + "/methods"
+				n.reserve(scope, "run")
+			}
+			for _, h := range group.handlers {
+				node := group.kind + ":" + wf.Name + "." + h.Name
+				n.request(scope, node, h.Name, lang.handler, &h.ident)
+				params(node, h.Params, h.Results, true)
+			}
+		}
+	}
+	for _, act := range d.Activities {
+		owner := "activity:" + act.Name
+		params(owner, act.Params, act.Results, false)
+	}
+	n.resolve()
+	d.Identifiers = n.assigned
+}
+
+// identifierMap renders IdentifierMapFile.
+func identifierMap(design *Design) (Output, error) {
+	data, err := json.MarshalIndent(design.Identifiers, "", "  ")
+	if err != nil {
+		return Output{}, err
+	}
+	return Output{Path: IdentifierMapFile, Content: append(data, '\n')}, nil
+}
+
+// ReadIdentifierMap reads the IdentifierMapFile in dir for
+// Options.Identifiers. A missing file is an empty map.
+func ReadIdentifierMap(dir string) (map[string]string, error) {
+	idents, err := ReadIdentifierMapFile(filepath.Join(dir, IdentifierMapFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return idents, err
+}
+
+// ReadIdentifierMapFile is ReadIdentifierMap for an identifier map at path,
+// which must exist.
+func ReadIdentifierMapFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var idents map[string]string
+	if err := json.Unmarshal(data, &idents); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	for _, key := range slices.Sorted(maps.Keys(idents)) {
+		if id := idents[key]; !isIdent(id) {
+			return nil, fmt.Errorf("%s: %s maps to %q, which is not an identifier", path, key, id)
+		}
+	}
+	return idents, nil
+}
+
+// RenamesWorkflows reports whether a workflow registered on the queue is
+// renamed, so worker templates can import what registering it by its name
+// takes.
+func (q *TaskQueue) RenamesWorkflows() bool {
+	return slices.ContainsFunc(q.Workflows, func(wf *Workflow) bool { return wf.Renamed() })
+}
+
+// RenamesActivities is RenamesWorkflows for activities.
+func (q *TaskQueue) RenamesActivities() bool {
+	return slices.ContainsFunc(q.Activities, func(act *Activity) bool { return act.Renamed() })
+}
@@ -0,0 +1,520 @@
+package codegen
+
+import (
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/options"
+)
+
+// Design is the data every template executes against.
+type Design struct {
+	Package    string       // Go package name
+	Workflows  []*Workflow  // in source order
+	Activities []*Activity  // in source order
+	TaskQueues []*TaskQueue // in order of first deployment
+	// Types are the capitalized type names used in signatures, sorted, when
+	// Options.Types is set.
+	Types []*Type
+	// Identifiers maps the keys of IdentifierMapFile to the identifiers
+	// assigned, when Options.Lang is set.
+	Identifiers map[string]string
+}
+
+// Workflow is a workflow definition.
+type Workflow struct {
+	Name string
+	// Ident is the workflow's function or class identifier in the target
+	// language; Renamed reports whether it differs from Name.
+	ident
+	Params      []Param
+	Results     []string // return types, empty when the workflow returns nothing
+	Annotations []Annotation
+	Signals     []*Handler
+	Queries     []*Handler
+	Updates     []*Handler
+	Activities  []string  // activities called, in order of first call
+	Children    []string  // child workflows called, in order of first call
+	ChildIDs    []ChildID // workflow ID templates of child workflow calls, in source order
+	FanOuts     []FanOut  // parallel for each loops, in source order
+}
+
+// FanOut is a parallel for each loop: the body runs once per item of
+// Iterable, bound to Variable, with at most Max iterations at a time.
+type FanOut struct {
+	Variable string
+	Iterable string
+	Max      string
+}
+
+// ChildID is the workflow ID template a workflow starts a child workflow
+// with, as written in the design: "ship-{order.id}".
+type ChildID struct {
+	Workflow string
+	ID       string
+}
+
+// Activity is an activity definition.
+type Activity struct {
+	Name string
+	// Ident is the activity's function identifier: Name in Go, camelCase
+	// in TypeScript, snake_case in Python.
+	ident
+	Params      []Param
+	Results     []string
+	Annotations []Annotation
+	// Options are the effective options of the first call to the activity
+	// that sets any, or else those every call starts from: the activity's
+	// own options over the option defaults. Stubs can default to what the
+	// design uses.
+	Options []Option
+}
+
+// TaskQueue is a task queue that namespaces deploy workers on, with the
+// definitions those workers register.
+type TaskQueue struct {
+	Name string
+	// Ident spells the queue in the identifiers of worker templates:
+	// PascalCase in Go, camelCase in TypeScript, snake_case in Python.
+	ident
+	Namespaces []string // namespaces deploying a worker on the queue
+	Workers    []string // workers deployed on the queue
+	Workflows  []*Workflow
+	Activities []*Activity
+	// Options are the options of the first deployment on the queue,
+	// including task_queue.
+	Options []Option
+}
+
+// QueueDesign is the data worker templates execute against: the design and
+// one of its task queues.
+type QueueDesign struct {
+	*Design
+	Queue *TaskQueue
+}
+
+// Type is a named type used in a signature.
+type Type struct {
+	Name string
+	// Module is the module defining the type, from Options.TypeModules;
+	// TypesModule for the types generated as stubs.
+	Module string
+}
+
+// Generated reports whether the type is generated as a stub.
+func (t *Type) Generated() bool {
+	return t.Module == TypesModule
+}
+
+// Import is a module and the type names a file imports from it.
+type Import struct {
+	Module string
+	Names  []string
+}
+
+// Handler is a signal, query, or update declared by a workflow. Signals
+// have no results.
+type Handler struct {
+	Name string
+	// Ident is the handler's method identifier in Python, and elsewhere
+	// the PascalCase suffix of its name constant.
+	ident
+	Params  []Param
+	Results []string
+}
+
+// Param is one parameter. Type is empty when the design omits it, and
+// Default when the parameter is required.
+type Param struct {
+	Name    string
+	Type    string
+	Default string // TWF value as written
+	// Ident is the parameter's identifier.
+	ident
+}
+
+// Annotation is an @name(args) annotation; Value is its unquoted argument.
+type Annotation struct {
+	Name  string
+	Value string
+}
+
+// Option is an entry of an options block. Type is the option's value type:
+// "string", "duration", "number", "bool", "enum", "list", "map", or
+// "nested" for blocks such as retry_policy, whose entries are in Nested.
+type Option struct {
+	Key    string
+	Value  string
+	Type   string
+	Nested []Option
+}
+
+// ActivitiesUseDurations reports whether any activity signature uses the
+// duration type, so activity templates can import time support only when
+// it is used.
+func (d *Design) ActivitiesUseDurations() bool {
+	for _, act := range d.Activities {
+		if usesDuration(act.Params, act.Results) {
+			return true
+		}
+	}
+	return false
+}
+
+// WorkflowsUseDurations reports whether any workflow, signal, query, or
+// update signature uses the duration type or any activity option is a
+// duration, so workflow templates can import time support only when it is
+// used.
+func (d *Design) WorkflowsUseDurations() bool {
+	for _, wf := range d.Workflows {
+		if usesDuration(wf.Params, wf.Results) {
+			return true
+		}
+		for _, hs := range [][]*Handler{wf.Signals, wf.Queries, wf.Updates} {
+			for _, h := range hs {
+				if usesDuration(h.Params, h.Results) {
+					return true
+				}
+			}
+		}
+	}
+	for _, act := range d.Activities {
+		for _, o := range act.Options {
+			if o.Type == "duration" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GeneratedTypes returns the types generated as stubs.
+func (d *Design) GeneratedTypes() []*Type {
+	var out []*Type
+	for _, t := range d.Types {
+		if t.Generated() {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// ActivityImports returns the modules defining the types of activity
+// signatures, sorted, for languages that import types by module.
+func (d *Design) ActivityImports() []Import {
+	return d.imports(d.activityTypeNames(nil))
+}
+
+// WorkflowImports returns the modules defining the types of workflow,
+// signal, query, and update signatures, sorted.
+func (d *Design) WorkflowImports() []Import {
+	return d.imports(d.workflowTypeNames(nil))
+}
+
+func (d *Design) imports(names []string) []Import {
+	modules := make(map[string][]string)
+	for _, t := range d.Types {
+		if slices.Contains(names, t.Name) {
+			modules[t.Module] = append(modules[t.Module], t.Name)
+		}
+	}
+	var out []Import
+	for _, m := range slices.Sorted(maps.Keys(modules)) {
+		out = append(out, Import{Module: m, Names: modules[m]})
+	}
+	return out
+}
+
+func (d *Design) activityTypeNames(names []string) []string {
+	for _, act := range d.Activities {
+		names = appendTypeNames(names, act.Params, act.Results)
+	}
+	return names
+}
+
+func (d *Design) workflowTypeNames(names []string) []string {
+	for _, wf := range d.Workflows {
+		names = appendTypeNames(names, wf.Params, wf.Results)
+		for _, hs := range [][]*Handler{wf.Signals, wf.Queries, wf.Updates} {
+			for _, h := range hs {
+				names = appendTypeNames(names, h.Params, h.Results)
+			}
+		}
+	}
+	return names
+}
+
+// collectTypes sets d.Types to the type names used in signatures, each
+// defined in its module from modules or else generated.
+func (d *Design) collectTypes(modules map[string]string) {
+	names := d.workflowTypeNames(d.activityTypeNames(nil))
+	slices.Sort(names)
+	for _, name := range names {
+		module := modules[name]
+		if module == "" {
+			module = TypesModule
+		}
+		d.Types = append(d.Types, &Type{Name: name, Module: module})
+	}
+}
+
+var typeName = regexp.MustCompile(`[A-Za-z_][\w.]*`)
+
+// appendTypeNames appends the capitalized, unqualified type names in the
+// given parameter and result types: map[string][]Item names Item. Other
+// names are scalars or qualified names the generator cannot stub.
+func appendTypeNames(names []string, params []Param, results []string) []string {
+	types := slices.Clone(results)
+	for _, p := range params {
+		types = append(types, p.Type)
+	}
+	for _, t := range types {
+		for _, name := range typeName.FindAllString(t, -1) {
+			if unicode.IsUpper([]rune(name)[0]) && !strings.Contains(name, ".") && !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+func usesDuration(params []Param, results []string) bool {
+	for _, p := range params {
+		if strings.Contains(p.Type, "duration") {
+			return true
+		}
+	}
+	for _, r := range results {
+		if strings.Contains(r, "duration") {
+			return true
+		}
+	}
+	return false
+}
+
+// NewDesign builds the template data for file, which should be resolved.
+// Only opts.Lang, opts.Package, opts.Types, opts.TypeModules,
+// opts.Identifiers, and opts.OptionDefaults are used. Identifiers are
+// assigned when opts.Lang is one of Languages.
+func NewDesign(file *ast.File, opts Options) *Design {
+	d := &Design{Package: opts.Package}
+	workflows := make(map[*ast.WorkflowDef]*Workflow)
+	activities := make(map[string]*Activity)
+	for _, def := range file.Definitions {
+		switch def := def.(type) {
+		case *ast.WorkflowDef:
+			wf := newWorkflow(def)
+			workflows[def] = wf
+			d.Workflows = append(d.Workflows, wf)
+		case *ast.ActivityDef:
+			act := &Activity{
+				Name:        def.Name,
+				Params:      splitParams(def.Params),
+				Results:     splitTypes(def.ReturnType),
+				Annotations: annotations(def.Annotations),
+			}
+			act.Options = effective(options.Resolve(def, nil, opts.OptionDefaults))
+			activities[def.Name] = act
+			d.Activities = append(d.Activities, act)
+		}
+	}
+
+	// Activities take the options of their first configured call.
+	configured := make(map[string]bool)
+	for _, def := range file.Definitions {
+		wf, ok := def.(*ast.WorkflowDef)
+		if !ok {
+			continue
+		}
+		walkWorkflow(wf, func(s ast.Statement) bool {
+			call, ok := s.(*ast.ActivityCall)
+			if !ok || call.Options == nil {
+				return true
+			}
+			if act := activities[call.Activity.Name]; act != nil && !configured[act.Name] {
+				configured[act.Name] = true
+				act.Options = effective(options.Resolve(call.Activity.Resolved, call, opts.OptionDefaults))
+			}
+			return true
+		}, nil)
+	}
+
+	d.TaskQueues = taskQueues(file, workflows, activities)
+	if opts.Types {
+		d.collectTypes(opts.TypeModules)
+	}
+	if lang := languages[opts.Lang]; lang != nil {
+		d.nameIdentifiers(lang, opts.Identifiers)
+	}
+	return d
+}
+
+// taskQueues groups the workers that namespaces deploy with a task_queue by
+// queue, collecting what they register. Workers deployed without a
+// task_queue are skipped.
+func taskQueues(file *ast.File, workflows map[*ast.WorkflowDef]*Workflow, activities map[string]*Activity) []*TaskQueue {
+	var queues []*TaskQueue
+	byName := make(map[string]*TaskQueue)
+	seen := make(map[string]bool)
+	once := func(q *TaskQueue, kind, name string) bool {
+		key := q.Name + "\x00" + kind + "\x00" + name
+		if seen[key] {
+			return false
+		}
+		seen[key] = true
+		return true
+	}
+
+	for _, def := range file.Definitions {
+		ns, ok := def.(*ast.NamespaceDef)
+		if !ok {
+			continue
+		}
+		for _, nw := range ns.Workers {
+			name := ast.OptionValue(nw.Options, "task_queue")
+			w := nw.Worker.Resolved
+			if name == "" || w == nil {
+				continue
+			}
+			q := byName[name]
+			if q == nil {
+				q = &TaskQueue{Name: name, Options: optionEntries(nw.Options.Entries)}
+				byName[name] = q
+				queues = append(queues, q)
+			}
+			if once(q, "namespace", ns.Name) {
+				q.Namespaces = append(q.Namespaces, ns.Name)
+			}
+			if once(q, "worker", w.Name) {
+				q.Workers = append(q.Workers, w.Name)
+			}
+			for _, ref := range w.Workflows {
+				if wf := workflows[ref.Resolved]; wf != nil && once(q, "workflow", wf.Name) {
+					q.Workflows = append(q.Workflows, wf)
+				}
+			}
+			for _, ref := range w.Activities {
+				if act := activities[ref.Name]; act != nil && once(q, "activity", act.Name) {
+					q.Activities = append(q.Activities, act)
+				}
+			}
+		}
+	}
+	return queues
+}
+
+func newWorkflow(def *ast.WorkflowDef) *Workflow {
+	wf := &Workflow{
+		Name:        def.Name,
+		Params:      splitParams(def.Params),
+		Results:     splitTypes(def.ReturnType),
+		Annotations: annotations(def.Annotations),
+	}
+	for _, s := range def.Signals {
+		wf.Signals = append(wf.Signals, &Handler{Name: s.Name, Params: splitParams(s.Params)})
+	}
+	for _, q := range def.Queries {
+		wf.Queries = append(wf.Queries, &Handler{Name: q.Name, Params: splitParams(q.Params), Results: splitTypes(q.ReturnType)})
+	}
+	for _, u := range def.Updates {
+		wf.Updates = append(wf.Updates, &Handler{Name: u.Name, Params: splitParams(u.Params), Results: splitTypes(u.ReturnType)})
+	}
+
+	seen := make(map[string]bool)
+	add := func(list *[]string, kind, name string) {
+		if !seen[kind+" "+name] {
+			seen[kind+" "+name] = true
+			*list = append(*list, name)
+		}
+	}
+	walkWorkflow(def, func(s ast.Statement) bool {
+		switch s := s.(type) {
+		case *ast.ActivityCall:
+			add(&wf.Activities, "activity", s.Activity.Name)
+		case *ast.WorkflowCall:
+			add(&wf.Children, "workflow", s.Workflow.Name)
+			if id := (ChildID{Workflow: s.Workflow.Name, ID: s.ID}); s.ID != "" && !slices.Contains(wf.ChildIDs, id) {
+				wf.ChildIDs = append(wf.ChildIDs, id)
+			}
+		case *ast.ForStmt:
+			if s.Variant == ast.ForParallel {
+				wf.FanOuts = append(wf.FanOuts, FanOut{Variable: s.Variable, Iterable: s.Iterable, Max: s.Concurrency})
+			}
+		}
+		return true
+	}, func(target ast.AsyncTarget, _ ast.Statement) bool {
+		switch t := target.(type) {
+		case *ast.ActivityTarget:
+			add(&wf.Activities, "activity", t.Activity.Name)
+		case *ast.WorkflowTarget:
+			add(&wf.Children, "workflow", t.Workflow.Name)
+		}
+		return true
+	})
+	return wf
+}
+
+// walkWorkflow walks the handler bodies and then the body of wf. targets
+// may be nil.
+func walkWorkflow(wf *ast.WorkflowDef, fn func(ast.Statement) bool, targets func(ast.AsyncTarget, ast.Statement) bool) {
+	var opts []ast.WalkOption
+	if targets != nil {
+		opts = append(opts, ast.WithAsyncTargets(targets))
+	}
+	for _, s := range wf.Signals {
+		ast.WalkStatements(s.Body, fn, opts...)
+	}
+	for _, u := range wf.Updates {
+		ast.WalkStatements(u.Body, fn, opts...)
+	}
+	ast.WalkStatements(wf.Body, fn, opts...)
+}
+
+func annotations(anns []*ast.Annotation) []Annotation {
+	var out []Annotation
+	for _, a := range anns {
+		out = append(out, Annotation{Name: a.Name, Value: a.Value()})
+	}
+	return out
+}
+
+func optionEntries(entries []*ast.OptionEntry) []Option {
+	var out []Option
+	for _, e := range entries {
+		o := Option{Key: e.Key, Value: e.Value, Type: e.ValueType}
+		if e.Nested != nil {
+			o.Type = "nested"
+			o.Nested = optionEntries(e.Nested)
+		}
+		out = append(out, o)
+	}
+	return out
+}
+
+// effective converts effective options to template options.
+func effective(opts []options.Option) []Option {
+	var out []Option
+	for _, o := range opts {
+		out = append(out, Option{Key: o.Key, Value: o.Value, Type: o.ValueType, Nested: effective(o.Nested)})
+	}
+	return out
+}
+
+// splitParams splits an opaque parameter list such as
+// "order: Order, items: []Item" into its parameters.
+func splitParams(params string) []Param {
+	var out []Param
+	for _, p := range ast.ParseParams(params) {
+		out = append(out, Param{Name: p.Name, Type: p.Type, Default: p.Default})
+	}
+	return out
+}
+
+// splitTypes splits an opaque return type list such as "Result, error".
+func splitTypes(types string) []string {
+	return ast.SplitList(types)
+}
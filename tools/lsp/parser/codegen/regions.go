@@ -0,0 +1,117 @@
+package codegen
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Protected regions are the lines between a "twf:begin custom NAME" and a
+// "twf:end custom NAME" comment, with // or # comments. Built-in templates
+// wrap each body in a region named after its definition. NAME is optional
+// on the end marker; unnamed regions are matched by their order in the file.
+var (
+	beginMarker = regexp.MustCompile(`^\s*(?://|#)\s*twf:begin custom(?:\s+(.*?))?\s*$`)
+	endMarker   = regexp.MustCompile(`^\s*(?://|#)\s*twf:end custom(?:\s+(.*?))?\s*$`)
+)
+
+// region is a protected region, located by the lines of its markers.
+type region struct {
+	name       string
+	begin, end int // 0-based line indexes of the markers
+}
+
+// Merge keeps the protected regions of existing, a previously generated
+// file that may have been edited, in generated, its regeneration. Each
+// region of generated takes the content of the region of existing with the
+// same name. It also returns the names of the regions of existing that
+// generated no longer has, whose content is dropped.
+func Merge(generated, existing []byte) ([]byte, []string, error) {
+	oldLines := strings.SplitAfter(string(existing), "\n")
+	oldRegions, err := regions(oldLines)
+	if err != nil {
+		return nil, nil, fmt.Errorf("existing file: %v", err)
+	}
+	newLines := strings.SplitAfter(string(generated), "\n")
+	newRegions, err := regions(newLines)
+	if err != nil {
+		return nil, nil, fmt.Errorf("generated file: %v", err)
+	}
+
+	kept := make(map[string]bool)
+	var b strings.Builder
+	next := 0
+	for _, r := range newRegions {
+		b.WriteString(strings.Join(newLines[next:r.begin+1], ""))
+		next = r.begin + 1
+		old, ok := findRegion(oldRegions, r.name)
+		if !ok {
+			continue
+		}
+		kept[r.name] = true
+		b.WriteString(strings.Join(oldLines[old.begin+1:old.end], ""))
+		next = r.end
+	}
+	b.WriteString(strings.Join(newLines[next:], ""))
+
+	var dropped []string
+	for _, r := range oldRegions {
+		if !kept[r.name] && strings.Join(oldLines[r.begin+1:r.end], "") != "" {
+			dropped = append(dropped, r.name)
+		}
+	}
+	return []byte(b.String()), dropped, nil
+}
+
+func findRegion(regions []region, name string) (region, bool) {
+	for _, r := range regions {
+		if r.name == name {
+			return r, true
+		}
+	}
+	return region{}, false
+}
+
+// regions finds the protected regions of lines. Unnamed regions are named
+// by their position among the unnamed ones: "#1", "#2", and so on.
+func regions(lines []string) ([]region, error) {
+	var out []region
+	seen := make(map[string]bool)
+	unnamed := 0
+	open := -1
+	for i, line := range lines {
+		if m := beginMarker.FindStringSubmatch(line); m != nil {
+			if open >= 0 {
+				return nil, fmt.Errorf("line %d: region %q is not ended before the next begins", i+1, out[len(out)-1].name)
+			}
+			name := m[1]
+			if name == "" {
+				unnamed++
+				name = "#" + strconv.Itoa(unnamed)
+			}
+			if seen[name] {
+				return nil, fmt.Errorf("line %d: duplicate region %q", i+1, name)
+			}
+			seen[name] = true
+			out = append(out, region{name: name, begin: i})
+			open = i
+			continue
+		}
+		if m := endMarker.FindStringSubmatch(line); m != nil {
+			if open < 0 {
+				return nil, fmt.Errorf("line %d: twf:end custom without twf:begin custom", i+1)
+			}
+			r := &out[len(out)-1]
+			if m[1] != "" && m[1] != r.name {
+				return nil, fmt.Errorf("line %d: twf:end custom %s ends region %q", i+1, m[1], r.name)
+			}
+			r.end = i
+			open = -1
+		}
+	}
+	if open >= 0 {
+		return nil, fmt.Errorf("line %d: region %q is never ended", open+1, out[len(out)-1].name)
+	}
+	return out, nil
+}
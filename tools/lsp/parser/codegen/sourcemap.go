@@ -0,0 +1,170 @@
+package codegen
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+)
+
+// SourceMapFile is the output linking generated code back to the design.
+const SourceMapFile = "twf-sourcemap.json"
+
+// SourceMapVersion is the version of the SourceMapFile format. It
+// increments when a field is removed, renamed, or changes type.
+const SourceMapVersion = 1
+
+// SourceMap is the content of SourceMapFile: where each definition of the
+// design is implemented in the generated files.
+type SourceMap struct {
+	Version  int       `json:"version"`
+	Mappings []Mapping `json:"mappings"`
+}
+
+// Mapping links the lines of a generated file implementing a definition to
+// the definition in the design. The lines are those of the protected
+// region named after it, from its begin marker to its end marker.
+type Mapping struct {
+	Path      string `json:"path"`      // generated file, relative to the output directory
+	StartLine int    `json:"startLine"` // 1-based line of the begin marker
+	EndLine   int    `json:"endLine"`   // 1-based line of the end marker
+
+	// Node identifies the definition by kind and name, such as
+	// "workflow:Order", "activity:Charge", or "signal:Order.Approve" for a
+	// handler, so it survives edits that move the definition.
+	Node       string `json:"node"`
+	SourceFile string `json:"sourceFile,omitempty"` // design file, when parsed from several
+	Line       int    `json:"line"`
+	Column     int    `json:"column"`
+}
+
+// BuildSourceMap maps the protected regions of outputs to the definitions
+// of file they are named after, and returns SourceMapFile. outputs should
+// hold the files as written, after Merge, so the lines match them. Regions
+// naming no definition, such as imports, and those of the types files are
+// left out.
+func BuildSourceMap(file *ast.File, outputs []Output) (Output, error) {
+	nodes := indexNodes(file.Definitions)
+	sm := SourceMap{Version: SourceMapVersion, Mappings: []Mapping{}}
+	for _, out := range outputs {
+		base := path.Base(out.Path)
+		if strings.HasPrefix(base, TypesModule+".") || strings.HasSuffix(base, ".json") {
+			continue
+		}
+		rs, err := regions(strings.SplitAfter(string(out.Content), "\n"))
+		if err != nil {
+			return Output{}, fmt.Errorf("%s: %v", out.Path, err)
+		}
+		for _, r := range rs {
+			n, ok := nodes.lookup(r.name, strings.HasPrefix(base, "activities."))
+			if !ok {
+				continue
+			}
+			sm.Mappings = append(sm.Mappings, Mapping{
+				Path:       out.Path,
+				StartLine:  r.begin + 1,
+				EndLine:    r.end + 1,
+				Node:       n.ID,
+				SourceFile: n.SourceFile,
+				Line:       n.Line,
+				Column:     n.Column,
+			})
+		}
+	}
+	data, err := json.MarshalIndent(sm, "", "  ")
+	if err != nil {
+		return Output{}, err
+	}
+	return Output{Path: SourceMapFile, Content: append(data, '\n')}, nil
+}
+
+// Node is a definition that source maps link generated code to: a
+// workflow, one of its signals, queries, or updates, or an activity.
+type Node struct {
+	ID         string // as in Mapping.Node
+	Region     string // name of the protected region implementing it: "Order", or "Order.Approve" for a handler
+	Kind       string // workflow, signal, query, update, or activity
+	SourceFile string
+	ast.Pos
+}
+
+// SourceNodes returns the nodes of defs in source order, each workflow
+// followed by its handlers.
+func SourceNodes(defs []ast.Definition) []Node {
+	var nodes []Node
+	add := func(kind, region, sourceFile string, pos ast.Pos) {
+		nodes = append(nodes, Node{ID: kind + ":" + region, Region: region, Kind: kind, SourceFile: sourceFile, Pos: pos})
+	}
+	for _, def := range defs {
+		switch d := def.(type) {
+		case *ast.WorkflowDef:
+			add("workflow", d.Name, d.SourceFile, d.Pos)
+			for _, s := range d.Signals {
+				add("signal", d.Name+"."+s.Name, d.SourceFile, s.Pos)
+			}
+			for _, q := range d.Queries {
+				add("query", d.Name+"."+q.Name, d.SourceFile, q.Pos)
+			}
+			for _, u := range d.Updates {
+				add("update", d.Name+"."+u.Name, d.SourceFile, u.Pos)
+			}
+		case *ast.ActivityDef:
+			add("activity", d.Name, d.SourceFile, d.Pos)
+		}
+	}
+	return nodes
+}
+
+// ReadSourceMap reads the SourceMapFile in dir. A missing file is nil.
+func ReadSourceMap(dir string) (*SourceMap, error) {
+	data, err := os.ReadFile(filepath.Join(dir, SourceMapFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var sm SourceMap
+	if err := json.Unmarshal(data, &sm); err != nil {
+		return nil, fmt.Errorf("%s: %v", SourceMapFile, err)
+	}
+	return &sm, nil
+}
+
+// nodeIndex holds the nodes regions may be named after, by region name.
+type nodeIndex struct {
+	activities, others map[string]Node
+}
+
+func indexNodes(defs []ast.Definition) nodeIndex {
+	idx := nodeIndex{activities: make(map[string]Node), others: make(map[string]Node)}
+	for _, n := range SourceNodes(defs) {
+		if n.Kind == "activity" {
+			idx.activities[n.Region] = n
+		} else {
+			idx.others[n.Region] = n
+		}
+	}
+	return idx
+}
+
+// lookup finds the node a region is named after, preferring an activity
+// in an activities file and a workflow elsewhere when both have the name.
+// Handler regions are qualified, so they cannot clash.
+func (idx nodeIndex) lookup(name string, activitiesFile bool) (Node, bool) {
+	first, second := idx.others, idx.activities
+	if activitiesFile {
+		first, second = second, first
+	}
+	if n, ok := first[name]; ok {
+		return n, true
+	}
+	n, ok := second[name]
+	return n, ok
+}
@@ -0,0 +1,304 @@
+package deps
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// FilterOptions selects a subgraph of a Graph.
+type FilterOptions struct {
+	// Root keeps only the definitions a workflow reaches through calls,
+	// plus the workers and namespaces containing them. Empty keeps all.
+	Root string
+	// Depth limits how many calls away from Root a definition may be.
+	// Negative means unlimited; ignored without Root.
+	Depth int
+	// Callers turns the traversal from Root around, keeping the definitions
+	// that reach Root through calls instead of those it reaches.
+	Callers bool
+	// Exclude drops definitions whose name matches any of these globs
+	// (path.Match syntax), along with their edges. Excluded workflows are
+	// not traversed from Root.
+	Exclude []string
+	// Annotations keeps only the workflows and activities carrying every
+	// listed annotation, written name=value, or name to accept any value.
+	// Nexus services cannot be annotated, so they are dropped when set.
+	Annotations []string
+	// CollapseActivities replaces the activities each caller uses with a
+	// single "activities" node per caller, listing them as Members.
+	CollapseActivities bool
+}
+
+// Filter returns the subgraph of g selected by opts. Edges and unresolved
+// references are kept when their caller is kept (and, for edges, their
+// callee); containment, coarsened edges, and the summary are recomputed.
+func (g *Graph) Filter(opts FilterOptions) (*Graph, error) {
+	for _, pattern := range opts.Exclude {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+		}
+	}
+	excluded := func(name string) bool {
+		for _, pattern := range opts.Exclude {
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	nodes := g.nodeSet()
+	keep := make(map[nodeKey]bool)
+	for _, n := range g.Nodes {
+		if !excluded(n.Name) && n.annotated(opts.Annotations) {
+			keep[nodeKey{n.Kind, n.Name}] = true
+		}
+	}
+	narrowed := opts.Root != "" || len(opts.Annotations) > 0
+
+	if opts.Root != "" {
+		root := nodeKey{"workflow", opts.Root}
+		if !keep[root] {
+			if nodes[root] {
+				return nil, fmt.Errorf("root workflow %s is excluded", opts.Root)
+			}
+			return nil, fmt.Errorf("no workflow named %s", opts.Root)
+		}
+		keep = g.reachable(nodes, root, opts.Depth, opts.Callers, keep)
+	}
+
+	out := &Graph{Containment: make(map[string][]string), Coarsened: &CoarsenedGraph{}}
+	for _, e := range g.Edges {
+		if keep[callerKey(nodes, e.From)] && keep[calleeKey(e)] {
+			out.Edges = append(out.Edges, e)
+		}
+	}
+	for _, u := range g.Unresolved {
+		if keep[callerKey(nodes, u.From)] {
+			out.Unresolved = append(out.Unresolved, u)
+		}
+	}
+
+	// Keep containers of kept definitions: workers of kept children, then
+	// namespaces of kept workers.
+	for _, kind := range []string{"worker", "namespace"} {
+		for _, n := range g.Nodes {
+			if n.Kind != kind || !keep[nodeKey{kind, n.Name}] {
+				continue
+			}
+			var children []string
+			for _, child := range g.Containment[n.Name] {
+				if keptChild(kind, child, keep) {
+					children = append(children, child)
+				}
+			}
+			if len(children) > 0 {
+				out.Containment[n.Name] = children
+			} else if narrowed {
+				keep[nodeKey{kind, n.Name}] = false
+			}
+		}
+	}
+
+	for _, n := range g.Nodes {
+		if keep[nodeKey{n.Kind, n.Name}] {
+			out.Nodes = append(out.Nodes, n)
+		}
+	}
+
+	if opts.CollapseActivities {
+		out.collapseActivities()
+	}
+	out.coarsen(out.containers())
+	out.summarize()
+	return out, nil
+}
+
+// annotated reports whether n carries every annotation in filters. Workers
+// and namespaces always pass; they are kept for their contents.
+func (n Node) annotated(filters []string) bool {
+	if len(filters) == 0 || n.Kind == "worker" || n.Kind == "namespace" {
+		return true
+	}
+	for _, f := range filters {
+		name, value, hasValue := strings.Cut(f, "=")
+		values, ok := n.Annotations[name]
+		if !ok || hasValue && !contains(values, value) {
+			return false
+		}
+	}
+	return true
+}
+
+// nodeKey identifies a node; names are only unique within a kind.
+type nodeKey struct{ kind, name string }
+
+// nodeSet returns the keys of g's nodes.
+func (g *Graph) nodeSet() map[nodeKey]bool {
+	set := make(map[nodeKey]bool, len(g.Nodes))
+	for _, n := range g.Nodes {
+		set[nodeKey{n.Kind, n.Name}] = true
+	}
+	return set
+}
+
+// reachable returns the kept nodes within depth calls of root, following
+// calls backwards from callee to caller when reverse is set. Containers stay
+// kept so Filter can decide on them from their children.
+func (g *Graph) reachable(nodes map[nodeKey]bool, root nodeKey, depth int, reverse bool, keep map[nodeKey]bool) map[nodeKey]bool {
+	calls := make(map[nodeKey][]nodeKey)
+	for _, e := range g.Edges {
+		from, to := callerKey(nodes, e.From), calleeKey(e)
+		if reverse {
+			from, to = to, from
+		}
+		calls[from] = append(calls[from], to)
+	}
+
+	dist := map[nodeKey]int{root: 0}
+	queue := []nodeKey{root}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		if depth >= 0 && dist[cur] >= depth {
+			continue
+		}
+		for _, next := range calls[cur] {
+			if _, seen := dist[next]; seen || !keep[next] {
+				continue
+			}
+			dist[next] = dist[cur] + 1
+			queue = append(queue, next)
+		}
+	}
+
+	out := make(map[nodeKey]bool)
+	for k := range dist {
+		out[k] = true
+	}
+	for k, ok := range keep {
+		if ok && (k.kind == "worker" || k.kind == "namespace") {
+			out[k] = true
+		}
+	}
+	return out
+}
+
+// keptChild reports whether a container's child is kept. Worker children
+// may be workflows, activities, or nexus services; namespace children are
+// workers.
+func keptChild(containerKind, child string, keep map[nodeKey]bool) bool {
+	if containerKind == "namespace" {
+		return keep[nodeKey{"worker", child}]
+	}
+	return keep[nodeKey{"workflow", child}] || keep[nodeKey{"activity", child}] || keep[nodeKey{"nexusService", child}]
+}
+
+// callerKey returns the node an edge or unresolved reference starts from.
+// Calls come from workflows, activities, and sync nexus operations.
+func callerKey(nodes map[nodeKey]bool, name string) nodeKey {
+	for _, kind := range []string{"workflow", "activity", "nexusService"} {
+		if k := (nodeKey{kind, name}); nodes[k] {
+			return k
+		}
+	}
+	return nodeKey{}
+}
+
+// calleeKey returns the node an edge points at. Nexus edges name
+// "Service.Operation" and point at the service.
+func calleeKey(e Edge) nodeKey {
+	switch e.Kind {
+	case "workflowCall":
+		return nodeKey{"workflow", e.To}
+	case "nexusCall":
+		svc, _, _ := strings.Cut(e.To, ".")
+		return nodeKey{"nexusService", svc}
+	case activityGroupKind:
+		return nodeKey{activityGroupKind, e.To}
+	}
+	return nodeKey{"activity", e.To}
+}
+
+// activityGroupKind is the kind of the nodes CollapseActivities creates.
+const activityGroupKind = "activities"
+
+// collapseActivities replaces activity nodes with one group node per caller,
+// named "<caller> activities", and merges the caller's activity edges into
+// one edge to the group.
+func (g *Graph) collapseActivities() {
+	groups := make(map[string]*Node)
+	var order []string
+	var edges []Edge
+	for _, e := range g.Edges {
+		if e.Kind != "activityCall" {
+			edges = append(edges, e)
+			continue
+		}
+		grp, ok := groups[e.From]
+		if !ok {
+			grp = &Node{Name: e.From + " activities", Kind: activityGroupKind, Line: e.Line}
+			groups[e.From] = grp
+			order = append(order, e.From)
+			edges = append(edges, Edge{From: e.From, To: grp.Name, Kind: activityGroupKind, Line: e.Line})
+		}
+		if !contains(grp.Members, e.To) {
+			grp.Members = append(grp.Members, e.To)
+		}
+	}
+	g.Edges = edges
+
+	var nodes []Node
+	for _, n := range g.Nodes {
+		if n.Kind != "activity" {
+			nodes = append(nodes, n)
+		}
+	}
+	for _, caller := range order {
+		nodes = append(nodes, *groups[caller])
+	}
+	g.Nodes = nodes
+
+	set := g.nodeSet()
+	for parent, children := range g.Containment {
+		var kept []string
+		for _, c := range children {
+			if set[nodeKey{"workflow", c}] || set[nodeKey{"nexusService", c}] || set[nodeKey{"worker", c}] {
+				kept = append(kept, c)
+			}
+		}
+		if len(kept) > 0 {
+			g.Containment[parent] = kept
+		} else {
+			delete(g.Containment, parent)
+		}
+	}
+}
+
+// containers rebuilds the child-to-worker and worker-to-namespace maps from
+// Containment.
+func (g *Graph) containers() (childToWorker, workerToNamespace map[string]string) {
+	childToWorker = make(map[string]string)
+	workerToNamespace = make(map[string]string)
+	for _, n := range g.Nodes {
+		for _, child := range g.Containment[n.Name] {
+			switch n.Kind {
+			case "worker":
+				childToWorker[child] = n.Name
+			case "namespace":
+				workerToNamespace[child] = n.Name
+			}
+		}
+	}
+	return childToWorker, workerToNamespace
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
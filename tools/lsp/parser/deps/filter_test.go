@@ -0,0 +1,416 @@
+package deps
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/parser"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/resolver"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/twftest"
+)
+
+const filterSource = `workflow Order(id: string):
+    activity Charge(id)
+    activity Reserve(id)
+    workflow Ship(id)
+    workflow NotifyCustomer(id)
+
+workflow Ship(id: string):
+    activity Pack(id)
+    workflow Deliver(id)
+
+workflow Deliver(id: string):
+    activity Drive(id)
+
+workflow NotifyCustomer(id: string):
+    activity Email(id)
+
+workflow Audit(id: string):
+    activity Log(id)
+
+activity Charge(id: string):
+    return id
+
+activity Reserve(id: string):
+    return id
+
+activity Pack(id: string):
+    return id
+
+activity Drive(id: string):
+    return id
+
+activity Email(id: string):
+    return id
+
+activity Log(id: string):
+    return id
+
+worker orders:
+    workflow Order
+    activity Charge
+    activity Reserve
+
+worker shipping:
+    workflow Ship
+    workflow Deliver
+    activity Pack
+    activity Drive
+
+worker audit:
+    workflow Audit
+    activity Log
+`
+
+func extract(t *testing.T, src string) *Graph {
+	t.Helper()
+	file := twftest.MustParse(t, src)
+	resolver.Resolve(file)
+	return Extract(file)
+}
+
+func nodeNames(g *Graph) string {
+	var names []string
+	for _, n := range g.Nodes {
+		names = append(names, n.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestFilterRootDepthExclude(t *testing.T) {
+	g, err := extract(t, filterSource).Filter(FilterOptions{
+		Root:    "Order",
+		Depth:   2,
+		Exclude: []string{"Notify*"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Drive is three calls from Order; Audit is unreachable; NotifyCustomer
+	// is excluded, so its Email is never reached. The audit worker holds
+	// nothing that was kept.
+	if got, want := nodeNames(g), "Order,Ship,Deliver,Charge,Reserve,Pack,orders,shipping"; got != want {
+		t.Fatalf("nodes:\ngot  %s\nwant %s", got, want)
+	}
+	if g.Summary.Edges != 5 || g.Summary.Workflows != 3 || g.Summary.Workers != 2 {
+		t.Errorf("summary: %+v", g.Summary)
+	}
+	if got := g.Containment["shipping"]; strings.Join(got, ",") != "Ship,Deliver,Pack" {
+		t.Errorf("shipping containment: %v", got)
+	}
+	if len(g.Coarsened.WorkerEdges) != 1 || g.Coarsened.WorkerEdges[0].From != "orders" {
+		t.Errorf("worker edges: %+v", g.Coarsened.WorkerEdges)
+	}
+	for _, ce := range g.Coarsened.WorkerEdges {
+		for _, i := range ce.DerivedFrom {
+			if e := g.Edges[i]; e.From != "Order" || e.To != "Ship" {
+				t.Errorf("coarsened edge derived from %+v", e)
+			}
+		}
+	}
+}
+
+func TestFilterWithoutRoot(t *testing.T) {
+	g, err := extract(t, filterSource).Filter(FilterOptions{Depth: -1, Exclude: []string{"Notify*", "Email"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range g.Nodes {
+		if n.Name == "NotifyCustomer" || n.Name == "Email" {
+			t.Errorf("%s should be excluded", n.Name)
+		}
+	}
+	if g.Summary.Workflows != 4 || g.Summary.Workers != 3 {
+		t.Errorf("summary: %+v", g.Summary)
+	}
+}
+
+func TestFilterCallers(t *testing.T) {
+	full := extract(t, filterSource)
+	g, err := full.Filter(FilterOptions{Root: "Deliver", Depth: -1, Callers: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Drive is called by Deliver, not a caller of it.
+	if got, want := nodeNames(g), "Order,Ship,Deliver,orders,shipping"; got != want {
+		t.Fatalf("nodes:\ngot  %s\nwant %s", got, want)
+	}
+	if g.Summary.Edges != 2 {
+		t.Errorf("summary: %+v", g.Summary)
+	}
+
+	g, err = full.Filter(FilterOptions{Root: "Deliver", Depth: 1, Callers: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := nodeNames(g), "Ship,Deliver,shipping"; got != want {
+		t.Errorf("nodes at depth 1:\ngot  %s\nwant %s", got, want)
+	}
+}
+
+func TestLink(t *testing.T) {
+	// Each file resolved on its own leaves the calls between them unresolved.
+	var file ast.File
+	for _, src := range []string{
+		"workflow Order(id: string):\n    activity Charge(id)\n    workflow Missing(id)\n",
+		"activity Charge(id: string):\n    return id\n",
+	} {
+		f, errs := parser.ParseFileAll(src)
+		if len(errs) != 0 {
+			t.Fatalf("parse errors: %v", errs)
+		}
+		resolver.ResolveFile(f)
+		file.Definitions = append(file.Definitions, f.Definitions...)
+	}
+	g := Extract(&file)
+	if len(g.Edges) != 0 || len(g.Unresolved) != 2 {
+		t.Fatalf("before linking: edges %+v, unresolved %+v", g.Edges, g.Unresolved)
+	}
+	g.Link()
+	if len(g.Edges) != 1 || g.Edges[0].From != "Order" || g.Edges[0].To != "Charge" || g.Edges[0].Line != 2 {
+		t.Errorf("edges: %+v", g.Edges)
+	}
+	if len(g.Unresolved) != 1 || g.Unresolved[0].Name != "Missing" {
+		t.Errorf("unresolved: %+v", g.Unresolved)
+	}
+	if g.Summary.Edges != 1 || g.Summary.Unresolved != 1 {
+		t.Errorf("summary: %+v", g.Summary)
+	}
+}
+
+func TestFilterCollapseActivities(t *testing.T) {
+	g, err := extract(t, filterSource).Filter(FilterOptions{Root: "Order", Depth: 1, CollapseActivities: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := nodeNames(g), "Order,Ship,NotifyCustomer,orders,shipping,Order activities"; got != want {
+		t.Fatalf("nodes:\ngot  %s\nwant %s", got, want)
+	}
+	grp := g.Nodes[len(g.Nodes)-1]
+	if grp.Kind != "activities" || strings.Join(grp.Members, ",") != "Charge,Reserve" {
+		t.Errorf("group node: %+v", grp)
+	}
+	if g.Summary.Activities != 0 || g.Summary.Edges != 3 {
+		t.Errorf("summary: %+v", g.Summary)
+	}
+	if got := g.Containment["orders"]; strings.Join(got, ",") != "Order" {
+		t.Errorf("orders containment: %v", got)
+	}
+}
+
+func TestFilterAnnotations(t *testing.T) {
+	g, err := extract(t, `@tag(critical)
+workflow Order(id: string):
+    activity Charge(id)
+    activity Email(id)
+
+@tag("critical") @tag(pci)
+@owner("payments")
+activity Charge(id: string):
+    return id
+
+@owner("comms")
+activity Email(id: string):
+    return id
+
+worker orders:
+    workflow Order
+    activity Charge
+
+worker comms:
+    activity Email
+`).Filter(FilterOptions{Depth: -1, Annotations: []string{"tag=critical"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := nodeNames(g); got != "Order,Charge,orders" {
+		t.Errorf("nodes: %s", got)
+	}
+	if got := g.Nodes[1].Annotations["tag"]; strings.Join(got, ",") != "critical,pci" {
+		t.Errorf("Charge tags: %v", got)
+	}
+	if len(g.Edges) != 1 || g.Edges[0].To != "Charge" {
+		t.Errorf("edges: %+v", g.Edges)
+	}
+
+	g, err = g.Filter(FilterOptions{Depth: -1, Annotations: []string{"owner", "tag=pci"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := nodeNames(g); got != "Charge,orders" {
+		t.Errorf("nodes with an owner and tag=pci: %s", got)
+	}
+}
+
+func TestFilterErrors(t *testing.T) {
+	g := extract(t, filterSource)
+	for _, tc := range []struct {
+		opts FilterOptions
+		want string
+	}{
+		{FilterOptions{Root: "Missing"}, "no workflow named Missing"},
+		{FilterOptions{Root: "Charge"}, "no workflow named Charge"},
+		{FilterOptions{Root: "Order", Exclude: []string{"Ord*"}}, "root workflow Order is excluded"},
+		{FilterOptions{Exclude: []string{"["}}, "invalid exclude pattern"},
+	} {
+		if _, err := g.Filter(tc.opts); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%+v: got %v, want %q", tc.opts, err, tc.want)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	g, err := extract(t, `workflow Order(id: string):
+    activity Charge(id)
+    activity Charge(id)
+    nexus pay PaymentService.Authorize(id)
+    workflow Pay(id) id "pay-{id}"
+    for each (line in lines) parallel(max: 5):
+        activity Charge(line)
+
+nexus service PaymentService:
+    async Authorize workflow Pay
+
+workflow Pay(id: string):
+    return
+
+activity Charge(id: string):
+    return id
+
+namespace prod:
+    nexus endpoint pay
+        options:
+            task_queue: "pay"
+`).Filter(FilterOptions{Depth: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mermaid := g.Mermaid()
+	for _, want := range []string{
+		"flowchart LR\n",
+		`    workflow_Order["Order"]`,
+		`    activity_Charge(["Charge"])`,
+		`    nexusService_PaymentService{{"PaymentService"}}`,
+		`    workflow_Order -->|"Authorize"| nexusService_PaymentService`,
+		`    workflow_Order -->|"id #quot;pay-{id}#quot;"| workflow_Pay`,
+		`    fanOut_Order_6[/"each line in lines<br/>max 5"\]`,
+		`    workflow_Order --> fanOut_Order_6`,
+		`    fanOut_Order_6 --> activity_Charge`,
+	} {
+		if !strings.Contains(mermaid, want) {
+			t.Errorf("Mermaid output missing %q:\n%s", want, mermaid)
+		}
+	}
+	if strings.Count(mermaid, "workflow_Order --> activity_Charge") != 1 {
+		t.Errorf("repeated calls should be drawn once:\n%s", mermaid)
+	}
+
+	dot := g.DOT()
+	for _, want := range []string{
+		"digraph twf {\n",
+		`    "workflow_Order" [label="Order", shape=box];`,
+		`    "workflow_Order" -> "activity_Charge";`,
+		`    "workflow_Order" -> "nexusService_PaymentService" [label="Authorize"];`,
+		`    "workflow_Order" -> "workflow_Pay" [label="id \"pay-{id}\""];`,
+		`    "fanOut_Order_6" [label="each line in lines\nmax 5", shape=trapezium];`,
+		`    "fanOut_Order_6" -> "activity_Charge";`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("DOT output missing %q:\n%s", want, dot)
+		}
+	}
+}
+
+func TestAwaitAllJoin(t *testing.T) {
+	g := extract(t, `workflow Order(id: string):
+    await all options(onError: continue, minSuccess: 1):
+        activity Charge(id)
+        activity Reserve(id)
+    await one:
+        await all:
+            activity Charge(id)
+        timer(5m):
+            return
+    activity Reserve(id)
+
+activity Charge(id: string):
+    return id
+
+activity Reserve(id: string):
+    return id
+`)
+	var joins []string
+	for _, e := range g.Edges {
+		if e.Join == nil {
+			joins = append(joins, "")
+		} else {
+			joins = append(joins, e.Join.String())
+		}
+	}
+	want := []string{"await all, onError continue, minSuccess 1", "await all, onError continue, minSuccess 1", "await all", ""}
+	if strings.Join(joins, "|") != strings.Join(want, "|") {
+		t.Fatalf("joins = %q, want %q", joins, want)
+	}
+	if mermaid := g.Mermaid(); !strings.Contains(mermaid, `workflow_Order -->|"await all, onError continue, minSuccess 1"| activity_Reserve`) {
+		t.Errorf("Mermaid output missing the await all policy:\n%s", mermaid)
+	}
+}
+
+func TestElifBranches(t *testing.T) {
+	for _, kw := range []string{"elif", "else if"} {
+		g := extract(t, `workflow Order(tier: string):
+    if (tier == "gold"):
+        activity Expedite(tier)
+    `+kw+` (tier == "silver"):
+        activity Prioritize(tier)
+        if (late):
+            activity Expedite(tier)
+    else:
+        activity Queue(tier)
+    activity Queue(tier)
+
+activity Expedite(tier: string):
+    return
+
+activity Prioritize(tier: string):
+    return
+
+activity Queue(tier: string):
+    return
+`)
+		var got []string
+		for _, e := range g.Edges {
+			if e.Branch == nil {
+				got = append(got, e.To)
+				continue
+			}
+			got = append(got, fmt.Sprintf("%s@%d:%s", e.To, e.Branch.Chain, e.Branch))
+		}
+		want := []string{
+			`Expedite@2:if tier == "gold"`,
+			`Prioritize@2:elif tier == "silver"`,
+			`Expedite@6:if late`,
+			`Queue@2:else`,
+			`Queue`,
+		}
+		if strings.Join(got, "|") != strings.Join(want, "|") {
+			t.Errorf("%s: branches = %q, want %q", kw, got, want)
+		}
+
+		mermaid := g.Mermaid()
+		for _, want := range []string{
+			`    workflow_Order -->|"if tier == #quot;gold#quot;"| activity_Expedite`,
+			`    workflow_Order -->|"elif tier == #quot;silver#quot;"| activity_Prioritize`,
+			`    workflow_Order -->|"else"| activity_Queue`,
+			`    workflow_Order --> activity_Queue`,
+		} {
+			if !strings.Contains(mermaid, want) {
+				t.Errorf("%s: Mermaid output missing %q:\n%s", kw, want, mermaid)
+			}
+		}
+	}
+}
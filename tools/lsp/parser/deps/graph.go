@@ -1,24 +1,96 @@
 package deps
 
 import (
+	"cmp"
+	"fmt"
+	"slices"
+
 	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
 )
 
 // Node represents a definition in the dependency graph.
 type Node struct {
-	Name       string `json:"name"`
-	Kind       string `json:"kind"` // workflow, activity, nexusService, worker, namespace
-	SourceFile string `json:"sourceFile,omitempty"`
-	Line       int    `json:"line"`
-	Column     int    `json:"column"`
+	Name       string   `json:"name"`
+	Kind       string   `json:"kind"` // workflow, activity, nexusService, worker, namespace
+	SourceFile string   `json:"sourceFile,omitempty"`
+	Line       int      `json:"line"`
+	Column     int      `json:"column"`
+	Members    []string `json:"members,omitempty"` // activities merged into an "activities" node by Filter
+
+	// Annotations maps each annotation name of a workflow or activity to its
+	// values, e.g. {"tag": ["critical", "pci"]}.
+	Annotations map[string][]string `json:"annotations,omitempty"`
+
+	// Metrics is measured behavior overlaid by Annotate, if any.
+	Metrics *Metrics `json:"metrics,omitempty"`
 }
 
 // Edge represents a dependency from one definition to another.
 type Edge struct {
-	From string `json:"from"`
-	To   string `json:"to"`
-	Kind string `json:"kind"` // activityCall, workflowCall, nexusCall
-	Line int    `json:"line"` // source line of the call
+	From       string  `json:"from"`
+	To         string  `json:"to"`
+	Kind       string  `json:"kind"`                 // activityCall, workflowCall, nexusCall, or activities (collapsed by Filter)
+	Line       int     `json:"line"`                 // source line of the call
+	Condition  string  `json:"condition,omitempty"`  // guard the call is started under, if any
+	WorkflowID string  `json:"workflowId,omitempty"` // workflow ID template of a child workflow call, if any
+	FanOut     *FanOut `json:"fanOut,omitempty"`     // parallel loop the call is started in, if any
+	Join       *Join   `json:"join,omitempty"`       // await all block the call is started in, if any
+	Branch     *Branch `json:"branch,omitempty"`     // if, elif, or else clause the call is started in, if any
+}
+
+// FanOut is a parallel for each loop that starts a call once per item of a
+// collection, at most Max at a time.
+type FanOut struct {
+	Line     int    `json:"line"` // source line of the loop
+	Variable string `json:"variable"`
+	Iterable string `json:"iterable"`
+	Max      string `json:"max"`
+}
+
+// Join is an await all block that starts calls side by side, with its
+// partial-failure policy.
+type Join struct {
+	Line       int    `json:"line"`    // source line of the block
+	OnError    string `json:"onError"` // fail or continue
+	MinSuccess int    `json:"minSuccess,omitempty"`
+}
+
+// String describes the block and its policy, as "await all" or "await all,
+// onError continue, minSuccess 2".
+func (j *Join) String() string {
+	s := "await all"
+	if j.OnError != ast.AwaitAllFail {
+		s += ", onError " + j.OnError
+	}
+	if j.MinSuccess > 0 {
+		s += fmt.Sprintf(", minSuccess %d", j.MinSuccess)
+	}
+	return s
+}
+
+// Branch is a clause of an if statement. The clauses of an elif chain are
+// siblings: each names the line of the if starting the chain, and an elif
+// clause holds only its own condition, not those of the clauses before it.
+type Branch struct {
+	Line      int    `json:"line,omitempty"` // source line of an if or elif clause; the AST does not locate else
+	Chain     int    `json:"chain"`          // source line of the if starting the chain
+	Clause    string `json:"clause"`
+	Condition string `json:"condition,omitempty"` // empty for else
+}
+
+// Clauses of a Branch. elif stands for else if too.
+const (
+	ClauseIf   = "if"
+	ClauseElif = "elif"
+	ClauseElse = "else"
+)
+
+// String describes the clause, as "if ready", "elif late", or "else".
+func (b *Branch) String() string {
+	if b.Clause == ClauseElse {
+		return b.Clause
+	}
+	return b.Clause + " " + b.Condition
 }
 
 // UnresolvedRef represents a reference that could not be resolved.
@@ -50,12 +122,12 @@ type Summary struct {
 
 // Graph is the full dependency graph output.
 type Graph struct {
-	Nodes       []Node            `json:"nodes"`
-	Edges       []Edge            `json:"edges"`
+	Nodes       []Node              `json:"nodes"`
+	Edges       []Edge              `json:"edges"`
 	Containment map[string][]string `json:"containment"`
-	Coarsened   *CoarsenedGraph   `json:"coarsened"`
-	Unresolved  []UnresolvedRef   `json:"unresolved"`
-	Summary     Summary           `json:"summary"`
+	Coarsened   *CoarsenedGraph     `json:"coarsened"`
+	Unresolved  []UnresolvedRef     `json:"unresolved"`
+	Summary     Summary             `json:"summary"`
 }
 
 // CoarsenedGraph holds edges projected to worker and namespace levels.
@@ -80,8 +152,10 @@ func Extract(file *ast.File) *Graph {
 		switch d := def.(type) {
 		case *ast.WorkflowDef:
 			g.addNode(d.Name, "workflow", d.SourceFile, d.Line, d.Column)
+			g.annotate(d.Annotations)
 		case *ast.ActivityDef:
 			g.addNode(d.Name, "activity", d.SourceFile, d.Line, d.Column)
+			g.annotate(d.Annotations)
 		case *ast.NexusServiceDef:
 			g.addNode(d.Name, "nexusService", d.SourceFile, d.Line, d.Column)
 		case *ast.WorkerDef:
@@ -143,7 +217,36 @@ func Extract(file *ast.File) *Graph {
 	// Pass 3: Coarsen edges.
 	g.coarsen(childToWorker, workerToNamespace)
 
-	// Summary.
+	g.summarize()
+
+	return g
+}
+
+// Link turns each unresolved reference naming a node of g into an edge.
+// It is for graphs extracted from definitions resolved file by file, such
+// as a language server's workspace index, where a call to a definition in
+// another file is unresolved in its own. Linked edges carry no guard,
+// fan-out, or join, which only the call itself records.
+func (g *Graph) Link() {
+	nodes := g.nodeSet()
+	var unresolved []UnresolvedRef
+	for _, u := range g.Unresolved {
+		e := Edge{From: u.From, To: u.Name, Kind: u.Kind, Line: u.Line}
+		if !nodes[calleeKey(e)] {
+			unresolved = append(unresolved, u)
+			continue
+		}
+		g.Edges = append(g.Edges, e)
+	}
+	g.Unresolved = unresolved
+	g.Coarsened = &CoarsenedGraph{}
+	g.coarsen(g.containers())
+	g.summarize()
+}
+
+// summarize counts the graph's nodes, edges, and unresolved references.
+func (g *Graph) summarize() {
+	g.Summary = Summary{}
 	for _, n := range g.Nodes {
 		switch n.Kind {
 		case "namespace":
@@ -160,8 +263,18 @@ func Extract(file *ast.File) *Graph {
 	}
 	g.Summary.Edges = len(g.Edges)
 	g.Summary.Unresolved = len(g.Unresolved)
+}
 
-	return g
+// annotate records annotations on the most recently added node.
+func (g *Graph) annotate(anns []*ast.Annotation) {
+	if len(anns) == 0 {
+		return
+	}
+	n := &g.Nodes[len(g.Nodes)-1]
+	n.Annotations = make(map[string][]string)
+	for _, a := range anns {
+		n.Annotations[a.Name] = append(n.Annotations[a.Name], a.Value())
+	}
 }
 
 func (g *Graph) addNode(name, kind, sourceFile string, line, column int) {
@@ -175,30 +288,130 @@ func (g *Graph) addNode(name, kind, sourceFile string, line, column int) {
 }
 
 func (g *Graph) extractFromBody(from string, stmts []ast.Statement) {
+	fanOuts, joins, branches := parallelLoops(stmts), awaitAllBlocks(stmts), ifBranches(stmts)
 	ast.WalkStatements(stmts, func(s ast.Statement) bool {
+		var e *Edge
 		switch stmt := s.(type) {
 		case *ast.ActivityCall:
-			g.addCallEdge(from, stmt.Activity.Name, "activityCall", stmt.Line, stmt.Activity.Resolved != nil)
+			e = g.addCallEdge(from, stmt.Activity.Name, "activityCall", stmt.Line, stmt.Activity.Resolved != nil, "")
 		case *ast.WorkflowCall:
-			g.addCallEdge(from, stmt.Workflow.Name, "workflowCall", stmt.Line, stmt.Workflow.Resolved != nil)
+			if e = g.addCallEdge(from, stmt.Workflow.Name, "workflowCall", stmt.Line, stmt.Workflow.Resolved != nil, ""); e != nil {
+				e.WorkflowID = stmt.ID
+			}
 		case *ast.NexusCall:
-			g.addCallEdge(from, stmt.Service.Name+"."+stmt.Operation.Name, "nexusCall", stmt.Line, stmt.Operation.Resolved != nil)
+			e = g.addCallEdge(from, stmt.Service.Name+"."+stmt.Operation.Name, "nexusCall", stmt.Line, stmt.Operation.Resolved != nil, "")
+		}
+		if e != nil {
+			e.FanOut, e.Join, e.Branch = fanOuts[s], joins[s], branches[s]
 		}
 		return true
 	}, ast.WithAsyncTargets(func(target ast.AsyncTarget, parent ast.Statement) bool {
+		// A guarded await one case only starts its target when the guard holds.
+		var cond string
+		if c, ok := parent.(*ast.AwaitOneCase); ok {
+			cond = c.Guard
+		}
+		var e *Edge
 		switch t := target.(type) {
 		case *ast.ActivityTarget:
-			g.addCallEdge(from, t.Activity.Name, "activityCall", parent.NodeLine(), t.Activity.Resolved != nil)
+			e = g.addCallEdge(from, t.Activity.Name, "activityCall", parent.NodeLine(), t.Activity.Resolved != nil, cond)
 		case *ast.WorkflowTarget:
-			g.addCallEdge(from, t.Workflow.Name, "workflowCall", parent.NodeLine(), t.Workflow.Resolved != nil)
+			e = g.addCallEdge(from, t.Workflow.Name, "workflowCall", parent.NodeLine(), t.Workflow.Resolved != nil, cond)
 		case *ast.NexusTarget:
-			g.addCallEdge(from, t.Service.Name+"."+t.Operation.Name, "nexusCall", parent.NodeLine(), t.Operation.Resolved != nil)
+			e = g.addCallEdge(from, t.Service.Name+"."+t.Operation.Name, "nexusCall", parent.NodeLine(), t.Operation.Resolved != nil, cond)
+		}
+		if e != nil {
+			e.FanOut, e.Join, e.Branch = fanOuts[parent], joins[parent], branches[parent]
 		}
 		return true
 	}))
 }
 
-func (g *Graph) addCallEdge(from, to, kind string, line int, resolved bool) {
+// parallelLoops maps each statement inside a parallel for each loop in stmts
+// to the innermost such loop, as a FanOut.
+func parallelLoops(stmts []ast.Statement) map[ast.Statement]*FanOut {
+	out := make(map[ast.Statement]*FanOut)
+	ast.WalkStatements(stmts, func(s ast.Statement) bool {
+		loop, ok := s.(*ast.ForStmt)
+		if !ok || loop.Variant != ast.ForParallel {
+			return true
+		}
+		f := &FanOut{Line: loop.Line, Variable: loop.Variable, Iterable: loop.Iterable, Max: loop.Concurrency}
+		// Loops are visited before the statements they contain, so inner
+		// loops overwrite the entries of outer ones.
+		ast.WalkStatements(loop.Body, func(s ast.Statement) bool {
+			out[s] = f
+			return true
+		})
+		return true
+	})
+	return out
+}
+
+// awaitAllBlocks maps each statement inside an await all block in stmts to
+// the innermost such block, as a Join.
+func awaitAllBlocks(stmts []ast.Statement) map[ast.Statement]*Join {
+	out := make(map[ast.Statement]*Join)
+	ast.WalkStatements(stmts, func(s ast.Statement) bool {
+		block, ok := s.(*ast.AwaitAllBlock)
+		if !ok {
+			return true
+		}
+		j := &Join{Line: block.Line, OnError: ast.AwaitAllFail}
+		if block.Options != nil {
+			j.OnError, j.MinSuccess = block.Options.OnError, block.Options.MinSuccess
+		}
+		// As with parallel loops, inner blocks overwrite outer ones.
+		ast.WalkStatements(block.Body, func(s ast.Statement) bool {
+			out[s] = j
+			return true
+		})
+		return true
+	})
+	return out
+}
+
+// ifBranches maps each statement inside an if statement in stmts to the
+// innermost clause containing it, as a Branch. An elif clause is an else
+// holding only the chained if, so the chain is followed from its first if
+// and the chained ifs are not taken as clauses of an else.
+func ifBranches(stmts []ast.Statement) map[ast.Statement]*Branch {
+	out := make(map[ast.Statement]*Branch)
+	chained := make(map[*ast.IfStmt]bool)
+	ast.WalkStatements(stmts, func(s ast.Statement) bool {
+		head, ok := s.(*ast.IfStmt)
+		if !ok || chained[head] {
+			return true
+		}
+		mark := func(body []ast.Statement, b *Branch) {
+			// As with parallel loops, inner clauses overwrite outer ones.
+			ast.WalkStatements(body, func(s ast.Statement) bool {
+				out[s] = b
+				return true
+			})
+		}
+		clause := ClauseIf
+		for n := head; n != nil; {
+			mark(n.Body, &Branch{Line: n.Line, Chain: head.Line, Clause: clause, Condition: n.Condition})
+			if !n.ElseIf {
+				if len(n.ElseBody) > 0 {
+					mark(n.ElseBody, &Branch{Chain: head.Line, Clause: ClauseElse})
+				}
+				break
+			}
+			n = n.ElseBody[0].(*ast.IfStmt)
+			chained[n] = true
+			clause = ClauseElif
+		}
+		return true
+	})
+	return out
+}
+
+// addCallEdge records a call from from to to, as an edge when the callee
+// resolved and as an unresolved reference otherwise. It returns the edge, or
+// nil for an unresolved callee.
+func (g *Graph) addCallEdge(from, to, kind string, line int, resolved bool, condition string) *Edge {
 	if !resolved {
 		g.Unresolved = append(g.Unresolved, UnresolvedRef{
 			From: from,
@@ -206,14 +419,16 @@ func (g *Graph) addCallEdge(from, to, kind string, line int, resolved bool) {
 			Kind: kind,
 			Line: line,
 		})
-		return
+		return nil
 	}
 	g.Edges = append(g.Edges, Edge{
-		From: from,
-		To:   to,
-		Kind: kind,
-		Line: line,
+		From:      from,
+		To:        to,
+		Kind:      kind,
+		Line:      line,
+		Condition: condition,
 	})
+	return &g.Edges[len(g.Edges)-1]
 }
 
 // coarsen projects edges to worker-level and namespace-level.
@@ -244,6 +459,7 @@ func (g *Graph) coarsen(childToWorker, workerToNamespace map[string]string) {
 	for _, ce := range workerAgg {
 		g.Coarsened.WorkerEdges = append(g.Coarsened.WorkerEdges, *ce)
 	}
+	slices.SortFunc(g.Coarsened.WorkerEdges, compareCoarsened)
 
 	// Namespace-level coarsening.
 	nsAgg := make(map[edgeKey]*CoarsenedEdge)
@@ -271,4 +487,11 @@ func (g *Graph) coarsen(childToWorker, workerToNamespace map[string]string) {
 	for _, ce := range nsAgg {
 		g.Coarsened.NamespaceEdges = append(g.Coarsened.NamespaceEdges, *ce)
 	}
+	slices.SortFunc(g.Coarsened.NamespaceEdges, compareCoarsened)
+}
+
+// compareCoarsened orders coarsened edges by their ends, since they are
+// aggregated in a map.
+func compareCoarsened(a, b CoarsenedEdge) int {
+	return cmp.Or(cmp.Compare(a.From, b.From), cmp.Compare(a.To, b.To))
 }
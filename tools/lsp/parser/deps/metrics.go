@@ -0,0 +1,111 @@
+package deps
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/eval"
+)
+
+// Metrics is behavior measured outside the design, such as an activity's
+// latency and failure rate in production, overlaid on its node.
+type Metrics struct {
+	Latency     string   `json:"latency,omitempty"`     // a duration, such as "250ms"
+	FailureRate *float64 `json:"failureRate,omitempty"` // fraction of executions failing, 0 to 1
+}
+
+// Heat thresholds: nodes failing at least this often are drawn warm or hot.
+const (
+	warmFailureRate = 0.01
+	hotFailureRate  = 0.05
+)
+
+// ParseMetrics decodes a metrics file: a JSON object mapping a node's name,
+// or its rendered ID such as activity_Charge when a workflow and an
+// activity share a name, to its Metrics.
+func ParseMetrics(data []byte) (map[string]Metrics, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	metrics := make(map[string]Metrics, len(raw))
+	for _, key := range slices.Sorted(maps.Keys(raw)) {
+		dec := json.NewDecoder(bytes.NewReader(raw[key]))
+		dec.DisallowUnknownFields()
+		var m Metrics
+		if err := dec.Decode(&m); err != nil {
+			return nil, fmt.Errorf("metrics for %s: %w", key, err)
+		}
+		if _, ok := eval.ParseDuration(m.Latency); m.Latency != "" && !ok {
+			return nil, fmt.Errorf("metrics for %s: latency %q is not a duration", key, m.Latency)
+		}
+		if r := m.FailureRate; r != nil && (*r < 0 || *r > 1) {
+			return nil, fmt.Errorf("metrics for %s: failureRate %g is not between 0 and 1", key, *r)
+		}
+		metrics[key] = m
+	}
+	return metrics, nil
+}
+
+// Annotate sets the Metrics of each node named by a key of metrics, by
+// rendered ID first and then by name, and returns the keys that name no
+// node, sorted. A name shared by nodes of several kinds annotates them all.
+func (g *Graph) Annotate(metrics map[string]Metrics) []string {
+	used := make(map[string]bool)
+	for i := range g.Nodes {
+		n := &g.Nodes[i]
+		key := renderID(nodeKey{n.Kind, n.Name})
+		m, ok := metrics[key]
+		if !ok {
+			key = n.Name
+			m, ok = metrics[key]
+		}
+		if ok {
+			n.Metrics = &m
+			used[key] = true
+		}
+	}
+	var unknown []string
+	for key := range metrics {
+		if !used[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	slices.Sort(unknown)
+	return unknown
+}
+
+// metricsLabel describes m for a node label, as "latency 250ms" and
+// "3.0% failing" joined by sep, or "" for no metrics.
+func metricsLabel(m *Metrics, sep string) string {
+	if m == nil {
+		return ""
+	}
+	var parts []string
+	if m.Latency != "" {
+		parts = append(parts, "latency "+m.Latency)
+	}
+	if m.FailureRate != nil {
+		parts = append(parts, fmt.Sprintf("%.1f%% failing", *m.FailureRate*100))
+	}
+	return strings.Join(parts, sep)
+}
+
+// heatColor is the fill of a node with metrics m: green, amber, or red by
+// failure rate, or "" when there is none to go by.
+func heatColor(m *Metrics) string {
+	switch {
+	case m == nil || m.FailureRate == nil:
+		return ""
+	case *m.FailureRate >= hotFailureRate:
+		return "#f8d7da"
+	case *m.FailureRate >= warmFailureRate:
+		return "#fff3cd"
+	default:
+		return "#d4edda"
+	}
+}
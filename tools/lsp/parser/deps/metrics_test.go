@@ -0,0 +1,74 @@
+package deps
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseMetrics(t *testing.T) {
+	metrics, err := ParseMetrics([]byte(`{
+		"Charge": {"latency": "250ms", "failureRate": 0.08},
+		"activity_Reserve": {"failureRate": 0}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := metrics["Charge"]; m.Latency != "250ms" || m.FailureRate == nil || *m.FailureRate != 0.08 {
+		t.Errorf("unexpected Charge metrics: %+v", m)
+	}
+	if m := metrics["activity_Reserve"]; m.FailureRate == nil || *m.FailureRate != 0 {
+		t.Errorf("a zero failure rate should be kept: %+v", m)
+	}
+
+	for _, tt := range []struct{ data, want string }{
+		{`[]`, "cannot unmarshal array"},
+		{`{"Charge": {"latency": "soon"}}`, `metrics for Charge: latency "soon" is not a duration`},
+		{`{"Charge": {"failureRate": 1.5}}`, "metrics for Charge: failureRate 1.5 is not between 0 and 1"},
+		{`{"Charge": {"latncy": "1s"}}`, `metrics for Charge: json: unknown field "latncy"`},
+	} {
+		if _, err := ParseMetrics([]byte(tt.data)); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseMetrics(%s) = %v, want an error containing %q", tt.data, err, tt.want)
+		}
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	g := extract(t, filterSource)
+	metrics, err := ParseMetrics([]byte(`{
+		"Charge": {"latency": "250ms", "failureRate": 0.08},
+		"activity_Pack": {"failureRate": 0.02},
+		"workflow_Ship": {"latency": "3m"},
+		"Refund": {"failureRate": 0.5}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unknown := g.Annotate(metrics); strings.Join(unknown, ",") != "Refund" {
+		t.Errorf("unknown keys = %v, want [Refund]", unknown)
+	}
+	g, err = g.Filter(FilterOptions{Root: "Order", Depth: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mermaid := g.Mermaid()
+	for _, want := range []string{
+		`    activity_Charge(["Charge<br/>latency 250ms<br/>8.0% failing"])`,
+		`    workflow_Ship["Ship<br/>latency 3m"]`,
+		`    style activity_Charge fill:#f8d7da`,
+		`    style activity_Pack fill:#fff3cd`,
+	} {
+		if !strings.Contains(mermaid, want) {
+			t.Errorf("Mermaid output missing %q:\n%s", want, mermaid)
+		}
+	}
+	if strings.Contains(mermaid, "style workflow_Ship") {
+		t.Errorf("a node without a failure rate should not be filled:\n%s", mermaid)
+	}
+
+	dot := g.DOT()
+	want := `    "activity_Charge" [label="Charge\nlatency 250ms\n8.0% failing", shape=ellipse, style=filled, fillcolor="#f8d7da"];`
+	if !strings.Contains(dot, want) {
+		t.Errorf("DOT output missing %q:\n%s", want, dot)
+	}
+}
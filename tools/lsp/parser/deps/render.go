@@ -0,0 +1,210 @@
+package deps
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Mermaid renders the call graph as a Mermaid flowchart. Workflows are
+// boxes, activities are stadiums, nexus services are hexagons, and collapsed
+// activity groups are subroutine boxes listing their members. Edges carry
+// their nexus operation, child workflow ID, if clause, and guard as a label,
+// the clauses of an elif chain labeled side by side. Calls in a
+// parallel for each loop pass through a trapezoid fan-out node naming the
+// collection and the limit. Nodes with metrics list them under their name
+// and are filled by failure rate. Workers, namespaces, and unresolved
+// references are not drawn.
+func (g *Graph) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	var styles []string
+	for _, n := range g.callNodes() {
+		label := mermaidText(nodeLabel(n, "<br/>"))
+		id := renderID(nodeKey{n.Kind, n.Name})
+		switch n.Kind {
+		case "activity":
+			fmt.Fprintf(&b, "    %s([%s])\n", id, label)
+		case "nexusService":
+			fmt.Fprintf(&b, "    %s{{%s}}\n", id, label)
+		case activityGroupKind:
+			fmt.Fprintf(&b, "    %s[[%s]]\n", id, label)
+		default:
+			fmt.Fprintf(&b, "    %s[%s]\n", id, label)
+		}
+		if color := heatColor(n.Metrics); color != "" {
+			styles = append(styles, fmt.Sprintf("    style %s fill:%s\n", id, color))
+		}
+	}
+	edges, fanOuts := g.renderEdges()
+	for _, f := range fanOuts {
+		fmt.Fprintf(&b, "    %s[/%s\\]\n", renderID(f.key), mermaidText(f.label("<br/>")))
+	}
+	for _, e := range edges {
+		from, to := renderID(e.from), renderID(e.to)
+		if e.label != "" {
+			fmt.Fprintf(&b, "    %s -->|%s| %s\n", from, mermaidText(e.label), to)
+			continue
+		}
+		fmt.Fprintf(&b, "    %s --> %s\n", from, to)
+	}
+	for _, s := range styles {
+		b.WriteString(s)
+	}
+	return b.String()
+}
+
+// DOT renders the call graph in Graphviz DOT, with the same nodes and edges
+// as Mermaid.
+func (g *Graph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph twf {\n    rankdir=LR;\n")
+	for _, n := range g.callNodes() {
+		shape := "box"
+		switch n.Kind {
+		case "activity":
+			shape = "ellipse"
+		case "nexusService":
+			shape = "hexagon"
+		case activityGroupKind:
+			shape = "box3d"
+		}
+		fill := ""
+		if color := heatColor(n.Metrics); color != "" {
+			fill = ", style=filled, fillcolor=" + strconv.Quote(color)
+		}
+		fmt.Fprintf(&b, "    %s [label=%s, shape=%s%s];\n",
+			strconv.Quote(renderID(nodeKey{n.Kind, n.Name})), strconv.Quote(nodeLabel(n, "\n")), shape, fill)
+	}
+	edges, fanOuts := g.renderEdges()
+	for _, f := range fanOuts {
+		fmt.Fprintf(&b, "    %s [label=%s, shape=trapezium];\n", strconv.Quote(renderID(f.key)), strconv.Quote(f.label("\n")))
+	}
+	for _, e := range edges {
+		from, to := strconv.Quote(renderID(e.from)), strconv.Quote(renderID(e.to))
+		if e.label != "" {
+			fmt.Fprintf(&b, "    %s -> %s [label=%s];\n", from, to, strconv.Quote(e.label))
+			continue
+		}
+		fmt.Fprintf(&b, "    %s -> %s;\n", from, to)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// callNodes returns the nodes that take part in calls, in graph order.
+func (g *Graph) callNodes() []Node {
+	var out []Node
+	for _, n := range g.Nodes {
+		if n.Kind != "worker" && n.Kind != "namespace" {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// renderEdge is an edge as drawn: repeated calls with the same label are
+// drawn once.
+type renderEdge struct {
+	from, to nodeKey
+	label    string
+}
+
+// fanOutKind is the render kind of fan-out nodes, which are drawn but are
+// not nodes of the graph.
+const fanOutKind = "fanOut"
+
+// renderFanOut is a parallel loop drawn as a node between its caller and
+// the calls it starts.
+type renderFanOut struct {
+	key nodeKey
+	*FanOut
+}
+
+func (f renderFanOut) label(sep string) string {
+	return fmt.Sprintf("each %s in %s%smax %s", f.Variable, f.Iterable, sep, f.Max)
+}
+
+// renderEdges returns the edges as drawn and the fan-out nodes they pass
+// through, in order of first use.
+func (g *Graph) renderEdges() ([]renderEdge, []renderFanOut) {
+	nodes := g.nodeSet()
+	seen := make(map[renderEdge]bool)
+	fanOutSeen := make(map[nodeKey]bool)
+	var out []renderEdge
+	var fanOuts []renderFanOut
+	add := func(re renderEdge) {
+		if !seen[re] {
+			seen[re] = true
+			out = append(out, re)
+		}
+	}
+	for _, e := range g.Edges {
+		re := renderEdge{from: callerKey(nodes, e.From), to: calleeKey(e)}
+		if !nodes[re.from] || !nodes[re.to] {
+			continue
+		}
+		var parts []string
+		if e.Kind == "nexusCall" {
+			if _, op, ok := strings.Cut(e.To, "."); ok {
+				parts = append(parts, op)
+			}
+		}
+		if e.WorkflowID != "" {
+			parts = append(parts, "id "+strconv.Quote(e.WorkflowID))
+		}
+		if e.Branch != nil {
+			parts = append(parts, e.Branch.String())
+		}
+		if e.Condition != "" {
+			parts = append(parts, "if "+e.Condition)
+		}
+		if e.Join != nil {
+			parts = append(parts, e.Join.String())
+		}
+		re.label = strings.Join(parts, " ")
+		if e.FanOut != nil {
+			key := nodeKey{fanOutKind, fmt.Sprintf("%s_%d", re.from.name, e.FanOut.Line)}
+			if !fanOutSeen[key] {
+				fanOutSeen[key] = true
+				fanOuts = append(fanOuts, renderFanOut{key: key, FanOut: e.FanOut})
+			}
+			add(renderEdge{from: re.from, to: key})
+			re.from = key
+		}
+		add(re)
+	}
+	return out, fanOuts
+}
+
+// nodeLabel is a node's name, followed by its members for an activity group
+// and its metrics, if any.
+func nodeLabel(n Node, sep string) string {
+	lines := append([]string{n.Name}, n.Members...)
+	if m := metricsLabel(n.Metrics, sep); m != "" {
+		lines = append(lines, m)
+	}
+	return strings.Join(lines, sep)
+}
+
+// renderID is a node identifier safe in both output formats, prefixed by
+// kind so a workflow and an activity may share a name.
+func renderID(k nodeKey) string {
+	var b strings.Builder
+	b.WriteString(k.kind)
+	b.WriteByte('_')
+	for _, r := range k.name {
+		if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// mermaidText quotes s for a Mermaid label. Mermaid has no escape for a
+// double quote inside a quoted label other than its #quot; entity.
+func mermaidText(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
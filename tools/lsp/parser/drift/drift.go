@@ -0,0 +1,252 @@
+// Package drift compares TWF designs with the Go workers implementing them.
+//
+// ScanGo finds workflow and activity implementations by their first
+// parameter and the signal, query, and update handlers they register.
+// Compare matches them with the design by name and reports what only one
+// side has, signatures whose types differ, and handlers the design does not
+// declare. Types are compared as the Go stubs of twf generate spell them,
+// ignoring package qualifiers and pointers.
+package drift
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/codegen"
+)
+
+// Finding kinds.
+const (
+	// MissingInDesign is an implementation the design does not define.
+	MissingInDesign = "missing-in-design"
+	// MissingInCode is a definition no implementation matches.
+	MissingInCode = "missing-in-code"
+	// SignatureMismatch is an implementation whose parameter or result
+	// types differ from the design.
+	SignatureMismatch = "signature"
+	// UndocumentedHandler is a handler the design does not declare.
+	UndocumentedHandler = "undocumented-handler"
+	// UnhandledHandler is a declared handler an implemented workflow does
+	// not register.
+	UnhandledHandler = "unhandled"
+)
+
+// Finding is one difference between the design and the code. File and Line
+// locate it in the code, or in the design for what the code lacks.
+type Finding struct {
+	Kind    string `json:"kind"`
+	Name    string `json:"name"`
+	Message string `json:"message"`
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+}
+
+// String formats f as "file:line: message".
+func (f Finding) String() string {
+	if f.File == "" {
+		return f.Message
+	}
+	return fmt.Sprintf("%s:%d: %s", f.File, f.Line, f.Message)
+}
+
+// Compare reports the differences between file, which should be resolved,
+// and code, sorted by location.
+func Compare(file *ast.File, code *Code) []Finding {
+	design := codegen.NewDesign(file, codegen.Options{})
+	positions := definitionPositions(file)
+	var findings []Finding
+
+	compareFuncs := func(kind string, designed map[string]signature, impls []*Func) {
+		implemented := make(map[string]bool)
+		for _, impl := range impls {
+			implemented[impl.Name] = true
+			want, ok := designed[impl.Name]
+			if !ok {
+				findings = append(findings, Finding{
+					Kind:    MissingInDesign,
+					Name:    impl.Name,
+					Message: fmt.Sprintf("%s %s is implemented but not designed", kind, impl.Name),
+					File:    impl.File,
+					Line:    impl.Line,
+				})
+				continue
+			}
+			if msg := want.mismatch(impl); msg != "" {
+				findings = append(findings, Finding{
+					Kind:    SignatureMismatch,
+					Name:    impl.Name,
+					Message: fmt.Sprintf("%s %s %s", kind, impl.Name, msg),
+					File:    impl.File,
+					Line:    impl.Line,
+				})
+			}
+		}
+		for name := range designed {
+			if implemented[name] {
+				continue
+			}
+			pos := positions[kind+" "+name]
+			findings = append(findings, Finding{
+				Kind:    MissingInCode,
+				Name:    name,
+				Message: fmt.Sprintf("%s %s is designed but not implemented", kind, name),
+				File:    pos.SourceFile,
+				Line:    pos.Line,
+			})
+		}
+	}
+
+	workflows := make(map[string]signature)
+	declared := make(map[string]bool)       // "kind name" declared by any workflow
+	declaredBy := make(map[string][]string) // workflow to "kind name" of its handlers
+	for _, wf := range design.Workflows {
+		workflows[wf.Name] = newSignature(wf.Params, wf.Results)
+		for kind, hs := range map[string][]*codegen.Handler{"signal": wf.Signals, "query": wf.Queries, "update": wf.Updates} {
+			for _, h := range hs {
+				declared[kind+" "+h.Name] = true
+				declaredBy[wf.Name] = append(declaredBy[wf.Name], kind+" "+h.Name)
+			}
+		}
+	}
+	activities := make(map[string]signature)
+	for _, act := range design.Activities {
+		activities[act.Name] = newSignature(act.Params, act.Results)
+	}
+	compareFuncs("workflow", workflows, code.Workflows)
+	compareFuncs("activity", activities, code.Activities)
+
+	// Handlers registered by a designed workflow must be declared by it;
+	// handlers registered elsewhere, by any workflow.
+	handled := make(map[string]bool) // "workflow kind name", or " kind name" outside workflows
+	for _, h := range code.Handlers {
+		key := h.Kind + " " + h.Name
+		if _, ok := workflows[h.Func]; !ok {
+			handled[" "+key] = true
+			if !declared[key] {
+				findings = append(findings, Finding{
+					Kind:    UndocumentedHandler,
+					Name:    h.Name,
+					Message: fmt.Sprintf("%s %s is handled in %s but no workflow in the design declares it", h.Kind, h.Name, h.Func),
+					File:    h.File,
+					Line:    h.Line,
+				})
+			}
+			continue
+		}
+		handled[h.Func+" "+key] = true
+		if !slices.Contains(declaredBy[h.Func], key) {
+			findings = append(findings, Finding{
+				Kind:    UndocumentedHandler,
+				Name:    h.Name,
+				Message: fmt.Sprintf("workflow %s handles %s %s, which the design does not declare", h.Func, h.Kind, h.Name),
+				File:    h.File,
+				Line:    h.Line,
+			})
+		}
+	}
+	for _, impl := range code.Workflows {
+		for _, key := range declaredBy[impl.Name] {
+			if handled[impl.Name+" "+key] || handled[" "+key] {
+				continue
+			}
+			pos := positions["workflow "+impl.Name]
+			findings = append(findings, Finding{
+				Kind:    UnhandledHandler,
+				Name:    impl.Name,
+				Message: fmt.Sprintf("workflow %s declares %s, which the code does not handle", impl.Name, key),
+				File:    pos.SourceFile,
+				Line:    pos.Line,
+			})
+		}
+	}
+
+	slices.SortFunc(findings, func(a, b Finding) int {
+		return cmp.Or(cmp.Compare(a.File, b.File), cmp.Compare(a.Line, b.Line), cmp.Compare(a.Message, b.Message))
+	})
+	return findings
+}
+
+// position is where a definition is in the design.
+type position struct {
+	SourceFile string
+	Line       int
+}
+
+func definitionPositions(file *ast.File) map[string]position {
+	positions := make(map[string]position)
+	for _, def := range file.Definitions {
+		switch def := def.(type) {
+		case *ast.WorkflowDef:
+			positions["workflow "+def.Name] = position{def.SourceFile, def.Line}
+		case *ast.ActivityDef:
+			positions["activity "+def.Name] = position{def.SourceFile, def.Line}
+		}
+	}
+	return positions
+}
+
+// signature is a designed signature in Go types. An empty type matches any.
+type signature struct {
+	params, results []string
+}
+
+func newSignature(params []codegen.Param, results []string) signature {
+	var s signature
+	for _, p := range params {
+		t := ""
+		if p.Type != "" {
+			t = codegen.GoType(p.Type)
+		}
+		s.params = append(s.params, t)
+	}
+	for _, r := range results {
+		s.results = append(s.results, codegen.GoType(r))
+	}
+	return s
+}
+
+// mismatch describes how impl differs from s, or returns "".
+func (s signature) mismatch(impl *Func) string {
+	if !typesMatch(s.params, impl.Params) {
+		return fmt.Sprintf("takes (%s) in code but (%s) in the design", strings.Join(impl.Params, ", "), typeList(s.params))
+	}
+	if !typesMatch(s.results, impl.Results) {
+		return fmt.Sprintf("returns (%s) in code but (%s) in the design", strings.Join(impl.Results, ", "), typeList(s.results))
+	}
+	return ""
+}
+
+// typeList joins designed types, showing untyped parameters as "?".
+func typeList(types []string) string {
+	out := make([]string, len(types))
+	for i, t := range types {
+		out[i] = cmp.Or(t, "?")
+	}
+	return strings.Join(out, ", ")
+}
+
+func typesMatch(designed, implemented []string) bool {
+	if len(designed) != len(implemented) {
+		return false
+	}
+	for i, t := range designed {
+		if t != "" && normalize(t) != normalize(implemented[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+var qualifier = regexp.MustCompile(`\b[A-Za-z_]\w*\.`)
+
+// normalize drops package qualifiers and pointers and spells the empty
+// interface as any, so orders.Order, *Order, and Order compare equal.
+func normalize(t string) string {
+	t = qualifier.ReplaceAllString(t, "")
+	t = strings.ReplaceAll(t, "*", "")
+	return strings.ReplaceAll(t, "interface{}", "any")
+}
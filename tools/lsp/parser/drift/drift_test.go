@@ -0,0 +1,185 @@
+package drift
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/twftest"
+)
+
+const design = `workflow Order(order: Order, rush: bool) -> (Receipt):
+    signal Cancel(reason: string):
+        close fail(reason)
+    query Status() -> (string):
+        return status
+    activity Charge(order) -> payment
+    activity Ship(order)
+    close complete(Receipt{})
+
+activity Charge(order: Order) -> (Payment):
+    return payment
+
+activity Ship(order: Order):
+    return
+
+activity Notify(to: string):
+    return
+`
+
+const workflows = `package orders
+
+import (
+	"time"
+
+	wf "go.temporal.io/sdk/workflow"
+)
+
+const OrderCancelSignal = "Cancel"
+
+func Order(ctx wf.Context, order *Order, rush bool) (Receipt, error) {
+	cancel := wf.GetSignalChannel(ctx, OrderCancelSignal)
+	pause := wf.GetSignalChannel(ctx, "Pause")
+	_, _ = cancel, pause
+	return Receipt{}, nil
+}
+
+func registerDebug(ctx wf.Context) {
+	wf.SetQueryHandler(ctx, "Debug", func() (string, error) { return "", nil })
+}
+
+func Refund(ctx wf.Context, order Order, after time.Duration) error {
+	return nil
+}
+`
+
+const activities = `package orders
+
+import "context"
+
+type Activities struct{}
+
+func (a *Activities) Charge(ctx context.Context, order orders.Order, amount int) (Payment, error) {
+	return Payment{}, nil
+}
+
+func Ship(ctx context.Context, order Order) error {
+	return nil
+}
+
+func helper(ctx context.Context) {}
+`
+
+func mustResolve(t *testing.T, input string) *ast.File {
+	t.Helper()
+	file := twftest.AssertResolves(t, input)
+	for _, def := range file.Definitions {
+		switch def := def.(type) {
+		case *ast.WorkflowDef:
+			def.SourceFile = "orders.twf"
+		case *ast.ActivityDef:
+			def.SourceFile = "orders.twf"
+		}
+	}
+	return file
+}
+
+func writeCode(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestScanGo(t *testing.T) {
+	dir := writeCode(t, map[string]string{
+		"workflows.go":            workflows,
+		"activities.go":           activities,
+		"workflows_test.go":       "package orders\n\nimport \"context\"\n\nfunc TestOnly(ctx context.Context) {}\n",
+		"vendor/x/x.go":           "package x\n\nimport \"context\"\n\nfunc Vendored(ctx context.Context) {}\n",
+		"internal/more/signal.go": "package more\n\nimport \"go.temporal.io/sdk/workflow\"\n\nfunc Nested(ctx workflow.Context) {}\n",
+	})
+	code, err := ScanGo(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, f := range code.Workflows {
+		names = append(names, f.Name)
+	}
+	for _, f := range code.Activities {
+		names = append(names, f.Name)
+	}
+	if got := strings.Join(names, ","); got != "Nested,Order,Refund,Charge,Ship" {
+		t.Errorf("unexpected implementations: %s", got)
+	}
+	order := code.Workflows[1]
+	if strings.Join(order.Params, ",") != "*Order,bool" || strings.Join(order.Results, ",") != "Receipt" {
+		t.Errorf("unexpected signature: %+v", order)
+	}
+	if !strings.HasSuffix(order.File, "workflows.go") || order.Line != 11 {
+		t.Errorf("unexpected position: %s:%d", order.File, order.Line)
+	}
+
+	var handlers []string
+	for _, h := range code.Handlers {
+		handlers = append(handlers, h.Func+":"+h.Kind+" "+h.Name)
+	}
+	if got := strings.Join(handlers, ","); got != "Order:signal Cancel,Order:signal Pause,registerDebug:query Debug" {
+		t.Errorf("unexpected handlers: %s", got)
+	}
+
+	if _, err := ScanGo(writeCode(t, map[string]string{"bad.go": "package"})); err == nil {
+		t.Error("expected a Go syntax error")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	code, err := ScanGo(writeCode(t, map[string]string{"workflows.go": workflows, "activities.go": activities}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range Compare(mustResolve(t, design), code) {
+		got = append(got, f.Kind+": "+filepath.Base(f.File)+": "+f.Message)
+	}
+	want := []string{
+		"signature: activities.go: activity Charge takes (orders.Order, int) in code but (Order) in the design",
+		"undocumented-handler: workflows.go: workflow Order handles signal Pause, which the design does not declare",
+		"undocumented-handler: workflows.go: query Debug is handled in registerDebug but no workflow in the design declares it",
+		"missing-in-design: workflows.go: workflow Refund is implemented but not designed",
+		"unhandled: orders.twf: workflow Order declares query Status, which the code does not handle",
+		"missing-in-code: orders.twf: activity Notify is designed but not implemented",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("findings:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCompareInSync(t *testing.T) {
+	code, err := ScanGo(writeCode(t, map[string]string{"activities.go": `package orders
+
+import "context"
+
+func Notify(ctx context.Context, to string) error { return nil }
+`}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := mustResolve(t, "activity Notify(to: string):\n    return\n\nactivity Log(line):\n    return\n")
+	findings := Compare(file, code)
+	if len(findings) != 1 || findings[0].Kind != MissingInCode || findings[0].String() != "orders.twf:4: activity Log is designed but not implemented" {
+		t.Errorf("unexpected findings: %v", findings)
+	}
+}
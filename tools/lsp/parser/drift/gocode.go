@@ -0,0 +1,238 @@
+package drift
+
+import (
+	goast "go/ast"
+	goparser "go/parser"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	sdkWorkflow = "go.temporal.io/sdk/workflow"
+	stdContext  = "context"
+)
+
+// Code is what a Go worker implements, as found by ScanGo.
+type Code struct {
+	Workflows  []*Func
+	Activities []*Func
+	Handlers   []*Handler
+}
+
+// Func is an exported function or method implementing a workflow, whose
+// first parameter is a workflow.Context, or an activity, whose first
+// parameter is a context.Context. Params and Results are Go type
+// expressions without the context and the trailing error.
+type Func struct {
+	Name    string
+	Params  []string
+	Results []string
+	File    string
+	Line    int
+}
+
+// Handler is a signal, query, or update handler registered with the
+// workflow package. Func is the function registering it.
+type Handler struct {
+	Kind string // "signal", "query", or "update"
+	Name string
+	Func string
+	File string
+	Line int
+}
+
+// handlerCalls maps the workflow package functions that register handlers
+// to the handler kind.
+var handlerCalls = map[string]string{
+	"GetSignalChannel":            "signal",
+	"GetSignalChannelWithOptions": "signal",
+	"SetQueryHandler":             "query",
+	"SetQueryHandlerWithOptions":  "query",
+	"SetUpdateHandler":            "update",
+	"SetUpdateHandlerWithOptions": "update",
+}
+
+// ScanGo reads the Go files under dir, skipping tests and vendor, testdata,
+// and hidden directories. Handler names may be string literals or string
+// constants declared in any of the files.
+func ScanGo(dir string) (*Code, error) {
+	fset := token.NewFileSet()
+	var files []*goast.File
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != dir && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			return nil
+		}
+		f, err := goparser.ParseFile(fset, path, nil, goparser.SkipObjectResolution)
+		if err != nil {
+			return err
+		}
+		files = append(files, f)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	consts := stringConsts(files)
+	code := &Code{}
+	for _, f := range files {
+		scanFile(code, fset, f, consts)
+	}
+	return code, nil
+}
+
+// stringConsts collects the package-level constants with string literal
+// values.
+func stringConsts(files []*goast.File) map[string]string {
+	consts := make(map[string]string)
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*goast.GenDecl)
+			if !ok || gen.Tok != token.CONST {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				vs := spec.(*goast.ValueSpec)
+				for i, name := range vs.Names {
+					if i >= len(vs.Values) {
+						break
+					}
+					if s, ok := stringLit(vs.Values[i]); ok {
+						consts[name.Name] = s
+					}
+				}
+			}
+		}
+	}
+	return consts
+}
+
+func scanFile(code *Code, fset *token.FileSet, f *goast.File, consts map[string]string) {
+	workflowPkg, contextPkg := importName(f, sdkWorkflow), importName(f, stdContext)
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*goast.FuncDecl)
+		if !ok {
+			continue
+		}
+		if workflowPkg != "" && fn.Body != nil {
+			code.Handlers = append(code.Handlers, handlers(fset, fn, workflowPkg, consts)...)
+		}
+		params := fieldTypes(fn.Type.Params)
+		if !fn.Name.IsExported() || len(params) == 0 {
+			continue
+		}
+		pos := fset.Position(fn.Pos())
+		impl := &Func{
+			Name:    fn.Name.Name,
+			Params:  params[1:],
+			Results: fieldTypes(fn.Type.Results),
+			File:    pos.Filename,
+			Line:    pos.Line,
+		}
+		if n := len(impl.Results); n > 0 && impl.Results[n-1] == "error" {
+			impl.Results = impl.Results[:n-1]
+		}
+		switch params[0] {
+		case workflowPkg + ".Context":
+			code.Workflows = append(code.Workflows, impl)
+		case contextPkg + ".Context":
+			code.Activities = append(code.Activities, impl)
+		}
+	}
+}
+
+// handlers returns the handlers fn registers.
+func handlers(fset *token.FileSet, fn *goast.FuncDecl, workflowPkg string, consts map[string]string) []*Handler {
+	var out []*Handler
+	goast.Inspect(fn.Body, func(n goast.Node) bool {
+		call, ok := n.(*goast.CallExpr)
+		if !ok || len(call.Args) < 2 {
+			return true
+		}
+		sel, ok := call.Fun.(*goast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		pkg, ok := sel.X.(*goast.Ident)
+		kind := handlerCalls[sel.Sel.Name]
+		if !ok || pkg.Name != workflowPkg || kind == "" {
+			return true
+		}
+		name, ok := handlerName(call.Args[1], consts)
+		if !ok {
+			return true
+		}
+		pos := fset.Position(call.Pos())
+		out = append(out, &Handler{Kind: kind, Name: name, Func: fn.Name.Name, File: pos.Filename, Line: pos.Line})
+		return true
+	})
+	return out
+}
+
+// handlerName resolves a handler name argument: a string literal or a
+// string constant, possibly package-qualified.
+func handlerName(arg goast.Expr, consts map[string]string) (string, bool) {
+	switch arg := arg.(type) {
+	case *goast.Ident:
+		s, ok := consts[arg.Name]
+		return s, ok
+	case *goast.SelectorExpr:
+		s, ok := consts[arg.Sel.Name]
+		return s, ok
+	}
+	return stringLit(arg)
+}
+
+func stringLit(e goast.Expr) (string, bool) {
+	lit, ok := e.(*goast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return s, err == nil
+}
+
+// importName returns the name f refers to the package at path by, or "" if
+// f does not import it.
+func importName(f *goast.File, path string) string {
+	for _, imp := range f.Imports {
+		if p, _ := strconv.Unquote(imp.Path.Value); p != path {
+			continue
+		}
+		if imp.Name != nil {
+			return imp.Name.Name
+		}
+		return path[strings.LastIndex(path, "/")+1:]
+	}
+	return ""
+}
+
+// fieldTypes returns the type of each parameter or result in fields,
+// repeating a type shared by several names.
+func fieldTypes(fields *goast.FieldList) []string {
+	if fields == nil {
+		return nil
+	}
+	var out []string
+	for _, field := range fields.List {
+		t := types.ExprString(field.Type)
+		for range max(len(field.Names), 1) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
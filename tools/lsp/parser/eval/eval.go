@@ -0,0 +1,327 @@
+// Package eval folds TWF expressions to constant values.
+//
+// TWF designs are not executed, so the evaluator only answers questions that
+// can be decided from literals and known constants: whether a condition is
+// always true, whether two case values are equal, and so on. Anything that
+// depends on runtime data evaluates to "not constant".
+package eval
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+)
+
+// Kind identifies the type of a constant Value.
+type Kind int
+
+const (
+	KindInvalid Kind = iota
+	KindNumber
+	KindString
+	KindBool
+	KindDuration
+)
+
+// Value is a folded constant.
+type Value struct {
+	Kind Kind
+	Num  float64       // KindNumber
+	Str  string        // KindString
+	Bool bool          // KindBool
+	Dur  time.Duration // KindDuration
+}
+
+// String renders the value as TWF source.
+func (v Value) String() string {
+	switch v.Kind {
+	case KindNumber:
+		return strconv.FormatFloat(v.Num, 'f', -1, 64)
+	case KindString:
+		return strconv.Quote(v.Str)
+	case KindBool:
+		return strconv.FormatBool(v.Bool)
+	case KindDuration:
+		return v.Dur.String()
+	default:
+		return "<invalid>"
+	}
+}
+
+// Equal reports whether two constants have the same kind and value.
+func (v Value) Equal(o Value) bool {
+	if v.Kind != o.Kind {
+		return false
+	}
+	switch v.Kind {
+	case KindNumber:
+		return v.Num == o.Num
+	case KindString:
+		return v.Str == o.Str
+	case KindBool:
+		return v.Bool == o.Bool
+	case KindDuration:
+		return v.Dur == o.Dur
+	default:
+		return false
+	}
+}
+
+// Env maps identifiers to constant values. A nil Env treats every
+// identifier as non-constant.
+type Env map[string]Value
+
+// Eval folds x to a constant. ok is false when x depends on a non-constant
+// identifier, uses an unsupported form (lists, maps, field access), or
+// combines operands of mismatched types.
+func Eval(x ast.Expr, env Env) (v Value, ok bool) {
+	switch x := x.(type) {
+	case *ast.NumberLit:
+		n, err := strconv.ParseFloat(x.Value, 64)
+		if err != nil {
+			return Value{}, false
+		}
+		return Value{Kind: KindNumber, Num: n}, true
+	case *ast.StringLit:
+		return Value{Kind: KindString, Str: x.Value}, true
+	case *ast.BoolLit:
+		return Value{Kind: KindBool, Bool: x.Value}, true
+	case *ast.DurationLit:
+		d, ok := ParseDuration(x.Value)
+		if !ok {
+			return Value{}, false
+		}
+		return Value{Kind: KindDuration, Dur: d}, true
+	case *ast.Ident:
+		v, ok := env[x.Name]
+		return v, ok
+	case *ast.UnaryExpr:
+		return evalUnary(x, env)
+	case *ast.BinaryExpr:
+		return evalBinary(x, env)
+	default:
+		return Value{}, false
+	}
+}
+
+// Truth folds a condition. ok is false when the condition is not a
+// constant boolean.
+func Truth(x ast.Expr, env Env) (value, ok bool) {
+	v, ok := Eval(x, env)
+	if !ok || v.Kind != KindBool {
+		return false, false
+	}
+	return v.Bool, true
+}
+
+// ParseDuration parses a TWF duration literal (e.g. 500ms, 30s, 7d).
+func ParseDuration(lit string) (time.Duration, bool) {
+	if n, found := strings.CutSuffix(lit, "d"); found {
+		f, err := strconv.ParseFloat(n, 64)
+		if err != nil {
+			return 0, false
+		}
+		return time.Duration(f * float64(24*time.Hour)), true
+	}
+	d, err := time.ParseDuration(lit)
+	if err != nil {
+		return 0, false
+	}
+	return d, true
+}
+
+// FreeIdents returns the root identifiers an expression reads, in first-use
+// order without duplicates. For order.total the root is order.
+func FreeIdents(x ast.Expr) []string {
+	var names []string
+	seen := make(map[string]bool)
+	var walk func(ast.Expr)
+	walk = func(x ast.Expr) {
+		switch x := x.(type) {
+		case *ast.Ident:
+			if !seen[x.Name] {
+				seen[x.Name] = true
+				names = append(names, x.Name)
+			}
+		case *ast.SelectorExpr:
+			walk(x.X)
+		case *ast.UnaryExpr:
+			walk(x.X)
+		case *ast.BinaryExpr:
+			walk(x.X)
+			walk(x.Y)
+		case *ast.ListLit:
+			for _, e := range x.Elems {
+				walk(e)
+			}
+		case *ast.MapLit:
+			for _, e := range x.Entries {
+				walk(e.Value)
+			}
+		}
+	}
+	walk(x)
+	return names
+}
+
+func evalUnary(x *ast.UnaryExpr, env Env) (Value, bool) {
+	v, ok := Eval(x.X, env)
+	if !ok {
+		return Value{}, false
+	}
+	switch x.Op {
+	case "not", "!":
+		if v.Kind == KindBool {
+			return Value{Kind: KindBool, Bool: !v.Bool}, true
+		}
+	case "-":
+		switch v.Kind {
+		case KindNumber:
+			return Value{Kind: KindNumber, Num: -v.Num}, true
+		case KindDuration:
+			return Value{Kind: KindDuration, Dur: -v.Dur}, true
+		}
+	}
+	return Value{}, false
+}
+
+func evalBinary(x *ast.BinaryExpr, env Env) (Value, bool) {
+	switch x.Op {
+	case "and", "&&":
+		return evalLogical(x, env, false)
+	case "or", "||":
+		return evalLogical(x, env, true)
+	}
+
+	l, ok := Eval(x.X, env)
+	if !ok {
+		return Value{}, false
+	}
+	r, ok := Eval(x.Y, env)
+	if !ok {
+		return Value{}, false
+	}
+
+	switch x.Op {
+	case "==":
+		return boolValue(l.Equal(r)), l.Kind == r.Kind
+	case "!=":
+		return boolValue(!l.Equal(r)), l.Kind == r.Kind
+	case "<", "<=", ">", ">=":
+		c, ok := compare(l, r)
+		if !ok {
+			return Value{}, false
+		}
+		switch x.Op {
+		case "<":
+			return boolValue(c < 0), true
+		case "<=":
+			return boolValue(c <= 0), true
+		case ">":
+			return boolValue(c > 0), true
+		default:
+			return boolValue(c >= 0), true
+		}
+	default:
+		return arith(x.Op, l, r)
+	}
+}
+
+// evalLogical folds and/or with short-circuiting: a constant left operand
+// equal to short decides the result even when the right side is unknown.
+func evalLogical(x *ast.BinaryExpr, env Env, short bool) (Value, bool) {
+	l, lok := Truth(x.X, env)
+	if lok && l == short {
+		return boolValue(short), true
+	}
+	r, rok := Truth(x.Y, env)
+	if rok && r == short {
+		return boolValue(short), true
+	}
+	if lok && rok {
+		return boolValue(r), true
+	}
+	return Value{}, false
+}
+
+// compare orders two constants of the same ordered kind.
+func compare(l, r Value) (int, bool) {
+	if l.Kind != r.Kind {
+		return 0, false
+	}
+	switch l.Kind {
+	case KindNumber:
+		return cmp3(l.Num < r.Num, l.Num > r.Num), true
+	case KindDuration:
+		return cmp3(l.Dur < r.Dur, l.Dur > r.Dur), true
+	case KindString:
+		return strings.Compare(l.Str, r.Str), true
+	default:
+		return 0, false
+	}
+}
+
+func cmp3(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// arith folds + - * / % over numbers and durations. Durations may be added
+// to or subtracted from durations, and scaled by numbers.
+func arith(op string, l, r Value) (Value, bool) {
+	switch {
+	case l.Kind == KindNumber && r.Kind == KindNumber:
+		switch op {
+		case "+":
+			return Value{Kind: KindNumber, Num: l.Num + r.Num}, true
+		case "-":
+			return Value{Kind: KindNumber, Num: l.Num - r.Num}, true
+		case "*":
+			return Value{Kind: KindNumber, Num: l.Num * r.Num}, true
+		case "/":
+			if r.Num == 0 {
+				return Value{}, false
+			}
+			return Value{Kind: KindNumber, Num: l.Num / r.Num}, true
+		case "%":
+			if r.Num == 0 || l.Num != float64(int64(l.Num)) || r.Num != float64(int64(r.Num)) {
+				return Value{}, false
+			}
+			return Value{Kind: KindNumber, Num: float64(int64(l.Num) % int64(r.Num))}, true
+		}
+	case l.Kind == KindDuration && r.Kind == KindDuration:
+		switch op {
+		case "+":
+			return Value{Kind: KindDuration, Dur: l.Dur + r.Dur}, true
+		case "-":
+			return Value{Kind: KindDuration, Dur: l.Dur - r.Dur}, true
+		}
+	case l.Kind == KindDuration && r.Kind == KindNumber:
+		switch op {
+		case "*":
+			return Value{Kind: KindDuration, Dur: time.Duration(float64(l.Dur) * r.Num)}, true
+		case "/":
+			if r.Num == 0 {
+				return Value{}, false
+			}
+			return Value{Kind: KindDuration, Dur: time.Duration(float64(l.Dur) / r.Num)}, true
+		}
+	case l.Kind == KindNumber && r.Kind == KindDuration && op == "*":
+		return Value{Kind: KindDuration, Dur: time.Duration(l.Num * float64(r.Dur))}, true
+	case l.Kind == KindString && r.Kind == KindString && op == "+":
+		return Value{Kind: KindString, Str: l.Str + r.Str}, true
+	}
+	return Value{}, false
+}
+
+func boolValue(b bool) Value {
+	return Value{Kind: KindBool, Bool: b}
+}
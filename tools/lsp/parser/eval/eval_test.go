@@ -0,0 +1,104 @@
+package eval
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/parser"
+)
+
+func mustParseExpr(t *testing.T, input string) Value {
+	t.Helper()
+	x, err := parser.ParseExpr(input)
+	if err != nil {
+		t.Fatalf("ParseExpr(%q): %v", input, err)
+	}
+	v, ok := Eval(x, nil)
+	if !ok {
+		t.Fatalf("Eval(%q): expected constant", input)
+	}
+	return v
+}
+
+func TestEvalConstants(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Value
+	}{
+		{`1 + 2 * 3`, Value{Kind: KindNumber, Num: 7}},
+		{`7 % 3`, Value{Kind: KindNumber, Num: 1}},
+		{`-2.5`, Value{Kind: KindNumber, Num: -2.5}},
+		{`"a" + "b"`, Value{Kind: KindString, Str: "ab"}},
+		{`1m + 30s`, Value{Kind: KindDuration, Dur: 90 * time.Second}},
+		{`2 * 1d`, Value{Kind: KindDuration, Dur: 48 * time.Hour}},
+		{`3 < 5 and not false`, Value{Kind: KindBool, Bool: true}},
+		{`1h > 90m`, Value{Kind: KindBool, Bool: false}},
+		{`"gold" == "gold"`, Value{Kind: KindBool, Bool: true}},
+		{`!true || 1 != 1`, Value{Kind: KindBool, Bool: false}},
+	}
+	for _, tt := range tests {
+		if got := mustParseExpr(t, tt.input); !got.Equal(tt.want) {
+			t.Errorf("Eval(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestEvalNonConstant(t *testing.T) {
+	for _, input := range []string{
+		`retries < 3`,
+		`order.total > 100`,
+		`1 / 0`,
+		`"a" < 1`,
+		`1 == "1"`,
+		`[1, 2]`,
+		`true and ready`,
+	} {
+		x, err := parser.ParseExpr(input)
+		if err != nil {
+			t.Fatalf("ParseExpr(%q): %v", input, err)
+		}
+		if v, ok := Eval(x, nil); ok {
+			t.Errorf("Eval(%q): expected non-constant, got %s", input, v)
+		}
+	}
+}
+
+func TestEvalShortCircuit(t *testing.T) {
+	for input, want := range map[string]bool{
+		`false and ready`: false,
+		`ready and false`: false,
+		`true or ready`:   true,
+		`ready || true`:   true,
+	} {
+		x, err := parser.ParseExpr(input)
+		if err != nil {
+			t.Fatalf("ParseExpr(%q): %v", input, err)
+		}
+		got, ok := Truth(x, nil)
+		if !ok || got != want {
+			t.Errorf("Truth(%q) = %t, %t; want %t, true", input, got, ok, want)
+		}
+	}
+}
+
+func TestEvalEnv(t *testing.T) {
+	x, err := parser.ParseExpr(`limit * 2`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, ok := Eval(x, Env{"limit": {Kind: KindNumber, Num: 4}})
+	if !ok || v.Num != 8 {
+		t.Errorf("expected 8, got %s (ok=%t)", v, ok)
+	}
+}
+
+func TestFreeIdents(t *testing.T) {
+	x, err := parser.ParseExpr(`order.total > limit and order.paid`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := FreeIdents(x)
+	if len(got) != 2 || got[0] != "order" || got[1] != "limit" {
+		t.Errorf("expected [order limit], got %v", got)
+	}
+}
@@ -0,0 +1,59 @@
+// Package examples embeds canonical TWF designs, one for each common
+// idiom, for learning the language from the CLI (twf examples) and as a
+// corpus the whole analysis pipeline is tested against.
+//
+// Each example is a file named after it, opening with a comment whose
+// first line summarizes it. Together they form a valid workspace: their
+// definition names do not collide.
+package examples
+
+import (
+	"embed"
+	"io/fs"
+	"strings"
+)
+
+//go:embed *.twf
+var files embed.FS
+
+// Example is one embedded design.
+type Example struct {
+	Name    string `json:"name"` // the file name without .twf, such as "saga"
+	Summary string `json:"summary"`
+	Source  string `json:"source"`
+}
+
+// All returns the examples, sorted by name.
+func All() []Example {
+	// ReadDir sorts by file name.
+	entries, _ := fs.ReadDir(files, ".")
+	out := make([]Example, 0, len(entries))
+	for _, e := range entries {
+		ex, _ := Lookup(strings.TrimSuffix(e.Name(), ".twf"))
+		out = append(out, ex)
+	}
+	return out
+}
+
+// Names returns the names of the examples, sorted.
+func Names() []string {
+	var names []string
+	for _, ex := range All() {
+		names = append(names, ex.Name)
+	}
+	return names
+}
+
+// Lookup returns the example named name.
+func Lookup(name string) (Example, bool) {
+	if strings.ContainsAny(name, "/.") {
+		return Example{}, false
+	}
+	data, err := files.ReadFile(name + ".twf")
+	if err != nil {
+		return Example{}, false
+	}
+	src := string(data)
+	first, _, _ := strings.Cut(src, "\n")
+	return Example{Name: name, Summary: strings.TrimPrefix(first, "# "), Source: src}, true
+}
@@ -0,0 +1,79 @@
+package examples
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/deps"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/parser"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/resolver"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/validator"
+)
+
+// strict enables every lint rule, which the examples, as designs to copy,
+// must all pass.
+var strict = validator.Policy{
+	RequireOwner:           true,
+	CriticalTag:            "critical",
+	RequireTimeout:         true,
+	RequireActivityTimeout: true,
+	UnknownNames:           validator.UnknownNamesAll,
+	ReturnInWorkflow:       validator.ReturnInWorkflowError,
+}
+
+// TestExamples runs each example through the pipeline twf check and twf
+// graph run, expecting no diagnostics from any stage.
+func TestExamples(t *testing.T) {
+	want := []string{"approval", "order-fulfillment", "polling", "saga"}
+	if got := Names(); !slices.Equal(got, want) {
+		t.Fatalf("Names() = %v, want %v", got, want)
+	}
+	merged := &ast.File{}
+	for _, ex := range All() {
+		t.Run(ex.Name, func(t *testing.T) {
+			if ex.Summary == "" || ex.Summary == ex.Source {
+				t.Errorf("summary %q is not the first comment line", ex.Summary)
+			}
+			file, errs := parser.ParseFileAll(ex.Source)
+			for _, e := range errs {
+				t.Errorf("parse: %v", e)
+			}
+			for _, def := range file.Definitions {
+				ast.SetSourceFile(def, ex.Name+".twf")
+			}
+			merged.Definitions = append(merged.Definitions, file.Definitions...)
+			checkFile(t, file)
+		})
+	}
+	t.Run("together", func(t *testing.T) { checkFile(t, merged) })
+}
+
+// checkFile resolves, validates, lints, and graphs file.
+func checkFile(t *testing.T, file *ast.File) {
+	t.Helper()
+	resolved := resolver.ResolveFile(file)
+	for _, e := range resolved.Errors {
+		t.Errorf("resolve: %d:%d: %s", e.Line, e.Column, e.Msg)
+	}
+	for _, e := range validator.ValidateSymbols(resolved.Symbols) {
+		t.Errorf("validate: %d:%d: %s", e.Line, e.Column, e.Msg)
+	}
+	for _, e := range validator.CheckPolicy(resolved.Symbols, strict) {
+		t.Errorf("lint: %d:%d: %s", e.Line, e.Column, e.Msg)
+	}
+	if g := deps.Extract(file); len(g.Edges) == 0 {
+		t.Error("graph has no edges")
+	}
+}
+
+func TestLookup(t *testing.T) {
+	if ex, ok := Lookup("saga"); !ok || ex.Name != "saga" {
+		t.Errorf("Lookup(saga) = %+v, %v", ex, ok)
+	}
+	for _, name := range []string{"", "missing", "../saga", "saga.twf"} {
+		if _, ok := Lookup(name); ok {
+			t.Errorf("Lookup(%q) found an example", name)
+		}
+	}
+}
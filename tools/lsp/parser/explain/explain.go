@@ -0,0 +1,356 @@
+// Package explain holds a short explanation and an example of each TWF
+// construct, for learning the language from the CLI (twf explain) and the
+// editor (the language server's twf/explain request).
+package explain
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Entry explains one construct.
+type Entry struct {
+	Keyword string `json:"keyword"` // the construct as written, such as "await one"
+	Summary string `json:"summary"`
+	Example string `json:"example"`
+	// Removed marks constructs the language no longer has; Summary names
+	// what replaces them and Example shows it.
+	Removed bool `json:"removed,omitempty"`
+
+	also []string // other keywords of the construct, such as "elif" for "if"
+}
+
+// Entries are the constructs explained, in the order twf explain lists
+// them.
+var Entries = []Entry{
+	{
+		Keyword: "workflow",
+		Summary: "Defines a workflow: durable orchestration code whose progress survives crashes and restarts. Inside a workflow, `workflow Name(args)` starts a child workflow and waits for its result; `id \"...\"` sets the child's workflow ID.",
+		Example: `workflow ProcessOrder(order: Order) -> (Receipt):
+    activity Charge(order) -> receipt
+    workflow ShipOrder(order) id "ship-{order.id}"
+    close complete(receipt)`,
+	},
+	{
+		Keyword: "activity",
+		Summary: "Defines an activity: a unit of side-effecting work, such as a network call, that Temporal retries on failure. Inside a workflow, `activity Name(args) -> result` runs it and binds its result.",
+		Example: `activity Charge(order: Order) -> (Receipt):
+    receipt = gateway.charge(order)
+    return receipt`,
+	},
+	{
+		Keyword: "description",
+		Summary: "States in prose what a workflow is for. It comes first in the workflow, and hovering the workflow or a call to it shows it.",
+		Example: `workflow Refund(order: Order):
+    description:
+        Returns the customer's money when an order is cancelled.
+    activity Reverse(order)
+    close complete`,
+	},
+	{
+		Keyword: "state",
+		Summary: "Declares a workflow's state at the top of its body: named conditions and initial values, which signal and update handlers may change. It holds declarations only, no calls or waits.",
+		Example: `workflow Approval(req: Request):
+    state:
+        condition approved
+        attempts: int = 0
+    await approved
+    close complete`,
+	},
+	{
+		Keyword: "condition",
+		Summary: "A named boolean declared in `state:`. `set` makes it true and `unset` false, usually in a signal or update handler, and `await name` waits until it is true.",
+		Example: `workflow Approval(req: Request):
+    state:
+        condition approved
+    signal Approve():
+        set approved
+    await approved
+    close complete`,
+		also: []string{"set", "unset"},
+	},
+	{
+		Keyword: "signal",
+		Summary: "Declares a signal handler: a message sent to a running workflow, with a body that runs when it arrives. `await signal Name` waits for one.",
+		Example: `workflow Order(order: Order):
+    signal Cancel(reason: string):
+        cancelled = true
+    await signal Cancel -> (reason)
+    close fail(reason)`,
+	},
+	{
+		Keyword: "query",
+		Summary: "Declares a query handler: a read-only question about a running workflow, answered with return. Queries must not change state or wait.",
+		Example: `workflow Order(order: Order):
+    query Status() -> (string):
+        return status
+    activity Ship(order)
+    close complete`,
+	},
+	{
+		Keyword: "update",
+		Summary: "Declares an update handler: a message that may change a running workflow and returns a result to its sender. `await update Name` waits for one.",
+		Example: `workflow Order(order: Order):
+    update ChangeAddress(addr: Address) -> (bool):
+        order.address = addr
+        return true
+    await update ChangeAddress
+    close complete`,
+	},
+	{
+		Keyword: "await",
+		Summary: "Waits for one thing to finish: a timer, a signal or update, an activity or child workflow, a nexus call, a promise, or a condition. `await all` and `await one` wait for several.",
+		Example: `await timer(5m)
+await signal Approved -> (approver)
+await activity Process(data) -> result
+await reportPromise -> report`,
+	},
+	{
+		Keyword: "await all",
+		Summary: "Runs the statements of its block side by side and waits until all of them finish. `options(onError: continue, minSuccess: N)` tolerates some failures.",
+		Example: `await all:
+    activity ReserveInventory(order)
+    activity ChargePayment(order)`,
+	},
+	{
+		Keyword: "await one",
+		Summary: "Races its cases, such as signals, timers, and calls, and runs the body of the first to finish; the others are cancelled. A case may carry a guard, `if (expr)`.",
+		Example: `await one:
+    signal Approve -> (amount) if (amount < 1000):
+        activity AutoApprove(amount)
+    timer(24h):
+        close fail("approval timed out")`,
+	},
+	{
+		Keyword: "promise",
+		Summary: "Starts an asynchronous operation without waiting for it, binding it with `<-`. Await the promise later, alone or in an `await one` case.",
+		Example: `promise report <- workflow BuildReport(data)
+activity Notify(data)
+await report -> summary`,
+	},
+	{
+		Keyword: "timer",
+		Summary: "A durable sleep: `await timer(d)` waits for the duration, even across worker restarts. Durations are written like 30s, 5m, 24h, or 7d.",
+		Example: `await timer(1h)
+promise deadline <- timer(7d)`,
+	},
+	{
+		Keyword: "detach",
+		Summary: "Starts a child workflow or nexus operation fire-and-forget: the caller neither waits for it nor gets a result.",
+		Example: `detach workflow SendReceipt(order) id "receipt-{order.id}"
+detach nexus NotifyEndpoint NotifyService.SendEmail(email)`,
+	},
+	{
+		Keyword: "nexus",
+		Summary: "Calls across namespaces. `nexus service` defines operations, sync with a body or async backed by a workflow; `nexus Endpoint Service.Operation(args)` calls one through an endpoint a namespace declares.",
+		Example: `nexus service OrderService:
+    async PlaceOrder workflow ProcessOrder
+    sync GetStatus(id: string) -> (Status):
+        activity FetchStatus(id) -> status
+        close complete(status)
+
+workflow Checkout(order: Order):
+    nexus OrderEndpoint OrderService.PlaceOrder(order) -> result
+    close complete(result)`,
+		also: []string{"service", "endpoint", "sync", "async"},
+	},
+	{
+		Keyword: "close",
+		Summary: "Ends the workflow: `close complete` succeeds, with an optional result, and `close fail` fails, with an error or message. Only the workflow body can close; handlers cannot.",
+		Example: `if (order.total == 0):
+    close fail("empty order")
+close complete(Receipt{id: order.id})`,
+		also: []string{"complete", "fail", "close complete", "close fail"},
+	},
+	{
+		Keyword: "continue_as_new",
+		Summary: "Ends the current run and starts the workflow again with new arguments and an empty history, so long-running or looping workflows do not grow without bound.",
+		Example: `for (batch in batches):
+    activity Process(batch)
+close continue_as_new(cursor)`,
+		also: []string{"close continue_as_new"},
+	},
+	{
+		Keyword: "return",
+		Summary: "Returns a value from a query, update, or activity. In a workflow body it is deprecated in favor of `close complete`.",
+		Example: `query Status() -> (string):
+    return status`,
+	},
+	{
+		Keyword: "if",
+		Summary: "Runs a block when a condition holds. `elif` (or `else if`) chains further conditions, and `else` runs when none held.",
+		Example: `if (order.tier == "gold"):
+    activity Expedite(order)
+elif (order.tier == "silver"):
+    activity Prioritize(order)
+else:
+    activity Standard(order)`,
+		also: []string{"elif", "else"},
+	},
+	{
+		Keyword: "switch",
+		Summary: "Runs the case matching a value, or else the `else` block. A switch on an enum should cover every value.",
+		Example: `switch (order.kind):
+    case "invoice":
+        activity SendInvoice(order)
+    case "refund":
+        activity IssueRefund(order)
+    else:
+        close fail("unknown kind")`,
+		also: []string{"case"},
+	},
+	{
+		Keyword: "for",
+		Summary: "Loops: forever with no header, while a condition holds with `(expr)`, over a collection with `(item in items)`, or concurrently with `each (item in items) parallel(max: N)`.",
+		Example: `for (item in order.items):
+    activity Reserve(item)
+for each (item in order.items) parallel(max: 10):
+    activity Pack(item)`,
+		also: []string{"in", "each", "parallel"},
+	},
+	{
+		Keyword: "break",
+		Summary: "`break` leaves the innermost loop and `continue` starts its next pass. Both may name a labeled outer loop.",
+		Example: `outer: for (order in orders):
+    for (item in order.items):
+        if (item.recalled):
+            continue outer
+        activity Ship(item)`,
+		also: []string{"continue"},
+	},
+	{
+		Keyword: "heartbeat",
+		Summary: "Reports an activity's progress to Temporal, so a long activity is known to be alive and can resume from the last details it reported. Only activities heartbeat.",
+		Example: `activity Import(file: string):
+    for (chunk in chunks):
+        heartbeat(chunk.offset)
+    return`,
+	},
+	{
+		Keyword: "options",
+		Summary: "Sets Temporal options on the call above it, such as timeouts, the task queue, and the retry policy, one key per line. Opening an activity or workflow definition, it sets defaults for every call to it, which a call's own options override key by key.",
+		Example: `activity ChargePayment(order) -> payment
+    options:
+        start_to_close_timeout: 60s
+        retry_policy:
+            maximum_attempts: 3`,
+	},
+	{
+		Keyword: "worker",
+		Summary: "Defines a worker type: the workflows, activities, and nexus services one worker process registers. Namespaces deploy it.",
+		Example: `worker orderTypes:
+    workflow ProcessOrder
+    activity ChargePayment
+    nexus service OrderService`,
+	},
+	{
+		Keyword: "namespace",
+		Summary: "Deploys workers, each on a task queue, and declares the nexus endpoints other namespaces call it through.",
+		Example: `namespace orders:
+    worker orderTypes
+        options:
+            task_queue: "orders"
+    nexus endpoint OrderEndpoint
+        options:
+            task_queue: "orders"`,
+		also: []string{"task_queue"},
+	},
+	{
+		Keyword: "const",
+		Summary: "Names a string, duration, number, or bool once, for use in timers and option values.",
+		Example: `const approvalTimeout = 7d
+
+workflow Approval(req: Request):
+    await timer(approvalTimeout)
+    close complete`,
+	},
+	{
+		Keyword: "enum",
+		Summary: "Names a closed set of values. A switch on a name of an enum type must cover every value or have an else.",
+		Example: `enum OrderType: invoice, refund
+
+workflow Process(kind: OrderType):
+    switch (kind):
+        case invoice:
+            activity SendInvoice(kind)
+        case refund:
+            activity IssueRefund(kind)
+    close complete`,
+	},
+	{
+		Keyword: "hint",
+		Summary: "`hint` and `hints` statements were removed from the language, and such a line is warned about as having no effect. Signals and updates are waited for with `await signal` or `await update`, or raced in `await one` with a case for each; `twf fix --apply hint-to-await` rewrites a hint to that block.",
+		Example: `await one:
+    signal Approved:
+    signal AdminOverride:`,
+		Removed: true,
+	},
+	{
+		Keyword: "watch",
+		Summary: "`watch` was removed from the language. Race signals, updates, and timers in an `await one` block instead.",
+		Example: `await one:
+    signal Cancel:
+        close fail("cancelled")
+    timer(1h):`,
+		Removed: true,
+	},
+}
+
+// Lookup returns the entry explaining query, such as "await one" or
+// "elif". A query naming no construct, such as "await signal", falls back
+// to its longest leading words that do.
+func Lookup(query string) (Entry, bool) {
+	words := strings.Fields(strings.ToLower(query))
+	for n := len(words); n > 0; n-- {
+		if e, ok := find(strings.Join(words[:n], " ")); ok {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// word matches a keyword or name in a line of source.
+var word = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*`)
+
+// At returns the entry for the keyword at byte offset col of line, read
+// with its neighbors: the cursor on either word of await one explains
+// await one. Names and other text explain nothing.
+func At(line string, col int) (Entry, bool) {
+	spans := word.FindAllStringIndex(line, -1)
+	i := slices.IndexFunc(spans, func(s []int) bool { return s[0] <= col && col <= s[1] })
+	if i < 0 {
+		return Entry{}, false
+	}
+	at := func(j int) string {
+		if j < 0 || j >= len(spans) {
+			return ""
+		}
+		return line[spans[j][0]:spans[j][1]]
+	}
+	for _, phrase := range []string{at(i) + " " + at(i+1), at(i-1) + " " + at(i), at(i)} {
+		if e, ok := find(strings.TrimSpace(phrase)); ok {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// find returns the entry with phrase as its keyword or one of its others.
+func find(phrase string) (Entry, bool) {
+	for _, e := range Entries {
+		if e.Keyword == phrase || slices.Contains(e.also, phrase) {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Keywords returns the keyword of each entry, in order.
+func Keywords() []string {
+	out := make([]string, len(Entries))
+	for i, e := range Entries {
+		out[i] = e.Keyword
+	}
+	return out
+}
@@ -0,0 +1,67 @@
+package explain
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/parser"
+)
+
+func TestExamplesParse(t *testing.T) {
+	for _, e := range Entries {
+		if e.Removed {
+			continue
+		}
+		// Definitions parse as a file; statements and handlers in a workflow.
+		if _, errs := parser.ParseFileAll(e.Example + "\n"); len(errs) == 0 {
+			continue
+		}
+		body := "    " + strings.ReplaceAll(e.Example, "\n", "\n    ")
+		if _, errs := parser.ParseFileAll("workflow Example():\n" + body + "\n"); len(errs) != 0 {
+			t.Errorf("example of %s does not parse: %v", e.Keyword, errs[0])
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	for query, want := range map[string]string{
+		"await one":             "await one",
+		"  Await   ALL ":        "await all",
+		"await signal Approved": "await",
+		"elif":                  "if",
+		"unset":                 "condition",
+		"close fail":            "close",
+		"close continue_as_new": "continue_as_new",
+		"nexus service":         "nexus",
+		"watch":                 "watch",
+	} {
+		if e, ok := Lookup(query); !ok || e.Keyword != want {
+			t.Errorf("Lookup(%q) = %q, %t; want %q", query, e.Keyword, ok, want)
+		}
+	}
+	if e, ok := Lookup("goto"); ok {
+		t.Errorf("Lookup(goto) = %q, want none", e.Keyword)
+	}
+}
+
+func TestAt(t *testing.T) {
+	for _, tc := range []struct {
+		line string
+		col  int
+		want string
+	}{
+		{"    await one:", 5, "await one"},
+		{"    await one:", 12, "await one"},
+		{"    await signal Approved", 6, "await"},
+		{"    await signal Approved", 20, ""},
+		{"    close fail(\"late\")", 11, "close"},
+		{"    close continue_as_new(cursor)", 4, "continue_as_new"},
+		{"    elif (x):", 4, "if"},
+		{"    activity Charge(order)", 0, ""},
+	} {
+		e, ok := At(tc.line, tc.col)
+		if e.Keyword != tc.want || ok != (tc.want != "") {
+			t.Errorf("At(%q, %d) = %q, %t; want %q", tc.line, tc.col, e.Keyword, ok, tc.want)
+		}
+	}
+}
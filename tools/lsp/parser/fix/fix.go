@@ -0,0 +1,418 @@
+// Package fix rewrites TWF sources, replacing deprecated constructs with
+// their current forms.
+//
+// Each fix computes edits from a parsed file, so the language server can
+// offer them one statement at a time and twf fix can apply them to a tree.
+package fix
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/eval"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/lexer"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/validator"
+)
+
+// Edit replaces the text between two columns of one line, or from Column
+// of Line to EndColumn of EndLine. Columns are 1-based byte offsets, and
+// EndColumn is exclusive.
+type Edit struct {
+	Line      int
+	Column    int
+	EndLine   int // 0 for Line
+	EndColumn int
+	NewText   string
+}
+
+// End returns the line EndColumn is on.
+func (e Edit) End() int {
+	if e.EndLine == 0 {
+		return e.Line
+	}
+	return e.EndLine
+}
+
+// Fix computes the edits rewriting one construct in file, parsed from src.
+// Fixes that follow references, such as MissingTimeouts, need file resolved
+// and skip references that are not.
+type Fix func(file *ast.File, src string) []Edit
+
+// Fixes are the fixes twf fix applies, by name.
+var Fixes = map[string]Fix{
+	"hint-to-await":    HintToAwait,
+	"keyword-case":     KeywordCase,
+	"missing-timeouts": MissingTimeouts,
+	"return-to-close":  ReturnToClose,
+}
+
+// Names returns the names of Fixes, sorted.
+func Names() []string {
+	names := make([]string, 0, len(Fixes))
+	for name := range Fixes {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// KeywordCase lowercases each keyword of src written in another case, as
+// Workflow or IF. Such keywords parse only with case-insensitive keywords
+// on, so apply it only to src parsed that way; otherwise the words are
+// names, which the fix would rename.
+func KeywordCase(file *ast.File, src string) []Edit {
+	var edits []Edit
+	for _, tok := range lexer.MiscasedKeywords(src) {
+		edits = append(edits, Edit{
+			Line:      tok.Line,
+			Column:    tok.Column,
+			EndColumn: tok.Column + len(tok.Literal),
+			NewText:   strings.ToLower(tok.Literal),
+		})
+	}
+	return edits
+}
+
+// HintToAwait replaces each statement of the removed hint construct in a
+// workflow with the await one block it stood for, with an empty case for
+// each name it lists: hints signal Approved, Override becomes
+//
+//	await one:
+//	    signal Approved:
+//	    signal Override:
+func HintToAwait(file *ast.File, src string) []Edit {
+	lines := strings.Split(src, "\n")
+	var edits []Edit
+	for _, def := range file.Definitions {
+		wf, ok := def.(*ast.WorkflowDef)
+		if !ok {
+			continue
+		}
+		visit := func(s ast.Statement) bool {
+			if raw, ok := s.(*ast.RawStmt); ok {
+				if e, ok := hintToAwait(lines, raw); ok {
+					edits = append(edits, e)
+				}
+			}
+			return true
+		}
+		ast.WalkStatements(wf.Body, visit)
+		for _, s := range wf.Signals {
+			ast.WalkStatements(s.Body, visit)
+		}
+		for _, u := range wf.Updates {
+			ast.WalkStatements(u.Body, visit)
+		}
+	}
+	return edits
+}
+
+// hintToAwait returns the edit replacing raw when it is a hint, or false.
+func hintToAwait(lines []string, raw *ast.RawStmt) (Edit, bool) {
+	h, ok := validator.ParseHint(raw)
+	if !ok || raw.Line < 1 || raw.Line > len(lines) {
+		return Edit{}, false
+	}
+	line := lines[raw.Line-1]
+	start := raw.Column - 1
+	if start < 0 || !strings.HasPrefix(line[min(start, len(line)):], raw.Text) {
+		return Edit{}, false
+	}
+	indent := line[:start] + "    "
+	var b strings.Builder
+	b.WriteString("await one:")
+	for _, n := range h.Names {
+		b.WriteString("\n" + indent + h.Kind + " " + n.Name + ":")
+	}
+	return Edit{Line: raw.Line, Column: raw.Column, EndColumn: raw.Column + len(raw.Text), NewText: b.String()}, true
+}
+
+// ReturnToClose converts each return statement in a workflow body into
+// close complete, passing on the returned value: return becomes close
+// complete, and return result becomes close complete(result). Returns in
+// signal, query, and update handlers and in activities are left alone,
+// since they do not end the workflow.
+func ReturnToClose(file *ast.File, src string) []Edit {
+	lines := strings.Split(src, "\n")
+	var edits []Edit
+	for _, def := range file.Definitions {
+		wf, ok := def.(*ast.WorkflowDef)
+		if !ok {
+			continue
+		}
+		ast.WalkStatements(wf.Body, func(s ast.Statement) bool {
+			if ret, ok := s.(*ast.ReturnStmt); ok {
+				if e, ok := returnToClose(lines, ret); ok {
+					edits = append(edits, e)
+				}
+			}
+			return true
+		})
+	}
+	return edits
+}
+
+// returnToClose returns the edit converting ret, or false when its source
+// is not where the statement says, as when the value spans lines.
+func returnToClose(lines []string, ret *ast.ReturnStmt) (Edit, bool) {
+	if ret.Line < 1 || ret.Line > len(lines) || strings.Contains(ret.Value, "\n") {
+		return Edit{}, false
+	}
+	line := lines[ret.Line-1]
+	start := ret.Column - 1
+	if start < 0 || !strings.HasPrefix(line[min(start, len(line)):], "return") {
+		return Edit{}, false
+	}
+	end := start + len("return")
+	if ret.Value == "" {
+		return Edit{Line: ret.Line, Column: ret.Column, EndColumn: end + 1, NewText: "close complete"}, true
+	}
+	i := strings.Index(line[end:], ret.Value)
+	if i < 0 {
+		return Edit{}, false
+	}
+	end += i + len(ret.Value)
+	value := ret.Value
+	if !parenthesized(value) {
+		value = "(" + value + ")"
+	}
+	return Edit{Line: ret.Line, Column: ret.Column, EndColumn: end + 1, NewText: "close complete" + value}, true
+}
+
+// MissingTimeouts bounds each workflow call starting a workflow with an
+// @sla annotation by that SLA, adding workflow_execution_timeout to the
+// call's options when neither it nor workflow_run_timeout is set. Calls
+// with no options gain an options block. An @sla that is not a duration,
+// such as the @sla(TODO) stub, is left for the author.
+func MissingTimeouts(file *ast.File, src string) []Edit {
+	var edits []Edit
+	visit := func(s ast.Statement) bool {
+		if call, ok := s.(*ast.WorkflowCall); ok {
+			if e, ok := MissingTimeout(src, call); ok {
+				edits = append(edits, e)
+			}
+		}
+		return true
+	}
+	for _, def := range file.Definitions {
+		switch def := def.(type) {
+		case *ast.WorkflowDef:
+			ast.WalkStatements(def.Body, visit)
+			for _, s := range def.Signals {
+				ast.WalkStatements(s.Body, visit)
+			}
+			for _, u := range def.Updates {
+				ast.WalkStatements(u.Body, visit)
+			}
+		case *ast.NexusServiceDef:
+			for _, op := range def.Operations {
+				ast.WalkStatements(op.Body, visit)
+			}
+		}
+	}
+	return edits
+}
+
+// MissingTimeout returns the edit MissingTimeouts makes to one call, or
+// false when the call needs none or its target is unresolved.
+func MissingTimeout(src string, call *ast.WorkflowCall) (Edit, bool) {
+	lines := strings.Split(src, "\n")
+	target := call.Workflow.Resolved
+	if target == nil || call.Line < 1 || call.Line > len(lines) {
+		return Edit{}, false
+	}
+	var sla string
+	for _, a := range target.Annotations {
+		if a.Name == "sla" {
+			sla = a.Value()
+			break
+		}
+	}
+	if _, ok := eval.ParseDuration(sla); !ok {
+		return Edit{}, false
+	}
+	if hasOption(call.Options, "workflow_execution_timeout", "workflow_run_timeout") {
+		return Edit{}, false
+	}
+	return callOption(lines, call.Line, call.Options, "workflow_execution_timeout: "+sla)
+}
+
+// ActivityTimeout is the start_to_close_timeout the activity timeout fixes
+// set, for the author to adjust.
+const ActivityTimeout = "1m"
+
+// activityTimeoutEntry is the option the activity timeout fixes add.
+const activityTimeoutEntry = "start_to_close_timeout: " + ActivityTimeout
+
+// ActivityTimeoutAtCall returns the edit setting start_to_close_timeout to
+// ActivityTimeout in the options of call, or false when they already set it
+// or schedule_to_close_timeout.
+func ActivityTimeoutAtCall(src string, call *ast.ActivityCall) (Edit, bool) {
+	lines := strings.Split(src, "\n")
+	if call.Line < 1 || call.Line > len(lines) || hasOption(call.Options, "start_to_close_timeout", "schedule_to_close_timeout") {
+		return Edit{}, false
+	}
+	return callOption(lines, call.Line, call.Options, activityTimeoutEntry)
+}
+
+// ActivityTimeoutAtDefinition is ActivityTimeoutAtCall for the options
+// block opening def, parsed from src, which sets the timeout for every call.
+// A definition with no block gains one above its first statement.
+func ActivityTimeoutAtDefinition(src string, def *ast.ActivityDef) (Edit, bool) {
+	lines := strings.Split(src, "\n")
+	if hasOption(def.Options, "start_to_close_timeout", "schedule_to_close_timeout") {
+		return Edit{}, false
+	}
+	if def.Options != nil {
+		return firstOption(lines, def.Options, activityTimeoutEntry)
+	}
+	if len(def.Body) == 0 {
+		return Edit{}, false
+	}
+	first := def.Body[0]
+	line := first.NodeLine()
+	if line < 1 || line > len(lines) || first.NodeColumn()-1 > len(lines[line-1]) {
+		return Edit{}, false
+	}
+	indent := lines[line-1][:first.NodeColumn()-1]
+	return Edit{Line: line, Column: 1, EndColumn: 1, NewText: indent + "options:\n" + indent + "    " + activityTimeoutEntry + "\n"}, true
+}
+
+// hasOption reports whether ob sets any of keys at its top level. A block
+// that does not parse might, so it counts as setting them and is left to
+// its author.
+func hasOption(ob *ast.OptionsBlock, keys ...string) bool {
+	if ob == nil {
+		return false
+	}
+	if ob.Unparsed() {
+		return true
+	}
+	return slices.ContainsFunc(ob.Entries, func(e *ast.OptionEntry) bool { return slices.Contains(keys, e.Key) })
+}
+
+// callOption returns the edit adding entry to ob, the options of the call
+// on line: a new block under the call, one level in, when it has none.
+func callOption(lines []string, line int, ob *ast.OptionsBlock, entry string) (Edit, bool) {
+	if ob == nil {
+		text := lines[line-1]
+		indent := text[:len(text)-len(strings.TrimLeft(text, " \t"))] + "    "
+		end := len(text) + 1
+		return Edit{Line: line, Column: end, EndColumn: end, NewText: "\n" + indent + "options:\n" + indent + "    " + entry}, true
+	}
+	return firstOption(lines, ob, entry)
+}
+
+// firstOption returns the edit adding entry to ob as its first entry, at
+// the indentation of the others.
+func firstOption(lines []string, ob *ast.OptionsBlock, entry string) (Edit, bool) {
+	if len(ob.Entries) == 0 {
+		return Edit{}, false
+	}
+	first := ob.Entries[0]
+	if first.Line > len(lines) || first.Column-1 > len(lines[first.Line-1]) {
+		return Edit{}, false
+	}
+	indent := lines[first.Line-1][:first.Column-1]
+	return Edit{Line: first.Line, Column: 1, EndColumn: 1, NewText: indent + entry + "\n"}, true
+}
+
+// AwaitOneOrder returns the edit moving the cases of block, parsed from
+// src, into the order validator.AwaitOneRank gives them under mode, or
+// false when they are in it. A case moves with its body, and the cases
+// are separated by as many blank lines as the first two were.
+func AwaitOneOrder(src string, block *ast.AwaitOneBlock, mode string) (Edit, bool) {
+	lines := strings.Split(src, "\n")
+	order := slices.Clone(block.Cases)
+	slices.SortStableFunc(order, func(a, b *ast.AwaitOneCase) int {
+		return validator.AwaitOneRank(mode, a) - validator.AwaitOneRank(mode, b)
+	})
+	if slices.Equal(order, block.Cases) {
+		return Edit{}, false
+	}
+	indent := block.Cases[0].Column - 1
+	indentOf := func(line string) int { return len(line) - len(strings.TrimLeft(line, " \t")) }
+	blank := func(line string) bool { return strings.TrimSpace(line) == "" }
+
+	// starts[i] is the 0-based line of case i, and starts[n] the line after
+	// the last case's last non-blank line.
+	n := len(block.Cases)
+	starts := make([]int, n+1)
+	for i, c := range block.Cases {
+		start := c.Line - 1
+		if start < 0 || start >= len(lines) || (i > 0 && start <= starts[i-1]) || indentOf(lines[start]) != indent {
+			return Edit{}, false
+		}
+		starts[i] = start
+	}
+	end := starts[n-1] + 1
+	for i := end; i < len(lines) && (blank(lines[i]) || indentOf(lines[i]) > indent); i++ {
+		if !blank(lines[i]) {
+			end = i + 1
+		}
+	}
+	starts[n] = end
+
+	texts := make(map[*ast.AwaitOneCase]string, n)
+	var sep string
+	for i, c := range block.Cases {
+		span := lines[starts[i]:starts[i+1]]
+		trimmed := len(span)
+		for trimmed > 1 && blank(span[trimmed-1]) {
+			trimmed--
+		}
+		if i == 0 {
+			sep = strings.Repeat("\n", len(span)-trimmed)
+		}
+		texts[c] = strings.Join(span[:trimmed], "\n")
+	}
+	parts := make([]string, n)
+	for i, c := range order {
+		parts[i] = texts[c]
+	}
+	return Edit{
+		Line:      starts[0] + 1,
+		Column:    1,
+		EndLine:   end,
+		EndColumn: len(lines[end-1]) + 1,
+		NewText:   strings.Join(parts, "\n"+sep),
+	}, true
+}
+
+// parenthesized reports whether s is wrapped in one pair of parentheses,
+// as in (a, b) but not (a) + (b).
+func parenthesized(s string) bool {
+	if !strings.HasPrefix(s, "(") {
+		return false
+	}
+	depth := 0
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i == len(s)-1
+			}
+		}
+	}
+	return false
+}
+
+// Apply returns src with edits applied. Edits must not overlap.
+func Apply(src string, edits []Edit) string {
+	// Later edits first, so earlier columns stay put.
+	edits = slices.Clone(edits)
+	slices.SortFunc(edits, func(a, b Edit) int {
+		return cmp.Or(b.Line-a.Line, b.Column-a.Column)
+	})
+	lines := strings.Split(src, "\n")
+	for _, e := range edits {
+		text := lines[e.Line-1][:e.Column-1] + e.NewText + lines[e.End()-1][e.EndColumn-1:]
+		lines = slices.Replace(lines, e.Line-1, e.End(), text)
+	}
+	return strings.Join(lines, "\n")
+}
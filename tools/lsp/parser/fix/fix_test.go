@@ -0,0 +1,289 @@
+package fix
+
+import (
+	"testing"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/lexer"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/parser"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/resolver"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/validator"
+)
+
+func TestReturnToClose(t *testing.T) {
+	src := `workflow Order(order: Order) -> (Result):
+    update Rename(name: string) -> (string):
+        return name
+    if (order.cancelled):
+        return  # nothing to do
+    await one:
+        timer(1h):
+            return Result{status: "late"}
+    return (result)
+
+activity Charge(order: Order) -> (Receipt):
+    return receipt
+`
+	want := `workflow Order(order: Order) -> (Result):
+    update Rename(name: string) -> (string):
+        return name
+    if (order.cancelled):
+        close complete  # nothing to do
+    await one:
+        timer(1h):
+            close complete(Result{status: "late"})
+    close complete(result)
+
+activity Charge(order: Order) -> (Receipt):
+    return receipt
+`
+	file, err := parser.ParseFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := Apply(src, ReturnToClose(file, src)); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	fixed, err := parser.ParseFile(want)
+	if err != nil {
+		t.Fatalf("fixed source does not parse: %v", err)
+	}
+	if edits := ReturnToClose(fixed, want); len(edits) != 0 {
+		t.Errorf("expected fixed source to need no edits, got %+v", edits)
+	}
+}
+
+func TestParenthesized(t *testing.T) {
+	for s, want := range map[string]bool{
+		"(a)":         true,
+		"(a, (b, c))": true,
+		"(a) + (b)":   false,
+		"a":           false,
+		"(a":          false,
+	} {
+		if got := parenthesized(s); got != want {
+			t.Errorf("parenthesized(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestMissingTimeouts(t *testing.T) {
+	src := `@sla(24h)
+workflow Fulfill(order: Order):
+    close complete
+
+@sla(TODO)
+workflow Audit(order: Order):
+    close complete
+
+workflow Order(order: Order):
+    workflow Fulfill(order)
+    workflow Fulfill(order)
+        options:
+            task_queue: "fulfillment"
+    workflow Fulfill(order)
+        options:
+            workflow_run_timeout: 1h
+    workflow Audit(order)
+    close complete
+`
+	want := `@sla(24h)
+workflow Fulfill(order: Order):
+    close complete
+
+@sla(TODO)
+workflow Audit(order: Order):
+    close complete
+
+workflow Order(order: Order):
+    workflow Fulfill(order)
+        options:
+            workflow_execution_timeout: 24h
+    workflow Fulfill(order)
+        options:
+            workflow_execution_timeout: 24h
+            task_queue: "fulfillment"
+    workflow Fulfill(order)
+        options:
+            workflow_run_timeout: 1h
+    workflow Audit(order)
+    close complete
+`
+	resolve := func(src string) *ast.File {
+		t.Helper()
+		file, err := parser.ParseFile(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resolver.Resolve(file)
+		return file
+	}
+	if got := Apply(src, MissingTimeouts(resolve(src), src)); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if edits := MissingTimeouts(resolve(want), want); len(edits) != 0 {
+		t.Errorf("expected fixed source to need no edits, got %+v", edits)
+	}
+
+	// Unresolved calls are skipped.
+	file, err := parser.ParseFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edits := MissingTimeouts(file, src); len(edits) != 0 {
+		t.Errorf("expected no edits before resolving, got %+v", edits)
+	}
+}
+
+func TestActivityTimeouts(t *testing.T) {
+	src := `activity Charge(order: Order):
+    return
+
+activity Refund(order: Order):
+  options:
+    heartbeat_timeout: 10s
+  return
+
+workflow Order(order: Order):
+    activity Charge(order)
+    activity Charge(order)
+        options:
+            task_queue: "payments"
+    activity Refund(order)
+        options:
+            schedule_to_close_timeout: 5m
+    close complete
+`
+	file, err := parser.ParseFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	charge := file.Definitions[0].(*ast.ActivityDef)
+	refund := file.Definitions[1].(*ast.ActivityDef)
+	body := file.Definitions[2].(*ast.WorkflowDef).Body
+
+	var edits []Edit
+	for _, s := range body[:3] {
+		if e, ok := ActivityTimeoutAtCall(src, s.(*ast.ActivityCall)); ok {
+			edits = append(edits, e)
+		}
+	}
+	for _, def := range []*ast.ActivityDef{charge, refund} {
+		if e, ok := ActivityTimeoutAtDefinition(src, def); ok {
+			edits = append(edits, e)
+		}
+	}
+	want := `activity Charge(order: Order):
+    options:
+        start_to_close_timeout: 1m
+    return
+
+activity Refund(order: Order):
+  options:
+    start_to_close_timeout: 1m
+    heartbeat_timeout: 10s
+  return
+
+workflow Order(order: Order):
+    activity Charge(order)
+        options:
+            start_to_close_timeout: 1m
+    activity Charge(order)
+        options:
+            start_to_close_timeout: 1m
+            task_queue: "payments"
+    activity Refund(order)
+        options:
+            schedule_to_close_timeout: 5m
+    close complete
+`
+	got := Apply(src, edits)
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if _, err := parser.ParseFile(got); err != nil {
+		t.Errorf("fixed source does not parse: %v", err)
+	}
+}
+
+func TestHintToAwait(t *testing.T) {
+	src := "workflow Order():\n    signal Approve():\n        ok = true\n    hints signal Approve, Override\n    if (urgent):\n        hint signal Approve\n    close complete\n"
+	file, err := parser.ParseFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "workflow Order():\n    signal Approve():\n        ok = true\n    await one:\n        signal Approve:\n        signal Override:\n    if (urgent):\n        await one:\n            signal Approve:\n    close complete\n"
+	if got := Apply(src, HintToAwait(file, src)); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestAwaitOneOrder(t *testing.T) {
+	src := `workflow Order(order: Order):
+    await one:
+        timer(1h):
+            # the order times out
+            close fail("late")
+
+        activity Charge(order) -> receipt:
+            close complete
+
+        signal Cancel:
+            cancelled = true
+
+            close complete
+    close complete
+`
+	want := `workflow Order(order: Order):
+    await one:
+        signal Cancel:
+            cancelled = true
+
+            close complete
+
+        activity Charge(order) -> receipt:
+            close complete
+
+        timer(1h):
+            # the order times out
+            close fail("late")
+    close complete
+`
+	file, err := parser.ParseFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := file.Definitions[0].(*ast.WorkflowDef).Body[0].(*ast.AwaitOneBlock)
+	if _, ok := AwaitOneOrder(src, block, validator.AwaitOneOrderTimerLast); !ok {
+		t.Fatal("timer-last: expected an edit")
+	}
+	e, ok := AwaitOneOrder(src, block, validator.AwaitOneOrderWatchFirst)
+	if !ok {
+		t.Fatal("watch-first: expected an edit")
+	}
+	got := Apply(src, []Edit{e})
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	fixed, err := parser.ParseFile(got)
+	if err != nil {
+		t.Fatalf("fixed source does not parse: %v", err)
+	}
+	block = fixed.Definitions[0].(*ast.WorkflowDef).Body[0].(*ast.AwaitOneBlock)
+	if e, ok := AwaitOneOrder(got, block, validator.AwaitOneOrderWatchFirst); ok {
+		t.Errorf("expected fixed source to need no edit, got %+v", e)
+	}
+}
+
+func TestKeywordCase(t *testing.T) {
+	src := "Workflow Order(state: State):\n    IF (state.ready):\n        Activity Ship()\n    close complete\n"
+	file, err := parser.WithLexer(lexer.Options{CaseInsensitiveKeywords: true}).ParseFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "workflow Order(state: State):\n    if (state.ready):\n        activity Ship()\n    close complete\n"
+	if got := Apply(src, KeywordCase(file, src)); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
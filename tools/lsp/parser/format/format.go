@@ -0,0 +1,726 @@
+// Package format prints TWF sources in one canonical layout: four spaces
+// of indentation per block, one blank line between definitions, at most one
+// blank line where the source had any, and single spaces in headers,
+// argument lists, and option entries.
+//
+// The layout is printed from the parsed AST. Comments, which the AST keeps
+// only in statement bodies, are read from the token stream instead and put
+// back before the line that followed them, at the depth they were written
+// at, or at the end of their line. Source reparses what it prints and
+// refuses the result unless it has the same AST and comments as the input,
+// so formatting never changes what a design means.
+package format
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/lexer"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/parser"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/token"
+)
+
+// indent is one level of indentation.
+const indent = "    "
+
+// Source returns src in the canonical layout. A source that does not parse
+// is returned with its parse error, and one the layout would change the
+// meaning of with an error naming where.
+func Source(src string) (string, error) {
+	return SourceWith(src, lexer.Options{})
+}
+
+// SourceWith is Source for src lexed with lex, such as under keyword
+// aliases.
+func SourceWith(src string, lex lexer.Options) (string, error) {
+	file, err := parser.WithLexer(lex).ParseFile(src)
+	if err != nil {
+		return "", err
+	}
+	p := newPrinter(src, lex)
+	for i, def := range file.Definitions {
+		p.sep = i > 0
+		p.definition(def)
+	}
+	p.flush(len(p.lines) + 1)
+	out := p.b.String()
+	if err := verify(src, file, out, lex); err != nil {
+		return "", err
+	}
+	return out, nil
+}
+
+// comment is a comment from the token stream, waiting to be printed.
+type comment struct {
+	line     int
+	depth    int // blocks open where it was written
+	text     string
+	trailing bool // written after code on its line
+}
+
+// printer writes the canonical layout of one file.
+type printer struct {
+	b        strings.Builder
+	lines    []string      // the source, for the blank lines between nodes
+	tokens   []token.Token // the source, for keywords the AST keeps no position of
+	comments []comment     // not yet printed, in source order
+	depth    int           // blocks open at the next line
+	last     int           // source line of the last line printed; 0 before the first
+	open     bool          // the last line printed opens a block
+	sep      bool          // the next top-level line starts a definition after another
+}
+
+func newPrinter(src string, lex lexer.Options) *printer {
+	p := &printer{lines: strings.Split(src, "\n"), tokens: lex.New(src).AllTokens()}
+	depth := 0
+	var prev token.Token
+	for _, tok := range p.tokens {
+		switch tok.Type {
+		case token.INDENT:
+			depth++
+			continue
+		case token.DEDENT:
+			depth--
+			continue
+		case token.COMMENT:
+			p.comments = append(p.comments, comment{
+				line:     tok.Line,
+				depth:    depth,
+				text:     strings.TrimRight(tok.Literal, " \t\r"),
+				trailing: prev.Type != token.NEWLINE && prev.Line == tok.Line,
+			})
+		}
+		prev = tok
+	}
+	return p
+}
+
+// line prints text for the node on source line src, after the comments
+// written before it. A comment after the node on its line stays there.
+func (p *printer) line(src int, text string) {
+	p.flush(src)
+	p.write(src, p.depth, text)
+	if len(p.comments) > 0 && p.comments[0].trailing && p.comments[0].line == src {
+		p.b.WriteString("  #" + p.comments[0].text)
+		p.comments = p.comments[1:]
+	}
+	p.b.WriteByte('\n')
+	p.open = false
+}
+
+// header prints text like line, as the header of a block.
+func (p *printer) header(src int, text string) {
+	p.line(src, text)
+	p.open = true
+}
+
+// flush prints the comments written before source line src.
+func (p *printer) flush(src int) {
+	for len(p.comments) > 0 && p.comments[0].line < src {
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+		p.write(c.line, c.depth, "#"+c.text)
+		p.b.WriteByte('\n')
+		p.open = false
+	}
+}
+
+// write indents text to depth, after a blank line when it starts a
+// definition or the source had one before it.
+func (p *printer) write(src, depth int, text string) {
+	switch {
+	case p.sep && depth == 0:
+		p.b.WriteByte('\n')
+		p.sep = false
+	case p.last > 0 && !p.open && src-1 > p.last && strings.TrimSpace(p.lines[src-2]) == "":
+		p.b.WriteByte('\n')
+	}
+	p.b.WriteString(strings.Repeat(indent, depth))
+	p.b.WriteString(text)
+	p.last = max(p.last, src)
+}
+
+// keyword returns the line of the first token after the last line printed
+// that match reports, for parts of the source the AST has no position for,
+// such as else:.
+func (p *printer) keyword(match func(token.Token) bool) token.Token {
+	for _, tok := range p.tokens {
+		if tok.Line > p.last && match(tok) {
+			return tok
+		}
+	}
+	return token.Token{Line: p.last + 1}
+}
+
+func (p *printer) elseLine() int {
+	return p.keyword(func(tok token.Token) bool { return tok.Type == token.ELSE }).Line
+}
+
+func (p *printer) definition(def ast.Definition) {
+	switch d := def.(type) {
+	case *ast.WorkflowDef:
+		p.workflow(d)
+	case *ast.ActivityDef:
+		p.annotations(d.Annotations)
+		p.header(d.Line, "activity "+d.Name+signature(d.Params, d.ReturnType)+":")
+		p.depth++
+		p.options(d.Options)
+		p.statements(d.Body)
+		p.depth--
+	case *ast.WorkerDef:
+		p.worker(d)
+	case *ast.NamespaceDef:
+		p.namespace(d)
+	case *ast.NexusServiceDef:
+		p.nexusService(d)
+	case *ast.ConstDef:
+		p.line(d.Line, "const "+d.Name+" = "+ast.ExprString(d.ValueExpr))
+	case *ast.EnumDef:
+		names := make([]string, len(d.Values))
+		for i, v := range d.Values {
+			names[i] = v.Name
+		}
+		p.line(d.Line, "enum "+d.Name+": "+strings.Join(names, ", "))
+	}
+}
+
+func (p *printer) annotations(anns []*ast.Annotation) {
+	for _, a := range anns {
+		text := "@" + a.Name
+		if args := strings.TrimSpace(a.Args); args != "" {
+			text += "(" + args + ")"
+		}
+		p.line(a.Line, text)
+	}
+}
+
+// sourced is a line of a block whose parts the AST keeps in separate
+// lists, such as a worker's workflows and activities, to be printed in
+// source order.
+type sourced struct {
+	line  int
+	print func()
+}
+
+func (p *printer) inOrder(parts []sourced) {
+	slices.SortStableFunc(parts, func(a, b sourced) int { return cmp.Compare(a.line, b.line) })
+	for _, part := range parts {
+		part.print()
+	}
+}
+
+func (p *printer) workflow(w *ast.WorkflowDef) {
+	p.annotations(w.Annotations)
+	p.header(w.Line, "workflow "+w.Name+signature(w.Params, w.ReturnType)+":")
+	p.depth++
+	if w.Description != "" {
+		p.description(w.Description)
+	}
+	p.options(w.Options)
+	if w.State != nil {
+		p.state(w.State)
+	}
+	var handlers []sourced
+	for _, s := range w.Signals {
+		handlers = append(handlers, sourced{s.Line, func() { p.handler(s.Line, "signal "+s.Name+"("+paramList(s.Params)+")", s.Body) }})
+	}
+	for _, q := range w.Queries {
+		handlers = append(handlers, sourced{q.Line, func() { p.handler(q.Line, "query "+q.Name+signature(q.Params, q.ReturnType), q.Body) }})
+	}
+	for _, u := range w.Updates {
+		handlers = append(handlers, sourced{u.Line, func() { p.handler(u.Line, "update "+u.Name+signature(u.Params, u.ReturnType), u.Body) }})
+	}
+	p.inOrder(handlers)
+	p.statements(w.Body)
+	p.depth--
+}
+
+func (p *printer) handler(line int, head string, body []ast.Statement) {
+	p.header(line, head+":")
+	p.block(body)
+}
+
+// description prints a description: block, whose text the lexer keeps
+// verbatim, so it is indented line by line with no comments inside.
+func (p *printer) description(text string) {
+	head := p.keyword(func(tok token.Token) bool { return tok.Type == token.IDENT && tok.Literal == "description" })
+	p.header(head.Line, "description:")
+	for _, l := range strings.Split(text, "\n") {
+		if l != "" {
+			p.b.WriteString(strings.Repeat(indent, p.depth+1) + l)
+		}
+		p.b.WriteByte('\n')
+	}
+	body := p.keyword(func(tok token.Token) bool { return tok.Type == token.TEXT })
+	p.last = body.Line + strings.Count(body.Literal, "\n")
+	p.open = false
+}
+
+func (p *printer) state(s *ast.StateBlock) {
+	p.header(s.Line, "state:")
+	p.depth++
+	var parts []sourced
+	for _, c := range s.Conditions {
+		parts = append(parts, sourced{c.Line, func() { p.line(c.Line, "condition "+c.Name) }})
+	}
+	for _, r := range s.RawStmts {
+		parts = append(parts, sourced{r.Line, func() { p.line(r.Line, r.Text) }})
+	}
+	p.inOrder(parts)
+	p.depth--
+}
+
+// options prints an options: block at the current depth; nil prints nothing.
+func (p *printer) options(o *ast.OptionsBlock) {
+	if o == nil {
+		return
+	}
+	p.header(o.Line, "options:")
+	p.depth++
+	if o.Unparsed() {
+		for i, l := range strings.Split(o.RawText, "\n") {
+			p.line(o.Line+1+i, l)
+		}
+	}
+	p.entries(o.Entries)
+	p.depth--
+}
+
+func (p *printer) entries(entries []*ast.OptionEntry) {
+	for _, e := range entries {
+		if e.Nested != nil {
+			p.header(e.Line, e.Key+":")
+			p.depth++
+			p.entries(e.Nested)
+			p.depth--
+			continue
+		}
+		p.line(e.Line, e.Key+": "+optionValue(e))
+	}
+}
+
+// optionValue returns the value of a flat option entry as written: a
+// constant by name and a string quoted.
+func optionValue(e *ast.OptionEntry) string {
+	switch {
+	case e.Expr != nil:
+		return ast.ExprString(e.Expr)
+	case e.Const.Name != "":
+		return e.Const.Name
+	case e.ValueType == "string":
+		return quote(e.Value)
+	}
+	return e.Value
+}
+
+// call prints a call and the options: block below it.
+func (p *printer) call(line int, text string, options *ast.OptionsBlock) {
+	p.line(line, text)
+	p.depth++
+	p.options(options)
+	p.depth--
+}
+
+func (p *printer) worker(w *ast.WorkerDef) {
+	p.header(w.Line, "worker "+w.Name+":")
+	p.depth++
+	var parts []sourced
+	add := func(pos ast.Pos, text string) {
+		parts = append(parts, sourced{pos.Line, func() { p.line(pos.Line, text) }})
+	}
+	for _, r := range w.Workflows {
+		add(r.Pos, "workflow "+r.Name)
+	}
+	for _, r := range w.Activities {
+		add(r.Pos, "activity "+r.Name)
+	}
+	for _, r := range w.Services {
+		add(r.Pos, "nexus service "+r.Name)
+	}
+	p.inOrder(parts)
+	p.depth--
+}
+
+func (p *printer) namespace(n *ast.NamespaceDef) {
+	p.header(n.Line, "namespace "+n.Name+":")
+	p.depth++
+	var parts []sourced
+	for _, w := range n.Workers {
+		parts = append(parts, sourced{w.Line, func() { p.call(w.Line, "worker "+w.Worker.Name, w.Options) }})
+	}
+	for _, e := range n.Endpoints {
+		parts = append(parts, sourced{e.Line, func() { p.call(e.Line, "nexus endpoint "+e.EndpointName, e.Options) }})
+	}
+	p.inOrder(parts)
+	p.depth--
+}
+
+func (p *printer) nexusService(s *ast.NexusServiceDef) {
+	p.header(s.Line, "nexus service "+s.Name+":")
+	p.depth++
+	for _, op := range s.Operations {
+		if op.OpType == ast.NexusOpAsync {
+			p.line(op.Line, "async "+op.Name+" workflow "+op.Workflow.Name)
+			continue
+		}
+		p.handler(op.Line, "sync "+op.Name+"("+paramList(op.Params)+") -> ("+typeList(op.ReturnType)+")", op.Body)
+	}
+	p.depth--
+}
+
+// block prints a body one level deeper.
+func (p *printer) block(stmts []ast.Statement) {
+	p.depth++
+	p.statements(stmts)
+	p.depth--
+}
+
+func (p *printer) statements(stmts []ast.Statement) {
+	for _, s := range stmts {
+		p.statement(s)
+	}
+}
+
+func (p *printer) statement(stmt ast.Statement) {
+	switch s := stmt.(type) {
+	case *ast.Comment:
+		// Printed from the token stream with the comments outside bodies.
+	case *ast.ActivityCall:
+		p.call(s.Line, "activity "+s.Activity.Name+args(s.Args, s.ArgExprs)+arrow(s.Result), s.Options)
+	case *ast.WorkflowCall:
+		text := "workflow " + s.Workflow.Name + args(s.Args, s.ArgExprs)
+		if s.Mode == ast.CallDetach {
+			text = "detach " + text
+		}
+		if s.ID != "" {
+			text += " id " + quote(s.ID)
+		}
+		p.call(s.Line, text+arrow(s.Result), s.Options)
+	case *ast.NexusCall:
+		text := "nexus " + s.Endpoint.Name + " " + s.Service.Name + "." + s.Operation.Name + args(s.Args, s.ArgExprs)
+		if s.Detach {
+			text = "detach " + text
+		}
+		p.call(s.Line, text+arrow(s.Result), s.Options)
+	case *ast.AwaitStmt:
+		p.line(s.Line, "await "+target(s.Target))
+	case *ast.AwaitAllBlock:
+		p.awaitAll(s)
+	case *ast.AwaitOneBlock:
+		p.header(s.Line, "await one:")
+		p.depth++
+		for _, c := range s.Cases {
+			p.awaitOneCase(c)
+		}
+		p.depth--
+	case *ast.PromiseStmt:
+		p.line(s.Line, "promise "+s.Name+" <- "+target(s.Target))
+	case *ast.SetStmt:
+		p.line(s.Line, "set "+s.Condition.Name)
+	case *ast.UnsetStmt:
+		p.line(s.Line, "unset "+s.Condition.Name)
+	case *ast.SwitchBlock:
+		p.switchBlock(s)
+	case *ast.IfStmt:
+		p.ifStmt(s, "if")
+	case *ast.ForStmt:
+		p.forStmt(s)
+	case *ast.ReturnStmt:
+		p.line(s.Line, withValue("return", s.Value))
+	case *ast.CloseStmt:
+		text := "close " + closeReasons[s.Reason]
+		if strings.TrimSpace(s.Args) != "" {
+			text += args(s.Args, s.ArgExprs)
+		}
+		p.line(s.Line, text)
+	case *ast.BreakStmt:
+		p.line(s.Line, withValue("break", s.Label.Name))
+	case *ast.ContinueStmt:
+		p.line(s.Line, withValue("continue", s.Label.Name))
+	case *ast.RawStmt:
+		p.line(s.Line, s.Text)
+	}
+}
+
+var closeReasons = map[ast.CloseReason]string{
+	ast.CloseComplete:      "complete",
+	ast.CloseFailWorkflow:  "fail",
+	ast.CloseContinueAsNew: "continue_as_new",
+}
+
+func (p *printer) awaitAll(a *ast.AwaitAllBlock) {
+	head := "await all"
+	if a.Options != nil {
+		head += " " + a.Options.String()
+	}
+	p.handler(a.Line, head, a.Body)
+}
+
+func (p *printer) awaitOneCase(c *ast.AwaitOneCase) {
+	if c.AwaitAll != nil {
+		p.awaitAll(c.AwaitAll)
+		return
+	}
+	head := target(c.Target)
+	if c.Guard != "" {
+		head += " if (" + condition(c.Guard, c.GuardExpr) + ")"
+	}
+	if len(c.Body) == 0 {
+		p.line(c.Line, head+":")
+		return
+	}
+	p.handler(c.Line, head, c.Body)
+}
+
+func (p *printer) switchBlock(s *ast.SwitchBlock) {
+	p.header(s.Line, "switch ("+condition(s.Expr, s.SubjectExpr)+"):")
+	p.depth++
+	for _, c := range s.Cases {
+		value := strings.TrimSpace(c.Value)
+		if c.ValueExpr != nil {
+			value = ast.ExprString(c.ValueExpr)
+		}
+		p.handler(c.Line, "case "+value, c.Body)
+	}
+	if len(s.Default) > 0 {
+		p.handler(p.elseLine(), "else", s.Default)
+	}
+	p.depth--
+}
+
+// ifStmt prints an if statement, or the elif clause continuing a chain,
+// with the clauses after it.
+func (p *printer) ifStmt(s *ast.IfStmt, keyword string) {
+	p.handler(s.Line, keyword+" ("+condition(s.Condition, s.CondExpr)+")", s.Body)
+	switch {
+	case s.ElseIf:
+		p.ifStmt(s.ElseBody[0].(*ast.IfStmt), "elif")
+	case len(s.ElseBody) > 0:
+		p.handler(p.elseLine(), "else", s.ElseBody)
+	}
+}
+
+func (p *printer) forStmt(s *ast.ForStmt) {
+	head := "for"
+	switch s.Variant {
+	case ast.ForConditional:
+		head += " (" + condition(s.Condition, s.CondExpr) + ")"
+	case ast.ForIteration:
+		head += " (" + s.Variable + " in " + s.Iterable + ")"
+	case ast.ForParallel:
+		head += " each (" + s.Variable + " in " + s.Iterable + ") parallel(max: " + s.Concurrency + ")"
+	}
+	if s.Label != nil {
+		head = s.Label.Name + ": " + head
+	}
+	p.handler(s.Line, head, s.Body)
+}
+
+// target returns an async target as written after await or in an await
+// one case.
+func target(t ast.AsyncTarget) string {
+	switch t := t.(type) {
+	case *ast.TimerTarget:
+		return "timer(" + strings.TrimSpace(t.Duration) + ")" + arrow(t.Result)
+	case *ast.SignalTarget:
+		return "signal " + t.Signal.Name + arrow(t.Params)
+	case *ast.UpdateTarget:
+		return "update " + t.Update.Name + arrow(t.Params)
+	case *ast.ActivityTarget:
+		return "activity " + t.Activity.Name + args(t.Args, t.ArgExprs) + arrow(t.Result)
+	case *ast.WorkflowTarget:
+		text := "workflow " + t.Workflow.Name + args(t.Args, t.ArgExprs) + arrow(t.Result)
+		if t.Mode == ast.CallDetach {
+			text = "detach " + text
+		}
+		return text
+	case *ast.NexusTarget:
+		text := "nexus " + t.Endpoint.Name + " " + t.Service.Name + "." + t.Operation.Name + args(t.Args, t.ArgExprs) + arrow(t.Result)
+		if t.Detach {
+			text = "detach " + text
+		}
+		return text
+	case *ast.IdentTarget:
+		return t.Name + arrow(t.Result)
+	}
+	return ""
+}
+
+// args returns a call's arguments in parens: rendered from their
+// expressions when they parsed as a list, as written otherwise.
+func args(raw string, exprs []ast.Expr) string {
+	if exprs == nil {
+		return "(" + strings.TrimSpace(raw) + ")"
+	}
+	parts := make([]string, len(exprs))
+	for i, x := range exprs {
+		parts[i] = ast.ExprString(x)
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+// condition returns the text of an if, for, switch, or guard condition:
+// rendered from its expression when it parsed, as written otherwise.
+func condition(raw string, x ast.Expr) string {
+	if x == nil {
+		return strings.TrimSpace(raw)
+	}
+	return ast.ExprString(x)
+}
+
+// arrow returns the binding after ->, in parens when it names several
+// values, or nothing when there is none.
+func arrow(binding string) string {
+	names := ast.SplitList(binding)
+	switch {
+	case len(names) == 0:
+		return ""
+	case len(names) == 1 && isName(names[0]):
+		return " -> " + names[0]
+	}
+	return " -> (" + strings.Join(names, ", ") + ")"
+}
+
+// signature returns a definition's parameters in parens and the return
+// types after them.
+func signature(params, returns string) string {
+	text := "(" + paramList(params) + ")"
+	if types := typeList(returns); types != "" {
+		text += " -> (" + types + ")"
+	}
+	return text
+}
+
+// paramList returns an opaque parameter list with one space after each
+// comma and colon and around each default's =.
+func paramList(params string) string {
+	var parts []string
+	for _, prm := range ast.ParseParams(params) {
+		var b strings.Builder
+		for _, ann := range prm.Annotations {
+			b.WriteString("@" + ann + " ")
+		}
+		b.WriteString(prm.Name)
+		if prm.Type != "" {
+			b.WriteString(": " + prm.Type)
+		}
+		if prm.Default != "" {
+			b.WriteString(" = " + prm.Default)
+		}
+		parts = append(parts, b.String())
+	}
+	return strings.Join(parts, ", ")
+}
+
+// typeList returns an opaque list of return types with one space after
+// each comma.
+func typeList(types string) string {
+	return strings.Join(ast.SplitList(types), ", ")
+}
+
+// withValue returns keyword followed by value, if there is one.
+func withValue(keyword, value string) string {
+	if value = strings.TrimSpace(value); value != "" {
+		return keyword + " " + value
+	}
+	return keyword
+}
+
+// quote returns s, an unquoted string literal, quoted as TWF writes it.
+func quote(s string) string {
+	return ast.ExprString(&ast.StringLit{Value: s})
+}
+
+func isName(s string) bool {
+	for i, r := range s {
+		if r != '_' && !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// verify reparses out, the layout printed for src, and reports an error
+// unless it has the same definitions and comments. Positions are ignored,
+// and so is the spacing within opaque text such as arguments, which the
+// layout normalizes.
+func verify(src string, file *ast.File, out string, lex lexer.Options) error {
+	got, err := parser.WithLexer(lex).ParseFile(out)
+	if err != nil {
+		return fmt.Errorf("formatting would not parse: %v", err)
+	}
+	for i, def := range file.Definitions {
+		if i >= len(got.Definitions) || shape(def) != shape(got.Definitions[i]) {
+			return fmt.Errorf("formatting would change the definition at line %d", def.NodeLine())
+		}
+	}
+	if len(got.Definitions) != len(file.Definitions) {
+		return fmt.Errorf("formatting would add definitions")
+	}
+	want, have := newPrinter(src, lex).comments, newPrinter(out, lex).comments
+	if len(have) > len(want) {
+		return fmt.Errorf("formatting would add comments")
+	}
+	for i, c := range want {
+		if i >= len(have) || have[i].text != c.text {
+			return fmt.Errorf("formatting would change the comment at line %d", c.line)
+		}
+	}
+	return nil
+}
+
+// shape returns the JSON of a definition without positions and with its
+// strings spaced alike.
+func shape(def ast.Definition) string {
+	data, err := json.Marshal(def)
+	if err != nil {
+		return err.Error()
+	}
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err.Error()
+	}
+	data, _ = json.Marshal(normalize(v))
+	return string(data)
+}
+
+func normalize(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		delete(v, "line")
+		delete(v, "column")
+		for k, x := range v {
+			v[k] = normalize(x)
+		}
+	case []any:
+		for i, x := range v {
+			v[i] = normalize(x)
+		}
+	case string:
+		return squeeze(v)
+	}
+	return v
+}
+
+// squeeze drops the whitespace in s except single spaces between words.
+func squeeze(s string) string {
+	var b strings.Builder
+	word := func(r rune) bool { return r == '_' || r == '"' || unicode.IsLetter(r) || unicode.IsDigit(r) }
+	fields := strings.Fields(s)
+	for i, f := range fields {
+		if i > 0 {
+			prev, _ := utf8.DecodeLastRuneInString(fields[i-1])
+			if next := []rune(f)[0]; word(prev) && word(next) {
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(f)
+	}
+	return b.String()
+}
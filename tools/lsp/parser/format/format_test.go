@@ -0,0 +1,219 @@
+package format
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/examples"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/lexer"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/parser"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "headers and arguments",
+			src: `@sla( 5m )   @owner("team")
+workflow   Order( id:string ,@pii email : string,retries: int=3 )->( Result,error ):
+  activity Charge( id,email )->r
+  workflow Child(id) id "order-{id}" -> (a,b)
+  if (total>3 and not approved):
+      close fail(  "too many" )
+  else if(total<1):
+      y = 2
+  else:
+      close complete
+`,
+			want: `@sla(5m)
+@owner("team")
+workflow Order(id: string, @pii email: string, retries: int = 3) -> (Result, error):
+    activity Charge(id, email) -> r
+    workflow Child(id) id "order-{id}" -> (a, b)
+    if (total > 3 and not approved):
+        close fail("too many")
+    elif (total < 1):
+        y = 2
+    else:
+        close complete
+`,
+		},
+		{
+			name: "options",
+			src: `const Limit = 5
+activity Charge():
+  options:
+      start_to_close_timeout:   30s
+      retry_policy:
+          maximum_attempts:  Limit
+          non_retryable_error_types:  ["A","B"]
+  return
+`,
+			want: `const Limit = 5
+
+activity Charge():
+    options:
+        start_to_close_timeout: 30s
+        retry_policy:
+            maximum_attempts: Limit
+            non_retryable_error_types: ["A", "B"]
+    return
+`,
+		},
+		{
+			name: "blank lines",
+			src: `
+
+
+workflow A():
+
+
+
+  activity Step()
+
+
+  activity Step()
+activity Step():
+
+  return
+`,
+			want: `workflow A():
+    activity Step()
+
+    activity Step()
+
+activity Step():
+    return
+`,
+		},
+		{
+			name: "comments",
+			src: `# Orders.
+workflow Order():
+  # Declared first.
+  signal Cancel():
+      set cancelled
+      # Last in the handler.
+
+  # The body.
+  await one:
+      signal Cancel:
+          close fail   # cancelled
+      timer(1h):
+  # after await one
+  close complete
+# Workers.
+worker w:
+  # the order workflow
+  workflow Order
+`,
+			want: `# Orders.
+workflow Order():
+    # Declared first.
+    signal Cancel():
+        set cancelled
+        # Last in the handler.
+
+    # The body.
+    await one:
+        signal Cancel:
+            close fail  # cancelled
+        timer(1h):
+    # after await one
+    close complete
+
+# Workers.
+worker w:
+    # the order workflow
+    workflow Order
+`,
+		},
+		{
+			name: "interleaved entries keep their order",
+			src: `worker w:
+    activity A
+    workflow B
+    activity C
+
+namespace ns:
+    nexus endpoint E
+    worker w
+        options:
+            task_queue:"q"
+`,
+			want: `worker w:
+    activity A
+    workflow B
+    activity C
+
+namespace ns:
+    nexus endpoint E
+    worker w
+        options:
+            task_queue: "q"
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Source(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+			if again, err := Source(got); err != nil || again != got {
+				t.Errorf("formatting again changed it (%v):\n%s", err, again)
+			}
+		})
+	}
+}
+
+// TestSourceExamples requires the example designs to be formatted already,
+// since they are what twf examples init starts a design from.
+func TestSourceExamples(t *testing.T) {
+	for _, ex := range examples.All() {
+		got, err := Source(ex.Source)
+		if err != nil {
+			t.Errorf("%s: %v", ex.Name, err)
+			continue
+		}
+		if got != ex.Source {
+			t.Errorf("%s is not formatted; got:\n%s", ex.Name, got)
+		}
+	}
+}
+
+func TestSourceParseError(t *testing.T) {
+	if _, err := Source("workflow Order(:\n"); err == nil {
+		t.Error("expected a parse error")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	src := "workflow Order():\n    # charge first\n    activity Charge(order)\n"
+	file, err := parser.ParseFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for out, want := range map[string]string{
+		src: "",
+		"workflow Order():\n    # charge first\n    activity Charge( order )\n": "",
+		"workflow Order():\n    # charge first\n    activity Charge(other)\n":   "change the definition at line 1",
+		"workflow Order():\n    activity Charge(order)  # charge first\n":       "change the definition at line 1",
+		"workflow Order():\n    # charge  first\n    activity Charge(order)\n":  "change the comment at line 2",
+		"workflow Order():\n    activity Charge(order\n":                        "not parse",
+	} {
+		err := verify(src, file, out, lexer.Options{})
+		switch {
+		case want == "" && err != nil:
+			t.Errorf("verify(%q) = %v, want nil", out, err)
+		case want != "" && (err == nil || !strings.Contains(err.Error(), want)):
+			t.Errorf("verify(%q) = %v, want an error containing %q", out, err, want)
+		}
+	}
+}
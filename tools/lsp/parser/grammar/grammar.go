@@ -0,0 +1,56 @@
+// Package grammar generates editor grammars for TWF.
+//
+// Keyword lists come from the token table and their colors from
+// highlight.KeywordKind, so a keyword added to the lexer shows up in every
+// generated grammar with the same class the language server gives it.
+// Editors without LSP support still get highlighting that matches.
+package grammar
+
+import (
+	"strings"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/highlight"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/token"
+)
+
+// keywordKinds lists the keyword classes in the order grammars emit them.
+var keywordKinds = []highlight.Kind{highlight.Type, highlight.Property, highlight.Control}
+
+// softKeywords are words with keyword meaning that lex as identifiers
+// (heartbeat is an activity-only call), grouped by the class they are
+// colored as.
+var softKeywords = map[highlight.Kind][]string{
+	highlight.Type: {"heartbeat"},
+}
+
+// operators are the spellings the lexer accepts as OPERATOR tokens, longest
+// first so alternations match greedily.
+var operators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "+", "-", "*", "/", "%", "!", "="}
+
+// keywordsByKind groups every keyword and soft keyword by highlight kind.
+func keywordsByKind() map[highlight.Kind][]string {
+	groups := make(map[highlight.Kind][]string)
+	for _, kw := range token.Keywords() {
+		if kind, ok := highlight.KeywordKind(token.LookupIdent(kw)); ok {
+			groups[kind] = append(groups[kind], kw)
+		}
+	}
+	for kind, kws := range softKeywords {
+		groups[kind] = append(groups[kind], kws...)
+	}
+	return groups
+}
+
+// spelling returns the source spelling of a keyword token.
+func spelling(tt token.TokenType) string {
+	return strings.ToLower(tt.String())
+}
+
+// spellings returns the source spellings of keyword tokens.
+func spellings(tts ...token.TokenType) []string {
+	out := make([]string, len(tts))
+	for i, tt := range tts {
+		out[i] = spelling(tt)
+	}
+	return out
+}
@@ -0,0 +1,115 @@
+package grammar
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/token"
+)
+
+func TestTextMateKeywords(t *testing.T) {
+	out, err := TextMate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var g tmGrammar
+	if err := json.Unmarshal(out, &g); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+
+	// Every keyword must be matched by exactly the keyword pattern of its kind.
+	var keywordRes []*regexp.Regexp
+	for _, kind := range keywordKinds {
+		p := g.Repository["keyword-"+kind.String()].Patterns[0]
+		keywordRes = append(keywordRes, regexp.MustCompile(p.Match))
+	}
+	for _, kw := range token.Keywords() {
+		matched := 0
+		for _, re := range keywordRes {
+			if re.MatchString(kw) {
+				matched++
+			}
+		}
+		if matched != 1 {
+			t.Errorf("keyword %q matched by %d keyword patterns, want 1", kw, matched)
+		}
+	}
+}
+
+func TestTextMateDefinitions(t *testing.T) {
+	out, err := TextMate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var g tmGrammar
+	if err := json.Unmarshal(out, &g); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct{ line, name string }{
+		{"workflow Order(id: string):", "Order"},
+		{"worker orderWorker:", "orderWorker"},
+		{"    signal Cancel():", "Cancel"},
+		{"const Timeout = 5m", "Timeout"},
+	}
+	for _, tt := range tests {
+		found := false
+		for _, key := range []string{"definition", "declaration"} {
+			for _, p := range g.Repository[key].Patterns {
+				m := regexp.MustCompile(p.Match).FindStringSubmatch(tt.line)
+				if m != nil && m[2] == tt.name {
+					found = true
+				}
+			}
+		}
+		if !found {
+			t.Errorf("no definition pattern captures %q in %q", tt.name, tt.line)
+		}
+	}
+}
+
+// TestTextMateCheckedIn fails when the grammar shipped with the VS Code
+// extension differs from the generated one.
+func TestTextMateCheckedIn(t *testing.T) {
+	const path = "../../../../packages/vscode/syntaxes/twf.tmLanguage.json"
+	want, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		t.Skip("VS Code extension not present")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := TextMate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s is stale; regenerate with: twf grammar --textmate --out packages/vscode/syntaxes", path)
+	}
+}
+
+func TestTreeSitter(t *testing.T) {
+	js := TreeSitter()
+	for _, kw := range token.Keywords() {
+		if !strings.Contains(js, "'"+kw+"'") {
+			t.Errorf("grammar.js missing keyword %q", kw)
+		}
+	}
+	if strings.Count(js, "(") != strings.Count(js, ")") {
+		t.Error("grammar.js has unbalanced parentheses")
+	}
+
+	scm := TreeSitterHighlights()
+	for _, kind := range keywordKinds {
+		rule := treeSitterRule(kind)
+		if !strings.Contains(js, "    "+rule+": ") {
+			t.Errorf("grammar.js missing rule %s", rule)
+		}
+		if !strings.Contains(scm, "("+rule+") ") {
+			t.Errorf("highlights.scm missing capture for %s", rule)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package grammar
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/highlight"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/token"
+)
+
+// textMateScopes maps keyword kinds to TextMate scope names.
+var textMateScopes = map[highlight.Kind]string{
+	highlight.Type:     "storage.type.twf",
+	highlight.Property: "support.type.property-name.twf",
+	highlight.Control:  "keyword.control.twf",
+}
+
+// identPattern matches a TWF identifier.
+const identPattern = `[A-Za-z_][A-Za-z0-9_]*`
+
+type tmGrammar struct {
+	Schema     string               `json:"$schema"`
+	Name       string               `json:"name"`
+	ScopeName  string               `json:"scopeName"`
+	Patterns   []tmPattern          `json:"patterns"`
+	Repository map[string]tmPattern `json:"repository"`
+}
+
+type tmPattern struct {
+	Include       string               `json:"include,omitempty"`
+	Name          string               `json:"name,omitempty"`
+	Match         string               `json:"match,omitempty"`
+	Begin         string               `json:"begin,omitempty"`
+	End           string               `json:"end,omitempty"`
+	Captures      map[string]tmCapture `json:"captures,omitempty"`
+	BeginCaptures map[string]tmCapture `json:"beginCaptures,omitempty"`
+	EndCaptures   map[string]tmCapture `json:"endCaptures,omitempty"`
+	Patterns      []tmPattern          `json:"patterns,omitempty"`
+}
+
+type tmCapture struct {
+	Name string `json:"name"`
+}
+
+// TextMate returns the TextMate grammar for TWF as indented JSON. The
+// VS Code extension ships this output as syntaxes/twf.tmLanguage.json.
+func TextMate() ([]byte, error) {
+	groups := keywordsByKind()
+	repo := map[string]tmPattern{
+		"comment": {Patterns: []tmPattern{{
+			Name:  "comment.line.number-sign.twf",
+			Match: "#.*$",
+		}}},
+		"string": {Patterns: []tmPattern{
+			quoted("string.quoted.triple.twf", `"""`),
+			quoted("string.quoted.double.twf", `"`),
+		}},
+		// A description: line opens free-form text, which runs while lines
+		// are blank or indented deeper than it.
+		"description": {Patterns: []tmPattern{{
+			Name:  "string.unquoted.description.twf",
+			Begin: `^(\s*)(description)\s*(:)\s*$`,
+			End:   `^(?!\1\s+\S|\s*$)`,
+			BeginCaptures: map[string]tmCapture{
+				"2": {Name: textMateScopes[highlight.Type]},
+				"3": {Name: "keyword.operator.twf"},
+			},
+		}}},
+		"definition": {Patterns: []tmPattern{
+			named(`\b`, "entity.name.function.twf", spellings(token.WORKFLOW, token.ACTIVITY, token.WORKER, token.NAMESPACE)...),
+			named("^", "variable.other.constant.twf", spelling(token.CONST)),
+			named("^", "entity.name.type.enum.twf", spelling(token.ENUM)),
+		}},
+		"declaration": {Patterns: []tmPattern{
+			named(`\b`, "entity.name.function.twf", spellings(token.SIGNAL, token.QUERY, token.UPDATE)...),
+		}},
+		"annotation": {Patterns: []tmPattern{{
+			Name:  textMateScopes[highlight.Property],
+			Match: "@" + identPattern,
+		}}},
+		"constant": {Patterns: []tmPattern{
+			{Name: "constant.language.boolean.twf", Match: `\b(true|false)\b`},
+			{Name: "constant.numeric.duration.twf", Match: `\b[0-9]+(\.[0-9]+)?(ms|s|m|h|d)\b`},
+			{Name: "constant.numeric.twf", Match: `\b[0-9]+(\.[0-9]+)?\b`},
+		}},
+		"arrow": {Patterns: []tmPattern{{
+			Name:  "keyword.operator.arrow.twf",
+			Match: "->|<-",
+		}}},
+		"operator": {Patterns: []tmPattern{{
+			Name:  "keyword.operator.twf",
+			Match: alternation(operators),
+		}}},
+		"parens": {Patterns: []tmPattern{{
+			Name:          "meta.parens.twf",
+			Begin:         `\(`,
+			End:           `\)`,
+			BeginCaptures: capture0("punctuation.section.parens.begin.twf"),
+			EndCaptures:   capture0("punctuation.section.parens.end.twf"),
+			Patterns:      includes("#comment", "#string", "#arrow", "$self"),
+		}}},
+	}
+
+	top := includes("#comment", "#string", "#description", "#annotation", "#definition", "#declaration")
+	for _, kind := range keywordKinds {
+		key := "keyword-" + kind.String()
+		repo[key] = tmPattern{Patterns: []tmPattern{{
+			Name:  textMateScopes[kind],
+			Match: `\b` + alternation(groups[kind]) + `\b`,
+		}}}
+		top = append(top, tmPattern{Include: "#" + key})
+	}
+	top = append(top, includes("#constant", "#arrow", "#operator", "#parens")...)
+
+	g := tmGrammar{
+		Schema:     "https://raw.githubusercontent.com/martinring/tmlanguage/master/tmlanguage.json",
+		Name:       "Temporal Workflow",
+		ScopeName:  "source.twf",
+		Patterns:   top,
+		Repository: repo,
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(g); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// named matches one of keywords followed by the name it introduces, scoping
+// the keyword as storage.type and the name as nameScope. anchor precedes the
+// keyword group.
+func named(anchor, nameScope string, keywords ...string) tmPattern {
+	return tmPattern{
+		Match: anchor + alternation(keywords) + `\s+(` + identPattern + `)\b`,
+		Captures: map[string]tmCapture{
+			"1": {Name: textMateScopes[highlight.Type]},
+			"2": {Name: nameScope},
+		},
+	}
+}
+
+// quoted matches a string delimited by quote on both ends.
+func quoted(scope, quote string) tmPattern {
+	return tmPattern{
+		Name:          scope,
+		Begin:         quote,
+		End:           quote,
+		BeginCaptures: capture0("punctuation.definition.string.begin.twf"),
+		EndCaptures:   capture0("punctuation.definition.string.end.twf"),
+	}
+}
+
+func capture0(scope string) map[string]tmCapture {
+	return map[string]tmCapture{"0": {Name: scope}}
+}
+
+func includes(refs ...string) []tmPattern {
+	out := make([]tmPattern, len(refs))
+	for i, ref := range refs {
+		out[i] = tmPattern{Include: ref}
+	}
+	return out
+}
+
+// alternation returns a capturing regex group matching any of words
+// literally.
+func alternation(words []string) string {
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		quoted[i] = regexp.QuoteMeta(w)
+	}
+	return "(" + strings.Join(quoted, "|") + ")"
+}
@@ -0,0 +1,94 @@
+package grammar
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/highlight"
+)
+
+// treeSitterCaptures maps keyword kinds to tree-sitter highlight captures.
+var treeSitterCaptures = map[highlight.Kind]string{
+	highlight.Type:     "@type.builtin",
+	highlight.Property: "@property",
+	highlight.Control:  "@keyword",
+}
+
+// punctuation lists the single-character delimiters the lexer emits as
+// their own tokens.
+var punctuation = []string{":", ".", ",", "(", ")", "[", "]", "{", "}"}
+
+// TreeSitter returns a tree-sitter grammar.js for TWF. The grammar is
+// lexical: it recognizes tokens but not statements, which is all editors
+// need for highlighting and keeps it independent of the parser.
+func TreeSitter() string {
+	groups := keywordsByKind()
+	var b strings.Builder
+	b.WriteString("// Generated by `twf grammar --tree-sitter`. Do not edit.\n")
+	b.WriteString("module.exports = grammar({\n")
+	b.WriteString("  name: 'twf',\n\n")
+	b.WriteString("  word: $ => $.identifier,\n\n")
+	b.WriteString("  extras: $ => [/\\s/],\n\n")
+	b.WriteString("  rules: {\n")
+	b.WriteString("    source_file: $ => repeat($._token),\n\n")
+
+	tokens := []string{"$.comment", "$.string", "$.annotation", "$.duration", "$.number", "$.boolean"}
+	for _, kind := range keywordKinds {
+		tokens = append(tokens, "$."+treeSitterRule(kind))
+	}
+	tokens = append(tokens, "$.identifier", "$.operator", "$.punctuation")
+	fmt.Fprintf(&b, "    _token: $ => choice(\n      %s,\n    ),\n\n", strings.Join(tokens, ",\n      "))
+
+	b.WriteString("    comment: _ => token(seq('#', /.*/)),\n\n")
+	b.WriteString("    string: _ => choice(\n")
+	b.WriteString("      token(seq('\"\"\"', /([^\"]|\"[^\"]|\"\"[^\"])*/, '\"\"\"')),\n")
+	b.WriteString("      token(seq('\"', /[^\"\\n]*/, '\"')),\n")
+	b.WriteString("    ),\n\n")
+	b.WriteString("    annotation: _ => token(seq('@', /[A-Za-z_][A-Za-z0-9_]*/)),\n\n")
+	b.WriteString("    duration: _ => /\\d+(\\.\\d+)?(ms|s|m|h|d)/,\n\n")
+	b.WriteString("    number: _ => /\\d+(\\.\\d+)?/,\n\n")
+	b.WriteString("    boolean: _ => choice('true', 'false'),\n\n")
+	for _, kind := range keywordKinds {
+		fmt.Fprintf(&b, "    %s: _ => %s,\n\n", treeSitterRule(kind), jsChoice(groups[kind]))
+	}
+	b.WriteString("    identifier: _ => /[A-Za-z_][A-Za-z0-9_]*/,\n\n")
+	fmt.Fprintf(&b, "    operator: _ => %s,\n\n", jsChoice(append([]string{"->", "<-"}, operators...)))
+	fmt.Fprintf(&b, "    punctuation: _ => %s,\n", jsChoice(punctuation))
+	b.WriteString("  },\n")
+	b.WriteString("});\n")
+	return b.String()
+}
+
+// TreeSitterHighlights returns queries/highlights.scm for the grammar
+// returned by TreeSitter.
+func TreeSitterHighlights() string {
+	var b strings.Builder
+	b.WriteString("; Generated by `twf grammar --tree-sitter`. Do not edit.\n")
+	b.WriteString("(comment) @comment\n")
+	b.WriteString("(string) @string\n")
+	b.WriteString("(annotation) @attribute\n")
+	b.WriteString("(duration) @number\n")
+	b.WriteString("(number) @number\n")
+	b.WriteString("(boolean) @constant.builtin\n")
+	for _, kind := range keywordKinds {
+		fmt.Fprintf(&b, "(%s) %s\n", treeSitterRule(kind), treeSitterCaptures[kind])
+	}
+	b.WriteString("(operator) @operator\n")
+	b.WriteString("(punctuation) @punctuation.delimiter\n")
+	b.WriteString("(identifier) @variable\n")
+	return b.String()
+}
+
+// treeSitterRule names the grammar rule for keywords of kind.
+func treeSitterRule(kind highlight.Kind) string {
+	return kind.String() + "_keyword"
+}
+
+// jsChoice renders a tree-sitter choice over string literals.
+func jsChoice(words []string) string {
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		quoted[i] = "'" + w + "'"
+	}
+	return "choice(" + strings.Join(quoted, ", ") + ")"
+}
@@ -0,0 +1,383 @@
+// Package highlight classifies TWF source for syntax coloring.
+//
+// The language server, the CLI, and documentation tooling share this
+// classification so a design reads the same in an editor, a terminal, and a
+// rendered page. Classification is purely lexical: it needs no successful
+// parse and works on partial or invalid input.
+package highlight
+
+import (
+	"strings"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/lexer"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/token"
+)
+
+// Kind is the highlight class of a span.
+type Kind uint32
+
+// Kinds in Legend order. Their values double as LSP semantic token type
+// indices.
+const (
+	Keyword Kind = iota
+	Function
+	Method
+	Event
+	String
+	Comment
+	Operator
+	Parameter
+	Type
+	Variable
+	Property
+	Number
+
+	// Control marks control-flow keywords (if, for, await, ...). It is not
+	// in Legend: editors color these through the TextMate grammar so themes
+	// that merge keyword and type colors still tell them apart.
+	Control
+)
+
+// Legend names each Kind below Control, indexed by Kind. The names follow
+// the LSP semantic token types. The array size is a compile-time check that
+// every legend Kind has a name.
+var Legend = [Control]string{
+	Keyword:   "keyword",
+	Function:  "function",
+	Method:    "method",
+	Event:     "event",
+	String:    "string",
+	Comment:   "comment",
+	Operator:  "operator",
+	Parameter: "parameter",
+	Type:      "type",
+	Variable:  "variable",
+	Property:  "property",
+	Number:    "number",
+}
+
+// String returns the Legend name of k, or "control" for Control.
+func (k Kind) String() string {
+	if k == Control {
+		return "control"
+	}
+	if k < Control {
+		return Legend[k]
+	}
+	return "unknown"
+}
+
+// Modifier is a bit set of span modifiers.
+type Modifier uint32
+
+const (
+	// Declaration marks the name being defined (workflow Foo, signal Bar).
+	Declaration Modifier = 1 << iota
+)
+
+// Span is a classified run of source text on a single line. Line and Column
+// are 0-based; Column and Length count bytes.
+type Span struct {
+	Line      int
+	Column    int
+	Length    int
+	Kind      Kind
+	Modifiers Modifier
+}
+
+// Spans lexes src and returns its classified spans in source order. Tokens
+// that span lines, such as triple-quoted strings, yield one span per line.
+func Spans(src string) []Span {
+	return SpansWith(src, lexer.Options{})
+}
+
+// SpansWith is Spans for src lexed with lex, such as under keyword aliases.
+func SpansWith(src string, lex lexer.Options) []Span {
+	tokens := lex.New(src).AllTokens()
+
+	var spans []Span
+	var prevType token.TokenType
+	indentLevel := 0
+	inOptions := false
+	optionsBaseIndent := 0
+
+	for i, tok := range tokens {
+		switch tok.Type {
+		case token.INDENT:
+			indentLevel++
+			prevType = tok.Type
+			continue
+		case token.DEDENT:
+			indentLevel--
+			if indentLevel < 0 {
+				indentLevel = 0
+			}
+			if inOptions && indentLevel <= optionsBaseIndent {
+				inOptions = false
+			}
+			prevType = tok.Type
+			continue
+		}
+
+		if tok.Type == token.OPTIONS {
+			inOptions = true
+			optionsBaseIndent = indentLevel
+		}
+
+		if tok.Type == token.ARGS && strings.ContainsAny(tok.Literal, "[{") {
+			spans = append(spans, classifyLiteralArgs(tok, lex)...)
+			prevType = tok.Type
+			continue
+		}
+
+		kind, mods, ok := classifyToken(tok, prevType, indentLevel, inOptions)
+		if tok.Type == token.IDENT && i+2 < len(tokens) && tokens[i+2].Type == token.TEXT {
+			// description is a name everywhere but above its text block.
+			kind, mods = Type, 0
+		}
+		if ok {
+			for _, s := range tokenSpans(tok) {
+				if s.Length == 0 {
+					continue // the keywords an alias expands to after its first
+				}
+				s.Kind, s.Modifiers = kind, mods
+				spans = append(spans, s)
+			}
+		}
+
+		if !isStructural(tok.Type) {
+			prevType = tok.Type
+		}
+	}
+
+	return spans
+}
+
+// classifyLiteralArgs re-lexes the content of an ARGS token that contains
+// list or map literals so map keys, values, and literals are highlighted
+// individually instead of as one opaque parameter span.
+func classifyLiteralArgs(tok token.Token, lex lexer.Options) []Span {
+	subs := lex.NewInline(tok.Literal, tok.Line, tok.Column+1).AllTokens()
+	var out []Span
+	braceDepth := 0
+	for i, sub := range subs {
+		var kind Kind
+		switch sub.Type {
+		case token.LBRACE:
+			braceDepth++
+			continue
+		case token.RBRACE:
+			braceDepth--
+			continue
+		case token.IDENT:
+			kind = Parameter
+			isKey := braceDepth > 0 && i+1 < len(subs) && subs[i+1].Type == token.COLON
+			if isKey {
+				kind = Property
+			}
+		case token.STRING:
+			kind = String
+		case token.NUMBER, token.DURATION:
+			kind = Number
+		case token.BOOL:
+			kind = Keyword
+		default:
+			continue
+		}
+		for _, s := range tokenSpans(sub) {
+			s.Kind = kind
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// isStructural returns true for tokens that don't affect classification context.
+func isStructural(tt token.TokenType) bool {
+	switch tt {
+	case token.NEWLINE, token.EOF:
+		return true
+	default:
+		return false
+	}
+}
+
+// classifyToken determines the highlight kind and modifiers for a token.
+func classifyToken(tok token.Token, prevType token.TokenType, indentLevel int, inOptions bool) (kind Kind, mods Modifier, ok bool) {
+	if kind, ok := KeywordKind(tok.Type); ok {
+		return kind, 0, true
+	}
+	switch tok.Type {
+	case token.IDENT:
+		if inOptions {
+			// Inside options block: option keys and enum values.
+			return Property, 0, true
+		}
+		return classifyIdent(prevType, indentLevel)
+
+	case token.STRING, token.TEXT:
+		return String, 0, true
+
+	case token.COMMENT:
+		return Comment, 0, true
+
+	case token.COLON, token.ARROW, token.DOT, token.OPERATOR:
+		return Operator, 0, true
+
+	case token.AT:
+		// Annotations (@owner, @sla) are metadata, muted like option keys.
+		return Property, 0, true
+
+	case token.ARGS:
+		return Parameter, 0, true
+
+	case token.DURATION, token.NUMBER:
+		return Number, 0, true
+
+	case token.BOOL:
+		return Keyword, 0, true
+
+	default:
+		return 0, 0, false
+	}
+}
+
+// KeywordKind returns the highlight kind of a keyword token. Keywords are
+// classified without context, which lets grammar generators color them from
+// the token table alone. ok is false for non-keyword tokens.
+func KeywordKind(tt token.TokenType) (kind Kind, ok bool) {
+	switch tt {
+	// Temporal primitive keywords.
+	case token.WORKFLOW, token.ACTIVITY, token.WORKER, token.NAMESPACE, token.CONST, token.ENUM,
+		token.SIGNAL, token.QUERY, token.UPDATE,
+		token.TIMER,
+		token.PROMISE, token.STATE, token.CONDITION, token.SET, token.UNSET,
+		token.CLOSE, token.COMPLETE, token.FAIL, token.CONTINUE_AS_NEW,
+		token.SYNC, token.ASYNC:
+		return Type, true
+
+	// OPTIONS / config keywords: muted (property).
+	case token.OPTIONS, token.TASK_QUEUE:
+		return Property, true
+
+	case token.IF, token.ELSE, token.ELIF, token.FOR, token.IN,
+		token.SWITCH, token.CASE,
+		token.AWAIT, token.ALL, token.ONE,
+		token.RETURN, token.BREAK, token.CONTINUE,
+		token.DETACH, token.NEXUS:
+		return Control, true
+	}
+	return 0, false
+}
+
+// classifyIdent determines the highlight kind for an IDENT based on context.
+func classifyIdent(prevType token.TokenType, indentLevel int) (kind Kind, mods Modifier, ok bool) {
+	switch prevType {
+	// Temporal defined symbols — all use Function for consistent coloring.
+	case token.WORKFLOW, token.ACTIVITY:
+		if indentLevel == 0 {
+			return Function, Declaration, true
+		}
+		return Function, 0, true
+
+	case token.WORKER:
+		if indentLevel == 0 {
+			return Function, Declaration, true
+		}
+		// Worker reference inside namespace block
+		return Function, 0, true
+
+	case token.NAMESPACE:
+		if indentLevel == 0 {
+			return Function, Declaration, true
+		}
+		return Variable, 0, true
+
+	case token.SIGNAL, token.QUERY, token.UPDATE:
+		if indentLevel == 1 {
+			// Handler declarations live at indent 1 inside a workflow.
+			return Function, Declaration, true
+		}
+		return Function, 0, true
+
+	case token.NEXUS:
+		// After NEXUS: endpoint name in call, or soft keyword ("service"/"endpoint")
+		return Function, 0, true
+
+	case token.SYNC, token.ASYNC:
+		// After SYNC/ASYNC: operation name declaration
+		return Function, Declaration, true
+
+	case token.TASK_QUEUE:
+		return Variable, 0, true
+
+	case token.CONST, token.ENUM:
+		return Variable, Declaration, true
+
+	case token.AT:
+		return Property, 0, true
+
+	default:
+		// Bare ident in body — loose statement (params, assignments, expressions).
+		return Variable, 0, true
+	}
+}
+
+// tokenSpans splits a token into per-line spans. Only triple-quoted strings
+// and description text span lines, so they are the only tokens that yield
+// more than one span.
+func tokenSpans(tok token.Token) []Span {
+	line := tok.Line - 1  // 0-based
+	col := tok.Column - 1 // 0-based
+	if tok.Type == token.TEXT {
+		// Each line's indentation is left out; blank lines yield no span.
+		var spans []Span
+		for i, text := range strings.Split(tok.Literal, "\n") {
+			trimmed := strings.TrimLeft(text, " ")
+			if i > 0 {
+				col = len(text) - len(trimmed)
+			}
+			if trimmed != "" {
+				spans = append(spans, Span{Line: line + i, Column: col, Length: len(trimmed)})
+			}
+		}
+		return spans
+	}
+	if tok.Type != token.STRING || !tok.Triple || !strings.Contains(tok.Literal, "\n") {
+		return []Span{{Line: line, Column: col, Length: tokenLength(tok)}}
+	}
+
+	lines := strings.Split(tok.Literal, "\n")
+	spans := make([]Span, 0, len(lines))
+	for i, text := range lines {
+		length := len(text)
+		if i == 0 {
+			length += 3 // opening quotes
+		} else {
+			line++
+			col = 0
+		}
+		if i == len(lines)-1 {
+			length += 3 // closing quotes
+		}
+		spans = append(spans, Span{Line: line, Column: col, Length: length})
+	}
+	return spans
+}
+
+// tokenLength returns the source length of a token.
+func tokenLength(tok token.Token) int {
+	switch tok.Type {
+	case token.ARGS:
+		return len(tok.Literal) + 2 // parens
+	case token.STRING:
+		if tok.Triple {
+			return len(tok.Literal) + 6 // triple quotes
+		}
+		return len(tok.Literal) + 2 // quotes
+	case token.COMMENT:
+		return len(tok.Literal) + 1 // #
+	default:
+		return len(tok.Literal)
+	}
+}
@@ -0,0 +1,138 @@
+package highlight
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+const sample = `# Order flow
+@owner("payments")
+workflow Order(id: string) -> (Result):
+    description:
+        Charges and ships
+          one order.
+    signal Cancel():
+        cancelled = true
+    if (cancelled):
+        return "cancelled"
+    activity Charge(id)
+        options:
+            start_to_close_timeout: 30s
+    note = """two
+line"""
+`
+
+// spanText returns the source text covered by a span.
+func spanText(src string, s Span) string {
+	line := strings.Split(src, "\n")[s.Line]
+	return line[s.Column : s.Column+s.Length]
+}
+
+func TestSpans(t *testing.T) {
+	want := map[string]struct {
+		kind Kind
+		mods Modifier
+	}{
+		"# Order flow":           {Comment, 0},
+		"@":                      {Property, 0},
+		"owner":                  {Property, 0},
+		"workflow":               {Type, 0},
+		"Order":                  {Function, Declaration},
+		"description":            {Type, 0},
+		"Charges and ships":      {String, 0},
+		"one order.":             {String, 0},
+		"(id: string)":           {Parameter, 0},
+		"Cancel":                 {Function, Declaration},
+		"if":                     {Control, 0},
+		`"cancelled"`:            {String, 0},
+		"Charge":                 {Function, 0},
+		"options":                {Property, 0},
+		"start_to_close_timeout": {Property, 0},
+		"30s":                    {Number, 0},
+		`"""two`:                 {String, 0},
+		`line"""`:                {String, 0},
+	}
+	found := make(map[string]bool)
+	for _, s := range Spans(sample) {
+		text := spanText(sample, s)
+		w, ok := want[text]
+		if !ok {
+			continue
+		}
+		found[text] = true
+		if s.Kind != w.kind || s.Modifiers != w.mods {
+			t.Errorf("%q: got %s/%d, want %s/%d", text, s.Kind, s.Modifiers, w.kind, w.mods)
+		}
+	}
+	for text := range want {
+		if !found[text] {
+			t.Errorf("no span for %q", text)
+		}
+	}
+}
+
+func TestElseIfKeywords(t *testing.T) {
+	src := `workflow Route(tier: string):
+    if (tier == "gold"):
+        return
+    elif (tier == "silver"):
+        return
+    else if (tier == "bronze"):
+        return
+    else:
+        return
+`
+	var got []string
+	for _, s := range Spans(src) {
+		if s.Kind == Control {
+			got = append(got, spanText(src, s))
+		}
+	}
+	want := []string{"if", "return", "elif", "return", "else", "if", "return", "else", "return"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("control spans = %q, want %q", got, want)
+	}
+}
+
+func TestHTML(t *testing.T) {
+	out := HTML(sample)
+	if !strings.HasPrefix(out, `<pre class="twf"><code>`) {
+		t.Fatalf("missing pre wrapper: %q", out[:40])
+	}
+	if !strings.Contains(out, `<span class="twf-function twf-declaration">Order</span>`) {
+		t.Error("expected declaration class on workflow name")
+	}
+	if !strings.Contains(out, `<span class="twf-operator">-&gt;</span>`) {
+		t.Error("expected escaped arrow operator")
+	}
+
+	// Stripping tags and unescaping must give back the source.
+	tags := regexp.MustCompile(`<[^>]+>`)
+	if got := html.UnescapeString(tags.ReplaceAllString(out, "")); got != sample+"\n" {
+		t.Errorf("HTML text does not round-trip:\n%s", got)
+	}
+}
+
+func TestANSI(t *testing.T) {
+	out := ANSI(sample)
+	if !strings.Contains(out, "\x1b[1;35mif\x1b[0m") {
+		t.Error("expected colored control keyword")
+	}
+	esc := regexp.MustCompile("\x1b\\[[0-9;]*m")
+	if got := esc.ReplaceAllString(out, ""); got != sample {
+		t.Errorf("ANSI text does not round-trip:\n%s", got)
+	}
+}
+
+func TestLegend(t *testing.T) {
+	for k := Keyword; k < Control; k++ {
+		if Legend[k] == "" || k.String() != Legend[k] {
+			t.Errorf("kind %d has no legend name", k)
+		}
+	}
+	if Control.String() != "control" {
+		t.Errorf("got %q for Control", Control.String())
+	}
+}
@@ -0,0 +1,84 @@
+package highlight
+
+import (
+	"html"
+	"strings"
+)
+
+// HTML renders src as a <pre class="twf"> block. Each classified span is
+// wrapped in <span class="twf-KIND">, with a twf-declaration class added
+// for declarations, so pages style TWF with plain CSS.
+func HTML(src string) string {
+	var b strings.Builder
+	b.WriteString(`<pre class="twf"><code>`)
+	render(&b, src, html.EscapeString, func(s Span) (string, string) {
+		class := "twf-" + s.Kind.String()
+		if s.Modifiers&Declaration != 0 {
+			class += " twf-declaration"
+		}
+		return `<span class="` + class + `">`, "</span>"
+	})
+	b.WriteString("</code></pre>\n")
+	return b.String()
+}
+
+// ansiColors maps each Kind to an SGR parameter string. Kinds without an
+// entry print uncolored.
+var ansiColors = map[Kind]string{
+	Keyword:   "35",   // magenta
+	Function:  "33",   // yellow
+	Method:    "33",   // yellow
+	Event:     "33",   // yellow
+	String:    "32",   // green
+	Comment:   "90",   // bright black
+	Parameter: "36",   // cyan
+	Type:      "34",   // blue
+	Property:  "94",   // bright blue
+	Number:    "31",   // red
+	Control:   "1;35", // bold magenta
+}
+
+// ANSI renders src with ANSI color escapes for terminal output.
+// Declarations are also underlined.
+func ANSI(src string) string {
+	var b strings.Builder
+	render(&b, src, nil, func(s Span) (string, string) {
+		color, ok := ansiColors[s.Kind]
+		if !ok {
+			return "", ""
+		}
+		if s.Modifiers&Declaration != 0 {
+			color += ";4"
+		}
+		return "\x1b[" + color + "m", "\x1b[0m"
+	})
+	return b.String()
+}
+
+// render writes src to b, wrapping each span in the markup returned by wrap
+// and passing all text through escape when it is non-nil.
+func render(b *strings.Builder, src string, escape func(string) string, wrap func(Span) (open, close string)) {
+	if escape == nil {
+		escape = func(s string) string { return s }
+	}
+	spans := Spans(src)
+	lines := strings.SplitAfter(src, "\n")
+	for i, line := range lines {
+		col := 0
+		for len(spans) > 0 && spans[0].Line <= i {
+			s := spans[0]
+			spans = spans[1:]
+			end := min(s.Column+s.Length, len(line))
+			if s.Line < i || s.Column < col || s.Column >= end {
+				continue // overlapping or out of range
+			}
+			open, close := wrap(s)
+			b.WriteString(escape(line[col:s.Column]))
+			b.WriteString(open)
+			b.WriteString(escape(line[s.Column:end]))
+			b.WriteString(close)
+			col = end
+		}
+		b.WriteString(escape(line[col:]))
+	}
+}
@@ -0,0 +1,484 @@
+// Package history builds synthetic Temporal event histories from TWF designs.
+//
+// A skeleton follows one path through a workflow body in design order: the
+// first branch of if, switch, and await one, and a single iteration of each
+// loop. Operations complete successfully, so the history is what a worker
+// implementing the design produces on its happy path. SDK teams can load it
+// into a replayer to check that their implementation issues the designed
+// commands in the designed order. Payloads are omitted.
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/eval"
+)
+
+// History is an event history in Temporal's JSON format, as printed by
+// `temporal workflow show --output json` and read by the SDK replayers.
+type History struct {
+	Events []Event `json:"events"`
+}
+
+// Event is one history event. Type is the event type without its
+// EVENT_TYPE_ prefix in Go naming, e.g. "ActivityTaskScheduled".
+type Event struct {
+	ID         int64
+	Time       time.Time
+	Type       string
+	Attributes map[string]any
+}
+
+// MarshalJSON writes the event the way protojson does: an int64 id as a
+// string, an EVENT_TYPE_* enum name, and attributes under
+// "<type>EventAttributes".
+func (e Event) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"eventId":   strconv.FormatInt(e.ID, 10),
+		"eventTime": e.Time.UTC().Format(time.RFC3339),
+		"eventType": "EVENT_TYPE_" + upperSnake(e.Type),
+		strings.ToLower(e.Type[:1]) + e.Type[1:] + "EventAttributes": e.Attributes,
+	})
+}
+
+var wordBoundary = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+
+func upperSnake(s string) string {
+	return strings.ToUpper(wordBoundary.ReplaceAllString(s, "${1}_${2}"))
+}
+
+// Start is the time of the first event. Later events are one second apart,
+// plus the duration of each timer that fires.
+var Start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// DefaultTaskQueue is used when no namespace deploys the workflow's worker
+// with a task_queue.
+const DefaultTaskQueue = "default"
+
+// Skeleton builds the history of one run of the named workflow. The file
+// should be resolved, so calls link to their definitions. taskQueue
+// overrides the workflow's task queue; when empty it comes from the
+// namespace that deploys a worker registering the workflow.
+func Skeleton(file *ast.File, workflow, taskQueue string) (*History, error) {
+	var wf *ast.WorkflowDef
+	for _, def := range file.Definitions {
+		if d, ok := def.(*ast.WorkflowDef); ok && d.Name == workflow {
+			wf = d
+		}
+	}
+	if wf == nil {
+		return nil, fmt.Errorf("no workflow named %s", workflow)
+	}
+
+	b := &builder{
+		queues:   deployedQueues(file),
+		promises: make(map[*ast.PromiseStmt]func()),
+		clock:    Start,
+	}
+	if taskQueue == "" {
+		taskQueue = b.queueOf(wf)
+	}
+	b.taskQueue = taskQueue
+
+	b.add("WorkflowExecutionStarted", map[string]any{
+		"workflowType":        named(wf.Name),
+		"taskQueue":           taskQueueAttr(taskQueue),
+		"workflowTaskTimeout": "10s",
+		"attempt":             1,
+	})
+	b.workflowTask()
+	if b.statements(wf.Body) != flowClosed {
+		b.add("WorkflowExecutionCompleted", map[string]any{
+			"workflowTaskCompletedEventId": id(b.lastTask),
+		})
+	}
+	return &History{Events: b.events}, nil
+}
+
+// flow is how a statement list ended.
+type flow int
+
+const (
+	flowNext   flow = iota // fell through
+	flowBreak              // break or continue left the loop iteration
+	flowClosed             // return or close ended the workflow
+)
+
+type builder struct {
+	events    []Event
+	clock     time.Time
+	taskQueue string
+	lastTask  int64 // id of the last WorkflowTaskCompleted event
+	seq       int   // command sequence for activity, timer, and child ids
+
+	queues   map[*ast.WorkflowDef]string
+	promises map[*ast.PromiseStmt]func() // completions of started promises
+}
+
+func (b *builder) add(typ string, attrs map[string]any) int64 {
+	e := Event{ID: int64(len(b.events) + 1), Time: b.clock, Type: typ, Attributes: attrs}
+	b.events = append(b.events, e)
+	b.clock = b.clock.Add(time.Second)
+	return e.ID
+}
+
+// workflowTask records a workflow task scheduled, started, and completed;
+// commands issued by the workflow follow it.
+func (b *builder) workflowTask() {
+	scheduled := b.add("WorkflowTaskScheduled", map[string]any{
+		"taskQueue":           taskQueueAttr(b.taskQueue),
+		"startToCloseTimeout": "10s",
+		"attempt":             1,
+	})
+	started := b.add("WorkflowTaskStarted", map[string]any{
+		"scheduledEventId": id(scheduled),
+	})
+	b.lastTask = b.add("WorkflowTaskCompleted", map[string]any{
+		"scheduledEventId": id(scheduled),
+		"startedEventId":   id(started),
+	})
+}
+
+func (b *builder) statements(stmts []ast.Statement) flow {
+	for _, s := range stmts {
+		if f := b.statement(s); f != flowNext {
+			return f
+		}
+	}
+	return flowNext
+}
+
+func (b *builder) statement(s ast.Statement) flow {
+	switch s := s.(type) {
+	case *ast.ActivityCall:
+		b.await(b.activity(s.Activity, s.Options))
+	case *ast.WorkflowCall:
+		b.await(b.child(s.Workflow, s.Mode, s.Options))
+	case *ast.NexusCall:
+		b.await(b.nexus(s.Endpoint.Name, s.Service.Name, s.Operation, s.Detach))
+	case *ast.AwaitStmt:
+		b.await(b.target(s.Target))
+	case *ast.PromiseStmt:
+		b.promises[s] = b.target(s.Target)
+	case *ast.AwaitAllBlock:
+		b.awaitAll(s)
+	case *ast.AwaitOneBlock:
+		if len(s.Cases) == 0 {
+			return flowNext
+		}
+		c := s.Cases[0]
+		if c.AwaitAll != nil {
+			b.awaitAll(c.AwaitAll)
+		} else {
+			b.await(b.target(c.Target))
+		}
+		return b.statements(c.Body)
+	case *ast.IfStmt:
+		if v, ok := eval.Truth(s.CondExpr, nil); ok && !v {
+			return b.statements(s.ElseBody)
+		}
+		return b.statements(s.Body)
+	case *ast.SwitchBlock:
+		if len(s.Cases) > 0 {
+			return b.statements(s.Cases[0].Body)
+		}
+		return b.statements(s.Default)
+	case *ast.ForStmt:
+		if f := b.statements(s.Body); f == flowClosed {
+			return f
+		}
+	case *ast.BreakStmt, *ast.ContinueStmt:
+		return flowBreak
+	case *ast.ReturnStmt:
+		b.add("WorkflowExecutionCompleted", map[string]any{
+			"workflowTaskCompletedEventId": id(b.lastTask),
+		})
+		return flowClosed
+	case *ast.CloseStmt:
+		b.close(s)
+		return flowClosed
+	}
+	return flowNext
+}
+
+// await completes an operation and records the workflow task it wakes.
+func (b *builder) await(complete func()) {
+	if complete == nil {
+		return
+	}
+	complete()
+	b.workflowTask()
+}
+
+// awaitAll issues every operation in the block from one workflow task, then
+// completes them in design order and wakes the workflow once.
+func (b *builder) awaitAll(block *ast.AwaitAllBlock) {
+	var pending []func()
+	for _, s := range block.Body {
+		var complete func()
+		switch s := s.(type) {
+		case *ast.ActivityCall:
+			complete = b.activity(s.Activity, s.Options)
+		case *ast.WorkflowCall:
+			complete = b.child(s.Workflow, s.Mode, s.Options)
+		case *ast.NexusCall:
+			complete = b.nexus(s.Endpoint.Name, s.Service.Name, s.Operation, s.Detach)
+		case *ast.AwaitStmt:
+			complete = b.target(s.Target)
+		default:
+			b.statement(s)
+		}
+		if complete != nil {
+			pending = append(pending, complete)
+		}
+	}
+	for _, complete := range pending {
+		complete()
+	}
+	if len(pending) > 0 {
+		b.workflowTask()
+	}
+}
+
+// target issues the commands for an async target and returns a function
+// recording its completion, or nil when nothing is recorded.
+func (b *builder) target(t ast.AsyncTarget) func() {
+	switch t := t.(type) {
+	case *ast.ActivityTarget:
+		return b.activity(t.Activity, nil)
+	case *ast.WorkflowTarget:
+		return b.child(t.Workflow, t.Mode, nil)
+	case *ast.NexusTarget:
+		return b.nexus(t.Endpoint.Name, t.Service.Name, t.Operation, t.Detach)
+	case *ast.TimerTarget:
+		return b.timer(t.Duration)
+	case *ast.SignalTarget:
+		return func() {
+			b.add("WorkflowExecutionSignaled", map[string]any{"signalName": t.Signal.Name})
+		}
+	case *ast.UpdateTarget:
+		return func() {
+			b.seq++
+			updateID := strconv.Itoa(b.seq)
+			accepted := b.add("WorkflowExecutionUpdateAccepted", map[string]any{
+				"protocolInstanceId": updateID,
+				"acceptedRequest": map[string]any{
+					"meta":  map[string]any{"updateId": updateID},
+					"input": map[string]any{"name": t.Update.Name},
+				},
+			})
+			b.add("WorkflowExecutionUpdateCompleted", map[string]any{
+				"meta":            map[string]any{"updateId": updateID},
+				"acceptedEventId": id(accepted),
+				"outcome":         map[string]any{"success": map[string]any{}},
+			})
+		}
+	case *ast.IdentTarget:
+		if p := t.Resolved.Promise; p != nil {
+			complete := b.promises[p]
+			delete(b.promises, p)
+			return complete
+		}
+	}
+	return nil
+}
+
+func (b *builder) activity(ref ast.Ref[*ast.ActivityDef], opts *ast.OptionsBlock) func() {
+	b.seq++
+	attrs := map[string]any{
+		"activityId":                   strconv.Itoa(b.seq),
+		"activityType":                 named(ref.Name),
+		"taskQueue":                    taskQueueAttr(b.optionQueue(opts, b.taskQueue)),
+		"workflowTaskCompletedEventId": id(b.lastTask),
+	}
+	for key, attr := range activityTimeouts {
+		if v := ast.OptionValue(opts, key); v != "" {
+			if d, ok := eval.ParseDuration(v); ok {
+				attrs[attr] = seconds(d)
+			}
+		}
+	}
+	scheduled := b.add("ActivityTaskScheduled", attrs)
+	return func() {
+		started := b.add("ActivityTaskStarted", map[string]any{
+			"scheduledEventId": id(scheduled),
+			"attempt":          1,
+		})
+		b.add("ActivityTaskCompleted", map[string]any{
+			"scheduledEventId": id(scheduled),
+			"startedEventId":   id(started),
+		})
+	}
+}
+
+// activityTimeouts maps activity options to ActivityTaskScheduled fields.
+var activityTimeouts = map[string]string{
+	"start_to_close_timeout":    "startToCloseTimeout",
+	"schedule_to_close_timeout": "scheduleToCloseTimeout",
+	"schedule_to_start_timeout": "scheduleToStartTimeout",
+	"heartbeat_timeout":         "heartbeatTimeout",
+}
+
+// child starts a child workflow. A detached child is abandoned when the
+// parent closes, so only its start is recorded.
+func (b *builder) child(ref ast.Ref[*ast.WorkflowDef], mode ast.WorkflowCallMode, opts *ast.OptionsBlock) func() {
+	b.seq++
+	execution := map[string]any{"workflowId": fmt.Sprintf("%s-%d", ref.Name, b.seq)}
+	queue := b.taskQueue
+	if ref.Resolved != nil {
+		if q := b.queues[ref.Resolved]; q != "" {
+			queue = q
+		}
+	}
+	attrs := map[string]any{
+		"workflowId":                   execution["workflowId"],
+		"workflowType":                 named(ref.Name),
+		"taskQueue":                    taskQueueAttr(b.optionQueue(opts, queue)),
+		"workflowTaskCompletedEventId": id(b.lastTask),
+	}
+	if mode == ast.CallDetach {
+		attrs["parentClosePolicy"] = "PARENT_CLOSE_POLICY_ABANDON"
+	}
+	initiated := b.add("StartChildWorkflowExecutionInitiated", attrs)
+	return func() {
+		started := b.add("ChildWorkflowExecutionStarted", map[string]any{
+			"initiatedEventId":  id(initiated),
+			"workflowExecution": execution,
+			"workflowType":      named(ref.Name),
+		})
+		if mode == ast.CallDetach {
+			return
+		}
+		b.add("ChildWorkflowExecutionCompleted", map[string]any{
+			"initiatedEventId":  id(initiated),
+			"startedEventId":    id(started),
+			"workflowExecution": execution,
+			"workflowType":      named(ref.Name),
+		})
+	}
+}
+
+// nexus schedules a nexus operation. Async operations report a start before
+// completing; a detached call only records its start.
+func (b *builder) nexus(endpoint, service string, op ast.Ref[*ast.NexusOperation], detach bool) func() {
+	b.seq++
+	scheduled := b.add("NexusOperationScheduled", map[string]any{
+		"endpoint":                     endpoint,
+		"service":                      service,
+		"operation":                    op.Name,
+		"workflowTaskCompletedEventId": id(b.lastTask),
+	})
+	async := op.Resolved == nil || op.Resolved.OpType == ast.NexusOpAsync
+	return func() {
+		if async {
+			b.add("NexusOperationStarted", map[string]any{"scheduledEventId": id(scheduled)})
+			if detach {
+				return
+			}
+		}
+		b.add("NexusOperationCompleted", map[string]any{"scheduledEventId": id(scheduled)})
+	}
+}
+
+func (b *builder) timer(duration string) func() {
+	b.seq++
+	timerID := strconv.Itoa(b.seq)
+	d, _ := eval.ParseDuration(duration)
+	started := b.add("TimerStarted", map[string]any{
+		"timerId":                      timerID,
+		"startToFireTimeout":           seconds(d),
+		"workflowTaskCompletedEventId": id(b.lastTask),
+	})
+	return func() {
+		b.clock = b.clock.Add(d)
+		b.add("TimerFired", map[string]any{
+			"timerId":        timerID,
+			"startedEventId": id(started),
+		})
+	}
+}
+
+func (b *builder) close(s *ast.CloseStmt) {
+	completed := id(b.lastTask)
+	switch s.Reason {
+	case ast.CloseFailWorkflow:
+		b.add("WorkflowExecutionFailed", map[string]any{
+			"failure":                      map[string]any{"message": s.Args},
+			"workflowTaskCompletedEventId": completed,
+		})
+	case ast.CloseContinueAsNew:
+		first := b.events[0].Attributes
+		b.add("WorkflowExecutionContinuedAsNew", map[string]any{
+			"workflowType":                 first["workflowType"],
+			"taskQueue":                    first["taskQueue"],
+			"workflowTaskCompletedEventId": completed,
+		})
+	default:
+		b.add("WorkflowExecutionCompleted", map[string]any{
+			"workflowTaskCompletedEventId": completed,
+		})
+	}
+}
+
+// optionQueue returns the task_queue option, or fallback without one.
+func (b *builder) optionQueue(opts *ast.OptionsBlock, fallback string) string {
+	if q := ast.OptionValue(opts, "task_queue"); q != "" {
+		return q
+	}
+	return fallback
+}
+
+func (b *builder) queueOf(wf *ast.WorkflowDef) string {
+	if q := b.queues[wf]; q != "" {
+		return q
+	}
+	return DefaultTaskQueue
+}
+
+// deployedQueues maps each workflow to the task queue of the first
+// namespace worker registering it.
+func deployedQueues(file *ast.File) map[*ast.WorkflowDef]string {
+	queues := make(map[*ast.WorkflowDef]string)
+	for _, def := range file.Definitions {
+		ns, ok := def.(*ast.NamespaceDef)
+		if !ok {
+			continue
+		}
+		for _, nw := range ns.Workers {
+			q := ast.OptionValue(nw.Options, "task_queue")
+			if q == "" || nw.Worker.Resolved == nil {
+				continue
+			}
+			for _, ref := range nw.Worker.Resolved.Workflows {
+				if _, seen := queues[ref.Resolved]; ref.Resolved != nil && !seen {
+					queues[ref.Resolved] = q
+				}
+			}
+		}
+	}
+	return queues
+}
+
+func named(name string) map[string]any {
+	return map[string]any{"name": name}
+}
+
+func taskQueueAttr(name string) map[string]any {
+	return map[string]any{"name": name, "kind": "TASK_QUEUE_KIND_NORMAL"}
+}
+
+// id formats an event id as protojson formats int64 fields.
+func id(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
+
+// seconds formats a duration as protojson formats google.protobuf.Duration.
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "s"
+}
@@ -0,0 +1,167 @@
+package history
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/twftest"
+)
+
+func types(h *History) string {
+	var out []string
+	for _, e := range h.Events {
+		out = append(out, e.Type)
+	}
+	return strings.Join(out, ",")
+}
+
+const task = "WorkflowTaskScheduled,WorkflowTaskStarted,WorkflowTaskCompleted"
+
+func TestSkeletonSequence(t *testing.T) {
+	file := twftest.AssertResolves(t, `workflow Order(id: string):
+    activity Charge(id)
+        options:
+            start_to_close_timeout: 30s
+    await all:
+        activity Reserve(id)
+        workflow Ship(id)
+    await timer(1h)
+
+workflow Ship(id: string):
+    return
+
+activity Charge(id: string):
+    return id
+
+activity Reserve(id: string):
+    return id
+
+worker w:
+    workflow Order
+    workflow Ship
+    activity Charge
+    activity Reserve
+
+namespace prod:
+    worker w
+        options:
+            task_queue: "orders"
+`)
+	h, err := Skeleton(file, "Order", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := strings.Join([]string{
+		"WorkflowExecutionStarted", task,
+		"ActivityTaskScheduled,ActivityTaskStarted,ActivityTaskCompleted", task,
+		"ActivityTaskScheduled,StartChildWorkflowExecutionInitiated",
+		"ActivityTaskStarted,ActivityTaskCompleted",
+		"ChildWorkflowExecutionStarted,ChildWorkflowExecutionCompleted", task,
+		"TimerStarted,TimerFired", task,
+		"WorkflowExecutionCompleted",
+	}, ",")
+	if got := types(h); got != want {
+		t.Fatalf("events:\ngot  %s\nwant %s", got, want)
+	}
+
+	charge := h.Events[4].Attributes
+	if charge["activityType"].(map[string]any)["name"] != "Charge" || charge["startToCloseTimeout"] != "30s" {
+		t.Errorf("Charge scheduled: %v", charge)
+	}
+	if charge["workflowTaskCompletedEventId"] != "4" {
+		t.Errorf("Charge should be scheduled by the first workflow task: %v", charge)
+	}
+	if q := h.Events[0].Attributes["taskQueue"].(map[string]any)["name"]; q != "orders" {
+		t.Errorf("task queue from deployment: %v", q)
+	}
+	fired := h.Events[len(h.Events)-5]
+	if fired.Type != "TimerFired" || fired.Time.Sub(h.Events[len(h.Events)-6].Time).Hours() < 1 {
+		t.Errorf("timer should advance the clock by its duration: %+v", fired)
+	}
+}
+
+func TestSkeletonControlFlow(t *testing.T) {
+	file := twftest.AssertResolves(t, `workflow Loop():
+    signal Stop():
+        return
+    promise p <- activity Slow()
+    if (false):
+        activity Skipped()
+    else:
+        activity Taken()
+    for:
+        activity Tick()
+        break
+        activity Never()
+    await p
+    await one:
+        signal Stop:
+            close fail(stopped)
+        timer(5m):
+            activity Never()
+
+activity Slow():
+    return
+
+activity Skipped():
+    return
+
+activity Taken():
+    return
+
+activity Tick():
+    return
+
+activity Never():
+    return
+`)
+	h, err := Skeleton(file, "Loop", "q")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var scheduled []string
+	for _, e := range h.Events {
+		if e.Type == "ActivityTaskScheduled" {
+			scheduled = append(scheduled, e.Attributes["activityType"].(map[string]any)["name"].(string))
+		}
+	}
+	if got := strings.Join(scheduled, ","); got != "Slow,Taken,Tick" {
+		t.Errorf("scheduled activities: %s", got)
+	}
+
+	// Slow is scheduled when the promise is created but completes at await p.
+	var completions []string
+	for _, e := range h.Events {
+		if e.Type == "ActivityTaskCompleted" {
+			completions = append(completions, e.Attributes["scheduledEventId"].(string))
+		}
+	}
+	if len(completions) != 3 || completions[2] != "5" {
+		t.Errorf("Slow (event 5) should complete last: %v", completions)
+	}
+
+	last := h.Events[len(h.Events)-1]
+	if last.Type != "WorkflowExecutionFailed" || h.Events[len(h.Events)-5].Type != "WorkflowExecutionSignaled" {
+		t.Errorf("expected the signal case to fail the workflow, got %s", types(h))
+	}
+}
+
+func TestEventJSON(t *testing.T) {
+	data, err := json.Marshal(Event{ID: 5, Time: Start, Type: "ActivityTaskScheduled", Attributes: map[string]any{"activityId": "1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"activityTaskScheduledEventAttributes":{"activityId":"1"},"eventId":"5","eventTime":"2024-01-01T00:00:00Z","eventType":"EVENT_TYPE_ACTIVITY_TASK_SCHEDULED"}`
+	if string(data) != want {
+		t.Errorf("got  %s\nwant %s", data, want)
+	}
+}
+
+func TestSkeletonUnknownWorkflow(t *testing.T) {
+	file := twftest.AssertResolves(t, "workflow A():\n    return\n")
+	if _, err := Skeleton(file, "B", ""); err == nil || err.Error() != "no workflow named B" {
+		t.Errorf("got %v", err)
+	}
+}
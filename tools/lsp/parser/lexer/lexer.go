@@ -1,6 +1,10 @@
 package lexer
 
 import (
+	"bytes"
+	"strconv"
+	"strings"
+
 	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/token"
 )
 
@@ -15,20 +19,74 @@ type Lexer struct {
 
 	indentStack []int // stack of indent levels, starts at [0]
 	eofEmitted  bool  // true after first EOF has been emitted
+
+	bracketDepth int  // open '[' / '{' count; newlines inside brackets are whitespace (see bracketCloses)
+	inline       bool // no indentation tracking; all newlines are whitespace
+
+	aliases      token.Aliases // lexed as the keywords they stand for (Options.Aliases)
+	foldKeywords bool          // read keywords in any case (Options.CaseInsensitiveKeywords)
+}
+
+// Options select the vocabulary a Lexer reads beyond the token table. The
+// zero Options read the keywords of the token table only.
+type Options struct {
+	// Aliases are lexed as the keyword sequences they stand for, so
+	// experimental spellings can be trialled without changing the table.
+	Aliases token.Aliases
+	// CaseInsensitiveKeywords reads a keyword spelled in any case, such as
+	// Workflow or IF, as that keyword. With it on, such a word can no
+	// longer name anything, so it is opt-in for authors used to DSLs that
+	// ignore case.
+	CaseInsensitiveKeywords bool
+}
+
+// Key returns a string identifying o, for the keys of cached results that
+// depend on how their input lexes.
+func (o Options) Key() string {
+	var spelled []string
+	for _, name := range o.Aliases.Names() {
+		spelled = append(spelled, name+"="+o.Aliases.Expansion(name))
+	}
+	return strconv.FormatBool(o.CaseInsensitiveKeywords) + " " + strings.Join(spelled, ",")
 }
 
 // New creates a new Lexer for the given input.
 func New(input string) *Lexer {
+	return Options{}.New(input)
+}
+
+// New creates a new Lexer for the given input, reading it with o.
+func (o Options) New(input string) *Lexer {
 	return &Lexer{
-		input:       []byte(input),
-		pos:         0,
-		line:        1,
-		col:         1,
-		atBOL:       true,
-		indentStack: []int{0},
+		input:        []byte(input),
+		pos:          0,
+		line:         1,
+		col:          1,
+		atBOL:        true,
+		indentStack:  []int{0},
+		aliases:      o.Aliases,
+		foldKeywords: o.CaseInsensitiveKeywords,
 	}
 }
 
+// NewInline creates a Lexer for inline content such as the text between a
+// call's parentheses, starting at the given source position so token
+// positions match the enclosing file. Newlines are treated as whitespace and
+// no NEWLINE, INDENT, or DEDENT tokens are produced.
+func NewInline(input string, line, column int) *Lexer {
+	return Options{}.NewInline(input, line, column)
+}
+
+// NewInline is NewInline reading input with o.
+func (o Options) NewInline(input string, line, column int) *Lexer {
+	l := o.New(input)
+	l.line = line
+	l.col = column
+	l.atBOL = false
+	l.inline = true
+	return l
+}
+
 // NextToken returns the next token from the input.
 func (l *Lexer) NextToken() token.Token {
 	for {
@@ -61,6 +119,13 @@ func (l *Lexer) NextToken() token.Token {
 
 		ch := l.input[l.pos]
 
+		if ch == '\n' && (l.inline || l.bracketDepth > 0) {
+			l.advance()
+			l.line++
+			l.col = 1
+			continue
+		}
+
 		var tok token.Token
 		switch {
 		case ch == '\n':
@@ -81,7 +146,11 @@ func (l *Lexer) NextToken() token.Token {
 
 		case ch == ':':
 			tok = l.makeToken(token.COLON, ":")
+			header := l.atDescriptionHeader()
 			l.advance()
+			if header {
+				l.scanDescription()
+			}
 
 		case ch == '-' && l.pos+1 < len(l.input) && l.input[l.pos+1] == '>':
 			tok = l.makeToken(token.ARROW, "->")
@@ -97,6 +166,42 @@ func (l *Lexer) NextToken() token.Token {
 			tok = l.makeToken(token.DOT, ".")
 			l.advance()
 
+		case ch == ',':
+			tok = l.makeToken(token.COMMA, ",")
+			l.advance()
+
+		case ch == '@':
+			tok = l.makeToken(token.AT, "@")
+			l.advance()
+
+		case ch == '[' || ch == '{':
+			tt := token.LBRACKET
+			if ch == '{' {
+				tt = token.LBRACE
+			}
+			if !l.inline && l.bracketDepth == 0 && !l.bracketCloses() {
+				tok = l.makeToken(token.ILLEGAL, "unterminated '"+string(ch)+"'")
+				l.advance()
+				break
+			}
+			tok = l.makeToken(tt, string(ch))
+			l.advance()
+			l.bracketDepth++
+
+		case ch == ']' || ch == '}':
+			tt := token.RBRACKET
+			if ch == '}' {
+				tt = token.RBRACE
+			}
+			tok = l.makeToken(tt, string(ch))
+			l.advance()
+			if l.bracketDepth > 0 {
+				l.bracketDepth--
+			}
+
+		case isOperatorStart(ch):
+			tok = l.scanOperator()
+
 		case isDigit(ch):
 			tok = l.scanNumber()
 
@@ -124,6 +229,20 @@ func (l *Lexer) AllTokens() []token.Token {
 	return tokens
 }
 
+// MiscasedKeywords returns the keyword tokens of input not spelled in
+// lowercase, such as Workflow or IF, lexing it with case-insensitive
+// keywords. Such words are keywords only to lexers with
+// CaseInsensitiveKeywords on; to others they are names.
+func MiscasedKeywords(input string) []token.Token {
+	var out []token.Token
+	for _, tok := range (Options{CaseInsensitiveKeywords: true}).New(input).AllTokens() {
+		if tt, lower, ok := token.LookupKeywordFold(tok.Literal); ok && tt == tok.Type && tok.Literal != lower {
+			out = append(out, tok)
+		}
+	}
+	return out
+}
+
 // handleIndent processes whitespace at the beginning of a line.
 // Returns a token and true if a single token should be returned immediately,
 // or zero-value and false if tokens were queued into pending (or blank line skipped).
@@ -184,6 +303,9 @@ func (l *Lexer) emitEOF() token.Token {
 		return l.makeToken(token.EOF, "")
 	}
 	l.eofEmitted = true
+	if l.inline {
+		return l.makeToken(token.EOF, "")
+	}
 
 	// If the input doesn't end with a newline, emit a synthetic one so
 	// the parser always sees NEWLINE before DEDENT/EOF.
@@ -223,6 +345,132 @@ func (l *Lexer) scanComment() token.Token {
 	return tok
 }
 
+// atDescriptionHeader reports whether the ':' at the current position ends
+// a line reading just "description:", which opens a block of free-form text.
+func (l *Lexer) atDescriptionHeader() bool {
+	if l.inline || l.bracketDepth > 0 {
+		return false
+	}
+	start := bytes.LastIndexByte(l.input[:l.pos], '\n') + 1
+	if string(bytes.TrimLeft(l.input[start:l.pos], " ")) != "description" {
+		return false
+	}
+	end := bytes.IndexByte(l.input[l.pos:], '\n')
+	if end < 0 {
+		end = len(l.input) - l.pos
+	}
+	return len(bytes.TrimSpace(l.input[l.pos+1:l.pos+end])) == 0
+}
+
+// scanDescription queues the lines indented below a description: header as
+// one TEXT token, kept verbatim from the first non-space character to the
+// end of the last non-blank line, and leaves the lexer at the newline ending
+// that line. Nothing is queued when no line is indented below the header,
+// so the parser reports the missing text.
+func (l *Lexer) scanDescription() {
+	lineStart := bytes.LastIndexByte(l.input[:l.pos], '\n') + 1
+	indent := len(l.input[lineStart:]) - len(bytes.TrimLeft(l.input[lineStart:], " "))
+
+	// Find the block: the following lines that are blank or indented
+	// deeper than the header, up to the last non-blank one.
+	next := bytes.IndexByte(l.input[l.pos:], '\n')
+	if next < 0 {
+		return
+	}
+	pos, line := l.pos+next+1, l.line+1
+	first, firstLine, end, endLine := -1, 0, -1, 0
+	for pos < len(l.input) {
+		eol := bytes.IndexByte(l.input[pos:], '\n')
+		if eol < 0 {
+			eol = len(l.input) - pos
+		}
+		text := l.input[pos : pos+eol]
+		trimmed := bytes.TrimLeft(text, " ")
+		if len(bytes.TrimSpace(text)) != 0 {
+			if len(text)-len(trimmed) <= indent {
+				break
+			}
+			if first < 0 {
+				first, firstLine = pos+len(text)-len(trimmed), line
+			}
+			end, endLine = pos+len(bytes.TrimRight(text, " \r")), line
+		}
+		pos += eol + 1
+		line++
+	}
+	if first < 0 {
+		return
+	}
+
+	l.pending = append(l.pending, token.Token{
+		Type:    token.TEXT,
+		Literal: string(l.input[first:end]),
+		Line:    firstLine,
+		Column:  first - bytes.LastIndexByte(l.input[:first], '\n'),
+		Offset:  first,
+	})
+	l.pos = end
+	l.line = endLine
+	l.col = end - bytes.LastIndexByte(l.input[:end], '\n')
+}
+
+// bracketCloses reports whether the '[' or '{' at the current position is
+// closed before the statement holding it ends. A bracket may run over
+// several lines, such as a list in an options block, as long as the lines
+// after the first are indented further than it, or at its indentation
+// start with the closing bracket. A bracket left open, as in
+// "total = items[0", would otherwise join every later line into one.
+func (l *Lexer) bracketCloses() bool {
+	lineStart := bytes.LastIndexByte(l.input[:l.pos], '\n') + 1
+	indent := leadingSpaces(l.input[lineStart:])
+	depth := 0
+	inString := false
+	for i := l.pos; i < len(l.input); i++ {
+		ch := l.input[i]
+		switch {
+		case ch == '\n':
+			inString = false
+			rest := l.input[i+1:]
+			n := leadingSpaces(rest)
+			if n == len(rest) || rest[n] == '\n' || rest[n] == '\r' || rest[n] == '#' {
+				continue // blank or comment line
+			}
+			if n < indent || n == indent && rest[n] != ']' && rest[n] != '}' {
+				return false
+			}
+		case inString:
+			if ch == '\\' {
+				i++
+			} else if ch == '"' {
+				inString = false
+			}
+		case ch == '"':
+			inString = true
+		case ch == '#':
+			for i+1 < len(l.input) && l.input[i+1] != '\n' {
+				i++
+			}
+		case ch == '[' || ch == '{':
+			depth++
+		case ch == ']' || ch == '}':
+			depth--
+			if depth == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// leadingSpaces returns the number of spaces and tabs b starts with.
+func leadingSpaces(b []byte) int {
+	n := 0
+	for n < len(b) && (b[n] == ' ' || b[n] == '\t') {
+		n++
+	}
+	return n
+}
+
 func (l *Lexer) scanArgs() token.Token {
 	tok := l.makeToken(token.ARGS, "")
 	l.advance() // consume '('
@@ -237,11 +485,16 @@ func (l *Lexer) scanArgs() token.Token {
 	tok.Literal = string(l.input[start:l.pos])
 	if l.pos < len(l.input) {
 		l.advance() // consume ')'
+	} else {
+		tok.Unterminated = true
 	}
 	return tok
 }
 
 func (l *Lexer) scanString() token.Token {
+	if l.hasPrefix(`"""`) {
+		return l.scanTripleString()
+	}
 	tok := l.makeToken(token.STRING, "")
 	l.advance() // consume opening '"'
 	start := l.pos
@@ -259,6 +512,35 @@ func (l *Lexer) scanString() token.Token {
 	return tok
 }
 
+// scanTripleString scans a """-delimited string. The content is kept verbatim,
+// including newlines and indentation, and may contain single '"' characters.
+// Opening quotes that nothing closes become an ILLEGAL token, and scanning
+// resumes after them rather than taking the rest of the file as the string.
+func (l *Lexer) scanTripleString() token.Token {
+	tok := l.makeToken(token.STRING, "")
+	for i := 0; i < 3; i++ {
+		l.advance() // consume opening '"""'
+	}
+	if !bytes.Contains(l.input[l.pos:], []byte(`"""`)) {
+		tok.Type, tok.Literal = token.ILLEGAL, "unterminated string"
+		return tok
+	}
+	tok.Triple = true
+	start := l.pos
+	for !l.hasPrefix(`"""`) {
+		if l.input[l.pos] == '\n' {
+			l.line++
+			l.col = 0
+		}
+		l.advance()
+	}
+	tok.Literal = string(l.input[start:l.pos])
+	for i := 0; i < 3; i++ {
+		l.advance() // consume closing '"""'
+	}
+	return tok
+}
+
 func (l *Lexer) scanIdentifier() token.Token {
 	tok := l.makeToken(token.IDENT, "")
 	start := l.pos
@@ -268,6 +550,19 @@ func (l *Lexer) scanIdentifier() token.Token {
 	literal := string(l.input[start:l.pos])
 	tok.Literal = literal
 	tok.Type = token.LookupIdent(literal)
+	if tok.Type == token.IDENT && l.foldKeywords {
+		if tt, _, ok := token.LookupKeywordFold(literal); ok {
+			tok.Type = tt
+		}
+	}
+	if expansion, ok := l.aliases[literal]; ok && tok.Type == token.IDENT {
+		// An alias lexes as its first keyword. The rest follow at the same
+		// position with no text, so they cover none of the source.
+		tok.Type = expansion[0]
+		for _, tt := range expansion[1:] {
+			l.pending = append(l.pending, token.Token{Type: tt, Line: tok.Line, Column: tok.Column, Offset: tok.Offset})
+		}
+	}
 	return tok
 }
 
@@ -301,6 +596,30 @@ func (l *Lexer) scanNumber() token.Token {
 	return tok
 }
 
+// twoCharOperators lists operators that take precedence over their
+// single-character prefixes.
+var twoCharOperators = []string{"==", "!=", "<=", ">=", "&&", "||"}
+
+func (l *Lexer) scanOperator() token.Token {
+	tok := l.makeToken(token.OPERATOR, "")
+	for _, op := range twoCharOperators {
+		if l.hasPrefix(op) {
+			l.advance()
+			l.advance()
+			tok.Literal = op
+			return tok
+		}
+	}
+	ch := l.input[l.pos]
+	if ch == '&' || ch == '|' {
+		// A lone & or | is not an operator.
+		return l.scanRawText()
+	}
+	l.advance()
+	tok.Literal = string(ch)
+	return tok
+}
+
 func (l *Lexer) scanRawText() token.Token {
 	tok := l.makeToken(token.RAW_TEXT, "")
 	start := l.pos
@@ -315,6 +634,7 @@ func (l *Lexer) makeToken(tt token.TokenType, literal string) token.Token {
 		Literal: literal,
 		Line:    l.line,
 		Column:  l.col,
+		Offset:  l.pos,
 	}
 }
 
@@ -323,8 +643,15 @@ func (l *Lexer) advance() {
 	l.col++
 }
 
+// hasPrefix reports whether the unread input starts with prefix.
+func (l *Lexer) hasPrefix(prefix string) bool {
+	return bytes.HasPrefix(l.input[l.pos:], []byte(prefix))
+}
+
+// skipSpaces skips spaces, and in inline content, where there is no
+// indentation for a tab to confuse, tabs too.
 func (l *Lexer) skipSpaces() {
-	for l.pos < len(l.input) && l.input[l.pos] == ' ' {
+	for l.pos < len(l.input) && (l.input[l.pos] == ' ' || l.inline && l.input[l.pos] == '\t') {
 		l.advance()
 	}
 }
@@ -337,6 +664,14 @@ func isIdentContinue(ch byte) bool {
 	return isIdentStart(ch) || (ch >= '0' && ch <= '9')
 }
 
+func isOperatorStart(ch byte) bool {
+	switch ch {
+	case '=', '!', '<', '>', '+', '-', '*', '/', '%', '&', '|':
+		return true
+	}
+	return false
+}
+
 func isDigit(ch byte) bool {
 	return ch >= '0' && ch <= '9'
 }
@@ -1,6 +1,7 @@
 package lexer
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/token"
@@ -279,14 +280,39 @@ func TestWorkflowHeaderTokenStream(t *testing.T) {
 }
 
 func TestRawText(t *testing.T) {
-	input := "= +"
+	input := "; @"
 	l := New(input)
 	tok := l.NextToken()
 	if tok.Type != token.RAW_TEXT {
 		t.Fatalf("expected RAW_TEXT, got %s", tok.Type)
 	}
-	if tok.Literal != "=" {
-		t.Fatalf("expected '=', got %q", tok.Literal)
+	if tok.Literal != ";" {
+		t.Fatalf("expected ';', got %q", tok.Literal)
+	}
+	if tok = l.NextToken(); tok.Type != token.AT {
+		t.Fatalf("expected AT, got %s", tok.Type)
+	}
+}
+
+func TestOperators(t *testing.T) {
+	input := "== != <= >= && || < > + - * / % ! = -> <- & |"
+	expected := []struct {
+		tt  token.TokenType
+		lit string
+	}{
+		{token.OPERATOR, "=="}, {token.OPERATOR, "!="}, {token.OPERATOR, "<="}, {token.OPERATOR, ">="},
+		{token.OPERATOR, "&&"}, {token.OPERATOR, "||"}, {token.OPERATOR, "<"}, {token.OPERATOR, ">"},
+		{token.OPERATOR, "+"}, {token.OPERATOR, "-"}, {token.OPERATOR, "*"}, {token.OPERATOR, "/"},
+		{token.OPERATOR, "%"}, {token.OPERATOR, "!"}, {token.OPERATOR, "="},
+		{token.ARROW, "->"}, {token.LEFT_ARROW, "<-"},
+		{token.RAW_TEXT, "&"}, {token.RAW_TEXT, "|"},
+	}
+	l := New(input)
+	for i, exp := range expected {
+		tok := l.NextToken()
+		if tok.Type != exp.tt || tok.Literal != exp.lit {
+			t.Fatalf("token[%d]: expected %s(%q), got %s(%q)", i, exp.tt, exp.lit, tok.Type, tok.Literal)
+		}
 	}
 }
 
@@ -400,6 +426,27 @@ func TestNumberToken(t *testing.T) {
 	}
 }
 
+func TestBoolToken(t *testing.T) {
+	tests := []struct {
+		input string
+		want  token.TokenType
+	}{
+		{"true", token.BOOL},
+		{"false", token.BOOL},
+		{"True", token.IDENT},
+		{"trueish", token.IDENT},
+	}
+	for _, tt := range tests {
+		tok := New(tt.input).NextToken()
+		if tok.Type != tt.want {
+			t.Errorf("input %q: expected %s, got %s", tt.input, tt.want, tok.Type)
+		}
+		if tok.Literal != tt.input {
+			t.Errorf("input %q: expected literal %q, got %q", tt.input, tt.input, tok.Literal)
+		}
+	}
+}
+
 func TestEmitEOFIdempotent(t *testing.T) {
 	input := "a:\n    b"
 	l := New(input)
@@ -580,3 +627,296 @@ func TestOptionsBlockTokenStream(t *testing.T) {
 		}
 	}
 }
+
+func TestTripleQuotedString(t *testing.T) {
+	input := "\"\"\"first \"line\"\n  second\"\"\" next\n"
+	l := New(input)
+	tok := l.NextToken()
+	if tok.Type != token.STRING {
+		t.Fatalf("expected STRING, got %s", tok.Type)
+	}
+	if !tok.Triple {
+		t.Error("expected Triple to be set")
+	}
+	if tok.Literal != "first \"line\"\n  second" {
+		t.Fatalf("expected verbatim content, got %q", tok.Literal)
+	}
+	if tok.Line != 1 || tok.Column != 1 {
+		t.Errorf("expected string at 1:1, got %d:%d", tok.Line, tok.Column)
+	}
+	tok = l.NextToken()
+	if tok.Type != token.IDENT || tok.Literal != "next" {
+		t.Fatalf("expected IDENT 'next', got %s (%q)", tok.Type, tok.Literal)
+	}
+	if tok.Line != 2 || tok.Column != 13 {
+		t.Errorf("expected 'next' at 2:13, got %d:%d", tok.Line, tok.Column)
+	}
+}
+
+func TestTripleQuotedStringSuppressesIndent(t *testing.T) {
+	input := "a:\n    \"\"\"x\ny\n        z\"\"\"\nb\n"
+	expected := []token.TokenType{
+		token.IDENT, token.COLON, token.NEWLINE,
+		token.INDENT, token.STRING, token.NEWLINE,
+		token.DEDENT, token.IDENT, token.NEWLINE,
+		token.EOF,
+	}
+	l := New(input)
+	for i, exp := range expected {
+		tok := l.NextToken()
+		if tok.Type != exp {
+			t.Fatalf("token[%d]: expected %s, got %s (%q)", i, exp, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestDescriptionText(t *testing.T) {
+	input := "a:\n    description:  \n      Don't (lex) \"this\".\n\n        Nor this.\n    b\n"
+	expected := []token.TokenType{
+		token.IDENT, token.COLON, token.NEWLINE,
+		token.INDENT, token.IDENT, token.COLON, token.TEXT, token.NEWLINE,
+		token.IDENT, token.NEWLINE,
+		token.DEDENT, token.EOF,
+	}
+	l := New(input)
+	for i, exp := range expected {
+		tok := l.NextToken()
+		if tok.Type != exp {
+			t.Fatalf("token[%d]: expected %s, got %s (%q)", i, exp, tok.Type, tok.Literal)
+		}
+		if tok.Type == token.TEXT {
+			if tok.Literal != "Don't (lex) \"this\".\n\n        Nor this." {
+				t.Errorf("expected the block verbatim, got %q", tok.Literal)
+			}
+			if tok.Line != 3 || tok.Column != 7 {
+				t.Errorf("expected text at 3:7, got %d:%d", tok.Line, tok.Column)
+			}
+		}
+		if tok.Type == token.IDENT && tok.Literal == "b" && (tok.Line != 6 || tok.Column != 5) {
+			t.Errorf("expected 'b' at 6:5, got %d:%d", tok.Line, tok.Column)
+		}
+	}
+
+	// With text after the colon, description is an ordinary name.
+	l = New("description: string\n")
+	for _, exp := range []token.TokenType{token.IDENT, token.COLON, token.IDENT} {
+		if tok := l.NextToken(); tok.Type != exp {
+			t.Fatalf("expected %s, got %s (%q)", exp, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestUnclosedTripleQuotedString(t *testing.T) {
+	// The opening quotes become an ILLEGAL token and the lines after them
+	// are scanned as usual, rather than swallowed into the string.
+	input := "x: \"\"\"hello\nworld"
+	l := New(input)
+	expected := []struct {
+		tt   token.TokenType
+		lit  string
+		line int
+		col  int
+	}{
+		{token.IDENT, "x", 1, 1},
+		{token.COLON, ":", 1, 2},
+		{token.ILLEGAL, "unterminated string", 1, 4},
+		{token.IDENT, "hello", 1, 7},
+		{token.NEWLINE, "", 1, 12},
+		{token.IDENT, "world", 2, 1},
+	}
+	for _, exp := range expected {
+		tok := l.NextToken()
+		if tok.Type != exp.tt || tok.Literal != exp.lit || tok.Line != exp.line || tok.Column != exp.col {
+			t.Fatalf("expected %s %q at %d:%d, got %s %q at %d:%d", exp.tt, exp.lit, exp.line, exp.col, tok.Type, tok.Literal, tok.Line, tok.Column)
+		}
+	}
+}
+
+func TestEmptyStringIsNotTriple(t *testing.T) {
+	input := `"" x`
+	l := New(input)
+	tok := l.NextToken()
+	if tok.Type != token.STRING || tok.Triple || tok.Literal != "" {
+		t.Fatalf("expected empty STRING, got %s (%q, triple=%v)", tok.Type, tok.Literal, tok.Triple)
+	}
+}
+
+func TestBracketTokens(t *testing.T) {
+	input := `[a, {k: 1}]`
+	expected := []token.TokenType{
+		token.LBRACKET, token.IDENT, token.COMMA,
+		token.LBRACE, token.IDENT, token.COLON, token.NUMBER, token.RBRACE,
+		token.RBRACKET, token.NEWLINE, token.EOF,
+	}
+	l := New(input)
+	for i, exp := range expected {
+		tok := l.NextToken()
+		if tok.Type != exp {
+			t.Fatalf("token[%d]: expected %s, got %s (%q)", i, exp, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestNewlinesInsideBrackets(t *testing.T) {
+	input := "a:\n    k: [\n        \"x\",\n      \"y\",\n    ]\n    b\n"
+	expected := []token.TokenType{
+		token.IDENT, token.COLON, token.NEWLINE,
+		token.INDENT, token.IDENT, token.COLON, token.LBRACKET,
+		token.STRING, token.COMMA, token.STRING, token.COMMA,
+		token.RBRACKET, token.NEWLINE,
+		token.IDENT, token.NEWLINE,
+		token.DEDENT, token.EOF,
+	}
+	l := New(input)
+	for i, exp := range expected {
+		tok := l.NextToken()
+		if tok.Type != exp {
+			t.Fatalf("token[%d]: expected %s, got %s (%q)", i, exp, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestUnclosedBracket(t *testing.T) {
+	// The bracket becomes an ILLEGAL token, and the lines after it keep
+	// their newlines and indentation instead of joining the statement.
+	input := "workflow A():\n    total = items[0\n    x\n\nworkflow B():\n    y\n"
+	l := New(input)
+	expected := []struct {
+		tt   token.TokenType
+		lit  string
+		line int
+	}{
+		{token.WORKFLOW, "workflow", 1},
+		{token.IDENT, "A", 1},
+		{token.ARGS, "", 1},
+		{token.COLON, ":", 1},
+		{token.NEWLINE, "", 1},
+		{token.INDENT, "", 2},
+		{token.IDENT, "total", 2},
+		{token.OPERATOR, "=", 2},
+		{token.IDENT, "items", 2},
+		{token.ILLEGAL, "unterminated '['", 2},
+		{token.NUMBER, "0", 2},
+		{token.NEWLINE, "", 2},
+		{token.IDENT, "x", 3},
+		{token.NEWLINE, "", 3},
+		{token.DEDENT, "", 5},
+		{token.WORKFLOW, "workflow", 5},
+		{token.IDENT, "B", 5},
+	}
+	for _, exp := range expected {
+		tok := l.NextToken()
+		if tok.Type != exp.tt || tok.Literal != exp.lit || tok.Line != exp.line {
+			t.Fatalf("expected %s %q on line %d, got %s %q on line %d", exp.tt, exp.lit, exp.line, tok.Type, tok.Literal, tok.Line)
+		}
+	}
+}
+
+func TestBracketClosedAtItsIndentation(t *testing.T) {
+	for _, input := range []string{
+		"k: [\n    1,\n]\n",
+		"k: {a: [1,\n        2]}\n",
+		"k: [\n    \"]\",  # ]\n    2,\n]\n",
+	} {
+		for _, tok := range New(input).AllTokens() {
+			if tok.Type == token.ILLEGAL {
+				t.Errorf("%q: unexpected %q at %d:%d", input, tok.Literal, tok.Line, tok.Column)
+			}
+		}
+	}
+}
+
+func TestInlineLexer(t *testing.T) {
+	l := NewInline("a,\n  b", 4, 10)
+	tokens := l.AllTokens()
+	expected := []token.Token{
+		{Type: token.IDENT, Literal: "a", Line: 4, Column: 10, Offset: 0},
+		{Type: token.COMMA, Literal: ",", Line: 4, Column: 11, Offset: 1},
+		{Type: token.IDENT, Literal: "b", Line: 5, Column: 3, Offset: 5},
+		{Type: token.EOF, Line: 5, Column: 4, Offset: 6},
+	}
+	if len(tokens) != len(expected) {
+		t.Fatalf("expected %d tokens, got %d: %v", len(expected), len(tokens), tokens)
+	}
+	for i, exp := range expected {
+		if tokens[i] != exp {
+			t.Errorf("token[%d]: expected %v, got %v", i, exp, tokens[i])
+		}
+	}
+}
+
+func TestTokenOffsets(t *testing.T) {
+	input := "workflow W():\n    activity  A( \tx,\n  y)\n"
+	var args []token.Token
+	for _, tok := range New(input).AllTokens() {
+		if tok.Type == token.ARGS {
+			args = append(args, tok)
+		}
+	}
+	if len(args) != 2 {
+		t.Fatalf("expected 2 ARGS tokens, got %d", len(args))
+	}
+	for _, tok := range args {
+		if input[tok.Offset] != '(' {
+			t.Errorf("ARGS offset %d points at %q, want '('", tok.Offset, input[tok.Offset])
+		}
+		if !strings.HasPrefix(input[tok.Offset+1:], tok.Literal) {
+			t.Errorf("text after ARGS offset %d does not start with %q", tok.Offset, tok.Literal)
+		}
+	}
+	if tok := args[1]; tok.Line != 2 || tok.Column != 16 {
+		t.Errorf("ARGS at %d:%d, want 2:16", tok.Line, tok.Column)
+	}
+}
+
+func TestAliases(t *testing.T) {
+	aliases, err := token.ParseAliases([]byte(`{"sleep": "timer", "race": "await one"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := Options{Aliases: aliases}.New("sleep race sleeper")
+	expected := []token.Token{
+		{Type: token.TIMER, Literal: "sleep", Line: 1, Column: 1},
+		{Type: token.AWAIT, Literal: "race", Line: 1, Column: 7},
+		{Type: token.ONE, Literal: "", Line: 1, Column: 7},
+		{Type: token.IDENT, Literal: "sleeper", Line: 1, Column: 12},
+	}
+	for i, exp := range expected {
+		tok := l.NextToken()
+		if tok.Type != exp.Type || tok.Literal != exp.Literal || tok.Line != exp.Line || tok.Column != exp.Column {
+			t.Fatalf("token[%d]: expected %s %q at %d:%d, got %s %q at %d:%d", i,
+				exp.Type, exp.Literal, exp.Line, exp.Column, tok.Type, tok.Literal, tok.Line, tok.Column)
+		}
+	}
+
+	for _, bad := range []string{
+		`{"timer": "await"}`,
+		`{"two words": "await"}`,
+		`{"race": ""}`,
+		`{"race": "await quickly"}`,
+	} {
+		if _, err := token.ParseAliases([]byte(bad)); err == nil {
+			t.Errorf("ParseAliases(%s): expected an error", bad)
+		}
+	}
+}
+
+func TestCaseInsensitiveKeywords(t *testing.T) {
+	src := "Workflow Order():\n    IF (x):\n        close complete\n"
+	if toks := New(src).AllTokens(); toks[0].Type != token.IDENT {
+		t.Fatalf("Workflow lexed as %s with case-insensitive keywords off", toks[0].Type)
+	}
+
+	toks := Options{CaseInsensitiveKeywords: true}.New(src).AllTokens()
+	if toks[0].Type != token.WORKFLOW || toks[0].Literal != "Workflow" {
+		t.Errorf("token[0]: expected WORKFLOW \"Workflow\", got %s", toks[0])
+	}
+	var got []string
+	for _, tok := range MiscasedKeywords(src) {
+		got = append(got, tok.String())
+	}
+	want := `WORKFLOW("Workflow")@1:1 IF("IF")@2:5`
+	if strings.Join(got, " ") != want {
+		t.Errorf("MiscasedKeywords:\n got %s\nwant %s", strings.Join(got, " "), want)
+	}
+}
@@ -0,0 +1,151 @@
+package options
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/eval"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/parser"
+)
+
+// Config holds the workspace's option defaults for each kind of call, the
+// lowest layer of the effective options.
+type Config struct {
+	Activity []*ast.OptionEntry
+	Workflow []*ast.OptionEntry
+	Nexus    []*ast.OptionEntry
+}
+
+func (c *Config) defaults(kind string) []*ast.OptionEntry {
+	switch kind {
+	case KindActivity:
+		return c.Activity
+	case KindWorkflow:
+		return c.Workflow
+	case KindNexus:
+		return c.Nexus
+	}
+	return nil
+}
+
+var configContexts = map[string]parser.OptionsContext{
+	KindActivity: parser.OptionsContextActivity,
+	KindWorkflow: parser.OptionsContextWorkflow,
+	KindNexus:    parser.OptionsContextNexusCall,
+}
+
+// ParseConfig parses option defaults from JSON: an object with a section
+// for each kind of call, holding options as the options block of such a
+// call would, with nested blocks as objects:
+//
+//	{"activity": {"start_to_close_timeout": "30s", "retry_policy": {"maximum_attempts": 3}}}
+//
+// Durations, strings, and enum values are JSON strings. Keys and value
+// types are checked against the options the kind of call accepts. Entries
+// are kept in key order.
+func ParseConfig(data []byte) (*Config, error) {
+	var raw map[string]map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("option defaults: %w", err)
+	}
+	c := &Config{}
+	for _, kind := range slices.Sorted(maps.Keys(raw)) {
+		ctx, ok := configContexts[kind]
+		if !ok {
+			return nil, fmt.Errorf("option defaults: unknown section %q; want %s, %s, or %s", kind, KindActivity, KindWorkflow, KindNexus)
+		}
+		entries, err := configEntries(ctx, raw[kind], nil)
+		if err != nil {
+			return nil, fmt.Errorf("option defaults: %s: %w", kind, err)
+		}
+		switch kind {
+		case KindActivity:
+			c.Activity = entries
+		case KindWorkflow:
+			c.Workflow = entries
+		case KindNexus:
+			c.Nexus = entries
+		}
+	}
+	return c, nil
+}
+
+// LoadConfig reads option defaults from the JSON file at path.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := ParseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// configEntries converts the options of obj, found at path below the
+// section, to entries.
+func configEntries(ctx parser.OptionsContext, obj map[string]any, path []string) ([]*ast.OptionEntry, error) {
+	entries := []*ast.OptionEntry{}
+	for _, key := range slices.Sorted(maps.Keys(obj)) {
+		at := append(path[:len(path):len(path)], key)
+		valueType, ok := parser.OptionType(ctx, at...)
+		if !ok {
+			return nil, fmt.Errorf("unknown option key: %s", dotted(at))
+		}
+		e := &ast.OptionEntry{Key: key, ValueType: valueType}
+		v := obj[key]
+		switch valueType {
+		case "nested":
+			nested, ok := v.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("option %s expects an object", dotted(at))
+			}
+			var err error
+			if e.Nested, err = configEntries(ctx, nested, at); err != nil {
+				return nil, err
+			}
+			e.ValueType = ""
+		case "string", "enum", "duration":
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("option %s expects a %s string", dotted(at), valueType)
+			}
+			if _, ok := eval.ParseDuration(s); valueType == "duration" && !ok {
+				return nil, fmt.Errorf("option %s: %q is not a duration", dotted(at), s)
+			}
+			e.Value = s
+		case "number":
+			n, ok := v.(float64)
+			if !ok {
+				return nil, fmt.Errorf("option %s expects a number", dotted(at))
+			}
+			e.Value = strconv.FormatFloat(n, 'f', -1, 64)
+		case "bool":
+			b, ok := v.(bool)
+			if !ok {
+				return nil, fmt.Errorf("option %s expects a bool", dotted(at))
+			}
+			e.Value = strconv.FormatBool(b)
+		default:
+			// Lists and maps keep their JSON text as the value.
+			text, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			e.Value = string(text)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func dotted(path []string) string {
+	return strings.Join(path, ".")
+}
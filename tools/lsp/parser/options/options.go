@@ -0,0 +1,175 @@
+// Package options computes the options a call runs with. Three layers set
+// them, each overriding the one below key by key:
+//
+//   - the call: the options block written below the call;
+//   - the definition: the options block heading the called workflow or
+//     activity;
+//   - the config: workspace defaults for each kind of call, read from an
+//     option defaults file.
+//
+// Nested blocks such as retry_policy merge the same way, so a call setting
+// only retry_policy.maximum_attempts keeps the definition's
+// retry_policy.initial_interval. Hover, lint rules, and codegen all read
+// options through Resolve, so they agree on what a call runs with.
+package options
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+)
+
+// Layer names where an effective option was set.
+type Layer string
+
+const (
+	LayerCall       Layer = "call"
+	LayerDefinition Layer = "definition"
+	LayerConfig     Layer = "config"
+)
+
+// Option is one effective option and the layer that set it. A nested
+// block's layer is the highest of the layers setting its entries.
+type Option struct {
+	Key       string
+	Value     string // literal for flat options
+	ValueType string // as ast.OptionEntry.ValueType; "nested" for blocks
+	Nested    []Option
+	Layer     Layer
+	// Line and Column are where the option is written; zero for options
+	// from the config.
+	Line, Column int
+}
+
+// Effective are the options a call runs with, in the order their keys
+// first appear from the config layer up.
+type Effective []Option
+
+// Lookup returns the option at a dotted path such as
+// "retry_policy.maximum_attempts".
+func (e Effective) Lookup(path string) (Option, bool) {
+	opts := []Option(e)
+	keys := strings.Split(path, ".")
+	for i, key := range keys {
+		j := slices.IndexFunc(opts, func(o Option) bool { return o.Key == key })
+		if j < 0 {
+			return Option{}, false
+		}
+		if i == len(keys)-1 {
+			return opts[j], true
+		}
+		opts = opts[j].Nested
+	}
+	return Option{}, false
+}
+
+// Get returns the value of the option at a dotted path, or "".
+func (e Effective) Get(path string) string {
+	o, _ := e.Lookup(path)
+	return o.Value
+}
+
+// Has reports whether any of the dotted paths is set.
+func (e Effective) Has(paths ...string) bool {
+	return slices.ContainsFunc(paths, func(p string) bool {
+		_, ok := e.Lookup(p)
+		return ok
+	})
+}
+
+// Resolve returns the effective options of call, a statement calling def,
+// under config. Either of call and def may be nil, as def is for a call
+// whose target did not resolve; with no call it returns
+// the options every call of def starts from. config may be nil.
+//
+// The targets of await and promise statements set no options, so for them
+// pass a nil call and the definition they call.
+func Resolve(def ast.Definition, call ast.Node, config *Config) Effective {
+	var out []Option
+	kind := kindOf(def, call)
+	if config != nil {
+		out = merge(out, config.defaults(kind), LayerConfig)
+	}
+	switch d := def.(type) {
+	case *ast.ActivityDef:
+		if d != nil {
+			out = merge(out, entries(d.Options), LayerDefinition)
+		}
+	case *ast.WorkflowDef:
+		if d != nil {
+			out = merge(out, entries(d.Options), LayerDefinition)
+		}
+	}
+	out = merge(out, entries(callOptions(call)), LayerCall)
+	return Effective(out)
+}
+
+// Kinds of call, naming the sections of an option defaults file.
+const (
+	KindActivity = "activity"
+	KindWorkflow = "workflow"
+	KindNexus    = "nexus"
+)
+
+// kindOf returns the kind of call a call or definition is, or "".
+func kindOf(def ast.Definition, call ast.Node) string {
+	switch call.(type) {
+	case *ast.ActivityCall:
+		return KindActivity
+	case *ast.WorkflowCall:
+		return KindWorkflow
+	case *ast.NexusCall:
+		return KindNexus
+	}
+	switch def.(type) {
+	case *ast.ActivityDef:
+		return KindActivity
+	case *ast.WorkflowDef:
+		return KindWorkflow
+	}
+	return ""
+}
+
+func callOptions(call ast.Node) *ast.OptionsBlock {
+	switch c := call.(type) {
+	case *ast.ActivityCall:
+		return c.Options
+	case *ast.WorkflowCall:
+		return c.Options
+	case *ast.NexusCall:
+		return c.Options
+	}
+	return nil
+}
+
+func entries(ob *ast.OptionsBlock) []*ast.OptionEntry {
+	if ob == nil {
+		return nil
+	}
+	return ob.Entries
+}
+
+// merge returns base with entries set over it at layer: a flat entry
+// replaces the option of its key, and a nested one merges into it.
+func merge(base []Option, entries []*ast.OptionEntry, layer Layer) []Option {
+	out := slices.Clone(base)
+	for _, e := range entries {
+		o := Option{Key: e.Key, Value: e.Value, ValueType: e.ValueType, Layer: layer, Line: e.Line, Column: e.Column}
+		i := slices.IndexFunc(out, func(b Option) bool { return b.Key == e.Key })
+		if e.Nested != nil {
+			var prev []Option
+			if i >= 0 && out[i].ValueType == "nested" {
+				prev = out[i].Nested
+			}
+			o.ValueType = "nested"
+			o.Nested = merge(prev, e.Nested, layer)
+		}
+		if i >= 0 {
+			out[i] = o
+		} else {
+			out = append(out, o)
+		}
+	}
+	return out
+}
@@ -0,0 +1,115 @@
+package options_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/options"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/twftest"
+)
+
+const design = `activity Charge(order: Order):
+    options:
+        start_to_close_timeout: 30s
+        retry_policy:
+            initial_interval: 1s
+            maximum_attempts: 5
+    return
+
+workflow Order(order: Order):
+    activity Charge(order)
+        options:
+            start_to_close_timeout: 10s
+            retry_policy:
+                maximum_attempts: 3
+    activity Charge(order)
+    close complete
+`
+
+func TestResolve(t *testing.T) {
+	file := twftest.AssertResolves(t, design)
+	charge := file.Definitions[0].(*ast.ActivityDef)
+	body := file.Definitions[1].(*ast.WorkflowDef).Body
+	config, err := options.ParseConfig([]byte(`{"activity": {"heartbeat_timeout": "5s", "start_to_close_timeout": "1m", "retry_policy": {"backoff_coefficient": 2}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name string
+		call ast.Node
+		want map[string]string // path to "value layer"
+	}{
+		{"configured call", body[0], map[string]string{
+			"start_to_close_timeout":           "10s call",
+			"heartbeat_timeout":                "5s config",
+			"retry_policy.maximum_attempts":    "3 call",
+			"retry_policy.initial_interval":    "1s definition",
+			"retry_policy.backoff_coefficient": "2 config",
+		}},
+		{"plain call", body[1], map[string]string{
+			"start_to_close_timeout":        "30s definition",
+			"retry_policy.maximum_attempts": "5 definition",
+		}},
+		{"no call", nil, map[string]string{
+			"start_to_close_timeout": "30s definition",
+			"heartbeat_timeout":      "5s config",
+		}},
+	} {
+		eff := options.Resolve(charge, tt.call, config)
+		for path, want := range tt.want {
+			o, ok := eff.Lookup(path)
+			if got := o.Value + " " + string(o.Layer); !ok || got != want {
+				t.Errorf("%s: %s is %q, want %q", tt.name, path, got, want)
+			}
+		}
+	}
+
+	if eff := options.Resolve(charge, body[0], config); eff[0].Key != "heartbeat_timeout" || len(eff) != 3 {
+		t.Errorf("expected config keys first and no duplicates, got %+v", eff)
+	}
+	if eff := options.Resolve((*ast.ActivityDef)(nil), body[1], nil); len(eff) != 0 {
+		t.Errorf("expected no options for an unresolved call, got %+v", eff)
+	}
+	if !options.Resolve(charge, nil, nil).Has("schedule_to_close_timeout", "start_to_close_timeout") {
+		t.Error("expected Has to report any of its paths")
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	c, err := options.ParseConfig([]byte(`{
+		"workflow": {"workflow_run_timeout": "24h", "parent_close_policy": "ABANDON"},
+		"nexus": {"schedule_to_close_timeout": "2m"},
+		"activity": {"request_eager_execution": true, "retry_policy": {"non_retryable_error_types": ["Invalid"]}}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Workflow) != 2 || c.Workflow[0].Key != "parent_close_policy" || c.Workflow[1].ValueType != "duration" {
+		t.Errorf("unexpected workflow defaults: %+v", c.Workflow)
+	}
+	if len(c.Nexus) != 1 || c.Nexus[0].Value != "2m" {
+		t.Errorf("unexpected nexus defaults: %+v", c.Nexus)
+	}
+	if got := c.Activity[1].Nested[0]; got.Value != `["Invalid"]` || got.ValueType != "list" {
+		t.Errorf("unexpected list default: %+v", got)
+	}
+
+	for _, tt := range []struct {
+		input, wantErr string
+	}{
+		{`[]`, "option defaults: json"},
+		{`{"worker": {}}`, `unknown section "worker"`},
+		{`{"activity": {"task_queues": "a"}}`, "activity: unknown option key: task_queues"},
+		{`{"activity": {"retry_policy": {"max": 1}}}`, "unknown option key: retry_policy.max"},
+		{`{"activity": {"start_to_close_timeout": 30}}`, "start_to_close_timeout expects a duration string"},
+		{`{"activity": {"start_to_close_timeout": "soon"}}`, `"soon" is not a duration`},
+		{`{"activity": {"retry_policy": 3}}`, "retry_policy expects an object"},
+		{`{"activity": {"retry_policy": {"maximum_attempts": "3"}}}`, "retry_policy.maximum_attempts expects a number"},
+	} {
+		if _, err := options.ParseConfig([]byte(tt.input)); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: expected error %q, got %v", tt.input, tt.wantErr, err)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/token"
+)
+
+// LoadAliases reads the keyword alias table in the JSON file at path (see
+// token.ParseAliases), for the Lexer options of later parses.
+func LoadAliases(path string) (token.Aliases, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	aliases, err := token.ParseAliases(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return aliases, nil
+}
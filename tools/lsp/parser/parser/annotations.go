@@ -0,0 +1,53 @@
+package parser
+
+import (
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/token"
+)
+
+// parseAnnotatedDef parses annotations and the definition they precede:
+// { AT IDENT [ ARGS ] { AT IDENT [ ARGS ] } NEWLINE } ( workflow_def | activity_def )
+// Blank lines and comments may separate the annotations from the definition.
+func parseAnnotatedDef(p *Parser) (ast.Definition, error) {
+	var anns []*ast.Annotation
+	for p.current.Type == token.AT {
+		pos := ast.Pos{Line: p.current.Line, Column: p.current.Column}
+		p.advance() // consume AT
+
+		name, err := p.expect(token.IDENT)
+		if err != nil {
+			return nil, err
+		}
+		ann := &ast.Annotation{Pos: pos, Name: name.Literal}
+		if p.current.Type == token.ARGS {
+			ann.Args = p.current.Literal
+			p.advance()
+		}
+		anns = append(anns, ann)
+
+		if p.current.Type != token.AT {
+			if _, err := p.expect(token.NEWLINE); err != nil {
+				return nil, err
+			}
+			p.skipBlankLinesAndComments()
+		}
+	}
+
+	switch p.current.Type {
+	case token.WORKFLOW:
+		def, err := parseWorkflowDef(p)
+		if err != nil {
+			return nil, err
+		}
+		def.(*ast.WorkflowDef).Annotations = anns
+		return def, nil
+	case token.ACTIVITY:
+		def, err := parseActivityDef(p)
+		if err != nil {
+			return nil, err
+		}
+		def.(*ast.ActivityDef).Annotations = anns
+		return def, nil
+	}
+	return nil, p.errorf("annotations must precede a workflow or activity definition, got %s", p.current.Type)
+}
@@ -0,0 +1,54 @@
+package parser
+
+import (
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/token"
+)
+
+// parseConstDef parses:
+// CONST IDENT '=' ( STRING | DURATION | NUMBER | BOOL ) NEWLINE
+func parseConstDef(p *Parser) (ast.Definition, error) {
+	pos := ast.Pos{Line: p.current.Line, Column: p.current.Column}
+	p.advance() // consume CONST
+
+	name, err := p.expect(token.IDENT)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.current.Type != token.OPERATOR || p.current.Literal != "=" {
+		return nil, p.errorf("expected = after constant name %s, got %s", name.Literal, p.current.Type)
+	}
+	p.advance() // consume '='
+
+	valuePos := ast.Pos{Line: p.current.Line, Column: p.current.Column}
+	switch p.current.Type {
+	case token.STRING, token.DURATION, token.NUMBER, token.BOOL:
+	default:
+		return nil, &ParseError{
+			Msg:    "constant " + name.Literal + " must be a string, duration, number, or bool literal",
+			Line:   valuePos.Line,
+			Column: valuePos.Column,
+		}
+	}
+	value := p.current.Literal
+	expr, err := p.parsePrimaryExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.current.Type != token.NEWLINE && p.current.Type != token.EOF {
+		return nil, p.errorf("unexpected %s after constant value", p.current.Type)
+	}
+	if p.current.Type == token.NEWLINE {
+		p.advance()
+	}
+
+	return &ast.ConstDef{
+		Pos:       pos,
+		Name:      name.Literal,
+		Value:     value,
+		ValueType: exprValueType(expr),
+		ValueExpr: expr,
+	}, nil
+}
@@ -1,13 +1,15 @@
 package parser
 
 import (
+	"strings"
+
 	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
 	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/token"
 )
 
 // parseWorkflowDef parses:
 // WORKFLOW IDENT ARGS [ ARROW ARGS ] COLON NEWLINE
-// INDENT { signal_def | query_def | update_def } workflow_body DEDENT
+// INDENT [ description ] [ state_block ] { signal_def | query_def | update_def } workflow_body DEDENT
 func parseWorkflowDef(p *Parser) (ast.Definition, error) {
 	pos := ast.Pos{Line: p.current.Line, Column: p.current.Column}
 	p.advance() // consume WORKFLOW
@@ -36,6 +38,23 @@ func parseWorkflowDef(p *Parser) (ast.Definition, error) {
 		return nil, err
 	}
 
+	// Optional description (must come first).
+	p.skipBlankLinesAndComments()
+	var description string
+	if isDescriptionHeader(p) {
+		description, err = parseDescription(p)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Optional options: defaults for calls starting this workflow.
+	p.skipBlankLinesAndComments()
+	options, err := parseDefinitionOptions(p, OptionsContextWorkflow)
+	if err != nil {
+		return nil, err
+	}
+
 	// Optional state block (must come before handlers and body).
 	p.skipBlankLinesAndComments()
 	var stateBlock *ast.StateBlock
@@ -87,21 +106,70 @@ declLoop:
 	}
 
 	return &ast.WorkflowDef{
-		Pos:        pos,
-		Name:       name.Literal,
-		Params:     params.Literal,
-		ReturnType: returnType,
-		State:      stateBlock,
-		Signals:    signals,
-		Queries:    queries,
-		Updates:    updates,
-		Body:       body,
+		Pos:         pos,
+		Name:        name.Literal,
+		Params:      params.Literal,
+		ReturnType:  returnType,
+		Description: description,
+		Options:     options,
+		State:       stateBlock,
+		Signals:     signals,
+		Queries:     queries,
+		Updates:     updates,
+		Body:        body,
 	}, nil
 }
 
+// isDescriptionHeader reports whether the parser is at "description:".
+// description is not a keyword, so it stays usable as a name elsewhere.
+func isDescriptionHeader(p *Parser) bool {
+	return p.current.Type == token.IDENT && p.current.Literal == "description" && p.peek.Type == token.COLON
+}
+
+// parseDescription parses: IDENT("description") COLON TEXT NEWLINE
+// The lexer reads the lines indented below the header as one TEXT token;
+// the indentation of its first line is removed from every line.
+func parseDescription(p *Parser) (string, error) {
+	p.advance() // consume "description"
+	p.advance() // consume COLON
+	if p.current.Type != token.TEXT {
+		return "", p.errorf("expected indented text under description:")
+	}
+	text := p.current
+	p.advance()
+	if p.current.Type == token.NEWLINE {
+		p.advance()
+	}
+
+	indent := strings.Repeat(" ", text.Column-1)
+	lines := strings.Split(text.Literal, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(strings.TrimPrefix(line, indent), " \r")
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// parseDefinitionOptions parses the options block a workflow or activity
+// definition may open with: OPTIONS COLON NEWLINE INDENT entries DEDENT.
+// It returns nil when the parser is not at one.
+func parseDefinitionOptions(p *Parser, ctx OptionsContext) (*ast.OptionsBlock, error) {
+	if p.current.Type != token.OPTIONS || p.peek.Type != token.COLON {
+		return nil, nil
+	}
+	p.advance() // consume OPTIONS
+	opts, err := p.parseOptionsBlock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if p.current.Type == token.NEWLINE {
+		p.advance()
+	}
+	return opts, nil
+}
+
 // parseActivityDef parses:
 // ACTIVITY IDENT ARGS [ ARROW ARGS ] COLON NEWLINE
-// INDENT activity_body DEDENT
+// INDENT [ options_block ] activity_body DEDENT
 func parseActivityDef(p *Parser) (ast.Definition, error) {
 	pos := ast.Pos{Line: p.current.Line, Column: p.current.Column}
 	p.advance() // consume ACTIVITY
@@ -130,6 +198,12 @@ func parseActivityDef(p *Parser) (ast.Definition, error) {
 		return nil, err
 	}
 
+	// Optional options: defaults for calls to this activity.
+	options, err := parseDefinitionOptions(p, OptionsContextActivity)
+	if err != nil {
+		return nil, err
+	}
+
 	body, err := p.parseBodyAs(bodyActivity)
 	if err != nil {
 		return nil, err
@@ -140,6 +214,7 @@ func parseActivityDef(p *Parser) (ast.Definition, error) {
 		Name:       name.Literal,
 		Params:     params.Literal,
 		ReturnType: returnType,
+		Options:    options,
 		Body:       body,
 	}, nil
 }
@@ -0,0 +1,45 @@
+package parser
+
+import (
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/token"
+)
+
+// parseEnumDef parses:
+// ENUM IDENT COLON IDENT { COMMA IDENT } NEWLINE
+func parseEnumDef(p *Parser) (ast.Definition, error) {
+	pos := ast.Pos{Line: p.current.Line, Column: p.current.Column}
+	p.advance() // consume ENUM
+
+	name, err := p.expect(token.IDENT)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(token.COLON); err != nil {
+		return nil, err
+	}
+
+	def := &ast.EnumDef{Pos: pos, Name: name.Literal}
+	for {
+		if p.current.Type != token.IDENT {
+			return nil, p.errorf("expected value name in enum %s, got %s", name.Literal, p.current.Type)
+		}
+		def.Values = append(def.Values, &ast.EnumValue{
+			Pos:  ast.Pos{Line: p.current.Line, Column: p.current.Column},
+			Name: p.current.Literal,
+		})
+		p.advance()
+		if p.current.Type != token.COMMA {
+			break
+		}
+		p.advance() // consume ','
+	}
+
+	if p.current.Type != token.NEWLINE && p.current.Type != token.EOF {
+		return nil, p.errorf("unexpected %s after enum values", p.current.Type)
+	}
+	if p.current.Type == token.NEWLINE {
+		p.advance()
+	}
+	return def, nil
+}
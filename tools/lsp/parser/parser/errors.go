@@ -0,0 +1,219 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/internal/editdistance"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/token"
+)
+
+// topLevelTokens start the definitions allowed at the top level, in the
+// order errors list them.
+var topLevelTokens = []token.TokenType{
+	token.WORKFLOW, token.ACTIVITY, token.WORKER, token.NAMESPACE,
+	token.NEXUS, token.CONST, token.ENUM, token.AT,
+}
+
+// parenExamples show the parenthesized form expected after a token, for
+// the errors of writing it without.
+var parenExamples = map[token.TokenType]string{
+	token.IF:     "if (ready):",
+	token.ELIF:   "elif (ready):",
+	token.FOR:    "for (item in items):",
+	token.SWITCH: "switch (status):",
+	token.TIMER:  "timer(5m)",
+	token.ARROW:  "-> (Result)",
+}
+
+// spell returns how tt is written in source, for listing expected tokens.
+func spell(tt token.TokenType) string {
+	switch tt {
+	case token.COLON:
+		return "':'"
+	case token.ARROW:
+		return "'->'"
+	case token.LEFT_ARROW:
+		return "'<-'"
+	case token.COMMA:
+		return "','"
+	case token.AT:
+		return "'@'"
+	case token.ARGS:
+		return "'(...)'"
+	case token.NEWLINE:
+		return "end of line"
+	case token.INDENT:
+		return "an indented block"
+	case token.DEDENT:
+		return "end of block"
+	case token.EOF:
+		return "end of file"
+	case token.IDENT:
+		return "a name"
+	case token.STRING:
+		return "a string"
+	case token.NUMBER:
+		return "a number"
+	case token.DURATION:
+		return "a duration"
+	}
+	if _, lower, ok := token.LookupKeywordFold(tt.String()); ok {
+		return lower
+	}
+	return tt.String()
+}
+
+// describe returns how to name tok in an error: its text, or what it
+// stands for when it has none.
+func describe(tok token.Token) string {
+	switch tok.Type {
+	case token.NEWLINE, token.INDENT, token.DEDENT, token.EOF:
+		return spell(tok.Type)
+	case token.IDENT:
+		return fmt.Sprintf("name %q", tok.Literal)
+	case token.STRING:
+		return "a string"
+	case token.ARGS:
+		return "'(" + tok.Literal + ")'"
+	}
+	return fmt.Sprintf("'%s'", tok.Literal)
+}
+
+// spellAll lists the spellings of tts, as ParseError.Expected holds them.
+func spellAll(tts []token.TokenType) []string {
+	out := make([]string, len(tts))
+	for i, tt := range tts {
+		out[i] = spell(tt)
+	}
+	return out
+}
+
+// orList joins words as "a, b, or c".
+func orList(words []string) string {
+	switch len(words) {
+	case 0:
+		return ""
+	case 1:
+		return words[0]
+	case 2:
+		return words[0] + " or " + words[1]
+	}
+	return strings.Join(words[:len(words)-1], ", ") + ", or " + words[len(words)-1]
+}
+
+// unexpected returns the error for the current token where one of want
+// was needed. Common mistakes, such as a missing ':' or '()', get a
+// message saying how to fix them; others list what was expected.
+func (p *Parser) unexpected(want ...token.TokenType) error {
+	err := &ParseError{
+		Line:     p.current.Line,
+		Column:   p.current.Column,
+		Expected: spellAll(want),
+	}
+	if msg, ok := p.layoutError(); ok {
+		err.Msg = msg
+		return err
+	}
+	if len(want) > 0 {
+		if msg, ok := p.commonMistake(want[0]); ok {
+			err.Msg = msg
+			return err
+		}
+	}
+	err.Msg = fmt.Sprintf("expected %s, got %s", orList(err.Expected), describe(p.current))
+	return err
+}
+
+// commonMistake returns a message for the current token where want, the
+// first of the tokens expected, was needed, when the pair is a common
+// mistake.
+func (p *Parser) commonMistake(want token.TokenType) (string, bool) {
+	cur, prev := p.current, p.prev
+	switch want {
+	case token.COLON:
+		switch {
+		case cur.Type == token.NEWLINE || cur.Type == token.EOF || cur.Type == token.COMMENT:
+			return "missing ':' at the end of the line; a line opening a block ends with ':'", true
+		case cur.Type == token.OPERATOR && cur.Literal == "=" && prev.Type == token.IDENT:
+			return fmt.Sprintf("expected ':' after %s, got '='; write %s: value", prev.Literal, prev.Literal), true
+		}
+	case token.ARGS:
+		if cur.Type == token.ARGS && cur.Unterminated {
+			return "unclosed '(': no ')' matches it before the end of the file", true
+		}
+		if example, ok := parenExamples[prev.Type]; ok {
+			return fmt.Sprintf("missing '(' after %s; write %s", prev.Literal, example), true
+		}
+		if prev.Type == token.IDENT && (cur.Type == token.COLON || cur.Type == token.NEWLINE || cur.Type == token.EOF) {
+			return fmt.Sprintf("missing '()' after %s; write %s() even when there is nothing to pass", prev.Literal, prev.Literal), true
+		}
+	case token.INDENT:
+		return "expected an indented block: the lines under a line ending in ':' must be indented further than it", true
+	}
+	return "", false
+}
+
+// layoutError returns a message for the current token when it is
+// misplaced by indentation rather than by grammar, or when the lexer could
+// not make a token of it at all.
+func (p *Parser) layoutError() (string, bool) {
+	cur := p.current
+	switch {
+	case cur.Type == token.ILLEGAL && cur.Literal == "inconsistent indentation":
+		return fmt.Sprintf("inconsistent indentation: %d spaces matches the indentation of no enclosing block", cur.Column-1), true
+	case cur.Type == token.ILLEGAL && cur.Literal == "unterminated string":
+		return `unterminated string: no closing """ matches this """ before the end of the file`, true
+	case cur.Type == token.ILLEGAL && (cur.Literal == "unterminated '['" || cur.Literal == "unterminated '{'"):
+		closer := map[string]string{"unterminated '['": "]", "unterminated '{'": "}"}[cur.Literal]
+		return fmt.Sprintf("%s: no '%s' closes it before the statement ends; lines continuing it must be indented further", cur.Literal, closer), true
+	case cur.Type == token.RAW_TEXT && strings.HasPrefix(cur.Literal, "\t"):
+		return "indentation uses a tab; indent with spaces", true
+	case cur.Type == token.INDENT:
+		return "unexpected indentation: this line is indented further than the one above, which does not open a block; if it should, end that line with ':'", true
+	}
+	return "", false
+}
+
+// unexpectedTopLevel returns the error for the current token where a
+// definition should start.
+func (p *Parser) unexpectedTopLevel() error {
+	err := &ParseError{
+		Line:     p.current.Line,
+		Column:   p.current.Column,
+		Expected: spellAll(topLevelTokens),
+	}
+	cur := p.current
+	if msg, ok := p.layoutError(); ok {
+		err.Msg = msg
+		return err
+	}
+	if cur.Type == token.IDENT {
+		if _, lower, ok := token.LookupKeywordFold(cur.Literal); ok {
+			err.Msg = fmt.Sprintf("%s is not a keyword; keywords are lowercase, so write %s", cur.Literal, lower)
+			return err
+		}
+		if kw, ok := nearestKeyword(cur.Literal, topLevelTokens); ok {
+			err.Msg = fmt.Sprintf("unknown keyword %s at top level; did you mean %s?", cur.Literal, kw)
+			return err
+		}
+	}
+	if cur.Column > 1 {
+		err.Msg = fmt.Sprintf("unexpected %s at top level: it is indented as if in a block, but the block ended above; check the indentation of the lines before it", describe(cur))
+		return err
+	}
+	err.Msg = fmt.Sprintf("unexpected token %s at top level; expected %s", cur.Type, orList(err.Expected))
+	return err
+}
+
+// nearestKeyword returns the keyword of tts within two edits of word.
+func nearestKeyword(word string, tts []token.TokenType) (string, bool) {
+	best, bestDist := "", 3
+	for _, tt := range tts {
+		kw := spell(tt)
+		if d := editdistance.Distance(strings.ToLower(word), kw); d < bestDist {
+			best, bestDist = kw, d
+		}
+	}
+	return best, best != ""
+}
@@ -0,0 +1,80 @@
+package parser
+
+import (
+	"slices"
+	"testing"
+)
+
+// TestCommonMistakes pins the messages for the mistakes users make most,
+// which should say how to fix the source rather than name tokens.
+func TestCommonMistakes(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"missing colon after header", "workflow A()\n    close complete\n",
+			"parse error at 1:13: missing ':' at the end of the line; a line opening a block ends with ':'"},
+		{"missing colon after if", "workflow A():\n    if (x)\n        close complete\n",
+			"parse error at 2:11: missing ':' at the end of the line; a line opening a block ends with ':'"},
+		{"missing parens in header", "workflow A:\n    close complete\n",
+			"parse error at 1:11: missing '()' after A; write A() even when there is nothing to pass"},
+		{"missing parens on call", "workflow A():\n    activity Charge\n    close complete\n",
+			"parse error at 2:20: missing '()' after Charge; write Charge() even when there is nothing to pass"},
+		{"condition without parens", "workflow A():\n    if ready:\n        close complete\n",
+			"parse error at 2:8: missing '(' after if; write if (ready):"},
+		{"loop without parens", "workflow A():\n    for item in items:\n        close complete\n",
+			"parse error at 2:9: missing '(' after for; write for (item in items):"},
+		{"equals in options", "workflow A():\n    activity Charge()\n        options:\n            start_to_close_timeout = 5m\n",
+			"parse error at 4:36: expected ':' after start_to_close_timeout, got '='; write start_to_close_timeout: value"},
+		{"unclosed parenthesis", "workflow A():\n    activity Charge(order\n    close complete\n",
+			"parse error at 2:20: unclosed '(': no ')' matches it before the end of the file"},
+		{"block not indented", "workflow A():\nclose complete\n",
+			"parse error at 2:1: expected an indented block: the lines under a line ending in ':' must be indented further than it"},
+		{"over-indented line", "workflow A():\n    activity Charge()\n      activity Ship()\n",
+			"parse error at 3:7: unexpected indentation: this line is indented further than the one above, which does not open a block; if it should, end that line with ':'"},
+		{"inconsistent dedent", "workflow A():\n    activity Charge()\n   close complete\n",
+			"parse error at 3:4: inconsistent indentation: 3 spaces matches the indentation of no enclosing block"},
+		{"unterminated triple-quoted string", "workflow A():\n    workflow Child()\n        options:\n            cron_schedule: \"\"\"abc\n\nworkflow B():\n    close complete\n",
+			`parse error at 4:28: unterminated string: no closing """ matches this """ before the end of the file`},
+		{"unclosed bracket in a raw statement", "workflow A():\n    total = items[0\n    close complete\n\nworkflow B():\n    close complete\n",
+			"parse error at 2:18: unterminated '[': no ']' closes it before the statement ends; lines continuing it must be indented further"},
+		{"unclosed brace in options", "workflow A():\n    workflow Child()\n        options:\n            memo: {team: \"p\"\n    close complete\n",
+			"parse error at 4:19: unterminated '{': no '}' closes it before the statement ends; lines continuing it must be indented further"},
+		{"tab indentation", "workflow A():\n\tclose complete\n",
+			"parse error at 2:1: indentation uses a tab; indent with spaces"},
+		{"misspelled keyword", "workflw A():\n    close complete\n",
+			"parse error at 1:1: unknown keyword workflw at top level; did you mean workflow?"},
+		{"capitalized keyword", "Workflow A():\n    close complete\n",
+			"parse error at 1:1: Workflow is not a keyword; keywords are lowercase, so write workflow"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseFile(tt.input)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("got  %v\nwant %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrorExpected(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"workflow A()\n    close complete\n", []string{"':'"}},
+		{"workflow A():\n    close\n", []string{"complete", "fail", "continue_as_new"}},
+		{"42\n", []string{"workflow", "activity", "worker", "namespace", "nexus", "const", "enum", "'@'"}},
+	}
+	for _, tt := range tests {
+		_, errs := ParseFileAll(tt.input)
+		if len(errs) == 0 || !slices.Equal(errs[0].Expected, tt.want) {
+			t.Errorf("%q: expected %q, got %v", tt.input, tt.want, errs)
+		}
+	}
+	_, errs := ParseFileAll("workflow A():\n    close\n")
+	if want := "expected complete, fail, or continue_as_new, got end of line"; errs[0].Msg != want {
+		t.Errorf("got %q, want %q", errs[0].Msg, want)
+	}
+}
@@ -0,0 +1,289 @@
+package parser
+
+import (
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/lexer"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/token"
+)
+
+// ParseExpr parses a single expression from inline source text.
+// Positions in the result are relative to line 1, column 1 of input.
+func ParseExpr(input string) (ast.Expr, error) {
+	p := newInlineParser(input, 1, 1, DefaultLimits, lexer.Options{})
+	expr, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if p.current.Type != token.EOF {
+		return nil, p.errorf("unexpected %s after expression", p.current.Type)
+	}
+	return expr, nil
+}
+
+// newInlineParser creates a Parser over inline content that starts at the
+// given source position (e.g. the text between a call's parentheses),
+// lexing it with lex.
+func newInlineParser(input string, line, column int, limits Limits, lex lexer.Options) *Parser {
+	p := &Parser{lex: lex.NewInline(input, line, column), limits: limits, lexOpts: lex}
+	p.advance() // fill current
+	p.advance() // fill peek
+	return p
+}
+
+// argsPos returns the position of the content of an ARGS token, which
+// begins one column after the opening paren.
+func argsPos(args token.Token) ast.Pos {
+	return ast.Pos{Line: args.Line, Column: args.Column + 1}
+}
+
+// parseArgExprs parses the content of an ARGS token as a comma-separated
+// expression list. Argument text is free-form in the grammar, so this is
+// best-effort: nil is returned for empty args or content that is not a
+// plain expression list, leaving the opaque Args string as the only form.
+func (p *Parser) parseArgExprs(args token.Token) []ast.Expr {
+	start := argsPos(args)
+	ip := newInlineParser(args.Literal, start.Line, start.Column, p.limits, p.lexOpts)
+	if ip.current.Type == token.EOF {
+		return nil
+	}
+	exprs, err := ip.parseExprList(token.EOF)
+	if err != nil {
+		return nil
+	}
+	return exprs
+}
+
+// parseArgsExpr parses the content of an ARGS token as a single expression,
+// such as an if or for condition. Like parseArgExprs it is best-effort and
+// returns nil when the content is not a plain expression.
+func (p *Parser) parseArgsExpr(args token.Token) ast.Expr {
+	// Content begins one column after the opening paren.
+	return p.parseInlineExpr(args.Literal, args.Line, args.Column+1)
+}
+
+// parseInlineExpr parses text that starts at the given source position as a
+// single expression, returning nil when it is empty or not a plain expression.
+func (p *Parser) parseInlineExpr(text string, line, column int) ast.Expr {
+	if text == "" {
+		return nil
+	}
+	ip := newInlineParser(text, line, column, p.limits, p.lexOpts)
+	x, err := ip.parseExpr()
+	if err != nil || ip.current.Type != token.EOF {
+		return nil
+	}
+	return x
+}
+
+// binaryPrecedence maps binary operators to their binding power.
+// Higher binds tighter. Logical operators accept word and symbol spellings.
+var binaryPrecedence = map[string]int{
+	"or": 1, "||": 1,
+	"and": 2, "&&": 2,
+	"==": 3, "!=": 3, "<": 3, "<=": 3, ">": 3, ">=": 3,
+	"+": 4, "-": 4,
+	"*": 5, "/": 5, "%": 5,
+}
+
+// currentBinaryOp returns the binary operator at the current token, if any.
+func (p *Parser) currentBinaryOp() (string, bool) {
+	switch p.current.Type {
+	case token.OPERATOR:
+		if _, ok := binaryPrecedence[p.current.Literal]; ok {
+			return p.current.Literal, true
+		}
+	case token.IDENT:
+		if p.current.Literal == "and" || p.current.Literal == "or" {
+			return p.current.Literal, true
+		}
+	}
+	return "", false
+}
+
+// parseExpr parses a full expression using precedence climbing.
+func (p *Parser) parseExpr() (ast.Expr, error) {
+	return p.parseBinaryExpr(1)
+}
+
+// parseBinaryExpr parses: unary { op unary } for operators binding at least minPrec.
+func (p *Parser) parseBinaryExpr(minPrec int) (ast.Expr, error) {
+	x, err := p.parseUnaryExpr()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.currentBinaryOp()
+		if !ok || binaryPrecedence[op] < minPrec {
+			return x, nil
+		}
+		p.advance()
+		y, err := p.parseBinaryExpr(binaryPrecedence[op] + 1)
+		if err != nil {
+			return nil, err
+		}
+		x = &ast.BinaryExpr{
+			Pos: ast.Pos{Line: x.NodeLine(), Column: x.NodeColumn()},
+			Op:  op,
+			X:   x,
+			Y:   y,
+		}
+	}
+}
+
+// parseUnaryExpr parses: [ NOT | '!' | '-' ] unary | postfix
+// Every nested expression passes through it, so it bounds their depth.
+func (p *Parser) parseUnaryExpr() (ast.Expr, error) {
+	p.exprDepth++
+	defer func() { p.exprDepth-- }()
+	if p.limits.MaxDepth > 0 && p.exprDepth > p.limits.MaxDepth {
+		return nil, p.errorf("expression nested more than %d levels deep", p.limits.MaxDepth)
+	}
+	isUnary := (p.current.Type == token.OPERATOR && (p.current.Literal == "!" || p.current.Literal == "-")) ||
+		(p.current.Type == token.IDENT && p.current.Literal == "not")
+	if !isUnary {
+		return p.parsePostfixExpr()
+	}
+	pos := ast.Pos{Line: p.current.Line, Column: p.current.Column}
+	op := p.current.Literal
+	p.advance()
+	x, err := p.parseUnaryExpr()
+	if err != nil {
+		return nil, err
+	}
+	return &ast.UnaryExpr{Pos: pos, Op: op, X: x}, nil
+}
+
+// parsePostfixExpr parses: primary { DOT IDENT }
+func (p *Parser) parsePostfixExpr() (ast.Expr, error) {
+	x, err := p.parsePrimaryExpr()
+	if err != nil {
+		return nil, err
+	}
+	for p.current.Type == token.DOT {
+		p.advance()
+		sel, err := p.expect(token.IDENT)
+		if err != nil {
+			return nil, err
+		}
+		x = &ast.SelectorExpr{
+			Pos: ast.Pos{Line: x.NodeLine(), Column: x.NodeColumn()},
+			X:   x,
+			Sel: sel.Literal,
+		}
+	}
+	return x, nil
+}
+
+// parsePrimaryExpr parses an identifier, literal, list, or map. An
+// identifier followed by a map is a typed literal.
+func (p *Parser) parsePrimaryExpr() (ast.Expr, error) {
+	tok := p.current
+	pos := ast.Pos{Line: tok.Line, Column: tok.Column}
+	switch tok.Type {
+	case token.IDENT:
+		p.advance()
+		if p.current.Type == token.LBRACE {
+			x, err := p.parseMapLit()
+			if err != nil {
+				return nil, err
+			}
+			m := x.(*ast.MapLit)
+			m.Pos, m.Type = pos, tok.Literal
+			return m, nil
+		}
+		return &ast.Ident{Pos: pos, Name: tok.Literal}, nil
+	case token.STRING:
+		p.advance()
+		return &ast.StringLit{Pos: pos, Value: tok.Literal}, nil
+	case token.NUMBER:
+		p.advance()
+		return &ast.NumberLit{Pos: pos, Value: tok.Literal}, nil
+	case token.DURATION:
+		p.advance()
+		return &ast.DurationLit{Pos: pos, Value: tok.Literal}, nil
+	case token.BOOL:
+		p.advance()
+		return &ast.BoolLit{Pos: pos, Value: tok.Literal == "true"}, nil
+	case token.LBRACKET:
+		return p.parseListLit()
+	case token.LBRACE:
+		return p.parseMapLit()
+	default:
+		return nil, p.errorf("expected expression, got %s (%q)", tok.Type, tok.Literal)
+	}
+}
+
+// parseListLit parses: LBRACKET [ expr { COMMA expr } [ COMMA ] ] RBRACKET
+func (p *Parser) parseListLit() (ast.Expr, error) {
+	pos := ast.Pos{Line: p.current.Line, Column: p.current.Column}
+	p.advance() // consume '['
+	elems, err := p.parseExprList(token.RBRACKET)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(token.RBRACKET); err != nil {
+		return nil, err
+	}
+	return &ast.ListLit{Pos: pos, Elems: elems}, nil
+}
+
+// parseMapLit parses: LBRACE [ entry { COMMA entry } [ COMMA ] ] RBRACE
+// where entry is (IDENT | STRING) COLON expr.
+func (p *Parser) parseMapLit() (ast.Expr, error) {
+	pos := ast.Pos{Line: p.current.Line, Column: p.current.Column}
+	p.advance() // consume '{'
+	m := &ast.MapLit{Pos: pos}
+	seen := make(map[string]bool)
+	for p.current.Type != token.RBRACE {
+		if p.current.Type != token.IDENT && p.current.Type != token.STRING {
+			return nil, p.errorf("expected map key, got %s (%q)", p.current.Type, p.current.Literal)
+		}
+		key := p.current
+		if seen[key.Literal] {
+			return nil, p.errorf("duplicate map key %q", key.Literal)
+		}
+		seen[key.Literal] = true
+		p.advance()
+		if _, err := p.expect(token.COLON); err != nil {
+			return nil, err
+		}
+		value, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		m.Entries = append(m.Entries, &ast.MapEntry{
+			KeyPos: ast.Pos{Line: key.Line, Column: key.Column},
+			Key:    key.Literal,
+			Value:  value,
+		})
+		if p.current.Type != token.COMMA {
+			break
+		}
+		p.advance()
+	}
+	if _, err := p.expect(token.RBRACE); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// parseExprList parses comma-separated expressions up to (but not including)
+// the closing token. A trailing comma is allowed.
+func (p *Parser) parseExprList(closing token.TokenType) ([]ast.Expr, error) {
+	var exprs []ast.Expr
+	for p.current.Type != closing {
+		x, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, x)
+		if p.current.Type != token.COMMA {
+			if p.current.Type != closing {
+				return nil, p.errorf("expected ',' or %s, got %s (%q)", closing, p.current.Type, p.current.Literal)
+			}
+			break
+		}
+		p.advance()
+	}
+	return exprs, nil
+}
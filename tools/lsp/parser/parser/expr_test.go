@@ -0,0 +1,318 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+)
+
+func TestParseExprLiterals(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`order`, `order`},
+		{`order.items.first`, `order.items.first`},
+		{`"text"`, `"text"`},
+		{`2.5`, `2.5`},
+		{`30s`, `30s`},
+		{`true`, `true`},
+		{`[a, "b", 3]`, `[a, "b", 3]`},
+		{`[a, b,]`, `[a, b]`},
+		{`{key: value, "other key": [1, 2]}`, `{key: value, "other key": [1, 2]}`},
+		{"[\n  a,\n  b\n]", `[a, b]`},
+		{`OrderResult{status: "done", id: order.id}`, `OrderResult{status: "done", id: order.id}`},
+		{`Empty{}`, `Empty{}`},
+		{`retries < 3`, `retries < 3`},
+		{`not approved`, `not approved`},
+		{`!approved && -x >= 2`, `!approved && -x >= 2`},
+	}
+	for _, tt := range tests {
+		x, err := ParseExpr(tt.input)
+		if err != nil {
+			t.Errorf("ParseExpr(%q): unexpected error: %v", tt.input, err)
+			continue
+		}
+		if got := ast.ExprString(x); got != tt.want {
+			t.Errorf("ParseExpr(%q): expected %q, got %q", tt.input, tt.want, got)
+		}
+	}
+}
+
+func TestParseExprPrecedence(t *testing.T) {
+	x, err := ParseExpr("a or b and c == 1 + 2 * 3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	or, ok := x.(*ast.BinaryExpr)
+	if !ok || or.Op != "or" {
+		t.Fatalf("expected top-level 'or', got %#v", x)
+	}
+	and, ok := or.Y.(*ast.BinaryExpr)
+	if !ok || and.Op != "and" {
+		t.Fatalf("expected 'and' on right of 'or', got %#v", or.Y)
+	}
+	eq, ok := and.Y.(*ast.BinaryExpr)
+	if !ok || eq.Op != "==" {
+		t.Fatalf("expected '==' on right of 'and', got %#v", and.Y)
+	}
+	add, ok := eq.Y.(*ast.BinaryExpr)
+	if !ok || add.Op != "+" {
+		t.Fatalf("expected '+' on right of '==', got %#v", eq.Y)
+	}
+	if mul, ok := add.Y.(*ast.BinaryExpr); !ok || mul.Op != "*" {
+		t.Fatalf("expected '*' on right of '+', got %#v", add.Y)
+	}
+}
+
+func TestParseExprErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		msg   string
+	}{
+		{`[a b]`, "expected ',' or RBRACKET"},
+		{`{a: 1, a: 2}`, "duplicate map key"},
+		{`{1: a}`, "expected map key"},
+		{`a b`, "unexpected IDENT after expression"},
+		{`[a`, "expected ',' or RBRACKET"},
+	}
+	for _, tt := range tests {
+		_, err := ParseExpr(tt.input)
+		if err == nil {
+			t.Errorf("ParseExpr(%q): expected error", tt.input)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.msg) {
+			t.Errorf("ParseExpr(%q): expected error containing %q, got %q", tt.input, tt.msg, err.Error())
+		}
+	}
+}
+
+func TestCallArgExprs(t *testing.T) {
+	input := `workflow Foo(x: int) -> (Result):
+    activity Notify(order.email, {subject: "hi", tags: ["a", "b"]})
+    activity Compute(items[0]) -> y
+`
+	file, err := ParseFile(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wf := file.Definitions[0].(*ast.WorkflowDef)
+
+	call := wf.Body[0].(*ast.ActivityCall)
+	if len(call.ArgExprs) != 2 {
+		t.Fatalf("expected 2 arg exprs, got %d", len(call.ArgExprs))
+	}
+	sel, ok := call.ArgExprs[0].(*ast.SelectorExpr)
+	if !ok || sel.Sel != "email" {
+		t.Errorf("expected selector .email, got %#v", call.ArgExprs[0])
+	}
+	m, ok := call.ArgExprs[1].(*ast.MapLit)
+	if !ok {
+		t.Fatalf("expected map literal, got %T", call.ArgExprs[1])
+	}
+	if len(m.Entries) != 2 || m.Entries[1].Key != "tags" {
+		t.Fatalf("unexpected map entries: %+v", m.Entries)
+	}
+	// Positions map back to the source file.
+	if m.Line != 2 || m.Column != 34 {
+		t.Errorf("expected map at 2:34, got %d:%d", m.Line, m.Column)
+	}
+	if m.Entries[1].KeyPos.Column != 50 {
+		t.Errorf("expected key 'tags' at column 50, got %d", m.Entries[1].KeyPos.Column)
+	}
+
+	// Free-form args are left opaque.
+	opaque := wf.Body[1].(*ast.ActivityCall)
+	if opaque.ArgExprs != nil {
+		t.Errorf("expected nil arg exprs for free-form args, got %v", opaque.ArgExprs)
+	}
+	if opaque.Args != "items[0]" {
+		t.Errorf("expected raw args preserved, got %q", opaque.Args)
+	}
+}
+
+func TestCloseArgExprs(t *testing.T) {
+	input := `workflow Foo() -> (Result):
+    close complete(Result{status: "done"})
+    close fail(Result{shipment})
+`
+	file, err := ParseFile(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wf := file.Definitions[0].(*ast.WorkflowDef)
+
+	done := wf.Body[0].(*ast.CloseStmt)
+	if len(done.ArgExprs) != 1 {
+		t.Fatalf("expected 1 arg expr, got %d", len(done.ArgExprs))
+	}
+	m, ok := done.ArgExprs[0].(*ast.MapLit)
+	if !ok || m.Type != "Result" {
+		t.Fatalf("expected typed literal Result{...}, got %#v", done.ArgExprs[0])
+	}
+	if m.Line != 2 || m.Column != 20 {
+		t.Errorf("expected typed literal at 2:20, got %d:%d", m.Line, m.Column)
+	}
+
+	// Shorthand fields are not map entries, so the args stay opaque.
+	if failed := wf.Body[1].(*ast.CloseStmt); failed.ArgExprs != nil {
+		t.Errorf("expected nil arg exprs for shorthand fields, got %v", failed.ArgExprs)
+	}
+}
+
+// ArgsPos and TextPos map indexes in Args back to the source, whatever the
+// spacing inside the parentheses: each argument lands where the expression
+// parser put it.
+func TestArgsPos(t *testing.T) {
+	input := "workflow Foo() -> (Result):\n" +
+		"    activity  Notify(  \temail,   subject)\n" +
+		"    await activity Ship(order)\n" +
+		"    close complete(\n" +
+		"        Result{status: \"done\"}\n" +
+		"    )\n"
+	file, err := ParseFile(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wf := file.Definitions[0].(*ast.WorkflowDef)
+
+	call := wf.Body[0].(*ast.ActivityCall)
+	target := wf.Body[1].(*ast.AwaitStmt).Target.(*ast.ActivityTarget)
+	done := wf.Body[2].(*ast.CloseStmt)
+	tests := []struct {
+		args    string
+		argsPos ast.Pos
+		exprs   []ast.Expr
+		want    []string
+	}{
+		{call.Args, call.ArgsPos, call.ArgExprs, []string{"email", "subject"}},
+		{target.Args, target.ArgsPos, target.ArgExprs, []string{"order"}},
+		{done.Args, done.ArgsPos, done.ArgExprs, []string{"Result"}},
+	}
+	for _, tt := range tests {
+		if len(tt.exprs) != len(tt.want) {
+			t.Fatalf("expected %d arg exprs in %q, got %d", len(tt.want), tt.args, len(tt.exprs))
+		}
+		from := 0
+		for i, x := range tt.exprs {
+			j := from + strings.Index(tt.args[from:], tt.want[i])
+			from = j + len(tt.want[i])
+			got := ast.TextPos(tt.args, tt.argsPos, j)
+			if got.Line != x.NodeLine() || got.Column != x.NodeColumn() {
+				t.Errorf("%s in %q: TextPos gives %d:%d, expression is at %d:%d", tt.want[i], tt.args, got.Line, got.Column, x.NodeLine(), x.NodeColumn())
+			}
+		}
+	}
+	if done.ArgsPos != (ast.Pos{Line: 4, Column: 20}) {
+		t.Errorf("expected close args at 4:20, got %d:%d", done.ArgsPos.Line, done.ArgsPos.Column)
+	}
+}
+
+func TestOptionsListAndMapValues(t *testing.T) {
+	input := `workflow Foo(x: int) -> (Result):
+    workflow Child(x) -> r
+        options:
+            memo: {team: "payments", tier: 1}
+            retry_policy:
+                non_retryable_error_types: [
+                    "InvalidInput",
+                    "NotFound",
+                ]
+                maximum_attempts: 3
+`
+	file, err := ParseFile(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	call := file.Definitions[0].(*ast.WorkflowDef).Body[0].(*ast.WorkflowCall)
+	memo := call.Options.Entries[0]
+	if memo.ValueType != "map" {
+		t.Errorf("expected map value type, got %q", memo.ValueType)
+	}
+	if _, ok := memo.Expr.(*ast.MapLit); !ok {
+		t.Errorf("expected MapLit expr, got %T", memo.Expr)
+	}
+	retry := call.Options.Entries[1]
+	if len(retry.Nested) != 2 {
+		t.Fatalf("expected 2 nested entries, got %d", len(retry.Nested))
+	}
+	errTypes := retry.Nested[0]
+	if errTypes.ValueType != "list" || errTypes.Value != `["InvalidInput", "NotFound"]` {
+		t.Errorf("unexpected list entry: %s %q", errTypes.ValueType, errTypes.Value)
+	}
+	if retry.Nested[1].Key != "maximum_attempts" || retry.Nested[1].Line != 10 {
+		t.Errorf("expected maximum_attempts on line 10, got %q on %d", retry.Nested[1].Key, retry.Nested[1].Line)
+	}
+}
+
+func TestOptionsListTypeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		msg   string
+	}{
+		{"wrong element", `["A", 3]`, "expected list of string, got number element"},
+		{"map for list", `{a: "b"}`, "expected list, got map"},
+		{"scalar for list", `"A"`, "expected list, got string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := `workflow Foo(x: int) -> (Result):
+    activity Charge(x)
+        options:
+            retry_policy:
+                non_retryable_error_types: ` + tt.value + "\n"
+			_, err := ParseFile(input)
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.msg) {
+				t.Errorf("expected error containing %q, got %q", tt.msg, err.Error())
+			}
+		})
+	}
+}
+
+func TestSwitchExprs(t *testing.T) {
+	input := `workflow W(x: int) -> (int):
+    switch (order.tier):
+        case "gold":
+            activity A(x)
+        case 1m:
+            activity A(x)
+        case next(x):
+            activity A(x)
+`
+	file, err := ParseFile(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sw := file.Definitions[0].(*ast.WorkflowDef).Body[0].(*ast.SwitchBlock)
+	if got := ast.ExprString(sw.SubjectExpr); got != "order.tier" {
+		t.Errorf("expected subject order.tier, got %q", got)
+	}
+
+	gold := sw.Cases[0]
+	if gold.Value != `"gold"` {
+		t.Errorf("expected case value %q, got %q", `"gold"`, gold.Value)
+	}
+	lit, ok := gold.ValueExpr.(*ast.StringLit)
+	if !ok || lit.Value != "gold" {
+		t.Fatalf("expected string literal gold, got %#v", gold.ValueExpr)
+	}
+	if lit.Line != 3 || lit.Column != 14 {
+		t.Errorf("expected case value at 3:14, got %d:%d", lit.Line, lit.Column)
+	}
+
+	if _, ok := sw.Cases[1].ValueExpr.(*ast.DurationLit); !ok {
+		t.Errorf("expected duration literal, got %T", sw.Cases[1].ValueExpr)
+	}
+	if sw.Cases[2].ValueExpr != nil {
+		t.Errorf("expected nil ValueExpr for free-form case, got %T", sw.Cases[2].ValueExpr)
+	}
+	if sw.Cases[2].Value != "next(x)" {
+		t.Errorf("expected case value next(x), got %q", sw.Cases[2].Value)
+	}
+}
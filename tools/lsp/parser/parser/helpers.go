@@ -10,25 +10,38 @@ import (
 
 // advance moves to the next token.
 func (p *Parser) advance() {
+	p.prev = p.current
 	p.current = p.peek
+	if p.limitErr != nil {
+		return // the token stream ended at the limit
+	}
 	p.peek = p.lex.NextToken()
+	p.checkDepth()
 }
 
 // expect consumes the current token if it matches the expected type.
 // Returns the consumed token or an error.
 func (p *Parser) expect(tt token.TokenType) (token.Token, error) {
-	if p.current.Type != tt {
-		return token.Token{}, p.errorf("expected %s, got %s (%q)", tt, p.current.Type, p.current.Literal)
+	if p.current.Type != tt || tt == token.ARGS && p.current.Unterminated {
+		return token.Token{}, p.unexpected(tt)
 	}
 	tok := p.current
 	p.advance()
 	return tok, nil
 }
 
-// errorf creates a ParseError at the current token position.
+// errorf creates a ParseError at the current token position. When the
+// lexer could not make a token there, its message replaces format, since
+// that is the mistake whatever the parser expected.
 func (p *Parser) errorf(format string, args ...interface{}) error {
+	msg := fmt.Sprintf(format, args...)
+	if p.current.Type == token.ILLEGAL {
+		if layout, ok := p.layoutError(); ok {
+			msg = layout
+		}
+	}
 	return &ParseError{
-		Msg:    fmt.Sprintf(format, args...),
+		Msg:    msg,
 		Line:   p.current.Line,
 		Column: p.current.Column,
 	}
@@ -39,22 +52,24 @@ func (p *Parser) addError(err *ParseError) {
 	p.errors = append(p.errors, err)
 }
 
-// recoverTopLevel skips tokens until the parser reaches a WORKFLOW or ACTIVITY
-// keyword at column 1 (top-level boundary) or EOF.
+// recoverTopLevel skips tokens until the parser reaches a top-level keyword or
+// annotation at column 1 (top-level boundary) or EOF.
 func (p *Parser) recoverTopLevel() {
 	for p.current.Type != token.EOF {
 		if (p.current.Type == token.WORKFLOW || p.current.Type == token.ACTIVITY ||
 			p.current.Type == token.WORKER || p.current.Type == token.NAMESPACE ||
-			p.current.Type == token.NEXUS) && p.current.Column == 1 {
+			p.current.Type == token.NEXUS || p.current.Type == token.CONST ||
+			p.current.Type == token.ENUM || p.current.Type == token.AT) && p.current.Column == 1 {
 			return
 		}
 		p.advance()
 	}
 }
 
-// collectRawUntil reads and concatenates token literals until one of the
+// collectRawUntil reads and concatenates token source text until one of the
 // terminator token types is found. The terminator is NOT consumed.
-// Uses token positions to preserve original spacing.
+// Uses token positions to preserve original spacing. It also stops at a
+// token the lexer could not make, which the caller reports.
 func (p *Parser) collectRawUntil(terminators ...token.TokenType) string {
 	var b strings.Builder
 	lastEnd := 0 // column after last token
@@ -64,7 +79,7 @@ func (p *Parser) collectRawUntil(terminators ...token.TokenType) string {
 				return strings.TrimSpace(b.String())
 			}
 		}
-		if p.current.Type == token.EOF {
+		if p.current.Type == token.EOF || p.current.Type == token.ILLEGAL {
 			return strings.TrimSpace(b.String())
 		}
 		// Reconstruct spacing: if this token starts after last token ended,
@@ -74,12 +89,73 @@ func (p *Parser) collectRawUntil(terminators ...token.TokenType) string {
 				b.WriteByte(' ')
 			}
 		}
-		b.WriteString(p.current.Literal)
-		lastEnd = p.current.Column + len(p.current.Literal)
+		src := tokenSource(p.current)
+		b.WriteString(src)
+		lastEnd = p.current.Column + len(src)
 		p.advance()
 	}
 }
 
+// openBlocks returns the number of blocks open once the current token is
+// consumed, counting the block it opens or not the one it closes.
+func (p *Parser) openBlocks() int {
+	switch p.peek.Type {
+	case token.INDENT:
+		return p.depth - 1
+	case token.DEDENT:
+		return p.depth + 1
+	}
+	return p.depth
+}
+
+// sourceLines returns the source from line from up to, not including, line
+// to, without the indentation the lines share or trailing blank lines.
+func (p *Parser) sourceLines(from, to int) string {
+	all := strings.Split(p.input, "\n")
+	if to < 1 || to > len(all) {
+		to = len(all) + 1
+	}
+	if from < 1 || from >= to {
+		return ""
+	}
+	lines := all[from-1 : to-1]
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if n := len(line) - len(strings.TrimLeft(line, " ")); indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	for i, line := range lines {
+		if len(line) >= indent {
+			lines[i] = line[indent:]
+		} else {
+			lines[i] = ""
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// tokenSource returns the source text of tok. STRING and ARGS literals are
+// stored without their delimiters, so those are restored here.
+func tokenSource(tok token.Token) string {
+	switch {
+	case tok.Type == token.STRING && tok.Triple:
+		return `"""` + tok.Literal + `"""`
+	case tok.Type == token.STRING:
+		return `"` + tok.Literal + `"`
+	case tok.Type == token.ARGS:
+		return "(" + tok.Literal + ")"
+	default:
+		return tok.Literal
+	}
+}
+
 // expectBlock consumes the COLON NEWLINE INDENT sequence that opens an
 // indented block.
 func (p *Parser) expectBlock() error {
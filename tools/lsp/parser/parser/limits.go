@@ -0,0 +1,189 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/lexer"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/token"
+)
+
+// Limits bounds the input the parser accepts, so a pathological file fails
+// with a ParseError instead of exhausting the stack or memory of a
+// long-running process such as the language server. A zero field is no
+// limit.
+type Limits struct {
+	MaxBytes       int // input size in bytes
+	MaxDepth       int // nesting of indented blocks, and of list, map, and unary expressions
+	MaxDefinitions int // top-level definitions
+}
+
+// DefaultLimits are the limits of ParseFile, ParseFileAll, ParseDefinition,
+// and ParseExpr, far beyond any hand-written design. Set it before parsing.
+var DefaultLimits = Limits{
+	MaxBytes:       8 << 20,
+	MaxDepth:       100,
+	MaxDefinitions: 10000,
+}
+
+// ParseFile is ParseFile with limits l.
+func (l Limits) ParseFile(input string) (*ast.File, error) {
+	return Options{Limits: l}.ParseFile(input)
+}
+
+// ParseFileAll is ParseFileAll with limits l. When a limit is exceeded,
+// the definitions parsed before it are returned with the errors found
+// before it, followed by the error for the limit.
+func (l Limits) ParseFileAll(input string) (*ast.File, []*ParseError) {
+	return Options{Limits: l}.ParseFileAll(input)
+}
+
+// Options configure a parse: the limits it enforces and how it lexes its
+// input. Content parsed inline, such as call arguments, is lexed the same
+// way as the file.
+type Options struct {
+	Limits Limits
+	Lexer  lexer.Options
+}
+
+// WithLexer returns the Options parsing within DefaultLimits and lexing
+// with lex, such as the keyword aliases a tool was given.
+func WithLexer(lex lexer.Options) Options {
+	return Options{Limits: DefaultLimits, Lexer: lex}
+}
+
+// ParseFile is ParseFile with options o.
+func (o Options) ParseFile(input string) (*ast.File, error) {
+	p, err := o.newParser(input, false)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &ast.File{}
+
+	for p.current.Type != token.EOF {
+		switch {
+		case p.current.Type == token.NEWLINE:
+			p.advance()
+			continue
+		case p.current.Type == token.COMMENT:
+			p.advance()
+			continue
+		default:
+			if err := p.checkDefinitions(len(file.Definitions)); err != nil {
+				return nil, err
+			}
+			parser, ok := topLevelParsers[p.current.Type]
+			if !ok {
+				return nil, p.unexpectedTopLevel()
+			}
+			def, err := parser(p)
+			if p.limitErr != nil {
+				return nil, p.limitErr
+			}
+			if err != nil {
+				return nil, err
+			}
+			file.Definitions = append(file.Definitions, def)
+		}
+	}
+
+	return file, nil
+}
+
+// ParseFileAll is ParseFileAll with options o, returning what the limits
+// of o allow as Limits.ParseFileAll does.
+func (o Options) ParseFileAll(input string) (*ast.File, []*ParseError) {
+	file := &ast.File{}
+	p, err := o.newParser(input, true)
+	if err != nil {
+		return file, []*ParseError{err.(*ParseError)}
+	}
+
+	for p.current.Type != token.EOF {
+		switch {
+		case p.current.Type == token.NEWLINE:
+			p.advance()
+			continue
+		case p.current.Type == token.COMMENT:
+			p.advance()
+			continue
+		default:
+			if err := p.checkDefinitions(len(file.Definitions)); err != nil {
+				return file, append(p.errors, err.(*ParseError))
+			}
+			parser, ok := topLevelParsers[p.current.Type]
+			if !ok {
+				p.addError(p.unexpectedTopLevel().(*ParseError))
+				p.recoverTopLevel()
+				continue
+			}
+			start := p.current
+			def, err := parser(p)
+			if p.limitErr != nil {
+				return file, append(p.errors[:p.limitErrs], p.limitErr)
+			}
+			if err != nil {
+				if pe, ok := err.(*ParseError); ok {
+					p.addError(pe)
+				}
+				// A definition that failed on its first token is still at
+				// a top-level keyword, where recovery would stop at once.
+				if p.current == start {
+					p.advance()
+				}
+				p.recoverTopLevel()
+				continue
+			}
+			file.Definitions = append(file.Definitions, def)
+		}
+	}
+
+	return file, p.errors
+}
+
+// newParser creates a Parser over input, or returns the error for input
+// larger than o allows.
+func (o Options) newParser(input string, collecting bool) (*Parser, error) {
+	if l := o.Limits; l.MaxBytes > 0 && len(input) > l.MaxBytes {
+		return nil, &ParseError{
+			Msg:    fmt.Sprintf("input is %d bytes, more than the limit of %d", len(input), l.MaxBytes),
+			Line:   1,
+			Column: 1,
+		}
+	}
+	p := &Parser{lex: o.Lexer.New(input), input: input, limits: o.Limits, lexOpts: o.Lexer, collecting: collecting}
+	p.advance() // fill current
+	p.advance() // fill peek
+	return p, nil
+}
+
+// checkDefinitions returns the error for a definition at the current token
+// when n definitions have already been parsed.
+func (p *Parser) checkDefinitions(n int) error {
+	if p.limits.MaxDefinitions > 0 && n >= p.limits.MaxDefinitions {
+		return p.errorf("more than %d top-level definitions", p.limits.MaxDefinitions)
+	}
+	return nil
+}
+
+// checkDepth tracks the block nesting at the peek token. Past the limit it
+// records the error and ends the token stream, so the parsers unwind
+// without nesting further.
+func (p *Parser) checkDepth() {
+	switch p.peek.Type {
+	case token.INDENT:
+		p.depth++
+	case token.DEDENT:
+		p.depth--
+	}
+	if p.limits.MaxDepth > 0 && p.depth > p.limits.MaxDepth {
+		p.limitErr = &ParseError{
+			Msg:    fmt.Sprintf("blocks nested more than %d levels deep", p.limits.MaxDepth),
+			Line:   p.peek.Line,
+			Column: p.peek.Column,
+		}
+		p.limitErrs = len(p.errors)
+		p.peek = token.Token{Type: token.EOF, Line: p.peek.Line, Column: p.peek.Column}
+	}
+}
@@ -98,7 +98,7 @@ func parseAsyncOperation(p *Parser) (*ast.NexusOperation, error) {
 		Pos:          pos,
 		OpType:       ast.NexusOpAsync,
 		Name:         opName.Literal,
-		Workflow:      ast.Ref[*ast.WorkflowDef]{Name: wfName.Literal},
+		Workflow:      ast.Ref[*ast.WorkflowDef]{Pos: ast.Pos{Line: wfName.Line, Column: wfName.Column}, Name: wfName.Literal},
 	}, nil
 }
 
@@ -146,7 +146,7 @@ func parseSyncOperation(p *Parser) (*ast.NexusOperation, error) {
 	}, nil
 }
 
-// parseNexusCall parses: NEXUS IDENT IDENT DOT IDENT ARGS [ARROW IDENT] NEWLINE [options]
+// parseNexusCall parses: NEXUS IDENT IDENT DOT IDENT ARGS [ARROW binding] NEWLINE [options]
 // Called when current token is NEXUS inside a workflow body.
 func parseNexusCall(p *Parser) (ast.Statement, error) {
 	pos := ast.Pos{Line: p.current.Line, Column: p.current.Column}
@@ -184,11 +184,9 @@ func parseNexusCallInner(p *Parser, pos ast.Pos, detach bool) (ast.Statement, er
 	var result string
 	if p.current.Type == token.ARROW {
 		p.advance()
-		res, err := p.expect(token.IDENT)
-		if err != nil {
+		if result, err = parseParamBinding(p); err != nil {
 			return nil, err
 		}
-		result = res.Literal
 	}
 
 	// Validate: detach + arrow = error
@@ -216,6 +214,8 @@ func parseNexusCallInner(p *Parser, pos ast.Pos, detach bool) (ast.Statement, er
 		Service:   ast.Ref[*ast.NexusServiceDef]{Pos: ast.Pos{Line: service.Line, Column: service.Column}, Name: service.Literal},
 		Operation: ast.Ref[*ast.NexusOperation]{Pos: ast.Pos{Line: operation.Line, Column: operation.Column}, Name: operation.Literal},
 		Args:      args.Literal,
+		ArgsPos:   argsPos(args),
+		ArgExprs:  p.parseArgExprs(args),
 		Result:    result,
 		Options:   options,
 	}, nil
@@ -245,14 +245,17 @@ func parseWorkflowCallOrNexus(p *Parser) (ast.Statement, error) {
 		return nil, err
 	}
 
+	id, err := parseWorkflowID(p)
+	if err != nil {
+		return nil, err
+	}
+
 	var result string
 	if p.current.Type == token.ARROW {
 		p.advance()
-		res, err := p.expect(token.IDENT)
-		if err != nil {
+		if result, err = parseParamBinding(p); err != nil {
 			return nil, err
 		}
-		result = res.Literal
 	}
 
 	// Validate: detach + arrow = error
@@ -278,6 +281,9 @@ func parseWorkflowCallOrNexus(p *Parser) (ast.Statement, error) {
 		Mode:    ast.CallDetach,
 		Workflow: ast.Ref[*ast.WorkflowDef]{Pos: pos, Name: name.Literal},
 		Args:     args.Literal,
+		ArgsPos:  argsPos(args),
+		ArgExprs: p.parseArgExprs(args),
+		ID:       id,
 		Result:   result,
 		Options:  options,
 	}, nil
@@ -21,9 +21,10 @@ const (
 // optionSchema describes the expected value type for an option key.
 // "nested" means the key introduces a nested block (e.g. retry_policy:).
 type optionSchema struct {
-	valueType string // "string", "duration", "number", "bool", "enum", "nested"
+	valueType string // "string", "duration", "number", "bool", "enum", "list", "map", "nested"
 	nested    map[string]*optionSchema
 	allowed   []string // allowed values for enum type
+	elemType  string   // required element type for list type
 }
 
 var retryPolicySchema = map[string]*optionSchema{
@@ -31,7 +32,7 @@ var retryPolicySchema = map[string]*optionSchema{
 	"backoff_coefficient":     {valueType: "number"},
 	"maximum_interval":        {valueType: "duration"},
 	"maximum_attempts":        {valueType: "number"},
-	"non_retryable_error_types": {valueType: "string"},
+	"non_retryable_error_types": {valueType: "list", elemType: "string"},
 }
 
 var prioritySchema = map[string]*optionSchema{
@@ -77,6 +78,8 @@ var workflowOptionSchema = map[string]*optionSchema{
 	"parent_close_policy":          {valueType: "enum", allowed: []string{"TERMINATE", "ABANDON", "REQUEST_CANCEL"}},
 	"workflow_id_reuse_policy":     {valueType: "enum", allowed: []string{"ALLOW_DUPLICATE", "ALLOW_DUPLICATE_FAILED_ONLY", "REJECT_DUPLICATE", "TERMINATE_IF_RUNNING"}},
 	"cron_schedule":                {valueType: "string"},
+	"memo":                         {valueType: "map"},
+	"search_attributes":            {valueType: "map"},
 	"retry_policy":                 {valueType: "nested", nested: retryPolicySchema},
 	"priority":                     {valueType: "nested", nested: prioritySchema},
 }
@@ -103,8 +106,31 @@ func schemaForContext(ctx OptionsContext) map[string]*optionSchema {
 	return optionSchemas[ctx]
 }
 
+// OptionType returns the value type of the option at path in ctx, such as
+// "duration" for start_to_close_timeout or "number" for retry_policy
+// maximum_attempts. Keys introducing a nested block have type "nested". It
+// reports false when ctx has no option at path.
+func OptionType(ctx OptionsContext, path ...string) (string, bool) {
+	schema := schemaForContext(ctx)
+	for i, key := range path {
+		sch, ok := schema[key]
+		if !ok {
+			return "", false
+		}
+		if i == len(path)-1 {
+			return sch.valueType, true
+		}
+		schema = sch.nested
+	}
+	return "", false
+}
+
 // parseOptionsBlock parses the contents of an options block: COLON NEWLINE INDENT entries DEDENT.
 // The OPTIONS keyword has already been consumed. Expects current token = COLON.
+//
+// When collecting errors, entries that fail to parse do not fail the call
+// or definition holding the block: the error is recorded and the block
+// keeps its source as RawText, with no entries.
 func (p *Parser) parseOptionsBlock(ctx OptionsContext) (*ast.OptionsBlock, error) {
 	pos := ast.Pos{Line: p.current.Line, Column: p.current.Column}
 
@@ -119,12 +145,24 @@ func (p *Parser) parseOptionsBlock(ctx OptionsContext) (*ast.OptionsBlock, error
 	}
 
 	schema := schemaForContext(ctx)
+	first, open := p.current.Line, p.openBlocks()
 	entries, err := p.parseOptionEntries(schema)
 	if err != nil {
-		return nil, err
+		pe, ok := err.(*ParseError)
+		if !ok || !p.collecting || p.limitErr != nil {
+			return nil, err
+		}
+		p.addError(pe)
+		for p.current.Type != token.EOF && (p.current.Type != token.DEDENT || p.openBlocks() >= open) {
+			p.advance()
+		}
+		return &ast.OptionsBlock{
+			Pos:     pos,
+			RawText: p.sourceLines(first, p.current.Line),
+		}, p.expectOptionsEnd()
 	}
 
-	if _, err := p.expect(token.DEDENT); err != nil {
+	if err := p.expectOptionsEnd(); err != nil {
 		return nil, err
 	}
 
@@ -134,6 +172,12 @@ func (p *Parser) parseOptionsBlock(ctx OptionsContext) (*ast.OptionsBlock, error
 	}, nil
 }
 
+// expectOptionsEnd consumes the DEDENT closing an options block.
+func (p *Parser) expectOptionsEnd() error {
+	_, err := p.expect(token.DEDENT)
+	return err
+}
+
 // parseOptionEntries parses key-value pairs until DEDENT is encountered.
 func (p *Parser) parseOptionEntries(schema map[string]*optionSchema) ([]*ast.OptionEntry, error) {
 	var entries []*ast.OptionEntry
@@ -231,12 +275,31 @@ func (p *Parser) parseOptionEntry(schema map[string]*optionSchema) (*ast.OptionE
 			}
 		}
 
-		value, valueType, err := p.parseOptionValue(sch)
-		if err != nil {
-			return nil, err
+		if p.current.Type == token.LBRACKET || p.current.Type == token.LBRACE {
+			expr, valueType, err := p.parseCompositeOptionValue(sch)
+			if err != nil {
+				return nil, err
+			}
+			entry.Value = ast.ExprString(expr)
+			entry.ValueType = valueType
+			entry.Expr = expr
+		} else if p.current.Type == token.IDENT && sch != nil && isScalarOptionType(sch.valueType) {
+			// A bare identifier where a scalar is expected names a constant.
+			entry.Const = ast.Ref[*ast.ConstDef]{
+				Pos:  ast.Pos{Line: p.current.Line, Column: p.current.Column},
+				Name: p.current.Literal,
+			}
+			entry.Value = p.current.Literal
+			entry.ValueType = sch.valueType
+			p.advance()
+		} else {
+			value, valueType, err := p.parseOptionValue(sch)
+			if err != nil {
+				return nil, err
+			}
+			entry.Value = value
+			entry.ValueType = valueType
 		}
-		entry.Value = value
-		entry.ValueType = valueType
 	}
 
 	// Consume trailing newline if present.
@@ -286,20 +349,21 @@ func (p *Parser) parseOptionValue(sch *optionSchema) (string, string, error) {
 		}
 		return val, "number", nil
 
-	case token.IDENT:
+	case token.BOOL:
 		val := p.current.Literal
 		p.advance()
-		// Could be bool (true/false) or enum value.
-		if val == "true" || val == "false" {
-			if sch != nil && sch.valueType != "bool" {
-				return "", "", &ParseError{
-					Msg:    "expected " + sch.valueType + ", got bool",
-					Line:   p.current.Line,
-					Column: p.current.Column,
-				}
+		if sch != nil && sch.valueType != "bool" {
+			return "", "", &ParseError{
+				Msg:    "expected " + sch.valueType + ", got bool",
+				Line:   p.current.Line,
+				Column: p.current.Column,
 			}
-			return val, "bool", nil
 		}
+		return val, "bool", nil
+
+	case token.IDENT:
+		val := p.current.Literal
+		p.advance()
 		// Enum value.
 		if sch != nil && sch.valueType == "enum" {
 			valid := false
@@ -326,3 +390,69 @@ func (p *Parser) parseOptionValue(sch *optionSchema) (string, string, error) {
 	}
 }
 
+
+// parseCompositeOptionValue parses a list or map literal value after COLON.
+// Returns the expression and its value type ("list" or "map").
+func (p *Parser) parseCompositeOptionValue(sch *optionSchema) (ast.Expr, string, error) {
+	pos := ast.Pos{Line: p.current.Line, Column: p.current.Column}
+	expr, err := p.parsePrimaryExpr()
+	if err != nil {
+		return nil, "", err
+	}
+	valueType := "map"
+	if _, ok := expr.(*ast.ListLit); ok {
+		valueType = "list"
+	}
+	if sch == nil {
+		return expr, valueType, nil
+	}
+	if sch.valueType != valueType {
+		return nil, "", &ParseError{
+			Msg:    "expected " + sch.valueType + ", got " + valueType,
+			Line:   pos.Line,
+			Column: pos.Column,
+		}
+	}
+	if list, ok := expr.(*ast.ListLit); ok && sch.elemType != "" {
+		for _, elem := range list.Elems {
+			if t := exprValueType(elem); t != sch.elemType {
+				return nil, "", &ParseError{
+					Msg:    "expected list of " + sch.elemType + ", got " + t + " element",
+					Line:   elem.NodeLine(),
+					Column: elem.NodeColumn(),
+				}
+			}
+		}
+	}
+	return expr, valueType, nil
+}
+
+// isScalarOptionType reports whether a schema value type may be supplied by a
+// constant reference.
+func isScalarOptionType(valueType string) bool {
+	switch valueType {
+	case "string", "duration", "number", "bool":
+		return true
+	}
+	return false
+}
+
+// exprValueType names the option value type of an expression.
+func exprValueType(x ast.Expr) string {
+	switch x.(type) {
+	case *ast.StringLit:
+		return "string"
+	case *ast.NumberLit:
+		return "number"
+	case *ast.DurationLit:
+		return "duration"
+	case *ast.BoolLit:
+		return "bool"
+	case *ast.ListLit:
+		return "list"
+	case *ast.MapLit:
+		return "map"
+	default:
+		return "identifier"
+	}
+}
@@ -13,6 +13,10 @@ type ParseError struct {
 	Msg    string
 	Line   int
 	Column int
+
+	// Expected spells the tokens that were valid where the error is, such
+	// as "':'" or "workflow", when the parser knows them.
+	Expected []string
 }
 
 func (e *ParseError) Error() string {
@@ -34,13 +38,22 @@ const (
 // Parser is a recursive descent parser for .twf files.
 type Parser struct {
 	lex     *lexer.Lexer
+	input   string // the source, for the text of blocks that fail to parse
 	current token.Token
 	peek    token.Token
+	prev    token.Token // the token before current, for error messages
 
 	bodyCtx bodyContext
 
 	collecting bool          // true when collecting errors instead of bailing
 	errors     []*ParseError // accumulated errors in collecting mode
+
+	limits    Limits
+	lexOpts   lexer.Options // for inline content, as for the file
+	depth     int         // blocks open at the peek token
+	exprDepth int         // expressions being parsed
+	limitErr  *ParseError // set when a limit ended the token stream
+	limitErrs int         // len(errors) when limitErr was set
 }
 
 // Registration maps for keyword dispatch.
@@ -57,6 +70,9 @@ func init() {
 		token.WORKER:    parseWorkerDef,
 		token.NAMESPACE: parseNamespaceDef,
 		token.NEXUS:     parseNexusTopLevel,
+		token.CONST:     parseConstDef,
+		token.ENUM:      parseEnumDef,
+		token.AT:        parseAnnotatedDef,
 	}
 
 	workflowStmtParsers = map[token.TokenType]stmtParser{
@@ -110,78 +126,51 @@ var temporalKeywords = map[token.TokenType]bool{
 	token.CLOSE:           true,
 }
 
-// ParseFile parses a .twf source string into an AST File.
+// ParseFile parses a .twf source string into an AST File, within
+// DefaultLimits.
 func ParseFile(input string) (*ast.File, error) {
-	l := lexer.New(input)
-	p := &Parser{lex: l}
-	p.advance() // fill current
-	p.advance() // fill peek
-
-	file := &ast.File{}
-
-	for p.current.Type != token.EOF {
-		switch {
-		case p.current.Type == token.NEWLINE:
-			p.advance()
-			continue
-		case p.current.Type == token.COMMENT:
-			p.advance()
-			continue
-		default:
-			parser, ok := topLevelParsers[p.current.Type]
-			if !ok {
-				return nil, p.errorf("unexpected token %s at top level", p.current.Type)
-			}
-			def, err := parser(p)
-			if err != nil {
-				return nil, err
-			}
-			file.Definitions = append(file.Definitions, def)
-		}
-	}
-
-	return file, nil
+	return DefaultLimits.ParseFile(input)
 }
 
 // ParseFileAll parses a .twf source string, collecting as many errors as
 // possible instead of stopping at the first one. It returns a partial AST
-// (which may have successfully parsed definitions) alongside all parse errors.
+// (which may have successfully parsed definitions) alongside all parse
+// errors. Input beyond DefaultLimits ends the parse with an error.
 func ParseFileAll(input string) (*ast.File, []*ParseError) {
-	l := lexer.New(input)
-	p := &Parser{lex: l, collecting: true}
-	p.advance() // fill current
-	p.advance() // fill peek
+	return DefaultLimits.ParseFileAll(input)
+}
 
-	file := &ast.File{}
+// ParseDefinition parses a source string holding exactly one workflow or
+// activity definition, with its annotations, such as an editor snippet or a
+// generated stub. Blank lines and comments may surround it; anything else is
+// an error.
+func ParseDefinition(input string) (ast.Definition, error) {
+	p, err := Options{Limits: DefaultLimits}.newParser(input, false)
+	if err != nil {
+		return nil, err
+	}
 
-	for p.current.Type != token.EOF {
-		switch {
-		case p.current.Type == token.NEWLINE:
-			p.advance()
-			continue
-		case p.current.Type == token.COMMENT:
-			p.advance()
-			continue
-		default:
-			parser, ok := topLevelParsers[p.current.Type]
-			if !ok {
-				p.addError(p.errorf("unexpected token %s at top level", p.current.Type).(*ParseError))
-				p.recoverTopLevel()
-				continue
-			}
-			def, err := parser(p)
-			if err != nil {
-				if pe, ok := err.(*ParseError); ok {
-					p.addError(pe)
-				}
-				p.recoverTopLevel()
-				continue
-			}
-			file.Definitions = append(file.Definitions, def)
-		}
+	p.skipBlankLinesAndComments()
+	var parse defParser
+	switch p.current.Type {
+	case token.WORKFLOW, token.ACTIVITY, token.AT:
+		parse = topLevelParsers[p.current.Type]
+	default:
+		return nil, p.errorf("expected a workflow or activity definition, got %s", p.current.Type)
+	}
+	def, err := parse(p)
+	if p.limitErr != nil {
+		return nil, p.limitErr
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	return file, p.errors
+	p.skipBlankLinesAndComments()
+	if p.current.Type != token.EOF {
+		return nil, p.errorf("unexpected %s after the definition", p.current.Type)
+	}
+	return def, nil
 }
 
 // parseBody parses statements inside an indented block (after INDENT, until DEDENT).
@@ -204,6 +193,24 @@ func (p *Parser) parseBody() ([]ast.Statement, error) {
 			continue
 		}
 
+		if p.current.Type == token.INDENT {
+			return nil, p.unexpected()
+		}
+		if p.current.Type == token.ELIF {
+			return nil, p.errorf("elif without a preceding if")
+		}
+		if isDescriptionHeader(p) {
+			return nil, p.errorf("description: must come first in a workflow, directly under its header")
+		}
+		if p.current.Type == token.IDENT && p.peek.Type == token.COLON {
+			stmt, err := parseLabeledForStmt(p)
+			if err != nil {
+				return nil, err
+			}
+			stmts = append(stmts, stmt)
+			continue
+		}
+
 		var parseFn stmtParser
 		var ok bool
 		switch p.bodyCtx {
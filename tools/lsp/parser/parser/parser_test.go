@@ -4,6 +4,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
 )
@@ -303,6 +304,66 @@ func TestWorkflowCallDetach(t *testing.T) {
 	}
 }
 
+func TestWorkflowCallID(t *testing.T) {
+	input := `workflow Foo(order: Order, id: string):
+    workflow ShipOrder(order) id "ship-{order.id}" -> shipResult
+    detach workflow Notify(id) id "notify-{id}"
+    workflow Audit(order) -> id
+`
+	file, err := ParseFile(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wf := file.Definitions[0].(*ast.WorkflowDef)
+	ship := wf.Body[0].(*ast.WorkflowCall)
+	if ship.ID != "ship-{order.id}" || ship.Result != "shipResult" {
+		t.Errorf("expected id \"ship-{order.id}\" -> shipResult, got %q -> %q", ship.ID, ship.Result)
+	}
+	notify := wf.Body[1].(*ast.WorkflowCall)
+	if notify.Mode != ast.CallDetach || notify.ID != "notify-{id}" || notify.Args != "id" {
+		t.Errorf("unexpected detached call: %+v", notify)
+	}
+	if audit := wf.Body[2].(*ast.WorkflowCall); audit.ID != "" || audit.Result != "id" {
+		t.Errorf("expected id to remain a valid result name, got id %q, result %q", audit.ID, audit.Result)
+	}
+
+	if _, err := ParseFile("workflow Foo():\n    workflow Bar() id ship\n"); err == nil {
+		t.Error("expected an error for an id without a string")
+	}
+}
+
+func TestMultiValueResultBinding(t *testing.T) {
+	input := `workflow Foo(order: Order) -> (Order, error):
+    activity Charge(order) -> (payment, err)
+    workflow Ship(order) -> (tracking)
+    await one:
+        activity Charge(order) -> (payment, err):
+            close complete
+`
+	file, err := ParseFile(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wf := file.Definitions[0].(*ast.WorkflowDef)
+	if got := ast.SplitList(wf.ReturnType); len(got) != 2 || got[1] != "error" {
+		t.Errorf("expected return types Order and error, got %q", got)
+	}
+	if call := wf.Body[0].(*ast.ActivityCall); call.Result != "payment, err" {
+		t.Errorf("expected result 'payment, err', got %q", call.Result)
+	}
+	if call := wf.Body[1].(*ast.WorkflowCall); call.Result != "tracking" {
+		t.Errorf("expected result 'tracking', got %q", call.Result)
+	}
+	target := wf.Body[2].(*ast.AwaitOneBlock).Cases[0].Target.(*ast.ActivityTarget)
+	if target.Result != "payment, err" {
+		t.Errorf("expected case result 'payment, err', got %q", target.Result)
+	}
+
+	if _, err := ParseFile("workflow Foo():\n    detach workflow Bar() -> (a, b)\n"); err == nil {
+		t.Error("expected an error for a detached call binding results")
+	}
+}
+
 func TestNexusCallBasic(t *testing.T) {
 	input := `workflow Foo(x: int) -> (Result):
     nexus PaymentEndpoint PaymentService.Charge(card) -> chargeResult
@@ -440,6 +501,100 @@ func TestStateBlockWithCondition(t *testing.T) {
 	}
 }
 
+func TestWorkflowDescription(t *testing.T) {
+	input := `workflow Foo(x: int):
+    # Kept out of the description.
+    description:
+        Charges the customer's card (once) and ships "the" order.
+
+        Retries are bounded:
+            three attempts at most.
+    state:
+        condition ready
+
+    close complete
+`
+	file, err := ParseFile(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wf := file.Definitions[0].(*ast.WorkflowDef)
+	want := "Charges the customer's card (once) and ships \"the\" order.\n\nRetries are bounded:\n    three attempts at most."
+	if wf.Description != want {
+		t.Errorf("unexpected description %q", wf.Description)
+	}
+	if wf.State == nil || len(wf.State.Conditions) != 1 || len(wf.Body) != 1 {
+		t.Errorf("expected the state block and body to follow, got %+v", wf)
+	}
+
+	for _, tt := range []struct {
+		input, wantErr string
+	}{
+		{"workflow Foo():\n    description:\n    close complete\n", "expected indented text under description:"},
+		{"workflow Foo():\n    activity A()\n    description:\n        Too late.\n", "description: must come first in a workflow"},
+	} {
+		_, errs := ParseFileAll(tt.input)
+		if len(errs) == 0 || !strings.Contains(errs[0].Msg, tt.wantErr) {
+			t.Errorf("%q: expected error %q, got %v", tt.input, tt.wantErr, errs)
+		}
+	}
+
+	// Elsewhere description is an ordinary name.
+	if _, err := ParseFile("workflow Foo(description: string):\n    if (description != \"\"):\n        close complete\n"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestDefinitionOptions(t *testing.T) {
+	input := `activity Charge(order: Order):
+    options:
+        start_to_close_timeout: 30s
+        retry_policy:
+            maximum_attempts: 3
+    return
+
+workflow Order(order: Order):
+    description:
+        Charges the order.
+    options:
+        workflow_run_timeout: 1h
+    state:
+        condition paid
+    activity Charge(order)
+    close complete
+`
+	file, err := ParseFile(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	act := file.Definitions[0].(*ast.ActivityDef)
+	if act.Options == nil || len(act.Options.Entries) != 2 || act.Options.Entries[1].Nested[0].Value != "3" {
+		t.Errorf("unexpected activity options: %+v", act.Options)
+	}
+	if len(act.Body) != 1 {
+		t.Errorf("expected the body to follow the options, got %d statements", len(act.Body))
+	}
+	wf := file.Definitions[1].(*ast.WorkflowDef)
+	if wf.Options == nil || wf.Options.Entries[0].Key != "workflow_run_timeout" || wf.Options.Line != 11 {
+		t.Errorf("unexpected workflow options: %+v", wf.Options)
+	}
+	if wf.Description != "Charges the order." || wf.State == nil || len(wf.Body) != 2 {
+		t.Errorf("expected description, state, and body around the options, got %+v", wf)
+	}
+
+	for _, tt := range []struct {
+		input, wantErr string
+	}{
+		{"activity A():\n    options:\n        workflow_run_timeout: 1h\n    return\n", "unknown option key: workflow_run_timeout"},
+		{"workflow W():\n    options:\n        heartbeat_timeout: 1m\n    close complete\n", "unknown option key: heartbeat_timeout"},
+	} {
+		_, errs := ParseFileAll(tt.input)
+		if len(errs) == 0 || !strings.Contains(errs[0].Msg, tt.wantErr) {
+			t.Errorf("%q: expected error %q, got %v", tt.input, tt.wantErr, errs)
+		}
+	}
+}
+
 func TestSetUnsetStatements(t *testing.T) {
 	input := `workflow Foo(x: int) -> (Result):
     state:
@@ -620,6 +775,78 @@ func TestOptionsOnCall(t *testing.T) {
 	}
 }
 
+func TestOptionsTripleQuotedString(t *testing.T) {
+	input := `workflow Foo(x: int) -> (Result):
+    workflow ShipOrder(order) -> result
+        options:
+            cron_schedule: """0 12 * * *
+  "daily" run"""
+            workflow_run_timeout: 1h
+`
+	file, err := ParseFile(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wf := file.Definitions[0].(*ast.WorkflowDef)
+	call := wf.Body[0].(*ast.WorkflowCall)
+	if call.Options == nil || len(call.Options.Entries) != 2 {
+		t.Fatalf("expected 2 option entries, got %+v", call.Options)
+	}
+	entry := call.Options.Entries[0]
+	if entry.Value != "0 12 * * *\n  \"daily\" run" {
+		t.Errorf("expected verbatim value, got %q", entry.Value)
+	}
+	if entry.ValueType != "string" {
+		t.Errorf("expected value type 'string', got %q", entry.ValueType)
+	}
+	if next := call.Options.Entries[1]; next.Line != 6 {
+		t.Errorf("expected second entry on line 6, got %d", next.Line)
+	}
+}
+
+func TestOptionsBoolValue(t *testing.T) {
+	input := `workflow Foo(x: int) -> (Result):
+    activity CreateShipment(order) -> shipment
+        options:
+            request_eager_execution: true
+`
+	file, err := ParseFile(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	call := file.Definitions[0].(*ast.WorkflowDef).Body[0].(*ast.ActivityCall)
+	entry := call.Options.Entries[0]
+	if entry.Value != "true" || entry.ValueType != "bool" {
+		t.Errorf("expected bool 'true', got %s %q", entry.ValueType, entry.Value)
+	}
+}
+
+func TestOptionsBoolTypeMismatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		options string
+		msg     string
+	}{
+		{"bool for number", "retry_policy:\n                maximum_attempts: false", "expected number, got bool"},
+		{"number for bool", "request_eager_execution: 1", "expected bool, got number"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := `workflow Foo(x: int) -> (Result):
+    activity CreateShipment(order) -> shipment
+        options:
+            ` + tt.options + "\n"
+			_, err := ParseFile(input)
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.msg) {
+				t.Errorf("expected error containing %q, got %q", tt.msg, err.Error())
+			}
+		})
+	}
+}
+
 func TestOptionsOnWorkflowCall(t *testing.T) {
 	input := `workflow Foo(x: int) -> (Result):
     workflow ShipOrder(order) -> result
@@ -675,6 +902,53 @@ func TestAwaitAllBlock(t *testing.T) {
 	}
 }
 
+func TestAwaitAllOptions(t *testing.T) {
+	input := `workflow Foo():
+    await all options(onError: continue, minSuccess: 2):
+        activity A()
+        activity B()
+        activity C()
+    await all:
+        activity A()
+`
+	file, err := ParseFile(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wf := file.Definitions[0].(*ast.WorkflowDef)
+	opts := wf.Body[0].(*ast.AwaitAllBlock).Options
+	if opts == nil || opts.OnError != ast.AwaitAllContinue || opts.MinSuccess != 2 || opts.Line != 2 || opts.Column != 22 {
+		t.Fatalf("unexpected options: %+v", opts)
+	}
+	if len(wf.Body[0].(*ast.AwaitAllBlock).Body) != 3 {
+		t.Errorf("expected 3 body statements, got %d", len(wf.Body[0].(*ast.AwaitAllBlock).Body))
+	}
+	if opts := wf.Body[1].(*ast.AwaitAllBlock).Options; opts != nil {
+		t.Errorf("expected no options, got %+v", opts)
+	}
+
+	tests := []struct {
+		options string
+		want    string
+	}{
+		{"onError: maybe", "onError must be fail or continue"},
+		{"minSuccess: 0", "minSuccess must be a positive count"},
+		{"minSuccess: 2", "minSuccess requires onError: continue"},
+		{"onError: fail, minSuccess: 2", "minSuccess requires onError: continue"},
+		{"onError: continue, onError: fail", "onError is set more than once"},
+		{"retries: 3", "unknown await all option retries"},
+		{"continue", "expected key: value"},
+		{"", "must set onError or minSuccess"},
+	}
+	for _, tt := range tests {
+		input := "workflow Foo():\n    await all options(" + tt.options + "):\n        activity A()\n"
+		_, err := ParseFile(input)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: expected %q, got %v", tt.options, tt.want, err)
+		}
+	}
+}
+
 func TestAwaitOneBlock(t *testing.T) {
 	// await one cases support timer (with bodies), and nested await all.
 	input := `workflow Foo(x: int) -> (Result):
@@ -724,6 +998,73 @@ func TestAwaitOneBlock(t *testing.T) {
 	}
 }
 
+func TestAwaitOneGuard(t *testing.T) {
+	input := `workflow Foo(x: int) -> (Result):
+    signal Approve(amount: int):
+        approved = true
+    await one:
+        signal Approve -> amount if (amount < 1000):
+            activity AutoApprove(amount)
+        timer (24h):
+            activity Escalate(x)
+`
+	file, err := ParseFile(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wf := file.Definitions[0].(*ast.WorkflowDef)
+	awaitOne := wf.Body[0].(*ast.AwaitOneBlock)
+	guarded := awaitOne.Cases[0]
+	if _, ok := guarded.Target.(*ast.SignalTarget); !ok {
+		t.Fatalf("expected SignalTarget, got %T", guarded.Target)
+	}
+	if guarded.Guard != "amount < 1000" {
+		t.Errorf("expected guard 'amount < 1000', got %q", guarded.Guard)
+	}
+	if got := ast.ExprString(guarded.GuardExpr); got != "amount < 1000" {
+		t.Errorf("expected parsed guard, got %q", got)
+	}
+	if len(guarded.Body) != 1 {
+		t.Errorf("expected 1 body statement, got %d", len(guarded.Body))
+	}
+	if awaitOne.Cases[1].Guard != "" || awaitOne.Cases[1].GuardExpr != nil {
+		t.Errorf("expected unguarded timer case, got %q", awaitOne.Cases[1].Guard)
+	}
+
+	_, err = ParseFile(strings.Replace(input, "if (amount < 1000)", "if ( )", 1))
+	if want := "parse error at 5:37: empty guard: write the condition the case waits on, as in if (ready), or drop the if"; err == nil || err.Error() != want {
+		t.Errorf("got  %v\nwant %s", err, want)
+	}
+}
+
+func TestAwaitOneCaseBindings(t *testing.T) {
+	input := `workflow Foo(x: int) -> (Result):
+    state:
+        condition approved
+
+    await one:
+        approved -> approvalInfo:
+            activity Ship(approvalInfo)
+        timer (24h) -> expiredAt if (x > 0):
+            activity Escalate(expiredAt)
+`
+	file, err := ParseFile(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	awaitOne := file.Definitions[0].(*ast.WorkflowDef).Body[0].(*ast.AwaitOneBlock)
+	if ident := awaitOne.Cases[0].Target.(*ast.IdentTarget); ident.Result != "approvalInfo" {
+		t.Errorf("expected condition binding 'approvalInfo', got %q", ident.Result)
+	}
+	timer := awaitOne.Cases[1].Target.(*ast.TimerTarget)
+	if timer.Duration != "24h" || timer.Result != "expiredAt" {
+		t.Errorf("expected timer (24h) -> expiredAt, got %q -> %q", timer.Duration, timer.Result)
+	}
+	if awaitOne.Cases[1].Guard != "x > 0" {
+		t.Errorf("expected guard after the binding, got %q", awaitOne.Cases[1].Guard)
+	}
+}
+
 func TestSwitchBlock(t *testing.T) {
 	input := `workflow Foo(x: int) -> (Result):
     switch (batch.type):
@@ -781,6 +1122,57 @@ func TestIfElse(t *testing.T) {
 	}
 }
 
+func TestIfElifChain(t *testing.T) {
+	for _, kw := range []string{"elif", "else if"} {
+		t.Run(kw, func(t *testing.T) {
+			input := `workflow Foo(x: int) -> (Result):
+    if (order.tier == "gold"):
+        activity ExpediteOrder(order)
+    ` + kw + ` (order.tier == "silver"):
+        activity PriorityProcessing(order)
+    else:
+        activity StandardProcessing(order)
+`
+			file, err := ParseFile(input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			wf := file.Definitions[0].(*ast.WorkflowDef)
+			if len(wf.Body) != 1 {
+				t.Fatalf("expected 1 body statement, got %d", len(wf.Body))
+			}
+			outer := wf.Body[0].(*ast.IfStmt)
+			if !outer.ElseIf || len(outer.ElseBody) != 1 {
+				t.Fatalf("expected chained else-if, got ElseIf=%t with %d else statements", outer.ElseIf, len(outer.ElseBody))
+			}
+			inner, ok := outer.ElseBody[0].(*ast.IfStmt)
+			if !ok {
+				t.Fatalf("expected chained IfStmt, got %T", outer.ElseBody[0])
+			}
+			if inner.Condition != `order.tier == "silver"` {
+				t.Errorf("unexpected chained condition: %q", inner.Condition)
+			}
+			if inner.Line != 4 {
+				t.Errorf("expected chained if on line 4, got %d", inner.Line)
+			}
+			if inner.ElseIf || len(inner.ElseBody) != 1 {
+				t.Errorf("expected plain else on chained if, got ElseIf=%t with %d else statements", inner.ElseIf, len(inner.ElseBody))
+			}
+		})
+	}
+}
+
+func TestElifWithoutIf(t *testing.T) {
+	input := `workflow Foo(x: int) -> (Result):
+    elif (x):
+        activity A(x)
+`
+	_, err := ParseFile(input)
+	if err == nil || !strings.Contains(err.Error(), "elif without a preceding if") {
+		t.Fatalf("expected elif without if error, got %v", err)
+	}
+}
+
 func TestForInfinite(t *testing.T) {
 	input := `workflow Foo(x: int) -> (Result):
     for:
@@ -838,6 +1230,38 @@ func TestForIteration(t *testing.T) {
 	}
 }
 
+func TestForParallel(t *testing.T) {
+	input := `workflow Foo(order: Order):
+    batch: for each (item in order.items) parallel(max: 10):
+        activity ProcessItem(item)
+`
+	file, err := ParseFile(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	forStmt := file.Definitions[0].(*ast.WorkflowDef).Body[0].(*ast.ForStmt)
+	if forStmt.Variant != ast.ForParallel {
+		t.Errorf("expected ForParallel, got %d", forStmt.Variant)
+	}
+	if forStmt.Variable != "item" || forStmt.Iterable != "order.items" || forStmt.Concurrency != "10" {
+		t.Errorf("expected item in order.items max 10, got %q in %q max %q", forStmt.Variable, forStmt.Iterable, forStmt.Concurrency)
+	}
+	if forStmt.Label == nil || forStmt.Label.Name != "batch" || len(forStmt.Body) != 1 {
+		t.Errorf("unexpected label or body: %+v", forStmt)
+	}
+
+	for _, bad := range []string{
+		"for each (items) parallel(max: 10):",
+		"for each (item in items):",
+		"for each (item in items) parallel(10):",
+		"for each (item in items) parallel(min: 1):",
+	} {
+		if _, err := ParseFile("workflow Foo():\n    " + bad + "\n        activity A()\n"); err == nil {
+			t.Errorf("%s: expected a parse error", bad)
+		}
+	}
+}
+
 func TestContinueAsNew(t *testing.T) {
 	input := `workflow Foo(x: int) -> (Result):
     close continue_as_new(newArgs)
@@ -884,6 +1308,152 @@ func TestBreakContinue(t *testing.T) {
 	}
 }
 
+func TestLabeledLoop(t *testing.T) {
+	input := `workflow Foo(x: int) -> (Result):
+    outer: for (item in items):
+        for (part in item.parts):
+            continue outer
+            break
+`
+	file, err := ParseFile(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wf := file.Definitions[0].(*ast.WorkflowDef)
+	outer, ok := wf.Body[0].(*ast.ForStmt)
+	if !ok {
+		t.Fatalf("expected ForStmt, got %T", wf.Body[0])
+	}
+	if outer.Label == nil || outer.Label.Name != "outer" {
+		t.Fatalf("expected label 'outer', got %+v", outer.Label)
+	}
+	if outer.Label.Column != 5 || outer.Column != 12 {
+		t.Errorf("expected label at column 5 and for at column 12, got %d and %d", outer.Label.Column, outer.Column)
+	}
+	inner := outer.Body[0].(*ast.ForStmt)
+	if inner.Label != nil {
+		t.Errorf("expected unlabeled inner loop, got %q", inner.Label.Name)
+	}
+	cont := inner.Body[0].(*ast.ContinueStmt)
+	if cont.Label.Name != "outer" || cont.Label.Column != 22 {
+		t.Errorf("expected continue label 'outer' at column 22, got %q at %d", cont.Label.Name, cont.Label.Column)
+	}
+	if brk := inner.Body[1].(*ast.BreakStmt); brk.Label.Name != "" {
+		t.Errorf("expected unlabeled break, got %q", brk.Label.Name)
+	}
+}
+
+func TestLabelWithoutLoop(t *testing.T) {
+	input := `workflow Foo(x: int) -> (Result):
+    outer: activity A(x)
+`
+	_, err := ParseFile(input)
+	if err == nil || !strings.Contains(err.Error(), "label outer must be followed by a for loop") {
+		t.Fatalf("expected label error, got %v", err)
+	}
+}
+
+func TestConstDef(t *testing.T) {
+	input := `const approvalTimeout = 7d
+const region = "us-east"
+
+workflow Foo(x: int) -> (Result):
+    await timer(approvalTimeout)
+    activity Bar(x)
+        options:
+            start_to_close_timeout: approvalTimeout
+            retry_policy:
+                maximum_attempts: 3
+`
+	file, err := ParseFile(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c, ok := file.Definitions[0].(*ast.ConstDef)
+	if !ok {
+		t.Fatalf("expected ConstDef, got %T", file.Definitions[0])
+	}
+	if c.Name != "approvalTimeout" || c.Value != "7d" || c.ValueType != "duration" {
+		t.Errorf("unexpected const: %+v", c)
+	}
+	if s := file.Definitions[1].(*ast.ConstDef); s.Value != "us-east" || s.ValueType != "string" {
+		t.Errorf("expected unquoted string constant, got %q (%s)", s.Value, s.ValueType)
+	}
+
+	wf := file.Definitions[2].(*ast.WorkflowDef)
+	timer := wf.Body[0].(*ast.AwaitStmt).Target.(*ast.TimerTarget)
+	if timer.Const.Name != "approvalTimeout" || timer.Const.Column != 17 {
+		t.Errorf("expected timer const ref at column 17, got %q at %d", timer.Const.Name, timer.Const.Column)
+	}
+	entries := wf.Body[1].(*ast.ActivityCall).Options.Entries
+	if entries[0].Const.Name != "approvalTimeout" || entries[0].ValueType != "duration" {
+		t.Errorf("expected duration option naming a constant, got %+v", entries[0])
+	}
+	if entries[1].Nested[0].Const.Name != "" {
+		t.Errorf("expected literal nested value, got const %q", entries[1].Nested[0].Const.Name)
+	}
+}
+
+func TestConstDefErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"const x 7d\n", "expected = after constant name x"},
+		{"const x = other\n", "constant x must be a string, duration, number, or bool literal"},
+		{"const x = 1 + 2\n", "unexpected OPERATOR after constant value"},
+	}
+	for _, tt := range tests {
+		_, err := ParseFile(tt.input)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: expected %q, got %v", tt.input, tt.want, err)
+		}
+	}
+}
+
+func TestEnumDef(t *testing.T) {
+	input := `enum OrderType: invoice, refund, subscription
+
+workflow Foo(kind: OrderType):
+    close complete
+`
+	file, err := ParseFile(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	e, ok := file.Definitions[0].(*ast.EnumDef)
+	if !ok {
+		t.Fatalf("expected EnumDef, got %T", file.Definitions[0])
+	}
+	if e.Name != "OrderType" || len(e.Values) != 3 {
+		t.Fatalf("unexpected enum: %+v", e)
+	}
+	if v := e.Values[1]; v.Name != "refund" || v.Line != 1 || v.Column != 26 {
+		t.Errorf("expected refund at 1:26, got %s at %d:%d", v.Name, v.Line, v.Column)
+	}
+	if _, ok := file.Definitions[1].(*ast.WorkflowDef); !ok {
+		t.Errorf("expected WorkflowDef after enum, got %T", file.Definitions[1])
+	}
+}
+
+func TestEnumDefErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"enum OrderType invoice\n", "expected ':', got name \"invoice\""},
+		{"enum OrderType:\n", "expected value name in enum OrderType"},
+		{"enum OrderType: invoice,\n", "expected value name in enum OrderType"},
+		{"enum OrderType: invoice refund\n", "unexpected IDENT after enum values"},
+	}
+	for _, tt := range tests {
+		_, err := ParseFile(tt.input)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: expected %q, got %v", tt.input, tt.want, err)
+		}
+	}
+}
+
 func TestRawStmt(t *testing.T) {
 	input := `workflow Foo(x: int) -> (Result):
     order.status = "completed"
@@ -903,6 +1473,21 @@ func TestRawStmt(t *testing.T) {
 	}
 }
 
+func TestRawStmtPreservesDelimiters(t *testing.T) {
+	input := `workflow Foo(x: int) -> (Result):
+    notify(x, "a b") + "!"
+`
+	file, err := ParseFile(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wf := file.Definitions[0].(*ast.WorkflowDef)
+	raw := wf.Body[0].(*ast.RawStmt)
+	if want := `notify(x, "a b") + "!"`; raw.Text != want {
+		t.Errorf("expected raw text %q, got %q", want, raw.Text)
+	}
+}
+
 func TestComment(t *testing.T) {
 	input := `workflow Foo(x: int) -> (Result):
     # this is a comment
@@ -1207,6 +1792,71 @@ workflow Bad2
 	}
 }
 
+func TestParseFileAllUnparsedOptions(t *testing.T) {
+	// A bad entry keeps the block as raw text rather than losing the workflow.
+	input := `workflow Order():
+    activity Charge()
+        options:
+            retry_policy:
+                maximum_attempts = 3
+            start_to_close_timeout: 1m
+    activity Ship()
+    close complete
+
+activity Ship():
+    options:
+        start_to_close_timeout 1m
+    return
+`
+	file, errs := ParseFileAll(input)
+	if len(errs) != 2 || errs[0].Line != 5 || errs[1].Line != 12 {
+		t.Fatalf("expected errors on lines 5 and 12, got %v", errs)
+	}
+	if len(file.Definitions) != 2 {
+		t.Fatalf("expected both definitions, got %d", len(file.Definitions))
+	}
+	wf := file.Definitions[0].(*ast.WorkflowDef)
+	if len(wf.Body) != 3 {
+		t.Fatalf("expected 3 statements after the block, got %d", len(wf.Body))
+	}
+	call := wf.Body[0].(*ast.ActivityCall)
+	want := "retry_policy:\n    maximum_attempts = 3\nstart_to_close_timeout: 1m"
+	if !call.Options.Unparsed() || call.Options.RawText != want || len(call.Options.Entries) != 0 {
+		t.Errorf("expected the raw block %q, got %+v", want, call.Options)
+	}
+	act := file.Definitions[1].(*ast.ActivityDef)
+	if act.Options.RawText != "start_to_close_timeout 1m" || len(act.Body) != 1 {
+		t.Errorf("expected the definition's raw block and body, got %+v", act)
+	}
+
+	if _, err := ParseFile(input); err == nil || !strings.Contains(err.Error(), "5:34") {
+		t.Errorf("expected ParseFile to fail at the entry, got %v", err)
+	}
+}
+
+func TestParseFileAllRecoversFromFirstToken(t *testing.T) {
+	// Each of these fails on the keyword that starts the definition, so
+	// recovery has to skip it rather than stop there again.
+	for _, input := range []string{
+		"nexus foo\n",
+		"nexus serelsevice P:\n    async Op workflow W\n\nworkflow W():\n    close complete\n",
+	} {
+		done := make(chan []*ParseError, 1)
+		go func() {
+			_, errs := ParseFileAll(input)
+			done <- errs
+		}()
+		select {
+		case errs := <-done:
+			if len(errs) != 1 {
+				t.Errorf("%q: expected 1 error, got %v", input, errs)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%q: ParseFileAll did not return", input)
+		}
+	}
+}
+
 func TestParseFileAllCleanInput(t *testing.T) {
 	// Clean input should produce zero errors and a complete AST.
 	input := `workflow Foo(x: int) -> (Result):
@@ -1982,3 +2632,181 @@ workflow ProcessOrder(order: Order) -> (Result):
 		t.Errorf("expected value 'orders', got %q", ep.Options.Entries[0].Value)
 	}
 }
+
+func TestAnnotations(t *testing.T) {
+	input := `@owner("payments-team") @tag(critical)
+# SLA agreed with finance
+@sla(24h)
+workflow Charge(id: string):
+    activity Bill(id)
+
+@deprecated
+activity Bill(id: string):
+    return id
+
+worker w:
+    workflow Charge
+`
+	file, err := ParseFile(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wf := file.Definitions[0].(*ast.WorkflowDef)
+	if wf.Line != 4 {
+		t.Errorf("expected the workflow at line 4, got %d", wf.Line)
+	}
+	if len(wf.Annotations) != 3 {
+		t.Fatalf("expected 3 annotations, got %d", len(wf.Annotations))
+	}
+	owner, tag, sla := wf.Annotations[0], wf.Annotations[1], wf.Annotations[2]
+	if owner.Name != "owner" || owner.Args != `"payments-team"` || owner.Value() != "payments-team" {
+		t.Errorf("unexpected owner annotation: %+v", owner)
+	}
+	if tag.Name != "tag" || tag.Value() != "critical" || tag.Line != 1 || tag.Column != 25 {
+		t.Errorf("unexpected tag annotation: %+v", tag)
+	}
+	if sla.Name != "sla" || sla.Value() != "24h" || sla.Line != 3 {
+		t.Errorf("unexpected sla annotation: %+v", sla)
+	}
+
+	act := file.Definitions[1].(*ast.ActivityDef)
+	if len(act.Annotations) != 1 || act.Annotations[0].Name != "deprecated" || act.Annotations[0].Args != "" {
+		t.Errorf("unexpected activity annotations: %+v", act.Annotations)
+	}
+	if ast.Annotations(file.Definitions[2]) != nil {
+		t.Error("workers carry no annotations")
+	}
+}
+
+func TestAnnotationErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"@owner(\"x\")\nworker w:\n    workflow A\n", "annotations must precede a workflow or activity definition"},
+		{"@(x)\nworkflow A():\n    return\n", "expected a name, got '(x)'"},
+		{"@owner(x) workflow A():\n    return\n", "expected end of line, got 'workflow'"},
+	}
+	for _, tt := range tests {
+		_, err := ParseFile(tt.input)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: expected error containing %q, got %v", tt.input, tt.want, err)
+		}
+	}
+
+	// Collecting mode recovers at the next definition, annotated or not.
+	file, errs := ParseFileAll("@owner(x) activity\nworker w:\n    workflow A\n\n@tag(y)\nworkflow A():\n    return\n")
+	if len(errs) != 1 || len(file.Definitions) != 2 {
+		t.Fatalf("expected one error and two definitions, got %v and %d", errs, len(file.Definitions))
+	}
+	if wf := file.Definitions[1].(*ast.WorkflowDef); wf.Annotations[0].Name != "tag" {
+		t.Errorf("unexpected annotations: %+v", wf.Annotations)
+	}
+}
+
+func TestParseDefinition(t *testing.T) {
+	def, err := ParseDefinition(`
+# Snippet
+@owner("payments")
+activity Charge(id: string) -> (Receipt):
+    return receipt
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	act, ok := def.(*ast.ActivityDef)
+	if !ok || act.Name != "Charge" || act.Line != 4 || len(act.Annotations) != 1 {
+		t.Fatalf("unexpected definition: %+v", def)
+	}
+
+	def, err = ParseDefinition("workflow Order():\n    activity Charge(id)\n    close complete\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if wf := def.(*ast.WorkflowDef); len(wf.Body) != 2 {
+		t.Errorf("expected 2 statements, got %d", len(wf.Body))
+	}
+}
+
+func TestParseDefinitionErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"", "expected a workflow or activity definition, got EOF"},
+		{"worker w:\n    workflow A\n", "expected a workflow or activity definition, got WORKER"},
+		{"activity A():\n    return\nactivity B():\n    return\n", "unexpected ACTIVITY after the definition"},
+		{"activity A():\n    timer(5m)\n", "is not allowed in activity body"},
+	}
+	for _, tt := range tests {
+		_, err := ParseDefinition(tt.input)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: expected error containing %q, got %v", tt.input, tt.want, err)
+		}
+	}
+}
+
+// nestedIfs returns a workflow whose body nests n if blocks.
+func nestedIfs(name string, n int) string {
+	var b strings.Builder
+	b.WriteString("workflow " + name + "():\n")
+	for i := 1; i <= n; i++ {
+		b.WriteString(strings.Repeat("    ", i) + "if (x):\n")
+	}
+	b.WriteString(strings.Repeat("    ", n+1) + "return\n")
+	return b.String()
+}
+
+func TestLimits(t *testing.T) {
+	ok := "activity A():\n    return\n\n"
+	tests := []struct {
+		name   string
+		limits Limits
+		input  string
+		want   string
+		line   int
+	}{
+		{"bytes", Limits{MaxBytes: 10}, ok, "input is 26 bytes, more than the limit of 10", 1},
+		{"depth", Limits{MaxDepth: 3}, ok + nestedIfs("W", 3), "blocks nested more than 3 levels deep", 8},
+		{"definitions", Limits{MaxDefinitions: 1}, ok + ok, "more than 1 top-level definitions", 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.limits.ParseFile(tt.input); err == nil || err.(*ParseError).Msg != tt.want || err.(*ParseError).Line != tt.line {
+				t.Errorf("ParseFile: expected %q at line %d, got %v", tt.want, tt.line, err)
+			}
+			file, errs := tt.limits.ParseFileAll(tt.input)
+			if len(errs) != 1 || errs[0].Msg != tt.want {
+				t.Fatalf("ParseFileAll: expected only %q, got %v", tt.want, errs)
+			}
+			if tt.name != "bytes" && len(file.Definitions) != 1 {
+				t.Errorf("ParseFileAll: expected the definition before the limit, got %d", len(file.Definitions))
+			}
+		})
+	}
+
+	if _, err := (Limits{MaxDepth: 3}).ParseFile(nestedIfs("W", 2)); err != nil {
+		t.Errorf("expected nesting at the limit to parse, got %v", err)
+	}
+}
+
+func TestLimitsExpressionDepth(t *testing.T) {
+	deep := strings.Repeat("[", 200) + strings.Repeat("]", 200)
+	if _, err := ParseExpr(deep); err == nil || !strings.Contains(err.Error(), "expression nested more than 100 levels deep") {
+		t.Errorf("expected the expression depth limit, got %v", err)
+	}
+
+	// Condition expressions are best-effort, so a deep one is left opaque.
+	file, err := ParseFile("workflow W():\n    if (" + deep + "):\n        return\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cond := file.Definitions[0].(*ast.WorkflowDef).Body[0].(*ast.IfStmt).CondExpr; cond != nil {
+		t.Errorf("expected no condition expression, got %T", cond)
+	}
+
+	// Far past the limit, parsing stops instead of overflowing the stack.
+	if _, err := ParseExpr(strings.Repeat("-", 1_000_000) + "x"); err == nil {
+		t.Error("expected the expression depth limit")
+	}
+}
@@ -1,6 +1,10 @@
 package parser
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+
 	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
 	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/token"
 )
@@ -78,7 +82,11 @@ func parseTimerTarget(p *Parser) (*ast.TimerTarget, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &ast.TimerTarget{Duration: duration.Literal}, nil
+	t := &ast.TimerTarget{Duration: duration.Literal}
+	if id, ok := p.parseArgsExpr(duration).(*ast.Ident); ok {
+		t.Const = ast.Ref[*ast.ConstDef]{Pos: id.Pos, Name: id.Name}
+	}
+	return t, nil
 }
 
 func parseSignalTarget(p *Parser, allowArrows bool) (*ast.SignalTarget, error) {
@@ -127,14 +135,12 @@ func parseActivityTarget(p *Parser, allowArrows bool) (*ast.ActivityTarget, erro
 	if err != nil {
 		return nil, err
 	}
-	t := &ast.ActivityTarget{Activity: ast.Ref[*ast.ActivityDef]{Name: name.Literal}, Args: args.Literal}
+	t := &ast.ActivityTarget{Activity: ast.Ref[*ast.ActivityDef]{Name: name.Literal}, Args: args.Literal, ArgsPos: argsPos(args), ArgExprs: p.parseArgExprs(args)}
 	if allowArrows && p.current.Type == token.ARROW {
 		p.advance()
-		result, err := p.expect(token.IDENT)
-		if err != nil {
+		if t.Result, err = parseParamBinding(p); err != nil {
 			return nil, err
 		}
-		t.Result = result.Literal
 	}
 	return t, nil
 }
@@ -168,14 +174,14 @@ func parseWorkflowOrNexusTarget(p *Parser, allowArrows bool, pos ast.Pos) (ast.A
 		Workflow: ast.Ref[*ast.WorkflowDef]{Name: name.Literal},
 		Mode:     mode,
 		Args:     args.Literal,
+		ArgsPos:  argsPos(args),
+		ArgExprs: p.parseArgExprs(args),
 	}
 	if allowArrows && p.current.Type == token.ARROW {
 		p.advance()
-		result, err := p.expect(token.IDENT)
-		if err != nil {
+		if t.Result, err = parseParamBinding(p); err != nil {
 			return nil, err
 		}
-		t.Result = result.Literal
 	}
 	if mode == ast.CallDetach && t.Result != "" {
 		return nil, &ParseError{
@@ -187,7 +193,7 @@ func parseWorkflowOrNexusTarget(p *Parser, allowArrows bool, pos ast.Pos) (ast.A
 	return t, nil
 }
 
-// parseNexusTarget parses: NEXUS IDENT IDENT DOT IDENT ARGS [ ARROW IDENT ]
+// parseNexusTarget parses: NEXUS IDENT IDENT DOT IDENT ARGS [ ARROW binding ]
 // The detach parameter controls whether Detach is set on the result.
 // When detach is true and a result arrow is present, an error is returned.
 func parseNexusTarget(p *Parser, detach, allowArrows bool, pos ast.Pos) (*ast.NexusTarget, error) {
@@ -216,15 +222,15 @@ func parseNexusTarget(p *Parser, detach, allowArrows bool, pos ast.Pos) (*ast.Ne
 		Service:   ast.Ref[*ast.NexusServiceDef]{Pos: ast.Pos{Line: service.Line, Column: service.Column}, Name: service.Literal},
 		Operation: ast.Ref[*ast.NexusOperation]{Pos: ast.Pos{Line: operation.Line, Column: operation.Column}, Name: operation.Literal},
 		Args:      args.Literal,
+		ArgsPos:   argsPos(args),
+		ArgExprs:  p.parseArgExprs(args),
 		Detach:    detach,
 	}
 	if allowArrows && p.current.Type == token.ARROW {
 		p.advance()
-		result, err := p.expect(token.IDENT)
-		if err != nil {
+		if t.Result, err = parseParamBinding(p); err != nil {
 			return nil, err
 		}
-		t.Result = result.Literal
 	}
 	if t.Detach && t.Result != "" {
 		return nil, &ParseError{
@@ -250,8 +256,9 @@ func parseIdentTarget(p *Parser, allowArrows bool) (*ast.IdentTarget, error) {
 	return t, nil
 }
 
-// parseParamBinding parses parameter binding after ARROW:
-// either IDENT (single param) or ARGS (multiple params in parens)
+// parseParamBinding parses a binding after ARROW: either IDENT (a single
+// param or result) or ARGS (multiple params, or the values of a multi-value
+// return, in parens).
 func parseParamBinding(p *Parser) (string, error) {
 	if p.current.Type == token.IDENT {
 		result := p.current.Literal
@@ -265,11 +272,24 @@ func parseParamBinding(p *Parser) (string, error) {
 	return "", p.errorf("expected identifier or ( after ->, got %s", p.current.Type)
 }
 
-// parseAwaitAllBlock parses: ALL COLON NEWLINE INDENT workflow_body DEDENT
+// parseAwaitAllBlock parses: ALL [OPTIONS ARGS] COLON NEWLINE INDENT workflow_body DEDENT
 func parseAwaitAllBlock(p *Parser) (ast.Statement, error) {
 	pos := ast.Pos{Line: p.current.Line, Column: p.current.Column}
 	p.advance() // consume ALL
 
+	var opts *ast.AwaitAllOptions
+	if p.current.Type == token.OPTIONS {
+		p.advance() // consume OPTIONS
+		if p.current.Type != token.ARGS {
+			return nil, p.errorf("expected ( after await all options, got %s", p.current.Type)
+		}
+		var err error
+		if opts, err = parseAwaitAllOptions(p.current); err != nil {
+			return nil, err
+		}
+		p.advance()
+	}
+
 	if _, err := p.expect(token.COLON); err != nil {
 		return nil, err
 	}
@@ -286,11 +306,59 @@ func parseAwaitAllBlock(p *Parser) (ast.Statement, error) {
 	}
 
 	return &ast.AwaitAllBlock{
-		Pos:  pos,
-		Body: body,
+		Pos:     pos,
+		Options: opts,
+		Body:    body,
 	}, nil
 }
 
+// parseAwaitAllOptions parses the "key: value" pairs in the parentheses of
+// an await all options clause: onError (fail or continue) and minSuccess (a
+// positive count, only with onError: continue).
+func parseAwaitAllOptions(args token.Token) (*ast.AwaitAllOptions, error) {
+	opts := &ast.AwaitAllOptions{
+		Pos:     ast.Pos{Line: args.Line, Column: args.Column},
+		OnError: ast.AwaitAllFail,
+	}
+	fail := func(format string, a ...any) error {
+		return &ParseError{Msg: fmt.Sprintf(format, a...), Line: args.Line, Column: args.Column}
+	}
+	seen := make(map[string]bool)
+	for _, entry := range ast.SplitList(args.Literal) {
+		key, value, ok := strings.Cut(entry, ":")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok || value == "" {
+			return nil, fail("expected key: value in await all options, got %q", entry)
+		}
+		if seen[key] {
+			return nil, fail("await all option %s is set more than once", key)
+		}
+		seen[key] = true
+		switch key {
+		case "onError":
+			if value != ast.AwaitAllFail && value != ast.AwaitAllContinue {
+				return nil, fail("await all option onError must be %s or %s, got %s", ast.AwaitAllFail, ast.AwaitAllContinue, value)
+			}
+			opts.OnError = value
+		case "minSuccess":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fail("await all option minSuccess must be a positive count, got %s", value)
+			}
+			opts.MinSuccess = n
+		default:
+			return nil, fail("unknown await all option %s; expected onError or minSuccess", key)
+		}
+	}
+	if len(seen) == 0 {
+		return nil, fail("await all options must set onError or minSuccess")
+	}
+	if opts.MinSuccess > 0 && opts.OnError != ast.AwaitAllContinue {
+		return nil, fail("await all option minSuccess requires onError: continue")
+	}
+	return opts, nil
+}
+
 // parseAwaitOneBlock parses: ONE COLON NEWLINE INDENT { await_one_case } DEDENT
 func parseAwaitOneBlock(p *Parser) (ast.Statement, error) {
 	pos := ast.Pos{Line: p.current.Line, Column: p.current.Column}
@@ -332,8 +400,9 @@ func parseAwaitOneBlock(p *Parser) (ast.Statement, error) {
 
 // parseAwaitOneCase parses a single await one case.
 // Supports: signal Name [-> params]:, update Name [-> params]:,
-// timer(duration):, activity Name(args) [-> result]:,
-// workflow Name(args) [-> result]:, or await all:
+// timer(duration) [-> firedAt]:, activity Name(args) [-> result]:,
+// workflow Name(args) [-> result]:, ident [-> result]:, or await all:
+// Target cases may carry a guard before the colon: signal Approve if (amount < 1000):
 // Case bodies are optional (can be empty after colon).
 func parseAwaitOneCase(p *Parser) (*ast.AwaitOneCase, error) {
 	pos := ast.Pos{Line: p.current.Line, Column: p.current.Column}
@@ -359,6 +428,29 @@ func parseAwaitOneCase(p *Parser) (*ast.AwaitOneCase, error) {
 	}
 	c.Target = target
 
+	// Only a case binds the time its timer fired.
+	if timer, ok := target.(*ast.TimerTarget); ok && p.current.Type == token.ARROW {
+		p.advance()
+		result, err := p.expect(token.IDENT)
+		if err != nil {
+			return nil, err
+		}
+		timer.Result = result.Literal
+	}
+
+	if p.current.Type == token.IF {
+		p.advance() // consume IF
+		guard, err := p.expect(token.ARGS)
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(guard.Literal) == "" {
+			return nil, &ParseError{Msg: "empty guard: write the condition the case waits on, as in if (ready), or drop the if", Line: guard.Line, Column: guard.Column}
+		}
+		c.Guard = guard.Literal
+		c.GuardExpr = p.parseArgsExpr(guard)
+	}
+
 	// All target cases (not await all) need colon + optional body
 	if _, err := p.expect(token.COLON); err != nil {
 		return nil, err
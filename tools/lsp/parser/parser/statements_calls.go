@@ -7,14 +7,19 @@ import (
 
 // callParts holds the shared parsed components of an activity or workflow call.
 type callParts struct {
-	pos     ast.Pos
-	name    string
-	args    string
-	result  string
-	options *ast.OptionsBlock
+	pos      ast.Pos
+	name     string
+	args     string
+	argsPos  ast.Pos
+	argExprs []ast.Expr
+	id       string // workflow calls only
+	result   string
+	options  *ast.OptionsBlock
 }
 
-// parseCallParts parses the shared IDENT ARGS [ ARROW IDENT ] NEWLINE [ options ] pattern.
+// parseCallParts parses the shared IDENT ARGS [ ARROW binding ] NEWLINE [ options ] pattern,
+// where the binding is one name or several in parens for a multi-value return.
+// Workflow calls may also give a workflow ID after ARGS.
 func parseCallParts(p *Parser, optCtx OptionsContext) (*callParts, error) {
 	pos := ast.Pos{Line: p.current.Line, Column: p.current.Column}
 	p.advance() // consume keyword
@@ -29,14 +34,19 @@ func parseCallParts(p *Parser, optCtx OptionsContext) (*callParts, error) {
 		return nil, err
 	}
 
+	var id string
+	if optCtx == OptionsContextWorkflow {
+		if id, err = parseWorkflowID(p); err != nil {
+			return nil, err
+		}
+	}
+
 	var result string
 	if p.current.Type == token.ARROW {
 		p.advance()
-		res, err := p.expect(token.IDENT)
-		if err != nil {
+		if result, err = parseParamBinding(p); err != nil {
 			return nil, err
 		}
-		result = res.Literal
 	}
 
 	if p.current.Type == token.NEWLINE {
@@ -48,10 +58,25 @@ func parseCallParts(p *Parser, optCtx OptionsContext) (*callParts, error) {
 		return nil, err
 	}
 
-	return &callParts{pos: pos, name: name.Literal, args: args.Literal, result: result, options: options}, nil
+	return &callParts{pos: pos, name: name.Literal, args: args.Literal, argsPos: argsPos(args), argExprs: p.parseArgExprs(args), id: id, result: result, options: options}, nil
+}
+
+// parseWorkflowID parses the optional workflow ID of a workflow call:
+// "id" STRING. The id keyword is contextual, so "id" remains a valid name
+// elsewhere.
+func parseWorkflowID(p *Parser) (string, error) {
+	if p.current.Type != token.IDENT || p.current.Literal != "id" {
+		return "", nil
+	}
+	p.advance() // consume id
+	id, err := p.expect(token.STRING)
+	if err != nil {
+		return "", err
+	}
+	return id.Literal, nil
 }
 
-// parseActivityCall parses: ACTIVITY IDENT ARGS [ ARROW IDENT ] NEWLINE [ options_line ]
+// parseActivityCall parses: ACTIVITY IDENT ARGS [ ARROW binding ] NEWLINE [ options_line ]
 func parseActivityCall(p *Parser) (ast.Statement, error) {
 	cp, err := parseCallParts(p, OptionsContextActivity)
 	if err != nil {
@@ -61,12 +86,14 @@ func parseActivityCall(p *Parser) (ast.Statement, error) {
 		Pos:      cp.pos,
 		Activity: ast.Ref[*ast.ActivityDef]{Pos: cp.pos, Name: cp.name},
 		Args:     cp.args,
+		ArgsPos:  cp.argsPos,
+		ArgExprs: cp.argExprs,
 		Result:   cp.result,
 		Options:  cp.options,
 	}, nil
 }
 
-// parseWorkflowCall parses: WORKFLOW IDENT ARGS [ ARROW IDENT ] NEWLINE [ options_line ]
+// parseWorkflowCall parses: WORKFLOW IDENT ARGS [ "id" STRING ] [ ARROW binding ] NEWLINE [ options_line ]
 func parseWorkflowCall(p *Parser) (ast.Statement, error) {
 	cp, err := parseCallParts(p, OptionsContextWorkflow)
 	if err != nil {
@@ -77,6 +104,9 @@ func parseWorkflowCall(p *Parser) (ast.Statement, error) {
 		Mode:     ast.CallChild,
 		Workflow: ast.Ref[*ast.WorkflowDef]{Pos: cp.pos, Name: cp.name},
 		Args:     cp.args,
+		ArgsPos:  cp.argsPos,
+		ArgExprs: cp.argExprs,
+		ID:       cp.id,
 		Result:   cp.result,
 		Options:  cp.options,
 	}, nil
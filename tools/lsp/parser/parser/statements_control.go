@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
@@ -55,13 +56,14 @@ func parseSwitchBlock(p *Parser) (ast.Statement, error) {
 		}
 
 		if p.current.Type != token.CASE {
-			return nil, p.errorf("expected case or else in switch, got %s", p.current.Type)
+			return nil, p.unexpected(token.CASE, token.ELSE)
 		}
 
 		casePos := ast.Pos{Line: p.current.Line, Column: p.current.Column}
 		p.advance() // consume CASE
 
 		// Collect the case value expression until COLON.
+		valueLine, valueColumn := p.current.Line, p.current.Column
 		value := p.collectRawUntil(token.COLON)
 
 		if _, err := p.expect(token.COLON); err != nil {
@@ -80,9 +82,10 @@ func parseSwitchBlock(p *Parser) (ast.Statement, error) {
 		}
 
 		cases = append(cases, &ast.SwitchCase{
-			Pos:   casePos,
-			Value: value,
-			Body:  body,
+			Pos:       casePos,
+			Value:     value,
+			ValueExpr: p.parseInlineExpr(value, valueLine, valueColumn),
+			Body:      body,
 		})
 	}
 
@@ -99,17 +102,20 @@ func parseSwitchBlock(p *Parser) (ast.Statement, error) {
 	}
 
 	return &ast.SwitchBlock{
-		Pos:     pos,
-		Expr:    expr.Literal,
-		Cases:   cases,
-		Default: defaultBody,
+		Pos:         pos,
+		Expr:        expr.Literal,
+		SubjectExpr: p.parseArgsExpr(expr),
+		Cases:       cases,
+		Default:     defaultBody,
 	}, nil
 }
 
-// parseIfStmt parses: IF ARGS COLON NEWLINE INDENT body DEDENT [ ELSE COLON NEWLINE INDENT body DEDENT ]
+// parseIfStmt parses: IF ARGS COLON NEWLINE INDENT body DEDENT [ else_clause ]
+// where else_clause is ( ELIF | ELSE IF ) if_rest, or ELSE COLON NEWLINE INDENT body DEDENT.
+// An elif clause is represented as an ElseBody holding a single chained IfStmt.
 func parseIfStmt(p *Parser) (ast.Statement, error) {
 	pos := ast.Pos{Line: p.current.Line, Column: p.current.Column}
-	p.advance() // consume IF
+	p.advance() // consume IF (or ELIF when parsing a chained clause)
 
 	cond, err := p.expect(token.ARGS)
 	if err != nil {
@@ -132,7 +138,18 @@ func parseIfStmt(p *Parser) (ast.Statement, error) {
 	}
 
 	var elseBody []ast.Statement
-	if p.current.Type == token.ELSE {
+	var elseIf bool
+	if p.current.Type == token.ELIF || (p.current.Type == token.ELSE && p.peek.Type == token.IF) {
+		if p.current.Type == token.ELSE {
+			p.advance() // consume ELSE; IF is consumed by the chained parse
+		}
+		chained, err := parseIfStmt(p)
+		if err != nil {
+			return nil, err
+		}
+		elseBody = []ast.Statement{chained}
+		elseIf = true
+	} else if p.current.Type == token.ELSE {
 		p.advance()
 		if _, err := p.expect(token.COLON); err != nil {
 			return nil, err
@@ -152,12 +169,34 @@ func parseIfStmt(p *Parser) (ast.Statement, error) {
 	return &ast.IfStmt{
 		Pos:       pos,
 		Condition: cond.Literal,
+		CondExpr:  p.parseArgsExpr(cond),
 		Body:      body,
 		ElseBody:  elseBody,
+		ElseIf:    elseIf,
 	}, nil
 }
 
-// parseForStmt parses: FOR [ ARGS ] COLON NEWLINE INDENT body DEDENT
+// parseLabeledForStmt parses: IDENT COLON for_stmt
+func parseLabeledForStmt(p *Parser) (ast.Statement, error) {
+	label := &ast.LoopLabel{
+		Pos:  ast.Pos{Line: p.current.Line, Column: p.current.Column},
+		Name: p.current.Literal,
+	}
+	p.advance() // consume IDENT
+	p.advance() // consume COLON
+
+	if p.current.Type != token.FOR {
+		return nil, p.errorf("label %s must be followed by a for loop, got %s", label.Name, p.current.Type)
+	}
+	stmt, err := parseForStmt(p)
+	if err != nil {
+		return nil, err
+	}
+	stmt.(*ast.ForStmt).Label = label
+	return stmt, nil
+}
+
+// parseForStmt parses: FOR [ ARGS | "each" ARGS "parallel" ARGS ] COLON NEWLINE INDENT body DEDENT
 func parseForStmt(p *Parser) (ast.Statement, error) {
 	pos := ast.Pos{Line: p.current.Line, Column: p.current.Column}
 	p.advance() // consume FOR
@@ -167,32 +206,29 @@ func parseForStmt(p *Parser) (ast.Statement, error) {
 	if p.current.Type == token.COLON {
 		// Infinite loop: for:
 		stmt.Variant = ast.ForInfinite
+	} else if p.current.Type == token.IDENT && p.current.Literal == "each" {
+		// Bounded fan-out: for each (var in collection) parallel(max: N):
+		if err := parseParallelFor(p, stmt); err != nil {
+			return nil, err
+		}
 	} else if p.current.Type == token.ARGS {
-		content := p.current.Literal
+		args := p.current
+		content := args.Literal
 		p.advance()
 
-		// Check for "in" keyword using strings.Fields to find standalone word.
-		fields := strings.Fields(content)
-		inIdx := -1
-		for i, f := range fields {
-			if f == "in" {
-				inIdx = i
-				break
-			}
-		}
-
-		if inIdx > 0 {
+		if variable, iterable, ok := splitIteration(content); ok {
 			// Iteration: for (var in collection):
 			stmt.Variant = ast.ForIteration
-			stmt.Variable = strings.Join(fields[:inIdx], " ")
-			stmt.Iterable = strings.Join(fields[inIdx+1:], " ")
+			stmt.Variable = variable
+			stmt.Iterable = iterable
 		} else {
 			// Conditional: for (condition):
 			stmt.Variant = ast.ForConditional
 			stmt.Condition = content
+			stmt.CondExpr = p.parseArgsExpr(args)
 		}
 	} else {
-		return nil, p.errorf("expected ( or : after for, got %s", p.current.Type)
+		return nil, p.unexpected(token.ARGS, token.COLON)
 	}
 
 	if _, err := p.expect(token.COLON); err != nil {
@@ -213,3 +249,49 @@ func parseForStmt(p *Parser) (ast.Statement, error) {
 
 	return stmt, nil
 }
+
+// parseParallelFor parses the header of a bounded fan-out loop after FOR:
+// "each" ARGS "parallel" ARGS, where the first ARGS is (var in collection)
+// and the second is (max: N). each and parallel are contextual keywords.
+// The limit is kept as written; the validator checks it.
+func parseParallelFor(p *Parser, stmt *ast.ForStmt) error {
+	p.advance() // consume each
+	iter, err := p.expect(token.ARGS)
+	if err != nil {
+		return err
+	}
+	variable, iterable, ok := splitIteration(iter.Literal)
+	if !ok {
+		return &ParseError{Msg: fmt.Sprintf("expected (var in collection) after for each, got (%s)", iter.Literal), Line: iter.Line, Column: iter.Column}
+	}
+	if p.current.Type != token.IDENT || p.current.Literal != "parallel" {
+		return p.errorf("expected parallel(max: N) after for each (%s), got %s", iter.Literal, p.current.Type)
+	}
+	p.advance() // consume parallel
+	limit, err := p.expect(token.ARGS)
+	if err != nil {
+		return err
+	}
+	key, value, ok := strings.Cut(limit.Literal, ":")
+	if !ok || strings.TrimSpace(key) != "max" || strings.TrimSpace(value) == "" {
+		return &ParseError{Msg: fmt.Sprintf("expected parallel(max: N), got parallel(%s)", limit.Literal), Line: limit.Line, Column: limit.Column}
+	}
+
+	stmt.Variant = ast.ForParallel
+	stmt.Variable = variable
+	stmt.Iterable = iterable
+	stmt.Concurrency = strings.TrimSpace(value)
+	return nil
+}
+
+// splitIteration splits the content of (var in collection) at the
+// standalone word "in", reporting false when there is none.
+func splitIteration(content string) (variable, iterable string, ok bool) {
+	fields := strings.Fields(content)
+	for i, f := range fields {
+		if f == "in" && i > 0 {
+			return strings.Join(fields[:i], " "), strings.Join(fields[i+1:], " "), true
+		}
+	}
+	return "", "", false
+}
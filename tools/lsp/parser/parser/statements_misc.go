@@ -50,9 +50,15 @@ func parseReturnStmt(p *Parser) (ast.Statement, error) {
 	pos := ast.Pos{Line: p.current.Line, Column: p.current.Column}
 	p.advance() // consume RETURN
 
+	// A trailing comment is a statement of its own, not part of the value.
 	var value string
-	if p.current.Type != token.NEWLINE && p.current.Type != token.DEDENT && p.current.Type != token.EOF {
-		value = p.collectRawUntil(token.NEWLINE)
+	switch p.current.Type {
+	case token.NEWLINE, token.DEDENT, token.EOF, token.COMMENT:
+	default:
+		value = p.collectRawUntil(token.NEWLINE, token.COMMENT)
+		if p.current.Type == token.ILLEGAL {
+			return nil, p.errorf("unexpected %s", p.current.Literal)
+		}
 	}
 
 	if p.current.Type == token.NEWLINE {
@@ -82,12 +88,16 @@ func parseCloseStmt(p *Parser) (ast.Statement, error) {
 		reason = ast.CloseContinueAsNew
 		p.advance()
 	default:
-		return nil, p.errorf("expected complete, fail, or continue_as_new after 'close', got %s", p.current.Type)
+		return nil, p.unexpected(token.COMPLETE, token.FAIL, token.CONTINUE_AS_NEW)
 	}
 
 	var args string
+	var argsStart ast.Pos
+	var argExprs []ast.Expr
 	if p.current.Type == token.ARGS {
 		args = p.current.Literal
+		argsStart = argsPos(p.current)
+		argExprs = p.parseArgExprs(p.current)
 		p.advance()
 	}
 
@@ -96,40 +106,62 @@ func parseCloseStmt(p *Parser) (ast.Statement, error) {
 	}
 
 	return &ast.CloseStmt{
-		Pos:    pos,
-		Reason: reason,
-		Args:   args,
+		Pos:      pos,
+		Reason:   reason,
+		Args:     args,
+		ArgsPos:  argsStart,
+		ArgExprs: argExprs,
 	}, nil
 }
 
-// parseBreakStmt parses: BREAK NEWLINE
+// parseBreakStmt parses: BREAK [ IDENT ] NEWLINE
 func parseBreakStmt(p *Parser) (ast.Statement, error) {
 	pos := ast.Pos{Line: p.current.Line, Column: p.current.Column}
 	p.advance() // consume BREAK
 
+	label := parseOptionalLoopLabelRef(p)
+
 	if p.current.Type == token.NEWLINE {
 		p.advance()
 	}
 
-	return &ast.BreakStmt{Pos: pos}, nil
+	return &ast.BreakStmt{Pos: pos, Label: label}, nil
 }
 
-// parseContinueStmt parses: CONTINUE NEWLINE
+// parseContinueStmt parses: CONTINUE [ IDENT ] NEWLINE
 func parseContinueStmt(p *Parser) (ast.Statement, error) {
 	pos := ast.Pos{Line: p.current.Line, Column: p.current.Column}
 	p.advance() // consume CONTINUE
 
+	label := parseOptionalLoopLabelRef(p)
+
 	if p.current.Type == token.NEWLINE {
 		p.advance()
 	}
 
-	return &ast.ContinueStmt{Pos: pos}, nil
+	return &ast.ContinueStmt{Pos: pos, Label: label}, nil
+}
+
+// parseOptionalLoopLabelRef parses the label after break or continue, if any.
+func parseOptionalLoopLabelRef(p *Parser) ast.Ref[*ast.ForStmt] {
+	if p.current.Type != token.IDENT {
+		return ast.Ref[*ast.ForStmt]{}
+	}
+	ref := ast.Ref[*ast.ForStmt]{
+		Pos:  ast.Pos{Line: p.current.Line, Column: p.current.Column},
+		Name: p.current.Literal,
+	}
+	p.advance()
+	return ref
 }
 
 // parseRawStmt captures the rest of the line as a raw statement.
 func parseRawStmt(p *Parser) (ast.Statement, error) {
 	pos := ast.Pos{Line: p.current.Line, Column: p.current.Column}
 	text := p.collectRawUntil(token.NEWLINE)
+	if p.current.Type == token.ILLEGAL {
+		return nil, p.errorf("unexpected %s", p.current.Literal)
+	}
 
 	if p.current.Type == token.NEWLINE {
 		p.advance()
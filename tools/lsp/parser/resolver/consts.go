@@ -0,0 +1,110 @@
+package resolver
+
+import (
+	"fmt"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+)
+
+// resolveConsts links timer durations and scalar option values in def that
+// name a top-level constant, then inlines the constant's literal so later
+// stages see the same value they would for a literal written in place.
+func resolveConsts(def ast.Definition, consts map[string]*ast.ConstDef, errs *[]*ResolveError) {
+	body := func(stmts []ast.Statement) {
+		ast.WalkStatements(stmts, func(s ast.Statement) bool {
+			switch s := s.(type) {
+			case *ast.ActivityCall:
+				resolveOptionConsts(s.Options, consts, errs)
+			case *ast.WorkflowCall:
+				resolveOptionConsts(s.Options, consts, errs)
+			case *ast.NexusCall:
+				resolveOptionConsts(s.Options, consts, errs)
+			}
+			return true
+		}, ast.WithAsyncTargets(func(target ast.AsyncTarget, _ ast.Statement) bool {
+			if t, ok := target.(*ast.TimerTarget); ok && t.Const.Name != "" {
+				if consts[t.Const.Name] == nil {
+					// Not a constant: a duration variable, kept as written.
+					t.Const = ast.Ref[*ast.ConstDef]{}
+				} else if resolveConst(&t.Const, "duration", consts, errs) {
+					t.Duration = t.Const.Resolved.Value
+				}
+			}
+			return true
+		}))
+	}
+
+	switch d := def.(type) {
+	case *ast.WorkflowDef:
+		resolveOptionConsts(d.Options, consts, errs)
+		for _, s := range d.Signals {
+			body(s.Body)
+		}
+		for _, q := range d.Queries {
+			body(q.Body)
+		}
+		for _, u := range d.Updates {
+			body(u.Body)
+		}
+		body(d.Body)
+	case *ast.ActivityDef:
+		resolveOptionConsts(d.Options, consts, errs)
+		body(d.Body)
+	case *ast.NexusServiceDef:
+		for _, op := range d.Operations {
+			body(op.Body)
+		}
+	case *ast.NamespaceDef:
+		for _, w := range d.Workers {
+			resolveOptionConsts(w.Options, consts, errs)
+		}
+		for _, ep := range d.Endpoints {
+			resolveOptionConsts(ep.Options, consts, errs)
+		}
+	}
+}
+
+// resolveOptionConsts resolves constant references in an options block,
+// including nested blocks.
+func resolveOptionConsts(ob *ast.OptionsBlock, consts map[string]*ast.ConstDef, errs *[]*ResolveError) {
+	if ob == nil {
+		return
+	}
+	var walk func([]*ast.OptionEntry)
+	walk = func(entries []*ast.OptionEntry) {
+		for _, e := range entries {
+			switch {
+			case e.Const.Name == "":
+			case consts[e.Const.Name] == nil:
+				// Not a constant: an identifier such as a workflow
+				// variable, kept as the opaque value it was before
+				// constants.
+				e.Const = ast.Ref[*ast.ConstDef]{}
+				e.ValueType = "enum"
+			case resolveConst(&e.Const, e.ValueType, consts, errs):
+				e.Value = e.Const.Resolved.Value
+			}
+			walk(e.Nested)
+		}
+	}
+	walk(ob.Entries)
+}
+
+// resolveConst resolves a constant reference and checks that the constant's
+// literal has the type the use site expects. It reports whether the
+// reference may be inlined.
+func resolveConst(ref *ast.Ref[*ast.ConstDef], want string, consts map[string]*ast.ConstDef, errs *[]*ResolveError) bool {
+	def := consts[ref.Name]
+	ref.Resolved = def
+	if def.ValueType != want {
+		*errs = append(*errs, &ResolveError{
+			Msg:    fmt.Sprintf("constant %s is a %s, expected %s", ref.Name, def.ValueType, want),
+			Line:   ref.Line,
+			Column: ref.Column,
+			Kind:   ErrConstType,
+			Name:   ref.Name,
+		})
+		return false
+	}
+	return true
+}
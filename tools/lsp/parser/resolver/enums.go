@@ -0,0 +1,24 @@
+package resolver
+
+import (
+	"fmt"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+)
+
+// checkEnumValues reports values an enum lists more than once.
+func checkEnumValues(def *ast.EnumDef, errs *[]*ResolveError) {
+	seen := make(map[string]bool, len(def.Values))
+	for _, v := range def.Values {
+		if seen[v.Name] {
+			*errs = append(*errs, &ResolveError{
+				Msg:    fmt.Sprintf("enum %s lists value %s more than once", def.Name, v.Name),
+				Line:   v.Line,
+				Column: v.Column,
+				Kind:   ErrDuplicateEnumValue,
+				Name:   def.Name,
+			})
+		}
+		seen[v.Name] = true
+	}
+}
@@ -0,0 +1,74 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+)
+
+// ResolveWith is ResolveContext for a file that is one of several in scope,
+// such as the files of a workspace folder. References in file may resolve to
+// the external definitions of the other files, which are collected into the
+// symbol tables but neither resolved nor reported on: Errors and the
+// reference index cover file alone. A name defined both in file and
+// externally is a duplicate reported at file's definition, as is an endpoint
+// name also defined by an external namespace.
+//
+// External definitions are only read, provided their files have been through
+// CollectSymbols or a resolution, so they may be shared by concurrent calls.
+// With no external definitions, ResolveWith is ResolveContext.
+func ResolveWith(ctx context.Context, file *ast.File, external []ast.Definition) (*ResolveResult, error) {
+	if len(external) == 0 {
+		return ResolveContext(ctx, file)
+	}
+
+	r := &ResolveResult{defErrs: make(map[ast.Definition][]*ResolveError)}
+	t := newSymbolTable()
+	var discard []*ResolveError
+	t.collectDefinitions(external, &discard)
+	t.collectDefinitions(file.Definitions, &r.dupErrs)
+	collectEndpoints(t.Namespaces, t.Endpoints, &discard)
+	for _, def := range file.Definitions {
+		if ns, ok := def.(*ast.NamespaceDef); ok {
+			r.dupErrs = append(r.dupErrs, sharedEndpoints(ns, t.Namespaces)...)
+		}
+	}
+	r.Symbols = t
+
+	for _, def := range file.Definitions {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		r.resolveDefinition(def)
+	}
+	r.collate(file)
+	return r, nil
+}
+
+// sharedEndpoints returns a duplicate error for each endpoint of ns whose
+// name another namespace also defines, naming the first such namespace by
+// name.
+func sharedEndpoints(ns *ast.NamespaceDef, namespaces map[string]*ast.NamespaceDef) []*ResolveError {
+	var errs []*ResolveError
+	names := slices.Sorted(maps.Keys(namespaces))
+	for _, ep := range ns.Endpoints {
+		for _, name := range names {
+			other := namespaces[name]
+			if other == ns || !slices.ContainsFunc(other.Endpoints, func(o ast.NamespaceEndpoint) bool { return o.EndpointName == ep.EndpointName }) {
+				continue
+			}
+			errs = append(errs, &ResolveError{
+				Msg:    fmt.Sprintf("duplicate nexus endpoint name %q: defined in namespace %s and namespace %s", ep.EndpointName, other.Name, ns.Name),
+				Line:   ep.Line,
+				Column: ep.Column,
+				Kind:   ErrDuplicateEndpoint,
+				Name:   ep.EndpointName,
+			})
+			break
+		}
+	}
+	return errs
+}
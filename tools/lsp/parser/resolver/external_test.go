@@ -0,0 +1,88 @@
+package resolver
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+const externalDefs = `workflow Ship(id: string):
+    activity Pack(id)
+
+activity Pack(id: string):
+    return id
+
+namespace prod:
+    nexus endpoint shipping
+        options:
+            task_queue: "shipping"
+`
+
+func TestResolveWithExternal(t *testing.T) {
+	ext := mustParse(t, externalDefs)
+	ResolveFile(ext)
+
+	file := mustParse(t, `workflow Order(id: string):
+    activity Pack(id)
+    workflow Ship(id)
+    activity Missing(id)
+`)
+	r, err := ResolveWith(context.Background(), file, ext.Definitions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Errors) != 1 || !strings.Contains(r.Errors[0].Msg, "Missing") {
+		t.Fatalf("errors = %v, want only the undefined Missing", r.Errors)
+	}
+	if r.Symbols.Workflows["Ship"] != ext.Definitions[0] {
+		t.Error("Ship does not resolve to the external definition")
+	}
+	// The index covers file alone, so the external Ship body's call to Pack
+	// is not a reference.
+	if refs := r.Symbols.References(ext.Definitions[1]); len(refs) != 1 {
+		t.Errorf("Pack has %d references, want 1", len(refs))
+	}
+}
+
+func TestResolveWithDuplicates(t *testing.T) {
+	ext := mustParse(t, externalDefs)
+	ResolveFile(ext)
+
+	file := mustParse(t, `activity Pack(id: string):
+    return id
+
+namespace staging:
+    nexus endpoint shipping
+        options:
+            task_queue: "shipping"
+`)
+	r, err := ResolveWith(context.Background(), file, ext.Definitions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Errors) != 2 {
+		t.Fatalf("errors = %v, want 2 duplicates", r.Errors)
+	}
+	for _, e := range r.Errors {
+		if e.Line != 1 && e.Line != 5 {
+			t.Errorf("duplicate reported at line %d, outside file's definitions: %v", e.Line, e)
+		}
+	}
+	if !strings.Contains(r.Errors[0].Msg, "duplicate activity definition: Pack") {
+		t.Errorf("first error = %q", r.Errors[0].Msg)
+	}
+	if !strings.Contains(r.Errors[1].Msg, `duplicate nexus endpoint name "shipping"`) {
+		t.Errorf("second error = %q", r.Errors[1].Msg)
+	}
+}
+
+func TestResolveWithoutExternal(t *testing.T) {
+	file := mustParse(t, externalDefs)
+	r, err := ResolveWith(context.Background(), file, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Errors) != 0 {
+		t.Fatalf("unexpected errors: %v", r.Errors)
+	}
+}
@@ -0,0 +1,155 @@
+package resolver
+
+import (
+	"context"
+	"slices"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+)
+
+// ResolveIncremental resolves file, a fresh parse after an edit, reusing
+// prev, the result of resolving the file before the edit.
+//
+// reuse maps definitions of file to the previously resolved definitions they
+// are unchanged from, such as ones with identical text at the same position.
+// Each such definition that is not affected by the edit is replaced in
+// file.Definitions by its previous node, keeping that node's links, errors,
+// and index entries. A definition is affected when it refers to, or failed to
+// resolve, a name whose definition was added, removed, or replaced; affected
+// and unmapped definitions are resolved from the fresh parse. The result
+// equals what ResolveFile would return for the fresh parse.
+//
+// Previous nodes are only read, never written, so prev's file stays a valid
+// snapshot for concurrent readers. ctx is checked between definitions as in
+// ResolveContext.
+func ResolveIncremental(ctx context.Context, prev *ResolveResult, file *ast.File, reuse map[ast.Definition]ast.Definition) (*ResolveResult, error) {
+	if prev == nil || len(reuse) == 0 {
+		return ResolveContext(ctx, file)
+	}
+
+	// Start by reusing every candidate, then fall back to the fresh node for
+	// each candidate that depends on a stale name. Falling back replaces a
+	// table entry, which can make more names stale, so repeat until settled.
+	defs := make([]ast.Definition, len(file.Definitions))
+	for i, def := range file.Definitions {
+		defs[i] = def
+		if old, ok := reuse[def]; ok {
+			if _, seen := prev.defErrs[old]; seen {
+				defs[i] = old
+			}
+		}
+	}
+	for {
+		var discard []*ResolveError
+		tentative := collectSymbols(&ast.File{Definitions: defs}, &discard)
+		if nexusTablesEmptied(prev.Symbols, tentative) {
+			return ResolveContext(ctx, file)
+		}
+		stale := staleNames(prev.Symbols, tentative)
+		settled := true
+		for i, def := range defs {
+			if def != file.Definitions[i] && dependsOn(prev.defErrs[def], prev.Symbols.edges[def], stale) {
+				defs[i] = file.Definitions[i]
+				settled = false
+			}
+		}
+		if settled {
+			break
+		}
+	}
+
+	fresh := slices.Clone(file.Definitions)
+	copy(file.Definitions, defs)
+	r := newResolveResult(file)
+	for i, def := range file.Definitions {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if def == fresh[i] {
+			r.resolveDefinition(def)
+			continue
+		}
+		r.defErrs[def] = prev.defErrs[def]
+		r.Symbols.edges[def] = prev.Symbols.edges[def]
+	}
+	r.collate(file)
+	return r, nil
+}
+
+// nexusTablesEmptied reports whether the endpoint or nexus service table went
+// from empty to non-empty or back. Nexus references are reported differently
+// when no endpoints or services exist at all, so every definition must be
+// resolved again.
+func nexusTablesEmptied(prev, next *SymbolTable) bool {
+	return (len(prev.Endpoints) == 0) != (len(next.Endpoints) == 0) ||
+		(len(prev.NexusServices) == 0) != (len(next.NexusServices) == 0)
+}
+
+// staleNames returns the names whose table entry differs between prev and
+// next. Names are not split by kind; a definition depending on a name of
+// another kind is merely resolved again.
+func staleNames(prev, next *SymbolTable) map[string]bool {
+	stale := make(map[string]bool)
+	diffTable(prev.Workflows, next.Workflows, stale)
+	diffTable(prev.Activities, next.Activities, stale)
+	diffTable(prev.Workers, next.Workers, stale)
+	diffTable(prev.Namespaces, next.Namespaces, stale)
+	diffTable(prev.NexusServices, next.NexusServices, stale)
+	diffTable(prev.Constants, next.Constants, stale)
+	diffTable(prev.Enums, next.Enums, stale)
+	diffTable(prev.Endpoints, next.Endpoints, stale)
+	return stale
+}
+
+func diffTable[T comparable](prev, next map[string]T, stale map[string]bool) {
+	for name, def := range prev {
+		if next[name] != def {
+			stale[name] = true
+		}
+	}
+	for name := range next {
+		if _, ok := prev[name]; !ok {
+			stale[name] = true
+		}
+	}
+}
+
+// dependsOn reports whether a definition with the given previous errors and
+// references must be resolved again: it either referred to a stale
+// definition or failed to resolve a name that may now be defined.
+func dependsOn(errs []*ResolveError, refs []reference, stale map[string]bool) bool {
+	if len(stale) == 0 {
+		return false
+	}
+	for _, e := range errs {
+		if stale[e.Name] {
+			return true
+		}
+	}
+	for _, ref := range refs {
+		if stale[symbolName(ref.target)] {
+			return true
+		}
+	}
+	return false
+}
+
+// symbolName returns the table name of a top-level reference target, or ""
+// for targets declared inside the referring definition.
+func symbolName(n ast.Node) string {
+	switch n := n.(type) {
+	case *ast.WorkflowDef:
+		return n.Name
+	case *ast.ActivityDef:
+		return n.Name
+	case *ast.WorkerDef:
+		return n.Name
+	case *ast.NexusServiceDef:
+		return n.Name
+	case *ast.ConstDef:
+		return n.Name
+	case *ast.NamespaceEndpoint:
+		return n.EndpointName
+	}
+	return ""
+}
@@ -0,0 +1,239 @@
+package resolver
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+)
+
+const incrementalBase = `const wait = 5m
+
+workflow Order(id: string):
+    activity Charge(id)
+    await timer(wait)
+
+workflow Ship(id: string):
+    activity Charge(id)
+
+activity Charge(id: string):
+    return id
+
+worker w:
+    workflow Order
+    workflow Ship
+    activity Charge
+`
+
+// editCases each replace one definition of incrementalBase, leaving the
+// others as candidates for reuse.
+var editCases = []struct {
+	name   string
+	source string
+	edited int // index of the replaced definition
+}{
+	{"body edit", `const wait = 5m
+
+workflow Order(id: string):
+    activity Refund(id)
+    await timer(wait)
+
+workflow Ship(id: string):
+    activity Charge(id)
+
+activity Charge(id: string):
+    return id
+
+worker w:
+    workflow Order
+    workflow Ship
+    activity Charge
+`, 1},
+	{"rename callee", `const wait = 5m
+
+workflow Order(id: string):
+    activity Charge(id)
+    await timer(wait)
+
+workflow Ship(id: string):
+    activity Charge(id)
+
+activity Bill(id: string):
+    return id
+
+worker w:
+    workflow Order
+    workflow Ship
+    activity Charge
+`, 3},
+	{"constant value", `const wait = 9m
+
+workflow Order(id: string):
+    activity Charge(id)
+    await timer(wait)
+
+workflow Ship(id: string):
+    activity Charge(id)
+
+activity Charge(id: string):
+    return id
+
+worker w:
+    workflow Order
+    workflow Ship
+    activity Charge
+`, 0},
+	{"constant renamed", `const pause = 5m
+
+workflow Order(id: string):
+    activity Charge(id)
+    await timer(wait)
+
+workflow Ship(id: string):
+    activity Charge(id)
+
+activity Charge(id: string):
+    return id
+
+worker w:
+    workflow Order
+    workflow Ship
+    activity Charge
+`, 0},
+}
+
+func TestResolveIncrementalMatchesFull(t *testing.T) {
+	for _, tc := range editCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prevFile := mustParse(t, incrementalBase)
+			prev := ResolveFile(prevFile)
+			prevJSON := marshal(t, prevFile)
+
+			file := mustParse(t, tc.source)
+			got, err := ResolveIncremental(context.Background(), prev, file, unchanged(prevFile, file, tc.edited))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			fresh := mustParse(t, tc.source)
+			want := ResolveFile(fresh)
+
+			if g, w := errorStrings(got.Errors), errorStrings(want.Errors); !slices.Equal(g, w) {
+				t.Errorf("errors differ:\ngot  %v\nwant %v", g, w)
+			}
+			if g, w := marshal(t, file), marshal(t, fresh); g != w {
+				t.Errorf("AST differs:\ngot  %s\nwant %s", g, w)
+			}
+			for i, def := range file.Definitions {
+				if g, w := len(got.Symbols.References(def)), len(want.Symbols.References(fresh.Definitions[i])); g != w {
+					t.Errorf("definition %d: %d references, want %d", i, g, w)
+				}
+			}
+			if marshal(t, prevFile) != prevJSON {
+				t.Error("previous file was modified")
+			}
+		})
+	}
+}
+
+func TestResolveIncrementalReusesUnaffected(t *testing.T) {
+	prevFile := mustParse(t, `workflow A():
+    activity Missing()
+
+workflow B():
+    activity Other()
+
+activity Other():
+    return
+`)
+	prev := ResolveFile(prevFile)
+
+	// Editing Other replaces its table entry, so B falls back to its fresh
+	// node; A only depends on Missing and keeps its previous node and error.
+	file := mustParse(t, `workflow A():
+    activity Missing()
+
+workflow B():
+    activity Other()
+
+activity Other():
+    return 1
+`)
+	freshB := file.Definitions[1]
+	got, err := ResolveIncremental(context.Background(), prev, file, unchanged(prevFile, file, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if file.Definitions[0] != prevFile.Definitions[0] {
+		t.Error("expected A's previous node to be reused")
+	}
+	if len(got.Errors) != 1 || got.Errors[0] != prev.Errors[0] {
+		t.Errorf("expected A's error to be carried over, got %v", got.Errors)
+	}
+	if file.Definitions[1] != freshB {
+		t.Error("expected B to be resolved from the fresh parse")
+	}
+	call := freshB.(*ast.WorkflowDef).Body[0].(*ast.ActivityCall)
+	if call.Activity.Resolved != file.Definitions[2] {
+		t.Error("B's call should link to the edited activity")
+	}
+	oldCall := prevFile.Definitions[1].(*ast.WorkflowDef).Body[0].(*ast.ActivityCall)
+	if oldCall.Activity.Resolved != prevFile.Definitions[2] {
+		t.Error("the previous B should still link to the previous activity")
+	}
+}
+
+func TestResolveIncrementalCancelled(t *testing.T) {
+	prevFile := mustParse(t, incrementalBase)
+	prev := ResolveFile(prevFile)
+	file := mustParse(t, incrementalBase)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ResolveIncremental(ctx, prev, file, unchanged(prevFile, file, 1)); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if _, err := ResolveContext(ctx, file); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestResolveIncrementalWithoutPrevious(t *testing.T) {
+	file := mustParse(t, incrementalBase)
+	got, err := ResolveIncremental(context.Background(), nil, file, nil)
+	if err != nil || len(got.Errors) != 0 {
+		t.Errorf("unexpected errors: %v %v", err, got.Errors)
+	}
+}
+
+// unchanged maps every definition of file except the edited one to the
+// definition at the same index of prev.
+func unchanged(prev, file *ast.File, edited int) map[ast.Definition]ast.Definition {
+	reuse := make(map[ast.Definition]ast.Definition)
+	for i, def := range file.Definitions {
+		if i != edited {
+			reuse[def] = prev.Definitions[i]
+		}
+	}
+	return reuse
+}
+
+func errorStrings(errs []*ResolveError) []string {
+	var out []string
+	for _, e := range errs {
+		out = append(out, e.Error())
+	}
+	return out
+}
+
+func marshal(t *testing.T, file *ast.File) string {
+	t.Helper()
+	data, err := json.Marshal(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
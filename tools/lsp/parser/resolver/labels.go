@@ -0,0 +1,71 @@
+package resolver
+
+import (
+	"fmt"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+)
+
+// resolveLabels links labeled break and continue statements in one body to
+// the enclosing loop carrying that label. Labels are scoped to the body, like
+// labels in a Go function: a name may label only one loop per body.
+func resolveLabels(stmts []ast.Statement, errs *[]*ResolveError) {
+	labels := make(map[string]*ast.ForStmt)
+	ast.WalkStatements(stmts, func(s ast.Statement) bool {
+		loop, ok := s.(*ast.ForStmt)
+		if !ok || loop.Label == nil {
+			return true
+		}
+		if prev, dup := labels[loop.Label.Name]; dup {
+			*errs = append(*errs, &ResolveError{
+				Msg:    fmt.Sprintf("duplicate loop label %s (first defined at line %d)", loop.Label.Name, prev.Label.Line),
+				Line:   loop.Label.Line,
+				Column: loop.Label.Column,
+				Kind:   ErrDuplicateLabel,
+				Name:   loop.Label.Name,
+			})
+		} else {
+			labels[loop.Label.Name] = loop
+		}
+
+		// The walk visits outer loops first, so inner loops overwrite
+		// Resolved for their own bodies.
+		ast.WalkStatements(loop.Body, func(s ast.Statement) bool {
+			if ref := labelRef(s); ref != nil && ref.Name == loop.Label.Name {
+				ref.Resolved = loop
+			}
+			return true
+		})
+		return true
+	})
+
+	ast.WalkStatements(stmts, func(s ast.Statement) bool {
+		ref := labelRef(s)
+		if ref == nil || ref.Name == "" || ref.Resolved != nil {
+			return true
+		}
+		msg := fmt.Sprintf("undefined loop label: %s", ref.Name)
+		if _, ok := labels[ref.Name]; ok {
+			msg = fmt.Sprintf("loop label %s does not enclose this statement", ref.Name)
+		}
+		*errs = append(*errs, &ResolveError{
+			Msg:    msg,
+			Line:   ref.Line,
+			Column: ref.Column,
+			Kind:   ErrUndefinedLabel,
+			Name:   ref.Name,
+		})
+		return true
+	})
+}
+
+// labelRef returns the label reference of a break or continue statement.
+func labelRef(s ast.Statement) *ast.Ref[*ast.ForStmt] {
+	switch n := s.(type) {
+	case *ast.BreakStmt:
+		return &n.Label
+	case *ast.ContinueStmt:
+		return &n.Label
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package resolver
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+)
+
+// checkParamDefaults reports required parameters of a definition that follow
+// a parameter with a default, since only trailing parameters may be omitted.
+func checkParamDefaults(kind, name, params string, pos ast.Pos, errs *[]*ResolveError) {
+	var defaulted string
+	for _, p := range ast.ParseParams(params) {
+		if p.Default != "" {
+			defaulted = p.Name
+			continue
+		}
+		if defaulted != "" {
+			*errs = append(*errs, &ResolveError{
+				Msg:    fmt.Sprintf("%s %s: parameter %s has no default but follows %s, which has one", kind, name, p.Name, defaulted),
+				Line:   pos.Line,
+				Column: pos.Column,
+				Kind:   ErrRequiredAfterDefault,
+				Name:   name,
+			})
+			return
+		}
+	}
+}
+
+// checkArgArity reports a call to the named definition that passes fewer
+// arguments than its required parameters or more than all of them.
+// Arguments that do not parse as an expression list are not counted.
+func (c *resolveCtx) checkArgArity(kind, name, params, args string, argExprs []ast.Expr, pos ast.Pos) {
+	n := len(argExprs)
+	if n == 0 && strings.TrimSpace(args) != "" {
+		return
+	}
+	decl := ast.ParseParams(params)
+	lo, hi := ast.RequiredParams(decl), len(decl)
+	if n >= lo && n <= hi {
+		return
+	}
+	want := plural(hi, "argument")
+	switch {
+	case lo < hi && n < lo:
+		want = fmt.Sprintf("at least %s", plural(lo, "argument"))
+	case lo < hi:
+		want = fmt.Sprintf("at most %s", plural(hi, "argument"))
+	}
+	c.errs = append(c.errs, &ResolveError{
+		Msg:    fmt.Sprintf("%s %s takes %s, but the call passes %d", kind, name, want, n),
+		Line:   pos.Line,
+		Column: pos.Column,
+		Kind:   ErrArgArity,
+		Name:   name,
+	})
+}
@@ -1,7 +1,10 @@
 package resolver
 
 import (
+	"context"
 	"fmt"
+	"maps"
+	"slices"
 
 	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
 )
@@ -39,7 +42,7 @@ const (
 	ErrUndefinedCondition
 	// ErrUndefinedPromiseOrCondition: an ident target matches neither a promise nor a condition.
 	ErrUndefinedPromiseOrCondition
-	// ErrConditionResultBinding: a condition target has a result binding (-> identifier), which is not allowed.
+	// ErrConditionResultBinding: a condition target outside an await one case has a result binding (-> identifier).
 	ErrConditionResultBinding
 
 	// --- Nexus resolution errors ---
@@ -70,6 +73,42 @@ const (
 
 	// ErrNamespaceUndefinedWorker: a namespace references an undefined worker.
 	ErrNamespaceUndefinedWorker
+
+	// --- Expression errors ---
+
+	// ErrInvalidGuard: an await one case guard does not parse as an expression.
+	ErrInvalidGuard
+
+	// --- Label errors ---
+
+	// ErrUndefinedLabel: a break or continue names a label that is not on an enclosing loop.
+	ErrUndefinedLabel
+	// ErrDuplicateLabel: a loop label is used more than once in the same body.
+	ErrDuplicateLabel
+
+	// --- Constant errors ---
+
+	// ErrDuplicateConst: a constant name appears more than once.
+	ErrDuplicateConst
+	// ErrConstType: a constant's literal type does not match where it is used.
+	ErrConstType
+
+	// --- Binding errors ---
+
+	// ErrResultArity: a call binds a different number of names than its callee returns,
+	// or an await of a signal or update than its handler takes parameters.
+	ErrResultArity
+	// ErrArgArity: a call passes fewer arguments than its callee requires, or more than it takes.
+	ErrArgArity
+	// ErrRequiredAfterDefault: a parameter without a default follows one with a default.
+	ErrRequiredAfterDefault
+
+	// --- Enum errors ---
+
+	// ErrDuplicateEnum: an enum name appears more than once.
+	ErrDuplicateEnum
+	// ErrDuplicateEnumValue: an enum lists the same value more than once.
+	ErrDuplicateEnumValue
 )
 
 // ResolveError represents a resolution error with position info.
@@ -80,139 +119,127 @@ type ResolveError struct {
 	Severity string // "error" (default) or "warning"
 	Kind     ErrorKind
 	Name     string // primary entity referenced by this error
+
+	// File is the source file of Line and Column, set on duplicate
+	// definition errors whose definition is stamped with one.
+	File string
+	// Related points at other locations involved, such as the definition
+	// a duplicate repeats.
+	Related []Related
+}
+
+// Related points at a secondary location for a ResolveError.
+type Related struct {
+	Msg    string
+	Line   int
+	Column int
+	File   string // source file of the location; empty when unstamped
 }
 
 func (e *ResolveError) Error() string {
 	return fmt.Sprintf("resolve error at %d:%d: %s", e.Line, e.Column, e.Msg)
 }
 
+// CrossFile reports whether e is a duplicate definition error whose two
+// definitions come from different source files, as when a catalog file
+// intentionally shadows another.
+func (e *ResolveError) CrossFile() bool {
+	return len(e.Related) > 0 && e.Related[0].File != e.File
+}
 
 // Resolve walks the AST, linking calls to their definitions.
 // Returns a list of errors (empty on success).
 func Resolve(file *ast.File) []*ResolveError {
-	workflows := make(map[string]*ast.WorkflowDef)
-	activities := make(map[string]*ast.ActivityDef)
-	workers := make(map[string]*ast.WorkerDef)
-	namespaces := make(map[string]*ast.NamespaceDef)
-	nexusServices := make(map[string]*ast.NexusServiceDef)
-	var errs []*ResolveError
+	return ResolveFile(file).Errors
+}
 
-	// Pass 1: Collect all definitions.
-	for _, def := range file.Definitions {
-		switch d := def.(type) {
-		case *ast.WorkflowDef:
-			collectDef(workflows, d.Name, d, "workflow", ErrDuplicateWorkflow, d.Line, d.Column, &errs)
-		case *ast.ActivityDef:
-			collectDef(activities, d.Name, d, "activity", ErrDuplicateActivity, d.Line, d.Column, &errs)
-		case *ast.WorkerDef:
-			collectDef(workers, d.Name, d, "worker", ErrDuplicateWorker, d.Line, d.Column, &errs)
-		case *ast.NamespaceDef:
-			collectDef(namespaces, d.Name, d, "namespace", ErrDuplicateNamespace, d.Line, d.Column, &errs)
-		case *ast.NexusServiceDef:
-			collectDef(nexusServices, d.Name, d, "nexus service", ErrDuplicateNexusService, d.Line, d.Column, &errs)
-		}
-	}
+// ResolveFile resolves file like Resolve and also returns the symbol tables
+// built during resolution, with an index from each definition to the nodes
+// that reference it.
+func ResolveFile(file *ast.File) *ResolveResult {
+	r, _ := ResolveContext(context.Background(), file)
+	return r
+}
 
-	// Build global endpoint map across all namespaces.
-	allEndpoints := make(map[string]*ast.NamespaceEndpoint)
-	for _, ns := range namespaces {
-		for i := range ns.Endpoints {
-			ep := &ns.Endpoints[i]
-			ep.Namespace = ns.Name
-			if existing, exists := allEndpoints[ep.EndpointName]; exists {
-				errs = append(errs, &ResolveError{
-					Msg:    fmt.Sprintf("duplicate nexus endpoint name %q: defined in namespace %s and namespace %s", ep.EndpointName, existing.Namespace, ns.Name),
-					Line:   ep.Line,
-					Column: ep.Column,
-					Kind:   ErrDuplicateEndpoint,
-					Name:   ep.EndpointName,
-				})
-			}
-			allEndpoints[ep.EndpointName] = ep
+// ResolveContext is ResolveFile with cancellation: it checks ctx between
+// definitions and returns ctx.Err() once it is done. A cancelled resolution
+// may leave file partly linked and should be discarded.
+//
+// The resolver keeps no state between calls, so files may be resolved
+// concurrently. Resolution writes to file, so it must not be read elsewhere
+// until ResolveContext returns; resolve a private parse, not one being served.
+func ResolveContext(ctx context.Context, file *ast.File) (*ResolveResult, error) {
+	r := newResolveResult(file)
+	// Resolve every definition even if there are duplicate definition
+	// errors. This provides better diagnostics by also reporting undefined
+	// references.
+	for _, def := range file.Definitions {
+		if err := ctx.Err(); err != nil {
+			return nil, err
 		}
+		r.resolveDefinition(def)
 	}
+	r.collate(file)
+	return r, nil
+}
 
-	// Continue to Pass 2 even if there are duplicate definition errors.
-	// This provides better diagnostics by also reporting undefined references.
+// newResolveResult collects the file's symbol tables, recording duplicate
+// definition errors, ahead of resolving any definition.
+func newResolveResult(file *ast.File) *ResolveResult {
+	r := &ResolveResult{defErrs: make(map[ast.Definition][]*ResolveError)}
+	r.Symbols = collectSymbols(file, &r.dupErrs)
+	return r
+}
 
-	// Pass 2: Walk workflow bodies, resolving references.
-	for _, def := range file.Definitions {
-		wf, ok := def.(*ast.WorkflowDef)
-		if !ok {
-			continue
-		}
+// resolveDefinition resolves the references inside one top-level definition
+// against the symbol tables and indexes them.
+func (r *ResolveResult) resolveDefinition(def ast.Definition) {
+	syms := r.Symbols
+	var errs []*ResolveError
 
-		// Build signal, query, and update maps for this workflow.
-		signals := make(map[string]*ast.SignalDecl)
-		queries := make(map[string]*ast.QueryDecl)
-		updates := make(map[string]*ast.UpdateDecl)
-		for _, s := range wf.Signals {
-			signals[s.Name] = s
+	switch d := def.(type) {
+	case *ast.WorkflowDef:
+		ctx := syms.resolveCtx(syms.Handlers[d])
+		for _, s := range d.Signals {
+			ctx.resolveStatements(s.Body)
 		}
-		for _, q := range wf.Queries {
-			queries[q.Name] = q
+		for _, q := range d.Queries {
+			ctx.resolveStatements(q.Body)
 		}
-		for _, u := range wf.Updates {
-			updates[u.Name] = u
+		for _, u := range d.Updates {
+			ctx.resolveStatements(u.Body)
 		}
+		ctx.resolveStatements(d.Body)
+		errs = append(errs, ctx.errs...)
+		checkParamDefaults("workflow", d.Name, d.Params, d.Pos, &errs)
 
-		// Build condition map from state block.
-		conditions := make(map[string]*ast.ConditionDecl)
-		if wf.State != nil {
-			for _, c := range wf.State.Conditions {
-				conditions[c.Name] = c
-			}
+		for _, s := range d.Signals {
+			resolveLabels(s.Body, &errs)
 		}
-
-		// Build promise set from workflow body.
-		promises := make(map[string]*ast.PromiseStmt)
-		for _, stmt := range wf.Body {
-			if p, ok := stmt.(*ast.PromiseStmt); ok {
-				promises[p.Name] = p
-			}
+		for _, q := range d.Queries {
+			resolveLabels(q.Body, &errs)
 		}
-
-		ctx := &resolveCtx{
-			workflows:    workflows,
-			activities:   activities,
-			signals:      signals,
-			queries:      queries,
-			updates:      updates,
-			conditions:   conditions,
-			promises:     promises,
-			nexusServices: nexusServices,
-			allEndpoints: allEndpoints,
+		for _, u := range d.Updates {
+			resolveLabels(u.Body, &errs)
 		}
+		resolveLabels(d.Body, &errs)
 
-		// Resolve handler bodies.
-		for _, s := range wf.Signals {
-			ctx.resolveStatements(s.Body)
-		}
-		for _, q := range wf.Queries {
-			ctx.resolveStatements(q.Body)
-		}
-		for _, u := range wf.Updates {
-			ctx.resolveStatements(u.Body)
-		}
+	case *ast.ActivityDef:
+		resolveLabels(d.Body, &errs)
+		checkParamDefaults("activity", d.Name, d.Params, d.Pos, &errs)
 
-		ctx.resolveStatements(wf.Body)
-		errs = append(errs, ctx.errs...)
-	}
+	case *ast.EnumDef:
+		checkEnumValues(d, &errs)
 
-	// Pass 2b: Resolve nexus service operation bodies.
-	for _, def := range file.Definitions {
-		svc, ok := def.(*ast.NexusServiceDef)
-		if !ok {
-			continue
-		}
-		for _, op := range svc.Operations {
+	case *ast.NexusServiceDef:
+		for _, op := range d.Operations {
 			if op.OpType == ast.NexusOpAsync {
 				// Async operations reference a workflow by name.
-				if wf, ok := workflows[op.Workflow.Name]; ok {
+				if wf, ok := syms.Workflows[op.Workflow.Name]; ok {
 					op.Workflow.Resolved = wf
 				} else {
 					errs = append(errs, &ResolveError{
-						Msg:    fmt.Sprintf("nexus service %s: async operation %s references undefined workflow: %s", svc.Name, op.Name, op.Workflow.Name),
+						Msg:    fmt.Sprintf("nexus service %s: async operation %s references undefined workflow: %s", d.Name, op.Name, op.Workflow.Name),
 						Line:   op.Line,
 						Column: op.Column,
 						Kind:   ErrNexusAsyncUndefinedWorkflow,
@@ -221,38 +248,26 @@ func Resolve(file *ast.File) []*ResolveError {
 				}
 			} else if op.OpType == ast.NexusOpSync {
 				// Sync operations have a body — resolve like a workflow body.
-				syncCtx := &resolveCtx{
-					workflows:    workflows,
-					activities:   activities,
-					signals:      make(map[string]*ast.SignalDecl),
-					queries:      make(map[string]*ast.QueryDecl),
-					updates:      make(map[string]*ast.UpdateDecl),
-					conditions:   make(map[string]*ast.ConditionDecl),
-					promises:     make(map[string]*ast.PromiseStmt),
-					nexusServices: nexusServices,
-					allEndpoints: allEndpoints,
-				}
+				syncCtx := syms.resolveCtx(nil)
 				syncCtx.resolveStatements(op.Body)
 				errs = append(errs, syncCtx.errs...)
+				resolveLabels(op.Body, &errs)
 			}
 		}
-	}
 
-	// Pass 3: Resolve worker and namespace references.
-	for _, w := range workers {
-		resolveWorkerRefs(w.Workflows, workflows, "workflow", ErrWorkerUndefinedWorkflow, &errs)
-		resolveWorkerRefs(w.Activities, activities, "activity", ErrWorkerUndefinedActivity, &errs)
-		resolveWorkerRefs(w.Services, nexusServices, "nexus service", ErrWorkerUndefinedNexusService, &errs)
-	}
+	case *ast.WorkerDef:
+		resolveWorkerRefs(d.Workflows, syms.Workflows, "workflow", ErrWorkerUndefinedWorkflow, &errs)
+		resolveWorkerRefs(d.Activities, syms.Activities, "activity", ErrWorkerUndefinedActivity, &errs)
+		resolveWorkerRefs(d.Services, syms.NexusServices, "nexus service", ErrWorkerUndefinedNexusService, &errs)
 
-	for _, ns := range namespaces {
-		for i := range ns.Workers {
-			nw := &ns.Workers[i]
-			if def, ok := workers[nw.Worker.Name]; ok {
-				nw.Worker.Resolved = def
+	case *ast.NamespaceDef:
+		for i := range d.Workers {
+			nw := &d.Workers[i]
+			if w, ok := syms.Workers[nw.Worker.Name]; ok {
+				nw.Worker.Resolved = w
 			} else {
 				errs = append(errs, &ResolveError{
-					Msg:    fmt.Sprintf("namespace %s references undefined worker: %s", ns.Name, nw.Worker.Name),
+					Msg:    fmt.Sprintf("namespace %s references undefined worker: %s", d.Name, nw.Worker.Name),
 					Line:   nw.Line,
 					Column: nw.Column,
 					Kind:   ErrNamespaceUndefinedWorker,
@@ -262,7 +277,52 @@ func Resolve(file *ast.File) []*ResolveError {
 		}
 	}
 
-	return errs
+	// Constants are resolved and inlined last, once their use sites are known.
+	resolveConsts(def, syms.Constants, &errs)
+
+	r.defErrs[def] = errs
+	syms.edges[def] = indexDefinition(def)
+}
+
+// collate assembles Errors and the reference index from the per-definition
+// results, in definition order after the duplicate definition errors.
+func (r *ResolveResult) collate(file *ast.File) {
+	r.Errors = append([]*ResolveError(nil), r.dupErrs...)
+	r.Symbols.refs = make(map[ast.Node][]ast.Node)
+	for _, def := range file.Definitions {
+		r.Errors = append(r.Errors, r.defErrs[def]...)
+		for _, e := range r.Symbols.edges[def] {
+			r.Symbols.refs[e.target] = append(r.Symbols.refs[e.target], e.site)
+		}
+	}
+}
+
+// collectEndpoints fills endpoints with the endpoints of every namespace,
+// setting each endpoint's owning namespace and appending an error for names
+// defined in more than one namespace. Namespaces are taken in name order, so
+// the first of them keeps a shared name.
+func collectEndpoints(namespaces map[string]*ast.NamespaceDef, endpoints map[string]*ast.NamespaceEndpoint, errs *[]*ResolveError) {
+	for _, name := range slices.Sorted(maps.Keys(namespaces)) {
+		ns := namespaces[name]
+		for i := range ns.Endpoints {
+			ep := &ns.Endpoints[i]
+			if ep.Namespace != ns.Name {
+				// Endpoints of a reused namespace already carry their
+				// namespace; skip the write so reused nodes stay read-only.
+				ep.Namespace = ns.Name
+			}
+			if existing, exists := endpoints[ep.EndpointName]; exists {
+				*errs = append(*errs, &ResolveError{
+					Msg:    fmt.Sprintf("duplicate nexus endpoint name %q: defined in namespace %s and namespace %s", ep.EndpointName, existing.Namespace, ns.Name),
+					Line:   ep.Line,
+					Column: ep.Column,
+					Kind:   ErrDuplicateEndpoint,
+					Name:   ep.EndpointName,
+				})
+			}
+			endpoints[ep.EndpointName] = ep
+		}
+	}
 }
 
 type resolveCtx struct {
@@ -278,23 +338,59 @@ type resolveCtx struct {
 	errs          []*ResolveError
 }
 
+// resolveCtx returns a context for resolving one body. ws holds the
+// enclosing workflow's declarations and is nil outside a workflow.
+func (t *SymbolTable) resolveCtx(ws *WorkflowSymbols) *resolveCtx {
+	if ws == nil {
+		ws = newWorkflowSymbols()
+	}
+	return &resolveCtx{
+		workflows:     t.Workflows,
+		activities:    t.Activities,
+		signals:       ws.Signals,
+		queries:       ws.Queries,
+		updates:       ws.Updates,
+		conditions:    ws.Conditions,
+		promises:      ws.Promises,
+		nexusServices: t.NexusServices,
+		allEndpoints:  t.Endpoints,
+	}
+}
+
 func (c *resolveCtx) resolveStatements(stmts []ast.Statement) {
 	ast.WalkStatements(stmts, func(s ast.Statement) bool {
 		switch s := s.(type) {
 		case *ast.ActivityCall:
 			resolveRef(&s.Activity, c.activities, "activity", ErrUndefinedActivity, &c.errs)
+			if def := s.Activity.Resolved; def != nil {
+				c.checkResultArity("activity", def.Name, def.ReturnType, s.Result, s.Pos)
+				c.checkArgArity("activity", def.Name, def.Params, s.Args, s.ArgExprs, s.Pos)
+			}
 		case *ast.WorkflowCall:
 			resolveRef(&s.Workflow, c.workflows, "workflow", ErrUndefinedWorkflow, &c.errs)
+			if def := s.Workflow.Resolved; def != nil {
+				c.checkResultArity("workflow", def.Name, def.ReturnType, s.Result, s.Pos)
+				c.checkArgArity("workflow", def.Name, def.Params, s.Args, s.ArgExprs, s.Pos)
+			}
 		case *ast.NexusCall:
 			c.resolveNexusRefs(&s.Endpoint, &s.Service, &s.Operation)
 		case *ast.SetStmt:
 			resolveRef(&s.Condition, c.conditions, "condition", ErrUndefinedCondition, &c.errs)
 		case *ast.UnsetStmt:
 			resolveRef(&s.Condition, c.conditions, "condition", ErrUndefinedCondition, &c.errs)
+		case *ast.AwaitOneCase:
+			if s.Guard != "" && s.GuardExpr == nil {
+				c.errs = append(c.errs, &ResolveError{
+					Msg:    fmt.Sprintf("await one case guard is not a valid expression: %s", s.Guard),
+					Line:   s.Line,
+					Column: s.Column,
+					Kind:   ErrInvalidGuard,
+				})
+			}
 		}
 		return true
 	}, ast.WithAsyncTargets(func(target ast.AsyncTarget, parent ast.Statement) bool {
-		c.resolveAsyncTarget(target, parent.NodeLine(), parent.NodeColumn())
+		c.resolveAsyncTarget(target, parent)
 		return true
 	}))
 }
@@ -353,17 +449,33 @@ func resolveRefWithWarn[T any](ref *ast.Ref[T], defs map[string]T, kind string,
 	return false
 }
 
-// resolveAsyncTarget resolves references inside an async target.
-func (c *resolveCtx) resolveAsyncTarget(target ast.AsyncTarget, line, column int) {
+// resolveAsyncTarget resolves references inside an async target of the
+// statement parent.
+func (c *resolveCtx) resolveAsyncTarget(target ast.AsyncTarget, parent ast.Statement) {
+	line, column := parent.NodeLine(), parent.NodeColumn()
 	switch t := target.(type) {
 	case *ast.SignalTarget:
 		resolveRef(&t.Signal, c.signals, "signal", ErrUndefinedSignal, &c.errs)
+		if decl := t.Signal.Resolved; decl != nil {
+			c.checkPayloadArity("signal", decl.Name, decl.Params, t.Params, ast.Pos{Line: line, Column: column})
+		}
 	case *ast.UpdateTarget:
 		resolveRef(&t.Update, c.updates, "update", ErrUndefinedUpdate, &c.errs)
+		if decl := t.Update.Resolved; decl != nil {
+			c.checkPayloadArity("update", decl.Name, decl.Params, t.Params, ast.Pos{Line: line, Column: column})
+		}
 	case *ast.ActivityTarget:
 		resolveRef(&t.Activity, c.activities, "activity", ErrUndefinedActivity, &c.errs)
+		if def := t.Activity.Resolved; def != nil {
+			c.checkResultArity("activity", def.Name, def.ReturnType, t.Result, ast.Pos{Line: line, Column: column})
+			c.checkArgArity("activity", def.Name, def.Params, t.Args, t.ArgExprs, ast.Pos{Line: line, Column: column})
+		}
 	case *ast.WorkflowTarget:
 		resolveRef(&t.Workflow, c.workflows, "workflow", ErrUndefinedWorkflow, &c.errs)
+		if def := t.Workflow.Resolved; def != nil {
+			c.checkResultArity("workflow", def.Name, def.ReturnType, t.Result, ast.Pos{Line: line, Column: column})
+			c.checkArgArity("workflow", def.Name, def.Params, t.Args, t.ArgExprs, ast.Pos{Line: line, Column: column})
+		}
 	case *ast.NexusTarget:
 		c.resolveNexusRefs(&t.Endpoint, &t.Service, &t.Operation)
 	case *ast.IdentTarget:
@@ -384,7 +496,8 @@ func (c *resolveCtx) resolveAsyncTarget(target ast.AsyncTarget, line, column int
 		if isCondition {
 			t.Resolved.Condition = condition
 		}
-		if isCondition && t.Result != "" {
+		// An await one case may bind what made its condition true.
+		if _, inCase := parent.(*ast.AwaitOneCase); isCondition && t.Result != "" && !inCase {
 			c.errs = append(c.errs, &ResolveError{
 				Msg:    fmt.Sprintf("condition %q cannot have a result binding (-> identifier)", t.Name),
 				Line:   line,
@@ -394,20 +507,87 @@ func (c *resolveCtx) resolveAsyncTarget(target ast.AsyncTarget, line, column int
 			})
 		}
 	case *ast.TimerTarget:
-		// No resolution needed for timers
+		// Constant durations are resolved with other constant references in Pass 4.
+	}
+}
+
+// checkResultArity reports a call to the named definition that binds a
+// different number of names than the definition's return types. Calls
+// without a binding, and definitions without a return type, are not
+// checked.
+func (c *resolveCtx) checkResultArity(kind, name, returnType, result string, pos ast.Pos) {
+	if result == "" || returnType == "" {
+		return
+	}
+	returns, names := ast.SplitList(returnType), ast.SplitList(result)
+	if len(names) == len(returns) {
+		return
 	}
+	c.errs = append(c.errs, &ResolveError{
+		Msg:    fmt.Sprintf("%s %s returns %s (%s), but the call binds %s", kind, name, plural(len(returns), "value"), returnType, plural(len(names), "name")),
+		Line:   pos.Line,
+		Column: pos.Column,
+		Kind:   ErrResultArity,
+		Name:   name,
+	})
+}
+
+// checkPayloadArity reports an await of a signal or update binding a
+// different number of names than its handler takes parameters.
+func (c *resolveCtx) checkPayloadArity(kind, name, params, bound string, pos ast.Pos) {
+	if bound == "" {
+		return
+	}
+	values, names := ast.ParseParams(params), ast.SplitList(bound)
+	if len(names) == len(values) {
+		return
+	}
+	carries := plural(len(values), "value")
+	if len(values) > 0 {
+		carries += " (" + params + ")"
+	}
+	c.errs = append(c.errs, &ResolveError{
+		Msg:    fmt.Sprintf("%s %s carries %s, but the await binds %s", kind, name, carries, plural(len(names), "name")),
+		Line:   pos.Line,
+		Column: pos.Column,
+		Kind:   ErrResultArity,
+		Name:   name,
+	})
+}
+
+// plural returns n and word, pluralized unless n is 1.
+func plural(n int, word string) string {
+	if n == 1 {
+		return "1 " + word
+	}
+	return fmt.Sprintf("%d %ss", n, word)
 }
 
 // collectDef registers a definition in the map, appending a duplicate error if
-// the name already exists.
-func collectDef[T any](m map[string]T, name string, def T, kind string, errKind ErrorKind, line, column int, errs *[]*ResolveError) {
-	if _, exists := m[name]; exists {
+// the name already exists. The error is reported at def and points at the
+// earlier definition, which def replaces in the map. When the two come from
+// different files the message names the other one, since a reader of merged
+// files cannot tell from a line number alone.
+func collectDef[T ast.Definition](m map[string]T, name string, def T, kind string, errKind ErrorKind, errs *[]*ResolveError) {
+	if prev, exists := m[name]; exists {
+		file, prevFile := ast.SourceFile(def), ast.SourceFile(prev)
+		msg := fmt.Sprintf("duplicate %s definition: %s", kind, name)
+		if file != prevFile {
+			msg += fmt.Sprintf(", also defined at %s:%d:%d", prevFile, prev.NodeLine(), prev.NodeColumn())
+		}
 		*errs = append(*errs, &ResolveError{
-			Msg:    fmt.Sprintf("duplicate %s definition: %s", kind, name),
-			Line:   line,
-			Column: column,
+			Msg:    msg,
+			Line:   def.NodeLine(),
+			Column: def.NodeColumn(),
 			Kind:   errKind,
 			Name:   name,
+			File:   file,
+			Related: []Related{{
+				Msg:    fmt.Sprintf("%s %s is first defined here", kind, name),
+				Line:   prev.NodeLine(),
+				Column: prev.NodeColumn(),
+				File:   prevFile,
+			}},
 		})
 	}
 	m[name] = def
@@ -1,6 +1,7 @@
 package resolver
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 
@@ -125,6 +126,40 @@ activity Foo(y: int) -> (Result):
 	}
 }
 
+func TestDuplicateAcrossFiles(t *testing.T) {
+	merged := &ast.File{}
+	for _, src := range []struct{ name, text string }{
+		{"a.twf", "workflow Foo():\n    close complete\n"},
+		{"b.twf", "activity Bar():\n    return\n\nworkflow Foo():\n    close complete\n\nactivity Bar():\n    return\n"},
+	} {
+		file := mustParse(t, src.text)
+		for _, def := range file.Definitions {
+			ast.SetSourceFile(def, src.name)
+		}
+		merged.Definitions = append(merged.Definitions, file.Definitions...)
+	}
+	errs := Resolve(merged)
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %v", errs)
+	}
+
+	foo := errs[0]
+	if foo.Msg != "duplicate workflow definition: Foo, also defined at a.twf:1:1" || foo.File != "b.twf" || foo.Line != 4 {
+		t.Errorf("unexpected cross-file error: %+v", foo)
+	}
+	if len(foo.Related) != 1 || foo.Related[0].File != "a.twf" || foo.Related[0].Line != 1 || !foo.CrossFile() {
+		t.Errorf("expected the related location in a.twf, got %+v", foo.Related)
+	}
+
+	bar := errs[1]
+	if bar.Msg != "duplicate activity definition: Bar" || bar.File != "b.twf" || bar.Line != 7 {
+		t.Errorf("unexpected same-file error: %+v", bar)
+	}
+	if len(bar.Related) != 1 || bar.Related[0].Line != 1 || bar.CrossFile() {
+		t.Errorf("expected the related location in b.twf, got %+v", bar.Related)
+	}
+}
+
 func TestNestedResolution(t *testing.T) {
 	input := `workflow Foo(x: int) -> (Result):
     if (x > 0):
@@ -927,6 +962,326 @@ namespace myNs:
 	}
 }
 
+func TestAwaitOneInvalidGuard(t *testing.T) {
+	input := `workflow Foo(x: int) -> (Result):
+    await one:
+        timer (1h) if (x <):
+            activity A(x)
+        timer (2h) if (x > 0):
+            activity A(x)
+
+activity A(x: int):
+    log(x)
+`
+	file := mustParse(t, input)
+	errs := Resolve(file)
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error, got %d: %v", len(errs), errs)
+	}
+	if errs[0].Kind != ErrInvalidGuard || errs[0].Line != 3 {
+		t.Errorf("expected invalid guard error at line 3, got %v", errs[0])
+	}
+}
+
+func TestConditionResultBinding(t *testing.T) {
+	input := `workflow Foo(x: int) -> (Result):
+    state:
+        condition approved
+
+    await one:
+        approved -> approvalInfo:
+            close complete(Result{})
+        timer (1h) -> expiredAt:
+            close fail(Result{})
+    await approved -> info
+`
+	file := mustParse(t, input)
+	errs := Resolve(file)
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error, got %d: %v", len(errs), errs)
+	}
+	if errs[0].Kind != ErrConditionResultBinding || errs[0].Line != 10 {
+		t.Errorf("expected a binding error for the single await at line 10, got %v", errs[0])
+	}
+}
+
+func TestResultArity(t *testing.T) {
+	input := `workflow Foo(order: Order):
+    activity Charge(order) -> (payment, err)
+    activity Charge(order) -> payment
+    activity Refund(order) -> (refund, err)
+    workflow Ship(order) -> (tracking, eta)
+    await one:
+        workflow Ship(order) -> tracking:
+            close complete
+
+workflow Ship(order: Order) -> (Tracking):
+    return
+
+activity Charge(order: Order) -> (Payment, error):
+    return
+
+activity Refund(order: Order):
+    return
+`
+	file := mustParse(t, input)
+	var got []string
+	for _, e := range Resolve(file) {
+		if e.Kind == ErrResultArity {
+			got = append(got, fmt.Sprintf("%d: %s", e.Line, e.Msg))
+		}
+	}
+	want := []string{
+		"3: activity Charge returns 2 values (Payment, error), but the call binds 1 name",
+		"5: workflow Ship returns 1 value (Tracking), but the call binds 2 names",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got errors:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestPayloadArity(t *testing.T) {
+	input := `workflow Foo():
+    signal Approved(approver: string, at: time):
+        log(approver)
+    update Cancel(reason: string) -> (bool):
+        return true
+    signal Ping():
+        log("ping")
+    await signal Approved -> (approver, at)
+    await signal Approved -> approver
+    await one:
+        update Cancel -> (reason, by):
+            close fail
+        signal Ping -> (at):
+            close complete
+        signal Ping:
+            close complete
+`
+	file := mustParse(t, input)
+	var got []string
+	for _, e := range Resolve(file) {
+		if e.Kind == ErrResultArity {
+			got = append(got, fmt.Sprintf("%d: %s", e.Line, e.Msg))
+		}
+	}
+	want := []string{
+		"9: signal Approved carries 2 values (approver: string, at: time), but the await binds 1 name",
+		"11: update Cancel carries 1 value (reason: string), but the await binds 2 names",
+		"13: signal Ping carries 0 values, but the await binds 1 name",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got errors:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestParamDefaults(t *testing.T) {
+	input := `workflow Foo(order: Order):
+    activity Notify(order)
+    activity Notify(order, true)
+    activity Notify(order, true, "email")
+    activity Notify()
+    await activity Notify(order, false, "sms", 3)
+    activity Notify(order, "a, b")
+    activity Notify(order, {free form})
+
+activity Notify(order: Order, verbose: bool = false, channel: string = "email, sms"):
+    return
+
+activity Broken(verbose: bool = false, order: Order):
+    return
+`
+	file := mustParse(t, input)
+	var got []string
+	for _, e := range Resolve(file) {
+		got = append(got, fmt.Sprintf("%d: %s", e.Line, e.Msg))
+	}
+	want := []string{
+		"5: activity Notify takes at least 1 argument, but the call passes 0",
+		"6: activity Notify takes at most 3 arguments, but the call passes 4",
+		"13: activity Broken: parameter order has no default but follows verbose, which has one",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got errors:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestLoopLabelResolution(t *testing.T) {
+	input := `workflow Foo(x: int) -> (Result):
+    outer: for (item in items):
+        inner: for (part in item.parts):
+            break outer
+            continue inner
+`
+	file := mustParse(t, input)
+	if errs := Resolve(file); len(errs) != 0 {
+		t.Fatalf("expected no errors, got %v", errs)
+	}
+	outer := file.Definitions[0].(*ast.WorkflowDef).Body[0].(*ast.ForStmt)
+	inner := outer.Body[0].(*ast.ForStmt)
+	if brk := inner.Body[0].(*ast.BreakStmt); brk.Label.Resolved != outer {
+		t.Error("break outer did not resolve to the outer loop")
+	}
+	if cont := inner.Body[1].(*ast.ContinueStmt); cont.Label.Resolved != inner {
+		t.Error("continue inner did not resolve to the inner loop")
+	}
+}
+
+func TestLoopLabelErrors(t *testing.T) {
+	input := `workflow Foo(x: int) -> (Result):
+    first: for (item in items):
+        break missing
+    first: for (item in items):
+        break first
+    second: for (item in items):
+        break
+    break second
+
+activity Bar(x: int):
+    loop: for (i in x):
+        break loop
+`
+	file := mustParse(t, input)
+	errs := Resolve(file)
+	if !hasError(errs, "undefined loop label: missing") {
+		t.Errorf("expected undefined label error, got %v", errs)
+	}
+	if !hasError(errs, "duplicate loop label first (first defined at line 2)") {
+		t.Errorf("expected duplicate label error, got %v", errs)
+	}
+	if !hasError(errs, "loop label second does not enclose this statement") {
+		t.Errorf("expected non-enclosing label error, got %v", errs)
+	}
+	if len(errs) != 3 {
+		t.Errorf("expected 3 errors, got %d: %v", len(errs), errs)
+	}
+}
+
+func TestConstResolution(t *testing.T) {
+	input := `const approvalTimeout = 7d
+const queue = "orders"
+
+workflow Foo(x: int) -> (Result):
+    await one:
+        timer(approvalTimeout):
+            return x
+    activity Bar(x)
+        options:
+            start_to_close_timeout: approvalTimeout
+            retry_policy:
+                initial_interval: approvalTimeout
+
+activity Bar(x: int):
+    return x
+
+namespace orders:
+    worker w
+        options:
+            task_queue: queue
+
+worker w:
+    workflow Foo
+`
+	file := mustParse(t, input)
+	if errs := Resolve(file); len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	wf := file.Definitions[2].(*ast.WorkflowDef)
+	timer := wf.Body[0].(*ast.AwaitOneBlock).Cases[0].Target.(*ast.TimerTarget)
+	if timer.Duration != "7d" || timer.Const.Resolved != file.Definitions[0] {
+		t.Errorf("expected timer inlined to 7d, got %q (resolved %v)", timer.Duration, timer.Const.Resolved)
+	}
+	entries := wf.Body[1].(*ast.ActivityCall).Options.Entries
+	if entries[0].Value != "7d" || entries[1].Nested[0].Value != "7d" {
+		t.Errorf("expected option values inlined to 7d, got %q and %q", entries[0].Value, entries[1].Nested[0].Value)
+	}
+	ns := file.Definitions[4].(*ast.NamespaceDef)
+	if v := ns.Workers[0].Options.Entries[0].Value; v != "orders" {
+		t.Errorf("expected task_queue inlined to orders, got %q", v)
+	}
+}
+
+func TestConstErrors(t *testing.T) {
+	input := `const approvalTimeout = 7d
+const approvalTimeout = 1d
+const queue = "orders"
+
+workflow Foo(x: int) -> (Result):
+    await timer(missing)
+    await timer(queue)
+    activity Bar(x)
+        options:
+            start_to_close_timeout: queue
+
+activity Bar(x: int):
+    return x
+`
+	file := mustParse(t, input)
+	errs := Resolve(file)
+	if !hasError(errs, "duplicate constant definition: approvalTimeout") {
+		t.Errorf("expected duplicate constant error, got %v", errs)
+	}
+	if !hasError(errs, "constant queue is a string, expected duration") {
+		t.Errorf("expected constant type error, got %v", errs)
+	}
+	if len(errs) != 3 {
+		t.Errorf("expected 3 errors, got %d: %v", len(errs), errs)
+	}
+	wf := file.Definitions[3].(*ast.WorkflowDef)
+	if timer := wf.Body[0].(*ast.AwaitStmt).Target.(*ast.TimerTarget); timer.Duration != "missing" || timer.Const.Name != "" {
+		t.Errorf("expected a name no constant has kept as a duration variable, got %q (const %q)", timer.Duration, timer.Const.Name)
+	}
+	if d := wf.Body[1].(*ast.AwaitStmt).Target.(*ast.TimerTarget).Duration; d != "queue" {
+		t.Errorf("expected mistyped constant not inlined, got %q", d)
+	}
+}
+
+// TestNonConstIdentifiers checks that identifiers naming no constant, such
+// as workflow variables, stay opaque values as they were before constants.
+func TestNonConstIdentifiers(t *testing.T) {
+	input := `workflow Process(request: Request, backoff: duration):
+    queue = selectQueue(request.priority)
+    await timer(backoff)
+    activity DoWork()
+        options:
+            task_queue: queue
+
+activity DoWork():
+    return
+`
+	file := mustParse(t, input)
+	if errs := Resolve(file); len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	wf := file.Definitions[0].(*ast.WorkflowDef)
+	if timer := wf.Body[1].(*ast.AwaitStmt).Target.(*ast.TimerTarget); timer.Duration != "backoff" || timer.Const.Name != "" {
+		t.Errorf("expected timer(backoff) kept as written, got %q (const %q)", timer.Duration, timer.Const.Name)
+	}
+	e := wf.Body[2].(*ast.ActivityCall).Options.Entries[0]
+	if e.Value != "queue" || e.ValueType != "enum" || e.Const.Name != "" {
+		t.Errorf("expected task_queue: queue kept as an identifier, got %q (%s, const %q)", e.Value, e.ValueType, e.Const.Name)
+	}
+}
+
+func TestEnumErrors(t *testing.T) {
+	input := `enum OrderType: invoice, refund, invoice
+enum OrderType: invoice
+
+workflow Foo(kind: OrderType):
+    close complete
+`
+	errs := Resolve(mustParse(t, input))
+	if !hasError(errs, "duplicate enum definition: OrderType") {
+		t.Errorf("expected duplicate enum error, got %v", errs)
+	}
+	if !hasError(errs, "enum OrderType lists value invoice more than once") {
+		t.Errorf("expected duplicate value error, got %v", errs)
+	}
+	if len(errs) != 2 {
+		t.Errorf("expected 2 errors, got %d: %v", len(errs), errs)
+	}
+}
+
 // hasError checks if any non-warning error contains the given substring.
 func hasError(errs []*ResolveError, substr string) bool {
 	for _, e := range errs {
@@ -0,0 +1,283 @@
+package resolver
+
+import (
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+)
+
+// ResolveResult is the outcome of ResolveFile: the resolve errors plus the
+// symbol tables the resolver built along the way.
+type ResolveResult struct {
+	Errors  []*ResolveError
+	Symbols *SymbolTable
+
+	dupErrs []*ResolveError                    // duplicate definition errors, which span definitions
+	defErrs map[ast.Definition][]*ResolveError // errors found inside each definition
+}
+
+// SymbolTable holds the definitions visible in a resolved file, keyed by name.
+// When a name is defined more than once the last definition wins, matching
+// what references resolve to.
+type SymbolTable struct {
+	Workflows     map[string]*ast.WorkflowDef
+	Activities    map[string]*ast.ActivityDef
+	Workers       map[string]*ast.WorkerDef
+	Namespaces    map[string]*ast.NamespaceDef
+	NexusServices map[string]*ast.NexusServiceDef
+	Constants     map[string]*ast.ConstDef
+	Enums         map[string]*ast.EnumDef
+	Endpoints     map[string]*ast.NamespaceEndpoint // across all namespaces
+
+	// Handlers holds the per-workflow symbols for each workflow definition,
+	// including duplicates that lost in Workflows.
+	Handlers map[*ast.WorkflowDef]*WorkflowSymbols
+
+	edges map[ast.Definition][]reference // references made from each definition
+	refs  map[ast.Node][]ast.Node        // edges grouped by target
+}
+
+// WorkflowSymbols holds the names declared inside a single workflow.
+type WorkflowSymbols struct {
+	Signals    map[string]*ast.SignalDecl
+	Queries    map[string]*ast.QueryDecl
+	Updates    map[string]*ast.UpdateDecl
+	Conditions map[string]*ast.ConditionDecl
+	Promises   map[string]*ast.PromiseStmt // top-level promise statements of the workflow body
+}
+
+// References returns the nodes that refer to def, in document order. Call
+// statements, await statements, and set/unset statements are returned whole;
+// worker registrations, namespace workers, async nexus operations, and option
+// entries are returned as the node holding the reference. def is any node a
+// Ref can resolve to: a definition, a signal/query/update/condition
+// declaration, a promise, a nexus operation, or a namespace endpoint.
+func (t *SymbolTable) References(def ast.Node) []ast.Node {
+	return t.refs[def]
+}
+
+// CollectSymbols builds the definition tables for file without resolving
+// references or reporting errors. The reverse reference index is only
+// available from ResolveFile.
+func CollectSymbols(file *ast.File) *SymbolTable {
+	var discard []*ResolveError
+	return collectSymbols(file, &discard)
+}
+
+// collectSymbols builds the definition and endpoint tables, appending
+// duplicate-definition errors.
+func collectSymbols(file *ast.File, errs *[]*ResolveError) *SymbolTable {
+	t := newSymbolTable()
+	t.collectDefinitions(file.Definitions, errs)
+	collectEndpoints(t.Namespaces, t.Endpoints, errs)
+	return t
+}
+
+func newSymbolTable() *SymbolTable {
+	return &SymbolTable{
+		Workflows:     make(map[string]*ast.WorkflowDef),
+		Activities:    make(map[string]*ast.ActivityDef),
+		Workers:       make(map[string]*ast.WorkerDef),
+		Namespaces:    make(map[string]*ast.NamespaceDef),
+		NexusServices: make(map[string]*ast.NexusServiceDef),
+		Constants:     make(map[string]*ast.ConstDef),
+		Enums:         make(map[string]*ast.EnumDef),
+		Endpoints:     make(map[string]*ast.NamespaceEndpoint),
+		Handlers:      make(map[*ast.WorkflowDef]*WorkflowSymbols),
+		edges:         make(map[ast.Definition][]reference),
+	}
+}
+
+// collectDefinitions adds defs to the definition tables, appending
+// duplicate-definition errors. Endpoints are collected separately, once
+// every namespace is known.
+func (t *SymbolTable) collectDefinitions(defs []ast.Definition, errs *[]*ResolveError) {
+	for _, def := range defs {
+		switch d := def.(type) {
+		case *ast.WorkflowDef:
+			collectDef(t.Workflows, d.Name, d, "workflow", ErrDuplicateWorkflow, errs)
+			t.Handlers[d] = collectWorkflowSymbols(d)
+		case *ast.ActivityDef:
+			collectDef(t.Activities, d.Name, d, "activity", ErrDuplicateActivity, errs)
+		case *ast.WorkerDef:
+			collectDef(t.Workers, d.Name, d, "worker", ErrDuplicateWorker, errs)
+		case *ast.NamespaceDef:
+			collectDef(t.Namespaces, d.Name, d, "namespace", ErrDuplicateNamespace, errs)
+		case *ast.NexusServiceDef:
+			collectDef(t.NexusServices, d.Name, d, "nexus service", ErrDuplicateNexusService, errs)
+		case *ast.ConstDef:
+			collectDef(t.Constants, d.Name, d, "constant", ErrDuplicateConst, errs)
+		case *ast.EnumDef:
+			collectDef(t.Enums, d.Name, d, "enum", ErrDuplicateEnum, errs)
+		}
+	}
+}
+
+func newWorkflowSymbols() *WorkflowSymbols {
+	return &WorkflowSymbols{
+		Signals:    make(map[string]*ast.SignalDecl),
+		Queries:    make(map[string]*ast.QueryDecl),
+		Updates:    make(map[string]*ast.UpdateDecl),
+		Conditions: make(map[string]*ast.ConditionDecl),
+		Promises:   make(map[string]*ast.PromiseStmt),
+	}
+}
+
+// collectWorkflowSymbols builds the signal, query, update, condition, and
+// promise maps for one workflow.
+func collectWorkflowSymbols(wf *ast.WorkflowDef) *WorkflowSymbols {
+	ws := newWorkflowSymbols()
+	for _, s := range wf.Signals {
+		ws.Signals[s.Name] = s
+	}
+	for _, q := range wf.Queries {
+		ws.Queries[q.Name] = q
+	}
+	for _, u := range wf.Updates {
+		ws.Updates[u.Name] = u
+	}
+	if wf.State != nil {
+		for _, c := range wf.State.Conditions {
+			ws.Conditions[c.Name] = c
+		}
+	}
+	for _, stmt := range wf.Body {
+		if p, ok := stmt.(*ast.PromiseStmt); ok {
+			ws.Promises[p.Name] = p
+		}
+	}
+	return ws
+}
+
+// reference is one edge of the reverse index: site refers to target.
+type reference struct {
+	target ast.Node
+	site   ast.Node
+}
+
+// indexer accumulates the references made from one definition.
+type indexer []reference
+
+// indexDefinition returns every resolved reference made from def, in
+// document order.
+func indexDefinition(def ast.Definition) []reference {
+	var ix indexer
+	switch d := def.(type) {
+	case *ast.WorkflowDef:
+		ix.options(d.Options)
+		for _, s := range d.Signals {
+			ix.statements(s.Body)
+		}
+		for _, q := range d.Queries {
+			ix.statements(q.Body)
+		}
+		for _, u := range d.Updates {
+			ix.statements(u.Body)
+		}
+		ix.statements(d.Body)
+	case *ast.ActivityDef:
+		ix.options(d.Options)
+		ix.statements(d.Body)
+	case *ast.NexusServiceDef:
+		for _, op := range d.Operations {
+			addRef(&ix, op.Workflow.Resolved, op)
+			ix.statements(op.Body)
+		}
+	case *ast.WorkerDef:
+		indexRefs(&ix, d.Workflows)
+		indexRefs(&ix, d.Activities)
+		indexRefs(&ix, d.Services)
+	case *ast.NamespaceDef:
+		for i := range d.Workers {
+			nw := &d.Workers[i]
+			addRef(&ix, nw.Worker.Resolved, nw)
+			ix.options(nw.Options)
+		}
+		for i := range d.Endpoints {
+			ix.options(d.Endpoints[i].Options)
+		}
+	}
+	return ix
+}
+
+func (ix *indexer) statements(stmts []ast.Statement) {
+	ast.WalkStatements(stmts, func(s ast.Statement) bool {
+		switch s := s.(type) {
+		case *ast.ActivityCall:
+			addRef(ix, s.Activity.Resolved, s)
+			ix.options(s.Options)
+		case *ast.WorkflowCall:
+			addRef(ix, s.Workflow.Resolved, s)
+			ix.options(s.Options)
+		case *ast.NexusCall:
+			ix.nexus(s.Endpoint, s.Service, s.Operation, s)
+			ix.options(s.Options)
+		case *ast.SetStmt:
+			addRef(ix, s.Condition.Resolved, s)
+		case *ast.UnsetStmt:
+			addRef(ix, s.Condition.Resolved, s)
+		}
+		return true
+	}, ast.WithAsyncTargets(func(target ast.AsyncTarget, parent ast.Statement) bool {
+		switch t := target.(type) {
+		case *ast.SignalTarget:
+			addRef(ix, t.Signal.Resolved, parent)
+		case *ast.UpdateTarget:
+			addRef(ix, t.Update.Resolved, parent)
+		case *ast.ActivityTarget:
+			addRef(ix, t.Activity.Resolved, parent)
+		case *ast.WorkflowTarget:
+			addRef(ix, t.Workflow.Resolved, parent)
+		case *ast.NexusTarget:
+			ix.nexus(t.Endpoint, t.Service, t.Operation, parent)
+		case *ast.IdentTarget:
+			addRef(ix, t.Resolved.Promise, parent)
+			addRef(ix, t.Resolved.Condition, parent)
+		case *ast.TimerTarget:
+			addRef(ix, t.Const.Resolved, parent)
+		}
+		return true
+	}))
+}
+
+func (ix *indexer) nexus(endpoint ast.Ref[*ast.NamespaceEndpoint], service ast.Ref[*ast.NexusServiceDef], operation ast.Ref[*ast.NexusOperation], site ast.Node) {
+	addRef(ix, endpoint.Resolved, site)
+	addRef(ix, service.Resolved, site)
+	addRef(ix, operation.Resolved, site)
+}
+
+// options records constant references in an options block, including nested
+// blocks.
+func (ix *indexer) options(ob *ast.OptionsBlock) {
+	if ob == nil {
+		return
+	}
+	var walk func([]*ast.OptionEntry)
+	walk = func(entries []*ast.OptionEntry) {
+		for _, e := range entries {
+			addRef(ix, e.Const.Resolved, e)
+			walk(e.Nested)
+		}
+	}
+	walk(ob.Entries)
+}
+
+// indexRefs records a worker's registrations, each as its own Ref node.
+func indexRefs[T symbol](ix *indexer, refs []ast.Ref[T]) {
+	for i := range refs {
+		addRef(ix, refs[i].Resolved, &refs[i])
+	}
+}
+
+// symbol is the type of a Ref's Resolved field.
+type symbol interface {
+	comparable
+	ast.Node
+}
+
+// addRef records site as a reference to def, ignoring unresolved (nil) defs.
+func addRef[T symbol](ix *indexer, def T, site ast.Node) {
+	var unresolved T
+	if def == unresolved {
+		return
+	}
+	*ix = append(*ix, reference{target: def, site: site})
+}
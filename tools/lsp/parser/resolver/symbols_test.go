@@ -0,0 +1,120 @@
+package resolver
+
+import (
+	"testing"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+)
+
+const symbolsInput = `const wait = 5m
+
+workflow Order(id: string) -> (Result):
+    state:
+        condition ready
+    signal Approve():
+        set ready
+    activity Charge(id)
+    await one:
+        ready:
+            activity Charge(id)
+        timer(wait):
+            workflow Ship(id)
+
+workflow Ship(id: string):
+    activity Charge(id)
+
+activity Charge(id: string):
+    return id
+
+worker w:
+    workflow Order
+    workflow Ship
+    activity Charge
+
+namespace prod:
+    worker w
+        options:
+            task_queue: "orders"
+`
+
+func TestResolveFileSymbols(t *testing.T) {
+	file := mustParse(t, symbolsInput)
+	res := ResolveFile(file)
+	if len(res.Errors) != 0 {
+		t.Fatalf("unexpected errors: %v", res.Errors)
+	}
+	syms := res.Symbols
+
+	order := file.Definitions[1].(*ast.WorkflowDef)
+	ship := file.Definitions[2].(*ast.WorkflowDef)
+	charge := file.Definitions[3].(*ast.ActivityDef)
+	worker := file.Definitions[4].(*ast.WorkerDef)
+	if syms.Workflows["Order"] != order || syms.Workflows["Ship"] != ship {
+		t.Errorf("workflow table: %v", syms.Workflows)
+	}
+	if syms.Activities["Charge"] != charge || syms.Workers["w"] != worker {
+		t.Error("activity or worker table missing definitions")
+	}
+	if syms.Constants["wait"] != file.Definitions[0] || syms.Namespaces["prod"] == nil {
+		t.Error("constant or namespace table missing definitions")
+	}
+
+	ws := syms.Handlers[order]
+	if ws == nil || ws.Signals["Approve"] == nil || ws.Conditions["ready"] == nil {
+		t.Fatalf("Order handlers: %+v", ws)
+	}
+	if len(syms.Handlers[ship].Signals) != 0 {
+		t.Error("Ship should declare no signals")
+	}
+
+	// Two calls in Order (one inside await one), one in Ship, one worker registration.
+	refs := syms.References(charge)
+	if len(refs) != 4 {
+		t.Fatalf("expected 4 references to Charge, got %d: %v", len(refs), refs)
+	}
+	if _, ok := refs[0].(*ast.ActivityCall); !ok || refs[0].NodeLine() != 8 {
+		t.Errorf("first reference should be the call on line 8, got %T at %d", refs[0], refs[0].NodeLine())
+	}
+	if _, ok := refs[3].(*ast.Ref[*ast.ActivityDef]); !ok {
+		t.Errorf("last reference should be the worker registration, got %T", refs[3])
+	}
+
+	// The condition is referenced by set and by the await one case.
+	if got := len(syms.References(ws.Conditions["ready"])); got != 2 {
+		t.Errorf("expected 2 references to condition ready, got %d", got)
+	}
+	if got := len(syms.References(file.Definitions[0])); got != 1 {
+		t.Errorf("expected 1 reference to constant wait, got %d", got)
+	}
+	if got := syms.References(worker); len(got) != 1 || got[0] != &file.Definitions[5].(*ast.NamespaceDef).Workers[0] {
+		t.Errorf("expected the namespace worker to reference w, got %v", got)
+	}
+	if got := len(syms.References(ship)); got != 2 {
+		t.Errorf("expected 2 references to Ship, got %d", got)
+	}
+}
+
+func TestResolveFileUnresolvedNotIndexed(t *testing.T) {
+	file := mustParse(t, `workflow Foo():
+    activity Missing()
+`)
+	res := ResolveFile(file)
+	if !hasError(res.Errors, "undefined activity: Missing") {
+		t.Fatalf("expected undefined activity error, got %v", res.Errors)
+	}
+	if len(res.Symbols.refs) != 0 {
+		t.Errorf("unresolved references should not be indexed: %v", res.Symbols.refs)
+	}
+}
+
+func TestCollectSymbolsMatchesResolve(t *testing.T) {
+	file := mustParse(t, symbolsInput)
+	collected := CollectSymbols(file)
+	resolved := ResolveFile(file).Symbols
+	if len(collected.Workflows) != len(resolved.Workflows) || len(collected.Endpoints) != len(resolved.Endpoints) {
+		t.Error("CollectSymbols and ResolveFile disagree on definitions")
+	}
+	if len(collected.References(collected.Activities["Charge"])) != 0 {
+		t.Error("CollectSymbols should not index references")
+	}
+}
@@ -0,0 +1,34 @@
+// Package telemetry defines hooks through which a program running the TWF
+// analysis pipeline observes it, for example to export metrics to
+// Prometheus or OpenTelemetry.
+//
+// An analysis covers one or more documents: each is parsed on its own, then
+// their definitions are resolved and validated together. The pipeline calls
+// the hooks synchronously as each stage ends, so they must be quick and safe
+// for concurrent use.
+package telemetry
+
+import "time"
+
+// Hooks receives the measurements of analyses.
+type Hooks interface {
+	// Parsed reports that parsing document took d.
+	Parsed(document string, d time.Duration)
+	// Resolved reports that resolving the definitions of documents took d.
+	Resolved(documents []string, d time.Duration)
+	// Validated reports that validating the definitions of documents took d.
+	Validated(documents []string, d time.Duration)
+	// Diagnosed reports the diagnostics of a completed analysis of
+	// documents, from all stages, by severity. An analysis cancelled before
+	// it completes is not diagnosed.
+	Diagnosed(documents []string, errors, warnings int)
+}
+
+// Nop is Hooks that ignores every measurement: the pipeline's default. Embed
+// it to implement only some of the hooks.
+type Nop struct{}
+
+func (Nop) Parsed(string, time.Duration)      {}
+func (Nop) Resolved([]string, time.Duration)  {}
+func (Nop) Validated([]string, time.Duration) {}
+func (Nop) Diagnosed([]string, int, int)      {}
@@ -0,0 +1,83 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Aliases maps experimental spellings to the keyword sequences they stand
+// for, such as "sleep" for TIMER or "race" for AWAIT ONE. They let the
+// vocabulary be trialled with users before it is added to the token table.
+type Aliases map[string][]TokenType
+
+// ParseAliases parses an alias table from JSON: an object mapping each alias
+// to the keywords it expands to, separated by spaces:
+//
+//	{"sleep": "timer", "race": "await one"}
+//
+// An alias must be an identifier that is not already a keyword, and every
+// word of its expansion must be a keyword.
+func ParseAliases(data []byte) (Aliases, error) {
+	var raw map[string]string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("aliases: %w", err)
+	}
+	aliases := make(Aliases, len(raw))
+	// In name order, so the error for a file with several bad aliases
+	// names the same one every time.
+	for _, alias := range slices.Sorted(maps.Keys(raw)) {
+		expansion := raw[alias]
+		if !isIdent(alias) {
+			return nil, fmt.Errorf("alias %q is not an identifier", alias)
+		}
+		if LookupIdent(alias) != IDENT {
+			return nil, fmt.Errorf("alias %q is already a keyword", alias)
+		}
+		words := strings.Fields(expansion)
+		if len(words) == 0 {
+			return nil, fmt.Errorf("alias %q has an empty expansion", alias)
+		}
+		for _, w := range words {
+			tt, ok := keywords[w]
+			if !ok {
+				return nil, fmt.Errorf("alias %q: %q is not a keyword", alias, w)
+			}
+			aliases[alias] = append(aliases[alias], tt)
+		}
+	}
+	return aliases, nil
+}
+
+// Names returns the aliases in sorted order.
+func (a Aliases) Names() []string {
+	names := make([]string, 0, len(a))
+	for name := range a {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Expansion returns the keywords alias stands for as they would be written,
+// or "" when it is not an alias.
+func (a Aliases) Expansion(alias string) string {
+	words := make([]string, len(a[alias]))
+	for i, tt := range a[alias] {
+		words[i] = strings.ToLower(tt.String())
+	}
+	return strings.Join(words, " ")
+}
+
+func isIdent(s string) bool {
+	for i, r := range s {
+		letter := r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		if !letter && (i == 0 || r < '0' || r > '9') {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,11 @@
+package token
+
+import "strings"
+
+// LookupKeywordFold returns the keyword ident spells when case is ignored,
+// and its canonical, lowercase spelling.
+func LookupKeywordFold(ident string) (TokenType, string, bool) {
+	lower := strings.ToLower(ident)
+	tt, ok := keywords[lower]
+	return tt, lower, ok
+}
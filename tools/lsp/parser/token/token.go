@@ -20,6 +20,8 @@ const (
 	WORKFLOW
 	ACTIVITY
 	WORKER
+	CONST
+	ENUM
 
 	// Keywords -- worker-level declarations
 	NAMESPACE
@@ -59,6 +61,7 @@ const (
 	// Keywords -- control flow
 	IF
 	ELSE
+	ELIF
 	FOR
 	IN
 
@@ -76,16 +79,25 @@ const (
 	ARROW      // ->
 	LEFT_ARROW // <-
 	DOT        // .
+	COMMA      // ,
+	LBRACKET   // [
+	RBRACKET   // ]
+	LBRACE     // {
+	RBRACE     // }
+	AT         // @ (annotation)
+	OPERATOR   // expression operators: == != < <= > >= + - * / % ! && || =
 
 	// Literals
 	NUMBER   // numeric literal (e.g. 3, 2.0)
 	DURATION // numeric with duration suffix (e.g. 60s, 5m, 1h, 500ms)
+	BOOL     // true or false
 
 	// Values
 	IDENT    // non-keyword identifiers
 	STRING   // quoted string
 	ARGS     // raw content between ( and ), no nested parens
 	COMMENT  // text after #
+	TEXT     // free-form lines of a description: block
 	RAW_TEXT // anything else
 
 	tokenCount // sentinel: must be last — used for compile-time table size check
@@ -112,6 +124,8 @@ var tokenTable = [...]tokenInfo{
 	WORKFLOW:        {"WORKFLOW", true},
 	ACTIVITY:        {"ACTIVITY", true},
 	WORKER:          {"WORKER", true},
+	CONST:           {"CONST", true},
+	ENUM:            {"ENUM", true},
 	NAMESPACE:       {"NAMESPACE", true},
 	TASK_QUEUE:      {"TASK_QUEUE", true},
 	SIGNAL:          {"SIGNAL", true},
@@ -135,6 +149,7 @@ var tokenTable = [...]tokenInfo{
 	CASE:            {"CASE", true},
 	IF:              {"IF", true},
 	ELSE:            {"ELSE", true},
+	ELIF:            {"ELIF", true},
 	FOR:             {"FOR", true},
 	IN:              {"IN", true},
 	CLOSE:           {"CLOSE", true},
@@ -148,12 +163,21 @@ var tokenTable = [...]tokenInfo{
 	ARROW:           {"ARROW", false},
 	LEFT_ARROW:      {"LEFT_ARROW", false},
 	DOT:             {"DOT", false},
+	COMMA:           {"COMMA", false},
+	LBRACKET:        {"LBRACKET", false},
+	RBRACKET:        {"RBRACKET", false},
+	LBRACE:          {"LBRACE", false},
+	RBRACE:          {"RBRACE", false},
+	AT:              {"AT", false},
+	OPERATOR:        {"OPERATOR", false},
 	NUMBER:          {"NUMBER", false},
 	DURATION:        {"DURATION", false},
+	BOOL:            {"BOOL", false},
 	IDENT:           {"IDENT", false},
 	STRING:          {"STRING", false},
 	ARGS:            {"ARGS", false},
 	COMMENT:         {"COMMENT", false},
+	TEXT:            {"TEXT", false},
 	RAW_TEXT:        {"RAW_TEXT", false},
 }
 
@@ -170,6 +194,19 @@ func init() {
 	}
 }
 
+// Keywords returns the spelling of every keyword in token table order.
+// Editor grammars are generated from this list so they cannot drift from
+// the lexer.
+func Keywords() []string {
+	var kws []string
+	for _, info := range tokenTable {
+		if info.isKeyword {
+			kws = append(kws, strings.ToLower(info.name))
+		}
+	}
+	return kws
+}
+
 func (t TokenType) String() string {
 	if int(t) >= 0 && int(t) < len(tokenTable) && tokenTable[t].name != "" {
 		return tokenTable[t].name
@@ -183,6 +220,19 @@ type Token struct {
 	Literal string
 	Line    int
 	Column  int
+
+	// Offset is the byte offset of the token's first character in the
+	// lexer's input. For ARGS that is the '(', so the argument text starts
+	// at Offset+1; tokens an alias expands to share the alias's offset.
+	Offset int
+
+	// Triple is set on STRING tokens written with """ delimiters. Such
+	// strings may span lines; Line and Column mark the opening quotes.
+	Triple bool
+
+	// Unterminated is set on ARGS tokens whose '(' no ')' closes before
+	// the end of the input.
+	Unterminated bool
 }
 
 func (t Token) String() string {
@@ -194,13 +244,16 @@ func (t Token) String() string {
 
 // LookupIdent returns the TokenType for an identifier string.
 // If the identifier is a keyword, the keyword token type is returned.
-// Otherwise, IDENT is returned.
+// The boolean literals true and false return BOOL. Otherwise, IDENT is returned.
 // Note: lookup is case-sensitive. Keywords are lowercase, so "Workflow" is
 // treated as an IDENT, not a keyword. This is intentional — the DSL is
-// case-sensitive.
+// case-sensitive. Lexers fold case only with lexer.Options.CaseInsensitiveKeywords.
 func LookupIdent(ident string) TokenType {
 	if tt, ok := keywords[ident]; ok {
 		return tt
 	}
+	if ident == "true" || ident == "false" {
+		return BOOL
+	}
 	return IDENT
 }
@@ -0,0 +1,368 @@
+// Package topology reports where the definitions of a design run: the
+// workers each namespace instantiates, the task queue each polls and what
+// it registers, the nexus endpoints served on those queues, and the
+// workflows started on a cron schedule. It is an inventory for planning
+// worker deployments, read from resolved designs.
+package topology
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+)
+
+// Report is the topology of a design.
+type Report struct {
+	Namespaces  []Namespace  `json:"namespaces"`
+	Definitions []Definition `json:"definitions"`
+	Schedules   []Schedule   `json:"schedules"`
+}
+
+// Namespace is a namespace and the workers and endpoints it instantiates.
+type Namespace struct {
+	Name      string     `json:"name"`
+	Workers   []Worker   `json:"workers"`
+	Endpoints []Endpoint `json:"endpoints,omitempty"`
+}
+
+// Worker is a worker instantiated in a namespace, with what it registers.
+type Worker struct {
+	Name       string   `json:"name"`
+	TaskQueue  string   `json:"taskQueue"`
+	Workflows  []string `json:"workflows,omitempty"`
+	Activities []string `json:"activities,omitempty"`
+	Services   []string `json:"services,omitempty"`
+}
+
+// Endpoint is a nexus endpoint, with the services registered by the
+// workers of its namespace polling its task queue.
+type Endpoint struct {
+	Name      string   `json:"name"`
+	TaskQueue string   `json:"taskQueue"`
+	Services  []string `json:"services,omitempty"`
+}
+
+// Placement is a worker a definition runs on.
+type Placement struct {
+	Namespace string `json:"namespace"`
+	TaskQueue string `json:"taskQueue"`
+	Worker    string `json:"worker"`
+}
+
+// Definition is a workflow or activity and where it runs.
+type Definition struct {
+	Kind        string              `json:"kind"` // workflow or activity
+	Name        string              `json:"name"`
+	SourceFile  string              `json:"sourceFile,omitempty"`
+	Line        int                 `json:"line"`
+	Annotations map[string][]string `json:"annotations,omitempty"`
+	// RunsOn lists the workers registering it; empty when no worker a
+	// namespace instantiates does.
+	RunsOn []Placement `json:"runsOn"`
+	// TaskQueues are the queues named by task_queue in its options or in
+	// the options of calls starting it, which some worker must poll.
+	TaskQueues []string `json:"taskQueues,omitempty"`
+}
+
+// Schedule is a workflow started on a cron schedule.
+type Schedule struct {
+	Workflow   string `json:"workflow"`
+	Cron       string `json:"cron"`
+	SourceFile string `json:"sourceFile,omitempty"`
+	Line       int    `json:"line"`
+	// Caller is the workflow whose call sets the schedule; empty when the
+	// workflow's own options set it.
+	Caller string `json:"caller,omitempty"`
+}
+
+// Build returns the topology of file, whose references are resolved.
+func Build(file *ast.File) *Report {
+	r := &Report{Namespaces: []Namespace{}, Definitions: []Definition{}, Schedules: []Schedule{}}
+	placements := make(map[string][]Placement) // by definition kind and name
+	queues := make(map[string][]string)        // task_queue options, by kind and name
+
+	for _, def := range file.Definitions {
+		ns, ok := def.(*ast.NamespaceDef)
+		if !ok {
+			continue
+		}
+		out := Namespace{Name: ns.Name, Workers: []Worker{}}
+		for _, nw := range ns.Workers {
+			w := Worker{Name: nw.Worker.Name, TaskQueue: taskQueue(nw.Options)}
+			if wd := nw.Worker.Resolved; wd != nil {
+				w.Workflows = refNames(wd.Workflows)
+				w.Activities = refNames(wd.Activities)
+				w.Services = refNames(wd.Services)
+			}
+			at := Placement{Namespace: ns.Name, TaskQueue: w.TaskQueue, Worker: w.Name}
+			for _, name := range w.Workflows {
+				placements["workflow "+name] = append(placements["workflow "+name], at)
+			}
+			for _, name := range w.Activities {
+				placements["activity "+name] = append(placements["activity "+name], at)
+			}
+			out.Workers = append(out.Workers, w)
+		}
+		for _, ep := range ns.Endpoints {
+			e := Endpoint{Name: ep.EndpointName, TaskQueue: taskQueue(ep.Options)}
+			for _, w := range out.Workers {
+				if w.TaskQueue == e.TaskQueue {
+					e.Services = append(e.Services, w.Services...)
+				}
+			}
+			slices.Sort(e.Services)
+			e.Services = slices.Compact(e.Services)
+			out.Endpoints = append(out.Endpoints, e)
+		}
+		r.Namespaces = append(r.Namespaces, out)
+	}
+
+	addQueue := func(key, queue string) {
+		if queue != "" && !slices.Contains(queues[key], queue) {
+			queues[key] = append(queues[key], queue)
+		}
+	}
+	for _, def := range file.Definitions {
+		switch d := def.(type) {
+		case *ast.WorkflowDef:
+			addQueue("workflow "+d.Name, taskQueue(d.Options))
+			if cron := ast.OptionValue(d.Options, "cron_schedule"); cron != "" {
+				r.Schedules = append(r.Schedules, Schedule{Workflow: d.Name, Cron: cron, SourceFile: d.SourceFile, Line: d.Options.Line})
+			}
+			for _, body := range ast.WorkflowBodies(d) {
+				ast.WalkStatements(body, func(s ast.Statement) bool {
+					switch c := s.(type) {
+					case *ast.ActivityCall:
+						addQueue("activity "+c.Activity.Name, taskQueue(c.Options))
+					case *ast.WorkflowCall:
+						addQueue("workflow "+c.Workflow.Name, taskQueue(c.Options))
+						if cron := ast.OptionValue(c.Options, "cron_schedule"); cron != "" {
+							r.Schedules = append(r.Schedules, Schedule{Workflow: c.Workflow.Name, Cron: cron, SourceFile: d.SourceFile, Line: c.Line, Caller: d.Name})
+						}
+					}
+					return true
+				})
+			}
+		case *ast.ActivityDef:
+			addQueue("activity "+d.Name, taskQueue(d.Options))
+		}
+	}
+
+	for _, def := range file.Definitions {
+		var out Definition
+		switch d := def.(type) {
+		case *ast.WorkflowDef:
+			out = Definition{Kind: "workflow", Name: d.Name, SourceFile: d.SourceFile, Line: d.Line}
+		case *ast.ActivityDef:
+			out = Definition{Kind: "activity", Name: d.Name, SourceFile: d.SourceFile, Line: d.Line}
+		default:
+			continue
+		}
+		key := out.Kind + " " + out.Name
+		out.RunsOn = placements[key]
+		if out.RunsOn == nil {
+			out.RunsOn = []Placement{}
+		}
+		out.TaskQueues = queues[key]
+		slices.Sort(out.TaskQueues)
+		for _, a := range ast.Annotations(def) {
+			if out.Annotations == nil {
+				out.Annotations = make(map[string][]string)
+			}
+			out.Annotations[a.Name] = append(out.Annotations[a.Name], a.Value())
+		}
+		r.Definitions = append(r.Definitions, out)
+	}
+	return r
+}
+
+// taskQueue returns the task_queue set in ob, or "".
+func taskQueue(ob *ast.OptionsBlock) string {
+	return ast.OptionValue(ob, "task_queue")
+}
+
+func refNames[T ast.Definition](refs []ast.Ref[T]) []string {
+	names := make([]string, len(refs))
+	for i, ref := range refs {
+		names[i] = ref.Name
+	}
+	return names
+}
+
+// Text renders r for a terminal: each namespace's task queues with the
+// workers polling them, then where each workflow and activity runs, then
+// the schedules.
+func (r *Report) Text() string {
+	var b strings.Builder
+	for _, ns := range r.Namespaces {
+		fmt.Fprintf(&b, "namespace %s\n", ns.Name)
+		for _, q := range queueOrder(ns) {
+			fmt.Fprintf(&b, "  task queue %s\n", queueName(q))
+			for _, w := range ns.Workers {
+				if w.TaskQueue != q {
+					continue
+				}
+				fmt.Fprintf(&b, "    worker %s\n", w.Name)
+				writeList(&b, "      workflows", w.Workflows)
+				writeList(&b, "      activities", w.Activities)
+				writeList(&b, "      nexus services", w.Services)
+			}
+			for _, e := range ns.Endpoints {
+				if e.TaskQueue == q {
+					fmt.Fprintf(&b, "    endpoint %s\n", e.Name)
+					writeList(&b, "      serves", e.Services)
+				}
+			}
+		}
+		b.WriteString("\n")
+	}
+	for _, kind := range [...]struct{ name, heading string }{{"workflow", "workflows"}, {"activity", "activities"}} {
+		width := 0
+		for _, d := range r.Definitions {
+			if d.Kind == kind.name {
+				width = max(width, len(d.Name))
+			}
+		}
+		if width == 0 {
+			continue
+		}
+		b.WriteString(kind.heading + "\n")
+		for _, d := range r.Definitions {
+			if d.Kind == kind.name {
+				fmt.Fprintf(&b, "  %-*s  %s\n", width, d.Name, runsOn(d))
+			}
+		}
+		b.WriteString("\n")
+	}
+	if len(r.Schedules) > 0 {
+		width := 0
+		for _, s := range r.Schedules {
+			width = max(width, len(s.Workflow))
+		}
+		b.WriteString("schedules\n")
+		for _, s := range r.Schedules {
+			fmt.Fprintf(&b, "  %-*s  %q  %s\n", width, s.Workflow, s.Cron, scheduleSource(s))
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// Markdown renders r as Markdown tables, for a wiki page or a pull request.
+func (r *Report) Markdown() string {
+	var b strings.Builder
+	b.WriteString("# Worker topology\n")
+	for _, ns := range r.Namespaces {
+		fmt.Fprintf(&b, "\n## Namespace %s\n\n", ns.Name)
+		b.WriteString("| Task queue | Worker | Workflows | Activities | Nexus services |\n")
+		b.WriteString("|------------|--------|-----------|------------|----------------|\n")
+		for _, q := range queueOrder(ns) {
+			for _, w := range ns.Workers {
+				if w.TaskQueue == q {
+					fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n", code(queueName(q)), w.Name, cell(w.Workflows), cell(w.Activities), cell(w.Services))
+				}
+			}
+		}
+		if len(ns.Endpoints) > 0 {
+			b.WriteString("\n| Nexus endpoint | Task queue | Serves |\n")
+			b.WriteString("|----------------|------------|--------|\n")
+			for _, e := range ns.Endpoints {
+				fmt.Fprintf(&b, "| %s | %s | %s |\n", e.Name, code(queueName(e.TaskQueue)), cell(e.Services))
+			}
+		}
+	}
+	if len(r.Definitions) > 0 {
+		b.WriteString("\n## Definitions\n\n")
+		b.WriteString("| Kind | Name | Runs on | Source |\n")
+		b.WriteString("|------|------|---------|--------|\n")
+		for _, d := range r.Definitions {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", d.Kind, d.Name, runsOn(d), source(d.SourceFile, d.Line))
+		}
+	}
+	if len(r.Schedules) > 0 {
+		b.WriteString("\n## Schedules\n\n")
+		b.WriteString("| Workflow | Cron | Set by |\n")
+		b.WriteString("|----------|------|--------|\n")
+		for _, s := range r.Schedules {
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", s.Workflow, code(s.Cron), scheduleSource(s))
+		}
+	}
+	return b.String()
+}
+
+// queueOrder returns the task queues of ns's workers and endpoints in the
+// order they first appear.
+func queueOrder(ns Namespace) []string {
+	var out []string
+	for _, w := range ns.Workers {
+		if !slices.Contains(out, w.TaskQueue) {
+			out = append(out, w.TaskQueue)
+		}
+	}
+	for _, e := range ns.Endpoints {
+		if !slices.Contains(out, e.TaskQueue) {
+			out = append(out, e.TaskQueue)
+		}
+	}
+	return out
+}
+
+func queueName(q string) string {
+	if q == "" {
+		return "(no task_queue)"
+	}
+	return q
+}
+
+// runsOn describes where d runs, as "Orders/orders (OrderWorker)", and
+// the task queues its options name that no placement polls.
+func runsOn(d Definition) string {
+	var parts []string
+	for _, p := range d.RunsOn {
+		parts = append(parts, fmt.Sprintf("%s/%s (%s)", p.Namespace, queueName(p.TaskQueue), p.Worker))
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "no worker in a namespace")
+	}
+	for _, q := range d.TaskQueues {
+		if !slices.ContainsFunc(d.RunsOn, func(p Placement) bool { return p.TaskQueue == q }) {
+			parts = append(parts, "task_queue "+q+" polled by none of them")
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+func scheduleSource(s Schedule) string {
+	by := "its options"
+	if s.Caller != "" {
+		by = "call in " + s.Caller
+	}
+	return by + " at " + source(s.SourceFile, s.Line)
+}
+
+func source(file string, line int) string {
+	if file == "" {
+		return fmt.Sprintf("line %d", line)
+	}
+	return fmt.Sprintf("%s:%d", file, line)
+}
+
+func writeList(b *strings.Builder, label string, names []string) {
+	if len(names) > 0 {
+		fmt.Fprintf(b, "%s: %s\n", label, strings.Join(names, ", "))
+	}
+}
+
+func cell(names []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	return strings.Join(names, ", ")
+}
+
+// code renders s as inline code, on one line so it stays in its cell.
+func code(s string) string {
+	return "`" + strings.ReplaceAll(s, "\n", " ") + "`"
+}
@@ -0,0 +1,132 @@
+package topology
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/resolver"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/twftest"
+)
+
+const batchSource = `workflow Nightly():
+    options:
+        cron_schedule: "0 2 * * *"
+    activity Export()
+    workflow Report()
+        options:
+            task_queue: "reports"
+            cron_schedule: "0 6 * * 1"
+
+@owner("data")
+workflow Report():
+    activity Export()
+        options:
+            task_queue: "exports"
+
+workflow Orphan():
+    return
+
+activity Export():
+    return
+
+worker BatchWorker:
+    workflow Nightly
+    workflow Report
+    activity Export
+    nexus service BatchService
+
+nexus service BatchService:
+    sync Run(input: Input) -> (Output):
+        return
+
+namespace batch:
+    worker BatchWorker
+        options:
+            task_queue: "batch"
+    nexus endpoint BatchEndpoint
+        options:
+            task_queue: "batch"
+`
+
+func build(t *testing.T, src string) *Report {
+	t.Helper()
+	file := twftest.MustParse(t, src)
+	resolver.Resolve(file)
+	return Build(file)
+}
+
+func TestBuild(t *testing.T) {
+	r := build(t, batchSource)
+
+	if len(r.Namespaces) != 1 {
+		t.Fatalf("got %d namespaces, want 1", len(r.Namespaces))
+	}
+	ns := r.Namespaces[0]
+	if len(ns.Workers) != 1 || len(ns.Endpoints) != 1 {
+		t.Fatalf("unexpected namespace: %+v", ns)
+	}
+	w := ns.Workers[0]
+	if w.Name != "BatchWorker" || w.TaskQueue != "batch" ||
+		strings.Join(w.Workflows, ",") != "Nightly,Report" ||
+		strings.Join(w.Activities, ",") != "Export" ||
+		strings.Join(w.Services, ",") != "BatchService" {
+		t.Errorf("unexpected worker: %+v", w)
+	}
+	if e := ns.Endpoints[0]; e.Name != "BatchEndpoint" || strings.Join(e.Services, ",") != "BatchService" {
+		t.Errorf("endpoint should serve the services of the workers on its queue: %+v", e)
+	}
+
+	defs := make(map[string]Definition)
+	for _, d := range r.Definitions {
+		defs[d.Name] = d
+	}
+	if d := defs["Report"]; len(d.RunsOn) != 1 || strings.Join(d.TaskQueues, ",") != "reports" ||
+		strings.Join(d.Annotations["owner"], ",") != "data" {
+		t.Errorf("unexpected Report: %+v", d)
+	}
+	if d := defs["Export"]; d.Kind != "activity" || strings.Join(d.TaskQueues, ",") != "exports" {
+		t.Errorf("the task_queue of an activity call should be kept: %+v", d)
+	}
+	if d := defs["Orphan"]; d.RunsOn == nil || len(d.RunsOn) != 0 {
+		t.Errorf("an unregistered workflow should run on no worker: %+v", d)
+	}
+
+	if len(r.Schedules) != 2 {
+		t.Fatalf("got %d schedules, want 2: %+v", len(r.Schedules), r.Schedules)
+	}
+	if s := r.Schedules[0]; s.Workflow != "Nightly" || s.Cron != "0 2 * * *" || s.Line != 2 || s.Caller != "" {
+		t.Errorf("unexpected schedule from options: %+v", s)
+	}
+	if s := r.Schedules[1]; s.Workflow != "Report" || s.Cron != "0 6 * * 1" || s.Line != 5 || s.Caller != "Nightly" {
+		t.Errorf("unexpected schedule from a call: %+v", s)
+	}
+}
+
+func TestRender(t *testing.T) {
+	r := build(t, batchSource)
+
+	text := r.Text()
+	for _, want := range []string{
+		"namespace batch\n  task queue batch\n    worker BatchWorker\n",
+		"    endpoint BatchEndpoint\n      serves: BatchService\n",
+		"  Report   batch/batch (BatchWorker); task_queue reports polled by none of them\n",
+		"  Orphan   no worker in a namespace\n",
+		"  Report   \"0 6 * * 1\"  call in Nightly at line 5",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text missing %q:\n%s", want, text)
+		}
+	}
+
+	md := r.Markdown()
+	for _, want := range []string{
+		"| `batch` | BatchWorker | Nightly, Report | Export | BatchService |\n",
+		"| BatchEndpoint | `batch` | BatchService |\n",
+		"| activity | Export | batch/batch (BatchWorker); task_queue exports polled by none of them | line 19 |\n",
+		"| Nightly | `0 2 * * *` | its options at line 2 |\n",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown missing %q:\n%s", want, md)
+		}
+	}
+}
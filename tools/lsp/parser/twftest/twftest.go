@@ -0,0 +1,226 @@
+// Package twftest helps test Go code built on the TWF parser, such as code
+// generators and custom lint rules: parsing and resolving sources inline,
+// matching the diagnostics they produce, and comparing output with golden
+// files.
+package twftest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/parser"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/resolver"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/validator"
+)
+
+// UpdateEnv is the environment variable that, when set, makes Golden write
+// its files instead of comparing against them.
+const UpdateEnv = "TWFTEST_UPDATE"
+
+// MustParse parses src, failing the test on any parse error.
+func MustParse(t testing.TB, src string) *ast.File {
+	t.Helper()
+	file, errs := parser.ParseFileAll(src)
+	for _, e := range errs {
+		t.Errorf("parse error at %d:%d: %s", e.Line, e.Column, e.Msg)
+	}
+	if len(errs) != 0 {
+		t.FailNow()
+	}
+	return file
+}
+
+// AssertResolves parses and resolves src, failing the test on any parse or
+// resolve error. Resolve warnings are allowed.
+func AssertResolves(t testing.TB, src string) *ast.File {
+	t.Helper()
+	file := MustParse(t, src)
+	failed := false
+	for _, e := range resolver.Resolve(file) {
+		if e.Severity != "warning" {
+			t.Errorf("resolve error at %d:%d: %s", e.Line, e.Column, e.Msg)
+			failed = true
+		}
+	}
+	if failed {
+		t.FailNow()
+	}
+	return file
+}
+
+// Diagnostic is a parse, resolve, or validation error or warning, as the
+// language server reports it.
+type Diagnostic struct {
+	Stage    string // "parse", "resolve", or "validate"
+	Line     int
+	Column   int
+	Severity string // "error" or "warning"
+	Msg      string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s %s: %s", d.Line, d.Column, d.Stage, d.Severity, d.Msg)
+}
+
+// Check parses, resolves, and validates src, returning the file and every
+// diagnostic in stage order. Files that do not parse are not resolved.
+func Check(src string) (*ast.File, []Diagnostic) {
+	var diags []Diagnostic
+	file, parseErrs := parser.ParseFileAll(src)
+	for _, e := range parseErrs {
+		diags = append(diags, Diagnostic{Stage: "parse", Line: e.Line, Column: e.Column, Severity: "error", Msg: e.Msg})
+	}
+	if len(parseErrs) != 0 {
+		return file, diags
+	}
+	for _, e := range resolver.Resolve(file) {
+		diags = append(diags, Diagnostic{Stage: "resolve", Line: e.Line, Column: e.Column, Severity: severity(e.Severity), Msg: e.Msg})
+	}
+	for _, e := range validator.Validate(file) {
+		diags = append(diags, Diagnostic{Stage: "validate", Line: e.Line, Column: e.Column, Severity: severity(e.Severity), Msg: e.Msg})
+	}
+	return file, diags
+}
+
+func severity(s string) string {
+	if s == "" {
+		return "error"
+	}
+	return s
+}
+
+// DiagnosticMatcher matches the diagnostics agreeing with the fields it
+// sets; zero fields match anything.
+type DiagnosticMatcher struct {
+	Stage    string
+	Line     int
+	Severity string
+	Contains string // a substring of the message
+}
+
+// Error matches an error whose message contains substr.
+func Error(substr string) DiagnosticMatcher {
+	return DiagnosticMatcher{Severity: "error", Contains: substr}
+}
+
+// Warning matches a warning whose message contains substr.
+func Warning(substr string) DiagnosticMatcher {
+	return DiagnosticMatcher{Severity: "warning", Contains: substr}
+}
+
+// At returns m matching only diagnostics on line.
+func (m DiagnosticMatcher) At(line int) DiagnosticMatcher {
+	m.Line = line
+	return m
+}
+
+// Match reports whether d agrees with m.
+func (m DiagnosticMatcher) Match(d Diagnostic) bool {
+	return (m.Stage == "" || m.Stage == d.Stage) &&
+		(m.Line == 0 || m.Line == d.Line) &&
+		(m.Severity == "" || m.Severity == d.Severity) &&
+		strings.Contains(d.Msg, m.Contains)
+}
+
+func (m DiagnosticMatcher) String() string {
+	var parts []string
+	if m.Stage != "" {
+		parts = append(parts, m.Stage)
+	}
+	if m.Severity != "" {
+		parts = append(parts, m.Severity)
+	}
+	if m.Line != 0 {
+		parts = append(parts, fmt.Sprintf("on line %d", m.Line))
+	}
+	if m.Contains != "" {
+		parts = append(parts, fmt.Sprintf("containing %q", m.Contains))
+	}
+	if len(parts) == 0 {
+		return "any diagnostic"
+	}
+	return strings.Join(parts, " ")
+}
+
+// AssertDiagnostics checks src and fails the test unless its diagnostics
+// and want pair up: each matcher matching a diagnostic of its own, and no
+// diagnostic left over. Matchers take the first unpaired diagnostic they
+// match, in order. It returns the checked file.
+func AssertDiagnostics(t testing.TB, src string, want ...DiagnosticMatcher) *ast.File {
+	t.Helper()
+	file, diags := Check(src)
+	used := make([]bool, len(diags))
+	for _, m := range want {
+		found := false
+		for i, d := range diags {
+			if !used[i] && m.Match(d) {
+				used[i], found = true, true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("no diagnostic matched %s", m)
+		}
+	}
+	for i, d := range diags {
+		if !used[i] {
+			t.Errorf("unexpected diagnostic %s", d)
+		}
+	}
+	return file
+}
+
+// Golden compares got with the contents of the golden file at path,
+// failing the test at the first line that differs. With UpdateEnv set, it
+// writes got to path instead, creating its directory.
+func Golden(t testing.TB, path string, got []byte) {
+	t.Helper()
+	if os.Getenv(UpdateEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file: %v (set %s=1 to create it)", err, UpdateEnv)
+	}
+	if bytes.Equal(got, want) {
+		return
+	}
+	gotLines, wantLines := strings.Split(string(got), "\n"), strings.Split(string(want), "\n")
+	for i := 0; ; i++ {
+		g, w := line(gotLines, i), line(wantLines, i)
+		if g != w {
+			t.Errorf("%s:%d: got %s, want %s (set %s=1 to update)", path, i+1, g, w, UpdateEnv)
+			return
+		}
+	}
+}
+
+// line returns line i of lines quoted, or "end of file" past the last.
+func line(lines []string, i int) string {
+	if i >= len(lines) {
+		return "end of file"
+	}
+	return fmt.Sprintf("%q", lines[i])
+}
+
+// GoldenJSON is Golden for v encoded as indented JSON, ending in a newline.
+func GoldenJSON(t testing.TB, path string, v any) {
+	t.Helper()
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatalf("encoding %s: %v", path, err)
+	}
+	Golden(t, path, append(data, '\n'))
+}
@@ -0,0 +1,112 @@
+package twftest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// recorder is a testing.TB noting failures instead of failing the test.
+type recorder struct {
+	testing.TB
+	errs []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errs = append(r.errs, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatal(args ...any) {
+	r.errs = append(r.errs, fmt.Sprint(args...))
+	runtime.Goexit()
+}
+
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.Errorf(format, args...)
+	runtime.Goexit()
+}
+
+func (r *recorder) FailNow() { runtime.Goexit() }
+
+// record runs f against a recorder, in a goroutine so Fatal can end it,
+// and returns the failures it reported.
+func record(t *testing.T, f func(tb testing.TB)) []string {
+	r := &recorder{TB: t}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f(r)
+	}()
+	<-done
+	return r.errs
+}
+
+const order = `workflow Order(id: string):
+    activity Charge(id)
+    close complete
+
+activity Charge(id: string):
+    return id
+`
+
+func TestMustParse(t *testing.T) {
+	if file := MustParse(t, order); len(file.Definitions) != 2 {
+		t.Errorf("expected 2 definitions, got %d", len(file.Definitions))
+	}
+	errs := record(t, func(tb testing.TB) { MustParse(tb, "workflow (:\n") })
+	if len(errs) == 0 || !strings.HasPrefix(errs[0], "parse error at 1:") {
+		t.Errorf("expected a parse error, got %q", errs)
+	}
+}
+
+func TestAssertResolves(t *testing.T) {
+	AssertResolves(t, order)
+	errs := record(t, func(tb testing.TB) { AssertResolves(tb, "workflow W():\n    activity Missing()\n") })
+	if len(errs) != 1 || !strings.Contains(errs[0], "Missing") {
+		t.Errorf("expected a resolve error for Missing, got %q", errs)
+	}
+}
+
+func TestAssertDiagnostics(t *testing.T) {
+	src := "workflow W():\n    activity Missing()\n    close complete\n"
+	AssertDiagnostics(t, src, Error("Missing").At(2))
+	AssertDiagnostics(t, order)
+
+	errs := record(t, func(tb testing.TB) { AssertDiagnostics(tb, src, Warning("Missing")) })
+	if len(errs) != 2 || errs[0] != `no diagnostic matched warning containing "Missing"` || !strings.HasPrefix(errs[1], "unexpected diagnostic 2:") {
+		t.Errorf("expected the matcher and the diagnostic unpaired, got %q", errs)
+	}
+}
+
+func TestGolden(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out", "order.golden")
+	t.Setenv(UpdateEnv, "1")
+	Golden(t, path, []byte("a\nb\n"))
+	t.Setenv(UpdateEnv, "")
+	Golden(t, path, []byte("a\nb\n"))
+
+	errs := record(t, func(tb testing.TB) { Golden(tb, path, []byte("a\nc\n")) })
+	if len(errs) != 1 || !strings.Contains(errs[0], `order.golden:2: got "c", want "b"`) {
+		t.Errorf("expected the second line to differ, got %q", errs)
+	}
+	errs = record(t, func(tb testing.TB) { Golden(tb, path, []byte("a\n")) })
+	if len(errs) != 1 || !strings.Contains(errs[0], `order.golden:2: got "", want "b"`) {
+		t.Errorf("expected the missing line to differ, got %q", errs)
+	}
+
+	missing := filepath.Join(filepath.Dir(path), "missing.json")
+	errs = record(t, func(tb testing.TB) { GoldenJSON(tb, missing, map[string]int{"n": 1}) })
+	if len(errs) != 1 || !strings.Contains(errs[0], UpdateEnv+"=1 to create it") {
+		t.Errorf("expected a missing golden file to fail, got %q", errs)
+	}
+	t.Setenv(UpdateEnv, "1")
+	GoldenJSON(t, missing, map[string]int{"n": 1})
+	if data, _ := os.ReadFile(missing); string(data) != "{\n  \"n\": 1\n}\n" {
+		t.Errorf("golden JSON written as %q", data)
+	}
+}
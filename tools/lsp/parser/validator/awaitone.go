@@ -0,0 +1,119 @@
+package validator
+
+import (
+	"fmt"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+)
+
+// Modes of Policy.AwaitOneOrder.
+const (
+	// AwaitOneOrderTimerLast puts timer cases after every other case.
+	AwaitOneOrderTimerLast = "timer-last"
+	// AwaitOneOrderWatchFirst puts the cases watching for something to
+	// happen, signals, updates, and conditions or promises, first, then
+	// the operations the workflow started, then timer cases.
+	AwaitOneOrderWatchFirst = "watch-first"
+)
+
+// AwaitOneRank returns the place of c in the order mode asks for: cases of
+// lower rank go before those of higher rank, and cases of equal rank keep
+// their order.
+func AwaitOneRank(mode string, c *ast.AwaitOneCase) int {
+	switch c.Target.(type) {
+	case *ast.TimerTarget:
+		return 2
+	case *ast.SignalTarget, *ast.UpdateTarget, *ast.IdentTarget:
+		return 0
+	}
+	if mode == AwaitOneOrderWatchFirst {
+		return 1
+	}
+	return 0
+}
+
+// checkAwaitOneOrder reports the await one blocks of wf whose cases are out
+// of the order mode asks for, at the first case out of place, and each
+// timer case after the first of its block, since the shortest timer always
+// fires first and one is enough to bound the wait.
+func checkAwaitOneOrder(wf *ast.WorkflowDef, mode string) []*Error {
+	var errs []*Error
+	visit := func(s ast.Statement) bool {
+		block, ok := s.(*ast.AwaitOneBlock)
+		if !ok {
+			return true
+		}
+		var prev *ast.AwaitOneCase
+		timers := 0
+		for _, c := range block.Cases {
+			rank := AwaitOneRank(mode, c)
+			if prev != nil && rank < AwaitOneRank(mode, prev) {
+				errs = append(errs, &Error{
+					Msg:      fmt.Sprintf("%s case of await one comes after %s case; %s orders %s", caseKind(c), caseKind(prev), mode, awaitOneOrder(mode)),
+					Line:     c.Line,
+					Column:   c.Column,
+					Severity: "warning",
+					Kind:     ErrAwaitOneOrder,
+					Name:     wf.Name,
+					Related: []Related{{
+						Msg:    "await one starts here",
+						Line:   block.Line,
+						Column: block.Column,
+					}},
+				})
+				break
+			}
+			if prev == nil || rank > AwaitOneRank(mode, prev) {
+				prev = c
+			}
+		}
+		for _, c := range block.Cases {
+			if _, ok := c.Target.(*ast.TimerTarget); !ok {
+				continue
+			}
+			if timers++; timers > 1 {
+				errs = append(errs, &Error{
+					Msg:      "await one has more than one timer case; the shortest always fires first, so one timer bounds the wait",
+					Line:     c.Line,
+					Column:   c.Column,
+					Severity: "warning",
+					Kind:     ErrAwaitOneTimers,
+					Name:     wf.Name,
+				})
+			}
+		}
+		return true
+	}
+	ast.WalkStatements(wf.Body, visit)
+	for _, s := range wf.Signals {
+		ast.WalkStatements(s.Body, visit)
+	}
+	for _, u := range wf.Updates {
+		ast.WalkStatements(u.Body, visit)
+	}
+	return errs
+}
+
+// caseKind describes the kind of case c is, as in "a timer" or "an await
+// all".
+func caseKind(c *ast.AwaitOneCase) string {
+	if c.AwaitAll != nil {
+		return "an await all"
+	}
+	switch kind := ast.AsyncTargetKind(c.Target); kind {
+	case "activity", "update":
+		return "an " + kind
+	case "ident":
+		return "a condition or promise"
+	default:
+		return "a " + kind
+	}
+}
+
+// awaitOneOrder describes the order mode asks for.
+func awaitOneOrder(mode string) string {
+	if mode == AwaitOneOrderWatchFirst {
+		return "signal, update, and condition cases first, then operations, then timers"
+	}
+	return "timer cases last"
+}
@@ -0,0 +1,192 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+)
+
+// checkCloseValues warns about close statements whose values do not fit the
+// workflow's declared return type. close complete passes the workflow's
+// results, so each value must be of the matching return type; close fail
+// passes the failure, so its value must be an error or a message string.
+// Only literal values have a known type: names and expressions pass.
+func (v *validationCtx) checkCloseValues() {
+	for _, wf := range owned(v.own, v.workflows) {
+		returns := ast.SplitList(wf.ReturnType)
+		check := func(s ast.Statement) bool {
+			if c, ok := s.(*ast.CloseStmt); ok {
+				v.checkClose(wf, returns, c)
+			}
+			return true
+		}
+		ast.WalkStatements(wf.Body, check)
+		for _, s := range wf.Signals {
+			ast.WalkStatements(s.Body, check)
+		}
+		for _, u := range wf.Updates {
+			ast.WalkStatements(u.Body, check)
+		}
+	}
+}
+
+func (v *validationCtx) checkClose(wf *ast.WorkflowDef, returns []string, c *ast.CloseStmt) {
+	switch c.Reason {
+	case ast.CloseComplete:
+		values := ast.SplitList(c.Args)
+		if len(values) > 0 && len(values) != len(returns) {
+			msg := fmt.Sprintf("close complete passes %d values, but workflow %s returns %d", len(values), wf.Name, len(returns))
+			if len(returns) == 0 {
+				msg = fmt.Sprintf("close complete passes a value, but workflow %s declares no return type", wf.Name)
+			}
+			v.warnCloseValue(wf, c.Pos, msg, "")
+			return
+		}
+		for i, x := range c.ArgExprs {
+			got, ok := literalType(x)
+			if !ok || typeAccepts(returns[i], x) {
+				continue
+			}
+			var fix string
+			if len(returns) == 1 && builtinKind(returns[0]) == "" {
+				fix = wrapInLiteral(returns[0], x)
+			}
+			msg := fmt.Sprintf("close complete passes %s, but workflow %s returns %s", got, wf.Name, returns[i])
+			v.warnCloseValue(wf, ast.Pos{Line: x.NodeLine(), Column: x.NodeColumn()}, msg, fix)
+		}
+	case ast.CloseFailWorkflow:
+		if len(c.ArgExprs) != 1 {
+			return
+		}
+		x := c.ArgExprs[0]
+		got, ok := literalType(x)
+		if !ok || isFailureValue(x) {
+			return
+		}
+		msg := fmt.Sprintf("close fail passes %s; workflow %s should fail with an error or a message string", got, wf.Name)
+		v.warnCloseValue(wf, ast.Pos{Line: x.NodeLine(), Column: x.NodeColumn()}, msg, "")
+	}
+}
+
+func (v *validationCtx) warnCloseValue(wf *ast.WorkflowDef, pos ast.Pos, msg, fix string) {
+	v.errs = append(v.errs, &Error{
+		Msg:        msg,
+		Line:       pos.Line,
+		Column:     pos.Column,
+		Severity:   "warning",
+		Kind:       ErrCloseValueMismatch,
+		Name:       wf.Name,
+		Suggestion: fix,
+	})
+}
+
+// literalType describes the type of a literal value, as in "a string" or
+// "a typed literal OrderResult", and reports whether x is a literal at all.
+func literalType(x ast.Expr) (string, bool) {
+	switch x := x.(type) {
+	case *ast.StringLit:
+		return "a string", true
+	case *ast.NumberLit:
+		return "a number", true
+	case *ast.DurationLit:
+		return "a duration", true
+	case *ast.BoolLit:
+		return "a bool", true
+	case *ast.ListLit:
+		return "a list", true
+	case *ast.MapLit:
+		if x.Type == "" {
+			return "an untyped map literal", true
+		}
+		return "a typed literal " + x.Type, true
+	default:
+		return "", false
+	}
+}
+
+// typeAccepts reports whether a literal x is a value of the declared type.
+// Builtin types take the literals of their kind, and any other type, a
+// record, takes only a typed literal naming it.
+func typeAccepts(declared string, x ast.Expr) bool {
+	declared = strings.TrimPrefix(declared, "*")
+	switch kind := builtinKind(declared); kind {
+	case "any":
+		return true
+	case "":
+		m, ok := x.(*ast.MapLit)
+		return ok && m.Type == declared
+	default:
+		return literalKind(x) == kind
+	}
+}
+
+// builtinKind returns the kind of literal a builtin type takes, "any" for
+// a type taking every value, or "" for a record type.
+func builtinKind(declared string) string {
+	declared = strings.TrimPrefix(declared, "*")
+	switch declared {
+	case "any", "interface{}":
+		return "any"
+	case "string", "bool", "duration", "number":
+		return declared
+	case "int", "int32", "int64", "uint", "uint32", "uint64", "float", "float32", "float64":
+		return "number"
+	case "time.Duration":
+		return "duration"
+	}
+	switch {
+	case strings.HasPrefix(declared, "[]"), declared == "list", strings.HasPrefix(declared, "list<"):
+		return "list"
+	case strings.HasPrefix(declared, "map"):
+		return "map"
+	}
+	return ""
+}
+
+// literalKind returns the builtinKind of the types taking a literal x, or
+// "record" for a typed literal.
+func literalKind(x ast.Expr) string {
+	switch x := x.(type) {
+	case *ast.StringLit:
+		return "string"
+	case *ast.NumberLit:
+		return "number"
+	case *ast.DurationLit:
+		return "duration"
+	case *ast.BoolLit:
+		return "bool"
+	case *ast.ListLit:
+		return "list"
+	case *ast.MapLit:
+		if x.Type != "" {
+			return "record"
+		}
+		return "map"
+	}
+	return ""
+}
+
+// wrapInLiteral returns the source of a literal of the record type declared
+// holding x: an untyped map becomes the typed literal with its entries, and
+// any other value becomes the literal's value field.
+func wrapInLiteral(declared string, x ast.Expr) string {
+	declared = strings.TrimPrefix(declared, "*")
+	if m, ok := x.(*ast.MapLit); ok && m.Type == "" {
+		return declared + ast.ExprString(m)
+	}
+	return declared + "{value: " + ast.ExprString(x) + "}"
+}
+
+// isFailureValue reports whether x is a fit value for close fail: a message
+// string, or a typed literal of an error type, named error or ending in
+// Error.
+func isFailureValue(x ast.Expr) bool {
+	switch x := x.(type) {
+	case *ast.StringLit:
+		return true
+	case *ast.MapLit:
+		return x.Type == "error" || strings.HasSuffix(x.Type, "Error")
+	}
+	return false
+}
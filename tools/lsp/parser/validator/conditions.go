@@ -0,0 +1,241 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/eval"
+)
+
+// checkConditions folds if/for conditions and switch cases with the constant
+// evaluator and warns about conditions that can never change.
+func (v *validationCtx) checkConditions() {
+	for _, wf := range owned(v.own, v.workflows) {
+		mutated := mutatedNames(wf)
+		v.checkConditionsIn(wf.Body, mutated)
+		for _, s := range wf.Signals {
+			v.checkConditionsIn(s.Body, mutated)
+		}
+		for _, u := range wf.Updates {
+			v.checkConditionsIn(u.Body, mutated)
+		}
+	}
+}
+
+func (v *validationCtx) checkConditionsIn(stmts []ast.Statement, mutated map[string]bool) {
+	ast.WalkStatements(stmts, func(s ast.Statement) bool {
+		switch n := s.(type) {
+		case *ast.IfStmt:
+			if n.CondExpr == nil {
+				return true
+			}
+			if val, ok := eval.Truth(n.CondExpr, nil); ok {
+				v.warnConstantCondition(n.Pos, "if", n.Condition, val)
+			}
+		case *ast.ForStmt:
+			if n.Variant != ast.ForConditional || n.CondExpr == nil {
+				return true
+			}
+			if val, ok := eval.Truth(n.CondExpr, nil); ok {
+				v.warnConstantCondition(n.Pos, "for", n.Condition, val)
+				return true
+			}
+			v.checkLoopTerminates(n, mutated)
+		case *ast.SwitchBlock:
+			v.checkSwitch(n)
+		}
+		return true
+	})
+}
+
+func (v *validationCtx) warnConstantCondition(pos ast.Pos, keyword, cond string, val bool) {
+	msg := fmt.Sprintf("%s condition (%s) is always %t", keyword, cond, val)
+	switch {
+	case keyword == "for" && val:
+		msg += "; use for: for an intentional infinite loop"
+	case keyword == "for":
+		msg += "; the loop body never runs"
+	case val:
+		msg += "; the else branch never runs"
+	default:
+		msg += "; the body never runs"
+	}
+	v.errs = append(v.errs, &Error{
+		Msg:      msg,
+		Line:     pos.Line,
+		Column:   pos.Column,
+		Severity: "warning",
+		Kind:     ErrConstantCondition,
+	})
+}
+
+// checkLoopTerminates warns when nothing a conditional loop's condition reads
+// can change: no identifier is written anywhere in the workflow and the body
+// has no break, close, or return to leave the loop.
+func (v *validationCtx) checkLoopTerminates(loop *ast.ForStmt, mutated map[string]bool) {
+	names := eval.FreeIdents(loop.CondExpr)
+	if len(names) == 0 {
+		return
+	}
+	for _, name := range names {
+		if mutated[name] {
+			return
+		}
+	}
+	if mentionsAny(loop.Body, names) || exitsLoop(loop) {
+		return
+	}
+	v.errs = append(v.errs, &Error{
+		Msg:      fmt.Sprintf("loop condition (%s) never changes: %s is not updated in the loop or any handler", loop.Condition, strings.Join(names, ", ")),
+		Line:     loop.Line,
+		Column:   loop.Column,
+		Severity: "warning",
+		Kind:     ErrLoopNeverTerminates,
+	})
+}
+
+// mutatedNames collects names a workflow may write outside of raw code:
+// call results, promises, set/unset conditions, and names mentioned in
+// signal/update handler bodies, which run concurrently with the main body.
+func mutatedNames(wf *ast.WorkflowDef) map[string]bool {
+	names := make(map[string]bool)
+	collect := func(stmts []ast.Statement) {
+		ast.WalkStatements(stmts, func(s ast.Statement) bool {
+			switch n := s.(type) {
+			case *ast.ActivityCall:
+				for _, name := range ast.SplitList(n.Result) {
+					names[name] = true
+				}
+			case *ast.WorkflowCall:
+				for _, name := range ast.SplitList(n.Result) {
+					names[name] = true
+				}
+			case *ast.NexusCall:
+				for _, name := range ast.SplitList(n.Result) {
+					names[name] = true
+				}
+			case *ast.PromiseStmt:
+				names[n.Name] = true
+			case *ast.SetStmt:
+				names[n.Condition.Name] = true
+			case *ast.UnsetStmt:
+				names[n.Condition.Name] = true
+			case *ast.ForStmt:
+				names[n.Variable] = true
+			}
+			return true
+		}, ast.WithAsyncTargets(func(t ast.AsyncTarget, _ ast.Statement) bool {
+			for _, name := range targetBindings(t) {
+				names[name] = true
+			}
+			return true
+		}))
+	}
+	collect(wf.Body)
+	for _, s := range wf.Signals {
+		collect(s.Body)
+		addWords(names, rawText(s.Body))
+	}
+	for _, u := range wf.Updates {
+		collect(u.Body)
+		addWords(names, rawText(u.Body))
+	}
+	delete(names, "")
+	return names
+}
+
+// targetBindings returns the names an async target binds on completion.
+func targetBindings(t ast.AsyncTarget) []string {
+	switch t := t.(type) {
+	case *ast.SignalTarget:
+		return identWords(t.Params)
+	case *ast.UpdateTarget:
+		return identWords(t.Params)
+	case *ast.ActivityTarget:
+		return ast.SplitList(t.Result)
+	case *ast.WorkflowTarget:
+		return ast.SplitList(t.Result)
+	case *ast.NexusTarget:
+		return ast.SplitList(t.Result)
+	case *ast.IdentTarget:
+		return []string{t.Result}
+	case *ast.TimerTarget:
+		return []string{t.Result}
+	}
+	return nil
+}
+
+// mentionsAny reports whether any raw statement in stmts mentions one of names.
+func mentionsAny(stmts []ast.Statement, names []string) bool {
+	words := make(map[string]bool)
+	addWords(words, rawText(stmts))
+	for _, name := range names {
+		if words[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// exitsLoop reports whether loop's body contains a break, close, or return
+// that leaves the loop. Unlabeled breaks inside nested loops, and labeled
+// breaks that target a nested loop, only leave that nested loop.
+func exitsLoop(loop *ast.ForStmt) bool {
+	nestedLoops := make(map[*ast.ForStmt]bool)
+	nestedBreaks := make(map[*ast.BreakStmt]bool)
+	ast.WalkStatements(loop.Body, func(s ast.Statement) bool {
+		if n, ok := s.(*ast.ForStmt); ok {
+			nestedLoops[n] = true
+			ast.WalkStatements(n.Body, func(s ast.Statement) bool {
+				if b, ok := s.(*ast.BreakStmt); ok && b.Label.Name == "" {
+					nestedBreaks[b] = true
+				}
+				return true
+			})
+		}
+		return true
+	})
+
+	found := false
+	ast.WalkStatements(loop.Body, func(s ast.Statement) bool {
+		switch n := s.(type) {
+		case *ast.BreakStmt:
+			if n.Label.Name == "" {
+				found = !nestedBreaks[n]
+			} else {
+				found = !nestedLoops[n.Label.Resolved]
+			}
+		case *ast.CloseStmt, *ast.ReturnStmt:
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// rawText concatenates the text of all raw statements in stmts.
+func rawText(stmts []ast.Statement) string {
+	var b strings.Builder
+	ast.WalkStatements(stmts, func(s ast.Statement) bool {
+		if raw, ok := s.(*ast.RawStmt); ok {
+			b.WriteString(raw.Text)
+			b.WriteByte('\n')
+		}
+		return true
+	})
+	return b.String()
+}
+
+func addWords(set map[string]bool, text string) {
+	for _, w := range identWords(text) {
+		set[w] = true
+	}
+}
+
+// identWords splits text into identifier-like words.
+func identWords(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !(r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9'))
+	})
+}
@@ -0,0 +1,132 @@
+package validator
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+)
+
+// Dialects of TWF, told apart by how a workflow body ends the workflow.
+const (
+	// DialectLegacy ends workflows with return, as the grammar did before
+	// close.
+	DialectLegacy = "legacy"
+	// DialectV2 ends workflows with close; return in a workflow body is not
+	// part of it.
+	DialectV2 = "v2"
+	// DialectAuto picks legacy or v2 for each file: by its dialect pragma,
+	// or else by whether its workflows use return or close.
+	DialectAuto = "auto"
+)
+
+// dialectPragma matches a "twf:dialect NAME" comment, which fixes the
+// dialect of the file it is in under DialectAuto.
+var dialectPragma = regexp.MustCompile(`^\s*#\s*twf:dialect(?:\s+(\S*))?\s*$`)
+
+// CheckDialect reports the constructs in defs, the definitions parsed from
+// src, that are not part of dialect: closes under DialectLegacy and returns
+// in workflow bodies under DialectV2. Under DialectAuto a file using both
+// is reported once, at its first construct of the dialect it uses second,
+// since there is no telling which one it means.
+func CheckDialect(defs []ast.Definition, src, dialect string) []*Error {
+	var returns []*ast.ReturnStmt
+	var closes []*ast.CloseStmt
+	for _, def := range defs {
+		wf, ok := def.(*ast.WorkflowDef)
+		if !ok {
+			continue
+		}
+		ast.WalkStatements(wf.Body, func(s ast.Statement) bool {
+			if ret, ok := s.(*ast.ReturnStmt); ok {
+				returns = append(returns, ret)
+			}
+			return true
+		})
+		for _, body := range handlerBodies(wf, nil) {
+			ast.WalkStatements(body, func(s ast.Statement) bool {
+				if c, ok := s.(*ast.CloseStmt); ok {
+					closes = append(closes, c)
+				}
+				return true
+			})
+		}
+	}
+
+	if dialect == DialectAuto {
+		pragma, line, found := findDialectPragma(src)
+		switch {
+		case found && pragma != DialectLegacy && pragma != DialectV2:
+			return []*Error{{
+				Msg:    fmt.Sprintf("unknown dialect %q; want %s or %s", pragma, DialectLegacy, DialectV2),
+				Line:   line,
+				Column: 1,
+				Kind:   ErrDialectMismatch,
+			}}
+		case found:
+			dialect = pragma
+		case len(returns) > 0 && len(closes) > 0:
+			return []*Error{mixedDialects(returns[0], closes[0])}
+		case len(returns) > 0:
+			dialect = DialectLegacy
+		default:
+			dialect = DialectV2
+		}
+	}
+
+	var errs []*Error
+	switch dialect {
+	case DialectLegacy:
+		for _, c := range closes {
+			errs = append(errs, &Error{
+				Msg:    "close is not part of the legacy dialect; end the workflow with return",
+				Line:   c.Line,
+				Column: c.Column,
+				Kind:   ErrDialectMismatch,
+			})
+		}
+	case DialectV2:
+		for _, ret := range returns {
+			want := "close complete"
+			if ret.Value != "" {
+				want += "(...)"
+			}
+			errs = append(errs, &Error{
+				Msg:    fmt.Sprintf("return in a workflow body is not part of the v2 dialect; use %s", want),
+				Line:   ret.Line,
+				Column: ret.Column,
+				Kind:   ErrDialectMismatch,
+			})
+		}
+	}
+	return errs
+}
+
+// findDialectPragma returns the dialect named by the first dialect pragma
+// in src and its line, or false when src has none.
+func findDialectPragma(src string) (string, int, bool) {
+	for i, line := range strings.Split(src, "\n") {
+		if m := dialectPragma.FindStringSubmatch(line); m != nil {
+			return m[1], i + 1, true
+		}
+	}
+	return "", 0, false
+}
+
+// mixedDialects reports a file ending workflows both with ret, the legacy
+// way, and with c, at whichever of the two comes later.
+func mixedDialects(ret *ast.ReturnStmt, c *ast.CloseStmt) *Error {
+	e := &Error{
+		Msg:  "file mixes dialects: return ends a workflow the legacy way and close the v2 way; use one, or name it with a '# twf:dialect legacy|v2' comment",
+		Kind: ErrDialectMismatch,
+	}
+	if ret.Line > c.Line {
+		e.Line, e.Column = ret.Line, ret.Column
+		e.Related = []Related{{Msg: "close, of the v2 dialect, is used here", Line: c.Line, Column: c.Column}}
+	} else {
+		e.Line, e.Column = c.Line, c.Column
+		e.Related = []Related{{Msg: "return, of the legacy dialect, is used here", Line: ret.Line, Column: ret.Column}}
+	}
+	return e
+}
@@ -0,0 +1,124 @@
+package validator
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+)
+
+// checkEnumSwitches warns about switches on an enum-typed parameter or state
+// variable that neither cover every value of the enum nor have an else.
+func (v *validationCtx) checkEnumSwitches() {
+	if len(v.enums) == 0 {
+		return
+	}
+	for _, wf := range owned(v.own, v.workflows) {
+		typed := make(map[string]*ast.EnumDef)
+		v.addEnumTyped(typed, ast.ParseParams(wf.Params))
+		if wf.State != nil {
+			for _, raw := range wf.State.RawStmts {
+				v.addEnumTyped(typed, ast.ParseParams(raw.Text))
+			}
+		}
+
+		v.checkEnumSwitchesIn(wf.Body, typed)
+		for _, s := range wf.Signals {
+			v.checkEnumSwitchesIn(s.Body, v.handlerEnumTyped(typed, s.Params))
+		}
+		for _, u := range wf.Updates {
+			v.checkEnumSwitchesIn(u.Body, v.handlerEnumTyped(typed, u.Params))
+		}
+	}
+}
+
+// addEnumTyped adds the params whose type names an enum to typed.
+func (v *validationCtx) addEnumTyped(typed map[string]*ast.EnumDef, params []ast.Param) {
+	for _, p := range params {
+		if enum, ok := v.enums[p.Type]; ok {
+			typed[p.Name] = enum
+		}
+	}
+}
+
+// handlerEnumTyped returns the enum-typed names visible in a handler body:
+// the workflow's, shadowed by the handler's own parameters.
+func (v *validationCtx) handlerEnumTyped(typed map[string]*ast.EnumDef, params string) map[string]*ast.EnumDef {
+	decl := ast.ParseParams(params)
+	if len(decl) == 0 {
+		return typed
+	}
+	out := maps.Clone(typed)
+	for _, p := range decl {
+		delete(out, p.Name)
+	}
+	v.addEnumTyped(out, decl)
+	return out
+}
+
+func (v *validationCtx) checkEnumSwitchesIn(stmts []ast.Statement, typed map[string]*ast.EnumDef) {
+	if len(typed) == 0 {
+		return
+	}
+	ast.WalkStatements(stmts, func(s ast.Statement) bool {
+		sw, ok := s.(*ast.SwitchBlock)
+		if !ok || sw.Default != nil {
+			return true
+		}
+		subject, ok := sw.SubjectExpr.(*ast.Ident)
+		if !ok || typed[subject.Name] == nil {
+			return true
+		}
+		enum := typed[subject.Name]
+		missing := MissingEnumCases(sw, enum)
+		if len(missing) == 0 {
+			return true
+		}
+		noun, advice := "case", "add it or an else"
+		if len(missing) > 1 {
+			noun, advice = "cases", "add them or an else"
+		}
+		v.errs = append(v.errs, &Error{
+			Msg:      fmt.Sprintf("switch on %s (%s) is missing %s %s; %s", subject.Name, enum.Name, noun, strings.Join(missing, ", "), advice),
+			Line:     sw.Line,
+			Column:   sw.Column,
+			Severity: "warning",
+			Kind:     ErrNonExhaustiveSwitch,
+			Name:     enum.Name,
+			Related: []Related{{
+				Msg:    fmt.Sprintf("enum %s is declared here", enum.Name),
+				Line:   enum.Line,
+				Column: enum.Column,
+				File:   enum.SourceFile,
+			}},
+		})
+		return true
+	})
+}
+
+// MissingEnumCases returns the values of enum, in declaration order, that no
+// case of sw matches. A case matches a value written bare (refund), quoted
+// ("refund"), or qualified by the enum's name (OrderType.refund).
+func MissingEnumCases(sw *ast.SwitchBlock, enum *ast.EnumDef) []string {
+	covered := make(map[string]bool)
+	for _, c := range sw.Cases {
+		switch e := c.ValueExpr.(type) {
+		case *ast.Ident:
+			covered[e.Name] = true
+		case *ast.StringLit:
+			covered[e.Value] = true
+		case *ast.SelectorExpr:
+			if x, ok := e.X.(*ast.Ident); ok && x.Name == enum.Name {
+				covered[e.Sel] = true
+			}
+		}
+	}
+	var missing []string
+	for _, val := range enum.Values {
+		if !covered[val.Name] {
+			missing = append(missing, val.Name)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,96 @@
+package validator
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+)
+
+// hintLine matches a statement of the removed hint construct, such as
+// "hint signal Approved" or "hints signal Approved, AdminOverride".
+var hintLine = regexp.MustCompile(`^hints?\s+(signal|update)\s+(\w+(?:\s*,\s*\w+)*)\s*$`)
+
+// Hint is a statement of the removed hint construct, which now parses as
+// raw code and has no effect.
+type Hint struct {
+	Kind  string // "signal" or "update"
+	Names []HintName
+}
+
+// HintName is one name a hint lists.
+type HintName struct {
+	Name   string
+	Offset int // byte offset of the name in the statement's text
+}
+
+// ParseHint returns the hint raw is, or false when it is another statement.
+func ParseHint(raw *ast.RawStmt) (Hint, bool) {
+	m := hintLine.FindStringSubmatchIndex(raw.Text)
+	if m == nil {
+		return Hint{}, false
+	}
+	h := Hint{Kind: raw.Text[m[2]:m[3]]}
+	list := raw.Text[m[4]:m[5]]
+	offset := m[4]
+	for _, part := range strings.Split(list, ",") {
+		name := strings.TrimSpace(part)
+		h.Names = append(h.Names, HintName{Name: name, Offset: offset + strings.Index(part, name)})
+		offset += len(part) + 1
+	}
+	return h, true
+}
+
+// checkRemovedHints warns about hint statements, which the language no
+// longer has: they parse as raw code, so the wait they describe never
+// happens. Each name the hint lists that the workflow does not declare is
+// reported at the name, with Name set to it.
+func (v *validationCtx) checkRemovedHints() {
+	for _, wf := range owned(v.own, v.workflows) {
+		declared := map[string]map[string]bool{"signal": {}, "update": {}}
+		for _, s := range wf.Signals {
+			declared["signal"][s.Name] = true
+		}
+		for _, u := range wf.Updates {
+			declared["update"][u.Name] = true
+		}
+		for _, body := range handlerBodies(wf, nil) {
+			ast.WalkStatements(body, func(s ast.Statement) bool {
+				raw, ok := s.(*ast.RawStmt)
+				if !ok {
+					return true
+				}
+				h, ok := ParseHint(raw)
+				if !ok {
+					return true
+				}
+				names := make([]string, len(h.Names))
+				for i, n := range h.Names {
+					names[i] = n.Name
+				}
+				v.errs = append(v.errs, &Error{
+					Msg:      fmt.Sprintf("hint was removed from the language, so this line has no effect; wait for %s %s with await one, a case for each", h.Kind, strings.Join(names, " or ")),
+					Line:     raw.Line,
+					Column:   raw.Column,
+					Severity: "warning",
+					Kind:     ErrRemovedHint,
+				})
+				for _, n := range h.Names {
+					if declared[h.Kind][n.Name] {
+						continue
+					}
+					v.errs = append(v.errs, &Error{
+						Msg:      fmt.Sprintf("workflow %s declares no %s %s", wf.Name, h.Kind, n.Name),
+						Line:     raw.Line,
+						Column:   raw.Column + n.Offset,
+						Severity: "warning",
+						Kind:     ErrRemovedHint,
+						Name:     n.Name,
+					})
+				}
+				return true
+			})
+		}
+	}
+}
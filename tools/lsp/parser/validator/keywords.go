@@ -0,0 +1,29 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/lexer"
+)
+
+// CheckKeywordCase warns about each keyword of src spelled other than in
+// lowercase, suggesting the lowercase spelling. Such keywords parse only
+// with lexer.Options.CaseInsensitiveKeywords, so check src only when it
+// was parsed with that on; otherwise the words are names.
+func CheckKeywordCase(src string) []*Error {
+	var errs []*Error
+	for _, tok := range lexer.MiscasedKeywords(src) {
+		lower := strings.ToLower(tok.Literal)
+		errs = append(errs, &Error{
+			Msg:        fmt.Sprintf("keyword %s should be written %s", tok.Literal, lower),
+			Line:       tok.Line,
+			Column:     tok.Column,
+			Severity:   "warning",
+			Kind:       ErrKeywordCase,
+			Name:       tok.Literal,
+			Suggestion: lower,
+		})
+	}
+	return errs
+}
@@ -0,0 +1,278 @@
+package validator
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/internal/editdistance"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/parser"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/resolver"
+)
+
+// Modes of Policy.UnknownNames.
+const (
+	// UnknownNamesTypos reports only the unknown names close enough to a
+	// declared one to be a likely misspelling of it.
+	UnknownNamesTypos = "typos"
+	// UnknownNamesAll reports every unknown name.
+	UnknownNamesAll = "all"
+)
+
+// assignment matches a raw statement assigning to a name or to a field or
+// element of one, such as status = "paid" or order.total += fee.
+var assignment = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)(?:\.[A-Za-z_][A-Za-z0-9_]*|\[[^\]]*\])*\s*[-+*/]?=\s*([^=].*)$`)
+
+// wellKnownNames are read in conditions without being declared.
+var wellKnownNames = map[string]bool{"nil": true, "null": true, "none": true}
+
+// checkUnknownNames reports the names that wf's raw assignments and its if,
+// for, switch, and await one guard conditions use without declaring them.
+//
+// A name is declared by the workflow's parameters and state: block, by the
+// parameters of the handler using it, by a call result, promise, loop
+// variable, or await binding anywhere in the workflow, and by the constants
+// and enums of the file. Names assigned by raw statements may be read
+// anywhere in the workflow, but assigning one is itself reported, since that
+// is where a misspelled state name goes unnoticed. In UnknownNamesTypos mode
+// only names within a few edits of a declared one are reported.
+func checkUnknownNames(symbols *resolver.SymbolTable, wf *ast.WorkflowDef, mode string) []*Error {
+	declared := make(map[string]bool)
+	for name := range symbols.Constants {
+		declared[name] = true
+	}
+	for name, enum := range symbols.Enums {
+		declared[name] = true
+		for _, val := range enum.Values {
+			declared[val.Name] = true
+		}
+	}
+	addParamNames(declared, wf.Params)
+	if wf.State != nil {
+		for _, c := range wf.State.Conditions {
+			declared[c.Name] = true
+		}
+		for _, raw := range wf.State.RawStmts {
+			addParamNames(declared, raw.Text)
+		}
+	}
+	assigned := make(map[string]bool)
+	bodies := [][]ast.Statement{wf.Body}
+	for _, s := range wf.Signals {
+		bodies = append(bodies, s.Body)
+	}
+	for _, q := range wf.Queries {
+		bodies = append(bodies, q.Body)
+	}
+	for _, u := range wf.Updates {
+		bodies = append(bodies, u.Body)
+	}
+	for _, body := range bodies {
+		addBindings(declared, body)
+		ast.WalkStatements(body, func(s ast.Statement) bool {
+			if raw, ok := s.(*ast.RawStmt); ok {
+				if m := assignment.FindStringSubmatch(raw.Text); m != nil {
+					assigned[m[1]] = true
+				}
+			}
+			return true
+		})
+	}
+	delete(declared, "")
+
+	c := &namesCtx{workflow: wf.Name, mode: mode, assigned: assigned}
+	c.checkBody(wf.Body, declared)
+	for _, s := range wf.Signals {
+		c.checkBody(s.Body, handlerScope(declared, s.Params))
+	}
+	for _, q := range wf.Queries {
+		c.checkBody(q.Body, handlerScope(declared, q.Params))
+	}
+	for _, u := range wf.Updates {
+		c.checkBody(u.Body, handlerScope(declared, u.Params))
+	}
+	slices.SortStableFunc(c.errs, func(a, b *Error) int {
+		return cmp.Or(a.Line-b.Line, a.Column-b.Column)
+	})
+	return c.errs
+}
+
+func addParamNames(names map[string]bool, params string) {
+	for _, p := range ast.ParseParams(params) {
+		names[p.Name] = true
+	}
+}
+
+// addBindings adds the names stmts bind outside of raw code.
+func addBindings(names map[string]bool, stmts []ast.Statement) {
+	ast.WalkStatements(stmts, func(s ast.Statement) bool {
+		switch n := s.(type) {
+		case *ast.ActivityCall:
+			for _, name := range ast.SplitList(n.Result) {
+				names[name] = true
+			}
+		case *ast.WorkflowCall:
+			for _, name := range ast.SplitList(n.Result) {
+				names[name] = true
+			}
+		case *ast.NexusCall:
+			for _, name := range ast.SplitList(n.Result) {
+				names[name] = true
+			}
+		case *ast.PromiseStmt:
+			names[n.Name] = true
+		case *ast.ForStmt:
+			names[n.Variable] = true
+		}
+		return true
+	}, ast.WithAsyncTargets(func(t ast.AsyncTarget, _ ast.Statement) bool {
+		for _, name := range targetBindings(t) {
+			names[name] = true
+		}
+		return true
+	}))
+}
+
+// handlerScope returns the names declared in a handler body: the
+// workflow's and the handler's parameters.
+func handlerScope(declared map[string]bool, params string) map[string]bool {
+	if len(ast.ParseParams(params)) == 0 {
+		return declared
+	}
+	out := maps.Clone(declared)
+	addParamNames(out, params)
+	return out
+}
+
+type namesCtx struct {
+	workflow string
+	mode     string
+	assigned map[string]bool
+	errs     []*Error
+}
+
+func (c *namesCtx) checkBody(stmts []ast.Statement, declared map[string]bool) {
+	ast.WalkStatements(stmts, func(s ast.Statement) bool {
+		switch n := s.(type) {
+		case *ast.RawStmt:
+			c.checkAssignment(n, declared)
+		case *ast.IfStmt:
+			c.checkReads(n.CondExpr, nil, declared)
+		case *ast.ForStmt:
+			c.checkReads(n.CondExpr, nil, declared)
+		case *ast.SwitchBlock:
+			c.checkReads(n.SubjectExpr, nil, declared)
+		case *ast.AwaitOneBlock:
+			for _, cs := range n.Cases {
+				c.checkReads(cs.GuardExpr, nil, declared)
+			}
+		}
+		return true
+	})
+}
+
+// checkAssignment checks the name a raw assignment writes and, when the
+// value is a plain expression, the names it reads.
+func (c *namesCtx) checkAssignment(raw *ast.RawStmt, declared map[string]bool) {
+	m := assignment.FindStringSubmatchIndex(raw.Text)
+	if m == nil {
+		return
+	}
+	target := raw.Text[m[2]:m[3]]
+	if !declared[target] {
+		c.report(target, raw.Pos, declared)
+	}
+	value, err := parser.ParseExpr(strings.TrimSpace(raw.Text[m[4]:m[5]]))
+	if err != nil {
+		return
+	}
+	// ParseExpr positions start at 1:1; shift them to the value's column.
+	c.checkReads(value, &ast.Pos{Line: raw.Line, Column: raw.Column + m[4]}, declared)
+}
+
+// checkReads checks the root names x reads. Positions in x are relative to
+// base when it is not nil.
+func (c *namesCtx) checkReads(x ast.Expr, base *ast.Pos, declared map[string]bool) {
+	for _, id := range rootIdents(x) {
+		if declared[id.Name] || c.assigned[id.Name] || wellKnownNames[id.Name] {
+			continue
+		}
+		pos := id.Pos
+		if base != nil {
+			pos = ast.Pos{Line: base.Line, Column: base.Column + id.Column - 1}
+		}
+		c.report(id.Name, pos, declared)
+	}
+}
+
+func (c *namesCtx) report(name string, pos ast.Pos, declared map[string]bool) {
+	suggestion := nearestName(name, declared)
+	if suggestion == "" && c.mode != UnknownNamesAll {
+		return
+	}
+	msg := fmt.Sprintf("unknown name %s in workflow %s: not a parameter, state entry, or binding", name, c.workflow)
+	if suggestion != "" {
+		msg = fmt.Sprintf("unknown name %s in workflow %s; did you mean %s?", name, c.workflow, suggestion)
+	}
+	c.errs = append(c.errs, &Error{
+		Msg:        msg,
+		Line:       pos.Line,
+		Column:     pos.Column,
+		Severity:   "warning",
+		Kind:       ErrUnknownName,
+		Name:       name,
+		Suggestion: suggestion,
+	})
+}
+
+// rootIdents returns the identifiers x reads at its roots, as FreeIdents
+// does, with their positions and repeats.
+func rootIdents(x ast.Expr) []*ast.Ident {
+	var ids []*ast.Ident
+	var walk func(ast.Expr)
+	walk = func(x ast.Expr) {
+		switch x := x.(type) {
+		case *ast.Ident:
+			ids = append(ids, x)
+		case *ast.SelectorExpr:
+			walk(x.X)
+		case *ast.UnaryExpr:
+			walk(x.X)
+		case *ast.BinaryExpr:
+			walk(x.X)
+			walk(x.Y)
+		case *ast.ListLit:
+			for _, e := range x.Elems {
+				walk(e)
+			}
+		case *ast.MapLit:
+			for _, e := range x.Entries {
+				walk(e.Value)
+			}
+		}
+	}
+	walk(x)
+	return ids
+}
+
+// nearestName returns the name in names closest to name, ignoring case, if
+// it is within a third of name's length in edits (at most two), or "".
+// Ties go to the name first in sorted order. Names differing only in a
+// numeric suffix, such as result and result2, are taken as distinct.
+func nearestName(name string, names map[string]bool) string {
+	limit := min(2, len(name)/3)
+	best, bestDist := "", limit+1
+	for _, candidate := range slices.Sorted(maps.Keys(names)) {
+		if strings.TrimRight(candidate, "0123456789") == strings.TrimRight(name, "0123456789") {
+			continue
+		}
+		if d := editdistance.Distance(strings.ToLower(name), strings.ToLower(candidate)); d < bestDist {
+			best, bestDist = candidate, d
+		}
+	}
+	return best
+}
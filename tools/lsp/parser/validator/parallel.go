@@ -0,0 +1,42 @@
+package validator
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+)
+
+// checkParallelLoops reports parallel for each loops whose max: limit is
+// not a positive integer.
+func (v *validationCtx) checkParallelLoops() {
+	for _, wf := range owned(v.own, v.workflows) {
+		bodies := [][]ast.Statement{wf.Body}
+		for _, s := range wf.Signals {
+			bodies = append(bodies, s.Body)
+		}
+		for _, u := range wf.Updates {
+			bodies = append(bodies, u.Body)
+		}
+		for _, body := range bodies {
+			ast.WalkStatements(body, func(s ast.Statement) bool {
+				if loop, ok := s.(*ast.ForStmt); ok && loop.Variant == ast.ForParallel {
+					v.checkConcurrency(loop)
+				}
+				return true
+			})
+		}
+	}
+}
+
+func (v *validationCtx) checkConcurrency(loop *ast.ForStmt) {
+	if n, err := strconv.Atoi(loop.Concurrency); err == nil && n > 0 {
+		return
+	}
+	v.errs = append(v.errs, &Error{
+		Msg:    fmt.Sprintf("parallel max must be a positive integer, got %s", loop.Concurrency),
+		Line:   loop.Line,
+		Column: loop.Column,
+		Kind:   ErrInvalidConcurrency,
+	})
+}
@@ -0,0 +1,237 @@
+package validator
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+)
+
+// logStatement matches a raw statement writing to a log, such as
+// log("sent " + email) or logger.info(order).
+var logStatement = regexp.MustCompile(`^(?:log|logger|print|println|printf)\b\s*[.(]`)
+
+// stringLiteral matches a double-quoted string, whose words are text and
+// not names.
+var stringLiteral = regexp.MustCompile(`"(?:[^"\\]|\\.)*"`)
+
+// checkPIIFlows warns about values marked @pii that reach a log statement
+// or leave the workflow's namespace through a detached call. A parameter or
+// state entry is marked by @pii before its name. Marks spread to names
+// assigned from a marked one, to loop variables over one, and to the
+// results of calls passed one, so the check follows a value as far as the
+// design shows it.
+func (v *validationCtx) checkPIIFlows() {
+	var namespaces map[string][]string
+	for _, wf := range owned(v.own, v.workflows) {
+		marked := piiMarks(wf)
+		if len(marked) == 0 {
+			continue
+		}
+		if namespaces == nil {
+			namespaces = v.workflowNamespaces()
+		}
+		bodies := handlerBodies(wf, nil)
+		spreadPII(marked, bodies)
+		for _, body := range bodies {
+			ast.WalkStatements(body, func(s ast.Statement) bool {
+				v.checkPIISink(wf, s, marked, namespaces)
+				return true
+			}, ast.WithAsyncTargets(func(t ast.AsyncTarget, s ast.Statement) bool {
+				v.checkPIITarget(wf, t, s, marked, namespaces)
+				return true
+			}))
+		}
+	}
+}
+
+// piiMarks returns the parameters and state entries of wf and of its
+// handlers marked @pii, with where each is declared.
+func piiMarks(wf *ast.WorkflowDef) map[string]ast.Pos {
+	marked := make(map[string]ast.Pos)
+	add := func(params string, pos ast.Pos) {
+		for _, p := range ast.ParseParams(params) {
+			if p.Annotated("pii") && p.Name != "" {
+				marked[p.Name] = pos
+			}
+		}
+	}
+	add(wf.Params, wf.Pos)
+	if wf.State != nil {
+		for _, raw := range wf.State.RawStmts {
+			add(raw.Text, raw.Pos)
+		}
+	}
+	for _, s := range wf.Signals {
+		add(s.Params, s.Pos)
+	}
+	for _, u := range wf.Updates {
+		add(u.Params, u.Pos)
+	}
+	return marked
+}
+
+// spreadPII adds to marked the names bodies derive from marked ones, until
+// no more are added. A derived name is reported as declared where the
+// value it came from was.
+func spreadPII(marked map[string]ast.Pos, bodies [][]ast.Statement) {
+	for changed := true; changed; {
+		changed = false
+		derive := func(names []string, from string) {
+			origin, ok := firstMarked(marked, from)
+			if !ok {
+				return
+			}
+			for _, name := range names {
+				if _, done := marked[name]; !done && name != "" {
+					marked[name] = origin
+					changed = true
+				}
+			}
+		}
+		for _, body := range bodies {
+			ast.WalkStatements(body, func(s ast.Statement) bool {
+				switch n := s.(type) {
+				case *ast.RawStmt:
+					if m := assignment.FindStringSubmatch(n.Text); m != nil {
+						derive([]string{m[1]}, m[2])
+					}
+				case *ast.ForStmt:
+					derive([]string{n.Variable}, n.Iterable)
+				case *ast.ActivityCall:
+					derive(ast.SplitList(n.Result), n.Args)
+				case *ast.WorkflowCall:
+					derive(ast.SplitList(n.Result), n.Args)
+				case *ast.NexusCall:
+					derive(ast.SplitList(n.Result), n.Args)
+				}
+				return true
+			})
+		}
+	}
+}
+
+// firstMarked returns the origin of the first marked name the code text
+// reads, ignoring the words of string literals.
+func firstMarked(marked map[string]ast.Pos, text string) (ast.Pos, bool) {
+	name, ok := firstMarkedName(marked, text)
+	return marked[name], ok
+}
+
+func firstMarkedName(marked map[string]ast.Pos, text string) (string, bool) {
+	for _, w := range identWords(stringLiteral.ReplaceAllString(text, `""`)) {
+		if _, ok := marked[w]; ok {
+			return w, true
+		}
+	}
+	return "", false
+}
+
+// checkPIISink reports a log statement or detached call among the
+// statements of wf that reads a marked name.
+func (v *validationCtx) checkPIISink(wf *ast.WorkflowDef, s ast.Statement, marked map[string]ast.Pos, namespaces map[string][]string) {
+	switch n := s.(type) {
+	case *ast.RawStmt:
+		if !logStatement.MatchString(n.Text) {
+			return
+		}
+		if name, ok := firstMarkedName(marked, n.Text); ok {
+			v.warnPII(wf, n.Pos, name, marked, fmt.Sprintf("PII value %s is written to a log in workflow %s", name, wf.Name))
+		}
+	case *ast.WorkflowCall:
+		if n.Mode == ast.CallDetach {
+			v.checkPIIDetach(wf, n.Pos, n.Workflow.Resolved, n.Args, marked, namespaces)
+		}
+	case *ast.NexusCall:
+		if n.Detach {
+			v.checkPIINexus(wf, n.Pos, n.Endpoint.Name, n.Args, marked)
+		}
+	}
+}
+
+// checkPIITarget is checkPIISink for the detached calls started by await
+// and promise statements.
+func (v *validationCtx) checkPIITarget(wf *ast.WorkflowDef, t ast.AsyncTarget, s ast.Statement, marked map[string]ast.Pos, namespaces map[string][]string) {
+	pos := ast.Pos{Line: s.NodeLine(), Column: s.NodeColumn()}
+	switch t := t.(type) {
+	case *ast.WorkflowTarget:
+		if t.Mode == ast.CallDetach {
+			v.checkPIIDetach(wf, pos, t.Workflow.Resolved, t.Args, marked, namespaces)
+		}
+	case *ast.NexusTarget:
+		if t.Detach {
+			v.checkPIINexus(wf, pos, t.Endpoint.Name, t.Args, marked)
+		}
+	}
+}
+
+// checkPIIDetach reports a detached workflow call passing a marked name to
+// target when target runs in none of the namespaces wf runs in. Calls are
+// only known to leave the namespace when both workflows are deployed.
+func (v *validationCtx) checkPIIDetach(wf *ast.WorkflowDef, pos ast.Pos, target *ast.WorkflowDef, args string, marked map[string]ast.Pos, namespaces map[string][]string) {
+	if target == nil {
+		return
+	}
+	name, ok := firstMarkedName(marked, args)
+	if !ok {
+		return
+	}
+	from, to := namespaces[wf.Name], namespaces[target.Name]
+	if len(from) == 0 || len(to) == 0 || slices.ContainsFunc(to, func(ns string) bool { return slices.Contains(from, ns) }) {
+		return
+	}
+	v.warnPII(wf, pos, name, marked, fmt.Sprintf("PII value %s is passed to detach workflow %s in namespace %s, outside %s where workflow %s runs",
+		name, target.Name, strings.Join(to, ", "), strings.Join(from, ", "), wf.Name))
+}
+
+// checkPIINexus reports a detached nexus call passing a marked name, which
+// always leaves the caller's namespace through the endpoint.
+func (v *validationCtx) checkPIINexus(wf *ast.WorkflowDef, pos ast.Pos, endpoint, args string, marked map[string]ast.Pos) {
+	if name, ok := firstMarkedName(marked, args); ok {
+		v.warnPII(wf, pos, name, marked, fmt.Sprintf("PII value %s is passed through nexus endpoint %s by a detach call in workflow %s", name, endpoint, wf.Name))
+	}
+}
+
+func (v *validationCtx) warnPII(wf *ast.WorkflowDef, pos ast.Pos, name string, marked map[string]ast.Pos, msg string) {
+	origin := marked[name]
+	v.errs = append(v.errs, &Error{
+		Msg:      msg,
+		Line:     pos.Line,
+		Column:   pos.Column,
+		Severity: "warning",
+		Kind:     ErrPIIFlow,
+		Name:     wf.Name,
+		Related: []Related{{
+			Msg:    fmt.Sprintf("the value of %s comes from a @pii declaration here", name),
+			Line:   origin.Line,
+			Column: origin.Column,
+		}},
+	})
+}
+
+// workflowNamespaces maps each workflow name to the namespaces running a
+// worker that registers it, in namespace name order.
+func (v *validationCtx) workflowNamespaces() map[string][]string {
+	out := make(map[string][]string)
+	names := make([]string, 0, len(v.namespaces))
+	for name := range v.namespaces {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		for _, nw := range v.namespaces[name].Workers {
+			w := nw.Worker.Resolved
+			if w == nil {
+				continue
+			}
+			for _, ref := range w.Workflows {
+				if !slices.Contains(out[ref.Name], name) {
+					out[ref.Name] = append(out[ref.Name], name)
+				}
+			}
+		}
+	}
+	return out
+}
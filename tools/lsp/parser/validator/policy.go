@@ -0,0 +1,250 @@
+package validator
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/options"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/resolver"
+)
+
+// Policy selects the ownership and review rules checked by CheckPolicy. The
+// zero Policy checks nothing.
+type Policy struct {
+	// RequireOwner requires every workflow to declare @owner.
+	RequireOwner bool
+	// CriticalTag is the @tag value that marks a workflow as critical.
+	// Critical workflows must declare @sla, and every workflow call starting
+	// one must set workflow_execution_timeout or workflow_run_timeout. Empty
+	// disables both rules.
+	CriticalTag string
+	// RequireTimeout warns about workflows with no timeout path: no await
+	// one timer case closing them with close fail, and no call setting
+	// workflow_execution_timeout or workflow_run_timeout.
+	RequireTimeout bool
+	// UnknownNames warns about names that raw assignments and conditions
+	// use without a workflow declaring them: UnknownNamesTypos reports those
+	// close to a declared name, suggesting it, and UnknownNamesAll every
+	// one. Empty disables the check, as free-form pseudocode often uses
+	// names it never declares.
+	UnknownNames string
+	// ReturnInWorkflow reports return statements in workflow bodies, which
+	// close is replacing, with the severity ReturnInWorkflowWarning or
+	// ReturnInWorkflowError. Empty or ReturnInWorkflowOff disables the
+	// check; twf check and twf lsp default to warnings.
+	ReturnInWorkflow string
+	// RequireActivityTimeout warns about activity calls whose effective
+	// options set neither start_to_close_timeout nor
+	// schedule_to_close_timeout, which Temporal requires of every activity.
+	RequireActivityTimeout bool
+	// AwaitOneOrder warns about await one blocks whose cases are out of
+	// the order AwaitOneOrderTimerLast or AwaitOneOrderWatchFirst asks for,
+	// and about blocks with more than one timer case. Empty disables the
+	// check.
+	AwaitOneOrder string
+	// OptionDefaults are the workspace defaults for call options, the
+	// lowest layer of the effective options the timeout rules read. They
+	// enable no rule themselves.
+	OptionDefaults *options.Config
+}
+
+// Enabled reports whether p checks anything.
+func (p Policy) Enabled() bool {
+	return p.RequireOwner || p.CriticalTag != "" || p.RequireTimeout || p.RequireActivityTimeout || p.UnknownNames != "" || p.AwaitOneOrder != "" || p.returnsChecked()
+}
+
+func (p Policy) returnsChecked() bool {
+	return p.ReturnInWorkflow != "" && p.ReturnInWorkflow != ReturnInWorkflowOff
+}
+
+// CheckPolicy reports the workflows that break the rules p enables. Missing
+// annotations are reported at the workflow header; missing timeouts at the
+// call, with the called definition's header as related location. Workflows
+// started by await or promise take no options and are not checked for
+// workflow timeouts; activities they start are, by their definition's
+// options and the defaults.
+func CheckPolicy(symbols *resolver.SymbolTable, p Policy) []*Error {
+	return checkPolicy(symbols, p, nil)
+}
+
+// CheckPolicyDefinitions is CheckPolicy restricted to the errors found in
+// defs, as ValidateDefinitions is to ValidateSymbols. A call in defs starting
+// a critical workflow defined elsewhere in symbols is still checked.
+func CheckPolicyDefinitions(symbols *resolver.SymbolTable, p Policy, defs []ast.Definition) []*Error {
+	return checkPolicy(symbols, p, ownSet(defs))
+}
+
+func checkPolicy(symbols *resolver.SymbolTable, p Policy, own map[ast.Node]bool) []*Error {
+	var errs []*Error
+	critical := make(map[*ast.WorkflowDef]bool)
+	for _, name := range slices.Sorted(maps.Keys(symbols.Workflows)) {
+		wf := symbols.Workflows[name]
+		mine := own == nil || own[wf]
+		if mine && p.RequireOwner && findAnnotation(wf.Annotations, "owner", "") == nil {
+			errs = append(errs, &Error{
+				Msg:    fmt.Sprintf("workflow %s has no @owner annotation", wf.Name),
+				Line:   wf.Line,
+				Column: wf.Column,
+				Kind:   ErrMissingOwner,
+				Name:   wf.Name,
+			})
+		}
+		if mine && p.RequireTimeout && len(TimeoutPaths(symbols, wf, p.OptionDefaults)) == 0 {
+			errs = append(errs, &Error{
+				Msg:      fmt.Sprintf("workflow %s has no timeout path: no await one timer case ends in close fail and no call sets workflow_execution_timeout or workflow_run_timeout", wf.Name),
+				Line:     wf.Line,
+				Column:   wf.Column,
+				Severity: "warning",
+				Kind:     ErrNoTimeoutPath,
+				Name:     wf.Name,
+			})
+		}
+		if mine && p.UnknownNames != "" {
+			errs = append(errs, checkUnknownNames(symbols, wf, p.UnknownNames)...)
+		}
+		if mine && p.returnsChecked() {
+			errs = append(errs, checkReturnsInWorkflow(wf, p.ReturnInWorkflow)...)
+		}
+		if mine && p.AwaitOneOrder != "" {
+			errs = append(errs, checkAwaitOneOrder(wf, p.AwaitOneOrder)...)
+		}
+		if p.CriticalTag == "" || findAnnotation(wf.Annotations, "tag", p.CriticalTag) == nil {
+			continue
+		}
+		critical[wf] = true
+		if mine && findAnnotation(wf.Annotations, "sla", "") == nil {
+			errs = append(errs, &Error{
+				Msg:    fmt.Sprintf("workflow %s is tagged %s but has no @sla annotation", wf.Name, p.CriticalTag),
+				Line:   wf.Line,
+				Column: wf.Column,
+				Kind:   ErrMissingSLA,
+				Name:   wf.Name,
+			})
+		}
+	}
+	if p.RequireActivityTimeout {
+		for _, body := range callingBodies(symbols, own) {
+			errs = checkActivityTimeouts(errs, body, p.OptionDefaults)
+		}
+	}
+	if len(critical) == 0 {
+		return errs
+	}
+	for _, body := range callingBodies(symbols, own) {
+		errs = checkCriticalCalls(errs, body, critical, p)
+	}
+	return errs
+}
+
+// callingBodies returns the statement lists of the owned workflows and
+// nexus services that may make calls: workflow, signal, and update bodies,
+// then sync operation bodies, in name order.
+func callingBodies(symbols *resolver.SymbolTable, own map[ast.Node]bool) [][]ast.Statement {
+	var bodies [][]ast.Statement
+	for _, wf := range owned(own, symbols.Workflows) {
+		bodies = append(bodies, wf.Body)
+		for _, s := range wf.Signals {
+			bodies = append(bodies, s.Body)
+		}
+		for _, u := range wf.Updates {
+			bodies = append(bodies, u.Body)
+		}
+	}
+	for _, svc := range owned(own, symbols.NexusServices) {
+		for _, op := range svc.Operations {
+			bodies = append(bodies, op.Body)
+		}
+	}
+	return bodies
+}
+
+// checkActivityTimeouts appends a warning for each activity started in
+// stmts whose effective options have no start_to_close_timeout or
+// schedule_to_close_timeout. The message names the layers that could set
+// one: the call, unless it is an await or promise target, which takes no
+// options; the activity definition; and the option defaults. An options
+// block that failed to parse might set either, so it gets no warning.
+func checkActivityTimeouts(errs []*Error, stmts []ast.Statement, defaults *options.Config) []*Error {
+	check := func(act *ast.ActivityDef, call ast.Node, line, column int) {
+		if act == nil || act.Options.Unparsed() || options.Resolve(act, call, defaults).Has("start_to_close_timeout", "schedule_to_close_timeout") {
+			return
+		}
+		if c, ok := call.(*ast.ActivityCall); ok && c.Options.Unparsed() {
+			return
+		}
+		where := fmt.Sprintf("activity %s's options or the option defaults", act.Name)
+		if call != nil {
+			where = fmt.Sprintf("the call's options, activity %s's options, or the option defaults", act.Name)
+		}
+		errs = append(errs, &Error{
+			Msg:      fmt.Sprintf("activity %s runs with no start_to_close_timeout or schedule_to_close_timeout; set one in %s", act.Name, where),
+			Line:     line,
+			Column:   column,
+			Severity: "warning",
+			Kind:     ErrMissingActivityTimeout,
+			Name:     act.Name,
+			Related: []Related{{
+				Msg:    fmt.Sprintf("options here apply to every call of activity %s", act.Name),
+				Line:   act.Line,
+				Column: act.Column,
+				File:   act.SourceFile,
+			}},
+		})
+	}
+	ast.WalkStatements(stmts, func(s ast.Statement) bool {
+		if call, ok := s.(*ast.ActivityCall); ok {
+			check(call.Activity.Resolved, call, call.Line, call.Column)
+		}
+		return true
+	}, ast.WithAsyncTargets(func(t ast.AsyncTarget, s ast.Statement) bool {
+		if t, ok := t.(*ast.ActivityTarget); ok {
+			check(t.Activity.Resolved, nil, s.NodeLine(), s.NodeColumn())
+		}
+		return true
+	}))
+	return errs
+}
+
+// checkCriticalCalls appends an error for each call in stmts that starts a
+// critical workflow without a workflow timeout in its effective options.
+func checkCriticalCalls(errs []*Error, stmts []ast.Statement, critical map[*ast.WorkflowDef]bool, p Policy) []*Error {
+	tag := p.CriticalTag
+	ast.WalkStatements(stmts, func(s ast.Statement) bool {
+		call, ok := s.(*ast.WorkflowCall)
+		if !ok || !critical[call.Workflow.Resolved] {
+			return true
+		}
+		wf := call.Workflow.Resolved
+		if options.Resolve(wf, call, p.OptionDefaults).Has("workflow_execution_timeout", "workflow_run_timeout") {
+			return true
+		}
+		errs = append(errs, &Error{
+			Msg:    fmt.Sprintf("workflow %s is tagged %s; set workflow_execution_timeout or workflow_run_timeout in the call or workflow options", wf.Name, tag),
+			Line:   call.Line,
+			Column: call.Column,
+			Kind:   ErrMissingTimeout,
+			Name:   wf.Name,
+			Related: []Related{{
+				Msg:    fmt.Sprintf("workflow %s is tagged %s here", wf.Name, tag),
+				Line:   wf.Line,
+				Column: wf.Column,
+				File:   wf.SourceFile,
+			}},
+		})
+		return true
+	})
+	return errs
+}
+
+// findAnnotation returns the first annotation called name, or nil. A
+// non-empty value must also match the annotation's value.
+func findAnnotation(anns []*ast.Annotation, name, value string) *ast.Annotation {
+	for _, a := range anns {
+		if a.Name == name && (value == "" || a.Value() == value) {
+			return a
+		}
+	}
+	return nil
+}
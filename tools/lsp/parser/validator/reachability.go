@@ -0,0 +1,109 @@
+package validator
+
+import (
+	"fmt"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+)
+
+// checkReachability warns about statements no path reaches: those after a
+// close, return, break, or continue, and those after an await one, if, or
+// switch every branch of which terminates.
+func (v *validationCtx) checkReachability() {
+	for _, wf := range owned(v.own, v.workflows) {
+		v.reachable(wf.Body)
+		for _, s := range wf.Signals {
+			v.reachable(s.Body)
+		}
+		for _, u := range wf.Updates {
+			v.reachable(u.Body)
+		}
+	}
+}
+
+// reachable checks stmts and the blocks nested in them, reporting the first
+// unreachable statement of each list, and reports whether control can fall
+// off the end of stmts.
+func (v *validationCtx) reachable(stmts []ast.Statement) bool {
+	for i, s := range stmts {
+		if v.terminates(s) {
+			if i+1 < len(stmts) {
+				v.warnUnreachable(s, stmts[i+1])
+			}
+			return false
+		}
+	}
+	return true
+}
+
+// terminates checks the blocks nested in s and reports whether every path
+// through s ends in a close, return, break, or continue.
+func (v *validationCtx) terminates(s ast.Statement) bool {
+	switch n := s.(type) {
+	case *ast.CloseStmt, *ast.ReturnStmt, *ast.BreakStmt, *ast.ContinueStmt:
+		return true
+	case *ast.IfStmt:
+		then := !v.reachable(n.Body)
+		otherwise := !v.reachable(n.ElseBody)
+		return then && otherwise && len(n.ElseBody) > 0
+	case *ast.SwitchBlock:
+		all := len(n.Default) > 0
+		for _, c := range n.Cases {
+			if v.reachable(c.Body) {
+				all = false
+			}
+		}
+		if v.reachable(n.Default) {
+			all = false
+		}
+		return all
+	case *ast.AwaitOneBlock:
+		all := len(n.Cases) > 0
+		for _, c := range n.Cases {
+			if c.AwaitAll != nil {
+				v.reachable(c.AwaitAll.Body)
+			}
+			if v.reachable(c.Body) {
+				all = false
+			}
+		}
+		return all
+	case *ast.AwaitAllBlock:
+		v.reachable(n.Body)
+	case *ast.ForStmt:
+		// A loop's break and continue end its iteration, not the code after it.
+		v.reachable(n.Body)
+	}
+	return false
+}
+
+func (v *validationCtx) warnUnreachable(end, next ast.Statement) {
+	msg := "unreachable: all branches above terminate"
+	related := "every branch of this block terminates"
+	switch end.(type) {
+	case *ast.CloseStmt:
+		msg = fmt.Sprintf("unreachable: the close on line %d ends this path", end.NodeLine())
+		related = "the workflow closes here"
+	case *ast.ReturnStmt:
+		msg = fmt.Sprintf("unreachable: the return on line %d ends this path", end.NodeLine())
+		related = "the path returns here"
+	case *ast.BreakStmt:
+		msg = fmt.Sprintf("unreachable: the break on line %d ends this path", end.NodeLine())
+		related = "the loop is left here"
+	case *ast.ContinueStmt:
+		msg = fmt.Sprintf("unreachable: the continue on line %d ends this path", end.NodeLine())
+		related = "the next iteration starts here"
+	}
+	v.errs = append(v.errs, &Error{
+		Msg:      msg,
+		Line:     next.NodeLine(),
+		Column:   next.NodeColumn(),
+		Severity: "warning",
+		Kind:     ErrUnreachable,
+		Related: []Related{{
+			Msg:    related,
+			Line:   end.NodeLine(),
+			Column: end.NodeColumn(),
+		}},
+	})
+}
@@ -0,0 +1,44 @@
+package validator
+
+import (
+	"fmt"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+)
+
+// Modes of Policy.ReturnInWorkflow.
+const (
+	// ReturnInWorkflowOff reports nothing.
+	ReturnInWorkflowOff = "off"
+	// ReturnInWorkflowWarning reports each return as a warning.
+	ReturnInWorkflowWarning = "warning"
+	// ReturnInWorkflowError reports each return as an error.
+	ReturnInWorkflowError = "error"
+)
+
+// checkReturnsInWorkflow reports the return statements in the body of wf,
+// which close the workflow under the old grammar and are deprecated in
+// favor of close. Handlers return their own values and are not checked.
+func checkReturnsInWorkflow(wf *ast.WorkflowDef, mode string) []*Error {
+	var errs []*Error
+	ast.WalkStatements(wf.Body, func(s ast.Statement) bool {
+		ret, ok := s.(*ast.ReturnStmt)
+		if !ok {
+			return true
+		}
+		want := "close complete"
+		if ret.Value != "" {
+			want += "(...)"
+		}
+		errs = append(errs, &Error{
+			Msg:      fmt.Sprintf("return in workflow %s is deprecated; use %s", wf.Name, want),
+			Line:     ret.Line,
+			Column:   ret.Column,
+			Severity: mode,
+			Kind:     ErrDeprecatedReturn,
+			Name:     wf.Name,
+		})
+		return true
+	})
+	return errs
+}
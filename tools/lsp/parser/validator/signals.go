@@ -0,0 +1,271 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+)
+
+// checkSignalDelivery checks that messages sent to a workflow can matter.
+// A signal, or an update with no result, whose handler only sets state
+// that nothing else reads, and that no await waits for, is dead: delivering
+// it changes nothing. And a workflow body waiting on a condition that no
+// handler sets, and that the body sets only after the wait or not at all,
+// waits forever.
+func (v *validationCtx) checkSignalDelivery() {
+	for _, wf := range owned(v.own, v.workflows) {
+		awaited := make(map[ast.Statement]bool)
+		for _, body := range handlerBodies(wf, nil) {
+			ast.WalkStatements(body, func(ast.Statement) bool { return true }, ast.WithAsyncTargets(func(t ast.AsyncTarget, _ ast.Statement) bool {
+				switch t := t.(type) {
+				case *ast.SignalTarget:
+					if t.Signal.Resolved != nil {
+						awaited[t.Signal.Resolved] = true
+					}
+				case *ast.UpdateTarget:
+					if t.Update.Resolved != nil {
+						awaited[t.Update.Resolved] = true
+					}
+				}
+				return true
+			}))
+		}
+		for _, s := range wf.Signals {
+			if !awaited[s] {
+				v.checkDeadHandler(wf, s, "signal", s.Name, s.Pos, s.Body)
+			}
+		}
+		for _, u := range wf.Updates {
+			if !awaited[u] && u.ReturnType == "" {
+				v.checkDeadHandler(wf, u, "update", u.Name, u.Pos, u.Body)
+			}
+		}
+		v.checkConditionWaits(wf)
+	}
+}
+
+// handlerBodies returns the statement lists of wf: its body and those of
+// all its handlers, queries included, except the handler skip.
+func handlerBodies(wf *ast.WorkflowDef, skip ast.Statement) [][]ast.Statement {
+	bodies := [][]ast.Statement{wf.Body}
+	for _, s := range wf.Signals {
+		if s != skip {
+			bodies = append(bodies, s.Body)
+		}
+	}
+	for _, q := range wf.Queries {
+		bodies = append(bodies, q.Body)
+	}
+	for _, u := range wf.Updates {
+		if u != skip {
+			bodies = append(bodies, u.Body)
+		}
+	}
+	return bodies
+}
+
+// checkDeadHandler warns about a handler that is never awaited when all it
+// does is set names that no other part of wf mentions. Handlers calling
+// anything, or doing anything but assign and set, may have effects of
+// their own and pass.
+func (v *validationCtx) checkDeadHandler(wf *ast.WorkflowDef, handler ast.Statement, kind, name string, pos ast.Pos, body []ast.Statement) {
+	type write struct {
+		name string
+		pos  ast.Pos
+	}
+	var writes []write
+	effects := false
+	ast.WalkStatements(body, func(s ast.Statement) bool {
+		switch n := s.(type) {
+		case *ast.RawStmt:
+			m := assignment.FindStringSubmatch(n.Text)
+			if m == nil {
+				effects = true
+			} else {
+				writes = append(writes, write{m[1], n.Pos})
+			}
+		case *ast.SetStmt:
+			writes = append(writes, write{n.Condition.Name, n.Pos})
+		case *ast.UnsetStmt:
+			writes = append(writes, write{n.Condition.Name, n.Pos})
+		case *ast.IfStmt, *ast.ForStmt, *ast.SwitchBlock, *ast.SwitchCase, *ast.Comment:
+		default:
+			effects = true
+		}
+		return !effects
+	})
+	if effects {
+		return
+	}
+
+	// Everything outside this handler that could read a name.
+	reads := make(map[string]bool)
+	for _, other := range handlerBodies(wf, handler) {
+		addWords(reads, statementText(other))
+	}
+	var related []Related
+	for _, w := range writes {
+		if reads[w.name] {
+			return
+		}
+		related = append(related, Related{
+			Msg:    fmt.Sprintf("%s is set here but never read", w.name),
+			Line:   w.pos.Line,
+			Column: w.pos.Column,
+		})
+	}
+
+	msg := fmt.Sprintf("%s %s of workflow %s is never awaited and its handler is empty; delivering it has no effect", kind, name, wf.Name)
+	if len(writes) > 0 {
+		msg = fmt.Sprintf("%s %s of workflow %s is never awaited and nothing reads what its handler sets; delivering it has no effect", kind, name, wf.Name)
+	}
+	v.errs = append(v.errs, &Error{
+		Msg:      msg,
+		Line:     pos.Line,
+		Column:   pos.Column,
+		Severity: "warning",
+		Kind:     ErrDeadHandler,
+		Name:     name,
+		Related:  related,
+	})
+}
+
+// checkConditionWaits warns about each await in wf's body on a condition
+// that no handler sets and that the body sets, if at all, only after the
+// wait. Sets in await all branches run alongside the wait, and awaits in
+// loops may follow a set from an earlier pass, so both pass.
+func (v *validationCtx) checkConditionWaits(wf *ast.WorkflowDef) {
+	concurrent := make(map[*ast.ConditionDecl]bool)
+	for _, body := range handlerBodies(wf, nil)[1:] {
+		ast.WalkStatements(body, func(s ast.Statement) bool {
+			if set, ok := s.(*ast.SetStmt); ok && set.Condition.Resolved != nil {
+				concurrent[set.Condition.Resolved] = true
+			}
+			return true
+		})
+	}
+	var sets []*ast.SetStmt
+	looped := make(map[*ast.AwaitStmt]bool)
+	ast.WalkStatements(wf.Body, func(s ast.Statement) bool {
+		switch n := s.(type) {
+		case *ast.SetStmt:
+			sets = append(sets, n)
+		case *ast.AwaitAllBlock:
+			ast.WalkStatements(n.Body, func(s ast.Statement) bool {
+				if set, ok := s.(*ast.SetStmt); ok && set.Condition.Resolved != nil {
+					concurrent[set.Condition.Resolved] = true
+				}
+				return true
+			})
+		case *ast.ForStmt:
+			ast.WalkStatements(n.Body, func(s ast.Statement) bool {
+				if a, ok := s.(*ast.AwaitStmt); ok {
+					looped[a] = true
+				}
+				return true
+			})
+		}
+		return true
+	})
+
+	ast.WalkStatements(wf.Body, func(s ast.Statement) bool {
+		a, ok := s.(*ast.AwaitStmt)
+		if !ok || looped[a] {
+			return true
+		}
+		t, ok := a.Target.(*ast.IdentTarget)
+		if !ok || t.Resolved.Condition == nil || concurrent[t.Resolved.Condition] {
+			return true
+		}
+		cond := t.Resolved.Condition
+		var later []Related
+		for _, set := range sets {
+			if set.Condition.Resolved != cond {
+				continue
+			}
+			if set.Line < a.Line {
+				return true
+			}
+			later = append(later, Related{
+				Msg:    fmt.Sprintf("%s is set here, after the wait", cond.Name),
+				Line:   set.Line,
+				Column: set.Column,
+			})
+		}
+		msg := fmt.Sprintf("await %s in workflow %s never ends: no signal or update handler sets %s", cond.Name, wf.Name, cond.Name)
+		if len(later) > 0 {
+			msg = fmt.Sprintf("await %s in workflow %s never ends: no signal or update handler sets %s, and the workflow sets it only after waiting", cond.Name, wf.Name, cond.Name)
+		}
+		related := append([]Related{{
+			Msg:    fmt.Sprintf("condition %s is declared here", cond.Name),
+			Line:   cond.Line,
+			Column: cond.Column,
+		}}, later...)
+		v.errs = append(v.errs, &Error{
+			Msg:      msg,
+			Line:     a.Line,
+			Column:   a.Column,
+			Severity: "warning",
+			Kind:     ErrUnsetCondition,
+			Name:     cond.Name,
+			Related:  related,
+		})
+		return true
+	})
+}
+
+// statementText concatenates the source text of stmts that may read names:
+// raw statements, conditions, case values, call and close arguments, and
+// the names awaits and returns mention.
+func statementText(stmts []ast.Statement) string {
+	var b strings.Builder
+	add := func(texts ...string) {
+		for _, t := range texts {
+			b.WriteString(t)
+			b.WriteByte('\n')
+		}
+	}
+	ast.WalkStatements(stmts, func(s ast.Statement) bool {
+		switch n := s.(type) {
+		case *ast.RawStmt:
+			add(n.Text)
+		case *ast.IfStmt:
+			add(n.Condition)
+		case *ast.ForStmt:
+			add(n.Condition, n.Iterable)
+		case *ast.SwitchBlock:
+			add(n.Expr)
+		case *ast.SwitchCase:
+			add(n.Value)
+		case *ast.AwaitOneCase:
+			add(n.Guard)
+		case *ast.ActivityCall:
+			add(n.Args)
+		case *ast.WorkflowCall:
+			add(n.Args)
+		case *ast.NexusCall:
+			add(n.Args)
+		case *ast.CloseStmt:
+			add(n.Args)
+		case *ast.ReturnStmt:
+			add(n.Value)
+		}
+		return true
+	}, ast.WithAsyncTargets(func(t ast.AsyncTarget, _ ast.Statement) bool {
+		switch t := t.(type) {
+		case *ast.IdentTarget:
+			add(t.Name)
+		case *ast.ActivityTarget:
+			add(t.Args)
+		case *ast.WorkflowTarget:
+			add(t.Args)
+		case *ast.NexusTarget:
+			add(t.Args)
+		case *ast.TimerTarget:
+			add(t.Duration)
+		}
+		return true
+	}))
+	return b.String()
+}
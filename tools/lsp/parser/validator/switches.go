@@ -0,0 +1,83 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/eval"
+)
+
+// checkSwitch reports case clauses that repeat an earlier case's value and
+// switches whose subject is a constant, so at most one case can match.
+func (v *validationCtx) checkSwitch(sw *ast.SwitchBlock) {
+	first := make(map[string]*ast.SwitchCase)
+	for _, c := range sw.Cases {
+		key := caseKey(c)
+		prev, dup := first[key]
+		if !dup {
+			first[key] = c
+			continue
+		}
+		v.errs = append(v.errs, &Error{
+			Msg:    fmt.Sprintf("duplicate case %s in switch (first at line %d); this case never matches", c.Value, prev.Line),
+			Line:   c.Line,
+			Column: c.Column,
+			Kind:   ErrDuplicateSwitchCase,
+			Related: []Related{{
+				Msg:    fmt.Sprintf("case %s first appears here", prev.Value),
+				Line:   prev.Line,
+				Column: prev.Column,
+			}},
+		})
+	}
+
+	if sw.SubjectExpr == nil {
+		return
+	}
+	subject, ok := eval.Eval(sw.SubjectExpr, nil)
+	if !ok {
+		return
+	}
+	for _, c := range sw.Cases {
+		if val, ok := eval.Eval(c.ValueExpr, nil); ok && val.Equal(subject) {
+			v.errs = append(v.errs, &Error{
+				Msg:      fmt.Sprintf("switch expression (%s) is constant; only case %s can match", sw.Expr, c.Value),
+				Line:     sw.Line,
+				Column:   sw.Column,
+				Severity: "warning",
+				Kind:     ErrConstantSwitch,
+				Related: []Related{{
+					Msg:    "the only matching case",
+					Line:   c.Line,
+					Column: c.Column,
+				}},
+			})
+			return
+		}
+	}
+	msg := fmt.Sprintf("switch expression (%s) is constant and matches no case", sw.Expr)
+	if sw.Default != nil {
+		msg += "; only the else branch runs"
+	}
+	v.errs = append(v.errs, &Error{
+		Msg:      msg,
+		Line:     sw.Line,
+		Column:   sw.Column,
+		Severity: "warning",
+		Kind:     ErrConstantSwitch,
+	})
+}
+
+// caseKey identifies a case value for duplicate detection. Constant values
+// compare by their folded value, so 60s and 1m collide; other expressions
+// compare by canonical source, and free-form values by trimmed text.
+func caseKey(c *ast.SwitchCase) string {
+	if c.ValueExpr == nil {
+		return "raw:" + strings.TrimSpace(c.Value)
+	}
+	if val, ok := eval.Eval(c.ValueExpr, nil); ok {
+		return fmt.Sprintf("const:%d:%s", val.Kind, val)
+	}
+	return "expr:" + ast.ExprString(c.ValueExpr)
+}
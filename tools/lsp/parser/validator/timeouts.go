@@ -0,0 +1,158 @@
+package validator
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/options"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/resolver"
+)
+
+// Timeout path kinds.
+const (
+	// TimeoutTimer is an await one timer case whose body closes the
+	// workflow with close fail.
+	TimeoutTimer = "timer"
+	// TimeoutExecution is a workflow_execution_timeout set by a call
+	// starting the workflow, by the workflow's options, or by the option
+	// defaults.
+	TimeoutExecution = "execution"
+	// TimeoutRun is a workflow_run_timeout set where a TimeoutExecution
+	// may be.
+	TimeoutRun = "run"
+)
+
+// TimeoutPath is a way a workflow ends because time ran out.
+type TimeoutPath struct {
+	Kind     string // TimeoutTimer, TimeoutExecution, or TimeoutRun
+	Duration string
+	Line     int // the await one block, or the call or option setting the timeout; 0 for option defaults
+	Column   int
+	File     string // source file of the call or workflow options; empty for a timer case
+}
+
+// String describes the path, as in "times out after 24h via await one at
+// line 42" or "execution timeout 72h".
+func (t TimeoutPath) String() string {
+	switch t.Kind {
+	case TimeoutTimer:
+		return fmt.Sprintf("times out after %s via await one at line %d", t.Duration, t.Line)
+	case TimeoutRun:
+		return "run timeout " + t.Duration
+	default:
+		return "execution timeout " + t.Duration
+	}
+}
+
+// TimeoutPaths returns the timeout paths of wf: its await one timer cases
+// leading to close fail, in source order, then the execution and run
+// timeouts every start of wf has from its options and defaults, then those
+// set by the workflow calls in symbols that start it.
+func TimeoutPaths(symbols *resolver.SymbolTable, wf *ast.WorkflowDef, defaults *options.Config) []TimeoutPath {
+	var paths []TimeoutPath
+	ast.WalkStatements(wf.Body, func(s ast.Statement) bool {
+		block, ok := s.(*ast.AwaitOneBlock)
+		if !ok {
+			return true
+		}
+		for _, c := range block.Cases {
+			if t, ok := c.Target.(*ast.TimerTarget); ok && closesFailed(c.Body) {
+				paths = append(paths, TimeoutPath{Kind: TimeoutTimer, Duration: t.Duration, Line: block.Line, Column: block.Column})
+			}
+		}
+		return true
+	})
+
+	for _, o := range options.Resolve(wf, nil, defaults) {
+		kind := timeoutKind(o.Key)
+		if kind == "" {
+			continue
+		}
+		path := TimeoutPath{Kind: kind, Duration: o.Value, Line: o.Line, Column: o.Column}
+		if o.Layer == options.LayerDefinition {
+			path.File = wf.SourceFile
+		}
+		paths = append(paths, path)
+	}
+
+	calls := func(stmts []ast.Statement) {
+		ast.WalkStatements(stmts, func(s ast.Statement) bool {
+			call, ok := s.(*ast.WorkflowCall)
+			if !ok || call.Workflow.Resolved != wf || call.Options == nil {
+				return true
+			}
+			for _, e := range call.Options.Entries {
+				kind := timeoutKind(e.Key)
+				if kind == "" {
+					continue
+				}
+				paths = append(paths, TimeoutPath{Kind: kind, Duration: e.Value, Line: call.Line, Column: call.Column})
+			}
+			return true
+		})
+	}
+	for _, name := range slices.Sorted(maps.Keys(symbols.Workflows)) {
+		caller := symbols.Workflows[name]
+		start := len(paths)
+		calls(caller.Body)
+		for _, s := range caller.Signals {
+			calls(s.Body)
+		}
+		for _, u := range caller.Updates {
+			calls(u.Body)
+		}
+		for i := start; i < len(paths); i++ {
+			paths[i].File = caller.SourceFile
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(symbols.NexusServices)) {
+		svc := symbols.NexusServices[name]
+		start := len(paths)
+		for _, op := range svc.Operations {
+			calls(op.Body)
+		}
+		for i := start; i < len(paths); i++ {
+			paths[i].File = svc.SourceFile
+		}
+	}
+	return paths
+}
+
+// timeoutKind returns the timeout path kind an option key sets, or "".
+func timeoutKind(key string) string {
+	switch key {
+	case "workflow_execution_timeout":
+		return TimeoutExecution
+	case "workflow_run_timeout":
+		return TimeoutRun
+	}
+	return ""
+}
+
+// TimeoutSummary joins the distinct descriptions of paths, as in "times out
+// after 24h via await one at line 42; execution timeout 72h". It is empty
+// when there are no paths.
+func TimeoutSummary(paths []TimeoutPath) string {
+	var parts []string
+	for _, p := range paths {
+		if s := p.String(); !slices.Contains(parts, s) {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// closesFailed reports whether stmts contain a close fail.
+func closesFailed(stmts []ast.Statement) bool {
+	found := false
+	ast.WalkStatements(stmts, func(s ast.Statement) bool {
+		if c, ok := s.(*ast.CloseStmt); ok && c.Reason == ast.CloseFailWorkflow {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
@@ -2,8 +2,13 @@ package validator
 
 import (
 	"fmt"
+	"iter"
+	"maps"
+	"slices"
 
 	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/options"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/resolver"
 )
 
 // ErrorKind classifies a validation error for structured handling.
@@ -25,6 +30,30 @@ const (
 	ErrExplicitRoutingMismatch
 	ErrImplicitRoutingMismatch
 	ErrEndpointServiceLinkage
+	ErrConstantCondition
+	ErrLoopNeverTerminates
+	ErrDuplicateSwitchCase
+	ErrConstantSwitch
+	ErrMissingOwner
+	ErrMissingSLA
+	ErrMissingTimeout
+	ErrWorkflowIDPlaceholder
+	ErrInvalidConcurrency
+	ErrNoTimeoutPath
+	ErrUnreachable
+	ErrNonExhaustiveSwitch
+	ErrUnknownName
+	ErrCloseValueMismatch
+	ErrDeprecatedReturn
+	ErrDeadHandler
+	ErrUnsetCondition
+	ErrDialectMismatch
+	ErrPIIFlow
+	ErrMissingActivityTimeout
+	ErrKeywordCase
+	ErrRemovedHint
+	ErrAwaitOneOrder
+	ErrAwaitOneTimers
 )
 
 // Error represents a validation error with position info.
@@ -34,7 +63,21 @@ type Error struct {
 	Column   int
 	Severity string // "error" (default) or "warning"
 	Kind     ErrorKind
-	Name     string // primary entity referenced by this error
+	Name     string    // primary entity referenced by this error
+	Related  []Related // other locations involved in the error
+
+	// Suggestion replaces Name at the error's position to fix it, such as
+	// the declared name an unknown one is likely a misspelling of.
+	Suggestion string
+}
+
+// Related points at a secondary location for an Error, such as the first
+// occurrence of a duplicated value.
+type Related struct {
+	Msg    string
+	Line   int
+	Column int
+	File   string // source file of the location; empty for the validated file
 }
 
 func (e *Error) Error() string {
@@ -48,43 +91,70 @@ type validationCtx struct {
 	namespaces    map[string]*ast.NamespaceDef
 	nexusServices map[string]*ast.NexusServiceDef
 	allEndpoints  map[string]*ast.NamespaceEndpoint
+	constants     map[string]*ast.ConstDef
+	enums         map[string]*ast.EnumDef
 	errs          []*Error
+
+	// own holds the definitions whose errors are reported; nil reports all.
+	// The maps above still hold every definition, for lookups.
+	own map[ast.Node]bool
+}
+
+// owned yields the entries of defs in own, or every entry when own is nil,
+// in name order, so errors come out in the same order on every run.
+func owned[T ast.Node](own map[ast.Node]bool, defs map[string]T) iter.Seq2[string, T] {
+	return func(yield func(string, T) bool) {
+		for _, name := range slices.Sorted(maps.Keys(defs)) {
+			def := defs[name]
+			if own != nil && !own[def] {
+				continue
+			}
+			if !yield(name, def) {
+				return
+			}
+		}
+	}
 }
 
 // Validate runs deployment/routing validation on a resolved AST.
 // Call after resolver.Resolve().
 func Validate(file *ast.File) []*Error {
-	v := &validationCtx{
-		workflows:     make(map[string]*ast.WorkflowDef),
-		activities:    make(map[string]*ast.ActivityDef),
-		workers:       make(map[string]*ast.WorkerDef),
-		namespaces:    make(map[string]*ast.NamespaceDef),
-		nexusServices: make(map[string]*ast.NexusServiceDef),
-		allEndpoints:  make(map[string]*ast.NamespaceEndpoint),
-	}
-
-	// Build definition maps from the AST.
-	for _, def := range file.Definitions {
-		switch d := def.(type) {
-		case *ast.WorkflowDef:
-			v.workflows[d.Name] = d
-		case *ast.ActivityDef:
-			v.activities[d.Name] = d
-		case *ast.WorkerDef:
-			v.workers[d.Name] = d
-		case *ast.NamespaceDef:
-			v.namespaces[d.Name] = d
-		case *ast.NexusServiceDef:
-			v.nexusServices[d.Name] = d
-		}
+	return ValidateSymbols(resolver.CollectSymbols(file))
+}
+
+// ValidateSymbols is Validate for a file whose symbol tables the caller
+// already has, typically from resolver.ResolveFile.
+func ValidateSymbols(symbols *resolver.SymbolTable) []*Error {
+	return validate(symbols, nil)
+}
+
+// ValidateDefinitions is ValidateSymbols for a table holding the definitions
+// of several files, such as one from resolver.ResolveWith: it reports only
+// the errors found in defs, checking them against every definition in
+// symbols.
+func ValidateDefinitions(symbols *resolver.SymbolTable, defs []ast.Definition) []*Error {
+	return validate(symbols, ownSet(defs))
+}
+
+func ownSet(defs []ast.Definition) map[ast.Node]bool {
+	own := make(map[ast.Node]bool, len(defs))
+	for _, def := range defs {
+		own[def] = true
 	}
+	return own
+}
 
-	// Build global endpoint map.
-	for _, ns := range v.namespaces {
-		for i := range ns.Endpoints {
-			ep := &ns.Endpoints[i]
-			v.allEndpoints[ep.EndpointName] = ep
-		}
+func validate(symbols *resolver.SymbolTable, own map[ast.Node]bool) []*Error {
+	v := &validationCtx{
+		workflows:     symbols.Workflows,
+		activities:    symbols.Activities,
+		workers:       symbols.Workers,
+		namespaces:    symbols.Namespaces,
+		nexusServices: symbols.NexusServices,
+		allEndpoints:  symbols.Endpoints,
+		constants:     symbols.Constants,
+		enums:         symbols.Enums,
+		own:           own,
 	}
 
 	// 1. Empty definition warnings.
@@ -102,11 +172,38 @@ func Validate(file *ast.File) []*Error {
 	// 5-6. Call routing + endpoint-service linkage (walks resolved bodies).
 	v.walkAllBodies()
 
+	// 7. Constant conditions, non-terminating loops, and switch cases.
+	v.checkConditions()
+
+	// 8. Workflow ID placeholders on child workflow calls.
+	v.checkWorkflowIDs()
+
+	// 9. Concurrency limits of parallel loops.
+	v.checkParallelLoops()
+
+	// 10. Statements after branches that all terminate.
+	v.checkReachability()
+
+	// 11. Switches on enum values that miss some of them.
+	v.checkEnumSwitches()
+
+	// 12. Close values that do not fit the workflow's return type.
+	v.checkCloseValues()
+
+	// 13. Signals and updates that cannot matter, and waits that never end.
+	v.checkSignalDelivery()
+
+	// 14. Values marked @pii reaching logs or other namespaces.
+	v.checkPIIFlows()
+
+	// 15. Statements of the removed hint construct.
+	v.checkRemovedHints()
+
 	return v.errs
 }
 
 func (v *validationCtx) checkEmptyDefinitions() {
-	for _, wf := range v.workflows {
+	for _, wf := range owned(v.own, v.workflows) {
 		if !hasNonCommentStmts(wf.Body) && len(wf.Signals) == 0 && len(wf.Queries) == 0 && len(wf.Updates) == 0 && wf.State == nil {
 			v.errs = append(v.errs, &Error{
 				Msg:      fmt.Sprintf("workflow %s has an empty body", wf.Name),
@@ -118,7 +215,7 @@ func (v *validationCtx) checkEmptyDefinitions() {
 			})
 		}
 	}
-	for _, act := range v.activities {
+	for _, act := range owned(v.own, v.activities) {
 		if !hasNonCommentStmts(act.Body) {
 			v.errs = append(v.errs, &Error{
 				Msg:      fmt.Sprintf("activity %s has an empty body", act.Name),
@@ -130,7 +227,7 @@ func (v *validationCtx) checkEmptyDefinitions() {
 			})
 		}
 	}
-	for _, w := range v.workers {
+	for _, w := range owned(v.own, v.workers) {
 		if len(w.Workflows) == 0 && len(w.Activities) == 0 && len(w.Services) == 0 {
 			v.errs = append(v.errs, &Error{
 				Msg:      fmt.Sprintf("worker %s has no workflow, activity, or nexus service registrations", w.Name),
@@ -142,7 +239,7 @@ func (v *validationCtx) checkEmptyDefinitions() {
 			})
 		}
 	}
-	for _, ns := range v.namespaces {
+	for _, ns := range owned(v.own, v.namespaces) {
 		if len(ns.Workers) == 0 && len(ns.Endpoints) == 0 {
 			v.errs = append(v.errs, &Error{
 				Msg:      fmt.Sprintf("namespace %s has no worker or endpoint instantiations", ns.Name),
@@ -157,7 +254,7 @@ func (v *validationCtx) checkEmptyDefinitions() {
 }
 
 func (v *validationCtx) checkTaskQueueRequirements() {
-	for _, ns := range v.namespaces {
+	for _, ns := range owned(v.own, v.namespaces) {
 		for _, nw := range ns.Workers {
 			tq := extractTaskQueue(nw.Options)
 			if tq == "" {
@@ -212,10 +309,10 @@ func (v *validationCtx) checkCoverage() {
 		}
 	}
 
-	checkUncovered(v.workflows, coveredWorkflows, "workflow %s is not registered on any instantiated worker", ErrUncoveredWorkflow, &v.errs)
-	checkUncovered(v.activities, coveredActivities, "activity %s is not registered on any instantiated worker", ErrUncoveredActivity, &v.errs)
-	checkUncovered(v.nexusServices, coveredServices, "nexus service %s is not referenced by any worker", ErrUncoveredService, &v.errs)
-	checkUncovered(v.workers, instantiatedWorkers, "worker %s is not instantiated in any namespace", ErrUninstantiatedWorker, &v.errs)
+	checkUncovered(owned(v.own, v.workflows), coveredWorkflows, "workflow %s is not registered on any instantiated worker", ErrUncoveredWorkflow, &v.errs)
+	checkUncovered(owned(v.own, v.activities), coveredActivities, "activity %s is not registered on any instantiated worker", ErrUncoveredActivity, &v.errs)
+	checkUncovered(owned(v.own, v.nexusServices), coveredServices, "nexus service %s is not referenced by any worker", ErrUncoveredService, &v.errs)
+	checkUncovered(owned(v.own, v.workers), instantiatedWorkers, "worker %s is not instantiated in any namespace", ErrUninstantiatedWorker, &v.errs)
 }
 
 func (v *validationCtx) checkTaskQueueCoherence() {
@@ -224,7 +321,7 @@ func (v *validationCtx) checkTaskQueueCoherence() {
 		workflows  map[string]bool
 		activities map[string]bool
 	}
-	for _, ns := range v.namespaces {
+	for _, ns := range owned(v.own, v.namespaces) {
 		queueWorkers := make(map[string][]queueInfo)
 		for _, nw := range ns.Workers {
 			tq := extractTaskQueue(nw.Options)
@@ -249,7 +346,8 @@ func (v *validationCtx) checkTaskQueueCoherence() {
 				activities: actSet,
 			})
 		}
-		for queue, infos := range queueWorkers {
+		for _, queue := range slices.Sorted(maps.Keys(queueWorkers)) {
+			infos := queueWorkers[queue]
 			if len(infos) < 2 {
 				continue
 			}
@@ -281,7 +379,7 @@ func (v *validationCtx) walkAllBodies() {
 		return
 	}
 
-	for _, wf := range v.workflows {
+	for _, wf := range owned(v.own, v.workflows) {
 		v.walkStatements(wf.Body, wf.Name)
 		for _, s := range wf.Signals {
 			v.walkStatements(s.Body, wf.Name)
@@ -294,7 +392,7 @@ func (v *validationCtx) walkAllBodies() {
 		}
 	}
 
-	for _, svc := range v.nexusServices {
+	for _, svc := range owned(v.own, v.nexusServices) {
 		for _, op := range svc.Operations {
 			if op.OpType == ast.NexusOpSync {
 				v.walkStatements(op.Body, "")
@@ -307,9 +405,9 @@ func (v *validationCtx) walkStatements(stmts []ast.Statement, callingWorkflow st
 	ast.WalkStatements(stmts, func(s ast.Statement) bool {
 		switch n := s.(type) {
 		case *ast.ActivityCall:
-			v.checkCallRouting("activity", n.Activity.Name, n.Options, callingWorkflow, n.Line, n.Column)
+			v.checkCallRouting("activity", n.Activity.Name, options.Resolve(n.Activity.Resolved, n, nil), callingWorkflow, n.Line, n.Column)
 		case *ast.WorkflowCall:
-			v.checkCallRouting("workflow", n.Workflow.Name, n.Options, callingWorkflow, n.Line, n.Column)
+			v.checkCallRouting("workflow", n.Workflow.Name, options.Resolve(n.Workflow.Resolved, n, nil), callingWorkflow, n.Line, n.Column)
 		case *ast.NexusCall:
 			v.checkEndpointServiceLinkage(n.Endpoint.Name, n.Service.Name, n.Line, n.Column)
 		default:
@@ -328,13 +426,14 @@ func (v *validationCtx) walkAsyncTarget(target ast.AsyncTarget, line, column int
 }
 
 // checkCallRouting validates that an activity or workflow call can reach its target
-// via task queue routing.
-func (v *validationCtx) checkCallRouting(kind, targetName string, opts *ast.OptionsBlock, callingWorkflow string, line, column int) {
+// via task queue routing. A task_queue in the call's or the target's options
+// routes it explicitly.
+func (v *validationCtx) checkCallRouting(kind, targetName string, opts options.Effective, callingWorkflow string, line, column int) {
 	if len(v.namespaces) == 0 {
 		return
 	}
 
-	explicitTQ := extractTaskQueue(opts)
+	explicitTQ := opts.Get("task_queue")
 
 	if explicitTQ != "" {
 		if v.typeOnQueue(kind, targetName, explicitTQ) {
@@ -405,7 +504,7 @@ func (v *validationCtx) typeOnQueue(kind, name, taskQueue string) bool {
 }
 
 // taskQueuesForType returns all task queues that a given workflow or activity
-// is instantiated on across all namespaces.
+// is instantiated on across all namespaces, sorted.
 func (v *validationCtx) taskQueuesForType(kind, name string) []string {
 	seen := make(map[string]bool)
 	var queues []string
@@ -441,6 +540,7 @@ func (v *validationCtx) taskQueuesForType(kind, name string) []string {
 			}
 		}
 	}
+	slices.Sort(queues)
 	return queues
 }
 
@@ -510,7 +610,7 @@ func sameStringSet(a, b map[string]bool) bool {
 
 // checkUncovered reports a warning for each definition in defs that is not
 // present in the covered set.
-func checkUncovered[T ast.Node](defs map[string]T, covered map[string]bool, msgFmt string, kind ErrorKind, errs *[]*Error) {
+func checkUncovered[T ast.Node](defs iter.Seq2[string, T], covered map[string]bool, msgFmt string, kind ErrorKind, errs *[]*Error) {
 	for name, node := range defs {
 		if !covered[name] {
 			*errs = append(*errs, &Error{
@@ -1,10 +1,15 @@
 package validator
 
 import (
+	"context"
+	"fmt"
+	"slices"
 	"strings"
 	"testing"
 
 	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/lexer"
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/options"
 	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/parser"
 	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/resolver"
 )
@@ -425,3 +430,1133 @@ namespace ns:
 		t.Error("expected error about endpoint-service linkage")
 	}
 }
+
+// ===== CONDITION TESTS =====
+
+func TestConstantIfCondition(t *testing.T) {
+	input := `workflow W(x: int) -> (int):
+    if (1 > 2):
+        activity A(x)
+
+activity A(x: int):
+    log(x)
+`
+	file := mustParseAndResolve(t, input)
+	errs := Validate(file)
+	if !hasWarning(errs, "if condition (1 > 2) is always false") {
+		t.Errorf("expected constant condition warning, got %v", errs)
+	}
+}
+
+func TestConstantForCondition(t *testing.T) {
+	input := `workflow W(x: int) -> (int):
+    for (true):
+        activity A(x)
+
+activity A(x: int):
+    log(x)
+`
+	file := mustParseAndResolve(t, input)
+	errs := Validate(file)
+	if !hasWarning(errs, "use for: for an intentional infinite loop") {
+		t.Errorf("expected constant loop warning, got %v", errs)
+	}
+}
+
+func TestLoopConditionNeverChanges(t *testing.T) {
+	input := `workflow W(x: int) -> (int):
+    retries = 0
+    for (retries < 3):
+        activity A(x)
+
+activity A(x: int):
+    log(x)
+`
+	file := mustParseAndResolve(t, input)
+	errs := Validate(file)
+	if !hasWarning(errs, "loop condition (retries < 3) never changes") {
+		t.Errorf("expected non-terminating loop warning, got %v", errs)
+	}
+}
+
+func TestLoopConditionChanges(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"raw increment", "        retries = retries + 1\n"},
+		{"call result", "        activity A(x) -> retries\n"},
+		{"break", "        activity A(x)\n        break\n"},
+		{"close", "        close complete\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := "workflow W(x: int) -> (int):\n    for (retries < 3):\n" + tt.body + `
+activity A(x: int):
+    log(x)
+`
+			file := mustParseAndResolve(t, input)
+			errs := Validate(file)
+			if hasWarning(errs, "never changes") {
+				t.Errorf("unexpected non-terminating loop warning: %v", errs)
+			}
+		})
+	}
+}
+
+func TestLoopConditionNestedBreaks(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want bool
+	}{
+		{"unlabeled nested break", "        for (item in items):\n            break\n", true},
+		{"labeled break to outer", "        for (item in items):\n            break outer\n", false},
+		{"labeled break to nested", "        inner: for (item in items):\n            break inner\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := "workflow W(x: int) -> (int):\n    outer: for (retries < 3):\n" + tt.body
+			file := mustParseAndResolve(t, input)
+			errs := Validate(file)
+			if got := hasWarning(errs, "never changes"); got != tt.want {
+				t.Errorf("expected warning=%t, got %t: %v", tt.want, got, errs)
+			}
+		})
+	}
+}
+
+func TestLoopConditionChangedByHandler(t *testing.T) {
+	input := `workflow W(x: int) -> (int):
+    signal Approve():
+        approved = true
+    for (not approved):
+        activity A(x)
+
+activity A(x: int):
+    log(x)
+`
+	file := mustParseAndResolve(t, input)
+	errs := Validate(file)
+	if hasWarning(errs, "never changes") {
+		t.Errorf("unexpected non-terminating loop warning: %v", errs)
+	}
+}
+
+// ===== SWITCH TESTS =====
+
+func findKind(errs []*Error, kind ErrorKind) *Error {
+	for _, e := range errs {
+		if e.Kind == kind {
+			return e
+		}
+	}
+	return nil
+}
+
+func TestDuplicateSwitchCase(t *testing.T) {
+	input := `workflow W(x: int) -> (int):
+    switch (x):
+        case "a":
+            activity A(x)
+        case "b":
+            activity A(x)
+        case "a":
+            activity A(x)
+
+activity A(x: int):
+    log(x)
+`
+	file := mustParseAndResolve(t, input)
+	e := findKind(Validate(file), ErrDuplicateSwitchCase)
+	if e == nil {
+		t.Fatal("expected duplicate case error")
+	}
+	if e.Line != 7 || e.Severity != "" {
+		t.Errorf("expected error at line 7, got line %d severity %q", e.Line, e.Severity)
+	}
+	if len(e.Related) != 1 || e.Related[0].Line != 3 {
+		t.Errorf("expected related info at line 3, got %+v", e.Related)
+	}
+}
+
+func TestDuplicateSwitchCaseFoldsConstants(t *testing.T) {
+	input := `workflow W(x: int) -> (int):
+    switch (x):
+        case 60s:
+            activity A(x)
+        case 1m:
+            activity A(x)
+
+activity A(x: int):
+    log(x)
+`
+	file := mustParseAndResolve(t, input)
+	if findKind(Validate(file), ErrDuplicateSwitchCase) == nil {
+		t.Error("expected 60s and 1m to be reported as duplicates")
+	}
+}
+
+func TestDistinctSwitchCases(t *testing.T) {
+	input := `workflow W(x: int) -> (int):
+    switch (x):
+        case "1":
+            activity A(x)
+        case 1:
+            activity A(x)
+        case status.open:
+            activity A(x)
+        case status.closed:
+            activity A(x)
+
+activity A(x: int):
+    log(x)
+`
+	file := mustParseAndResolve(t, input)
+	errs := Validate(file)
+	if e := findKind(errs, ErrDuplicateSwitchCase); e != nil {
+		t.Errorf("unexpected duplicate case error: %s", e.Msg)
+	}
+	if e := findKind(errs, ErrConstantSwitch); e != nil {
+		t.Errorf("unexpected constant switch warning: %s", e.Msg)
+	}
+}
+
+func TestEnumSwitchExhaustiveness(t *testing.T) {
+	input := `enum OrderType: invoice, refund, subscription
+
+workflow W(kind: OrderType, note: string):
+    state:
+        current: OrderType = invoice
+
+    signal Reclassify(kind: string):
+        switch (kind):
+            case "other":
+                return
+
+    switch (kind):
+        case invoice:
+            close complete
+    switch (current):
+        case OrderType.invoice:
+            activity A(note)
+        case "refund":
+            activity A(note)
+    switch (kind):
+        case invoice:
+            activity A(note)
+        else:
+            activity A(note)
+    switch (note):
+        case "x":
+            activity A(note)
+    close complete
+
+activity A(note: string):
+    log(note)
+`
+	file := mustParseAndResolve(t, input)
+	var got []string
+	for _, e := range Validate(file) {
+		if e.Kind != ErrNonExhaustiveSwitch {
+			continue
+		}
+		if e.Severity != "warning" || e.Name != "OrderType" {
+			t.Errorf("expected warning naming OrderType, got %q %q", e.Severity, e.Name)
+		}
+		if len(e.Related) != 1 || e.Related[0].Line != 1 {
+			t.Errorf("expected related info at the enum, got %+v", e.Related)
+		}
+		got = append(got, fmt.Sprintf("%d: %s", e.Line, e.Msg))
+	}
+	want := []string{
+		"12: switch on kind (OrderType) is missing cases refund, subscription; add them or an else",
+		"15: switch on current (OrderType) is missing case subscription; add it or an else",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestConstantSwitch(t *testing.T) {
+	input := `workflow W(x: int) -> (int):
+    switch ("b"):
+        case "a":
+            activity A(x)
+        case "b":
+            activity A(x)
+
+activity A(x: int):
+    log(x)
+`
+	file := mustParseAndResolve(t, input)
+	e := findKind(Validate(file), ErrConstantSwitch)
+	if e == nil {
+		t.Fatal("expected constant switch warning")
+	}
+	if !strings.Contains(e.Msg, `only case "b" can match`) {
+		t.Errorf("unexpected message: %s", e.Msg)
+	}
+	if len(e.Related) != 1 || e.Related[0].Line != 5 {
+		t.Errorf("expected related info at line 5, got %+v", e.Related)
+	}
+}
+
+func TestConstantSwitchNoMatch(t *testing.T) {
+	input := `workflow W(x: int) -> (int):
+    switch (3):
+        case 1:
+            activity A(x)
+        else:
+            activity A(x)
+
+activity A(x: int):
+    log(x)
+`
+	file := mustParseAndResolve(t, input)
+	if !hasWarning(Validate(file), "matches no case; only the else branch runs") {
+		t.Error("expected no-match constant switch warning")
+	}
+}
+
+// ===== WORKFLOW ID TESTS =====
+
+func TestWorkflowIDPlaceholders(t *testing.T) {
+	input := `const region = "eu"
+
+workflow Order(order: Order):
+    activity Charge(order) -> payment
+    workflow Ship(order) id "ship-{order.id}-{payment.id}-{region}"
+    workflow Ship(order) id "ship-{ customer.id }"
+    workflow Ship(order) id "ship-{}"
+    detach workflow Ship(order) id "ship-{order.id"
+
+workflow Ship(order: Order):
+    return
+
+activity Charge(order: Order):
+    return
+`
+	file := mustParseAndResolve(t, input)
+	var got []string
+	for _, e := range Validate(file) {
+		if e.Kind == ErrWorkflowIDPlaceholder {
+			got = append(got, e.Msg)
+		}
+	}
+	want := []string{
+		"workflow ID placeholder {customer.id} references customer, which is not bound in this workflow",
+		`workflow ID "ship-{}" has an empty {} placeholder`,
+		`workflow ID "ship-{order.id" has an unterminated {placeholder}`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got warnings:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestParallelLoopConcurrency(t *testing.T) {
+	input := `workflow Order(order: Order):
+    for each (item in order.items) parallel(max: 10):
+        activity Reserve(item)
+    for each (item in order.items) parallel(max: 0):
+        activity Reserve(item)
+    for each (item in order.items) parallel(max: many):
+        activity Reserve(item)
+
+activity Reserve(item: Item):
+    return
+`
+	file := mustParseAndResolve(t, input)
+	var lines []int
+	for _, e := range Validate(file) {
+		if e.Kind == ErrInvalidConcurrency {
+			lines = append(lines, e.Line)
+		}
+	}
+	if len(lines) != 2 || lines[0] != 4 || lines[1] != 6 {
+		t.Errorf("expected concurrency errors on lines 4 and 6, got %v", lines)
+	}
+	if !hasError(Validate(file), "parallel max must be a positive integer, got many") {
+		t.Error("expected the message to name the invalid limit")
+	}
+}
+
+func TestUnreachableStatements(t *testing.T) {
+	input := `workflow Order(order: Order):
+    signal Approve():
+        return
+    await one:
+        signal Approve:
+            close complete
+        timer (24h):
+            close fail
+    activity Ship(order)
+
+workflow Refund(order: Order):
+    if (order.paid):
+        return
+    else:
+        close fail
+    activity Ship(order)
+
+workflow Retry(order: Order):
+    for:
+        activity Ship(order)
+        break
+        activity Ship(order)
+    activity Ship(order)
+    if (order.paid):
+        close complete
+    activity Ship(order)
+
+activity Ship(order: Order):
+    return
+`
+	file := mustParseAndResolve(t, input)
+	var unreachable []*Error
+	for _, e := range Validate(file) {
+		if e.Kind == ErrUnreachable {
+			unreachable = append(unreachable, e)
+		}
+	}
+	slices.SortFunc(unreachable, func(a, b *Error) int { return a.Line - b.Line })
+	var got []string
+	for _, e := range unreachable {
+		got = append(got, fmt.Sprintf("%d: %s", e.Line, e.Msg))
+	}
+	want := []string{
+		"9: unreachable: all branches above terminate",
+		"16: unreachable: all branches above terminate",
+		"22: unreachable: the break on line 21 ends this path",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got warnings:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if len(unreachable) > 0 && (len(unreachable[0].Related) != 1 || unreachable[0].Related[0].Line != 4) {
+		t.Errorf("expected the warning to point at the await one block, got %+v", unreachable[0].Related)
+	}
+}
+
+func TestRemovedHints(t *testing.T) {
+	input := `workflow Order(order: Order):
+    signal Approve():
+        approved = true
+    update Cancel() -> (bool):
+        hint signal Approve
+        return true
+    hints signal Approve,Override , Escalate
+    hint update Cancel
+    hints = 3
+    close complete
+`
+	file := mustParseAndResolve(t, input)
+	var got []string
+	for _, e := range Validate(file) {
+		if e.Kind == ErrRemovedHint {
+			got = append(got, fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Msg))
+		}
+	}
+	slices.Sort(got)
+	want := []string{
+		"5:9: hint was removed from the language, so this line has no effect; wait for signal Approve with await one, a case for each",
+		"7:26: workflow Order declares no signal Override",
+		"7:37: workflow Order declares no signal Escalate",
+		"7:5: hint was removed from the language, so this line has no effect; wait for signal Approve or Override or Escalate with await one, a case for each",
+		"8:5: hint was removed from the language, so this line has no effect; wait for update Cancel with await one, a case for each",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got warnings:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCloseValues(t *testing.T) {
+	input := `workflow Order(order: Order) -> (OrderResult):
+    signal Cancel():
+        close fail(OrderResult{status: "cancelled"})
+    if (order.paid):
+        close complete(OrderResult{status: "paid"})
+    if (order.empty):
+        close complete({status: "empty"})
+    if (order.held):
+        close complete("held")
+    if (order.lost):
+        close fail(OrderError{status: "lost"})
+    if (order.late):
+        close fail("late")
+    close complete(order.result)
+
+workflow Count() -> (int):
+    close complete(3)
+
+workflow Ping():
+    close complete(true)
+
+workflow Pair() -> (string, int):
+    close complete("a", "b")
+`
+	file := mustParseAndResolve(t, input)
+	var got []string
+	for _, e := range Validate(file) {
+		if e.Kind == ErrCloseValueMismatch {
+			got = append(got, fmt.Sprintf("%d:%d: %s [%s]", e.Line, e.Column, e.Msg, e.Suggestion))
+		}
+	}
+	slices.Sort(got)
+	want := []string{
+		`20:5: close complete passes a value, but workflow Ping declares no return type []`,
+		`23:25: close complete passes a string, but workflow Pair returns int []`,
+		`3:20: close fail passes a typed literal OrderResult; workflow Order should fail with an error or a message string []`,
+		`7:24: close complete passes an untyped map literal, but workflow Order returns OrderResult [OrderResult{status: "empty"}]`,
+		`9:24: close complete passes a string, but workflow Order returns OrderResult [OrderResult{value: "held"}]`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got warnings:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// ===== POLICY TESTS =====
+
+const policyInput = `@owner("payments") @tag(critical)
+workflow Charge():
+    activity Bill()
+
+@tag(critical)
+@sla(1h)
+workflow Refund():
+    activity Bill()
+
+workflow Order():
+    workflow Charge()
+    workflow Refund()
+        options:
+            workflow_run_timeout: 2h
+    await workflow Charge()
+
+activity Bill():
+    return
+`
+
+func TestPolicyDisabled(t *testing.T) {
+	file := mustParseAndResolve(t, policyInput)
+	if errs := CheckPolicy(resolver.CollectSymbols(file), Policy{}); len(errs) != 0 {
+		t.Errorf("expected no errors from the zero policy, got %v", errs)
+	}
+}
+
+func TestPolicyRequireOwner(t *testing.T) {
+	file := mustParseAndResolve(t, policyInput)
+	errs := CheckPolicy(resolver.CollectSymbols(file), Policy{RequireOwner: true})
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %v", errs)
+	}
+	if errs[0].Kind != ErrMissingOwner || errs[0].Name != "Order" || errs[0].Line != 10 {
+		t.Errorf("unexpected first error: %+v", errs[0])
+	}
+	if errs[1].Name != "Refund" || errs[1].Line != 7 || errs[1].Column != 1 {
+		t.Errorf("expected the error at Refund's header, got %+v", errs[1])
+	}
+}
+
+func TestPolicyCritical(t *testing.T) {
+	file := mustParseAndResolve(t, policyInput)
+	errs := CheckPolicy(resolver.CollectSymbols(file), Policy{CriticalTag: "critical"})
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %v", errs)
+	}
+	if !hasError(errs, "workflow Charge is tagged critical but has no @sla annotation") || errs[0].Line != 2 {
+		t.Errorf("expected a missing @sla error at Charge's header, got %+v", errs[0])
+	}
+	timeout := findKind(errs, ErrMissingTimeout)
+	if timeout == nil || timeout.Line != 11 || timeout.Name != "Charge" {
+		t.Fatalf("expected a missing timeout error at the call on line 11, got %v", errs)
+	}
+	if len(timeout.Related) != 1 || timeout.Related[0].Line != 2 {
+		t.Errorf("expected the related location at Charge's header, got %+v", timeout.Related)
+	}
+
+	defaults, err := options.ParseConfig([]byte(`{"workflow": {"workflow_run_timeout": "1h"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs = CheckPolicy(resolver.CollectSymbols(file), Policy{CriticalTag: "critical", OptionDefaults: defaults})
+	if findKind(errs, ErrMissingTimeout) != nil {
+		t.Errorf("expected the option defaults to supply the timeout, got %v", errs)
+	}
+}
+
+func TestPolicyRequireTimeout(t *testing.T) {
+	file := mustParseAndResolve(t, policyInput)
+	errs := CheckPolicy(resolver.CollectSymbols(file), Policy{RequireTimeout: true})
+	var names []string
+	for _, e := range errs {
+		if e.Kind != ErrNoTimeoutPath || e.Severity != "warning" {
+			t.Errorf("unexpected error: %+v", e)
+		}
+		names = append(names, e.Name)
+	}
+	if strings.Join(names, ",") != "Charge,Order" {
+		t.Errorf("expected warnings for Charge and Order, got %v", names)
+	}
+}
+
+func TestPolicyActivityTimeout(t *testing.T) {
+	file := mustParseAndResolve(t, `activity Charge():
+    return
+
+activity Ship():
+    options:
+        schedule_to_close_timeout: 1h
+    return
+
+workflow Order():
+    activity Charge()
+    activity Charge()
+        options:
+            start_to_close_timeout: 30s
+    activity Ship()
+    await activity Charge()
+    close complete
+`)
+	symbols := resolver.CollectSymbols(file)
+	errs := CheckPolicy(symbols, Policy{RequireActivityTimeout: true})
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 warnings, got %v", errs)
+	}
+	call, await := errs[0], errs[1]
+	if call.Kind != ErrMissingActivityTimeout || call.Severity != "warning" || call.Line != 10 || call.Name != "Charge" {
+		t.Errorf("unexpected warning at the call: %+v", call)
+	}
+	if !strings.Contains(call.Msg, "set one in the call's options, activity Charge's options, or the option defaults") {
+		t.Errorf("expected every layer named, got %q", call.Msg)
+	}
+	if len(call.Related) != 1 || call.Related[0].Line != 1 {
+		t.Errorf("expected the related location at Charge's header, got %+v", call.Related)
+	}
+	if await.Line != 15 || !strings.Contains(await.Msg, "set one in activity Charge's options or the option defaults") {
+		t.Errorf("expected the await target to name only the definition and defaults, got %+v", await)
+	}
+
+	defaults, err := options.ParseConfig([]byte(`{"activity": {"start_to_close_timeout": "1m"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := CheckPolicy(symbols, Policy{RequireActivityTimeout: true, OptionDefaults: defaults}); len(errs) != 0 {
+		t.Errorf("expected the option defaults to supply the timeout, got %v", errs)
+	}
+
+	// A block that does not parse might set the timeout.
+	file, _ = parser.ParseFileAll(`activity Charge():
+    return
+
+activity Ship():
+    options:
+        start_to_close_timeout = 1m
+    return
+
+workflow Order():
+    activity Charge()
+        options:
+            start_to_close_timeout = 30s
+    activity Ship()
+    close complete
+`)
+	resolver.Resolve(file)
+	if errs := CheckPolicy(resolver.CollectSymbols(file), Policy{RequireActivityTimeout: true}); len(errs) != 0 {
+		t.Errorf("expected no warnings for blocks that do not parse, got %v", errs)
+	}
+}
+
+func TestPolicyUnknownNames(t *testing.T) {
+	file := mustParseAndResolve(t, `const LIMIT = 3
+
+workflow Order(orderId: string):
+    state:
+        status: string = "new"
+        retries = 0
+
+    signal Cancel(reason: string):
+        statu = "cancelled"
+        note = reason
+
+    activity Charge(orderId) -> receipt
+    if (receipt.ok and retrys < LIMIT):
+        status = "paid"
+    total = amount + fee
+    if (note != nil):
+        close fail
+
+activity Charge(orderId: string) -> (Receipt):
+    return
+`)
+	symbols := resolver.CollectSymbols(file)
+
+	typos := CheckPolicy(symbols, Policy{UnknownNames: UnknownNamesTypos})
+	var got []string
+	for _, e := range typos {
+		if e.Kind != ErrUnknownName || e.Severity != "warning" {
+			t.Errorf("unexpected error: %+v", e)
+		}
+		got = append(got, fmt.Sprintf("%d:%d %s->%s", e.Line, e.Column, e.Name, e.Suggestion))
+	}
+	// note is assigned in the Cancel handler, so reading it is fine; total,
+	// amount, and fee resemble nothing declared.
+	if want := "9:9 statu->status,13:24 retrys->retries"; strings.Join(got, ",") != want {
+		t.Errorf("expected %s, got %v", want, got)
+	}
+	if !hasWarning(typos, "unknown name statu in workflow Order; did you mean status?") {
+		t.Errorf("unexpected messages: %v", typos)
+	}
+
+	all := CheckPolicy(symbols, Policy{UnknownNames: UnknownNamesAll})
+	got = nil
+	for _, e := range all {
+		got = append(got, e.Name)
+	}
+	if want := "statu,note,retrys,total,amount,fee"; strings.Join(got, ",") != want {
+		t.Errorf("expected %s, got %v", want, got)
+	}
+	if fee := all[len(all)-1]; fee.Line != 15 || fee.Column != 22 || fee.Suggestion != "" {
+		t.Errorf("expected fee at 15:22 with no suggestion, got %+v", fee)
+	}
+}
+
+func TestPolicyReturnInWorkflow(t *testing.T) {
+	file := mustParseAndResolve(t, `workflow Order(order: Order) -> (Result):
+    update Rename(name: string) -> (string):
+        return name
+    if (order.cancelled):
+        return
+    return result
+
+activity Charge(order: Order):
+    return
+`)
+	symbols := resolver.CollectSymbols(file)
+
+	for _, mode := range []string{"", ReturnInWorkflowOff} {
+		if errs := CheckPolicy(symbols, Policy{ReturnInWorkflow: mode}); len(errs) != 0 {
+			t.Errorf("mode %q: expected no errors, got %v", mode, errs)
+		}
+	}
+	for _, mode := range []string{ReturnInWorkflowWarning, ReturnInWorkflowError} {
+		var got []string
+		for _, e := range CheckPolicy(symbols, Policy{ReturnInWorkflow: mode}) {
+			if e.Kind != ErrDeprecatedReturn || e.Severity != mode {
+				t.Errorf("mode %q: unexpected error: %+v", mode, e)
+			}
+			got = append(got, fmt.Sprintf("%d:%d %s", e.Line, e.Column, e.Msg))
+		}
+		want := []string{
+			"5:9 return in workflow Order is deprecated; use close complete",
+			"6:5 return in workflow Order is deprecated; use close complete(...)",
+		}
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Errorf("mode %q: got\n%s\nwant\n%s", mode, strings.Join(got, "\n"), strings.Join(want, "\n"))
+		}
+	}
+}
+
+func TestPolicyAwaitOneOrder(t *testing.T) {
+	file := mustParseAndResolve(t, `workflow Order(order: Order):
+    signal Cancel():
+        cancelled = true
+    await one:
+        timer(1h):
+            close fail("late")
+        activity Charge(order) -> receipt:
+            close complete
+        signal Cancel:
+            close complete
+        timer(2h):
+            close fail("later")
+
+activity Charge(order: Order) -> (Receipt):
+    return receipt
+`)
+	symbols := resolver.CollectSymbols(file)
+	if errs := CheckPolicy(symbols, Policy{}); len(errs) != 0 {
+		t.Errorf("expected no errors with the rule off, got %v", errs)
+	}
+	for mode, want := range map[string][]string{
+		AwaitOneOrderTimerLast: {
+			"7:9 an activity case of await one comes after a timer case; timer-last orders timer cases last",
+			"11:9 await one has more than one timer case; the shortest always fires first, so one timer bounds the wait",
+		},
+		AwaitOneOrderWatchFirst: {
+			"7:9 an activity case of await one comes after a timer case; watch-first orders signal, update, and condition cases first, then operations, then timers",
+			"11:9 await one has more than one timer case; the shortest always fires first, so one timer bounds the wait",
+		},
+	} {
+		var got []string
+		for _, e := range CheckPolicy(symbols, Policy{AwaitOneOrder: mode}) {
+			if e.Severity != "warning" {
+				t.Errorf("mode %q: unexpected severity: %+v", mode, e)
+			}
+			got = append(got, fmt.Sprintf("%d:%d %s", e.Line, e.Column, e.Msg))
+		}
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Errorf("mode %q: got\n%s\nwant\n%s", mode, strings.Join(got, "\n"), strings.Join(want, "\n"))
+		}
+	}
+
+	ordered := mustParseAndResolve(t, `workflow Order(order: Order):
+    signal Cancel():
+        cancelled = true
+    await one:
+        activity Charge(order) -> receipt:
+            close complete
+        signal Cancel:
+            close complete
+        timer(1h):
+            close fail("late")
+
+activity Charge(order: Order) -> (Receipt):
+    return receipt
+`)
+	symbols = resolver.CollectSymbols(ordered)
+	if errs := CheckPolicy(symbols, Policy{AwaitOneOrder: AwaitOneOrderTimerLast}); len(errs) != 0 {
+		t.Errorf("timer-last: expected no errors, got %v", errs)
+	}
+	var got []string
+	for _, e := range CheckPolicy(symbols, Policy{AwaitOneOrder: AwaitOneOrderWatchFirst}) {
+		got = append(got, fmt.Sprintf("%d:%d %s", e.Line, e.Column, e.Msg))
+	}
+	if want := "7:9 a signal case of await one comes after an activity case; watch-first orders signal, update, and condition cases first, then operations, then timers"; strings.Join(got, "\n") != want {
+		t.Errorf("watch-first: got %q, want %q", got, want)
+	}
+}
+
+func TestCheckKeywordCase(t *testing.T) {
+	src := "Workflow Order():\n    If (ready):\n        close complete\n"
+	if _, err := parser.WithLexer(lexer.Options{CaseInsensitiveKeywords: true}).ParseFile(src); err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	var got []string
+	for _, e := range CheckKeywordCase(src) {
+		if e.Kind != ErrKeywordCase || e.Severity != "warning" || e.Suggestion != strings.ToLower(e.Name) {
+			t.Errorf("unexpected error: %+v", e)
+		}
+		got = append(got, fmt.Sprintf("%d:%d %s", e.Line, e.Column, e.Msg))
+	}
+	want := []string{
+		"1:1 keyword Workflow should be written workflow",
+		"2:5 keyword If should be written if",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCheckDialect(t *testing.T) {
+	legacy := `workflow Order(order: Order) -> (Result):
+    update Rename(name: string) -> (string):
+        return name
+    if (order.cancelled):
+        return
+    return result
+`
+	v2 := `workflow Order(order: Order) -> (Result):
+    signal Cancel():
+        close fail("cancelled")
+    close complete(result)
+`
+	mixed := `workflow Order(order: Order) -> (Result):
+    if (order.cancelled):
+        close fail("cancelled")
+    return result
+`
+	tests := []struct {
+		name, src, dialect string
+		want               []string
+	}{
+		{"legacy as legacy", legacy, DialectLegacy, nil},
+		{"legacy as v2", legacy, DialectV2, []string{
+			"5:9 return in a workflow body is not part of the v2 dialect; use close complete",
+			"6:5 return in a workflow body is not part of the v2 dialect; use close complete(...)",
+		}},
+		{"legacy as auto", legacy, DialectAuto, nil},
+		{"v2 as legacy", v2, DialectLegacy, []string{
+			"4:5 close is not part of the legacy dialect; end the workflow with return",
+			"3:9 close is not part of the legacy dialect; end the workflow with return",
+		}},
+		{"v2 as auto", v2, DialectAuto, nil},
+		{"mixed as auto", mixed, DialectAuto, []string{
+			"4:5 file mixes dialects: return ends a workflow the legacy way and close the v2 way; use one, or name it with a '# twf:dialect legacy|v2' comment",
+		}},
+		{"mixed with pragma", "# twf:dialect v2\n" + mixed, DialectAuto, []string{
+			"5:5 return in a workflow body is not part of the v2 dialect; use close complete(...)",
+		}},
+		{"unknown pragma", "# twf:dialect v3\n" + v2, DialectAuto, []string{
+			`1:1 unknown dialect "v3"; want legacy or v2`,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := mustParseAndResolve(t, tt.src)
+			var got []string
+			for _, e := range CheckDialect(file.Definitions, tt.src, tt.dialect) {
+				if e.Kind != ErrDialectMismatch {
+					t.Errorf("unexpected kind: %+v", e)
+				}
+				got = append(got, fmt.Sprintf("%d:%d %s", e.Line, e.Column, e.Msg))
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestTimeoutPaths(t *testing.T) {
+	file := mustParseAndResolve(t, `workflow Approval():
+    signal Approve():
+        return
+    for:
+        await one:
+            signal Approve:
+                close complete
+            timer (1h):
+                continue
+            timer (24h):
+                if (late):
+                    close fail
+    await one:
+        timer (7d):
+            close complete
+
+workflow Order():
+    workflow Approval()
+        options:
+            workflow_execution_timeout: 72h
+            workflow_run_timeout: 30h
+    workflow Approval()
+`)
+	symbols := resolver.CollectSymbols(file)
+	paths := TimeoutPaths(symbols, symbols.Workflows["Approval"], nil)
+	want := "times out after 24h via await one at line 5; execution timeout 72h; run timeout 30h"
+	if got := TimeoutSummary(paths); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if paths[1].Line != 18 {
+		t.Errorf("expected the execution timeout at the call, got line %d", paths[1].Line)
+	}
+	if got := TimeoutPaths(symbols, symbols.Workflows["Order"], nil); len(got) != 0 {
+		t.Errorf("expected no timeout paths for Order, got %+v", got)
+	}
+
+	file = mustParseAndResolve(t, `workflow Batch():
+    options:
+        workflow_execution_timeout: 12h
+    close complete
+`)
+	symbols = resolver.CollectSymbols(file)
+	defaults, err := options.ParseConfig([]byte(`{"workflow": {"workflow_run_timeout": "1h"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths = TimeoutPaths(symbols, symbols.Workflows["Batch"], defaults)
+	if got := TimeoutSummary(paths); got != "run timeout 1h; execution timeout 12h" {
+		t.Errorf("unexpected summary %q", got)
+	}
+	if paths[0].Line != 0 || paths[1].Line != 3 {
+		t.Errorf("expected the defaults without a line and the workflow's option at line 3, got %+v", paths)
+	}
+}
+
+// ===== MULTI-FILE TESTS =====
+
+func TestValidateDefinitions(t *testing.T) {
+	ext, _ := parser.ParseFile(policyInput)
+	resolver.ResolveFile(ext)
+	for _, def := range ext.Definitions {
+		if wf, ok := def.(*ast.WorkflowDef); ok {
+			wf.SourceFile = "policy.twf"
+		}
+	}
+
+	file, err := parser.ParseFile(`workflow Audit():
+    workflow Charge()
+
+workflow Idle():
+    # nothing here
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := resolver.ResolveWith(context.Background(), file, ext.Definitions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Errors) != 0 {
+		t.Fatalf("unexpected resolve errors: %v", r.Errors)
+	}
+
+	errs := ValidateDefinitions(r.Symbols, file.Definitions)
+	if len(errs) != 1 || !hasWarning(errs, "workflow Idle has an empty body") {
+		t.Errorf("expected only Idle's empty body warning, got %v", errs)
+	}
+
+	errs = CheckPolicyDefinitions(r.Symbols, Policy{RequireOwner: true, CriticalTag: "critical"}, file.Definitions)
+	if len(errs) != 3 {
+		t.Fatalf("expected 3 errors, got %v", errs)
+	}
+	timeout := findKind(errs, ErrMissingTimeout)
+	if timeout == nil || timeout.Line != 2 {
+		t.Fatalf("expected a missing timeout error at the call on line 2, got %v", errs)
+	}
+	if len(timeout.Related) != 1 || timeout.Related[0].File != "policy.twf" || timeout.Related[0].Line != 2 {
+		t.Errorf("expected the related location at Charge's header in policy.twf, got %+v", timeout.Related)
+	}
+}
+
+func TestSignalDelivery(t *testing.T) {
+	input := `workflow Order(order: Order):
+    state:
+        condition approved
+        condition shipped
+        condition packed
+        condition paid
+    signal Ping():
+        pinged = true
+    signal Approve():
+        set approved
+    signal Note(text: string):
+        note = text
+    signal Cancel():
+        # not supported yet
+    signal Nudge():
+        nudged = true
+    update Pay():
+        set paid
+    query Status() -> (string):
+        return note
+    await signal Nudge
+    await approved
+    await shipped
+    set shipped
+    await all:
+        activity Pack(order)
+        set packed
+    await packed
+    await paid
+    close complete
+
+activity Pack(order: Order):
+    return
+`
+	file := mustParseAndResolve(t, input)
+	var got []string
+	for _, e := range Validate(file) {
+		if e.Kind != ErrDeadHandler && e.Kind != ErrUnsetCondition {
+			continue
+		}
+		line := fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Msg)
+		for _, r := range e.Related {
+			line += fmt.Sprintf(" [%d:%d: %s]", r.Line, r.Column, r.Msg)
+		}
+		got = append(got, line)
+	}
+	want := []string{
+		`7:5: signal Ping of workflow Order is never awaited and nothing reads what its handler sets; delivering it has no effect [8:9: pinged is set here but never read]`,
+		`13:5: signal Cancel of workflow Order is never awaited and its handler is empty; delivering it has no effect`,
+		`23:5: await shipped in workflow Order never ends: no signal or update handler sets shipped, and the workflow sets it only after waiting [4:9: condition shipped is declared here] [24:5: shipped is set here, after the wait]`,
+	}
+	slices.Sort(got)
+	slices.Sort(want)
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestPIIFlows(t *testing.T) {
+	input := `nexus service Mail:
+    async Send workflow Mailer
+
+workflow Order(@pii email: string, id: string):
+    state:
+        @pii address: string = ""
+    signal Move(@pii to: string):
+        address = to
+    contact = email
+    log("order " + id)
+    log("shipping to " + address)
+    activity Lookup(contact) -> profile
+    for (line in profile.lines):
+        print(line)
+    detach workflow Audit(id)
+    detach workflow Notify(email)
+    detach workflow Archive(profile)
+    detach nexus Ep Mail.Send(contact)
+    close complete
+
+workflow Audit(id: string):
+    close complete
+
+workflow Notify(email: string):
+    close complete
+
+workflow Archive(profile: Profile):
+    close complete
+
+workflow Mailer(to: string):
+    close complete
+
+activity Lookup(contact: string) -> (Profile):
+    return db.find(contact)
+
+worker orders:
+    workflow Order
+    workflow Notify
+    activity Lookup
+
+worker records:
+    workflow Audit
+    workflow Archive
+
+worker mail:
+    workflow Mailer
+    nexus service Mail
+
+namespace shop:
+    worker orders
+        options:
+            task_queue: "orders"
+
+namespace compliance:
+    worker records
+        options:
+            task_queue: "records"
+
+namespace messaging:
+    worker mail
+        options:
+            task_queue: "mail"
+    nexus endpoint Ep
+        options:
+            task_queue: "mail"
+`
+	file := mustParseAndResolve(t, input)
+	var got []string
+	for _, e := range Validate(file) {
+		if e.Kind != ErrPIIFlow {
+			continue
+		}
+		if e.Severity != "warning" {
+			t.Errorf("%s: severity %q, want warning", e.Msg, e.Severity)
+		}
+		line := fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Msg)
+		for _, r := range e.Related {
+			line += fmt.Sprintf(" [%d:%d: %s]", r.Line, r.Column, r.Msg)
+		}
+		got = append(got, line)
+	}
+	want := []string{
+		`11:5: PII value address is written to a log in workflow Order [6:9: the value of address comes from a @pii declaration here]`,
+		`14:9: PII value line is written to a log in workflow Order [4:1: the value of line comes from a @pii declaration here]`,
+		`17:5: PII value profile is passed to detach workflow Archive in namespace compliance, outside shop where workflow Order runs [4:1: the value of profile comes from a @pii declaration here]`,
+		`18:5: PII value contact is passed through nexus endpoint Ep by a detach call in workflow Order [4:1: the value of contact comes from a @pii declaration here]`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
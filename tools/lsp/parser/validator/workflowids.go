@@ -0,0 +1,113 @@
+package validator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmbarzee/temporal-skills/tools/lsp/parser/ast"
+)
+
+// checkWorkflowIDs warns about workflow ID templates on child workflow calls
+// whose {expr} placeholders are malformed or start from a name the calling
+// workflow never binds.
+func (v *validationCtx) checkWorkflowIDs() {
+	for _, wf := range owned(v.own, v.workflows) {
+		known := v.boundNames(wf)
+		check := func(stmts []ast.Statement) {
+			ast.WalkStatements(stmts, func(s ast.Statement) bool {
+				if call, ok := s.(*ast.WorkflowCall); ok && call.ID != "" {
+					v.checkWorkflowID(call, known)
+				}
+				return true
+			})
+		}
+		check(wf.Body)
+		for _, s := range wf.Signals {
+			check(s.Body)
+		}
+		for _, u := range wf.Updates {
+			check(u.Body)
+		}
+	}
+}
+
+// boundNames collects the names visible to expressions in wf: its
+// parameters, state, everything it writes or mentions in raw code, and the
+// file's constants.
+func (v *validationCtx) boundNames(wf *ast.WorkflowDef) map[string]bool {
+	names := mutatedNames(wf)
+	addWords(names, wf.Params)
+	addWords(names, rawText(wf.Body))
+	if wf.State != nil {
+		for _, c := range wf.State.Conditions {
+			names[c.Name] = true
+		}
+		for _, raw := range wf.State.RawStmts {
+			addWords(names, raw.Text)
+		}
+	}
+	for name := range v.constants {
+		names[name] = true
+	}
+	return names
+}
+
+func (v *validationCtx) checkWorkflowID(call *ast.WorkflowCall, known map[string]bool) {
+	warn := func(msg, name string) {
+		v.errs = append(v.errs, &Error{
+			Msg:      msg,
+			Line:     call.Line,
+			Column:   call.Column,
+			Severity: "warning",
+			Kind:     ErrWorkflowIDPlaceholder,
+			Name:     name,
+		})
+	}
+	placeholders, ok := idPlaceholders(call.ID)
+	if !ok {
+		warn(fmt.Sprintf("workflow ID %q has an unterminated {placeholder}", call.ID), "")
+		return
+	}
+	for _, expr := range placeholders {
+		if expr == "" {
+			warn(fmt.Sprintf("workflow ID %q has an empty {} placeholder", call.ID), "")
+			continue
+		}
+		root := rootIdent(expr)
+		if root != "" && !known[root] {
+			warn(fmt.Sprintf("workflow ID placeholder {%s} references %s, which is not bound in this workflow", expr, root), root)
+		}
+	}
+}
+
+// idPlaceholders returns the trimmed expressions inside the {...}
+// placeholders of a workflow ID template, and false when a placeholder is
+// not closed.
+func idPlaceholders(id string) ([]string, bool) {
+	var exprs []string
+	for {
+		open := strings.IndexByte(id, '{')
+		if open < 0 {
+			return exprs, true
+		}
+		end := strings.IndexByte(id[open:], '}')
+		if end < 0 {
+			return exprs, false
+		}
+		exprs = append(exprs, strings.TrimSpace(id[open+1:open+end]))
+		id = id[open+end+1:]
+	}
+}
+
+// rootIdent returns the identifier an expression such as order.id or
+// items[0] starts with, or "" when it starts with anything else.
+func rootIdent(expr string) string {
+	words := identWords(expr)
+	if len(words) == 0 || !strings.HasPrefix(expr, words[0]) {
+		return ""
+	}
+	if c := words[0][0]; c >= '0' && c <= '9' {
+		return ""
+	}
+	return words[0]
+}
@@ -5,6 +5,7 @@ import type {
   AwaitAllBlock,
   AwaitOneBlock,
   AwaitOneCase,
+  AsyncTarget,
   WorkflowDef,
 } from '../../types/ast'
 import { DefinitionContext, HandlerContext } from '../WorkflowCanvas'
@@ -15,46 +16,52 @@ import { THEME, AWAIT_TARGET_THEME } from '../../theme/temporal-theme'
 
 // Shared await target display - both getAwaitStmtDisplay and getAwaitOneCaseDisplay delegate here
 function getAwaitTargetDisplay(
-  target: { kind: string; timer?: string; signal?: string; signalParams?: string; update?: string; updateParams?: string; activity?: string; activityArgs?: string; activityResult?: string; workflow?: string; workflowMode?: string; workflowArgs?: string; workflowResult?: string; nexus?: string; nexusService?: string; nexusOperation?: string; nexusArgs?: string; nexusResult?: string; nexusDetach?: boolean; ident?: string; identResult?: string },
+  target: AsyncTarget,
   context: { activities: Map<string, any>; workflows: Map<string, any>; nexusServices: Map<string, any> },
   handlers: { signals: Map<string, any>; updates: Map<string, any> },
-): { icon: string; keyword: string; signature: string; expandableDef?: { body?: Statement[] }; nexusAsyncWorkflow?: WorkflowDef; nexusSyncBody?: Statement[]; isUnresolved: boolean } {
+): { icon: string; keyword: string; signature: string; expandableDef?: { body: Statement[] }; nexusAsyncWorkflow?: WorkflowDef; nexusSyncBody?: Statement[]; isUnresolved: boolean } {
   switch (target.kind) {
-    case 'timer':
-      return { icon: AWAIT_TARGET_THEME.timer.icon, keyword: 'timer', signature: `(${target.timer || ''})`, isUnresolved: false }
+    case 'timer': {
+      const timer = target.timer
+      const result = timer?.result ? ` → ${timer.result}` : ''
+      return { icon: AWAIT_TARGET_THEME.timer.icon, keyword: 'timer', signature: `(${timer?.const || timer?.duration || ''})${result}`, isUnresolved: false }
+    }
     case 'signal': {
-      const sig = target.signal || ''
-      const params = target.signalParams ? ` → ${target.signalParams}` : ''
+      const sig = target.signal?.name || ''
+      const params = target.signal?.params ? ` → ${target.signal.params}` : ''
       const handler = handlers.signals.get(sig)
       return { icon: AWAIT_TARGET_THEME.signal.icon, keyword: 'signal', signature: `${sig}${params}`, expandableDef: handler, isUnresolved: !handler }
     }
     case 'update': {
-      const sig = target.update || ''
-      const params = target.updateParams ? ` → ${target.updateParams}` : ''
+      const sig = target.update?.name || ''
+      const params = target.update?.params ? ` → ${target.update.params}` : ''
       const handler = handlers.updates.get(sig)
       return { icon: AWAIT_TARGET_THEME.update.icon, keyword: 'update', signature: `${sig}${params}`, expandableDef: handler, isUnresolved: !handler }
     }
     case 'activity': {
-      const sig = `${target.activity || ''}(${target.activityArgs || ''})`
-      const result = target.activityResult ? ` → ${target.activityResult}` : ''
-      const def = context.activities.get(target.activity || '')
+      const activity = target.activity
+      const sig = `${activity?.name || ''}(${activity?.args || ''})`
+      const result = activity?.result ? ` → ${activity.result}` : ''
+      const def = context.activities.get(activity?.name || '')
       return { icon: AWAIT_TARGET_THEME.activity.icon, keyword: 'activity', signature: `${sig}${result}`, expandableDef: def, isUnresolved: !def }
     }
     case 'workflow': {
-      const modePrefix = target.workflowMode === 'detach' ? 'detach ' : ''
-      const sig = `${target.workflow || ''}(${target.workflowArgs || ''})`
-      const result = target.workflowResult ? ` → ${target.workflowResult}` : ''
-      const def = context.workflows.get(target.workflow || '')
+      const workflow = target.workflow
+      const modePrefix = workflow?.mode === 'detach' ? 'detach ' : ''
+      const sig = `${workflow?.name || ''}(${workflow?.args || ''})`
+      const result = workflow?.result ? ` → ${workflow.result}` : ''
+      const def = context.workflows.get(workflow?.name || '')
       return { icon: AWAIT_TARGET_THEME.workflow.icon, keyword: `${modePrefix}workflow`, signature: `${sig}${result}`, expandableDef: def, isUnresolved: !def }
     }
     case 'nexus': {
-      const detachPrefix = target.nexusDetach ? 'detach ' : ''
-      const sig = `${target.nexus || ''} ${target.nexusService || ''}.${target.nexusOperation || ''}(${target.nexusArgs || ''})`
-      const result = target.nexusResult ? ` → ${target.nexusResult}` : ''
+      const nexus = target.nexus
+      const detachPrefix = nexus?.detach ? 'detach ' : ''
+      const sig = `${nexus?.endpoint || ''} ${nexus?.service || ''}.${nexus?.operation || ''}(${nexus?.args || ''})`
+      const result = nexus?.result ? ` → ${nexus.result}` : ''
       // Look up service and operation from context
-      const serviceDef = context.nexusServices.get(target.nexusService || '')
-      const operation = serviceDef?.operations?.find((op: any) => op.name === (target.nexusOperation || ''))
-      const isUnresolved = !!(target.nexusService && !serviceDef)
+      const serviceDef = context.nexusServices.get(nexus?.service || '')
+      const operation = serviceDef?.operations.find((op: any) => op.name === (nexus?.operation || ''))
+      const isUnresolved = !!(nexus?.service && !serviceDef)
       if (operation?.opType === 'async' && operation.workflowName) {
         const wf = context.workflows.get(operation.workflowName)
         if (wf) {
@@ -66,8 +73,8 @@ function getAwaitTargetDisplay(
       return { icon: AWAIT_TARGET_THEME.nexus.icon, keyword: `${detachPrefix}nexus`, signature: `${sig}${result}`, isUnresolved }
     }
     case 'ident': {
-      const name = target.ident || ''
-      const result = target.identResult ? ` → ${target.identResult}` : ''
+      const name = target.ident?.name || ''
+      const result = target.ident?.result ? ` → ${target.ident.result}` : ''
       return { icon: AWAIT_TARGET_THEME.ident.icon, keyword: '', signature: `${name}${result}`, isUnresolved: false }
     }
     default:
@@ -80,14 +87,15 @@ function getAwaitStmtDisplay(
   stmt: AwaitStmt,
   context: { activities: Map<string, any>; workflows: Map<string, any>; nexusServices: Map<string, any> },
   handlers: { signals: Map<string, any>; updates: Map<string, any> },
-): { icon: string; keyword: string; signature: string; blockClass: string; expandableDef?: { body?: Statement[] }; nexusAsyncWorkflow?: WorkflowDef; nexusSyncBody?: Statement[]; isUnresolved: boolean } {
-  const target = getAwaitTargetDisplay(stmt, context, handlers)
+): { icon: string; keyword: string; signature: string; blockClass: string; expandableDef?: { body: Statement[] }; nexusAsyncWorkflow?: WorkflowDef; nexusSyncBody?: Statement[]; isUnresolved: boolean } {
+  const kind = stmt.target.kind
+  const target = getAwaitTargetDisplay(stmt.target, context, handlers)
   return {
     ...target,
     // Activity/workflow/nexus use SVG icons at block level, not text icons
-    icon: (stmt.kind === 'activity' || stmt.kind === 'workflow' || stmt.kind === 'nexus') ? '' : target.icon,
+    icon: (kind === 'activity' || kind === 'workflow' || kind === 'nexus') ? '' : target.icon,
     keyword: target.keyword ? `await ${target.keyword}` : 'await',
-    blockClass: `block-await-stmt block-await-stmt-${stmt.kind}`,
+    blockClass: `block-await-stmt block-await-stmt-${kind}`,
   }
 }
 
@@ -97,17 +105,18 @@ function getAwaitOneCaseDisplay(
   context: { activities: Map<string, any>; workflows: Map<string, any>; nexusServices: Map<string, any> },
   handlers: { signals: Map<string, any>; updates: Map<string, any> },
 ): { contentClass: string; icon: string; keyword: string; signature: string; isUnresolved: boolean } {
-  // await_all is case-only, handle separately
-  if (c.kind === 'await_all') {
-    return { contentClass: 'tagged-await-all', icon: THEME.awaitAll.icon, keyword: 'await all', signature: `${c.awaitAll?.body?.length || 0} branch(es)`, isUnresolved: false }
+  const guard = c.guard ? ` if (${c.guard})` : ''
+  // await all is case-only, handle separately
+  if (c.awaitAll || !c.target) {
+    return { contentClass: 'tagged-await-all', icon: THEME.awaitAll.icon, keyword: 'await all', signature: `${c.awaitAll?.body.length || 0} branch(es)${guard}`, isUnresolved: false }
   }
-  const target = getAwaitTargetDisplay(c, context, handlers)
+  const target = getAwaitTargetDisplay(c.target, context, handlers)
   return {
     icon: target.icon,
     keyword: target.keyword,
-    signature: target.signature,
+    signature: `${target.signature}${guard}`,
     isUnresolved: target.isUnresolved,
-    contentClass: `tagged-${c.kind}`,
+    contentClass: `tagged-${c.target.kind}`,
   }
 }
 
@@ -140,8 +149,8 @@ export function AwaitStmtBlock({ stmt }: { stmt: AwaitStmt }) {
             <InlineWorkflowBlock def={nexusAsyncWorkflow} />
           ) : nexusSyncBody ? (
             <SyncBodyBlock body={nexusSyncBody} />
-          ) : expandableDef && (expandableDef.body || []).length > 0 ? (
-            (expandableDef.body || []).map((s) => (
+          ) : expandableDef && expandableDef.body.length > 0 ? (
+            expandableDef.body.map((s) => (
               <StatementBlock key={`${s.line}:${s.column}`} statement={s} />
             ))
           ) : (
@@ -163,12 +172,16 @@ export function AwaitAllBlockComponent({ stmt }: { stmt: AwaitAllBlock }) {
         <span className="block-toggle">{expanded ? '▼' : '▶'}</span>
         <span className="block-icon">{THEME.awaitAll.icon}</span>
         <span className="block-keyword">await all</span>
-        <span className="block-signature">{(stmt.body || []).length} branch(es)</span>
+        <span className="block-signature">
+          {stmt.body.length} branch(es)
+          {stmt.options?.onError === 'continue' && ', continue on error'}
+          {stmt.options?.minSuccess && `, at least ${stmt.options.minSuccess} must succeed`}
+        </span>
       </div>
 
       {expanded && (
         <div className="block-body">
-          {(stmt.body || []).map((s) => (
+          {stmt.body.map((s) => (
             <StatementBlock key={`${s.line}:${s.column}`} statement={s} />
           ))}
         </div>
@@ -206,7 +219,7 @@ export function AwaitOneBlockComponent({ stmt }: { stmt: AwaitOneBlock }) {
 function AwaitOneCaseBlock({ awaitCase }: { awaitCase: AwaitOneCase }) {
   const context = React.useContext(DefinitionContext)
   const handlers = React.useContext(HandlerContext)
-  const hasBody = awaitCase.body && awaitCase.body.length > 0
+  const hasBody = awaitCase.body.length > 0
   const isExpandable = hasBody || !!awaitCase.awaitAll
   const [expanded, toggle] = useToggle(false, isExpandable)
 
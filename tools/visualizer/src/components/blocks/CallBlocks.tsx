@@ -31,8 +31,8 @@ export function ActivityCallBlock({ stmt }: { stmt: ActivityCall }) {
 
       {expanded && isDefined && (
         <div className="block-body">
-          {(activityDef.body || []).length > 0 ? (
-            (activityDef.body || []).map((s) => (
+          {activityDef.body.length > 0 ? (
+            activityDef.body.map((s) => (
               <StatementBlock key={`${s.line}:${s.column}`} statement={s} />
             ))
           ) : (
@@ -83,7 +83,7 @@ export function NexusCallBlock({ stmt }: { stmt: NexusCall }) {
 
   // Look up the service and operation from context
   const serviceDef = context.nexusServices.get(stmt.service)
-  const operation = serviceDef?.operations?.find(op => op.name === stmt.operation)
+  const operation = serviceDef?.operations.find(op => op.name === stmt.operation)
   const isDefined = !!operation
 
   // For expansion: async shows linked workflow, sync shows body
@@ -137,6 +137,9 @@ function formatActivityCallSignature(stmt: ActivityCall): string {
 
 function formatWorkflowCallSignature(stmt: WorkflowCall): string {
   let sig = `${stmt.name}(${stmt.args})`
+  if (stmt.id) {
+    sig += ` id "${stmt.id}"`
+  }
   if (stmt.result) {
     sig += ` → ${stmt.result}`
   }
@@ -1,4 +1,4 @@
-import type { SwitchBlock, IfStmt, ForStmt } from '../../types/ast'
+import type { SwitchBlock, IfStmt, ForStmt, Statement } from '../../types/ast'
 import { useToggle } from './useToggle'
 import { StatementBlock } from './StatementBlock'
 import { THEME } from '../../theme/temporal-theme'
@@ -53,7 +53,7 @@ function SwitchCaseBlock({ switchCase }: { switchCase: SwitchBlock['cases'][0] }
         <span className="block-signature">{switchCase.value}</span>
       </div>
 
-      {expanded && switchCase.body && switchCase.body.length > 0 && (
+      {expanded && switchCase.body.length > 0 && (
         <div className="block-body">
           {switchCase.body.map((s) => (
             <StatementBlock key={`${s.line}:${s.column}`} statement={s} />
@@ -64,10 +64,10 @@ function SwitchCaseBlock({ switchCase }: { switchCase: SwitchBlock['cases'][0] }
   )
 }
 
-// If - expandable
+// If - expandable, with an elif chain drawn flat
 export function IfBlock({ stmt }: { stmt: IfStmt }) {
   const [expanded, toggle] = useToggle(true)
-  const hasElse = stmt.elseBody && stmt.elseBody.length > 0
+  const branches = ifBranches(stmt)
 
   return (
     <div className={`block block-if ${expanded ? 'expanded' : 'collapsed'}`}>
@@ -80,25 +80,37 @@ export function IfBlock({ stmt }: { stmt: IfStmt }) {
 
       {expanded && (
         <div className="block-body">
-          <div className="block-branch">
-            {(stmt.body || []).map((s) => (
-              <StatementBlock key={`${s.line}:${s.column}`} statement={s} />
-            ))}
-          </div>
-          {hasElse && (
-            <div className="block-branch">
-              <div className="branch-label">else:</div>
-              {(stmt.elseBody || []).map((s) => (
+          {branches.map((branch, i) => (
+            <div key={i} className="block-branch">
+              {branch.label && <div className="branch-label">{branch.label}</div>}
+              {branch.body.map((s) => (
                 <StatementBlock key={`${s.line}:${s.column}`} statement={s} />
               ))}
             </div>
-          )}
+          ))}
         </div>
       )}
     </div>
   )
 }
 
+// Branches of an if statement: its body, then one per elif (an elseIf
+// statement's elseBody holds exactly the chained if), then the else.
+function ifBranches(stmt: IfStmt): { label: string; body: Statement[] }[] {
+  const branches = [{ label: '', body: stmt.body }]
+  let current = stmt
+  for (;;) {
+    const next = current.elseBody?.[0]
+    if (!current.elseIf || next?.type !== 'if') break
+    current = next
+    branches.push({ label: `elif ${current.condition}:`, body: current.body })
+  }
+  if (current.elseBody && current.elseBody.length > 0) {
+    branches.push({ label: 'else:', body: current.elseBody })
+  }
+  return branches
+}
+
 // For - expandable
 export function ForBlock({ stmt }: { stmt: ForStmt }) {
   const [expanded, toggle] = useToggle(true)
@@ -106,6 +118,8 @@ export function ForBlock({ stmt }: { stmt: ForStmt }) {
   let label = ''
   if (stmt.variant === 'iteration') {
     label = `${stmt.variable} in ${stmt.iterable}`
+  } else if (stmt.variant === 'parallel') {
+    label = `each ${stmt.variable} in ${stmt.iterable}, parallel max ${stmt.concurrency}`
   } else if (stmt.variant === 'conditional') {
     label = stmt.condition || ''
   } else {
@@ -117,13 +131,13 @@ export function ForBlock({ stmt }: { stmt: ForStmt }) {
       <div className="block-header" onClick={toggle}>
         <span className="block-toggle">{expanded ? '▼' : '▶'}</span>
         <span className="block-icon">{THEME.forLoop.icon}</span>
-        <span className="block-keyword">for</span>
+        <span className="block-keyword">{stmt.label ? `${stmt.label}: for` : 'for'}</span>
         <span className="block-signature">{label}</span>
       </div>
 
       {expanded && (
         <div className="block-body">
-          {(stmt.body || []).map((s) => (
+          {stmt.body.map((s) => (
             <StatementBlock key={`${s.line}:${s.column}`} statement={s} />
           ))}
         </div>
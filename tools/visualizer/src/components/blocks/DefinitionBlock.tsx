@@ -1,5 +1,5 @@
 import React from 'react'
-import type { Definition, WorkflowDef, ActivityDef, WorkerDef, WorkerRef, NamespaceDef, NamespaceWorker, NamespaceEndpoint, NexusServiceDef, NexusOperation, SignalDecl, QueryDecl, UpdateDecl } from '../../types/ast'
+import type { Definition, WorkflowDef, ActivityDef, WorkerDef, WorkerRef, NamespaceDef, NamespaceWorker, NamespaceEndpoint, NexusServiceDef, NexusOperation, ConstDef, EnumDef, SignalDecl, QueryDecl, UpdateDecl } from '../../types/ast'
 import { StatementBlock } from './StatementBlock'
 import { WorkflowContent, InlineWorkflowBlock, SyncBodyBlock } from './WorkflowContent'
 import { THEME, ThemeIcon, WORKER_REF_THEME } from '../../theme/temporal-theme'
@@ -23,6 +23,10 @@ export function DefinitionBlock({ definition }: DefinitionBlockProps) {
       return <NamespaceDefBlock def={definition} />
     case 'nexusServiceDef':
       return <NexusServiceDefBlock def={definition} />
+    case 'constDef':
+      return <ConstDefBlock def={definition} />
+    case 'enumDef':
+      return <EnumDefBlock def={definition} />
     default:
       return null
   }
@@ -38,9 +42,9 @@ function WorkflowDefBlock({ def }: { def: WorkflowDef }) {
     const queries = new Map<string, QueryDecl>()
     const updates = new Map<string, UpdateDecl>()
 
-    for (const s of def.signals || []) signals.set(s.name, s)
-    for (const q of def.queries || []) queries.set(q.name, q)
-    for (const u of def.updates || []) updates.set(u.name, u)
+    for (const s of def.signals) signals.set(s.name, s)
+    for (const q of def.queries) queries.set(q.name, q)
+    for (const u of def.updates) updates.set(u.name, u)
 
     return { signals, queries, updates }
   }, [def])
@@ -80,7 +84,7 @@ function ActivityDefBlock({ def }: { def: ActivityDef }) {
 
       {expanded && (
         <div className="block-body">
-          {(def.body || []).map((stmt) => (
+          {def.body.map((stmt) => (
             <StatementBlock key={`${stmt.line}:${stmt.column}`} statement={stmt} />
           ))}
         </div>
@@ -92,7 +96,7 @@ function ActivityDefBlock({ def }: { def: ActivityDef }) {
 function WorkerDefBlock({ def }: { def: WorkerDef }) {
   const [expanded, toggle] = useToggle()
 
-  const totalRefs = (def.workflows?.length || 0) + (def.activities?.length || 0) + (def.services?.length || 0)
+  const totalRefs = def.workflows.length + def.activities.length + def.services.length
 
   return (
     <div className={`block block-worker-def ${expanded ? 'expanded' : 'collapsed'}`}>
@@ -105,13 +109,13 @@ function WorkerDefBlock({ def }: { def: WorkerDef }) {
 
       {expanded && (
         <div className="block-body">
-          {def.workflows?.length > 0 && (
+          {def.workflows.length > 0 && (
             <WorkerRefSection label="workflows" refs={def.workflows} refType="workflow" />
           )}
-          {def.activities?.length > 0 && (
+          {def.activities.length > 0 && (
             <WorkerRefSection label="activities" refs={def.activities} refType="activity" />
           )}
-          {def.services?.length > 0 && (
+          {def.services.length > 0 && (
             <WorkerRefSection label="nexus services" refs={def.services} refType="service" />
           )}
         </div>
@@ -166,11 +170,11 @@ function WorkerRefItem({ ref_, refType }: { ref_: WorkerRef; refType: 'workflow'
           {linkedDef?.type === 'workflowDef' ? (
             <WorkflowContent def={linkedDef} />
           ) : linkedDef ? (
-            (linkedDef.body || []).map((stmt) => (
+            linkedDef.body.map((stmt) => (
               <StatementBlock key={`${stmt.line}:${stmt.column}`} statement={stmt} />
             ))
           ) : linkedService ? (
-            (linkedService.operations || []).map((op) => (
+            linkedService.operations.map((op) => (
               <NexusOperationBlock key={`${op.line}:${op.column}`} operation={op} />
             ))
           ) : null}
@@ -183,7 +187,7 @@ function WorkerRefItem({ ref_, refType }: { ref_: WorkerRef; refType: 'workflow'
 function NamespaceDefBlock({ def }: { def: NamespaceDef }) {
   const [expanded, toggle] = useToggle()
 
-  const totalEntries = (def.workers?.length || 0) + (def.endpoints?.length || 0)
+  const totalEntries = def.workers.length + def.endpoints.length
 
   return (
     <div className={`block block-namespace-def ${expanded ? 'expanded' : 'collapsed'}`}>
@@ -196,7 +200,7 @@ function NamespaceDefBlock({ def }: { def: NamespaceDef }) {
 
       {expanded && (
         <div className="block-body">
-          {def.workers?.length > 0 && (
+          {def.workers.length > 0 && (
             <div className="namespace-entry-section">
               <div className="namespace-entry-label">workers</div>
               {def.workers.map((w) => (
@@ -204,7 +208,7 @@ function NamespaceDefBlock({ def }: { def: NamespaceDef }) {
               ))}
             </div>
           )}
-          {def.endpoints?.length > 0 && (
+          {def.endpoints.length > 0 && (
             <div className="namespace-entry-section">
               <div className="namespace-entry-label">nexus endpoints</div>
               {def.endpoints.map((ep) => (
@@ -239,13 +243,13 @@ function NamespaceWorkerEntry({ entry }: { entry: NamespaceWorker }) {
 
       {expanded && isDefined && workerDef && (
         <div className="block-body">
-          {workerDef.workflows?.length > 0 && (
+          {workerDef.workflows.length > 0 && (
             <WorkerRefSection label="workflows" refs={workerDef.workflows} refType="workflow" />
           )}
-          {workerDef.activities?.length > 0 && (
+          {workerDef.activities.length > 0 && (
             <WorkerRefSection label="activities" refs={workerDef.activities} refType="activity" />
           )}
-          {workerDef.services?.length > 0 && (
+          {workerDef.services.length > 0 && (
             <WorkerRefSection label="nexus services" refs={workerDef.services} refType="service" />
           )}
         </div>
@@ -266,6 +270,47 @@ function NamespaceEndpointEntry({ entry }: { entry: NamespaceEndpoint }) {
   )
 }
 
+// Constant - a single line: const name = value
+function ConstDefBlock({ def }: { def: ConstDef }) {
+  const value = def.valueType === 'string' ? `"${def.value}"` : def.value
+
+  return (
+    <div className="block block-const-def collapsed">
+      <div className="block-header">
+        <span className="block-toggle-placeholder" />
+        <span className="block-icon">{THEME.constant.icon}</span>
+        <span className="block-keyword">const</span>
+        <span className="block-signature">{def.name} = {value}</span>
+      </div>
+    </div>
+  )
+}
+
+// Enum - expandable to list its values
+function EnumDefBlock({ def }: { def: EnumDef }) {
+  const [expanded, toggle] = useToggle()
+  const valueCount = def.values.length
+
+  return (
+    <div className={`block block-enum-def ${expanded ? 'expanded' : 'collapsed'}`}>
+      <div className="block-header" onClick={toggle}>
+        <span className="block-toggle">{expanded ? '▼' : '▶'}</span>
+        <span className="block-icon">{THEME.enum.icon}</span>
+        <span className="block-keyword">enum</span>
+        <span className="block-signature">{def.name} ({valueCount} value{valueCount !== 1 ? 's' : ''})</span>
+      </div>
+
+      {expanded && (
+        <div className="block-body">
+          {def.values.map((v) => (
+            <div key={`${v.line}:${v.column}`} className="enum-value">{v.name}</div>
+          ))}
+        </div>
+      )}
+    </div>
+  )
+}
+
 function formatWorkflowSignature(def: WorkflowDef): string {
   let sig = `${def.name}(${def.params})`
   if (def.returnType) {
@@ -276,7 +321,7 @@ function formatWorkflowSignature(def: WorkflowDef): string {
 
 function NexusServiceDefBlock({ def }: { def: NexusServiceDef }) {
   const [expanded, toggle] = useToggle()
-  const opCount = def.operations?.length || 0
+  const opCount = def.operations.length
 
   return (
     <div className={`block block-nexus-service-def ${expanded ? 'expanded' : 'collapsed'}`}>
@@ -289,7 +334,7 @@ function NexusServiceDefBlock({ def }: { def: NexusServiceDef }) {
 
       {expanded && (
         <div className="block-body">
-          {(def.operations || []).map((op) => (
+          {def.operations.map((op) => (
             <NexusOperationBlock key={`${op.line}:${op.column}`} operation={op} />
           ))}
         </div>
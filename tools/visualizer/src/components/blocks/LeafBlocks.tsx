@@ -55,14 +55,15 @@ export function RawBlock({ stmt }: { stmt: RawStmt }) {
   )
 }
 
-// Simple block (break, continue)
-export function SimpleBlock({ keyword, className }: { keyword: string; className: string }) {
+// Simple block (break, continue), with the label of the loop it targets
+export function SimpleBlock({ keyword, className, label }: { keyword: string; className: string; label?: string }) {
   return (
     <div className={`block ${className} collapsed`}>
       <div className="block-header">
         <span className="block-toggle-placeholder" />
         <span className="block-icon">{THEME.breakContinue.icon}</span>
         <span className="block-keyword">{keyword}</span>
+        {label && <span className="block-signature">{label}</span>}
       </div>
     </div>
   )
@@ -72,20 +73,23 @@ export function SimpleBlock({ keyword, className }: { keyword: string; className
 export function PromiseBlock({ stmt }: { stmt: PromiseStmt }) {
   // Determine the async target description
   let target = ''
-  if (stmt.activity) {
-    target = `activity ${stmt.activity}(${stmt.activityArgs || ''})`
-  } else if (stmt.workflow) {
-    target = `workflow ${stmt.workflow}(${stmt.workflowArgs || ''})`
-  } else if (stmt.nexus) {
-    target = `nexus ${stmt.nexus} ${stmt.nexusService || ''}.${stmt.nexusOperation || ''}(${stmt.nexusArgs || ''})`
-  } else if (stmt.timer) {
-    target = `timer(${stmt.timer})`
-  } else if (stmt.signal) {
-    const params = stmt.signalParams ? `(${stmt.signalParams})` : ''
-    target = `signal ${stmt.signal}${params}`
-  } else if (stmt.update) {
-    const params = stmt.updateParams ? `(${stmt.updateParams})` : ''
-    target = `update ${stmt.update}${params}`
+  const t = stmt.target
+  if (t.activity) {
+    target = `activity ${t.activity.name}(${t.activity.args || ''})`
+  } else if (t.workflow) {
+    target = `workflow ${t.workflow.name}(${t.workflow.args || ''})`
+  } else if (t.nexus) {
+    target = `nexus ${t.nexus.endpoint} ${t.nexus.service}.${t.nexus.operation}(${t.nexus.args || ''})`
+  } else if (t.timer) {
+    target = `timer(${t.timer.const || t.timer.duration})`
+  } else if (t.signal) {
+    const params = t.signal.params ? `(${t.signal.params})` : ''
+    target = `signal ${t.signal.name}${params}`
+  } else if (t.update) {
+    const params = t.update.params ? `(${t.update.params})` : ''
+    target = `update ${t.update.name}${params}`
+  } else if (t.ident) {
+    target = t.ident.name
   }
 
   return (
@@ -36,9 +36,9 @@ export function StatementBlock({ statement }: StatementBlockProps) {
     case 'raw':
       return <RawBlock stmt={statement} />
     case 'break':
-      return <SimpleBlock keyword="break" className="block-break" />
+      return <SimpleBlock keyword="break" className="block-break" label={statement.label} />
     case 'continue':
-      return <SimpleBlock keyword="continue" className="block-continue" />
+      return <SimpleBlock keyword="continue" className="block-continue" label={statement.label} />
     case 'promise':
       return <PromiseBlock stmt={statement} />
     case 'set':
@@ -7,7 +7,7 @@ import { HandlerContext } from '../WorkflowCanvas'
 import './blocks.css'
 
 function HandlerDeclBlock({ decl }: { decl: HandlerDecl }) {
-  const hasBody = decl.body && decl.body.length > 0
+  const hasBody = decl.body.length > 0
   const [expanded, toggle] = useToggle(false, hasBody)
   const { icon, keyword, cssClass } = HANDLER_CONFIG[decl.type]
 
@@ -40,12 +40,12 @@ export function WorkflowContent({ def }: { def: WorkflowDef }) {
   const [queriesExpanded, toggleQueries] = useToggle()
   const [updatesExpanded, toggleUpdates] = useToggle()
 
-  const hasState = def.state && ((def.state.conditions && def.state.conditions.length > 0) || (def.state.rawStmts && def.state.rawStmts.length > 0))
+  const hasState = def.state && (def.state.conditions.length > 0 || def.state.rawStmts.length > 0)
   const hasSignals = def.signals && def.signals.length > 0
   const hasQueries = def.queries && def.queries.length > 0
   const hasUpdates = def.updates && def.updates.length > 0
 
-  const stateItemCount = (def.state?.conditions?.length || 0) + (def.state?.rawStmts?.length || 0)
+  const stateItemCount = (def.state?.conditions.length || 0) + (def.state?.rawStmts.length || 0)
 
   return (
     <>
@@ -60,7 +60,7 @@ export function WorkflowContent({ def }: { def: WorkflowDef }) {
           </div>
           {stateExpanded && (
             <div className="block-declarations">
-              {(def.state!.conditions || []).map((c) => (
+              {def.state!.conditions.map((c) => (
                 <div key={`${c.line}:${c.column}`} className="declaration declaration-condition">
                   <div className="declaration-header">
                     <span className="block-toggle-placeholder" />
@@ -70,7 +70,7 @@ export function WorkflowContent({ def }: { def: WorkflowDef }) {
                   </div>
                 </div>
               ))}
-              {(def.state!.rawStmts || []).map((r) => (
+              {def.state!.rawStmts.map((r) => (
                 <div key={`${r.line}:${r.column}`} className="declaration declaration-raw-state">
                   <div className="declaration-header">
                     <span className="block-toggle-placeholder" />
@@ -140,7 +140,7 @@ export function WorkflowContent({ def }: { def: WorkflowDef }) {
 
       {/* Body statements */}
       <div>
-        {(def.body || []).map((stmt) => (
+        {def.body.map((stmt) => (
           <StatementBlock key={`${stmt.line}:${stmt.column}`} statement={stmt} />
         ))}
       </div>
@@ -160,9 +160,9 @@ export function InlineWorkflowBlock({ def }: { def: WorkflowDef }) {
     const queries = new Map<string, QueryDecl>()
     const updates = new Map<string, UpdateDecl>()
 
-    for (const s of def.signals || []) signals.set(s.name, s)
-    for (const q of def.queries || []) queries.set(q.name, q)
-    for (const u of def.updates || []) updates.set(u.name, u)
+    for (const s of def.signals) signals.set(s.name, s)
+    for (const q of def.queries) queries.set(q.name, q)
+    for (const u of def.updates) updates.set(u.name, u)
 
     return { signals, queries, updates }
   }, [def])
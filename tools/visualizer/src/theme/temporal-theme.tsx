@@ -18,6 +18,7 @@ export type PrimitiveKind =
   | 'promise' | 'return'
   | 'closeComplete' | 'closeFail' | 'closeContinueAsNew'
   | 'forLoop' | 'awaitAll' | 'raw' | 'breakContinue' | 'error'
+  | 'constant' | 'enum'
 
 // --- Central theme map ---
 
@@ -45,6 +46,8 @@ export const THEME: Record<PrimitiveKind, PrimitiveTheme> = {
   raw:                { icon: '≡',   label: 'Raw Code',              cssVarPrefix: 'raw' },
   breakContinue:      { icon: '•',   label: 'Break/Continue',        cssVarPrefix: 'subtle' },
   error:              { icon: '⚠',   label: 'Error',                 cssVarPrefix: 'signal' },
+  constant:           { icon: '≔',   label: 'Constant',              cssVarPrefix: 'raw' },
+  enum:               { icon: '⋮',   label: 'Enum',                  cssVarPrefix: 'raw' },
 }
 
 // --- Derived lookup tables ---
@@ -62,6 +65,8 @@ export const DEF_TYPE_CONFIGS: DefTypeConfig[] = [
   { type: 'nexusServiceDef', icon: THEME.nexusService.icon, label: 'Nexus Services', defaultOn: false },
   { type: 'workflowDef',     icon: THEME.workflow.icon,     label: 'Workflows',      defaultOn: true },
   { type: 'activityDef',     icon: THEME.activity.icon,     label: 'Activities',     defaultOn: false },
+  { type: 'constDef',        icon: THEME.constant.icon,     label: 'Constants',      defaultOn: false },
+  { type: 'enumDef',         icon: THEME.enum.icon,         label: 'Enums',          defaultOn: false },
 ]
 
 export const DEF_TYPE_ORDER = new Map(DEF_TYPE_CONFIGS.map((cfg, i) => [cfg.type, i]))
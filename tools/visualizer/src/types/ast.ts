@@ -21,6 +21,9 @@ export interface FileError {
 
 // Top-level file
 export interface TWFFile {
+  // Set by twf parse; absent when the extension merges several files
+  schemaVersion?: number
+  summary?: FileSummary
   definitions: Definition[]
   // Added for focused-file visualization
   focusedFile?: string
@@ -28,6 +31,79 @@ export interface TWFFile {
   errors?: FileError[]
 }
 
+// Count of each definition type in a parsed file
+export interface FileSummary {
+  namespaces: number
+  workers: number
+  workflows: number
+  activities: number
+  nexusServices: number
+  constants?: number
+  enums?: number
+}
+
+// Structured expression, discriminated by kind (matches Go expr_json.go)
+export type Expr =
+  | IdentExpr
+  | SelectorExpr
+  | LiteralExpr
+  | BoolExpr
+  | ListExpr
+  | MapExpr
+  | BinaryExpr
+  | UnaryExpr
+
+export interface IdentExpr extends Position {
+  kind: 'ident'
+  name: string
+}
+
+export interface SelectorExpr extends Position {
+  kind: 'selector'
+  x: Expr
+  sel: string
+}
+
+// String, number, and duration literals keep their source form
+export interface LiteralExpr extends Position {
+  kind: 'string' | 'number' | 'duration'
+  value: string
+}
+
+export interface BoolExpr extends Position {
+  kind: 'bool'
+  value: boolean
+}
+
+export interface ListExpr extends Position {
+  kind: 'list'
+  elems: Expr[]
+}
+
+export interface MapExpr extends Position {
+  kind: 'map'
+  type?: string  // record type of a typed literal
+  entries: MapEntry[]
+}
+
+export interface MapEntry extends Position {
+  key: string
+  value: Expr
+}
+
+export interface BinaryExpr extends Position {
+  kind: 'binary'
+  op: string
+  x: Expr
+  y: Expr
+}
+
+export interface UnaryExpr extends Position {
+  kind: 'unary'
+  op: string
+  x: Expr
+}
+
 // Nexus service definition
 export type NexusOperationType = 'async' | 'sync'
 
@@ -43,18 +119,21 @@ export interface NexusOperation extends Position {
 export interface NexusServiceDef extends Position {
   type: 'nexusServiceDef'
   name: string
-  operations?: NexusOperation[]
+  operations: NexusOperation[]
   sourceFile?: string
 }
 
 // Definition types
-export type Definition = WorkflowDef | ActivityDef | WorkerDef | NamespaceDef | NexusServiceDef
+export type Definition = WorkflowDef | ActivityDef | WorkerDef | NamespaceDef | NexusServiceDef | ConstDef | EnumDef
 
 export interface WorkflowDef extends Position {
   type: 'workflowDef'
   name: string
   params: string
   returnType?: string
+  returns?: string[]  // returnType split into its types
+  description?: string
+  options?: OptionsBlock
   state?: StateBlock
   signals: SignalDecl[]
   queries: QueryDecl[]
@@ -62,12 +141,19 @@ export interface WorkflowDef extends Position {
   body: Statement[]
   // Source file path (added by extension)
   sourceFile?: string
+  annotations?: Annotation[]
+}
+
+// Annotation such as @owner("payments-team") above a workflow or activity
+export interface Annotation extends Position {
+  name: string
+  args?: string
 }
 
 // State block declared at the top of a workflow definition
 export interface StateBlock {
-  conditions?: ConditionDecl[]
-  rawStmts?: RawStmt[]
+  conditions: ConditionDecl[]
+  rawStmts: RawStmt[]
 }
 
 export interface ConditionDecl extends Position {
@@ -79,9 +165,36 @@ export interface ActivityDef extends Position {
   name: string
   params: string
   returnType?: string
+  returns?: string[]  // returnType split into its types
+  options?: OptionsBlock
   body: Statement[]
   // Source file path (added by extension)
   sourceFile?: string
+  annotations?: Annotation[]
+}
+
+// Top-level constant: const approvalTimeout = 7d
+export interface ConstDef extends Position {
+  type: 'constDef'
+  name: string
+  value: string       // literal text; strings are unquoted
+  valueType: string   // 'string', 'duration', 'number', or 'bool'
+  valueExpr?: Expr
+  // Source file path (added by extension)
+  sourceFile?: string
+}
+
+// Top-level enumeration: enum OrderType: invoice, refund
+export interface EnumDef extends Position {
+  type: 'enumDef'
+  name: string
+  values: EnumValue[]
+  // Source file path (added by extension)
+  sourceFile?: string
+}
+
+export interface EnumValue extends Position {
+  name: string
 }
 
 // Worker reference (a named ref to a workflow, activity, or nexus service)
@@ -132,7 +245,7 @@ export interface SignalDecl extends Position {
   type: 'signalDecl'
   name: string
   params: string
-  body?: Statement[]
+  body: Statement[]
 }
 
 export interface QueryDecl extends Position {
@@ -140,7 +253,7 @@ export interface QueryDecl extends Position {
   name: string
   params: string
   returnType?: string
-  body?: Statement[]
+  body: Statement[]
 }
 
 export interface UpdateDecl extends Position {
@@ -148,7 +261,7 @@ export interface UpdateDecl extends Position {
   name: string
   params: string
   returnType?: string
-  body?: Statement[]
+  body: Statement[]
 }
 
 // Statement types
@@ -176,8 +289,11 @@ export interface ActivityCall extends Position {
   type: 'activityCall'
   name: string
   args: string
+  argExprs?: Expr[]
   result?: string
-  options?: string
+  results?: string[]  // result split into its names
+  options?: OptionsBlock
+  resolved?: ResolvedRef
 }
 
 export type WorkflowCallMode = 'child' | 'detach'
@@ -187,8 +303,12 @@ export interface WorkflowCall extends Position {
   mode: WorkflowCallMode
   name: string
   args: string
+  argExprs?: Expr[]
+  id?: string  // workflow ID template: workflow Child(x) id "child-{x}"
   result?: string
-  options?: string
+  results?: string[]  // result split into its names
+  options?: OptionsBlock
+  resolved?: ResolvedRef
 }
 
 // Options block (key-value pairs used by nexus calls, namespaces, etc.)
@@ -196,11 +316,14 @@ export interface OptionEntry {
   key: string
   value?: string
   valueType?: string
+  expr?: Expr
+  const?: string  // constant the value names; value holds its literal
   nested?: OptionEntry[]
 }
 
 export interface OptionsBlock {
   entries: OptionEntry[]
+  rawText?: string  // set instead of entries when the block does not parse
 }
 
 // Nexus call - calls a nexus service operation
@@ -211,7 +334,9 @@ export interface NexusCall extends Position {
   service: string
   operation: string
   args: string
+  argExprs?: Expr[]
   result?: string
+  results?: string[]  // result split into its names
   options?: OptionsBlock
   resolvedEndpoint?: ResolvedRef
   resolvedEndpointNamespace?: string
@@ -219,84 +344,103 @@ export interface NexusCall extends Position {
   resolvedOperation?: ResolvedRef
 }
 
-// Single await statement: await timer/signal/update/activity/workflow/nexus/ident
-export type AwaitStmtKind = 'timer' | 'signal' | 'update' | 'activity' | 'workflow' | 'nexus' | 'ident'
+// Async target of an await, an await one case, or a promise. Exactly one
+// per-kind object is set, the one named by kind.
+export type AsyncTargetKind = 'timer' | 'signal' | 'update' | 'activity' | 'workflow' | 'nexus' | 'ident'
+
+export interface AsyncTarget {
+  kind: AsyncTargetKind
+  timer?: TimerTarget
+  signal?: SignalTarget
+  update?: UpdateTarget
+  activity?: ActivityTarget
+  workflow?: WorkflowTarget
+  nexus?: NexusTarget
+  ident?: IdentTarget
+}
+
+export interface TimerTarget {
+  duration: string
+  const?: string   // constant the duration names; duration holds its literal
+  result?: string  // await one case: timer(1h) -> expired
+}
+
+export interface SignalTarget {
+  name: string
+  params?: string
+}
+
+export interface UpdateTarget {
+  name: string
+  params?: string
+}
+
+export interface ActivityTarget {
+  name: string
+  args?: string
+  argExprs?: Expr[]
+  result?: string
+  results?: string[]  // result split into its names
+  resolved?: ResolvedRef
+}
+
+export interface WorkflowTarget {
+  name: string
+  mode: WorkflowCallMode
+  args?: string
+  argExprs?: Expr[]
+  result?: string
+  results?: string[]  // result split into its names
+  resolved?: ResolvedRef
+}
 
+export interface NexusTarget {
+  endpoint: string
+  service: string
+  operation: string
+  args?: string
+  argExprs?: Expr[]
+  result?: string
+  results?: string[]  // result split into its names
+  detach?: boolean
+  resolvedEndpoint?: ResolvedRef
+  resolvedEndpointNamespace?: string
+  resolvedService?: ResolvedRef
+  resolvedOperation?: ResolvedRef
+}
+
+// Promise or condition reference; result binds a condition's value
+export interface IdentTarget {
+  name: string
+  result?: string
+}
+
+// Single await statement: await timer/signal/update/activity/workflow/nexus/ident
 export interface AwaitStmt extends Position {
   type: 'await'
-  kind: AwaitStmtKind
-  timer?: string
-  signal?: string
-  signalParams?: string
-  update?: string
-  updateParams?: string
-  activity?: string
-  activityArgs?: string
-  activityResult?: string
-  workflow?: string
-  workflowMode?: string
-  workflowArgs?: string
-  workflowResult?: string
-  // Nexus await
-  nexus?: string
-  nexusService?: string
-  nexusOperation?: string
-  nexusArgs?: string
-  nexusResult?: string
-  nexusDetach?: boolean
-  nexusResolvedEndpoint?: ResolvedRef
-  nexusResolvedEndpointNamespace?: string
-  nexusResolvedService?: ResolvedRef
-  nexusResolvedOperation?: ResolvedRef
-  // Ident await (promise or condition reference)
-  ident?: string
-  identResult?: string
+  target: AsyncTarget
 }
 
 // await all: waits for all operations to complete
 export interface AwaitAllBlock extends Position {
   type: 'awaitAll'
+  options?: AwaitAllOptions
   body: Statement[]
 }
 
-// await one case: signal, update, timer, activity, workflow, nested await all, or ident
-export type AwaitOneCaseKind = 'signal' | 'update' | 'timer' | 'activity' | 'workflow' | 'nexus' | 'await_all' | 'ident'
+// Partial-failure policy of an await all block: options(onError: continue, minSuccess: 2)
+export interface AwaitAllOptions extends Position {
+  onError: 'fail' | 'continue'
+  minSuccess?: number
+}
 
+// await one case: an async target or a nested await all (exactly one set)
 export interface AwaitOneCase extends Position {
-  kind: AwaitOneCaseKind
-  // Signal case
-  signal?: string
-  signalParams?: string
-  // Update case
-  update?: string
-  updateParams?: string
-  // Timer case
-  timer?: string
-  // Activity case
-  activity?: string
-  activityArgs?: string
-  activityResult?: string
-  // Workflow case
-  workflow?: string
-  workflowMode?: string
-  workflowArgs?: string
-  workflowResult?: string
-  // Nexus case
-  nexus?: string
-  nexusService?: string
-  nexusOperation?: string
-  nexusArgs?: string
-  nexusResult?: string
-  nexusDetach?: boolean
-  nexusResolvedEndpoint?: ResolvedRef
-  nexusResolvedEndpointNamespace?: string
-  nexusResolvedService?: ResolvedRef
-  nexusResolvedOperation?: ResolvedRef
-  // Await all case (nested)
+  target?: AsyncTarget
   awaitAll?: AwaitAllBlock
-  // Ident case (promise or condition reference)
-  ident?: string
-  identResult?: string
+  // Guard: signal Approve -> amount if (amount < 1000):
+  guard?: string
+  guardExpr?: Expr
   // Body executed when this case wins (optional - can be empty)
   body: Statement[]
 }
@@ -309,12 +453,14 @@ export interface AwaitOneBlock extends Position {
 
 export interface SwitchCase extends Position {
   value: string
+  valueExpr?: Expr
   body: Statement[]
 }
 
 export interface SwitchBlock extends Position {
   type: 'switch'
   expr: string
+  subjectExpr?: Expr
   cases: SwitchCase[]
   default?: Statement[]
 }
@@ -322,18 +468,24 @@ export interface SwitchBlock extends Position {
 export interface IfStmt extends Position {
   type: 'if'
   condition: string
+  conditionExpr?: Expr
   body: Statement[]
   elseBody?: Statement[]
+  // elif / else if: elseBody holds exactly one chained if statement
+  elseIf?: boolean
 }
 
-export type ForVariant = 'infinite' | 'conditional' | 'iteration'
+export type ForVariant = 'infinite' | 'conditional' | 'iteration' | 'parallel'
 
 export interface ForStmt extends Position {
   type: 'for'
+  label?: string  // outer: for ...
   variant: ForVariant
   condition?: string
+  conditionExpr?: Expr
   variable?: string
   iterable?: string
+  concurrency?: string
   body: Statement[]
 }
 
@@ -346,14 +498,17 @@ export interface CloseStmt extends Position {
   type: 'close'
   reason: string // 'complete', 'fail', or 'continue_as_new'
   args?: string
+  argExprs?: Expr[]
 }
 
 export interface BreakStmt extends Position {
   type: 'break'
+  label?: string  // loop the break targets
 }
 
 export interface ContinueStmt extends Position {
   type: 'continue'
+  label?: string  // loop the continue targets
 }
 
 export interface RawStmt extends Position {
@@ -370,25 +525,7 @@ export interface Comment extends Position {
 export interface PromiseStmt extends Position {
   type: 'promise'
   name: string
-  // Async target (exactly one set)
-  timer?: string
-  signal?: string
-  signalParams?: string
-  update?: string
-  updateParams?: string
-  activity?: string
-  activityArgs?: string
-  workflow?: string
-  workflowArgs?: string
-  // Nexus target
-  nexus?: string
-  nexusService?: string
-  nexusOperation?: string
-  nexusArgs?: string
-  nexusResolvedEndpoint?: ResolvedRef
-  nexusResolvedEndpointNamespace?: string
-  nexusResolvedService?: ResolvedRef
-  nexusResolvedOperation?: ResolvedRef
+  target: AsyncTarget
 }
 
 // Set a condition to true
@@ -423,3 +560,11 @@ export function isNamespaceDef(def: Definition): def is NamespaceDef {
 export function isNexusServiceDef(def: Definition): def is NexusServiceDef {
   return def.type === 'nexusServiceDef'
 }
+
+export function isConstDef(def: Definition): def is ConstDef {
+  return def.type === 'constDef'
+}
+
+export function isEnumDef(def: Definition): def is EnumDef {
+  return def.type === 'enumDef'
+}